@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/archive"
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/ui"
+)
+
+// archiveHitLimit is how many matches /archive search lists
+const archiveHitLimit = 10
+
+// archiveBrowser holds the chat's /archive state: the hits of the last
+// search and the pages picked to quote in the next question
+type archiveBrowser struct {
+	hits   []archive.Hit
+	picked []crawler.CrawlResult
+}
+
+// handle implements /archive search <terms>, /archive use <n> and
+// /archive clear
+func (b *archiveBrowser) handle(args string, cfg *config.Config, display *ui.EnhancedDisplay) {
+	command, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch {
+	case command == "search" && rest != "":
+		b.search(rest, cfg, display)
+	case command == "use" && rest != "":
+		b.use(rest, display)
+	case command == "clear":
+		b.picked = nil
+		display.PrintInfo("No archived pages will be added")
+	default:
+		display.PrintInfo("Usage: /archive search <terms> | /archive use <n> | /archive clear")
+	}
+}
+
+// search lists the archived pages matching terms
+func (b *archiveBrowser) search(terms string, cfg *config.Config, display *ui.EnhancedDisplay) {
+	a, err := archive.New(cfg.ArchiveDir, cfg.ArchiveFormat)
+	if err != nil {
+		display.PrintError(err)
+		return
+	}
+	hits, err := a.Search(terms, archiveHitLimit)
+	if err != nil {
+		display.PrintError(err)
+		return
+	}
+	b.hits = hits
+	if len(hits) == 0 {
+		display.PrintInfo(fmt.Sprintf("No archived pages in %s match %q", cfg.ArchiveDir, terms))
+		return
+	}
+
+	for i, hit := range hits {
+		title := hit.Title
+		if title == "" {
+			title = hit.URL
+		}
+		display.PrintInfo(fmt.Sprintf("%2d. %s (%s, %s)", i+1, title, guard.Domain(hit.URL), hit.Archived.Format("2006-01-02")))
+		display.PrintInfo("    " + hit.Snippet)
+	}
+	display.PrintInfo("Quote pages in your next question with /archive use <n>, e.g. 1,3 or 2-4")
+}
+
+// use picks hits of the last search for the next question
+func (b *archiveBrowser) use(selection string, display *ui.EnhancedDisplay) {
+	if len(b.hits) == 0 {
+		display.PrintInfo("Search the archive first with /archive search <terms>")
+		return
+	}
+	picks, err := parseSelection(selection, len(b.hits))
+	if err != nil {
+		display.PrintWarning(err.Error())
+		return
+	}
+	for _, n := range picks {
+		b.picked = append(b.picked, b.hits[n-1].Result())
+	}
+	display.PrintSuccess(fmt.Sprintf("%d archived page(s) will be quoted in your next question, without fetching", len(b.picked)))
+}
+
+// Take returns the picked pages and clears them
+func (b *archiveBrowser) Take() []crawler.CrawlResult {
+	picked := b.picked
+	b.picked = nil
+	return picked
+}
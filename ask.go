@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"web-ollama/internal/bot"
+	"web-ollama/internal/config"
+	"web-ollama/internal/daemon"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// runAsk implements `web-ollama ask "question"`: a one-shot answer printed
+// to stdout. It goes through the daemon when one is running and answers
+// in-process otherwise. The plain format streams the answer; json and
+// markdown print it once it is complete. With --file it answers from that
+// document instead. Returns the process exit code.
+func runAsk(args []string) int {
+	format := flag.String("format", formatPlain, "Output format: plain, json or markdown")
+	file := flag.String("file", "", "Answer from this document (PDF, Markdown or text) instead of the web; without a question, ask follow-ups interactively")
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	question := strings.TrimSpace(strings.Join(flag.Args(), " "))
+	if *file != "" {
+		if err := checkFormat(*format); err != nil {
+			display.PrintError(err)
+			return 2
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		return askDocument(ctx, cfg, *file, question, *format, display)
+	}
+	if question == "" {
+		display.PrintError(fmt.Errorf("usage: web-ollama ask [flags] \"question\""))
+		return 2
+	}
+	if err := checkFormat(*format); err != nil {
+		display.PrintError(err)
+		return 2
+	}
+	stream := *format == formatPlain
+	tee, err := openTee(cfg.TeePath, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	defer tee.Close()
+	tee.Start(question)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	waiting := false
+	result, err := daemon.NewClient(cfg.DaemonSocket).AskRequest(ctx, daemon.Request{Question: question, Style: cfg.AnswerStyle}, func(e daemon.Event) {
+		switch e.Type {
+		case "queued":
+			fmt.Fprintf(os.Stderr, "Waiting for the daemon (position %d)...\n", e.Position)
+			waiting = true
+		case "chunk":
+			if waiting {
+				fmt.Fprintln(os.Stderr)
+				waiting = false
+			}
+			if stream {
+				fmt.Print(e.Text)
+			}
+			tee.Write(e.Text)
+		}
+	})
+	if errors.Is(err, daemon.ErrNotRunning) {
+		fmt.Fprintln(os.Stderr, "No daemon running; answering in-process. Start one with `web-ollama daemon` for faster answers.")
+		return askInProcess(ctx, cfg, question, *format, tee, display)
+	}
+	tee.End(err != nil)
+	if err != nil {
+		display.PrintError(err)
+		var remote *daemon.RemoteError
+		if errors.As(err, &remote) && remote.Hint != "" {
+			fmt.Fprintf(os.Stderr, "  → %s\n", remote.Hint)
+		}
+		return askFailed(question, *format, err)
+	}
+
+	if !stream {
+		return askPrint(resultRecord(question, result), *format)
+	}
+	printAskFooter(result.Answer, result.Sources)
+	return 0
+}
+
+// askInProcess answers question without a daemon, loading everything for
+// this one question
+func askInProcess(ctx context.Context, cfg *config.Config, question, format string, tee *answerTee, display *ui.EnhancedDisplay) int {
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+	p := &pipeline.Pipeline{
+		Config:       cfg,
+		Client:       ollamaClient,
+		Grounder:     grounder,
+		Cache:        openAnswerCache(cfg, display),
+		Sinks:        answerSinks,
+		Tools:        headlessTools(cfg, ollamaClient, grounder, display),
+		Post:         openPostprocessor(cfg, display),
+		Summary:      summarize.New(ollamaClient, cfg),
+		Perspectives: newPerspectives(cfg, ollamaClient),
+		Knowledge:    openKnowledge(cfg, ollamaClient, display),
+		Mode:         "ask",
+	}
+
+	onChunk := func(chunk string) {
+		if format == formatPlain {
+			fmt.Print(chunk)
+		}
+		tee.Write(chunk)
+	}
+	answer, err := p.Ask(ctx, question, nil, onChunk)
+	tee.End(err != nil)
+	if err != nil {
+		display.PrintError(err)
+		display.PrintHint(err)
+		return askFailed(question, format, err)
+	}
+	if format != formatPlain {
+		record := BatchRecord{Question: question, Sources: []string{}}
+		fillRecord(&record, answer)
+		return askPrint(record, format)
+	}
+	printAskFooter(answer.Answer, answer.Sources)
+	return 0
+}
+
+// askPrint writes a finished answer to stdout in format
+func askPrint(record BatchRecord, format string) int {
+	if err := writeRecord(os.Stdout, format, record); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// askFailed reports err on stdout too when the output is JSON, so scripts
+// always get a record to parse
+func askFailed(question, format string, err error) int {
+	if format == formatJSON {
+		askPrint(BatchRecord{Question: question, Sources: []string{}, Error: err.Error()}, format)
+	}
+	return 1
+}
+
+// printAskFooter ends the streamed answer and lists its sources
+func printAskFooter(answer string, sources []string) {
+	if !strings.HasSuffix(answer, "\n") {
+		fmt.Println()
+	}
+	if len(sources) > 0 {
+		fmt.Println()
+		fmt.Println(bot.FormatAnswer("", sources))
+	}
+}
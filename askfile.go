@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/history"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/terminal"
+	"web-ollama/internal/ui"
+)
+
+// documentTopK is the least number of chunks quoted per question about a
+// document, since every chunk is on topic
+const documentTopK = 8
+
+// askDocument implements `web-ollama ask --file <document> [question]`. The
+// document is chunked and embedded into an in-memory knowledge base, and
+// each question is answered from its most relevant chunks, without web
+// search. Without a question it reads questions from the terminal until
+// /exit, answering follow-ups with the conversation so far. Returns the
+// process exit code.
+func askDocument(ctx context.Context, cfg *config.Config, path, question, format string, display *ui.EnhancedDisplay) int {
+	title, text, err := knowledge.ExtractFile(path)
+	if err != nil {
+		display.PrintError(fmt.Errorf("failed to read %s: %w", path, err))
+		return 1
+	}
+	if strings.TrimSpace(text) == "" {
+		display.PrintError(fmt.Errorf("no text found in %s", path))
+		return 1
+	}
+
+	ollamaClient := newOllamaClient(cfg)
+	if err := ollamaClient.HealthCheck(); err != nil {
+		display.PrintError(err)
+		display.PrintHint(err)
+		return 1
+	}
+	checkContextWindow(ollamaClient, cfg, func(msg string) { slog.Warn(msg) })
+	fmt.Fprintf(os.Stderr, "Indexing %s (%d words)...\n", filepath.Base(path), len(strings.Fields(text)))
+	doc, err := newKnowledgeStore(cfg, ollamaClient, "")
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	source, err := filepath.Abs(path)
+	if err != nil {
+		source = path
+	}
+	if _, err := doc.Add(ctx, source, title, text); err != nil {
+		display.PrintError(err)
+		display.PrintHint(err)
+		return 1
+	}
+
+	// Every chunk is about the document, so quote the best ones whatever
+	// their score
+	docCfg := *cfg
+	docCfg.KnowledgeTopK = max(cfg.KnowledgeTopK, documentTopK)
+	docCfg.KnowledgeMinScore = 0
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+	p := &pipeline.Pipeline{
+		Config:    &docCfg,
+		Client:    ollamaClient,
+		Sinks:     answerSinks,
+		Post:      openPostprocessor(cfg, display),
+		Knowledge: doc,
+		Mode:      "ask",
+		Cache:     openAnswerCache(cfg, display),
+		// Answers about another document, or this one once edited, differ
+		Sources: []string{fmt.Sprintf("file:%s sha256:%x", source, sha256.Sum256([]byte(text)))},
+	}
+
+	tee, err := openTee(cfg.TeePath, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	defer tee.Close()
+
+	if question != "" {
+		return askDocumentOnce(ctx, p, question, format, tee, display)
+	}
+	if !terminal.IsTerminal() {
+		display.PrintError(fmt.Errorf("usage: web-ollama ask --file <document> \"question\""))
+		return 2
+	}
+
+	display.PrintInfo(fmt.Sprintf("Ask about %s; /exit to quit", title))
+	var recent []history.Message
+	for ctx.Err() == nil {
+		display.PrintPrompt()
+		input, err := terminal.ReadUserInput()
+		if err != nil || input == "/exit" || input == "/quit" {
+			break
+		}
+		if input = strings.TrimSpace(input); input == "" {
+			continue
+		}
+		answer, err := askDocumentTurn(ctx, p, input, recent, tee)
+		if err != nil {
+			display.PrintError(err)
+			continue
+		}
+		now := time.Now()
+		recent = append(recent,
+			history.Message{Role: "user", Content: input, Timestamp: now},
+			history.Message{Role: "assistant", Content: answer.Answer, Timestamp: now})
+		if n := contextbuilder.HistoryMessages(&docCfg); len(recent) > n {
+			recent = recent[len(recent)-n:]
+		}
+	}
+	return 0
+}
+
+// askDocumentOnce answers one question about the document and prints it in
+// format
+func askDocumentOnce(ctx context.Context, p *pipeline.Pipeline, question, format string, tee *answerTee, display *ui.EnhancedDisplay) int {
+	stream := format == formatPlain
+	tee.Start(question)
+	answer, err := p.Ask(ctx, question, nil, func(chunk string) {
+		if stream {
+			fmt.Print(chunk)
+		}
+		tee.Write(chunk)
+	})
+	tee.End(err != nil)
+	if err != nil {
+		display.PrintError(err)
+		display.PrintHint(err)
+		return askFailed(question, format, err)
+	}
+	if !stream {
+		record := BatchRecord{Question: question, Sources: []string{}}
+		fillRecord(&record, answer)
+		return askPrint(record, format)
+	}
+	printAskFooter(answer.Answer, answer.Sources)
+	return 0
+}
+
+// askDocumentTurn streams the answer to one interactive question
+func askDocumentTurn(ctx context.Context, p *pipeline.Pipeline, question string, recent []history.Message, tee *answerTee) (*pipeline.Answer, error) {
+	tee.Start(question)
+	answer, err := p.Ask(ctx, question, recent, func(chunk string) {
+		fmt.Print(chunk)
+		tee.Write(chunk)
+	})
+	tee.End(err != nil)
+	if err != nil {
+		return nil, err
+	}
+	printAskFooter(answer.Answer, answer.Sources)
+	return answer, nil
+}
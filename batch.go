@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// BatchRecord is one line of `web-ollama batch` output, and the JSON
+// written by `web-ollama ask --format json`
+type BatchRecord struct {
+	Index           int      `json:"index"`
+	Question        string   `json:"question"`
+	Answer          string   `json:"answer"`
+	Thinking        string   `json:"thinking,omitempty"`
+	Sources         []string `json:"sources"`
+	SearchPerformed bool     `json:"search_performed"`
+	SearchQueries   []string `json:"search_queries,omitempty"`
+	Cached          bool     `json:"cached,omitempty"`
+	Timings         struct {
+		GroundMs   int64 `json:"ground_ms"`
+		GenerateMs int64 `json:"generate_ms"`
+		TotalMs    int64 `json:"total_ms"`
+	} `json:"timings"`
+	Trace *grounding.Trace `json:"trace,omitempty"` // analyzer decision, quoted sources and token counts
+	Error string           `json:"error,omitempty"`
+}
+
+// runBatch implements `web-ollama batch questions.txt --out answers.jsonl`.
+// Returns the process exit code.
+func runBatch(args []string) int {
+	outPath := flag.String("out", "", "Write results to this file (default: stdout)")
+	format := flag.String("format", formatJSON, "Output format: json (JSON Lines), markdown or plain")
+	concurrency := flag.Int("concurrency", 1, "Number of questions answered in parallel")
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	if flag.NArg() != 1 {
+		display.PrintError(fmt.Errorf("usage: web-ollama batch [flags] <questions.txt> [--out answers.jsonl]"))
+		return 2
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+	if err := checkFormat(*format); err != nil {
+		display.PrintError(err)
+		return 2
+	}
+
+	questions, err := readQuestions(flag.Arg(0))
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	if len(questions) == 0 {
+		display.PrintInfo("No questions found")
+		return 0
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			display.PrintError(err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+	p := &pipeline.Pipeline{
+		Config:       cfg,
+		Client:       ollamaClient,
+		Grounder:     grounder,
+		Cache:        openAnswerCache(cfg, display),
+		Sinks:        answerSinks,
+		Tools:        headlessTools(cfg, ollamaClient, grounder, display),
+		Post:         openPostprocessor(cfg, display),
+		Summary:      summarize.New(ollamaClient, cfg),
+		Perspectives: newPerspectives(cfg, ollamaClient),
+		Mode:         "batch",
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Answer in parallel, but write in input order
+	records := make([]chan BatchRecord, len(questions))
+	for i := range records {
+		records[i] = make(chan BatchRecord, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				records[i] <- answerBatchQuestion(ctx, p, i, questions[i])
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range questions {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	failed := 0
+	for i := range questions {
+		var record BatchRecord
+		select {
+		case record = <-records[i]:
+		case <-ctx.Done():
+			display.PrintWarning(fmt.Sprintf("Interrupted after %d of %d questions", i, len(questions)))
+			return 1
+		}
+
+		if err := writeRecord(out, *format, record); err != nil {
+			display.PrintError(err)
+			return 1
+		}
+		if record.Error != "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "[%d/%d] ✗ %s: %s\n", i+1, len(questions), truncateQuestion(record.Question), record.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "[%d/%d] ✓ %s (%dms, %d sources)\n", i+1, len(questions), truncateQuestion(record.Question), record.Timings.TotalMs, len(record.Sources))
+		}
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// answerBatchQuestion runs one question through the pipeline
+func answerBatchQuestion(ctx context.Context, p *pipeline.Pipeline, index int, question string) BatchRecord {
+	record := BatchRecord{Index: index, Question: question, Sources: []string{}}
+
+	answer, err := p.Ask(ctx, question, nil, nil)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+
+	fillRecord(&record, answer)
+	return record
+}
+
+// fillRecord copies a pipeline answer into record
+func fillRecord(record *BatchRecord, answer *pipeline.Answer) {
+	record.Answer = answer.Answer
+	record.Thinking = answer.Thinking
+	if answer.Sources != nil {
+		record.Sources = answer.Sources
+	}
+	record.SearchPerformed = answer.SearchPerformed
+	record.SearchQueries = answer.SearchQueries
+	record.Cached = answer.Cached
+	record.Timings.GroundMs = answer.Timings.Ground.Milliseconds()
+	record.Timings.GenerateMs = answer.Timings.Generate.Milliseconds()
+	record.Timings.TotalMs = answer.Timings.Total.Milliseconds()
+	record.Trace = answer.Trace
+}
+
+// readQuestions reads one question per line, skipping blanks and # comments
+func readQuestions(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var questions []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		questions = append(questions, line)
+	}
+	return questions, scanner.Err()
+}
+
+// truncateQuestion shortens a question for progress output
+func truncateQuestion(q string) string {
+	if len(q) <= 60 {
+		return q
+	}
+	return q[:57] + "..."
+}
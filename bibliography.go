@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"web-ollama/internal/history"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/ui"
+)
+
+// sessionBibliography collects the sources cited by a session's answers.
+// Answers whose pages were saved contribute the sources they cite; older
+// answers without saved pages contribute their whole source list.
+func sessionBibliography(session *history.Session) *postprocess.Bibliography {
+	bib := &postprocess.Bibliography{}
+	for _, msg := range session.Messages {
+		meta := msg.Metadata
+		if msg.Role != "assistant" || meta == nil {
+			continue
+		}
+		if len(meta.Pages) == 0 {
+			refs := make([]postprocess.Reference, len(meta.SourceURLs))
+			for i, u := range meta.SourceURLs {
+				refs[i] = postprocess.Reference{Number: i + 1, URL: u}
+			}
+			bib.Add(refs, msg.Timestamp)
+			continue
+		}
+		refs := make([]postprocess.Reference, len(meta.Pages))
+		for i, p := range meta.Pages {
+			refs[i] = postprocess.Reference{Number: p.Number, URL: p.URL, Title: p.Title}
+		}
+		bib.Add(postprocess.Cited(msg.Content, refs), msg.Timestamp)
+	}
+	return bib
+}
+
+// showBibliography implements /bibliography: every source cited in the
+// current session, once each, with when it was first fetched. /export
+// appends the same list.
+func showBibliography(historyMgr *history.Manager, display *ui.EnhancedDisplay) {
+	session := historyMgr.GetCurrentSession()
+	if session == nil {
+		display.PrintInfo("No sources cited yet this session")
+		return
+	}
+	entries := sessionBibliography(session).Entries()
+	if len(entries) == 0 {
+		display.PrintInfo("No sources cited yet this session")
+		return
+	}
+
+	display.PrintSeparator()
+	fmt.Printf("Sources cited this session (%d)\n", len(entries))
+	display.PrintSeparator()
+	for i, e := range entries {
+		title := e.Title
+		if title == "" {
+			title = e.URL
+		}
+		fmt.Printf("[%d] %s\n    %s\n    first accessed %s", i+1, title, e.URL, e.Accessed.Format("2 Jan 2006 15:04"))
+		if e.Answers > 1 {
+			fmt.Printf(", cited in %d answers", e.Answers)
+		}
+		fmt.Println()
+	}
+	display.PrintInfo("/export writes this list at the end of the session")
+}
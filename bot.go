@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"web-ollama/internal/bot"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/history"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// runBot implements `web-ollama bot`: a Telegram bot that answers each chat
+// with web-grounded responses, keeping one history session per chat.
+// Returns the process exit code.
+func runBot(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	secretStore := loadSecrets(cfg)
+	token, ok := secretStore.Get("telegram")
+	if !ok {
+		display.PrintError(fmt.Errorf("no Telegram bot token found"))
+		display.PrintInfo("Set TELEGRAM_BOT_TOKEN or add a \"telegram\" entry under secrets in the config file")
+		return 1
+	}
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	historyMgr := history.NewManager(cfg.HistoryPath, cfg.MaxHistorySize)
+	historyMgr.SetRedactor(secretStore.Redact)
+	historyMgr.SetShared(cfg.SharedHistory)
+	if err := loadHistory(historyMgr, display); err != nil {
+		return 1
+	}
+	defer historyMgr.Close()
+
+	allowed := make(map[string]bool)
+	for _, id := range cfg.AllowedChatIDs {
+		allowed[strconv.FormatInt(id, 10)] = true
+	}
+	if len(allowed) == 0 {
+		display.PrintWarning("No allowed_chat_ids are configured, so the bot answers no one")
+		display.PrintInfo("Message the bot, then add the chat ID it logs to allowed_chat_ids in the config file")
+	}
+
+	// Enough workers for every question answered or waiting in the queue
+	workers := 0
+	if cfg.ChatConcurrency > 0 && cfg.ChatQueueDepth > 0 {
+		workers = cfg.ChatConcurrency + cfg.ChatQueueDepth
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+
+	b := &bot.Bot{
+		Platform: bot.NewTelegram(token),
+		Pipeline: &pipeline.Pipeline{
+			Config:       cfg,
+			Client:       ollamaClient,
+			Grounder:     grounder,
+			Cache:        openAnswerCache(cfg, display),
+			Sinks:        answerSinks,
+			Tools:        headlessTools(cfg, ollamaClient, grounder, display),
+			Post:         openPostprocessor(cfg, display),
+			Summary:      summarize.New(ollamaClient, cfg),
+			Perspectives: newPerspectives(cfg, ollamaClient),
+			Mode:         "bot",
+		},
+		History:    historyMgr,
+		MaxHistory: contextbuilder.HistoryMessages(cfg),
+		Allowed:    allowed,
+		Workers:    workers,
+		Queue:      scheduler.NewQueue(cfg.ChatConcurrency, cfg.ChatQueueDepth),
+		Log:        display.PrintInfo,
+	}
+
+	display.PrintSuccess(fmt.Sprintf("Telegram bot running (model: %s). Press Ctrl+C to stop.", cfg.ModelName))
+	if err := b.Run(ctx); err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	display.PrintInfo("Bot stopped")
+	return 0
+}
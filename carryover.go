@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// Limits on the files carried over to follow-up questions
+const (
+	carryTurns = 4 // a file not included for this many turns is forgotten
+	carryMax   = 5 // most files remembered at once
+)
+
+// fileMention matches phrases that point back at a file from an earlier
+// turn, such as "this file", "the script" or "that function"
+var fileMention = regexp.MustCompile(`(?i)\b(?:the|this|that|these|those|same|your|my)\s+(?:file|code|script|document|doc|function|method|class|module|config|snippet|program|test|csv|log|source|readme)s?\b|\b(?:in|from|of)\s+(?:it|there)\b|\bline\s+\d+\b`)
+
+// carriedFiles remembers the text files quoted in recent turns, so a
+// follow-up that clearly refers to them gets them again without another
+// @mention
+type carriedFiles struct {
+	turn  int
+	files []carriedFile // oldest first
+}
+
+// carriedFile is a file the model saw in an earlier turn
+type carriedFile struct {
+	path string // as the user wrote it after @
+	turn int    // the last turn it was included in
+}
+
+// record starts a turn and remembers the text files included in it
+func (c *carriedFiles) record(refs []FileReference) {
+	c.turn++
+	for _, ref := range refs {
+		if ref.Error != nil || ref.Image != "" {
+			continue
+		}
+		for i, f := range c.files {
+			if f.path == ref.Path {
+				c.files = append(c.files[:i], c.files[i+1:]...)
+				break
+			}
+		}
+		c.files = append(c.files, carriedFile{path: ref.Path, turn: c.turn})
+	}
+	kept := c.files[:0]
+	for _, f := range c.files {
+		if c.turn-f.turn < carryTurns {
+			kept = append(kept, f)
+		}
+	}
+	c.files = kept
+	if len(c.files) > carryMax {
+		c.files = c.files[len(c.files)-carryMax:]
+	}
+}
+
+// forget drops every remembered file, when the conversation changes
+func (c *carriedFiles) forget() {
+	c.files = nil
+}
+
+// referredTo returns the remembered files query refers to: those it names,
+// by file name or by name without the extension, or, when it names none
+// but speaks of "the file", "this function" and the like, the files of the
+// most recent turn that had any
+func (c *carriedFiles) referredTo(query string) []string {
+	if len(c.files) == 0 {
+		return nil
+	}
+	lower := strings.ToLower(query)
+	var named []string
+	for _, f := range c.files {
+		base := strings.ToLower(filepath.Base(f.path))
+		stem := strings.TrimSuffix(base, filepath.Ext(base))
+		if containsWord(lower, base) || len(stem) >= 4 && containsWord(lower, stem) {
+			named = append(named, f.path)
+		}
+	}
+	if len(named) > 0 || !fileMention.MatchString(query) {
+		return named
+	}
+
+	latest := c.files[len(c.files)-1].turn
+	var recent []string
+	for _, f := range c.files {
+		if f.turn == latest {
+			recent = append(recent, f.path)
+		}
+	}
+	return recent
+}
+
+// containsWord reports whether word appears in text on its own rather than
+// inside a longer word
+func containsWord(text, word string) bool {
+	for from := 0; ; {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// carryFiles reads the remembered files a follow-up refers to. Files that
+// would take more than half the prompt are summarized with the question in
+// mind, as oversized sources are.
+func carryFiles(ctx context.Context, cfg *config.Config, client summarize.Client, paths []string, query, workingDir string, display *ui.EnhancedDisplay) []FileReference {
+	refs := readFileReferences(paths, workingDir)
+	var loaded []FileReference
+	for _, ref := range refs {
+		if ref.Error == nil {
+			loaded = append(loaded, ref)
+		}
+	}
+	if len(loaded) == 0 {
+		return nil
+	}
+
+	results := make([]crawler.CrawlResult, len(loaded))
+	for i, ref := range loaded {
+		results[i] = crawler.CrawlResult{URL: ref.Path, Title: ref.Path, Content: ref.Content}
+	}
+	summarizer := summarize.OnDemand(client, cfg)
+	if summarizer.Needed(results) {
+		display.PrintInfo("Summarizing the earlier files for this question...")
+		for i, r := range summarizer.Summarize(ctx, query, results) {
+			if r.Content != loaded[i].Content {
+				loaded[i].Content, loaded[i].Summary = r.Content, true
+			}
+		}
+	}
+
+	names := make([]string, len(loaded))
+	for i, ref := range loaded {
+		names[i] = "@" + ref.Path
+		if ref.Summary {
+			names[i] += " (summarized)"
+		}
+	}
+	display.PrintInfo(fmt.Sprintf("Including %s from earlier in the conversation", strings.Join(names, ", ")))
+	return loaded
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/ui"
+)
+
+// runCheck implements `web-ollama check`: validate the configuration and run
+// every health check without starting the REPL. Returns the process exit code.
+func runCheck(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+	failed := false
+
+	// Configuration
+	if err := cfg.Validate(); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, p := range verr.Problems {
+				msg := fmt.Sprintf("%s: %s", p.Field, p.Message)
+				if p.Suggestion != "" {
+					msg += " → " + p.Suggestion
+				}
+				display.PrintWarning(msg)
+			}
+		} else {
+			display.PrintError(err)
+		}
+		failed = true
+	} else {
+		display.PrintSuccess("Configuration is valid")
+	}
+
+	if _, err := prompt.RenderSystem(cfg.SystemPrompt, prompt.NewSystemVars(time.Now(), cfg.ModelName)); err != nil {
+		display.PrintWarning(fmt.Sprintf("system_prompt: %v", err))
+		failed = true
+	}
+
+	// Ollama and model
+	ollamaClient := newOllamaClient(cfg)
+	if err := ollamaClient.HealthCheck(); err != nil {
+		display.PrintError(err)
+		failed = true
+	} else {
+		display.PrintSuccess(fmt.Sprintf("Ollama is reachable at %s", cfg.OllamaURL))
+		if len(cfg.OllamaFallbackURLs) > 0 && !checkOllamaHosts(ollamaClient, display) {
+			failed = true
+		}
+		if err := checkModel(ollamaClient, cfg.ModelName, display); err != nil {
+			failed = true
+		} else {
+			display.PrintSuccess(fmt.Sprintf("Model %s is available", cfg.ModelName))
+			checkVRAM(ollamaClient, cfg, display)
+		}
+		if m := cfg.AnalyzerModel; m != "" && m != cfg.ModelName {
+			if models, err := ollamaClient.ListModels(); err == nil && !hasModel(models, m) {
+				display.PrintWarning(fmt.Sprintf("analyzer_model %s is not pulled; search decisions will use %s", m, cfg.ModelName))
+				display.PrintInfo(fmt.Sprintf("Download it with: ollama pull %s", m))
+			} else if err == nil {
+				display.PrintSuccess(fmt.Sprintf("Analyzer model %s is available", m))
+			}
+		}
+	}
+
+	// SearXNG is optional for chatting, but report it like any other dependency
+	searxngClient := searxng.NewClient(cfg.SearXNGURL, cfg.SearchTimeout)
+	if err := searxngClient.HealthCheck(); err != nil {
+		display.PrintWarning(fmt.Sprintf("SearXNG check failed: %v", err))
+		display.PrintHint(err)
+		display.PrintInfo("Web search will be unavailable until SearXNG is running.")
+		failed = true
+	} else {
+		display.PrintSuccess(fmt.Sprintf("SearXNG is reachable at %s", cfg.SearXNGURL))
+	}
+
+	if failed {
+		return 1
+	}
+	display.PrintSuccess("All checks passed")
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/terminal"
+	"web-ollama/internal/ui"
+	"web-ollama/internal/verify"
+)
+
+// resolveConflict checks a finished answer against the trusted sources in
+// the prompt. When one contradicts it, the user chooses between keeping the
+// answer and generating it again with an instruction to follow the source.
+// It returns the thinking and answer to keep.
+func resolveConflict(ctx context.Context, checker *verify.Checker, chat contextbuilder.Chat, req ollama.ChatRequest, callbacks ollama.StreamCallbacks, generating *turnStop, tee *answerTee, query, thinking, answer string, sources []contextbuilder.Source, trace *ui.Trace, display *ui.EnhancedDisplay) (string, string) {
+	trusted := checker.Trusted(sources)
+	if len(trusted) == 0 {
+		return thinking, answer
+	}
+	display.PrintSearchActivity(fmt.Sprintf("\nChecking the answer against %d trusted source(s)...", len(trusted)))
+	conflict, err := checker.Check(ctx, query, answer, sources)
+	if err != nil {
+		slog.Warn("conflict check failed", "error", err)
+		trace.Add("Conflict check failed: %v", err)
+		return thinking, answer
+	}
+	if conflict == nil {
+		trace.Add("Conflict check: consistent with %d trusted source(s)", len(trusted))
+		return thinking, answer
+	}
+
+	display.PrintWarning(fmt.Sprintf("The answer contradicts trusted source [%d] %s", conflict.Source.Number, conflict.Source.URL))
+	display.PrintInfo(fmt.Sprintf("Answer: %s", conflict.Claim))
+	display.PrintInfo(fmt.Sprintf("Source: %s", conflict.SourceSays))
+	if !askRegenerate(display) {
+		trace.Add("Conflict with source [%d]: kept the answer", conflict.Source.Number)
+		return thinking, answer
+	}
+
+	req.Messages = verify.Insist(req.Messages, *conflict)
+	regenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	generating.start(cancel)
+	display.StartAssistantResponse()
+	tee.Start(query)
+	newThinking, newAnswer, err := chat(regenCtx, req, callbacks)
+	generating.done()
+	tee.End(err != nil)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Could not answer again, keeping the first answer: %v", err))
+		display.SetAnswer(answer)
+		trace.Add("Conflict with source [%d]: regenerating failed", conflict.Source.Number)
+		return thinking, answer
+	}
+	trace.Add("Conflict with source [%d]: answered again following it", conflict.Source.Number)
+	return newThinking, newAnswer
+}
+
+// askRegenerate asks whether to answer again after a conflict
+func askRegenerate(display *ui.EnhancedDisplay) bool {
+	for {
+		display.PrintInfo("r: answer again following the source | Enter: keep this answer")
+		display.PrintPrompt()
+		input, err := terminal.ReadUserInput()
+		if err != nil {
+			return false
+		}
+		switch strings.ToLower(input) {
+		case "", "k", "keep":
+			return false
+		case "r", "regenerate":
+			return true
+		}
+		display.PrintWarning(fmt.Sprintf("Unknown choice %q", input))
+	}
+}
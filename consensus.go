@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/consensus"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// askConsensus handles /consensus <question>: every consensus model answers
+// the grounded question, then the main model streams a reconciled answer
+func askConsensus(ctx context.Context, cfg *config.Config, display *ui.EnhancedDisplay, ollamaClient *ollama.Client, messages []ollama.Message, options map[string]interface{}, callbacks ollama.StreamCallbacks) (string, string, error) {
+	c := &consensus.Consensus{
+		Client:  ollamaClient,
+		Models:  cfg.ConsensusModels,
+		Judge:   cfg.ModelName,
+		Options: options,
+		Progress: func(msg string) {
+			display.PrintSearchActivity(msg)
+		},
+	}
+
+	result, err := c.Ask(ctx, messages, callbacks)
+	if err != nil {
+		return "", "", err
+	}
+	return result.Thinking, result.Answer, nil
+}
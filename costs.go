@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/cost"
+	"web-ollama/internal/ollama"
+)
+
+// turnCosts estimates what each answer costs and keeps the session's totals
+// for /stats
+type turnCosts struct {
+	estimator *cost.Estimator
+	session   cost.Session
+	client    *ollama.Client
+	params    map[string]float64 // parameter counts by model, read when needed
+}
+
+func newTurnCosts(cfg *config.Config, client *ollama.Client) *turnCosts {
+	return &turnCosts{estimator: cost.New(cfg), client: client, params: make(map[string]float64)}
+}
+
+// add estimates an answer from model's generation statistics and adds it
+// to the session
+func (t *turnCosts) add(model string, stats ollama.Stats) cost.Estimate {
+	busy := stats.PromptDuration + stats.Duration
+	var params float64
+	if busy <= 0 && !cost.IsCloud(model) {
+		params = t.modelParams(model)
+	}
+	est := t.estimator.Turn(model, stats.PromptTokens, stats.AnswerTokens, busy, params)
+	t.session.Add(est)
+	return est
+}
+
+// modelParams reads the model's parameter count once, for estimating the
+// energy of answers Ollama reported no timings for
+func (t *turnCosts) modelParams(model string) float64 {
+	if params, ok := t.params[model]; ok {
+		return params
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := t.client.ShowModel(ctx, model)
+	var params float64
+	if err != nil {
+		slog.Debug("could not read the model's size", "model", model, "error", err)
+	} else {
+		params = cost.ParseParams(info.ParameterSize)
+	}
+	t.params[model] = params
+	return params
+}
+
+// printTotals writes the session's estimated cost by model for /stats
+func (t *turnCosts) printTotals() {
+	models := t.session.ByModel()
+	if len(models) == 0 {
+		return
+	}
+	fmt.Printf("\n  Estimated cost of answers (rough, from local_watts, electricity_price and model_prices):\n\n")
+	var total, wattHours float64
+	for _, m := range models {
+		fmt.Printf("  %-32s %s\n", m.Model, m)
+		total += m.Cost
+		wattHours += m.WattHours
+	}
+	if len(models) > 1 {
+		fmt.Printf("  %-32s ~%.2f Wh locally, ≈%s in all\n", "Total", wattHours, cost.FormatCost(total))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// crawlSite implements /crawl-site <domain> <topic>: it lists the site's
+// pages from its sitemap, or from the links on its home page when it has
+// none, crawls the site_crawl_pages whose URLs best match the topic and
+// indexes them into the knowledge base, so the following questions are
+// answered from that site. It returns the knowledge base, which is enabled
+// for the session if it was off.
+func crawlSite(ctx context.Context, args string, cfg *config.Config, kb *knowledge.Store, ollamaClient *ollama.Client, webCrawler *crawler.Crawler, fetcher grounding.Fetcher, contentGuard *guard.Filter, display *ui.EnhancedDisplay) *knowledge.Store {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		display.PrintInfo("Usage: /crawl-site <domain> <topic>")
+		return kb
+	}
+	site, topic := fields[0], strings.Join(fields[1:], " ")
+
+	display.PrintSearchActivity(fmt.Sprintf("Reading the sitemap of %s", site))
+	pages, err := webCrawler.Sitemap(ctx, site)
+	if len(pages) == 0 {
+		// No sitemap: follow the links on the home page instead
+		display.PrintSearchActivity(fmt.Sprintf("No sitemap; reading the links on the home page of %s", site))
+		if pages, err = webCrawler.SiteLinks(ctx, site); err != nil {
+			display.PrintError(err)
+			return kb
+		}
+	}
+	var urls []string
+	for _, p := range crawler.RankSitemap(pages, topic, cfg.SiteCrawlPages) {
+		if reason, blocked := contentGuard.Blocked(p.Loc); blocked {
+			display.PrintWarning(fmt.Sprintf("Skipping %s: %s", p.Loc, reason))
+			continue
+		}
+		urls = append(urls, p.Loc)
+	}
+	if len(urls) == 0 {
+		display.PrintInfo(fmt.Sprintf("None of the %d pages listed for %s match %q", len(pages), site, topic))
+		return kb
+	}
+
+	display.PrintSearchActivity(fmt.Sprintf("Crawling %d of %d pages", len(urls), len(pages)))
+	results := fetcher.CrawlURLs(ctx, urls)
+	warnInjections(display, contentGuard.Clean(results))
+
+	if kb = enableKnowledge(kb, cfg, ollamaClient, display); kb == nil {
+		return nil
+	}
+	indexed := indexCrawlResults(ctx, kb, results, cfg, display)
+	if indexed == 0 {
+		display.PrintWarning(fmt.Sprintf("None of the %d pages could be read", len(urls)))
+		return kb
+	}
+	display.PrintSuccess(fmt.Sprintf("Indexed %d page(s) from %s about %q; ask away", indexed, site, topic))
+	return kb
+}
+
+// enableKnowledge opens the knowledge base for the rest of the session if
+// it is not open yet. It returns nil if loading it fails.
+func enableKnowledge(kb *knowledge.Store, cfg *config.Config, ollamaClient *ollama.Client, display *ui.EnhancedDisplay) *knowledge.Store {
+	if kb != nil {
+		return kb
+	}
+	kb, err := newKnowledgeStore(cfg, ollamaClient, cfg.KnowledgePath)
+	if err == nil {
+		err = kb.Load()
+	}
+	if err != nil {
+		display.PrintError(err)
+		return nil
+	}
+	display.PrintInfo("Knowledge base enabled for this session")
+	return kb
+}
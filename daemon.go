@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/daemon"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// rewarmInterval is how often the daemon reloads the model. Each answer
+// resets Ollama's keep_alive to its default of a few minutes, so a single
+// preload is not enough to keep the model in memory while idle.
+const rewarmInterval = 4 * time.Minute
+
+// runDaemon implements `web-ollama daemon`: a background process that keeps
+// the model loaded and the caches and knowledge base open, answering
+// `web-ollama ask` over a UNIX socket. Returns the process exit code.
+func runDaemon(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+	p := &pipeline.Pipeline{
+		Config:       cfg,
+		Client:       ollamaClient,
+		Grounder:     grounder,
+		Cache:        openAnswerCache(cfg, display),
+		Sinks:        answerSinks,
+		Tools:        headlessTools(cfg, ollamaClient, grounder, display),
+		Post:         openPostprocessor(cfg, display),
+		Summary:      summarize.New(ollamaClient, cfg),
+		Perspectives: newPerspectives(cfg, ollamaClient),
+		Knowledge:    openKnowledge(cfg, ollamaClient, display),
+		Mode:         "daemon",
+	}
+
+	display.PrintInfo(fmt.Sprintf("Loading %s...", cfg.ModelName))
+	if err := ollamaClient.Preload(ctx, cfg.ModelName); err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to preload the model: %v", err))
+	}
+	go keepWarm(ctx, ollamaClient, cfg.ModelName)
+	defer ollamaClient.StopModel(cfg.ModelName)
+
+	srv := daemon.NewServer(p, scheduler.NewQueue(cfg.ChatConcurrency, cfg.ChatQueueDepth))
+	display.PrintSuccess(fmt.Sprintf("Daemon listening on %s (model: %s). Press Ctrl+C to stop.", cfg.DaemonSocket, cfg.ModelName))
+	if err := srv.ListenAndServe(ctx, cfg.DaemonSocket); err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	display.PrintInfo("Daemon stopped")
+	return 0
+}
+
+// keepWarm reloads the model every rewarmInterval until ctx is cancelled
+func keepWarm(ctx context.Context, client *ollama.Client, model string) {
+	ticker := time.NewTicker(rewarmInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			client.Preload(ctx, model)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// openKnowledge loads the knowledge base, or returns nil when it is disabled
+// or unreadable
+func openKnowledge(cfg *config.Config, client *ollama.Client, display *ui.EnhancedDisplay) *knowledge.Store {
+	if !cfg.KnowledgeBase {
+		return nil
+	}
+	kb, err := newKnowledgeStore(cfg, client, cfg.KnowledgePath)
+	if err == nil {
+		err = kb.Load()
+	}
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Knowledge base disabled: %v", err))
+		return nil
+	}
+	return kb
+}
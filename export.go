@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/history"
+	"web-ollama/internal/htmlexport"
+	"web-ollama/internal/pages"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/ui"
+)
+
+// exportSession implements /export [file|html]: it writes the current
+// session as Markdown, quoting under each answer the saved text of the
+// sources it used. "html" or a file ending in .html writes a self-contained
+// page instead, for sharing with people who don't run web-ollama.
+func exportSession(args []string, cfg *config.Config, historyMgr *history.Manager, store *pages.Store, display *ui.EnhancedDisplay) {
+	session := historyMgr.GetCurrentSession()
+	if session == nil || len(session.Messages) == 0 {
+		display.PrintInfo("Nothing to export yet. Ask a question first.")
+		return
+	}
+
+	path := fmt.Sprintf("web-ollama-%s.md", session.StartedAt.Format("2006-01-02-150405"))
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "html" {
+		path = fmt.Sprintf("web-ollama-%s.html", session.StartedAt.Format("2006-01-02-150405"))
+	}
+
+	var content string
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".html" || ext == ".htm" {
+		var sb strings.Builder
+		if err := htmlexport.Write(&sb, htmlDocument(session, cfg.ModelName, store)); err != nil {
+			display.PrintError(err)
+			return
+		}
+		content = sb.String()
+	} else {
+		content = markdownExport(session, store, cfg.CitationStyle)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		display.PrintError(fmt.Errorf("failed to write export: %w", err))
+		return
+	}
+	display.PrintSuccess(fmt.Sprintf("Exported %d messages to %s", len(session.Messages), path))
+}
+
+// markdownExport renders a session as Markdown, with citations in
+// citationStyle where the answer's sources were saved
+func markdownExport(session *history.Session, store *pages.Store, citationStyle string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# web-ollama session, %s\n", session.StartedAt.Format("2006-01-02 15:04"))
+	for i, msg := range session.Messages {
+		if msg.Role == "user" {
+			fmt.Fprintf(&sb, "\n## %s\n\n", strings.Join(strings.Fields(msg.Content), " "))
+			continue
+		}
+		if msg.Metadata != nil && msg.Metadata.StandaloneQuery != "" {
+			fmt.Fprintf(&sb, "_Read as: %s_\n\n", msg.Metadata.StandaloneQuery)
+		}
+		answer := strings.TrimSpace(msg.Content)
+		if meta := msg.Metadata; store != nil && meta != nil && len(meta.Pages) > 0 {
+			answer = postprocess.Citations{
+				Style:    citationStyle,
+				Sources:  postprocess.References(contextbuilder.Restore(store.LoadAll(session.ID, meta.Pages), meta.Pages)),
+				Accessed: msg.Timestamp,
+				Label:    fmt.Sprintf("%d-", i), // footnotes must be unique in the document
+			}.Apply(answer)
+		}
+		sb.WriteString(answer + "\n")
+		writeExportSources(&sb, session.ID, msg.Metadata, store)
+	}
+	if bib := sessionBibliography(session).Markdown(citationStyle); bib != "" {
+		sb.WriteString("\n## Bibliography\n\n" + bib)
+	}
+	return sb.String()
+}
+
+// htmlDocument pairs each question with its answer for the HTML export,
+// restoring the saved passages behind each answer
+func htmlDocument(session *history.Session, model string, store *pages.Store) htmlexport.Document {
+	doc := htmlexport.Document{
+		Title:        "web-ollama session",
+		Started:      session.StartedAt,
+		Model:        model,
+		Bibliography: sessionBibliography(session).Entries(),
+	}
+	for _, msg := range session.Messages {
+		if msg.Role == "user" {
+			doc.Turns = append(doc.Turns, htmlexport.Turn{Question: strings.TrimSpace(msg.Content)})
+			continue
+		}
+		if len(doc.Turns) == 0 || doc.Turns[len(doc.Turns)-1].Answer != "" {
+			// An answer without a question, e.g. from an interrupted session
+			doc.Turns = append(doc.Turns, htmlexport.Turn{})
+		}
+		turn := &doc.Turns[len(doc.Turns)-1]
+		turn.Answer = strings.TrimSpace(msg.Content)
+		turn.Thinking = strings.TrimSpace(msg.Thinking)
+		if meta := msg.Metadata; meta != nil {
+			turn.ReadAs = meta.StandaloneQuery
+			turn.URLs = meta.SourceURLs
+			if store != nil && len(meta.Pages) > 0 {
+				turn.Sources = contextbuilder.Restore(store.LoadAll(session.ID, meta.Pages), meta.Pages)
+			}
+		}
+	}
+	return doc
+}
+
+// writeExportSources lists an answer's sources, quoting the saved passages
+// that were in the prompt when copies are available
+func writeExportSources(sb *strings.Builder, sessionID string, meta *history.Metadata, store *pages.Store) {
+	if meta == nil || len(meta.SourceURLs) == 0 {
+		return
+	}
+	sb.WriteString("\n**Sources**\n\n")
+	if store == nil || len(meta.Pages) == 0 {
+		for _, url := range meta.SourceURLs {
+			fmt.Fprintf(sb, "- %s\n", url)
+		}
+		return
+	}
+
+	sources := contextbuilder.Restore(store.LoadAll(sessionID, meta.Pages), meta.Pages)
+	for _, s := range sources {
+		title := s.Title
+		if title == "" {
+			title = s.URL
+		}
+		fmt.Fprintf(sb, "%d. [%s](%s)\n", s.Number, title, s.URL)
+		for i, c := range s.Chunks {
+			if i > 0 {
+				sb.WriteString("   >\n")
+			}
+			for _, line := range strings.Split(c.Text, "\n") {
+				fmt.Fprintf(sb, "   > %s\n", line)
+			}
+		}
+	}
+}
+
+// savePages stores copies of the pages behind an answer, recording which
+// chunks of each were in the prompt. It returns nil when saving is disabled
+// or fails.
+func savePages(store *pages.Store, historyMgr *history.Manager, crawled []crawler.CrawlResult, report contextbuilder.Report, display *ui.EnhancedDisplay) []history.PageRef {
+	sessionID := currentSessionID(historyMgr)()
+	if store == nil || sessionID == "" || len(report.Sources) == 0 {
+		return nil
+	}
+	refs, err := store.Save(sessionID, crawled)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to save pages: %v", err))
+		return nil
+	}
+	for i := range refs {
+		if n := refs[i].Number; n <= len(report.Sources) {
+			refs[i].Chunks = report.Sources[n-1].Included()
+		}
+	}
+	return refs
+}
+
+// currentSessionID returns a function reporting the ID of the current
+// history session, or "" before one has started
+func currentSessionID(historyMgr *history.Manager) func() string {
+	return func() string {
+		if s := historyMgr.GetCurrentSession(); s != nil {
+			return s.ID
+		}
+		return ""
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+// Subcommands define their own flags before parseFlags adds the shared ones,
+// so a name used by both panics at startup
+func TestSubcommandFlagsParse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for name, run := range map[string]func([]string) int{"ask": runAsk, "batch": runBatch} {
+		t.Run(name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(name, flag.ContinueOnError)
+			// Without a question or questions file both stop at the usage
+			if code := run([]string{"--format", "json", "--answer-format", "json"}); code != 2 {
+				t.Errorf("exit code %d, want 2 for missing arguments", code)
+			}
+		})
+	}
+}
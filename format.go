@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"web-ollama/internal/bot"
+	"web-ollama/internal/daemon"
+)
+
+// Output formats of the ask and batch subcommands
+const (
+	formatJSON     = "json"     // one JSON object per answer
+	formatMarkdown = "markdown" // a section per question with a source list
+	formatPlain    = "plain"    // the answer text followed by its sources
+)
+
+// checkFormat rejects unknown --format values
+func checkFormat(format string) error {
+	switch format {
+	case formatJSON, formatMarkdown, formatPlain:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q: use json, markdown or plain", format)
+}
+
+// writeRecord writes one answer to w in format. JSON is a single line, so a
+// series of records is JSON Lines.
+func writeRecord(w io.Writer, format string, r BatchRecord) error {
+	var err error
+	switch format {
+	case formatJSON:
+		err = json.NewEncoder(w).Encode(r)
+	case formatMarkdown:
+		_, err = io.WriteString(w, markdownRecord(r))
+	default:
+		_, err = io.WriteString(w, plainRecord(r))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write answer: %w", err)
+	}
+	return nil
+}
+
+// markdownRecord renders an answer as a section headed by its question
+func markdownRecord(r BatchRecord) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n\n", r.Question)
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "_Error: %s_\n\n", r.Error)
+		return sb.String()
+	}
+	sb.WriteString(strings.TrimSpace(r.Answer) + "\n\n")
+	if len(r.Sources) > 0 {
+		sb.WriteString("**Sources**\n\n")
+		for i, url := range r.Sources {
+			fmt.Fprintf(&sb, "%d. <%s>\n", i+1, url)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// plainRecord renders an answer as its question, text and sources
+func plainRecord(r BatchRecord) string {
+	if r.Error != "" {
+		return fmt.Sprintf("Q: %s\nError: %s\n\n", r.Question, r.Error)
+	}
+	return fmt.Sprintf("Q: %s\n\n%s\n\n", r.Question, bot.FormatAnswer(r.Answer, r.Sources))
+}
+
+// resultRecord converts an answer from the daemon
+func resultRecord(question string, result *daemon.Result) BatchRecord {
+	record := BatchRecord{
+		Question:        question,
+		Answer:          result.Answer,
+		Thinking:        result.Thinking,
+		Sources:         result.Sources,
+		SearchPerformed: result.SearchPerformed,
+		SearchQueries:   result.SearchQueries,
+		Cached:          result.Cached,
+	}
+	if record.Sources == nil {
+		record.Sources = []string{}
+	}
+	record.Timings.GroundMs = result.GroundMs
+	record.Timings.GenerateMs = result.GenerateMs
+	record.Timings.TotalMs = result.TotalMs
+	return record
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/archive"
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/cache"
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/degrade"
+	"web-ollama/internal/embed"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/hostile"
+	"web-ollama/internal/httpcache"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/perspectives"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/sandbox"
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/sinks"
+	"web-ollama/internal/tools"
+	"web-ollama/internal/translate"
+	"web-ollama/internal/trust"
+	"web-ollama/internal/ui"
+	"web-ollama/internal/verify"
+)
+
+// setupHeadless validates the config and connects the clients used by the
+// non-interactive subcommands. The returned grounder is nil when web search
+// is disabled or SearXNG is unreachable.
+func setupHeadless(cfg *config.Config, display *ui.EnhancedDisplay) (*ollama.Client, *grounding.Grounder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	sched := newScheduler(cfg)
+	ollamaClient := newOllamaClient(cfg)
+	ollamaClient.SetTransport(sched.Transport(scheduler.Ollama, ollamaClient.Transport()))
+	if err := ollamaClient.HealthCheck(); err != nil {
+		display.PrintInfo("Make sure Ollama is running: ollama serve")
+		return nil, nil, err
+	}
+	checkContextWindow(ollamaClient, cfg, func(msg string) { slog.Warn(msg) })
+
+	if !cfg.AutoSearch {
+		display.PrintInfo("Web search disabled")
+		return ollamaClient, nil, nil
+	}
+
+	searxngClient := searxng.NewClient(cfg.SearXNGURL, cfg.SearchTimeout)
+	httpCache := newHTTPCache(cfg)
+	searxngClient.SetTransport(httpCache.Transport(sched.Transport(scheduler.Search, nil)))
+	if cfg.Offline {
+		display.PrintInfo("Offline: answering from cached results, archived and saved pages")
+	} else if err := searxngClient.HealthCheck(); err != nil {
+		display.PrintWarning(fmt.Sprintf("SearXNG check failed: %v", err))
+		display.PrintHint(err)
+		display.PrintInfo("Web search will be disabled.")
+		return ollamaClient, nil, nil
+	}
+
+	webCrawler := newCrawler(cfg)
+	webCrawler.SetTransport(httpCache.Transport(bandwidth.NewMeter().Transport(sched.Transport(scheduler.Crawl, nil))))
+	if cfg.Offline {
+		goOffline(searxngClient, webCrawler)
+	}
+
+	tracker := degrade.NewTracker()
+	tracker.OnChange = func(capability string, state degrade.State) {
+		slog.Warn("capability changed", "capability", capability, "mode", state.Mode.String(), "detail", state.Detail)
+	}
+
+	contentGuard := openGuard(cfg, display)
+	webCrawler.SetPolicy(contentGuard.Check)
+	hostileDomains := openHostile(cfg, display)
+	grounder := newGrounder(cfg,
+		newAnalyzer(cfg, ollamaClient, nil, func(msg string) { slog.Warn(msg) }),
+		fallbackSearcher(cfg, searxngClient, tracker, display),
+		translateFetcher(cfg, ollamaClient, archiveFetcher(cfg, hostileFetcher(hostileDomains, webCrawler), display)),
+		contentGuard, hostileDomains)
+	return ollamaClient, grounder, nil
+}
+
+// newGrounder assembles a Grounder from its parts and the search, trust and
+// budget settings in cfg, for the REPL and the headless modes alike
+func newGrounder(cfg *config.Config, a grounding.Analyzer, searcher grounding.Searcher, fetcher grounding.Fetcher, contentGuard *guard.Filter, hostileDomains *hostile.Tracker) *grounding.Grounder {
+	return &grounding.Grounder{
+		Analyzer:       a,
+		Searcher:       searcher,
+		Fetcher:        fetcher,
+		MaxResults:     cfg.MaxResults,
+		BlockedDomains: cfg.BlockedDomains,
+		Trust:          trust.New(cfg.SourceTrust),
+		Guard:          contentGuard,
+		Hostile:        hostileDomains,
+		Budget:         cfg.TurnBudget,
+		DownloadLimit:  cfg.TurnDownloadLimit,
+	}
+}
+
+// newAnalyzer creates the search decision analyzer. It uses analyzer_model
+// when that is set and among the available models, or when they are not
+// known, falling back to the chat model if it fails.
+func newAnalyzer(cfg *config.Config, client *ollama.Client, available []string, warn func(string)) *analyzer.LLMAnalyzer {
+	model := cfg.AnalyzerModel
+	if model == "" || model == cfg.ModelName {
+		return analyzer.NewLLMAnalyzer(client, cfg.ModelName)
+	}
+	if available != nil && !hasModel(available, model) {
+		warn(fmt.Sprintf("analyzer_model %s is not pulled (ollama pull %s); search decisions use %s", model, model, cfg.ModelName))
+		return analyzer.NewLLMAnalyzer(client, cfg.ModelName)
+	}
+	a := analyzer.NewLLMAnalyzer(client, model)
+	a.SetFallback(cfg.ModelName)
+	return a
+}
+
+// hasModel reports whether name is among the listed models, where a name
+// without a tag means its latest tag
+func hasModel(models []string, name string) bool {
+	for _, m := range models {
+		if m == name || m == name+":latest" {
+			return true
+		}
+	}
+	return false
+}
+
+// newOllamaClient creates the Ollama client for cfg
+func newOllamaClient(cfg *config.Config) *ollama.Client {
+	c := ollama.NewClient(cfg.OllamaURL, cfg.OllamaTimeout)
+	c.SetMaxLineBytes(cfg.OllamaMaxLineBytes)
+	c.SetKeepAlive(cfg.KeepAlive)
+	c.SetRetry(cfg.OllamaMaxAttempts, cfg.OllamaRetryDelay)
+	auth := cfg.OllamaAuth
+	if auth == "" {
+		auth, _ = loadSecrets(cfg).Get("ollama")
+	}
+	c.SetAuth(auth)
+	c.SetHeaders(expandHeaders(cfg.OllamaHeaders))
+	for server, headers := range cfg.OllamaHostHeaders {
+		c.SetHostHeaders(server, expandHeaders(headers))
+	}
+	if err := c.SetHosts(append([]string{cfg.OllamaURL}, cfg.OllamaFallbackURLs...), cfg.OllamaBalance == "round_robin"); err != nil {
+		slog.Warn("using ollama_url alone", "error", err)
+	}
+	return c
+}
+
+// expandHeaders reads the $VARS in header values from the environment
+func expandHeaders(headers map[string]string) map[string]string {
+	expanded := make(map[string]string, len(headers))
+	for name, value := range headers {
+		expanded[name] = os.Expand(value, config.GetEnv)
+	}
+	return expanded
+}
+
+// newEmbedder creates the embedder named by embed_provider
+func newEmbedder(cfg *config.Config, client *ollama.Client) (embed.Embedder, error) {
+	var apiKey string
+	if cfg.EmbedProvider == embed.ProviderOpenAI {
+		apiKey, _ = loadSecrets(cfg).Get("openai")
+	}
+	embedder, err := embed.New(cfg.EmbedProvider, cfg.EmbedURL, apiKey, client, cfg.OllamaTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up embeddings: %w", err)
+	}
+	return embedder, nil
+}
+
+// newPerspectives creates the grouper of contested questions' sources, or
+// returns nil when perspectives are off or embeddings are unavailable
+func newPerspectives(cfg *config.Config, client *ollama.Client) *perspectives.Grouper {
+	if !cfg.Perspectives {
+		return nil
+	}
+	embedder, err := newEmbedder(cfg, client)
+	if err != nil {
+		slog.Warn("perspectives disabled", "error", err)
+		return nil
+	}
+	return perspectives.New(embedder, cfg)
+}
+
+// newConflictChecker returns the checker of answers against trusted
+// sources, or nil when check_conflicts is off
+func newConflictChecker(cfg *config.Config, client *ollama.Client) *verify.Checker {
+	if !cfg.CheckConflicts {
+		return nil
+	}
+	return verify.New(client, cfg)
+}
+
+// newKnowledgeStore creates a knowledge store saved at path, or kept in
+// memory when path is "", that embeds through embed_provider
+func newKnowledgeStore(cfg *config.Config, client *ollama.Client, path string) (*knowledge.Store, error) {
+	embedder, err := newEmbedder(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return knowledge.NewMemoryStore(embedder, cfg.EmbedModel), nil
+	}
+	return knowledge.NewStore(path, embedder, cfg.EmbedModel), nil
+}
+
+// newCrawler creates the page crawler, auto-tuning its worker count unless
+// crawl_auto_tune is off and sending browser headers if browser_headers is on
+func newCrawler(cfg *config.Config) *crawler.Crawler {
+	c := crawler.NewCrawler(cfg.CrawlTimeout, cfg.MaxCrawlers, cfg.MaxContentSize, cfg.UserAgent)
+	if cfg.BrowserHeaders {
+		c.SetBrowserHeaders(cfg.BrowserAgents, cfg.AcceptLanguage)
+	}
+	if cfg.CrawlAutoTune {
+		c.SetTuner(crawler.NewTuner(cfg.MaxCrawlers, 1, cfg.CrawlWorkersMax))
+	}
+	for domain, rule := range cfg.ExtractionRules {
+		extract, err := crawler.RuleExtractor(crawler.Rule{Keep: rule.Keep, Drop: rule.Drop})
+		if err != nil {
+			slog.Warn("extraction rule ignored", "domain", domain, "error", err)
+			continue
+		}
+		c.Extractors().RegisterDomainHTML(domain, extract)
+	}
+	return c
+}
+
+// newHTTPCache creates the HTTP cache shared by the crawler and SearXNG
+// clients, or returns nil when it is disabled
+func newHTTPCache(cfg *config.Config) *httpcache.Cache {
+	if cfg.HTTPCacheDir == "" {
+		return nil
+	}
+	return httpcache.New(cfg.HTTPCacheDir, cfg.HTTPCacheMaxSize, cfg.HTTPCacheHeuristicTTL)
+}
+
+// newScheduler creates the request scheduler shared by every client of one process
+func newScheduler(cfg *config.Config) *scheduler.Scheduler {
+	return scheduler.New(scheduler.Limits{
+		Ollama:       cfg.OllamaConcurrency,
+		Search:       cfg.SearchConcurrency,
+		Crawl:        cfg.CrawlConcurrency,
+		CrawlPerHost: cfg.CrawlPerHost,
+		HostDelay:    cfg.CrawlHostDelay,
+	})
+}
+
+// fallbackSearcher wraps the SearXNG client so that searches are answered
+// from the snippet cache while it is down. Offline, searches are answered
+// from the snippet cache and the page archive only.
+func fallbackSearcher(cfg *config.Config, client *searxng.Client, tracker *degrade.Tracker, display *ui.EnhancedDisplay) *degrade.Searcher {
+	searcher := &degrade.Searcher{Primary: client, Tracker: tracker, RetryAfter: 30 * time.Second, Offline: cfg.Offline}
+	if cfg.Offline {
+		if a, err := archive.New(cfg.ArchiveDir, cfg.ArchiveFormat); err == nil {
+			searcher.Local = &archive.Searcher{Archive: a}
+		}
+	}
+	if cfg.SnippetCachePath == "" {
+		return searcher
+	}
+	snippets := degrade.NewSnippets(cfg.SnippetCachePath)
+	if err := snippets.Load(); err != nil {
+		display.PrintWarning(fmt.Sprintf("Snippet cache disabled: %v", err))
+		return searcher
+	}
+	searcher.Snippets = snippets
+	return searcher
+}
+
+// archiveFetcher wraps the crawler so that fetched pages are snapshotted
+// when archiving is on. Offline, pages are read from the archive instead.
+func archiveFetcher(cfg *config.Config, next archive.Backend, display *ui.EnhancedDisplay) grounding.Fetcher {
+	if !cfg.Archive && !cfg.Offline {
+		return next
+	}
+	a, err := archive.New(cfg.ArchiveDir, cfg.ArchiveFormat)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Page archive disabled: %v", err))
+		return next
+	}
+	if cfg.Offline {
+		return &archive.Local{Archive: a}
+	}
+	return &archive.Fetcher{Archive: a, Next: next}
+}
+
+// translateFetcher wraps next so that the most relevant pages in other
+// languages are translated, when source translation is on
+func translateFetcher(cfg *config.Config, client *ollama.Client, next grounding.Fetcher) grounding.Fetcher {
+	if !cfg.TranslateSources {
+		return next
+	}
+	model := cfg.TranslateModel
+	if model == "" {
+		model = cfg.ModelName
+	}
+	return &translate.Fetcher{Client: client, Model: model, Max: cfg.TranslateMax, Next: next}
+}
+
+// openHostile loads the record of domains that keep refusing the crawler,
+// or returns nil when it is disabled or unreadable
+func openHostile(cfg *config.Config, display *ui.EnhancedDisplay) *hostile.Tracker {
+	if cfg.HostileDomainsPath == "" {
+		return nil
+	}
+	tracker := hostile.New(cfg.HostileDomainsPath, cfg.HostileStrikes, cfg.HostileCooldown)
+	if err := tracker.Load(); err != nil {
+		display.PrintWarning(fmt.Sprintf("Hostile domain tracking disabled: %v", err))
+		return nil
+	}
+	return tracker
+}
+
+// hostileFetcher wraps the crawler so that domains in their cooldown are
+// skipped and every fetch is recorded, when tracking is on
+func hostileFetcher(tracker *hostile.Tracker, webCrawler *crawler.Crawler) archive.Backend {
+	if tracker == nil {
+		return webCrawler
+	}
+	return &hostile.Fetcher{Tracker: tracker, Next: webCrawler}
+}
+
+// openGuard builds the guardrail filter; on error it warns and falls back
+// to no block lists, keeping the allow-list
+func openGuard(cfg *config.Config, display *ui.EnhancedDisplay) *guard.Filter {
+	filter, err := guard.New(cfg.BlockCategories, cfg.BlockLists, cfg.StripInjections)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Block lists disabled: %v", err))
+		filter, _ = guard.New(nil, nil, cfg.StripInjections)
+	}
+	filter.SetAllowed(cfg.AllowedDomains)
+	return filter
+}
+
+// openPostprocessor builds the answer post-processing steps; on error it
+// warns and returns nil, leaving answers as generated
+func openPostprocessor(cfg *config.Config, display *ui.EnhancedDisplay) *postprocess.Processor {
+	p, err := postprocess.New(cfg)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Answer post-processing disabled: %v", err))
+		return nil
+	}
+	return p
+}
+
+// openAnswerCache loads the answer cache, or returns nil when it is disabled
+// or unreadable
+func openAnswerCache(cfg *config.Config, display *ui.EnhancedDisplay) *cache.Answers {
+	if cfg.AnswerCacheTTL <= 0 {
+		return nil
+	}
+	answers := cache.NewAnswers(cfg.AnswerCachePath, cfg.AnswerCacheTTL)
+	if err := answers.Load(); err != nil {
+		display.PrintWarning(fmt.Sprintf("Answer cache disabled: %v", err))
+		return nil
+	}
+	return answers
+}
+
+// openSinks builds the configured answer sinks, or returns nil when there are none
+func openSinks(cfg *config.Config, display *ui.EnhancedDisplay) *sinks.Dispatcher {
+	if len(cfg.Sinks) == 0 {
+		return nil
+	}
+	d, err := sinks.NewDispatcher(cfg.Sinks)
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Answer sinks disabled: %v", err))
+		return nil
+	}
+	return d
+}
+
+// chatTools returns the loop for the tools the model may call while
+// answering: run_code when code_execution is on, and web_search and
+// fetch_url when web_tools is on and search is available. It returns nil
+// when no tool is on.
+func chatTools(cfg *config.Config, client *ollama.Client, searcher tools.Searcher, fetcher tools.Fetcher, filter *guard.Filter, display *ui.EnhancedDisplay) *tools.Loop {
+	loop := &tools.Loop{Client: client}
+	if cfg.CodeExecution {
+		sb := sandbox.New(cfg.CodeTimeout)
+		if err := sb.Check(); err != nil {
+			display.PrintWarning(fmt.Sprintf("Code execution disabled: %v", err))
+		} else {
+			loop.Sandbox = sb
+		}
+	}
+	if cfg.WebTools && searcher != nil && fetcher != nil {
+		loop.Tools = append(loop.Tools, tools.WebSearch(searcher, cfg.MaxResults, filter), tools.FetchURL(fetcher, filter))
+	}
+	if loop.Sandbox == nil && len(loop.Tools) == 0 {
+		return nil
+	}
+	return loop
+}
+
+// headlessTools is chatTools for the modes set up by setupHeadless, searching
+// and fetching with the grounder when there is one
+func headlessTools(cfg *config.Config, client *ollama.Client, grounder *grounding.Grounder, display *ui.EnhancedDisplay) *tools.Loop {
+	if grounder == nil {
+		return chatTools(cfg, client, nil, nil, nil, display)
+	}
+	return chatTools(cfg, client, grounder.Searcher, grounder.Fetcher, grounder.Guard, display)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// checkOllamaHosts reports which of the configured Ollama servers answer.
+// Requests go to those that do; the others are tried again later. It
+// returns false when any is down.
+func checkOllamaHosts(client *ollama.Client, display *ui.EnhancedDisplay) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	statuses := client.CheckHosts(ctx)
+	up := 0
+	for _, s := range statuses {
+		if s.Err != nil {
+			display.PrintWarning(fmt.Sprintf("Ollama at %s is not answering: %v", s.URL, s.Err))
+			continue
+		}
+		up++
+	}
+	display.PrintInfo(fmt.Sprintf("%d of %d Ollama hosts are up", up, len(statuses)))
+	return up == len(statuses)
+}
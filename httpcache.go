@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/httpcache"
+	"web-ollama/internal/ui"
+)
+
+// handleCache implements /cache, which shows the HTTP cache's size, and
+// /cache clear, which empties it
+func handleCache(args string, httpCache *httpcache.Cache, display *ui.EnhancedDisplay) {
+	if httpCache == nil {
+		display.PrintInfo("The HTTP cache is off; set http_cache_dir to turn it on")
+		return
+	}
+	switch strings.TrimSpace(args) {
+	case "":
+		entries, size, err := httpCache.Stats()
+		if err != nil {
+			display.PrintError(err)
+			return
+		}
+		display.PrintInfo(fmt.Sprintf("HTTP cache: %d responses, %s of %s in %s",
+			entries, bandwidth.FormatBytes(size), bandwidth.FormatBytes(httpCache.MaxBytes()), httpCache.Dir()))
+	case "clear":
+		if err := httpCache.Clear(); err != nil {
+			display.PrintError(err)
+			return
+		}
+		display.PrintSuccess("HTTP cache cleared")
+	default:
+		display.PrintInfo("Usage: /cache | /cache clear")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// maxImageBytes is the largest image sent to the model
+const maxImageBytes = 20 << 20
+
+// imageExtensions are the files attached as images rather than quoted as text
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".bmp": true,
+}
+
+// isImageFile reports whether path names an image, by its extension
+func isImageFile(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// loadImage reads an image and encodes it for ollama.Message.Images
+func loadImage(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxImageBytes {
+		return "", fmt.Errorf("%s is larger than %s", bandwidth.FormatBytes(info.Size()), bandwidth.FormatBytes(maxImageBytes))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// imageQueue holds the images attached with /image for the next question
+type imageQueue struct {
+	images []string
+}
+
+// handle implements /image <path> and /image clear
+func (q *imageQueue) handle(args, workDir string, display *ui.EnhancedDisplay) {
+	path := strings.Trim(strings.TrimSpace(args), `"`)
+	switch {
+	case path == "":
+		display.PrintInfo("Usage: /image <path> | /image clear")
+	case path == "clear":
+		q.images = nil
+		display.PrintInfo("No images will be attached")
+	default:
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
+		}
+		image, err := loadImage(path)
+		if err != nil {
+			display.PrintError(fmt.Errorf("failed to attach image: %w", err))
+			return
+		}
+		q.images = append(q.images, image)
+		display.PrintSuccess(fmt.Sprintf("Attached %s to your next question (%d image(s))", filepath.Base(path), len(q.images)))
+	}
+}
+
+// Take returns the queued images and empties the queue
+func (q *imageQueue) Take() []string {
+	images := q.images
+	q.images = nil
+	return images
+}
+
+// warnIfBlind warns when the model doesn't list vision among its
+// capabilities, since it would answer without seeing the images
+func warnIfBlind(client *ollama.Client, model string, display *ui.EnhancedDisplay) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	caps, err := client.Capabilities(ctx, model)
+	if err != nil || caps == nil {
+		slog.Debug("could not read model capabilities", "model", model, "error", err)
+		return
+	}
+	for _, c := range caps {
+		if c == "vision" {
+			return
+		}
+	}
+	display.PrintWarning(fmt.Sprintf("%s cannot see images; switch to a vision model such as llava or moondream with --model", model))
+}
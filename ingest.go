@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ui"
+)
+
+// runIngest implements `web-ollama ingest <paths...>`. Returns the process exit code.
+func runIngest(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		display.PrintError(fmt.Errorf("usage: web-ollama ingest [flags] <file or directory>..."))
+		return 2
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		return 1
+	}
+
+	ollamaClient := newOllamaClient(cfg)
+	if err := ollamaClient.HealthCheck(); err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	kb, err := newKnowledgeStore(cfg, ollamaClient, cfg.KnowledgePath)
+	if err == nil {
+		err = kb.Load()
+	}
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	if failed := ingestPaths(context.Background(), kb, paths, display); failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ingestPaths extracts, chunks and embeds files into the knowledge base with
+// progress output, saving them together at the end. Files whose content is
+// already indexed are skipped. Returns the number of files that failed.
+func ingestPaths(ctx context.Context, kb *knowledge.Store, paths []string, display *ui.EnhancedDisplay) int {
+	files, err := knowledge.ExpandPaths(paths)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	if len(files) == 0 {
+		display.PrintInfo("No supported files found (PDF, Markdown, text)")
+		return 0
+	}
+
+	// Everything ingested is saved in one write at the end
+	added, skipped, failed, chunks := 0, 0, 0, 0
+	err = kb.Batch(func() error {
+		for i, file := range files {
+			progress := fmt.Sprintf("[%d/%d] %s", i+1, len(files), file)
+
+			title, text, err := knowledge.ExtractFile(file)
+			if err != nil {
+				display.PrintWarning(fmt.Sprintf("%s: %v", progress, err))
+				failed++
+				continue
+			}
+			if kb.HasContent(text) {
+				display.PrintInfo(fmt.Sprintf("%s: already indexed, skipping", progress))
+				skipped++
+				continue
+			}
+
+			source, err := filepath.Abs(file)
+			if err != nil {
+				source = file
+			}
+			n, err := kb.Add(ctx, source, title, text)
+			if err != nil {
+				display.PrintWarning(fmt.Sprintf("%s: %v", progress, err))
+				failed++
+				continue
+			}
+
+			display.PrintSuccess(fmt.Sprintf("%s: %d chunk(s)", progress, n))
+			added++
+			chunks += n
+		}
+		return nil
+	})
+	if err != nil {
+		display.PrintError(err)
+		return failed + added
+	}
+
+	docs, total := kb.Stats()
+	display.PrintInfo(fmt.Sprintf("Ingested %d file(s) (%d chunks), skipped %d duplicate(s), %d failed. Knowledge base: %d documents, %d chunks.",
+		added, chunks, skipped, failed, docs, total))
+	return failed
+}
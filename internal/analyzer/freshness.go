@@ -0,0 +1,18 @@
+package analyzer
+
+import "regexp"
+
+// timeSensitivePattern matches words that ask about the present moment.
+// Unlike the keyword analyzer's substring list it matches whole words, so
+// "know" does not count as "now".
+var timeSensitivePattern = regexp.MustCompile(`(?i)\b(` +
+	`latest|newest|current(ly)?|today|tonight|now|yesterday|breaking|live|ongoing|so far|` +
+	`this (morning|afternoon|evening|week|month|year|season)|right now|at the moment|` +
+	`price|prices|stock|weather|forecast|score|scores|standings|exchange rate` +
+	`)\b`)
+
+// TimeSensitive reports whether query asks about something that changes
+// quickly, so earlier copies of pages should not be trusted to answer it
+func TimeSensitive(query string) bool {
+	return timeSensitivePattern.MatchString(query)
+}
@@ -0,0 +1,20 @@
+package analyzer
+
+import "testing"
+
+func TestTimeSensitive(t *testing.T) {
+	tests := map[string]bool{
+		"what is the latest Go release":           true,
+		"Who is winning the match right now?":     true,
+		"bitcoin price":                           true,
+		"weather in Oslo this weekend":            true,
+		"what do you know about the Roman empire": false,
+		"explain how TCP slow start works":        false,
+		"livestock farming in Wales":              false,
+	}
+	for query, want := range tests {
+		if got := TimeSensitive(query); got != want {
+			t.Errorf("TimeSensitive(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
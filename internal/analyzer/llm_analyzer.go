@@ -4,13 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
+
+	"web-ollama/internal/errs"
 )
 
 // LLMAnalyzer uses the LLM to decide if search is needed
 type LLMAnalyzer struct {
 	ollamaClient OllamaClient
-	model        string
+
+	mu       sync.Mutex
+	model    string
+	fallback string // used when model fails, e.g. the chat model behind a small analyzer model
 }
 
 // OllamaClient interface for making LLM calls
@@ -18,6 +25,18 @@ type OllamaClient interface {
 	ChatSync(ctx context.Context, model string, messages interface{}) (string, error)
 }
 
+// StructuredClient is an OllamaClient that can constrain a reply to a JSON
+// schema
+type StructuredClient interface {
+	ChatSyncJSON(ctx context.Context, model string, messages interface{}, schema json.RawMessage) (string, error)
+}
+
+// StreamingClient is an OllamaClient that can also stream JSON responses
+// constrained to a schema
+type StreamingClient interface {
+	ChatStreamJSON(ctx context.Context, model string, messages interface{}, schema json.RawMessage, onChunk func(string)) (string, error)
+}
+
 // OllamaMessage represents a chat message (matches ollama package)
 type OllamaMessage struct {
 	Role     string `json:"role"`
@@ -27,9 +46,12 @@ type OllamaMessage struct {
 
 // SearchDecision represents the LLM's decision
 type SearchDecision struct {
-	NeedsSearch   bool     `json:"needs_search"`
-	SearchQueries []string `json:"search_queries,omitempty"` // Support multiple searches
-	Reason        string   `json:"reason"`
+	// StandaloneQuery is the user's question rewritten to make sense without
+	// the conversation; it is the question itself when there is no history
+	StandaloneQuery string   `json:"standalone_query,omitempty"`
+	NeedsSearch     bool     `json:"needs_search"`
+	SearchQueries   []string `json:"search_queries,omitempty"` // Support multiple searches
+	Reason          string   `json:"reason"`
 }
 
 // NewLLMAnalyzer creates a new LLM-based analyzer
@@ -40,15 +62,149 @@ func NewLLMAnalyzer(client OllamaClient, model string) *LLMAnalyzer {
 	}
 }
 
+// SetFallback sets the model that decisions are retried on when the
+// analyzer's own model fails. A model that is missing is replaced by the
+// fallback for good.
+func (a *LLMAnalyzer) SetFallback(model string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if model != a.model {
+		a.fallback = model
+	}
+}
+
+// Model returns the model decisions are made with
+func (a *LLMAnalyzer) Model() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.model
+}
+
+// withFallback runs call on the analyzer's model, then on the fallback model
+// if that fails and retry allows it
+func (a *LLMAnalyzer) withFallback(ctx context.Context, call func(model string) (string, error), retry func() bool) (string, error) {
+	a.mu.Lock()
+	model, fallback := a.model, a.fallback
+	a.mu.Unlock()
+
+	response, err := call(model)
+	if err == nil || fallback == "" || ctx.Err() != nil || !retry() {
+		return response, err
+	}
+	if errs.Is(err, errs.ModelMissing) {
+		a.mu.Lock()
+		a.model, a.fallback = fallback, ""
+		a.mu.Unlock()
+		slog.Warn("analyzer model is missing; search decisions use the chat model from now on", "model", model, "fallback", fallback)
+	} else {
+		slog.Warn("analyzer model failed; retrying the decision on the chat model", "model", model, "fallback", fallback, "error", err)
+	}
+	return call(fallback)
+}
+
 // AnalyzeWithLLM asks the LLM if search is needed and what to search for
 func (a *LLMAnalyzer) AnalyzeWithLLM(ctx context.Context, userQuery string) (SearchDecision, error) {
+	return a.analyze(ctx, userQuery, nil)
+}
+
+// analyze runs the search decision prompt without streaming, with the reply
+// held to the decision schema when the client can do that
+func (a *LLMAnalyzer) analyze(ctx context.Context, userQuery string, recent []OllamaMessage) (SearchDecision, error) {
+	response, err := a.withFallback(ctx, func(model string) (string, error) {
+		if structured, ok := a.ollamaClient.(StructuredClient); ok {
+			return structured.ChatSyncJSON(ctx, model, analysisMessages(userQuery, recent), decisionSchema(len(recent) > 0))
+		}
+		return a.ollamaClient.ChatSync(ctx, model, analysisMessages(userQuery, recent))
+	}, func() bool { return true })
+	if err != nil {
+		return SearchDecision{}, fmt.Errorf("LLM call failed: %w", err)
+	}
+	return parseDecision(userQuery, response)
+}
+
+// AnalyzeStreaming is AnalyzeWithLLM, but calls onQuery with each search query
+// as soon as the model has finished writing it, so searches can start while
+// the rest of the decision is still being generated. Clients that cannot
+// stream get the queries once the whole decision is in.
+//
+// recent is the conversation so far, oldest first. When it is non-empty the
+// model first rewrites a follow-up ("how much does it cost?") into a
+// standalone question, returned in StandaloneQuery, and searches for that.
+func (a *LLMAnalyzer) AnalyzeStreaming(ctx context.Context, userQuery string, recent []OllamaMessage, onQuery func(string)) (SearchDecision, error) {
+	streamer, ok := a.ollamaClient.(StreamingClient)
+	if !ok {
+		decision, err := a.analyze(ctx, userQuery, recent)
+		if err == nil && decision.NeedsSearch {
+			for _, q := range decision.SearchQueries {
+				onQuery(q)
+			}
+		}
+		return decision, err
+	}
+
+	// Retry on the fallback model only before any query has gone out, so
+	// no search starts twice
+	var scanner queryScanner
+	emitted := false
+	response, err := a.withFallback(ctx, func(model string) (string, error) {
+		scanner = queryScanner{}
+		return streamer.ChatStreamJSON(ctx, model, analysisMessages(userQuery, recent), decisionSchema(len(recent) > 0), func(chunk string) {
+			for _, q := range scanner.Feed(chunk) {
+				emitted = true
+				onQuery(q)
+			}
+		})
+	}, func() bool { return !emitted })
+	if err != nil {
+		return SearchDecision{}, fmt.Errorf("LLM call failed: %w", err)
+	}
+	return parseDecision(userQuery, response)
+}
+
+// decisionSchema is the JSON schema of a SearchDecision, which Ollama holds
+// the analyzer's reply to. Properties are listed in the order the prompt
+// asks for them, so search_queries still streams before reason.
+// standalone_query is required only when there is a conversation to
+// rewrite a follow-up from.
+func decisionSchema(standalone bool) json.RawMessage {
+	required := `["needs_search", "search_queries", "reason"]`
+	if standalone {
+		required = `["standalone_query", "needs_search", "search_queries", "reason"]`
+	}
+	return json.RawMessage(`{"type": "object", "properties": {` +
+		`"standalone_query": {"type": "string"}, ` +
+		`"needs_search": {"type": "boolean"}, ` +
+		`"search_queries": {"type": "array", "items": {"type": "string"}}, ` +
+		`"reason": {"type": "string"}}, ` +
+		`"required": ` + required + `}`)
+}
+
+// Limits on how much conversation the analyzer sees when rewriting follow-ups
+const (
+	maxRecentTurns = 6
+	maxRecentRunes = 400
+)
+
+// analysisMessages builds the search decision prompt for a query, with the
+// recent conversation when there is one
+func analysisMessages(userQuery string, recent []OllamaMessage) []OllamaMessage {
+	conversation, standalone, rewrite := "", "", ""
+	if transcript := formatRecent(recent); transcript != "" {
+		conversation = "Conversation so far:\n" + transcript + "\n"
+		standalone = `  "standalone_query": "the query rewritten to make sense on its own",
+`
+		rewrite = `- The user query may be a follow-up that only makes sense with the conversation. Set standalone_query to the query rewritten as a self-contained question that names everything it refers to (e.g. "how much does it cost?" after discussing the Framework Laptop 16 becomes "How much does the Framework Laptop 16 cost?"). If it already stands alone, repeat it unchanged
+- Base search_queries on standalone_query, never on pronouns like "it" or "that"
+`
+	}
+
 	prompt := fmt.Sprintf(`You are a search decision system. Analyze if the user's query requires web search.
 
-User query: "%s"
+%sUser query: "%s"
 
 Decide if this query needs current web information. Respond ONLY with valid JSON in this exact format:
 {
-  "needs_search": true/false,
+%s  "needs_search": true/false,
   "search_queries": ["query 1", "query 2"],
   "reason": "brief reason"
 }
@@ -59,22 +215,44 @@ Guidelines:
 - If needs_search=true, provide search_queries as an array (each query: concise, 2-5 words)
 - You can provide multiple queries to gather comprehensive information (e.g., "iPhone 16 specs" and "Samsung S24 specs" for comparison)
 - Keep reason under 10 words
+%s
+Respond with JSON only, no other text.`, conversation, userQuery, standalone, rewrite)
 
-Respond with JSON only, no other text.`, userQuery)
-
-	messages := []OllamaMessage{
+	return []OllamaMessage{
 		{Role: "user", Content: prompt},
 	}
+}
 
-	response, err := a.ollamaClient.ChatSync(ctx, a.model, messages)
-	if err != nil {
-		return SearchDecision{}, fmt.Errorf("LLM call failed: %w", err)
+// formatRecent renders the last few turns as a transcript, truncating long
+// messages so the prompt stays small
+func formatRecent(recent []OllamaMessage) string {
+	if len(recent) > maxRecentTurns {
+		recent = recent[len(recent)-maxRecentTurns:]
+	}
+	var sb strings.Builder
+	for _, m := range recent {
+		content := strings.TrimSpace(m.Content)
+		if content == "" || (m.Role != "user" && m.Role != "assistant") {
+			continue
+		}
+		if r := []rune(content); len(r) > maxRecentRunes {
+			content = string(r[:maxRecentRunes]) + "..."
+		}
+		role := "User"
+		if m.Role == "assistant" {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, content)
 	}
+	return sb.String()
+}
 
-	// Parse JSON response
+// parseDecision decodes the analyzer's JSON reply
+func parseDecision(userQuery, response string) (SearchDecision, error) {
 	var decision SearchDecision
 
-	// Clean response - extract JSON if wrapped in markdown
+	// Clients that cannot hold the reply to the schema may wrap the JSON in
+	// a Markdown fence
 	response = strings.TrimSpace(response)
 	if strings.HasPrefix(response, "```json") {
 		response = strings.TrimPrefix(response, "```json")
@@ -87,8 +265,15 @@ Respond with JSON only, no other text.`, userQuery)
 	}
 
 	if err := json.Unmarshal([]byte(response), &decision); err != nil {
+		slog.Warn("analyzer response unparseable", "query", userQuery, "response", response)
 		return SearchDecision{}, fmt.Errorf("failed to parse LLM response: %w\nResponse: %s", err, response)
 	}
 
+	decision.StandaloneQuery = strings.TrimSpace(decision.StandaloneQuery)
+	if decision.StandaloneQuery == "" {
+		decision.StandaloneQuery = userQuery
+	}
+
+	slog.Info("search decision", "query", userQuery, "standalone", decision.StandaloneQuery, "needs_search", decision.NeedsSearch, "queries", decision.SearchQueries, "reason", decision.Reason)
 	return decision, nil
 }
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/errs"
+)
+
+// syncClient answers with a canned reply and keeps the prompt it was sent
+type syncClient struct {
+	reply  string
+	prompt string
+}
+
+func (c *syncClient) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	c.prompt = messages.([]OllamaMessage)[0].Content
+	return c.reply, nil
+}
+
+// structuredClient is a syncClient that can hold replies to a schema
+type structuredClient struct {
+	syncClient
+	schema json.RawMessage
+}
+
+func (c *structuredClient) ChatSyncJSON(ctx context.Context, model string, messages interface{}, schema json.RawMessage) (string, error) {
+	c.schema = schema
+	return c.ChatSync(ctx, model, messages)
+}
+
+func TestAnalyzeHoldsReplyToDecisionSchema(t *testing.T) {
+	client := &structuredClient{syncClient: syncClient{reply: `{"needs_search": false, "search_queries": [], "reason": "math"}`}}
+	decision, err := NewLLMAnalyzer(client, "m").AnalyzeWithLLM(context.Background(), "what is 2+2?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.NeedsSearch || decision.Reason != "math" {
+		t.Errorf("decision = %+v", decision)
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(client.schema, &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v\n%s", err, client.schema)
+	}
+	if schema.Properties["needs_search"].Type != "boolean" || schema.Properties["search_queries"].Type != "array" {
+		t.Errorf("properties = %+v", schema.Properties)
+	}
+	for _, field := range schema.Required {
+		if field == "standalone_query" {
+			t.Error("standalone_query required without a conversation")
+		}
+	}
+	if !strings.Contains(string(decisionSchema(true)), `"required": ["standalone_query"`) {
+		t.Error("standalone_query not required with a conversation")
+	}
+}
+
+func TestAnalyzeStreamingRewritesFollowUps(t *testing.T) {
+	client := &syncClient{reply: `{"standalone_query": "How much does the Framework Laptop 16 cost?", "needs_search": true, "search_queries": ["Framework Laptop 16 price 2025"], "reason": "prices"}`}
+	recent := []OllamaMessage{
+		{Role: "user", Content: "Tell me about the Framework Laptop 16"},
+		{Role: "assistant", Content: "It is a modular 16-inch laptop. " + strings.Repeat("x", 1000)},
+	}
+
+	var queries []string
+	decision, err := NewLLMAnalyzer(client, "m").AnalyzeStreaming(context.Background(), "how much does it cost?", recent, func(q string) {
+		queries = append(queries, q)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.StandaloneQuery != "How much does the Framework Laptop 16 cost?" {
+		t.Errorf("StandaloneQuery = %q", decision.StandaloneQuery)
+	}
+	if len(queries) != 1 || queries[0] != "Framework Laptop 16 price 2025" {
+		t.Errorf("queries = %q", queries)
+	}
+	for _, want := range []string{"User: Tell me about the Framework Laptop 16", "Assistant: It is a modular", `"standalone_query"`} {
+		if !strings.Contains(client.prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(client.prompt, strings.Repeat("x", 500)) {
+		t.Error("long assistant turn was not truncated")
+	}
+}
+
+func TestAnalyzeWithoutHistoryKeepsQuery(t *testing.T) {
+	client := &syncClient{reply: `{"needs_search": false, "reason": "coding"}`}
+
+	decision, err := NewLLMAnalyzer(client, "m").AnalyzeWithLLM(context.Background(), "reverse a slice in Go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.StandaloneQuery != "reverse a slice in Go" {
+		t.Errorf("StandaloneQuery = %q, want the query itself", decision.StandaloneQuery)
+	}
+	if strings.Contains(client.prompt, "standalone_query") || strings.Contains(client.prompt, "Conversation so far") {
+		t.Error("prompt asks for a rewrite without any conversation")
+	}
+}
+
+// modelClient fails for the models in fail and records which were asked
+type modelClient struct {
+	fail  map[string]error
+	asked []string
+}
+
+func (c *modelClient) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	c.asked = append(c.asked, model)
+	if err := c.fail[model]; err != nil {
+		return "", err
+	}
+	return `{"needs_search": false, "search_queries": [], "reason": "ok"}`, nil
+}
+
+func TestAnalyzerFallsBackToChatModel(t *testing.T) {
+	client := &modelClient{fail: map[string]error{"tiny": errs.New(errs.ModelMissing, "tiny", nil)}}
+	a := NewLLMAnalyzer(client, "tiny")
+	a.SetFallback("big")
+
+	for i := 0; i < 2; i++ {
+		if _, err := a.AnalyzeWithLLM(context.Background(), "q"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(client.asked, ","); got != "tiny,big,big" {
+		t.Errorf("asked %s, want a missing analyzer model replaced for good", got)
+	}
+	if a.Model() != "big" {
+		t.Errorf("Model() = %q", a.Model())
+	}
+
+	// Other failures are retried without giving up on the analyzer model
+	client = &modelClient{fail: map[string]error{"tiny": errors.New("timeout")}}
+	a = NewLLMAnalyzer(client, "tiny")
+	a.SetFallback("big")
+	a.AnalyzeWithLLM(context.Background(), "q")
+	if a.Model() != "tiny" || strings.Join(client.asked, ",") != "tiny,big" {
+		t.Errorf("asked %v, model %q", client.asked, a.Model())
+	}
+
+	// Without a fallback the error is returned
+	client = &modelClient{fail: map[string]error{"tiny": errors.New("timeout")}}
+	if _, err := NewLLMAnalyzer(client, "tiny").AnalyzeWithLLM(context.Background(), "q"); err == nil {
+		t.Error("want the analyzer model's error without a fallback")
+	}
+}
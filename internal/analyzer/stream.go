@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	needsSearchPattern = regexp.MustCompile(`"needs_search"\s*:\s*true`)
+	queriesPattern     = regexp.MustCompile(`"search_queries"\s*:\s*\[`)
+	queryItemPattern   = regexp.MustCompile(`^\s*,?\s*("(?:[^"\\]|\\.)*")`)
+)
+
+// queryScanner pulls completed search queries out of a partial analyzer
+// response while it streams in
+type queryScanner struct {
+	buf     strings.Builder
+	emitted int
+}
+
+// Feed appends a chunk and returns the queries it completed. Nothing is
+// returned until the response has said a search is needed.
+func (s *queryScanner) Feed(chunk string) []string {
+	s.buf.WriteString(chunk)
+	text := s.buf.String()
+	if !needsSearchPattern.MatchString(text) {
+		return nil
+	}
+	loc := queriesPattern.FindStringIndex(text)
+	if loc == nil {
+		return nil
+	}
+
+	var queries []string
+	rest := text[loc[1]:]
+	for {
+		m := queryItemPattern.FindStringSubmatchIndex(rest)
+		if m == nil {
+			break
+		}
+		var q string
+		if err := json.Unmarshal([]byte(rest[m[2]:m[3]]), &q); err != nil {
+			break
+		}
+		queries = append(queries, q)
+		rest = rest[m[1]:]
+	}
+
+	if len(queries) <= s.emitted {
+		return nil
+	}
+	var fresh []string
+	for _, q := range queries[s.emitted:] {
+		if q = strings.TrimSpace(q); q != "" {
+			fresh = append(fresh, q)
+		}
+	}
+	s.emitted = len(queries)
+	return fresh
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestQueryScannerEmitsCompletedQueries(t *testing.T) {
+	chunks := []string{
+		`{"needs_search": tr`, `ue, "search_queries": ["go 1.`, `22 release", "go `,
+		`\"loopvar\""`, `], "reason": "recent"}`,
+	}
+	want := [][]string{nil, nil, {"go 1.22 release"}, {`go "loopvar"`}, nil}
+
+	var s queryScanner
+	for i, chunk := range chunks {
+		if got := s.Feed(chunk); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("chunk %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestQueryScannerWaitsForNeedsSearch(t *testing.T) {
+	var s queryScanner
+	if got := s.Feed(`{"search_queries": ["weather berlin"], `); got != nil {
+		t.Errorf("got %q before needs_search, want nothing", got)
+	}
+	if got := s.Feed(`"needs_search": true}`); !reflect.DeepEqual(got, []string{"weather berlin"}) {
+		t.Errorf("got %q, want the query once needs_search is true", got)
+	}
+
+	var none queryScanner
+	if got := none.Feed(`{"needs_search": false, "search_queries": ["unused"]}`); got != nil {
+		t.Errorf("got %q, want nothing when no search is needed", got)
+	}
+}
+
+// fakeStreamer streams a canned reply and records what had been emitted
+// before each chunk was sent
+type fakeStreamer struct {
+	chunks  []string
+	emitted *[]string
+	seen    []int
+}
+
+func (f *fakeStreamer) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	panic("streaming client should not be called synchronously")
+}
+
+func (f *fakeStreamer) ChatStreamJSON(ctx context.Context, model string, messages interface{}, schema json.RawMessage, onChunk func(string)) (string, error) {
+	if !json.Valid(schema) {
+		panic("invalid decision schema")
+	}
+	var full string
+	for _, chunk := range f.chunks {
+		f.seen = append(f.seen, len(*f.emitted))
+		full += chunk
+		onChunk(chunk)
+	}
+	return full, nil
+}
+
+func TestAnalyzeStreamingReportsQueriesEarly(t *testing.T) {
+	var emitted []string
+	client := &fakeStreamer{
+		chunks:  []string{`{"needs_search": true, "search_queries": ["a b"`, `, "c d"], `, `"reason": "news"}`},
+		emitted: &emitted,
+	}
+
+	decision, err := NewLLMAnalyzer(client, "m").AnalyzeStreaming(context.Background(), "q", nil, func(q string) {
+		emitted = append(emitted, q)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decision.NeedsSearch || decision.Reason != "news" {
+		t.Errorf("decision = %+v", decision)
+	}
+	if !reflect.DeepEqual(emitted, []string{"a b", "c d"}) {
+		t.Errorf("emitted %q, want both queries", emitted)
+	}
+	// The first query was out before the reason had been generated
+	if client.seen[2] != 2 || client.seen[1] != 1 {
+		t.Errorf("queries emitted before chunks = %v, want [0 1 2]", client.seen)
+	}
+}
@@ -0,0 +1,218 @@
+// Package archive snapshots crawled pages into a personal research archive,
+// laid out as <dir>/<domain>/<date>/
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"web-ollama/internal/crawler"
+)
+
+// Formats supported by the archive
+const (
+	Markdown = "markdown"
+	WARC     = "warc"
+)
+
+// maxSlugLength keeps snapshot file names manageable
+const maxSlugLength = 60
+
+// Archive writes snapshots of crawled pages
+type Archive struct {
+	dir    string
+	format string
+	mu     sync.Mutex
+	now    func() time.Time
+}
+
+// New creates an archive rooted at dir writing format (markdown or warc)
+func New(dir, format string) (*Archive, error) {
+	if format != Markdown && format != WARC {
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+	return &Archive{dir: dir, format: format, now: time.Now}, nil
+}
+
+// Save snapshots every successfully crawled page in results and returns
+// the paths written to
+func (a *Archive) Save(results []crawler.CrawlResult) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var paths []string
+	for _, r := range results {
+		if r.Error != nil || r.Content == "" {
+			continue
+		}
+		var path string
+		var err error
+		if a.format == WARC {
+			path, err = a.appendWARC(r)
+		} else {
+			path, err = a.writeMarkdown(r)
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// dayDir returns <dir>/<domain>/<date> for rawURL, creating it
+func (a *Archive) dayDir(rawURL string, now time.Time) (string, error) {
+	domain := "unknown"
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		domain = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	dir := filepath.Join(a.dir, domain, now.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return dir, nil
+}
+
+// writeMarkdown stores one page as Markdown with YAML front matter. A page
+// whose content has not changed since it was last archived that day is
+// not written again.
+func (a *Archive) writeMarkdown(r crawler.CrawlResult) (string, error) {
+	now := a.now()
+	dir, err := a.dayDir(r.URL, now)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(r.URL + "\x00" + r.Content))
+	path := filepath.Join(dir, slug(r)+"-"+hex.EncodeToString(sum[:])[:8]+".md")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "url: %q\n", r.URL)
+	if r.Title != "" {
+		fmt.Fprintf(&sb, "title: %q\n", r.Title)
+	}
+	fmt.Fprintf(&sb, "archived: %s\n", now.Format(time.RFC3339))
+	sb.WriteString("---\n\n")
+	if r.Title != "" {
+		fmt.Fprintf(&sb, "# %s\n\n", r.Title)
+	}
+	sb.WriteString(strings.TrimSpace(r.Content) + "\n")
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return "", fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return path, nil
+}
+
+// appendWARC adds the page as a WARC/1.1 resource record to the day's file
+// for its domain, starting the file with a warcinfo record
+func (a *Archive) appendWARC(r crawler.CrawlResult) (string, error) {
+	now := a.now().UTC()
+	dir, err := a.dayDir(r.URL, now)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "pages.warc")
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat WARC file: %w", err)
+	}
+	var buf strings.Builder
+	if info.Size() == 0 {
+		writeRecord(&buf, "warcinfo", now, "", "application/warc-fields",
+			"software: web-ollama\r\nformat: WARC File Format 1.1\r\n")
+	}
+	body := r.Content
+	if r.Title != "" {
+		body = "# " + r.Title + "\n\n" + body
+	}
+	writeRecord(&buf, "resource", now, r.URL, "text/markdown; charset=utf-8", body)
+
+	if _, err := f.WriteString(buf.String()); err != nil {
+		return "", fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	return path, nil
+}
+
+// writeRecord formats one WARC record
+func writeRecord(buf *strings.Builder, kind string, date time.Time, target, contentType, body string) {
+	buf.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(buf, "WARC-Type: %s\r\n", kind)
+	fmt.Fprintf(buf, "WARC-Record-ID: <urn:uuid:%s>\r\n", uuid.New().String())
+	fmt.Fprintf(buf, "WARC-Date: %s\r\n", date.Format(time.RFC3339))
+	if target != "" {
+		fmt.Fprintf(buf, "WARC-Target-URI: %s\r\n", target)
+	}
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(buf, "Content-Length: %d\r\n", len(body))
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	buf.WriteString("\r\n\r\n")
+}
+
+var unsafeSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug names a snapshot after the page title, or its path when untitled
+func slug(r crawler.CrawlResult) string {
+	base := r.Title
+	if base == "" {
+		if u, err := url.Parse(r.URL); err == nil {
+			base = u.Path
+		}
+	}
+	s := strings.Trim(unsafeSlug.ReplaceAllString(strings.ToLower(base), "-"), "-")
+	if len(s) > maxSlugLength {
+		s = strings.TrimRight(s[:maxSlugLength], "-")
+	}
+	if s == "" {
+		s = "index"
+	}
+	return s
+}
+
+// Backend crawls pages
+type Backend interface {
+	CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult
+}
+
+// Fetcher crawls with Next and archives every page it fetches
+type Fetcher struct {
+	Archive *Archive
+	Next    Backend
+}
+
+// CrawlURLs implements grounding.Fetcher
+func (f *Fetcher) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	results := f.Next.CrawlURLs(ctx, urls)
+	if _, err := f.Archive.Save(results); err != nil {
+		slog.Warn("failed to archive pages", "error", err)
+	}
+	return results
+}
@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/crawler"
+)
+
+var pages = []crawler.CrawlResult{
+	{URL: "https://www.Go.dev/blog/go1.22", Title: "Go 1.22 is released!", Content: "Go 1.22 ships loop variable changes."},
+	{URL: "https://down.example", Error: errors.New("timeout")},
+	{URL: "https://example.com/docs/", Content: "No title here."},
+}
+
+func newTestArchive(t *testing.T, format string) (*Archive, string) {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := New(dir, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.now = func() time.Time { return time.Date(2024, 2, 6, 12, 0, 0, 0, time.UTC) }
+	return a, dir
+}
+
+func TestMarkdownSnapshots(t *testing.T) {
+	a, dir := newTestArchive(t, Markdown)
+	paths, err := a.Save(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("wrote %d snapshots, want 2", len(paths))
+	}
+
+	wantDir := filepath.Join(dir, "go.dev", "2024-02-06")
+	if filepath.Dir(paths[0]) != wantDir || !strings.HasPrefix(filepath.Base(paths[0]), "go-1-22-is-released-") {
+		t.Errorf("unexpected path %s", paths[0])
+	}
+	if !strings.HasPrefix(filepath.Base(paths[1]), "docs-") {
+		t.Errorf("untitled page should be named after its path: %s", paths[1])
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`url: "https://www.Go.dev/blog/go1.22"`, "# Go 1.22 is released!", "loop variable changes"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("snapshot missing %q:\n%s", want, data)
+		}
+	}
+
+	// Unchanged pages are not duplicated
+	again, _ := a.Save(pages[:1])
+	entries, _ := os.ReadDir(wantDir)
+	if again[0] != paths[0] || len(entries) != 1 {
+		t.Errorf("re-archiving an unchanged page wrote %d files", len(entries))
+	}
+}
+
+func TestWARCRecords(t *testing.T) {
+	a, dir := newTestArchive(t, WARC)
+	if _, err := a.Save(pages[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Save(pages[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "go.dev", "2024-02-06", "pages.warc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	warc := string(data)
+	if strings.Count(warc, "WARC-Type: warcinfo") != 1 || strings.Count(warc, "WARC-Type: resource") != 2 {
+		t.Errorf("want one warcinfo and two resource records:\n%s", warc)
+	}
+	if !strings.Contains(warc, "WARC-Target-URI: https://www.Go.dev/blog/go1.22\r\n") {
+		t.Error("resource record missing its target URI")
+	}
+}
+
+type fakeCrawler struct{}
+
+func (fakeCrawler) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	return pages
+}
+
+func TestFetcherArchives(t *testing.T) {
+	a, dir := newTestArchive(t, Markdown)
+	f := &Fetcher{Archive: a, Next: fakeCrawler{}}
+	if got := f.CrawlURLs(context.Background(), nil); len(got) != len(pages) {
+		t.Errorf("fetcher changed the results: %d", len(got))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "example.com")); err != nil {
+		t.Errorf("page was not archived: %v", err)
+	}
+	if _, err := New(dir, "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
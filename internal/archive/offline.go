@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/searxng"
+)
+
+// Searcher searches the archive in place of a search engine, for offline use
+type Searcher struct {
+	Archive *Archive
+}
+
+// Search implements grounding.Searcher, returning the matching snapshots as
+// results marked with when they were archived
+func (s *Searcher) Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error) {
+	hits, err := s.Archive.Search(query, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]searxng.SearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = searxng.SearchResult{
+			Title:   h.Title,
+			URL:     h.URL,
+			Content: h.Snippet,
+			Engine:  "archive",
+			Score:   float64(h.Score),
+			Cached:  h.Archived,
+		}
+	}
+	return results, nil
+}
+
+// Local reads pages from their latest snapshot instead of crawling them,
+// for offline use. Pages never archived fail.
+type Local struct {
+	Archive *Archive
+}
+
+// CrawlURLs implements grounding.Fetcher
+func (l *Local) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	entries, err := l.Archive.Entries()
+	if err != nil {
+		err = fmt.Errorf("failed to read the archive: %w", err)
+	}
+	latest := newest(entries)
+	results := make([]crawler.CrawlResult, len(urls))
+	for i, u := range urls {
+		e, ok := latest[u]
+		switch {
+		case ok:
+			results[i] = e.Result()
+		case err != nil:
+			results[i] = crawler.CrawlResult{URL: u, Error: err}
+		default:
+			results[i] = crawler.CrawlResult{URL: u, Error: fmt.Errorf("no archived copy of %s", u)}
+		}
+	}
+	return results
+}
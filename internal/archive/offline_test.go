@@ -0,0 +1,30 @@
+package archive
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOfflineSearchAndFetch(t *testing.T) {
+	a, _ := newTestArchive(t, Markdown)
+	if _, err := a.Save(pages); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := (&Searcher{Archive: a}).Search(context.Background(), "loop", 5)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search = %+v, %v", results, err)
+	}
+	if results[0].URL != pages[0].URL || results[0].Engine != "archive" || results[0].Cached.Month() != time.February {
+		t.Errorf("unexpected result %+v", results[0])
+	}
+
+	fetched := (&Local{Archive: a}).CrawlURLs(context.Background(), []string{pages[0].URL, "https://never.example"})
+	if fetched[0].Error != nil || fetched[0].Content != pages[0].Content || fetched[0].Saved.IsZero() {
+		t.Errorf("archived page = %+v", fetched[0])
+	}
+	if fetched[1].Error == nil {
+		t.Error("a page never archived should fail")
+	}
+}
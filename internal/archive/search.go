@@ -0,0 +1,237 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ollama/internal/crawler"
+)
+
+// snippetRunes is the length of the excerpt shown with each search hit
+const snippetRunes = 160
+
+// titleWeight is how much more a term in the title counts than one in the text
+const titleWeight = 5
+
+// Entry is one archived snapshot of a page
+type Entry struct {
+	URL      string
+	Title    string
+	Content  string
+	Archived time.Time
+	Path     string // Markdown file or WARC file holding it
+}
+
+// Result converts the snapshot back into a crawl result
+func (e Entry) Result() crawler.CrawlResult {
+	return crawler.CrawlResult{URL: e.URL, Title: e.Title, Content: e.Content, Saved: e.Archived}
+}
+
+// Hit is an archived page matching a search
+type Hit struct {
+	Entry
+	Score   int
+	Snippet string // text around the first match
+}
+
+// Entries reads every snapshot in the archive, in both formats
+func (a *Archive) Entries() ([]Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var entries []Entry
+	err := filepath.WalkDir(a.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == a.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return nil
+		case strings.HasSuffix(path, ".md"):
+			e, err := readMarkdown(path)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		case strings.HasSuffix(path, ".warc"):
+			records, err := readWARC(path)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, records...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return entries, nil
+}
+
+// Search returns up to limit archived pages containing every one of the
+// words in terms, best first. Only the newest snapshot of each URL is kept.
+// Matches in the title count more than matches in the text.
+func (a *Archive) Search(terms string, limit int) ([]Hit, error) {
+	words := strings.Fields(strings.ToLower(terms))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	entries, err := a.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	for _, e := range newest(entries) {
+		title, content := strings.ToLower(e.Title), strings.ToLower(e.Content)
+		score := 0
+		for _, w := range words {
+			n := titleWeight*strings.Count(title, w) + strings.Count(content, w)
+			if n == 0 {
+				score = 0
+				break
+			}
+			score += n
+		}
+		if score > 0 {
+			hits = append(hits, Hit{Entry: e, Score: score, Snippet: snippet(e.Content, words[0])})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Archived.After(hits[j].Archived)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// newest keeps the latest snapshot of each URL
+func newest(entries []Entry) map[string]Entry {
+	latest := make(map[string]Entry)
+	for _, e := range entries {
+		if prev, ok := latest[e.URL]; !ok || e.Archived.After(prev.Archived) {
+			latest[e.URL] = e
+		}
+	}
+	return latest
+}
+
+// snippet returns about snippetRunes of text around the first occurrence of
+// word, on one line
+func snippet(text, word string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	lower := strings.ToLower(text)
+	at := 0
+	if i := strings.Index(lower, word); i > 0 && len([]rune(lower)) == len(runes) {
+		at = len([]rune(lower[:i]))
+	}
+	start := max(at-snippetRunes/4, 0)
+	end := min(start+snippetRunes, len(runes))
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(runes) {
+		s += "..."
+	}
+	return s
+}
+
+// readMarkdown parses a snapshot written by writeMarkdown
+func readMarkdown(path string) (Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{Path: path}
+	text := string(data)
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		header, body, _ := strings.Cut(rest, "\n---\n")
+		for _, line := range strings.Split(header, "\n") {
+			key, value, _ := strings.Cut(line, ": ")
+			switch key {
+			case "url":
+				e.URL, _ = strconv.Unquote(value)
+			case "title":
+				e.Title, _ = strconv.Unquote(value)
+			case "archived":
+				e.Archived, _ = time.Parse(time.RFC3339, value)
+			}
+		}
+		text = body
+	}
+	text = strings.TrimSpace(text)
+	if e.Title != "" {
+		text = strings.TrimSpace(strings.TrimPrefix(text, "# "+e.Title))
+	}
+	e.Content = text
+	return e, nil
+}
+
+// readWARC parses the resource records appended by appendWARC
+func readWARC(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	r := bufio.NewReader(f)
+	for {
+		headers := make(map[string]string)
+		for {
+			line, err := r.ReadString('\n')
+			if err == io.EOF && line == "" {
+				return entries, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				if len(headers) == 0 {
+					continue // blank lines between records
+				}
+				break
+			}
+			if key, value, ok := strings.Cut(line, ": "); ok {
+				headers[key] = value
+			}
+		}
+
+		length, err := strconv.Atoi(headers["Content-Length"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: bad Content-Length", path)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if headers["WARC-Type"] != "resource" {
+			continue
+		}
+
+		e := Entry{URL: headers["WARC-Target-URI"], Path: path, Content: string(body)}
+		e.Archived, _ = time.Parse(time.RFC3339, headers["WARC-Date"])
+		if title, rest, ok := strings.Cut(e.Content, "\n\n"); ok && strings.HasPrefix(title, "# ") {
+			e.Title, e.Content = strings.TrimPrefix(title, "# "), rest
+		}
+		entries = append(entries, e)
+	}
+}
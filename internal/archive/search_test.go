@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/crawler"
+)
+
+func TestSearchBothFormats(t *testing.T) {
+	for _, format := range []string{Markdown, WARC} {
+		a, _ := newTestArchive(t, format)
+		if _, err := a.Save(pages); err != nil {
+			t.Fatal(err)
+		}
+		// A newer snapshot of the same page replaces the older one
+		a.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+		if _, err := a.Save([]crawler.CrawlResult{{URL: pages[0].URL, Title: pages[0].Title, Content: "Go 1.22 changes loop variables and adds range over integers."}}); err != nil {
+			t.Fatal(err)
+		}
+
+		hits, err := a.Search("LOOP go", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 {
+			t.Fatalf("%s: got %d hits, want 1: %+v", format, len(hits), hits)
+		}
+		hit := hits[0]
+		if hit.URL != pages[0].URL || hit.Title != pages[0].Title || !strings.Contains(hit.Content, "range over integers") {
+			t.Errorf("%s: unexpected hit %+v", format, hit)
+		}
+		if hit.Archived.Month() != time.March || !strings.Contains(hit.Snippet, "loop") {
+			t.Errorf("%s: hit archived %v with snippet %q", format, hit.Archived, hit.Snippet)
+		}
+
+		if hits, _ := a.Search("loop nonexistentword", 10); len(hits) != 0 {
+			t.Errorf("%s: every term must match, got %d hits", format, len(hits))
+		}
+	}
+}
+
+func TestSearchMissingArchive(t *testing.T) {
+	a, err := New(t.TempDir()+"/none", Markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, err := a.Search("anything", 5); err != nil || len(hits) != 0 {
+		t.Errorf("Search = %v, %v", hits, err)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 50) + "the needle is here " + strings.Repeat("tail ", 50)
+	s := snippet(text, "needle")
+	if !strings.HasPrefix(s, "...") || !strings.HasSuffix(s, "...") || !strings.Contains(s, "needle") {
+		t.Errorf("snippet = %q", s)
+	}
+}
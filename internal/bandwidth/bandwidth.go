@@ -0,0 +1,192 @@
+// Package bandwidth counts what the crawler downloads, per site and in
+// total, and stops fetching pages for a question once its downloads pass a
+// cap. Byte counts are of the response bodies as read, after any transparent
+// decompression, so they never underestimate what came over the wire.
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"web-ollama/internal/metrics"
+)
+
+// ErrCapReached is returned for requests made after the question's download
+// cap was reached
+var ErrCapReached = errors.New("download cap for this question reached")
+
+// Meter totals downloads since it was created
+type Meter struct {
+	mu    sync.Mutex
+	total int64
+	sites map[string]*Site
+}
+
+// Site is what was downloaded from one host
+type Site struct {
+	Host     string
+	Bytes    int64
+	Requests int
+}
+
+// Totals is a snapshot of a Meter
+type Totals struct {
+	Bytes    int64
+	Requests int
+	Sites    []Site // most downloaded first
+}
+
+// NewMeter creates an empty meter
+func NewMeter() *Meter {
+	return &Meter{sites: make(map[string]*Site)}
+}
+
+// Totals returns what has been downloaded so far
+func (m *Meter) Totals() Totals {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := Totals{Bytes: m.total}
+	for _, s := range m.sites {
+		t.Requests += s.Requests
+		t.Sites = append(t.Sites, *s)
+	}
+	sort.Slice(t.Sites, func(i, j int) bool {
+		if t.Sites[i].Bytes != t.Sites[j].Bytes {
+			return t.Sites[i].Bytes > t.Sites[j].Bytes
+		}
+		return t.Sites[i].Host < t.Sites[j].Host
+	})
+	return t
+}
+
+// site returns the entry for host (must be called with lock held)
+func (m *Meter) site(host string) *Site {
+	s, ok := m.sites[host]
+	if !ok {
+		s = &Site{Host: host}
+		m.sites[host] = s
+	}
+	return s
+}
+
+func (m *Meter) addRequest(host string) {
+	m.mu.Lock()
+	m.site(host).Requests++
+	m.mu.Unlock()
+}
+
+func (m *Meter) addBytes(host string, n int64) {
+	m.mu.Lock()
+	m.site(host).Bytes += n
+	m.total += n
+	m.mu.Unlock()
+	metrics.CrawlBytes.Add(float64(n))
+}
+
+// turn counts one question's downloads
+type turn struct {
+	limit  int64
+	bytes  atomic.Int64
+	capped atomic.Bool
+}
+
+type turnKey struct{}
+
+// WithTurn starts counting a question's downloads. Once they pass limit
+// bytes no further pages are fetched for it, though pages already
+// downloading finish. A limit of 0 means no cap.
+func WithTurn(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, turnKey{}, &turn{limit: limit})
+}
+
+// TurnBytes returns what the question in ctx has downloaded so far
+func TurnBytes(ctx context.Context) int64 {
+	if t, ok := ctx.Value(turnKey{}).(*turn); ok {
+		return t.bytes.Load()
+	}
+	return 0
+}
+
+// CapReached reports whether the question in ctx had pages skipped because
+// of its download cap
+func CapReached(ctx context.Context) bool {
+	t, ok := ctx.Value(turnKey{}).(*turn)
+	return ok && t.capped.Load()
+}
+
+// Transport returns a RoundTripper that counts every response body read
+// through it and refuses requests once their question's cap is reached
+func (m *Meter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{m: m, base: base}
+}
+
+type transport struct {
+	m    *Meter
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	turn, _ := req.Context().Value(turnKey{}).(*turn)
+	if turn != nil && turn.limit > 0 && turn.bytes.Load() >= turn.limit {
+		if !turn.capped.Swap(true) {
+			slog.Warn("download cap reached", "limit", turn.limit, "skipped", req.URL.String())
+		}
+		return nil, ErrCapReached
+	}
+
+	t.m.addRequest(host)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, m: t.m, host: host, turn: turn}
+	return resp, nil
+}
+
+// countingBody adds what is read to the meter and the question's turn
+type countingBody struct {
+	io.ReadCloser
+	m    *Meter
+	host string
+	turn *turn
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.m.addBytes(b.host, int64(n))
+		if b.turn != nil {
+			b.turn.bytes.Add(int64(n))
+		}
+	}
+	return n, err
+}
+
+// FormatBytes renders n as B, KB, MB or GB with one decimal
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
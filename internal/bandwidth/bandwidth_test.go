@@ -0,0 +1,102 @@
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func fetch(t *testing.T, client *http.Client, ctx context.Context, url string) error {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+func TestMeterCountsPerSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}))
+	defer server.Close()
+
+	meter := NewMeter()
+	client := &http.Client{Transport: meter.Transport(nil)}
+	ctx := WithTurn(context.Background(), 0)
+	for i := 0; i < 3; i++ {
+		if err := fetch(t, client, ctx, server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	totals := meter.Totals()
+	if totals.Bytes != 3000 || totals.Requests != 3 {
+		t.Errorf("totals = %d bytes in %d requests, want 3000 in 3", totals.Bytes, totals.Requests)
+	}
+	if len(totals.Sites) != 1 || totals.Sites[0].Host != "127.0.0.1" || totals.Sites[0].Bytes != 3000 {
+		t.Errorf("sites = %+v", totals.Sites)
+	}
+	if got := TurnBytes(ctx); got != 3000 {
+		t.Errorf("turn bytes = %d, want 3000", got)
+	}
+}
+
+func TestTurnCapSkipsLaterPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 600))
+	}))
+	defer server.Close()
+
+	meter := NewMeter()
+	client := &http.Client{Transport: meter.Transport(nil)}
+	ctx := WithTurn(context.Background(), 1000)
+
+	// The page that crosses the cap still finishes
+	for i := 0; i < 2; i++ {
+		if err := fetch(t, client, ctx, server.URL); err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+	}
+	if CapReached(ctx) {
+		t.Error("cap reported before any page was skipped")
+	}
+	if err := fetch(t, client, ctx, server.URL); !errors.Is(err, ErrCapReached) {
+		t.Fatalf("err = %v, want ErrCapReached", err)
+	}
+	if !CapReached(ctx) {
+		t.Error("cap not reported after a skipped page")
+	}
+	if got := meter.Totals().Requests; got != 2 {
+		t.Errorf("requests = %d, want the skipped one uncounted", got)
+	}
+
+	// Another question starts with a fresh allowance
+	if err := fetch(t, client, WithTurn(context.Background(), 1000), server.URL); err != nil {
+		t.Errorf("new turn: %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KB",
+		5 * 1024 * 1024: "5.0 MB",
+		3 << 30:         "3.0 GB",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
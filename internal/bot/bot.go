@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"web-ollama/internal/errs"
+	"web-ollama/internal/history"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/scheduler"
+)
+
+// Incoming is a message received from a chat platform
+type Incoming struct {
+	ChatID string // platform conversation (channel, group or DM)
+	From   string // display name of the sender
+	Text   string
+}
+
+// Platform connects the bot to a chat service
+type Platform interface {
+	Name() string
+	// Receive delivers messages until ctx is cancelled or an error occurs
+	Receive(ctx context.Context, out chan<- Incoming) error
+	// Send posts a reply to a conversation
+	Send(ctx context.Context, chatID string, text string) error
+	// Typing shows a "working" indicator where the platform supports it
+	Typing(ctx context.Context, chatID string)
+}
+
+// Bot answers chat messages with the web-grounded pipeline, keeping one
+// history session per conversation
+type Bot struct {
+	Platform   Platform
+	Pipeline   *pipeline.Pipeline
+	History    *history.Manager
+	MaxHistory int              // messages of context per conversation
+	Allowed    map[string]bool  // chats the bot answers; messages from others are logged and ignored
+	Workers    int              // messages handled at once, including those waiting in Queue; 0 uses defaultWorkers
+	Queue      *scheduler.Queue // questions wait here for their turn; nil answers all at once
+	Log        func(string)
+
+	mu      sync.Mutex
+	pending map[string][]Incoming // chat key -> messages not yet answered, while a worker has the chat
+}
+
+const (
+	// defaultWorkers bounds the messages handled at once when Workers is 0
+	defaultWorkers = 16
+	// maxChatBacklog is how many messages one chat may have waiting
+	maxChatBacklog = 5
+)
+
+// Run receives and answers messages until ctx is cancelled. A fixed pool of
+// workers answers them; each chat is handled by one worker at a time, so its
+// messages are answered in order.
+func (b *Bot) Run(ctx context.Context) error {
+	incoming := make(chan Incoming)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- b.Platform.Receive(ctx, incoming)
+	}()
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	// Chats with messages to answer wait here for a free worker
+	ready := make(chan string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range ready {
+				b.drain(ctx, key)
+			}
+		}()
+	}
+	defer func() {
+		close(ready)
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case msg := <-incoming:
+			if !b.Allowed[msg.ChatID] {
+				b.log(fmt.Sprintf("ignoring %s:%s (%s); add it to allowed_chat_ids to answer it", b.Platform.Name(), msg.ChatID, msg.From))
+				continue
+			}
+			if reply := b.dispatch(msg, ready); reply != "" {
+				b.reply(ctx, msg.ChatID, reply)
+			}
+		}
+	}
+}
+
+// dispatch queues msg behind earlier messages of its chat, handing the chat
+// to a worker if none has it. It returns what to tell the user when the
+// message cannot be queued.
+func (b *Bot) dispatch(msg Incoming, ready chan<- string) string {
+	key := b.Platform.Name() + ":" + msg.ChatID
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending == nil {
+		b.pending = make(map[string][]Incoming)
+	}
+
+	if queued, busy := b.pending[key]; busy {
+		if len(queued) >= maxChatBacklog {
+			return "You have several questions waiting already. Please wait for my answers before asking more."
+		}
+		b.pending[key] = append(queued, msg)
+		return ""
+	}
+	select {
+	case ready <- key:
+		b.pending[key] = []Incoming{msg}
+		return ""
+	default:
+		return "I'm busy with too many questions right now. Please ask again in a minute."
+	}
+}
+
+// drain answers a chat's messages in order until none are left
+func (b *Bot) drain(ctx context.Context, key string) {
+	for {
+		b.mu.Lock()
+		queued := b.pending[key]
+		if len(queued) == 0 || ctx.Err() != nil {
+			delete(b.pending, key)
+			b.mu.Unlock()
+			return
+		}
+		b.pending[key] = queued[1:]
+		b.mu.Unlock()
+
+		b.handle(ctx, key, queued[0])
+	}
+}
+
+// handle answers one message
+func (b *Bot) handle(ctx context.Context, key string, msg Incoming) {
+	text := strings.TrimSpace(msg.Text)
+	switch {
+	case text == "":
+		return
+	case text == "/start" || text == "/help":
+		b.reply(ctx, msg.ChatID, "Ask me anything. I search the web when a question needs current information and cite my sources. Send /new to start a fresh conversation.")
+		return
+	case text == "/new":
+		if err := b.History.ResetKey(key); err != nil {
+			b.log(fmt.Sprintf("failed to reset %s: %v", key, err))
+		}
+		b.reply(ctx, msg.ChatID, "Started a new conversation.")
+		return
+	}
+
+	b.log(fmt.Sprintf("%s (%s): %s", key, msg.From, text))
+	told := false
+	release, err := b.Queue.Enter(ctx, func(pos int) {
+		if !told {
+			b.reply(ctx, msg.ChatID, fmt.Sprintf("I'm answering other questions first; yours is number %d in line.", pos))
+			told = true
+		}
+	})
+	if err != nil {
+		if errs.Is(err, errs.QueueFull) {
+			b.log(fmt.Sprintf("%s: %v", key, err))
+			b.reply(ctx, msg.ChatID, "I'm busy with too many questions right now. Please ask again in a minute.")
+		}
+		return
+	}
+	defer release()
+	b.Platform.Typing(ctx, msg.ChatID)
+
+	sessionID := b.History.SessionForKey(key)
+	recent := b.History.GetSessionMessages(sessionID, b.MaxHistory)
+
+	answer, err := b.Pipeline.Ask(ctx, text, recent, nil)
+	if err != nil {
+		b.log(fmt.Sprintf("%s: %v", key, err))
+		b.reply(ctx, msg.ChatID, "Sorry, I couldn't answer that: "+err.Error())
+		return
+	}
+
+	b.History.AddMessageToSession(sessionID, history.Message{Role: "user", Content: text})
+	assistantMsg := history.Message{Role: "assistant", Content: answer.Answer, Thinking: answer.Thinking}
+	if len(answer.Sources) > 0 || answer.StandaloneQuery != "" {
+		assistantMsg.Metadata = &history.Metadata{
+			SearchPerformed: len(answer.Sources) > 0,
+			SourceURLs:      answer.Sources,
+			StandaloneQuery: answer.StandaloneQuery,
+		}
+	}
+	b.History.AddMessageToSession(sessionID, assistantMsg)
+
+	b.reply(ctx, msg.ChatID, FormatAnswer(answer.Answer, answer.Sources))
+}
+
+// reply sends text, logging failures
+func (b *Bot) reply(ctx context.Context, chatID, text string) {
+	if err := b.Platform.Send(ctx, chatID, text); err != nil {
+		b.log(fmt.Sprintf("send to %s failed: %v", chatID, err))
+	}
+}
+
+func (b *Bot) log(msg string) {
+	if b.Log != nil {
+		b.Log(msg)
+	}
+}
+
+// FormatAnswer appends numbered source links to an answer
+func FormatAnswer(answer string, sources []string) string {
+	answer = strings.TrimSpace(answer)
+	if len(sources) == 0 {
+		return answer
+	}
+
+	var sb strings.Builder
+	sb.WriteString(answer)
+	sb.WriteString("\n\nSources:\n")
+	for i, url := range sources {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, url))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// SplitMessage breaks text into pieces of at most limit characters (runes),
+// preferring paragraph and line boundaries
+func SplitMessage(text string, limit int) []string {
+	var parts []string
+	for {
+		// end is the byte offset just past the limit-th rune
+		end, n := len(text), 0
+		for i := range text {
+			if n == limit {
+				end = i
+				break
+			}
+			n++
+		}
+		if end == len(text) {
+			break
+		}
+
+		cut := strings.LastIndex(text[:end], "\n\n")
+		if cut < end/2 {
+			cut = strings.LastIndex(text[:end], "\n")
+		}
+		if cut < end/2 {
+			cut = strings.LastIndex(text[:end], " ")
+		}
+		if cut <= 0 {
+			cut = end
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
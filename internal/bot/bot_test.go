@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// fakePlatform delivers scripted messages and records replies
+type fakePlatform struct {
+	messages []Incoming
+
+	mu      sync.Mutex
+	replies map[string][]string
+}
+
+func (p *fakePlatform) Name() string { return "fake" }
+
+func (p *fakePlatform) Receive(ctx context.Context, out chan<- Incoming) error {
+	for _, msg := range p.messages {
+		select {
+		case out <- msg:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (p *fakePlatform) Send(ctx context.Context, chatID, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.replies == nil {
+		p.replies = make(map[string][]string)
+	}
+	p.replies[chatID] = append(p.replies[chatID], text)
+	return nil
+}
+
+func (p *fakePlatform) Typing(ctx context.Context, chatID string) {}
+
+func (p *fakePlatform) count(chatID string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.replies[chatID])
+}
+
+func TestBotAnswersOnlyAllowedChats(t *testing.T) {
+	platform := &fakePlatform{messages: []Incoming{
+		{ChatID: "42", Text: "/help"},
+		{ChatID: "666", Text: "/help"},
+		{ChatID: "42", Text: "/start"},
+	}}
+	var logged []string
+	b := &Bot{
+		Platform: platform,
+		Allowed:  map[string]bool{"42": true},
+		Workers:  2,
+		Log:      func(s string) { logged = append(logged, s) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for platform.count("42") < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := platform.count("42"); n != 2 {
+		t.Errorf("allowed chat got %d replies, want 2", n)
+	}
+	if n := platform.count("666"); n != 0 {
+		t.Errorf("other chat got %d replies, want none", n)
+	}
+	if len(logged) != 1 {
+		t.Errorf("logged %q, want the ignored chat once", logged)
+	}
+}
+
+func TestDispatchBoundsWaitingMessages(t *testing.T) {
+	b := &Bot{Platform: &fakePlatform{}}
+	ready := make(chan string, 1)
+
+	for i := 0; i < maxChatBacklog; i++ {
+		if reply := b.dispatch(Incoming{ChatID: "1", Text: "q"}, ready); reply != "" {
+			t.Fatalf("message %d refused: %s", i, reply)
+		}
+	}
+	if reply := b.dispatch(Incoming{ChatID: "1", Text: "q"}, ready); reply == "" {
+		t.Error("a chat's backlog was not bounded")
+	}
+	if reply := b.dispatch(Incoming{ChatID: "2", Text: "q"}, ready); reply == "" {
+		t.Error("a chat was queued with no room for it")
+	}
+	if len(ready) != 1 {
+		t.Errorf("%d chats handed to workers, want 1", len(ready))
+	}
+}
+
+func TestSplitMessageCountsRunes(t *testing.T) {
+	// No whitespace to break at, and every character is several bytes
+	text := strings.Repeat("日本語", 5)
+
+	parts := SplitMessage(text, 4)
+	if strings.Join(parts, "") != text {
+		t.Fatalf("parts %q do not add up to the text", parts)
+	}
+	for _, part := range parts {
+		if !utf8.ValidString(part) || utf8.RuneCountInString(part) > 4 {
+			t.Errorf("part %q is not at most 4 whole characters", part)
+		}
+	}
+	if len(parts) != 4 {
+		t.Errorf("got %d parts, want 4", len(parts))
+	}
+}
@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// telegramLimit is the maximum length of a Telegram message, in characters
+const telegramLimit = 4096
+
+// Telegram talks to the Telegram Bot API using long polling
+type Telegram struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewTelegram creates a Telegram platform for a bot token from @BotFather
+func NewTelegram(token string) *Telegram {
+	return &Telegram{
+		token:   token,
+		baseURL: "https://api.telegram.org",
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second, // longer than the long-poll timeout
+		},
+	}
+}
+
+// Name implements Platform
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		From struct {
+			FirstName string `json:"first_name"`
+			Username  string `json:"username"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+// Receive implements Platform using getUpdates long polling
+func (t *Telegram) Receive(ctx context.Context, out chan<- Incoming) error {
+	var offset int64
+	failures := 0
+
+	for ctx.Err() == nil {
+		params := url.Values{}
+		params.Set("timeout", "30")
+		params.Set("offset", strconv.FormatInt(offset, 10))
+		params.Set("allowed_updates", `["message"]`)
+
+		var updates []telegramUpdate
+		if err := t.call(ctx, "getUpdates?"+params.Encode(), nil, &updates); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			failures++
+			if failures >= 5 {
+				return fmt.Errorf("telegram polling failed repeatedly: %w", err)
+			}
+			time.Sleep(time.Duration(failures) * 2 * time.Second)
+			continue
+		}
+		failures = 0
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || u.Message.Text == "" {
+				continue
+			}
+			from := u.Message.From.Username
+			if from == "" {
+				from = u.Message.From.FirstName
+			}
+			select {
+			case out <- Incoming{ChatID: strconv.FormatInt(u.Message.Chat.ID, 10), From: from, Text: u.Message.Text}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Send implements Platform, splitting long answers across messages
+func (t *Telegram) Send(ctx context.Context, chatID string, text string) error {
+	for _, part := range SplitMessage(text, telegramLimit) {
+		body := map[string]interface{}{
+			"chat_id":                  chatID,
+			"text":                     part,
+			"disable_web_page_preview": true,
+		}
+		if err := t.call(ctx, "sendMessage", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Typing implements Platform
+func (t *Telegram) Typing(ctx context.Context, chatID string) {
+	t.call(ctx, "sendChatAction", map[string]interface{}{"chat_id": chatID, "action": "typing"}, nil)
+}
+
+// call invokes a Bot API method, POSTing body as JSON when non-nil
+func (t *Telegram) call(ctx context.Context, method string, body interface{}, result interface{}) error {
+	endpoint := fmt.Sprintf("%s/bot%s/%s", t.baseURL, t.token, method)
+
+	httpMethod := "GET"
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		httpMethod = "POST"
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		// Strip the URL, which contains the bot token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to parse telegram response: %w", err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s: %s", method, envelope.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoCachePrefix at the start of a question bypasses the answer cache
+const NoCachePrefix = "/nocache"
+
+// maxEntries bounds the cache file; the oldest entries are dropped first
+const maxEntries = 500
+
+// Entry is a cached final answer
+type Entry struct {
+	Query     string    `json:"query"`
+	Model     string    `json:"model"`
+	Answer    string    `json:"answer"`
+	Sources   []string  `json:"sources,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Answers is a persistent cache of final answers with a time-to-live
+type Answers struct {
+	filePath string
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]Entry
+}
+
+// NewAnswers creates an answer cache backed by a JSON file
+func NewAnswers(filePath string, ttl time.Duration) *Answers {
+	return &Answers{
+		filePath: filePath,
+		ttl:      ttl,
+		entries:  make(map[string]Entry),
+	}
+}
+
+// Load reads the cache from disk; a missing file yields an empty cache
+func (c *Answers) Load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read answer cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return fmt.Errorf("failed to parse answer cache: %w", err)
+	}
+	return nil
+}
+
+// Get returns the unexpired entry for key
+func (c *Answers) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.CreatedAt) > c.ttl {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Put stores an entry under key and saves the cache
+func (c *Answers) Put(key string, entry Entry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+	c.pruneUnlocked()
+	return c.saveUnlocked()
+}
+
+// pruneUnlocked drops expired entries and caps the size (must be called with lock held)
+func (c *Answers) pruneUnlocked() {
+	for key, entry := range c.entries {
+		if time.Since(entry.CreatedAt) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) <= maxEntries {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].CreatedAt.Before(c.entries[keys[j]].CreatedAt)
+	})
+	for _, key := range keys[:len(keys)-maxEntries] {
+		delete(c.entries, key)
+	}
+}
+
+// saveUnlocked writes the cache atomically (must be called with lock held)
+func (c *Answers) saveUnlocked() error {
+	if err := os.MkdirAll(filepath.Dir(c.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer cache: %w", err)
+	}
+
+	tempPath := c.filePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, c.filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// Key identifies an answer by normalized query, model, answer mode and the
+// explicit sources it was asked about, such as a document or a client's
+// system prompt, so answers about different material never share an entry.
+// Web search results are not part of it: the key is known before searching,
+// so a hit skips grounding entirely and the TTL alone bounds how stale an
+// answer can get
+func Key(query, model, mode string, sources []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", Normalize(query), model, mode)
+	for _, source := range sources {
+		fmt.Fprintf(h, "\x00%d:%s", len(source), source)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Normalize lowercases a query, collapses whitespace and drops trailing
+// punctuation so trivially different phrasings share a cache entry
+func Normalize(query string) string {
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	return strings.TrimRight(query, "?!. ")
+}
+
+// StripNoCache removes a leading /nocache from a question and reports whether it was present
+func StripNoCache(query string) (string, bool) {
+	trimmed := strings.TrimSpace(query)
+	rest, ok := strings.CutPrefix(trimmed, NoCachePrefix)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '\n') {
+		return query, false
+	}
+	return strings.TrimSpace(rest), true
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyNormalizesQuery(t *testing.T) {
+	if Key("What is Go?", "llama3", "", nil) != Key("  what   is go ", "llama3", "", nil) {
+		t.Error("equivalent queries should share a key")
+	}
+	if Key("what is go", "llama3", "", nil) == Key("what is go", "qwen3", "", nil) {
+		t.Error("different models should not share a key")
+	}
+	if Key("what is go", "llama3", "", nil) == Key("what is go", "llama3", "style=concise", nil) {
+		t.Error("different answer modes should not share a key")
+	}
+}
+
+func TestKeySeparatesExplicitSources(t *testing.T) {
+	web := Key("summarize this", "llama3", "", nil)
+	report := Key("summarize this", "llama3", "", []string{"/docs/report.pdf"})
+	notes := Key("summarize this", "llama3", "", []string{"/docs/notes.md"})
+	if report == notes {
+		t.Error("questions about different documents should not share a key")
+	}
+	if report == web || notes == web {
+		t.Error("questions about a document should not share a key with web answers")
+	}
+	if Key("q", "llama3", "", []string{"a", "b"}) == Key("q", "llama3", "", []string{"a\x00b"}) {
+		t.Error("sources should not run together")
+	}
+}
+
+func TestStripNoCache(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		noCache bool
+	}{
+		{"/nocache what is new in Go?", "what is new in Go?", true},
+		{"  /nocache\tlatest news", "latest news", true},
+		{"/nocache", "", true},
+		{"/nocaches are bad", "/nocaches are bad", false},
+		{"what is /nocache", "what is /nocache", false},
+	}
+	for _, tt := range tests {
+		got, noCache := StripNoCache(tt.in)
+		if got != tt.want || noCache != tt.noCache {
+			t.Errorf("StripNoCache(%q) = %q, %v; want %q, %v", tt.in, got, noCache, tt.want, tt.noCache)
+		}
+	}
+}
+
+func TestAnswersPersistAndExpire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.json")
+
+	c := NewAnswers(path, time.Hour)
+	if err := c.Put("fresh", Entry{Query: "q", Answer: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("stale", Entry{Query: "q", Answer: "old", CreatedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewAnswers(path, time.Hour)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := reloaded.Get("fresh"); !ok || entry.Answer != "a" {
+		t.Errorf("Get(fresh) = %+v, %v", entry, ok)
+	}
+	if _, ok := reloaded.Get("stale"); ok {
+		t.Error("expired entry should not be returned")
+	}
+	if _, ok := reloaded.Get("missing"); ok {
+		t.Error("unknown key should miss")
+	}
+}
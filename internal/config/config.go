@@ -1,35 +1,275 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"web-ollama/internal/selector"
 )
 
 // Config holds all application configuration
 type Config struct {
 	// Ollama settings
-	OllamaURL     string
-	ModelName     string
-	OllamaTimeout time.Duration
+	OllamaURL     string        `json:"ollama_url"`
+	ModelName     string        `json:"model"`
+	AnalyzerModel string        `json:"analyzer_model"` // small, fast model for search decisions, e.g. qwen2.5:0.5b; "" uses model
+	OllamaTimeout time.Duration `json:"-"`              // decoded by UnmarshalJSON
+
+	OllamaFallbackURLs []string      `json:"ollama_fallback_urls"`  // more Ollama servers, used when ollama_url is down or for round_robin
+	OllamaBalance      string        `json:"ollama_balance"`        // failover (ollama_url first, then the fallbacks in order) or round_robin
+	OllamaMaxLineBytes int           `json:"ollama_max_line_bytes"` // longest streamed response line accepted
+	OllamaMaxAttempts  int           `json:"ollama_max_attempts"`   // tries per chat request when Ollama fails transiently (reset connection, timeout, 5xx)
+	OllamaRetryDelay   time.Duration `json:"-"`                     // wait before the first retry, doubled with jitter for each one after
+	KeepAlive          string        `json:"keep_alive"`            // how long the model stays loaded between turns, e.g. "30m"; "0" unloads it, "-1m" keeps it; "" is Ollama's default
+	StartupBudget      time.Duration `json:"-"`                     // wall-clock limit on the Ollama, model and SearXNG checks run together at startup
+	Warmup             bool          `json:"warmup"`                // load the model at startup, so the first question doesn't wait for it
+
+	// Ollama behind an authenticating proxy (nginx basic auth, Cloudflare
+	// Access). These go to ollama_url only; fallback servers take
+	// user:password@ in their URL or ollama_host_headers.
+	OllamaAuth        string                       `json:"-"`                   // bearer token or user:password from --ollama-auth; "" uses the ollama secret (OLLAMA_API_KEY)
+	OllamaHeaders     map[string]string            `json:"ollama_headers"`      // extra headers for every request to ollama_url; $VARS in values are read from the environment
+	OllamaHostHeaders map[string]map[string]string `json:"ollama_host_headers"` // extra headers per server URL, e.g. for ollama_fallback_urls; $VARS as above
 
 	// SearXNG settings
-	SearXNGURL    string
-	SearchTimeout time.Duration
-	MaxResults    int
+	SearXNGURL    string        `json:"searxng_url"`
+	SearchTimeout time.Duration `json:"-"`
+	MaxResults    int           `json:"max_results"`
 
 	// Crawler settings
-	CrawlTimeout   time.Duration
-	MaxCrawlers    int
-	MaxContentSize int64
-	UserAgent      string
+	CrawlTimeout   time.Duration      `json:"-"`
+	MaxCrawlers    int                `json:"max_crawlers"`     // crawl workers, or where auto-tuning starts
+	MaxContentSize int64              `json:"max_content_size"` // bytes read per page; pages are parsed as they stream, not buffered
+	UserAgent      string             `json:"user_agent"`
+	BrowserHeaders bool               `json:"browser_headers"` // send a rotating browser user agent, Accept-Language and Referer instead of user_agent
+	BrowserAgents  []string           `json:"browser_agents"`  // user agents rotated with browser_headers; empty uses a built-in list
+	AcceptLanguage string             `json:"accept_language"` // Accept-Language sent with browser_headers
+	BlockedDomains []string           `json:"blocked_domains"` // never crawl these hosts or their subdomains
+	AllowedDomains []string           `json:"allowed_domains"` // research sandbox: when set, crawl only these hosts and their subdomains
+	SourceTrust    map[string]float64 `json:"source_trust"`    // domain or suffix (e.g. "gov") → weight for crawl ranking and prompt share; 1 is neutral, 0 never crawls
+	TurnBudget     time.Duration      `json:"-"`               // wall-clock limit on analysis, search and crawl per question; 0 means none
+	SelectSources  bool               `json:"select_sources"`  // list search results and ask which to crawl (chat only)
+
+	ExtractionRules map[string]ExtractionRule `json:"extraction_rules"` // domain → CSS selectors to keep and drop on its HTML pages, for sites the default extraction reads badly
+
+	TurnDownloadLimit int64 `json:"turn_download_limit"` // bytes crawled per question before further pages are skipped; 0 means none
+
+	// Crawl auto-tuning: grow the worker count while fetches are fast and
+	// halve it on timeouts, throttling or slowdowns
+	CrawlAutoTune   bool `json:"crawl_auto_tune"`
+	CrawlWorkersMax int  `json:"crawl_workers_max"` // ceiling for auto-tuning
+
+	// Archive settings
+	Archive       bool   `json:"archive"`        // snapshot every crawled page
+	ArchiveDir    string `json:"archive_dir"`    // snapshots go to <archive_dir>/<domain>/<date>/
+	ArchiveFormat string `json:"archive_format"` // markdown or warc
+
+	// Guardrail settings
+	BlockCategories []string `json:"block_categories"` // built-in domain lists: adult, malware
+	BlockLists      []string `json:"block_lists"`      // hosts-format files of domains to block
+	StripInjections bool     `json:"strip_injections"` // remove instruction-like text from crawled pages
+
+	// Hostile domains: sites that keep answering 403, a captcha or a paywall
+	// are demoted, then skipped for a cooldown; "" disables the tracking
+	HostileDomainsPath string        `json:"hostile_domains_path"`
+	HostileStrikes     int           `json:"hostile_strikes"` // failures in a row before a domain is skipped
+	HostileCooldown    time.Duration `json:"-"`               // how long a domain is skipped
+
+	// Scheduler settings, shared by all modes; 0 means unlimited
+	OllamaConcurrency int           `json:"ollama_concurrency"` // concurrent requests per Ollama server, including open streams
+	SearchConcurrency int           `json:"search_concurrency"` // concurrent SearXNG requests
+	CrawlConcurrency  int           `json:"crawl_concurrency"`  // concurrent page fetches across all sites
+	CrawlPerHost      int           `json:"crawl_per_host"`     // concurrent page fetches per site
+	CrawlHostDelay    time.Duration `json:"-"`                  // minimum gap between fetches from one site
+	ChatConcurrency   int           `json:"chat_concurrency"`   // questions answered at once in serve and bot modes
+	ChatQueueDepth    int           `json:"chat_queue_depth"`   // questions that may wait for their turn; more are refused
+
+	// Bot settings
+	AllowedChatIDs []int64 `json:"allowed_chat_ids"` // Telegram chats the bot answers; it ignores all others
+
+	// Prompt settings
+	ContextTokens   int    `json:"context_tokens"`   // model context window (num_ctx)
+	AnswerTokens    int    `json:"answer_tokens"`    // part of the window kept free for the answer
+	OverflowAction  string `json:"overflow_action"`  // when a chat turn does not fit: ask, trim, drop_sources or summarize
+	AnswerStyle     string `json:"answer_style"`     // default, concise, detailed or bullet; sets the prompt and answer length
+	HistoryMessages int    `json:"history_messages"` // earlier messages sent with each question; 0 sizes it to the context window
+	AnswerFormat    string `json:"answer_format"`    // "" for text, json, or json-schema=<file> for JSON matching a schema
+
+	// Sampling settings, sent to Ollama with every answer; unset keeps the
+	// model's own defaults
+	Temperature *float64 `json:"temperature"` // 0 always picks the likeliest token, higher is more varied
+	TopP        *float64 `json:"top_p"`       // sample only from the likeliest tokens covering this share
+	Seed        *int     `json:"seed"`        // fixed seed for reproducible answers
+
+	// Source summarization: condense each crawled page with a fast model
+	// when the pages would take more than half the prompt budget
+	SummarizeSources bool   `json:"summarize_sources"`
+	SummaryModel     string `json:"summary_model"` // "" uses model
+	SummaryWords     int    `json:"summary_words"` // target length of each summary
+
+	// Source translation: put pages written in other languages into
+	// English before they are quoted
+	TranslateSources bool   `json:"translate_sources"`
+	TranslateModel   string `json:"translate_model"` // "" uses model; a dedicated model such as aya or qwen2.5 translates better
+	TranslateMax     int    `json:"translate_max"`   // foreign pages translated per search, most relevant first
+
+	// Contested questions: group the sources by the view they take, with
+	// embed_model, and have the answer set out each view with attribution
+	Perspectives bool `json:"perspectives"`
+
+	// Conflict checks: ask whether an answer contradicts a source trusted
+	// at least conflict_trust in source_trust, and when it does let the user
+	// regenerate it (chat only)
+	CheckConflicts bool    `json:"check_conflicts"`
+	ConflictTrust  float64 `json:"conflict_trust"` // source_trust weight from which a source is checked against
+	ConflictModel  string  `json:"conflict_model"` // "" uses model
+
+	// Answer post-processing, applied before display and storage
+	StripThinkTags     bool            `json:"strip_think_tags"`    // remove leftover <think> blocks
+	NormalizeCitations bool            `json:"normalize_citations"` // rewrite [^1], 【1】, [1, 2] and similar as [1]
+	CitationStyle      string          `json:"citation_style"`      // inline ([1]), footnote ([^1]) or apa ((site, n.d.) and a reference list)
+	MaxAnswerChars     int             `json:"max_answer_chars"`    // cut longer answers; 0 means no limit
+	AnswerRewrites     []AnswerRewrite `json:"answer_rewrites"`     // regex replacements, applied in order
+	TLDR               bool            `json:"tldr"`                // in chat, ask for a 2-3 sentence TL;DR after long answers, shown above the sources
 
 	// History settings
-	HistoryPath    string
-	MaxHistorySize int
+	HistoryPath    string        `json:"history_path"`
+	MaxHistorySize int           `json:"max_history_size"`
+	SharedHistory  bool          `json:"shared_history"` // let several instances use history_path at once, merging their sessions on save
+	PagesDir       string        `json:"pages_dir"`      // compressed copies of the pages each answer used; "" disables them
+	PageReuseAge   time.Duration `json:"-"`              // how long a saved page is reused in place of a new crawl; 0 means for the whole session
+
+	// SessionSummaries writes a one-paragraph summary of each chat session
+	// when it is closed, shown by /sessions and used to prime /resume.
+	// Summaries use summary_model when set.
+	SessionSummaries bool `json:"session_summaries"`
+
+	// Answer cache settings
+	AnswerCachePath string        `json:"answer_cache_path"`
+	AnswerCacheTTL  time.Duration `json:"-"` // 0 disables the cache
+
+	// Snippet cache: recent search results, used when SearXNG is down; "" disables it
+	SnippetCachePath string `json:"snippet_cache_path"`
+
+	// HTTP cache shared by the crawler and SearXNG clients, following the
+	// servers' caching headers; "" disables it
+	HTTPCacheDir          string        `json:"http_cache_dir"`
+	HTTPCacheMaxSize      int64         `json:"http_cache_max_size"` // bytes on disk; least recently used responses go first
+	HTTPCacheHeuristicTTL time.Duration `json:"-"`                   // freshness of responses without an expiry or Last-Modified
+
+	// Knowledge base settings
+	KnowledgeBase     bool    `json:"knowledge_base"`      // index crawled pages and retrieve from them
+	KnowledgePath     string  `json:"knowledge_path"`      // vector store file
+	EmbedModel        string  `json:"embed_model"`         // embedding model, as named by embed_provider
+	EmbedProvider     string  `json:"embed_provider"`      // ollama, llamacpp or openai (any OpenAI-compatible embeddings API)
+	EmbedURL          string  `json:"embed_url"`           // base URL for llamacpp and openai, e.g. "http://localhost:8080/v1"; "" uses the provider's usual one
+	KnowledgeTopK     int     `json:"knowledge_top_k"`     // chunks retrieved per question
+	KnowledgeMinScore float64 `json:"knowledge_min_score"` // minimum cosine similarity to include a chunk
+
+	// Research settings
+	ResearchDir          string `json:"research_dir"`           // where /research reports are saved
+	ResearchRounds       int    `json:"research_rounds"`        // search rounds per subtopic
+	ResearchMaxSubtopics int    `json:"research_max_subtopics"` // cap on planned subtopics
+	SiteCrawlPages       int    `json:"site_crawl_pages"`       // pages /crawl-site reads from a site's sitemap
+
+	// Notes settings
+	NotesDir  string   `json:"notes_dir"`  // Obsidian vault (or subfolder) for /save-note
+	NotesTags []string `json:"notes_tags"` // tags added to every saved note
+
+	// Consensus settings
+	ConsensusModels []string `json:"consensus_models"` // models asked by /consensus; the main model reconciles
+
+	// Tools the model may call while answering
+	CodeExecution bool          `json:"code_execution"` // let the model run Python/Go snippets in a sandbox
+	WebTools      bool          `json:"web_tools"`      // let the model search the web and fetch pages while answering
+	CodeTimeout   time.Duration `json:"-"`              // wall-clock limit per snippet
+
+	// Recording settings
+	RecordingsDir string `json:"recordings_dir"` // default location for /record files
+
+	// Watch settings
+	WatchDir string `json:"watch_dir"` // state of `web-ollama watch` queries
+
+	// Server settings
+	ServeAddr    string `json:"serve_addr"`    // listen address for `web-ollama serve`
+	OTLPEndpoint string `json:"otlp_endpoint"` // OTLP/HTTP collector for traces, e.g. http://localhost:4318
+	DaemonSocket string `json:"daemon_socket"` // UNIX socket of `web-ollama daemon`, used by `web-ollama ask`
+
+	// Prompt settings
+	SystemPrompt string   `json:"system_prompt"` // text/template, see prompt.SystemVars
+	MemoryFacts  []string `json:"memory_facts"`  // facts about the user made available to the prompt
+
+	// Sinks receive every completed answer
+	Sinks []SinkConfig `json:"sinks"`
+
+	// Secrets maps provider names (brave, tavily, github, ...) to where their
+	// credentials live. Values are never stored in the config file itself.
+	Secrets map[string]SecretRef `json:"secrets"`
+
+	// Cost estimates, shown after each answer with show_cost and in /stats
+	ShowCost         bool                  `json:"show_cost"`
+	LocalWatts       float64               `json:"local_watts"`       // power drawn while a local model runs
+	ElectricityPrice float64               `json:"electricity_price"` // per kWh
+	ModelPrices      map[string]ModelPrice `json:"model_prices"`      // cloud models, by name or name without the tag
+
+	// Display settings
+	Theme string `json:"theme"` // markdown style: auto, dark, light, notty
+
+	// Logging settings
+	LogLevel string `json:"log_level"` // off, debug, info, warn or error
+	LogDir   string `json:"log_dir"`   // daily log files are written here
 
 	// Feature flags
-	AutoSearch bool
-	Verbose    bool
+	AutoSearch bool   `json:"auto_search"`
+	CarryFiles bool   `json:"carry_files"` // quote @files again when a follow-up refers to them
+	Offline    bool   `json:"offline"`     // search and read pages only from the snippet cache, archive and saved pages
+	Verbose    bool   `json:"verbose"`
+	DryRun     bool   `json:"-"` // show the assembled prompt instead of asking the model
+	TeePath    string `json:"-"` // stream each answer to this file as it is generated
+
+	// ConfigPath is the file the settings were loaded from
+	ConfigPath string `json:"-"`
+}
+
+// SinkConfig describes one destination for completed answers
+type SinkConfig struct {
+	Type    string `json:"type"`    // file, webhook or command
+	Path    string `json:"path"`    // file: where to append
+	Format  string `json:"format"`  // file: jsonl or markdown (default from extension)
+	URL     string `json:"url"`     // webhook: endpoint to POST to
+	Command string `json:"command"` // command: run with sh -c, record JSON on stdin
+}
+
+// AnswerRewrite replaces every match of a regular expression in an answer.
+// Replace may refer to groups as $1 or ${name}.
+type AnswerRewrite struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// SecretRef names the env var and/or OS keychain account holding a credential
+type SecretRef struct {
+	Env      string `json:"env"`
+	Keychain string `json:"keychain"`
+}
+
+// ModelPrice is what a cloud model charges per million tokens
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ExtractionRule picks the content of a site's pages by CSS selector
+type ExtractionRule struct {
+	Keep []string `json:"keep"` // elements holding the content; none keeps the whole page
+	Drop []string `json:"drop"` // elements removed first, such as sidebars or comment forms
 }
 
 // NewConfig creates a new configuration with default values
@@ -40,6 +280,13 @@ func NewConfig() *Config {
 		ModelName:     "deepseek-r1:8b",
 		OllamaTimeout: 600 * time.Second, // 10 minutes for large contexts
 
+		OllamaBalance:      "failover",
+		OllamaMaxLineBytes: 8 << 20, // 8 MB
+		OllamaMaxAttempts:  3,
+		OllamaRetryDelay:   time.Second,
+		StartupBudget:      6 * time.Second,
+		Warmup:             true,
+
 		// SearXNG defaults
 		SearXNGURL:    "http://localhost:9090",
 		SearchTimeout: 10 * time.Second,
@@ -50,34 +297,683 @@ func NewConfig() *Config {
 		MaxCrawlers:    5,
 		MaxContentSize: 5 * 1024 * 1024, // 5 MB
 		UserAgent:      "web-ollama/1.0",
+		AcceptLanguage: "en-US,en;q=0.9",
+
+		// Crawl auto-tuning defaults
+		CrawlAutoTune:   true,
+		CrawlWorkersMax: 10,
+
+		// Scheduler defaults
+		OllamaConcurrency: 2,
+		SearchConcurrency: 4,
+		CrawlConcurrency:  10,
+		CrawlPerHost:      2,
+		CrawlHostDelay:    250 * time.Millisecond,
+		ChatConcurrency:   1,
+		ChatQueueDepth:    10,
+
+		// Archive defaults
+		ArchiveDir:    expandHome("~/.web-ollama/archive"),
+		ArchiveFormat: "markdown",
+
+		// Guardrail defaults
+		BlockCategories: []string{"adult", "malware"},
+		StripInjections: true,
+
+		// Hostile domain defaults
+		HostileDomainsPath: expandHome("~/.web-ollama/hostile-domains.json"),
+		HostileStrikes:     3,
+		HostileCooldown:    24 * time.Hour,
+
+		// Prompt defaults
+		ContextTokens:  32768,
+		AnswerTokens:   4096,
+		OverflowAction: "ask",
+		AnswerStyle:    "default",
+
+		// Summarization defaults
+		SummaryWords: 150,
+		TranslateMax: 2,
+		Perspectives: true,
+
+		ConflictTrust: 1.5,
+
+		// Cost defaults: a desktop GPU under load, a typical household rate
+		// and hosted gpt-oss list prices
+		LocalWatts:       300,
+		ElectricityPrice: 0.15,
+		ModelPrices: map[string]ModelPrice{
+			"gpt-oss:20b-cloud":  {Input: 0.05, Output: 0.20},
+			"gpt-oss:120b-cloud": {Input: 0.15, Output: 0.60},
+		},
+
+		// Post-processing defaults
+		StripThinkTags:     true,
+		NormalizeCitations: true,
+		CitationStyle:      "inline",
 
 		// History defaults
 		HistoryPath:    expandHome("~/.web-ollama/history.json"),
 		MaxHistorySize: 10,
+		PagesDir:       expandHome("~/.web-ollama/pages"),
+		PageReuseAge:   6 * time.Hour,
+
+		SessionSummaries: true,
+
+		// Answer cache defaults
+		AnswerCachePath: expandHome("~/.web-ollama/answer-cache.json"),
+		AnswerCacheTTL:  0,
+
+		// Snippet cache defaults
+		SnippetCachePath: expandHome("~/.web-ollama/snippet-cache.json"),
+
+		// HTTP cache defaults
+		HTTPCacheDir:          expandHome("~/.web-ollama/http-cache"),
+		HTTPCacheMaxSize:      256 << 20,
+		HTTPCacheHeuristicTTL: 10 * time.Minute,
+
+		// Knowledge base defaults
+		KnowledgeBase:     false,
+		KnowledgePath:     expandHome("~/.web-ollama/knowledge.json"),
+		EmbedModel:        "nomic-embed-text",
+		EmbedProvider:     "ollama",
+		KnowledgeTopK:     4,
+		KnowledgeMinScore: 0.5,
+
+		// Research defaults
+		ResearchDir:          expandHome("~/.web-ollama/research"),
+		ResearchRounds:       2,
+		ResearchMaxSubtopics: 5,
+		SiteCrawlPages:       15,
+
+		// Notes defaults
+		NotesDir:  expandHome("~/.web-ollama/notes"),
+		NotesTags: []string{"web-ollama"},
+
+		// Tool defaults
+		CodeExecution: false,
+		WebTools:      false,
+		CodeTimeout:   10 * time.Second,
+
+		// Recording defaults
+		RecordingsDir: expandHome("~/.web-ollama/recordings"),
+
+		// Watch defaults
+		WatchDir: expandHome("~/.web-ollama/watch"),
+
+		// Server defaults
+		ServeAddr:    "127.0.0.1:8080",
+		DaemonSocket: expandHome("~/.web-ollama/daemon.sock"),
+
+		// Prompt defaults
+		SystemPrompt: DefaultSystemPrompt,
+
+		// Display defaults
+		Theme: "auto",
+
+		// Logging defaults
+		LogLevel: "off",
+		LogDir:   expandHome("~/.web-ollama/logs"),
 
 		// Feature flags
 		AutoSearch: true,
+		CarryFiles: true,
 		Verbose:    false,
 	}
 }
 
-// Validate checks if the configuration is valid
+// DefaultSystemPrompt reproduces the built-in assistant prompt as a template
+const DefaultSystemPrompt = `You are a helpful AI assistant.
+{{- if .HasSearchContext}} You have access to current web information to answer questions accurately. Cite sources when referencing specific information.{{end}}
+{{- if .HasKnowledgeContext}} Excerpts from the user's knowledge base are included; use them when relevant and cite their sources.{{end}}
+{{- if .HasFileContext}} The user has provided file contents that you MUST read and analyze carefully. Base your answer on the ACTUAL contents of the files provided, not on assumptions or general knowledge.{{end}}
+{{- if .MemoryFacts}}
+
+Things you know about the user:
+{{- range .MemoryFacts}}
+- {{.}}
+{{- end}}
+{{- end}}`
+
+// ModelOptions returns the Ollama options for an answer: the context window
+// and any sampling settings
+func (c *Config) ModelOptions() map[string]interface{} {
+	options := map[string]interface{}{
+		"num_ctx": c.ContextTokens,
+	}
+	if c.Temperature != nil {
+		options["temperature"] = *c.Temperature
+	}
+	if c.TopP != nil {
+		options["top_p"] = *c.TopP
+	}
+	if c.Seed != nil {
+		options["seed"] = *c.Seed
+	}
+	return options
+}
+
+// DefaultConfigPath returns the location of the optional JSON config file
+func DefaultConfigPath() string {
+	return expandHome("~/.web-ollama/config.json")
+}
+
+// LoadFile overlays settings from a JSON config file onto the configuration.
+// A missing file is not an error; keys absent from the file keep their values.
+func (c *Config) LoadFile(path string) error {
+	c.ConfigPath = path
+
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Normalize expands ~ in every path, whether it came from the config file
+// or a flag. Call it once all settings are applied and before Validate,
+// which only reports problems.
+func (c *Config) Normalize() {
+	c.HistoryPath = expandHome(c.HistoryPath)
+	c.AnswerCachePath = expandHome(c.AnswerCachePath)
+	c.SnippetCachePath = expandHome(c.SnippetCachePath)
+	c.HostileDomainsPath = expandHome(c.HostileDomainsPath)
+	c.HTTPCacheDir = expandHome(c.HTTPCacheDir)
+	c.PagesDir = expandHome(c.PagesDir)
+	c.ArchiveDir = expandHome(c.ArchiveDir)
+	c.KnowledgePath = expandHome(c.KnowledgePath)
+	c.ResearchDir = expandHome(c.ResearchDir)
+	c.WatchDir = expandHome(c.WatchDir)
+	c.DaemonSocket = expandHome(c.DaemonSocket)
+	c.RecordingsDir = expandHome(c.RecordingsDir)
+	c.NotesDir = expandHome(c.NotesDir)
+	c.LogDir = expandHome(c.LogDir)
+	for i := range c.Sinks {
+		c.Sinks[i].Path = expandHome(c.Sinks[i].Path)
+	}
+	for i := range c.BlockLists {
+		c.BlockLists[i] = expandHome(c.BlockLists[i])
+	}
+}
+
+// UnmarshalJSON decodes a config file, accepting durations as "15s" strings or plain seconds
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		OllamaTimeout         *fileDuration `json:"ollama_timeout"`
+		OllamaRetryDelay      *fileDuration `json:"ollama_retry_delay"`
+		SearchTimeout         *fileDuration `json:"search_timeout"`
+		CrawlTimeout          *fileDuration `json:"crawl_timeout"`
+		AnswerCacheTTL        *fileDuration `json:"answer_cache_ttl"`
+		CodeTimeout           *fileDuration `json:"code_timeout"`
+		CrawlHostDelay        *fileDuration `json:"crawl_host_delay"`
+		TurnBudget            *fileDuration `json:"turn_budget"`
+		StartupBudget         *fileDuration `json:"startup_budget"`
+		PageReuseAge          *fileDuration `json:"page_reuse_age"`
+		HostileCooldown       *fileDuration `json:"hostile_cooldown"`
+		HTTPCacheHeuristicTTL *fileDuration `json:"http_cache_heuristic_ttl"`
+	}{
+		plain:                 (*plain)(c),
+		OllamaTimeout:         (*fileDuration)(&c.OllamaTimeout),
+		OllamaRetryDelay:      (*fileDuration)(&c.OllamaRetryDelay),
+		SearchTimeout:         (*fileDuration)(&c.SearchTimeout),
+		CrawlTimeout:          (*fileDuration)(&c.CrawlTimeout),
+		AnswerCacheTTL:        (*fileDuration)(&c.AnswerCacheTTL),
+		CodeTimeout:           (*fileDuration)(&c.CodeTimeout),
+		CrawlHostDelay:        (*fileDuration)(&c.CrawlHostDelay),
+		TurnBudget:            (*fileDuration)(&c.TurnBudget),
+		StartupBudget:         (*fileDuration)(&c.StartupBudget),
+		PageReuseAge:          (*fileDuration)(&c.PageReuseAge),
+		HostileCooldown:       (*fileDuration)(&c.HostileCooldown),
+		HTTPCacheHeuristicTTL: (*fileDuration)(&c.HTTPCacheHeuristicTTL),
+	}
+	return json.Unmarshal(data, &aux)
+}
+
+// fileDuration is a time.Duration as written in the config file
+type fileDuration time.Duration
+
+// UnmarshalJSON parses "1m30s"-style strings or a number of seconds
+func (d *fileDuration) UnmarshalJSON(data []byte) error {
+	if seconds, err := strconv.ParseFloat(string(data), 64); err == nil {
+		*d = fileDuration(seconds * float64(time.Second))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15s\" or a number of seconds")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = fileDuration(parsed)
+	return nil
+}
+
+// Problem describes a single invalid configuration setting
+type Problem struct {
+	Field      string
+	Message    string
+	Suggestion string
+}
+
+// ValidationError collects every problem found in a configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error lists all problems, one per line, with their suggestions
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d configuration problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "\n  - %s: %s", p.Field, p.Message)
+		if p.Suggestion != "" {
+			fmt.Fprintf(&sb, " (%s)", p.Suggestion)
+		}
+	}
+	return sb.String()
+}
+
+// Validate checks if the configuration is valid and reports all problems at
+// once. It changes neither the configuration nor the filesystem.
 func (c *Config) Validate() error {
+	var problems []Problem
+	add := func(field, message, suggestion string) {
+		problems = append(problems, Problem{Field: field, Message: message, Suggestion: suggestion})
+	}
+
 	if c.OllamaURL == "" {
-		return fmt.Errorf("ollama URL cannot be empty")
+		add("ollama_url", "cannot be empty", "use --ollama-url http://localhost:11434")
+	} else if msg := checkURL(c.OllamaURL); msg != "" {
+		add("ollama_url", msg, "expected something like http://localhost:11434")
+	}
+	for _, u := range c.OllamaFallbackURLs {
+		if msg := checkURL(u); msg != "" {
+			add("ollama_fallback_urls", msg, "expected something like http://192.168.1.20:11434")
+		}
+	}
+	switch c.OllamaBalance {
+	case "", "failover", "round_robin":
+	default:
+		add("ollama_balance", fmt.Sprintf("unknown mode %q", c.OllamaBalance), "use failover or round_robin")
+	}
+	if c.SearXNGURL != "" {
+		if msg := checkURL(c.SearXNGURL); msg != "" {
+			add("searxng_url", msg, "expected something like http://localhost:9090")
+		}
+	}
+	if c.OTLPEndpoint != "" {
+		if msg := checkURL(c.OTLPEndpoint); msg != "" {
+			add("otlp_endpoint", msg, "expected something like http://localhost:4318")
+		}
 	}
 	if c.ModelName == "" {
-		return fmt.Errorf("model name cannot be empty")
+		add("model", "cannot be empty", "use --model deepseek-r1:8b or run 'ollama list'")
 	}
 	if c.MaxResults < 1 || c.MaxResults > 10 {
-		return fmt.Errorf("max results must be between 1 and 10")
+		add("max_results", fmt.Sprintf("must be between 1 and 10, got %d", c.MaxResults), "5 is a good default")
 	}
 	if c.MaxCrawlers < 1 {
-		return fmt.Errorf("max crawlers must be at least 1")
+		add("max_crawlers", fmt.Sprintf("must be at least 1, got %d", c.MaxCrawlers), "5 is a good default")
+	}
+	if c.CrawlAutoTune && c.CrawlWorkersMax < c.MaxCrawlers {
+		add("crawl_workers_max", fmt.Sprintf("%d is below max_crawlers (%d)", c.CrawlWorkersMax, c.MaxCrawlers), "raise it, or set crawl_auto_tune to false for a fixed worker count")
+	}
+	if c.MaxContentSize < 1024 {
+		add("max_content_size", fmt.Sprintf("%d bytes is too small to hold a web page", c.MaxContentSize), "5242880 (5 MB) is a good default")
+	}
+	for _, agent := range c.BrowserAgents {
+		if strings.TrimSpace(agent) == "" {
+			add("browser_agents", "contains an empty user agent", "remove it, or leave browser_agents empty for the built-in list")
+			break
+		}
+	}
+	for _, domain := range c.AllowedDomains {
+		if strings.TrimSpace(domain) == "" || strings.Contains(domain, "/") {
+			add("allowed_domains", fmt.Sprintf("%q is not a domain", domain), "list bare domains such as \"docs.python.org\"")
+			break
+		}
+	}
+	for domain, weight := range c.SourceTrust {
+		if weight < 0 || weight > 10 {
+			add("source_trust", fmt.Sprintf("weight %v for %s is outside 0 to 10", weight, domain), "use above 1 to prefer a domain, below 1 to demote it and 0 to skip it")
+		}
+	}
+	for domain, rule := range c.ExtractionRules {
+		if strings.TrimSpace(domain) == "" || strings.Contains(domain, "/") {
+			add("extraction_rules", fmt.Sprintf("%q is not a domain", domain), "use bare domains such as \"forum.example.com\"")
+		}
+		if len(rule.Keep) == 0 && len(rule.Drop) == 0 {
+			add("extraction_rules", fmt.Sprintf("the rule for %s has neither keep nor drop selectors", domain), "add e.g. \"keep\": [\"article\"] or remove the rule")
+		}
+		for _, sel := range append(append([]string{}, rule.Keep...), rule.Drop...) {
+			if _, err := selector.Compile(sel); err != nil {
+				add("extraction_rules", fmt.Sprintf("%s: %v", domain, err), "use tag, .class, #id and [attr=value] selectors joined by spaces or >")
+			}
+		}
+	}
+	if c.ContextTokens < 2048 {
+		add("context_tokens", fmt.Sprintf("must be at least 2048, got %d", c.ContextTokens), "32768 suits most recent models")
+	} else if c.AnswerTokens < 256 || c.AnswerTokens >= c.ContextTokens {
+		add("answer_tokens", fmt.Sprintf("must be between 256 and context_tokens, got %d", c.AnswerTokens), "about 1/8 of context_tokens works well")
+	}
+	if c.HistoryMessages < 0 {
+		add("history_messages", fmt.Sprintf("must not be negative, got %d", c.HistoryMessages), "0 sizes it to the context window")
+	}
+	if c.Temperature != nil && (*c.Temperature < 0 || *c.Temperature > 2) {
+		add("temperature", fmt.Sprintf("must be between 0 and 2, got %v", *c.Temperature), "0.7 is typical; use 0 for repeatable answers")
+	}
+	if c.TopP != nil && (*c.TopP <= 0 || *c.TopP > 1) {
+		add("top_p", fmt.Sprintf("must be above 0 and at most 1, got %v", *c.TopP), "0.9 is typical; remove it to keep the model's default")
+	}
+	switch c.OverflowAction {
+	case "ask", "trim", "drop_sources", "summarize":
+	default:
+		add("overflow_action", fmt.Sprintf("unknown action %q", c.OverflowAction), "use ask, trim, drop_sources or summarize")
+	}
+	switch c.AnswerStyle {
+	case "default", "concise", "detailed", "bullet":
+	default:
+		add("answer_style", fmt.Sprintf("unknown style %q", c.AnswerStyle), "use default, concise, detailed or bullet")
+	}
+	if c.AnswerFormat != "" && c.AnswerFormat != "json" {
+		path, ok := strings.CutPrefix(c.AnswerFormat, "json-schema=")
+		if !ok || path == "" {
+			add("answer_format", fmt.Sprintf("unknown format %q", c.AnswerFormat), "use json or json-schema=<file>")
+		} else if _, err := os.Stat(path); err != nil {
+			add("answer_format", fmt.Sprintf("cannot read the schema: %v", err), "point json-schema= at a JSON schema file")
+		}
+	}
+	switch c.CitationStyle {
+	case "inline", "footnote", "apa":
+	default:
+		add("citation_style", fmt.Sprintf("unknown style %q", c.CitationStyle), "use inline, footnote or apa")
+	}
+	if c.SummarizeSources && (c.SummaryWords < 20 || c.SummaryWords > 1000) {
+		add("summary_words", fmt.Sprintf("must be between 20 and 1000, got %d", c.SummaryWords), "150 is a good default")
+	}
+	if c.TranslateSources && (c.TranslateMax < 1 || c.TranslateMax > 10) {
+		add("translate_max", fmt.Sprintf("must be between 1 and 10, got %d", c.TranslateMax), "each translation is a model call, so 2 is a good default")
+	}
+	if c.CheckConflicts && c.ConflictTrust <= 0 {
+		add("conflict_trust", fmt.Sprintf("must be above 0, got %g", c.ConflictTrust), "sources at 1 are neutral; 1.5 checks only those raised in source_trust")
+	}
+	if c.LocalWatts < 0 || c.LocalWatts > 10000 {
+		add("local_watts", fmt.Sprintf("must be between 0 and 10000, got %g", c.LocalWatts), "300 suits a desktop with one GPU")
+	}
+	if c.ElectricityPrice < 0 {
+		add("electricity_price", fmt.Sprintf("must not be negative, got %g", c.ElectricityPrice), "use your rate per kWh, e.g. 0.15")
+	}
+	for name, price := range c.ModelPrices {
+		if price.Input < 0 || price.Output < 0 {
+			add("model_prices", fmt.Sprintf("%s has a negative price", name), "prices are per million tokens")
+		}
+	}
+	if c.MaxHistorySize < 1 {
+		add("max_history_size", fmt.Sprintf("must keep at least 1 session, got %d", c.MaxHistorySize), "10 is a good default")
+	}
+
+	checkTimeout := func(field string, d, min, max time.Duration) {
+		if d < min || d > max {
+			add(field, fmt.Sprintf("%v is outside the sane range %v to %v", d, min, max), fmt.Sprintf("try %q", clamp(d, min, max).String()))
+		}
+	}
+	checkTimeout("ollama_timeout", c.OllamaTimeout, 10*time.Second, 2*time.Hour)
+	if _, err := time.ParseDuration(c.KeepAlive); c.KeepAlive != "" && err != nil {
+		add("keep_alive", fmt.Sprintf("%q is not a duration", c.KeepAlive), "use e.g. \"30m\", \"0\" to unload after each answer or \"-1m\" to keep the model loaded")
+	}
+	if c.OllamaMaxAttempts < 1 || c.OllamaMaxAttempts > 10 {
+		add("ollama_max_attempts", fmt.Sprintf("must be between 1 and 10, got %d", c.OllamaMaxAttempts), "3 is a good default; 1 disables retries")
+	}
+	if c.OllamaRetryDelay < 0 || c.OllamaRetryDelay > time.Minute {
+		add("ollama_retry_delay", fmt.Sprintf("%s is outside 0 to 1m", c.OllamaRetryDelay), "1s is a good default")
+	}
+	checkTimeout("startup_budget", c.StartupBudget, time.Second, 2*time.Minute)
+	if c.OllamaMaxLineBytes < 64*1024 {
+		add("ollama_max_line_bytes", fmt.Sprintf("%d bytes is too small for streamed responses", c.OllamaMaxLineBytes), "8388608 (8 MB) is a good default")
+	}
+	checkTimeout("search_timeout", c.SearchTimeout, time.Second, 2*time.Minute)
+	checkTimeout("crawl_timeout", c.CrawlTimeout, time.Second, 5*time.Minute)
+	for field, n := range map[string]int{
+		"ollama_concurrency": c.OllamaConcurrency,
+		"search_concurrency": c.SearchConcurrency,
+		"crawl_concurrency":  c.CrawlConcurrency,
+		"crawl_per_host":     c.CrawlPerHost,
+		"chat_concurrency":   c.ChatConcurrency,
+		"chat_queue_depth":   c.ChatQueueDepth,
+	} {
+		if n < 0 {
+			add(field, fmt.Sprintf("cannot be negative, got %d", n), "use 0 for no limit")
+		}
+	}
+	if c.CrawlHostDelay < 0 || c.CrawlHostDelay > time.Minute {
+		add("crawl_host_delay", fmt.Sprintf("%v is outside the sane range 0s to 1m", c.CrawlHostDelay), `try "250ms"`)
+	}
+	if c.TurnBudget < 0 || c.TurnBudget > 10*time.Minute {
+		add("turn_budget", fmt.Sprintf("%v is outside the sane range 0s to 10m", c.TurnBudget), `try "20s", or 0 for no limit`)
+	}
+	if c.TurnDownloadLimit < 0 {
+		add("turn_download_limit", fmt.Sprintf("cannot be negative, got %d", c.TurnDownloadLimit), "try 5000000 (about 5 MB), or 0 for no limit")
+	}
+	if c.CodeExecution {
+		checkTimeout("code_timeout", c.CodeTimeout, time.Second, 2*time.Minute)
+	}
+
+	switch c.Theme {
+	case "auto", "dark", "light", "notty":
+	default:
+		add("theme", fmt.Sprintf("unknown theme %q", c.Theme), "use auto, dark, light or notty")
+	}
+
+	if len(c.ConsensusModels) == 1 {
+		add("consensus_models", "needs at least two models to compare", fmt.Sprintf(`e.g. ["%s", "llama3.1:8b"]`, c.ModelName))
+	}
+
+	for _, category := range c.BlockCategories {
+		if category != "adult" && category != "malware" {
+			add("block_categories", fmt.Sprintf("unknown category %q", category), "use adult and/or malware")
+		}
+	}
+	for _, path := range c.BlockLists {
+		if _, err := os.Stat(path); err != nil {
+			add("block_lists", fmt.Sprintf("cannot read %s: %v", path, err), "download a hosts-format list, e.g. from github.com/StevenBlack/hosts")
+		}
+	}
+
+	switch c.LogLevel {
+	case "off", "debug", "info", "warn", "error":
+	default:
+		add("log_level", fmt.Sprintf("unknown log level %q", c.LogLevel), "use off, debug, info, warn or error")
+	}
+
+	if msg := checkWritable(c.HistoryPath); msg != "" {
+		add("history_path", msg, "point history_path at a directory you own")
+	}
+	if c.PageReuseAge < 0 {
+		add("page_reuse_age", fmt.Sprintf("cannot be negative, got %v", c.PageReuseAge), "use 0 to reuse saved pages for the whole session")
+	}
+	if c.AnswerCacheTTL < 0 {
+		add("answer_cache_ttl", fmt.Sprintf("cannot be negative, got %v", c.AnswerCacheTTL), "use 0 to disable the cache")
+	} else if c.AnswerCacheTTL > 0 {
+		if msg := checkWritable(c.AnswerCachePath); msg != "" {
+			add("answer_cache_path", msg, "point answer_cache_path at a directory you own")
+		}
+	}
+	if c.SnippetCachePath != "" {
+		if msg := checkWritable(c.SnippetCachePath); msg != "" {
+			add("snippet_cache_path", msg, `point snippet_cache_path at a directory you own, or set it to "" to disable it`)
+		}
+	}
+	if c.HTTPCacheDir != "" {
+		if msg := checkWritable(filepath.Join(c.HTTPCacheDir, "check")); msg != "" {
+			add("http_cache_dir", msg, `point http_cache_dir at a directory you own, or set it to "" to disable it`)
+		}
+		if c.HTTPCacheMaxSize < 1<<20 {
+			add("http_cache_max_size", fmt.Sprintf("must be at least 1 MB, got %d bytes", c.HTTPCacheMaxSize), "268435456 (256 MB) is a good default")
+		}
+		if c.HTTPCacheHeuristicTTL < 0 {
+			add("http_cache_heuristic_ttl", fmt.Sprintf("cannot be negative, got %v", c.HTTPCacheHeuristicTTL), "use 0 to reuse only responses that say how long they stay fresh")
+		}
+	}
+	if c.HostileDomainsPath != "" {
+		if msg := checkWritable(c.HostileDomainsPath); msg != "" {
+			add("hostile_domains_path", msg, `point hostile_domains_path at a directory you own, or set it to "" to disable it`)
+		}
+		if c.HostileStrikes < 1 {
+			add("hostile_strikes", fmt.Sprintf("must be at least 1, got %d", c.HostileStrikes), "3 is a good default")
+		}
+		if c.HostileCooldown <= 0 {
+			add("hostile_cooldown", fmt.Sprintf("must be positive, got %v", c.HostileCooldown), `e.g. "24h"`)
+		}
+	}
+
+	if c.ResearchRounds < 1 || c.ResearchRounds > 5 {
+		add("research_rounds", fmt.Sprintf("must be between 1 and 5, got %d", c.ResearchRounds), "2 is a good default")
+	}
+	if c.ResearchMaxSubtopics < 1 || c.ResearchMaxSubtopics > 10 {
+		add("research_max_subtopics", fmt.Sprintf("must be between 1 and 10, got %d", c.ResearchMaxSubtopics), "5 is a good default")
+	}
+	if c.SiteCrawlPages < 1 || c.SiteCrawlPages > 100 {
+		add("site_crawl_pages", fmt.Sprintf("must be between 1 and 100, got %d", c.SiteCrawlPages), "15 is a good default")
+	}
+
+	switch c.EmbedProvider {
+	case "", "ollama", "llamacpp", "openai":
+	default:
+		add("embed_provider", fmt.Sprintf("unknown provider %q", c.EmbedProvider), "use ollama, llamacpp or openai")
+	}
+	if u, err := url.Parse(c.EmbedURL); c.EmbedURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		add("embed_url", fmt.Sprintf("%q is not a URL", c.EmbedURL), "e.g. \"http://localhost:8080/v1\"")
+	}
+	if c.KnowledgeBase {
+		if c.EmbedModel == "" {
+			add("embed_model", "cannot be empty when knowledge_base is on", "try nomic-embed-text")
+		}
+		if c.KnowledgeTopK < 1 {
+			add("knowledge_top_k", fmt.Sprintf("must be at least 1, got %d", c.KnowledgeTopK), "4 is a good default")
+		}
+		if msg := checkWritable(c.KnowledgePath); msg != "" {
+			add("knowledge_path", msg, "point knowledge_path at a directory you own")
+		}
+	}
+
+	if c.ArchiveFormat != "markdown" && c.ArchiveFormat != "warc" {
+		add("archive_format", fmt.Sprintf("unknown format %q", c.ArchiveFormat), "use markdown or warc")
+	}
+	if c.Archive && c.ArchiveDir == "" {
+		add("archive_dir", "cannot be empty when archive is on", "e.g. ~/.web-ollama/archive")
+	}
+	if c.MaxAnswerChars < 0 {
+		add("max_answer_chars", fmt.Sprintf("cannot be negative, got %d", c.MaxAnswerChars), "use 0 for no limit")
+	}
+	for i, rw := range c.AnswerRewrites {
+		field := fmt.Sprintf("answer_rewrites[%d].pattern", i)
+		if rw.Pattern == "" {
+			add(field, "cannot be empty", `e.g. "(?i)as an ai language model,? "`)
+		} else if _, err := regexp.Compile(rw.Pattern); err != nil {
+			add(field, fmt.Sprintf("invalid regular expression: %v", err), "patterns use Go RE2 syntax")
+		}
+	}
+
+	for i, sink := range c.Sinks {
+		field := fmt.Sprintf("sinks[%d]", i)
+		switch sink.Type {
+		case "file":
+			if msg := checkWritable(sink.Path); msg != "" {
+				add(field+".path", msg, "point the file sink at a directory you own")
+			}
+			if sink.Format != "" && sink.Format != "jsonl" && sink.Format != "markdown" {
+				add(field+".format", fmt.Sprintf("unknown format %q", sink.Format), "use jsonl or markdown")
+			}
+		case "webhook":
+			if msg := checkURL(sink.URL); msg != "" {
+				add(field+".url", msg, "expected something like https://example.com/hook")
+			}
+		case "command":
+			if strings.TrimSpace(sink.Command) == "" {
+				add(field+".command", "cannot be empty", `e.g. "cat >> ~/answers.jsonl"`)
+			}
+		default:
+			add(field+".type", fmt.Sprintf("unknown sink type %q", sink.Type), "use file, webhook or command")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
 	}
 	return nil
 }
 
+// checkURL returns a description of what is wrong with an HTTP URL, or ""
+func checkURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Sprintf("%q is not a valid URL: %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("%q has no host", raw)
+	}
+	return ""
+}
+
+// checkWritable returns a description of why a file cannot be written, or "".
+// It creates nothing: a missing directory is fine as long as the nearest one
+// that exists can be written to, since the file's owner creates the rest.
+func checkWritable(path string) string {
+	if path == "" {
+		return "cannot be empty"
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Sprintf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Sprintf("cannot read directory %s: %v", dir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Sprintf("no directory of %s exists", path)
+		}
+		dir = parent
+	}
+
+	if err := writable(dir); err != nil {
+		return fmt.Sprintf("directory %s is not writable: %v", dir, err)
+	}
+	return ""
+}
+
+// clamp limits a duration to the range [min, max]
+func clamp(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
 // expandHome expands the ~ in file paths to the user's home directory
 func expandHome(path string) string {
 	if len(path) > 0 && path[0] == '~' {
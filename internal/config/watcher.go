@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Reload carries the file settings before and after a config file change
+type Reload struct {
+	Prev *Config
+	Next *Config
+}
+
+// Watcher polls a config file and reports changes to it
+type Watcher struct {
+	path     string
+	interval time.Duration
+	reloads  chan Reload
+	errors   chan error
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewWatcher creates a watcher for the given config file
+func NewWatcher(path string, interval time.Duration) *Watcher {
+	return &Watcher{
+		path:     expandHome(path),
+		interval: interval,
+		reloads:  make(chan Reload, 8),
+		errors:   make(chan error, 8),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins polling in the background
+func (w *Watcher) Start() {
+	prev := loadFileOnly(w.path)
+	modTime := fileModTime(w.path)
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+
+			current := fileModTime(w.path)
+			if current.Equal(modTime) {
+				continue
+			}
+			modTime = current
+
+			next := NewConfig()
+			if err := next.LoadFile(w.path); err != nil {
+				w.sendError(err)
+				continue
+			}
+			next.Normalize()
+			if err := next.Validate(); err != nil {
+				w.sendError(fmt.Errorf("ignoring config change: %w", err))
+				continue
+			}
+
+			select {
+			case w.reloads <- Reload{Prev: prev, Next: next}:
+				prev = next
+			default:
+				// Consumer is behind; the next poll will diff against the same prev
+				modTime = time.Time{}
+			}
+		}
+	}()
+}
+
+// Stop ends polling
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+// Reloads delivers validated config file changes
+func (w *Watcher) Reloads() <-chan Reload {
+	return w.reloads
+}
+
+// Errors delivers problems reading or validating a changed file
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// sendError delivers an error without blocking the poll loop
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// ApplyReload copies hot-reloadable settings that changed between prev and next
+// into c and returns a description of each change. Settings that did not change
+// in the file are left alone so command-line overrides survive a reload.
+func (c *Config) ApplyReload(r Reload) []string {
+	var changes []string
+
+	apply := func(name string, dst, prev, next interface{}) {
+		p := reflect.ValueOf(prev).Elem().Interface()
+		n := reflect.ValueOf(next).Elem().Interface()
+		if reflect.DeepEqual(p, n) {
+			return
+		}
+		d := reflect.ValueOf(dst).Elem()
+		changes = append(changes, fmt.Sprintf("%s: %v → %v", name, d.Interface(), n))
+		d.Set(reflect.ValueOf(n))
+	}
+
+	apply("ollama_timeout", &c.OllamaTimeout, &r.Prev.OllamaTimeout, &r.Next.OllamaTimeout)
+	apply("search_timeout", &c.SearchTimeout, &r.Prev.SearchTimeout, &r.Next.SearchTimeout)
+	apply("crawl_timeout", &c.CrawlTimeout, &r.Prev.CrawlTimeout, &r.Next.CrawlTimeout)
+	apply("turn_budget", &c.TurnBudget, &r.Prev.TurnBudget, &r.Next.TurnBudget)
+	apply("turn_download_limit", &c.TurnDownloadLimit, &r.Prev.TurnDownloadLimit, &r.Next.TurnDownloadLimit)
+	apply("max_results", &c.MaxResults, &r.Prev.MaxResults, &r.Next.MaxResults)
+	apply("blocked_domains", &c.BlockedDomains, &r.Prev.BlockedDomains, &r.Next.BlockedDomains)
+	apply("source_trust", &c.SourceTrust, &r.Prev.SourceTrust, &r.Next.SourceTrust)
+	apply("theme", &c.Theme, &r.Prev.Theme, &r.Next.Theme)
+
+	return changes
+}
+
+// loadFileOnly returns defaults overlaid with the file, ignoring errors
+func loadFileOnly(path string) *Config {
+	c := NewConfig()
+	c.LoadFile(path)
+	c.Normalize()
+	return c
+}
+
+// fileModTime returns the file's modification time, or zero if it is missing
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
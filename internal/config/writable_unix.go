@@ -0,0 +1,14 @@
+//go:build !windows
+
+package config
+
+import "syscall"
+
+// accessWrite is W_OK for access(2)
+const accessWrite = 0x2
+
+// writable reports why the current user cannot create files in dir, if
+// it cannot
+func writable(dir string) error {
+	return syscall.Access(dir, accessWrite)
+}
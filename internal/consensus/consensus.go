@@ -0,0 +1,131 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"web-ollama/internal/ollama"
+)
+
+// Chatter streams a chat response
+type Chatter interface {
+	ChatWithCallbacks(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error)
+}
+
+// Candidate is one model's answer to the question
+type Candidate struct {
+	Model    string
+	Answer   string
+	Err      error
+	Duration time.Duration
+}
+
+// Consensus asks several models the same question and has a judge model
+// reconcile their answers
+type Consensus struct {
+	Client  Chatter
+	Models  []string // models asked for candidate answers
+	Judge   string   // model that writes the final answer
+	Options map[string]interface{}
+
+	// Progress, if set, receives short status lines
+	Progress func(string)
+}
+
+// Result is the reconciled answer and the candidates it was built from
+type Result struct {
+	Thinking   string
+	Answer     string
+	Candidates []Candidate
+}
+
+// Ask sends messages to every model in turn, then streams the judge's
+// reconciled answer through callbacks. The last message must be the user's
+// question; earlier ones (system prompt, sources, history) are shared by all.
+func (c *Consensus) Ask(ctx context.Context, messages []ollama.Message, callbacks ollama.StreamCallbacks) (*Result, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no question to ask")
+	}
+
+	result := &Result{}
+	var answered []Candidate
+	for _, model := range c.Models {
+		c.progress(fmt.Sprintf("Asking %s", model))
+		start := time.Now()
+		_, answer, err := c.Client.ChatWithCallbacks(ctx, ollama.ChatRequest{
+			Model:    model,
+			Messages: messages,
+			Options:  c.Options,
+		}, ollama.StreamCallbacks{})
+		candidate := Candidate{Model: model, Answer: strings.TrimSpace(answer), Err: err, Duration: time.Since(start)}
+		result.Candidates = append(result.Candidates, candidate)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			c.progress(fmt.Sprintf("%s failed: %v", model, err))
+			continue
+		}
+		c.progress(fmt.Sprintf("%s answered in %s", model, candidate.Duration.Round(100*time.Millisecond)))
+		answered = append(answered, candidate)
+	}
+
+	switch len(answered) {
+	case 0:
+		return nil, fmt.Errorf("no model produced an answer")
+	case 1:
+		// Nothing to reconcile; pass the lone answer through
+		c.progress("Only one model answered; skipping reconciliation")
+		if callbacks.OnDone != nil {
+			callbacks.OnDone()
+		}
+		if callbacks.OnAnswer != nil {
+			callbacks.OnAnswer(answered[0].Answer)
+		}
+		result.Answer = answered[0].Answer
+		return result, nil
+	}
+
+	c.progress(fmt.Sprintf("Reconciling %d answers with %s", len(answered), c.Judge))
+	thinking, answer, err := c.Client.ChatWithCallbacks(ctx, ollama.ChatRequest{
+		Model:    c.Judge,
+		Messages: reconcileMessages(messages, answered),
+		Options:  c.Options,
+	}, callbacks)
+	if err != nil {
+		return nil, fmt.Errorf("reconciliation failed: %w", err)
+	}
+
+	result.Thinking = thinking
+	result.Answer = answer
+	return result, nil
+}
+
+// reconcileMessages keeps the shared context and replaces the question with
+// a request to merge the candidate answers
+func reconcileMessages(messages []ollama.Message, candidates []Candidate) []ollama.Message {
+	question := messages[len(messages)-1].Content
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Several AI models answered the same question independently.\n\nQuestion: %s\n\n", question))
+	for i, c := range candidates {
+		sb.WriteString(fmt.Sprintf("### Answer %c (%s)\n%s\n\n", 'A'+i, c.Model, c.Answer))
+	}
+	sb.WriteString(`Write the single best final answer to the question for the user.
+- Where the answers agree, state the shared conclusion plainly.
+- Where they disagree on a fact, check the web sources above if there are any and go with the best-supported claim.
+- Do not mention "Answer A" and so on in the body; just answer.
+- End with a "**Disagreements:**" section listing each point the models disagreed on, which model said what, and which you believe is right and why. If they agreed on everything, end with "**Disagreements:** none."`)
+
+	out := append([]ollama.Message(nil), messages[:len(messages)-1]...)
+	return append(out, ollama.Message{Role: "user", Content: sb.String()})
+}
+
+func (c *Consensus) progress(msg string) {
+	if c.Progress != nil {
+		c.Progress(msg)
+	}
+}
@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/ollama"
+)
+
+// fakeChat answers with a canned reply per model
+type fakeChat struct {
+	replies  map[string]string
+	failing  map[string]bool
+	requests []ollama.ChatRequest
+}
+
+func (f *fakeChat) ChatWithCallbacks(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error) {
+	f.requests = append(f.requests, req)
+	if f.failing[req.Model] {
+		return "", "", errors.New("model not found")
+	}
+	reply := f.replies[req.Model]
+	if callbacks.OnAnswer != nil {
+		callbacks.OnAnswer(reply)
+	}
+	return "", reply, nil
+}
+
+var question = []ollama.Message{
+	{Role: "system", Content: "You are helpful."},
+	{Role: "user", Content: "How tall is Mont Blanc?"},
+}
+
+func TestAskReconcilesCandidates(t *testing.T) {
+	chat := &fakeChat{replies: map[string]string{
+		"a":     "4,806 m",
+		"b":     "4,808 m",
+		"judge": "About 4,806 m.\n\n**Disagreements:** b said 4,808 m.",
+	}}
+	c := &Consensus{Client: chat, Models: []string{"a", "b"}, Judge: "judge"}
+
+	var streamed strings.Builder
+	result, err := c.Ask(context.Background(), question, ollama.StreamCallbacks{
+		OnAnswer: func(s string) { streamed.WriteString(s) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Candidates) != 2 || !strings.Contains(result.Answer, "Disagreements") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if streamed.String() != result.Answer {
+		t.Errorf("only the final answer should stream, got %q", streamed.String())
+	}
+
+	judge := chat.requests[2]
+	if judge.Model != "judge" || len(judge.Messages) != 2 || judge.Messages[0].Role != "system" {
+		t.Fatalf("unexpected judge request: %+v", judge)
+	}
+	prompt := judge.Messages[1].Content
+	for _, want := range []string{"How tall is Mont Blanc?", "Answer A (a)", "4,808 m"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("judge prompt missing %q", want)
+		}
+	}
+}
+
+func TestAskPassesThroughLoneAnswer(t *testing.T) {
+	chat := &fakeChat{
+		replies: map[string]string{"a": "4,806 m"},
+		failing: map[string]bool{"b": true},
+	}
+	c := &Consensus{Client: chat, Models: []string{"a", "b"}, Judge: "judge"}
+
+	result, err := c.Ask(context.Background(), question, ollama.StreamCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Answer != "4,806 m" || len(chat.requests) != 2 {
+		t.Errorf("got answer %q after %d requests", result.Answer, len(chat.requests))
+	}
+}
+
+func TestAskFailsWhenNoModelAnswers(t *testing.T) {
+	chat := &fakeChat{failing: map[string]bool{"a": true, "b": true}}
+	c := &Consensus{Client: chat, Models: []string{"a", "b"}, Judge: "judge"}
+
+	if _, err := c.Ask(context.Background(), question, ollama.StreamCallbacks{}); err == nil {
+		t.Error("expected an error")
+	}
+}
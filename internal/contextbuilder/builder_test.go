@@ -0,0 +1,294 @@
+package contextbuilder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/history"
+)
+
+// words returns n distinct words, about 2 tokens each
+func words(prefix string, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return strings.Join(parts, " ")
+}
+
+func testHistory(n int) []history.Message {
+	var msgs []history.Message
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msgs = append(msgs, history.Message{Role: role, Content: words(fmt.Sprintf("h%d-", i), 50), Timestamp: time.Now()})
+	}
+	return msgs
+}
+
+func TestBuildWithoutLimitKeepsEverything(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.MemoryFacts = []string{"The user lives in Berlin"}
+	b := &Builder{Config: cfg}
+
+	messages, report := b.Build(Turn{
+		Query:            "question",
+		KnowledgeContext: "kb excerpt",
+		Sources:          whyResults,
+		History:          testHistory(2),
+	})
+
+	// system, knowledge + ack, search + ack, 2 history, query
+	if len(messages) != 8 {
+		t.Fatalf("got %d messages, want 8", len(messages))
+	}
+	if !strings.Contains(messages[0].Content, "Berlin") {
+		t.Error("memory facts missing from the system prompt")
+	}
+	if messages[len(messages)-1].Content != "question" {
+		t.Errorf("last message = %q, want the query", messages[len(messages)-1].Content)
+	}
+	if dropped := report.Dropped(); len(dropped) != 0 {
+		t.Errorf("nothing should be dropped without a budget, got %+v", dropped)
+	}
+}
+
+func TestBuildIncludesPrimer(t *testing.T) {
+	b := &Builder{Config: config.NewConfig()}
+
+	messages, report := b.Build(Turn{Query: "and the battery?", Primer: "We compared the Framework Laptop 16 with the XPS 16."})
+
+	// system, primer + ack, query
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+	if messages[1].Role != "user" || !strings.Contains(messages[1].Content, "Framework Laptop 16") {
+		t.Errorf("primer message = %+v", messages[1])
+	}
+	if report.Items[len(report.Items)-1].Kind != "primer" {
+		t.Errorf("report items = %+v, want the primer", report.Items)
+	}
+
+	// The primer gives way when the budget is tight
+	b.Budget = EstimateTokens(messages[0].Content) + 20
+	messages, _ = b.Build(Turn{Query: "and the battery?", Primer: words("p", 200)})
+	if len(messages) != 2 {
+		t.Errorf("got %d messages, want the primer dropped", len(messages))
+	}
+}
+
+func TestBuildDropsHistoryBeforeSources(t *testing.T) {
+	cfg := config.NewConfig()
+	page := crawler.CrawlResult{URL: "https://a.example", Title: "A", Content: words("a", 100)}
+	unlimited := &Builder{Config: cfg}
+	_, full := unlimited.Build(Turn{Query: "q", Sources: []crawler.CrawlResult{page}})
+
+	// Room for the prompt and the page plus one history message, not ten
+	b := &Builder{Config: cfg, Budget: full.Used + EstimateTokens(words("h0-", 50)) + messageOverhead}
+	messages, report := b.Build(Turn{Query: "q", Sources: []crawler.CrawlResult{page}, History: testHistory(10)})
+
+	if len(report.Sources) != 1 || len(report.Sources[0].Chunks) != 1 {
+		t.Fatalf("source should be fully included: %+v", report.Sources)
+	}
+	// system, search + ack, newest history message, query
+	if len(messages) != 5 {
+		t.Fatalf("got %d messages, want 5", len(messages))
+	}
+	if !strings.HasPrefix(messages[3].Content, "h9-") {
+		t.Errorf("kept %q, want the newest history message", messages[3].Content[:10])
+	}
+	if report.Used > b.Budget {
+		t.Errorf("used %d tokens, budget %d", report.Used, b.Budget)
+	}
+
+	dropped := 0
+	for _, item := range report.Dropped() {
+		if item.Kind != "history" {
+			t.Errorf("unexpected dropped item %+v", item)
+		}
+		dropped++
+	}
+	if dropped != 9 {
+		t.Errorf("dropped %d history messages, want 9", dropped)
+	}
+}
+
+func TestBuildKeepHistoryDropsSourcesFirst(t *testing.T) {
+	cfg := config.NewConfig()
+	page := crawler.CrawlResult{URL: "https://a.example", Title: "A", Content: words("a", 400)}
+	unlimited := &Builder{Config: cfg}
+	tiny := crawler.CrawlResult{URL: "https://a.example", Title: "A", Content: "a"}
+	_, small := unlimited.Build(Turn{Query: "q", Sources: []crawler.CrawlResult{tiny}, History: testHistory(4)})
+
+	// Room for the history and a one-word page, not the first chunk of a long one
+	b := &Builder{Config: cfg, Budget: small.Used}
+	messages, report := b.Build(Turn{Query: "q", Sources: []crawler.CrawlResult{page}, History: testHistory(4), KeepHistory: true})
+
+	for _, item := range report.Dropped() {
+		if item.Kind == "history" {
+			t.Errorf("history dropped with KeepHistory: %+v", item)
+		}
+	}
+	// system, 4 history, query; the page does not fit what is left
+	if len(messages) != 6 {
+		t.Errorf("got %d messages, want 6", len(messages))
+	}
+	if !report.Trimmed() || report.Wanted() <= b.Budget {
+		t.Errorf("Trimmed = %v, Wanted = %d, budget %d", report.Trimmed(), report.Wanted(), b.Budget)
+	}
+}
+
+func TestBuildSharesBudgetAcrossSources(t *testing.T) {
+	cfg := config.NewConfig()
+	sources := []crawler.CrawlResult{
+		{URL: "https://a.example", Title: "A", Content: words("a", 3*chunkWords)},
+		{URL: "https://b.example", Title: "B", Content: words("b", 3*chunkWords)},
+	}
+	firsts := []crawler.CrawlResult{
+		{URL: "https://a.example", Title: "A", Content: words("a", chunkWords)},
+		{URL: "https://b.example", Title: "B", Content: words("b", chunkWords)},
+	}
+	_, round := (&Builder{Config: cfg}).Build(Turn{Query: "q", Sources: firsts})
+
+	// Enough for the first chunk of each page but not a second round
+	b := &Builder{Config: cfg, Budget: round.Used + EstimateTokens(words("a", chunkWords))/2}
+	messages, report := b.Build(Turn{Query: "q", Sources: sources})
+
+	for _, s := range report.Sources {
+		if len(s.Chunks) != 1 || s.Chunks[0].Index != 0 || s.Total != 3 {
+			t.Errorf("source %d: chunks %+v of %d, want only the first of 3", s.Number, s.Chunks, s.Total)
+		}
+	}
+	if !strings.Contains(messages[1].Content, `<source id="2"`) {
+		t.Error("second source missing from the prompt")
+	}
+	if got := len(report.Dropped()); got != 4 {
+		t.Errorf("dropped %d chunks, want 4", got)
+	}
+	if !strings.Contains(report.Summary(), "2/6 source chunks") {
+		t.Errorf("summary = %q", report.Summary())
+	}
+}
+
+func TestBuildKeepsMostRelevantSourcesWhenShort(t *testing.T) {
+	cfg := config.NewConfig()
+	var sources []crawler.CrawlResult
+	for _, host := range []string{"first", "second", "third"} {
+		sources = append(sources, crawler.CrawlResult{URL: "https://" + host + ".example", Content: words(host, chunkWords)})
+	}
+	_, two := (&Builder{Config: cfg}).Build(Turn{Query: "q", Sources: sources[:2]})
+
+	// Room for the first chunks of the two best-ranked pages only
+	b := &Builder{Config: cfg, Budget: two.Used + EstimateTokens(words("third", chunkWords))/2}
+	messages, report := b.Build(Turn{Query: "q", Sources: sources})
+
+	var got []int
+	for _, s := range report.Sources {
+		got = append(got, len(s.Chunks))
+	}
+	if fmt.Sprint(got) != "[1 1 0]" {
+		t.Errorf("chunks per source = %v, want [1 1 0]: search order is relevance order", got)
+	}
+	if strings.Contains(messages[1].Content, "third.example") {
+		t.Error("least relevant source should be left out")
+	}
+	if report.Used > b.Budget {
+		t.Errorf("used %d tokens of a %d budget", report.Used, b.Budget)
+	}
+}
+
+func TestBuildGivesTrustedSourcesMoreBudget(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.SourceTrust = map[string]float64{"docs.example": 2, "farm.example": 0}
+	var sources, firsts []crawler.CrawlResult
+	for _, host := range []string{"docs", "plain", "farm"} {
+		url := "https://" + host + ".example/page"
+		sources = append(sources, crawler.CrawlResult{URL: url, Content: words(host, 4*chunkWords)})
+		firsts = append(firsts, crawler.CrawlResult{URL: url, Content: words(host, chunkWords)})
+	}
+	_, round := (&Builder{Config: cfg}).Build(Turn{Query: "q", Sources: firsts})
+
+	// Room for two chunks beyond the first of each page
+	chunk := EstimateTokens(words("docs", chunkWords))
+	b := &Builder{Config: cfg, Budget: round.Used + 2*chunk + chunk/2}
+	_, report := b.Build(Turn{Query: "q", Sources: sources})
+
+	var got []int
+	for _, s := range report.Sources {
+		got = append(got, len(s.Chunks))
+	}
+	if fmt.Sprint(got) != "[3 1 1]" {
+		t.Errorf("chunks per source = %v, want [3 1 1]: the trusted page gets both extra chunks", got)
+	}
+}
+
+func TestBuildGroupsIncludedSourcesByPerspective(t *testing.T) {
+	cfg := config.NewConfig()
+	var sources []crawler.CrawlResult
+	for _, host := range []string{"a", "b", "c"} {
+		sources = append(sources, crawler.CrawlResult{URL: "https://" + host + ".example", Content: words(host, chunkWords)})
+	}
+	groups := [][]string{{"https://a.example", "https://c.example"}, {"https://b.example", "https://gone.example"}}
+
+	messages, _ := (&Builder{Config: cfg}).Build(Turn{Query: "q", Sources: sources, Perspectives: groups})
+	if !strings.Contains(messages[1].Content, "Perspective A: sources 1, 3\n- Perspective B: sources 2\n") {
+		t.Errorf("search context lacks the perspectives:\n%s", messages[1].Content)
+	}
+
+	// Only one group left in the prompt: nothing to contrast
+	_, two := (&Builder{Config: cfg}).Build(Turn{Query: "q", Sources: sources[:1]})
+	b := &Builder{Config: cfg, Budget: two.Used + 20}
+	messages, _ = b.Build(Turn{Query: "q", Sources: sources, Perspectives: groups})
+	if strings.Contains(messages[1].Content, "Perspective") {
+		t.Error("perspectives listed with only one group's sources in the prompt")
+	}
+}
+
+func TestBuildAlwaysKeepsQuery(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.MemoryFacts = []string{"The user prefers metric units"}
+	b := &Builder{Config: cfg, Budget: 10}
+
+	messages, report := b.Build(Turn{Query: "question", Sources: whyResults, History: testHistory(2)})
+	if len(messages) != 2 || messages[1].Content != "question" {
+		t.Fatalf("want only the system prompt and query, got %d messages", len(messages))
+	}
+	if strings.Contains(messages[0].Content, "metric") {
+		t.Error("memory facts should be dropped when they do not fit")
+	}
+	if len(report.Dropped()) == 0 {
+		t.Error("report should list the dropped context")
+	}
+	if !errs.Is(report.Overflow(), errs.ContextOverflow) {
+		t.Errorf("Overflow() = %v, want ContextOverflow with the system prompt over budget", report.Overflow())
+	}
+
+	b.Budget = 0
+	if _, report := b.Build(Turn{Query: "question"}); report.Overflow() != nil {
+		t.Errorf("Overflow() = %v without a budget", report.Overflow())
+	}
+}
+
+func TestHistoryMessagesFollowsContextWindow(t *testing.T) {
+	cases := []struct {
+		context, answer, set, want int
+	}{
+		{32768, 4096, 0, 23},
+		{4096, 512, 0, 4},
+		{131072, 8192, 0, 40},
+		{32768, 4096, 6, 6},
+	}
+	for _, c := range cases {
+		cfg := &config.Config{ContextTokens: c.context, AnswerTokens: c.answer, HistoryMessages: c.set}
+		if got := HistoryMessages(cfg); got != c.want {
+			t.Errorf("HistoryMessages(%d, %d, %d) = %d, want %d", c.context, c.answer, c.set, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package contextbuilder
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// citationPattern matches [n] and [n.k] citations with the spaces before them
+var citationPattern = regexp.MustCompile(`[ \t]*\[(\d+)(?:\.(\d+))?\]`)
+
+// Attribution is an answer checked against the sources that were in its
+// prompt
+type Attribution struct {
+	Answer  string   // the answer with citations of unknown IDs removed
+	Cited   []Source // cited sources in number order, with the passages cited
+	Invalid []string // citations that matched nothing in the prompt, e.g. "[7.2]"
+
+	shown []Source // sources with passages in the prompt
+}
+
+// Attribute finds the citations in answer and keeps those that point at a
+// source, or a passage of one, that the model was shown. Citing a whole
+// source with [n] counts as citing all of its included passages. sources
+// must be in number order, as NumberSources returns them. Brackets in code
+// and indexes such as arr[1] are not citations; without sources the answer
+// is returned unchanged.
+func Attribute(sources []Source, answer string) Attribution {
+	a := Attribution{Answer: answer}
+	bySource := make(map[int]Source)
+	for _, s := range sources {
+		if len(s.Chunks) > 0 {
+			bySource[s.Number] = s
+			a.shown = append(a.shown, s)
+		}
+	}
+	if len(a.shown) == 0 {
+		return a
+	}
+
+	code := codeSpans(answer)
+	cited := make(map[int]map[int]bool) // source -> chunk indexes
+	var out strings.Builder
+	last := 0
+	for _, m := range citationPattern.FindAllStringSubmatchIndex(answer, -1) {
+		bracket := m[0] + strings.IndexByte(answer[m[0]:m[1]], '[')
+		if bracket > 0 && isWordByte(answer[bracket-1]) || inSpans(code, bracket) {
+			continue
+		}
+		n, _ := strconv.Atoi(answer[m[2]:m[3]])
+		k := 0
+		if m[4] >= 0 {
+			k, _ = strconv.Atoi(answer[m[4]:m[5]])
+		}
+		if markCited(cited, bySource, n, k) {
+			continue
+		}
+
+		id := strings.TrimSpace(answer[m[0]:m[1]])
+		if !contains(a.Invalid, id) {
+			a.Invalid = append(a.Invalid, id)
+		}
+		out.WriteString(answer[last:m[0]])
+		last = m[1]
+	}
+	if len(a.Invalid) > 0 {
+		out.WriteString(answer[last:])
+		a.Answer = out.String()
+	}
+
+	for _, s := range sources {
+		indexes := cited[s.Number]
+		if len(indexes) == 0 {
+			continue
+		}
+		c := Source{Number: s.Number, URL: s.URL, Title: s.Title, Total: s.Total}
+		for _, chunk := range s.Chunks {
+			if indexes[chunk.Index] {
+				c.Chunks = append(c.Chunks, chunk)
+			}
+		}
+		a.Cited = append(a.Cited, c)
+	}
+	return a
+}
+
+// markCited records a citation of passage k of source n, or of the whole
+// source when k is 0, and reports whether it was in the prompt
+func markCited(cited map[int]map[int]bool, bySource map[int]Source, n, k int) bool {
+	src, ok := bySource[n]
+	if !ok {
+		return false
+	}
+	found := false
+	for _, c := range src.Chunks {
+		if k == 0 || c.Index == k-1 {
+			if cited[n] == nil {
+				cited[n] = make(map[int]bool)
+			}
+			cited[n][c.Index] = true
+			found = true
+		}
+	}
+	return found
+}
+
+var codePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+
+// codeSpans returns the byte ranges of fenced and inline code
+func codeSpans(text string) [][]int {
+	return codePattern.FindAllStringIndex(text, -1)
+}
+
+func inSpans(spans [][]int, i int) bool {
+	for _, s := range spans {
+		if i >= s[0] && i < s[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Footer returns the sources to list under the answer: those cited or, when
+// nothing valid was cited, every source that was in the prompt
+func (a Attribution) Footer() []Source {
+	if len(a.Cited) > 0 {
+		return a.Cited
+	}
+	return a.shown
+}
+
+// IDs returns the citation IDs of the source's passages
+func (s Source) IDs() []string {
+	ids := make([]string, len(s.Chunks))
+	for i, c := range s.Chunks {
+		ids[i] = ChunkID(s.Number, c)
+	}
+	return ids
+}
@@ -0,0 +1,80 @@
+package contextbuilder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// citeSources has source 1 with passages 1 and 3 included and source 2
+// with passage 1; source 3 was dropped from the prompt
+var citeSources = []Source{
+	{Number: 1, URL: "https://go.dev/blog", Total: 3, Chunks: []Chunk{{Index: 0, Text: "a"}, {Index: 2, Text: "c"}}},
+	{Number: 2, URL: "https://news.example", Total: 1, Chunks: []Chunk{{Index: 0, Text: "x"}}},
+	{Number: 3, URL: "https://dropped.example", Total: 2},
+}
+
+func TestAttributeKeepsCitationsOfShownPassages(t *testing.T) {
+	answer := "Loops changed [1.3][2.1]. Also see [2]."
+	a := Attribute(citeSources, answer)
+
+	if a.Answer != answer || len(a.Invalid) != 0 {
+		t.Errorf("valid citations changed the answer: %q, invalid %v", a.Answer, a.Invalid)
+	}
+	if len(a.Cited) != 2 {
+		t.Fatalf("cited %d sources, want 2", len(a.Cited))
+	}
+	if got := a.Cited[0].IDs(); !reflect.DeepEqual(got, []string{"1.3"}) {
+		t.Errorf("source 1 passages = %v, want only the cited one", got)
+	}
+	if got := a.Cited[1].IDs(); !reflect.DeepEqual(got, []string{"2.1"}) {
+		t.Errorf("source 2 passages = %v", got)
+	}
+}
+
+func TestAttributeRemovesUnknownCitations(t *testing.T) {
+	a := Attribute(citeSources, "Go 1.22 shipped in February [1.2][3.1] and [7]. Fast [1.1].")
+
+	if want := "Go 1.22 shipped in February and. Fast [1.1]."; a.Answer != want {
+		t.Errorf("answer = %q, want %q", a.Answer, want)
+	}
+	if want := []string{"[1.2]", "[3.1]", "[7]"}; !reflect.DeepEqual(a.Invalid, want) {
+		t.Errorf("invalid = %v, want %v", a.Invalid, want)
+	}
+	if len(a.Cited) != 1 || a.Cited[0].Number != 1 {
+		t.Errorf("cited = %+v, want source 1", a.Cited)
+	}
+}
+
+func TestAttributeIgnoresCodeAndIndexes(t *testing.T) {
+	answer := "Use arr[9] or `m[5]`:\n```go\nx := y[4]\nz := [8]int{}\n```"
+	a := Attribute(citeSources, answer)
+	if a.Answer != answer || len(a.Invalid) != 0 {
+		t.Errorf("code was treated as citations: %q, invalid %v", a.Answer, a.Invalid)
+	}
+}
+
+func TestFooterFallsBackToShownSources(t *testing.T) {
+	a := Attribute(citeSources, "No citations here.")
+	var urls []string
+	for _, s := range a.Footer() {
+		urls = append(urls, s.URL)
+	}
+	if want := []string{"https://go.dev/blog", "https://news.example"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("footer = %v, want the sources that were in the prompt", urls)
+	}
+
+	if a := Attribute(nil, "Arrays [1] without sources."); a.Answer != "Arrays [1] without sources." || a.Footer() != nil {
+		t.Errorf("without sources the answer should be untouched: %+v", a)
+	}
+}
+
+func TestPromptCarriesPassageIDs(t *testing.T) {
+	text := SearchContext(whyResults)
+	if !strings.Contains(text, `domain="weather.example"`) {
+		t.Errorf("prompt lacks source domains:\n%s", text)
+	}
+	if !strings.Contains(text, "[2.1] ") {
+		t.Errorf("prompt lacks passage IDs:\n%s", text)
+	}
+}
@@ -0,0 +1,41 @@
+package contextbuilder
+
+import (
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/ollama"
+)
+
+// Preview renders messages for inspecting a prompt without sending it: each
+// message's role and size, then its content cut to about maxChars. Long
+// content keeps its start and end, where the instructions and the question
+// usually are.
+func Preview(messages []ollama.Message, maxChars int) string {
+	var b strings.Builder
+	total := 0
+	for i, m := range messages {
+		runes := []rune(m.Content)
+		tokens := EstimateTokens(m.Content) + messageOverhead
+		total += tokens
+		fmt.Fprintf(&b, "[%d] %s: %d chars, ~%d tokens\n", i+1, m.Role, len(runes), tokens)
+		for _, line := range strings.Split(clip(runes, maxChars), "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d messages, ~%d tokens", len(messages), total)
+	return b.String()
+}
+
+// clip keeps the first two thirds and last third of maxChars runes, marking
+// what was left out
+func clip(runes []rune, maxChars int) string {
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return string(runes)
+	}
+	head := maxChars * 2 / 3
+	tail := maxChars - head
+	return fmt.Sprintf("%s\n[... %d chars omitted ...]\n%s",
+		string(runes[:head]), len(runes)-head-tail, string(runes[len(runes)-tail:]))
+}
@@ -0,0 +1,38 @@
+package contextbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"web-ollama/internal/ollama"
+)
+
+func TestPreviewShowsRolesSizesAndClippedContent(t *testing.T) {
+	long := "START " + strings.Repeat("m", 1000) + " END"
+	messages := []ollama.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: long},
+	}
+
+	got := Preview(messages, 90)
+	for _, want := range []string{
+		"[1] system: 9 chars, ~",
+		"    Be brief.\n",
+		"[2] user: 1010 chars, ~",
+		"    START ",
+		"[... 920 chars omitted ...]",
+		" END\n",
+		"2 messages, ~",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preview lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "m") > 100 {
+		t.Errorf("long content was not clipped:\n%s", got)
+	}
+
+	if full := Preview(messages, 0); !strings.Contains(full, long) {
+		t.Error("maxChars 0 should show content in full")
+	}
+}
@@ -0,0 +1,149 @@
+package contextbuilder
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"web-ollama/internal/metrics"
+	"web-ollama/internal/ollama"
+)
+
+// refusalWindow is how much of an answer's opening is checked for a claim
+// that the model cannot see current information. Models say this up front,
+// so streamed answers are held back this far and no further.
+const refusalWindow = 400
+
+// refusalPattern matches the usual ways a model says it has no access to
+// current information
+var refusalPattern = regexp.MustCompile(`(?i)(` +
+	`\bi(['’]m| am)? (do not|don['’]?t|cannot|can['’]?t|unable to|not able to) (have )?(access|browse|search)[^.]{0,40}(current|real[- ]time|up[- ]to[- ]date|latest|recent|the internet|the web|live)` +
+	`|\bi (do not|don['’]?t) have (any )?(real[- ]time|current|up[- ]to[- ]date) (information|data)` +
+	`|as of my (last|latest) (knowledge )?(update|training)` +
+	`|my (knowledge|training data) (cutoff|cut-off|only goes|is limited)` +
+	`)`)
+
+// regroundInstruction is added to the question when the model answered as
+// if it had been given no sources
+const regroundInstruction = "\n\nImportant: the web search results and excerpts earlier in this conversation " +
+	"were retrieved just now for this question and are current. Answer from them and cite them. " +
+	"Do not say that you lack access to current or real-time information."
+
+// IgnoresSources reports whether answer opens by claiming the model has no
+// access to current information, which means it ignored the context it was
+// given
+func IgnoresSources(answer string) bool {
+	if len(answer) > refusalWindow {
+		answer = answer[:refusalWindow]
+	}
+	return refusalPattern.MatchString(answer)
+}
+
+// Grounded reports whether any search chunk or knowledge excerpt made it
+// into the prompt
+func (r Report) Grounded() bool {
+	for _, item := range r.Items {
+		if item.Included && (item.Kind == "source" || item.Kind == "knowledge") {
+			return true
+		}
+	}
+	return false
+}
+
+// Reground returns a copy of messages with a stronger instruction to use
+// the provided context appended to the final question, for a single retry
+func Reground(messages []ollama.Message) []ollama.Message {
+	out := append([]ollama.Message(nil), messages...)
+	last := len(out) - 1
+	out[last].Content += regroundInstruction
+	return out
+}
+
+// Chat is the signature of ollama.Client.ChatWithCallbacks and tools.Loop.Chat
+type Chat func(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error)
+
+// ChatGrounded runs chat and, when the prompt was grounded but the answer
+// opens by claiming no access to current information, logs it and asks once
+// more with Reground. callbacks.OnAnswer only sees the answer that is kept.
+func ChatGrounded(ctx context.Context, chat Chat, req ollama.ChatRequest, callbacks ollama.StreamCallbacks, grounded bool) (thinking, answer string, retried bool, err error) {
+	if !grounded {
+		thinking, answer, err = chat(ctx, req, callbacks)
+		return thinking, answer, false, err
+	}
+
+	onAnswer := callbacks.OnAnswer
+	hold := NewOpeningHold(onAnswer)
+	callbacks.OnAnswer = hold.Write
+	thinking, answer, err = chat(ctx, req, callbacks)
+
+	if err == nil && !hold.Released() && IgnoresSources(answer) {
+		slog.Warn("answer ignored the provided sources; retrying with a stronger instruction",
+			"model", req.Model, "opening", firstLine(answer))
+		metrics.GroundingRetries.Inc()
+		req.Messages = Reground(req.Messages)
+		hold = NewOpeningHold(onAnswer)
+		callbacks.OnAnswer = hold.Write
+		thinking, answer, err = chat(ctx, req, callbacks)
+		retried = true
+	}
+	hold.Flush()
+	return thinking, answer, retried, err
+}
+
+// firstLine returns the first line of s, for logging
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 200 {
+		s = s[:200]
+	}
+	return s
+}
+
+// OpeningHold passes streamed answer tokens on once it is clear the answer
+// is not a refusal to use the sources, holding back at most its opening
+type OpeningHold struct {
+	next     func(string) // receives released tokens; may be nil
+	held     []byte
+	released bool
+}
+
+// NewOpeningHold wraps next, which receives the answer's tokens
+func NewOpeningHold(next func(string)) *OpeningHold {
+	return &OpeningHold{next: next}
+}
+
+// Write takes the next streamed tokens
+func (h *OpeningHold) Write(chunk string) {
+	if h.released {
+		h.emit(chunk)
+		return
+	}
+	h.held = append(h.held, chunk...)
+	if len(h.held) >= refusalWindow && !IgnoresSources(string(h.held)) {
+		h.Flush()
+	}
+}
+
+// Flush passes on anything still held; the answer is being kept
+func (h *OpeningHold) Flush() {
+	h.released = true
+	if len(h.held) > 0 {
+		h.emit(string(h.held))
+		h.held = nil
+	}
+}
+
+// Released reports whether any of the answer has been passed on, after
+// which it can no longer be replaced
+func (h *OpeningHold) Released() bool {
+	return h.released
+}
+
+func (h *OpeningHold) emit(s string) {
+	if h.next != nil {
+		h.next(s)
+	}
+}
@@ -0,0 +1,79 @@
+package contextbuilder
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/ollama"
+)
+
+func TestIgnoresSources(t *testing.T) {
+	for answer, want := range map[string]bool{
+		"I don't have access to current information, but as of 2023 the price was $3.":                 true,
+		"I’m unable to browse the internet or access real-time data.":                                  true,
+		"As of my last update in April 2024, the latest version was 1.22.":                             true,
+		"I do not have real-time information about today's weather.":                                   true,
+		"Go 1.23 was released in August 2024 [1.1].":                                                   false,
+		"If you don't have access to the internet, download the installer beforehand.":                 false,
+		strings.Repeat("Go 1.23 adds iterators. ", 30) + "I don't have access to current information.": false,
+	} {
+		if got := IgnoresSources(answer); got != want {
+			t.Errorf("IgnoresSources(%.60q) = %v, want %v", answer, got, want)
+		}
+	}
+}
+
+// scriptedChat streams the next answer in small pieces on each call
+func scriptedChat(answers ...string) (Chat, *[]ollama.ChatRequest) {
+	var requests []ollama.ChatRequest
+	return func(ctx context.Context, req ollama.ChatRequest, cb ollama.StreamCallbacks) (string, string, error) {
+		requests = append(requests, req)
+		answer := answers[len(requests)-1]
+		for i := 0; i < len(answer); i += 7 {
+			cb.OnAnswer(answer[i:min(i+7, len(answer))])
+		}
+		return "", answer, nil
+	}, &requests
+}
+
+func TestChatGroundedRetriesOnce(t *testing.T) {
+	refusal := "I don't have access to real-time information, so I can't say."
+	good := "Go 1.23 was released in August 2024 [1.1]. " + strings.Repeat("It adds range-over-func iterators. ", 20)
+	chat, requests := scriptedChat(refusal, good)
+
+	var streamed strings.Builder
+	req := ollama.ChatRequest{Messages: []ollama.Message{{Role: "user", Content: "latest Go?"}}}
+	_, answer, retried, err := ChatGrounded(context.Background(), chat, req,
+		ollama.StreamCallbacks{OnAnswer: func(s string) { streamed.WriteString(s) }}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !retried || answer != good || streamed.String() != good {
+		t.Errorf("retried=%v answer=%.40q streamed=%.40q, want only the second answer", retried, answer, streamed.String())
+	}
+	if len(*requests) != 2 || !strings.Contains((*requests)[1].Messages[0].Content, "Important:") {
+		t.Errorf("retry request = %+v", *requests)
+	}
+	if strings.Contains(req.Messages[0].Content, "Important:") {
+		t.Error("Reground modified the caller's messages")
+	}
+}
+
+func TestChatGroundedKeepsAnswersWithoutSources(t *testing.T) {
+	refusal := "I don't have access to current information."
+	for _, grounded := range []bool{false, true} {
+		chat, requests := scriptedChat(refusal, refusal)
+		var streamed strings.Builder
+		_, answer, retried, _ := ChatGrounded(context.Background(), chat, ollama.ChatRequest{Messages: []ollama.Message{{Role: "user"}}},
+			ollama.StreamCallbacks{OnAnswer: func(s string) { streamed.WriteString(s) }}, grounded)
+
+		wantCalls := 1
+		if grounded {
+			wantCalls = 2 // retried once, then kept even though it refused again
+		}
+		if len(*requests) != wantCalls || retried != grounded || answer != refusal || streamed.String() != refusal {
+			t.Errorf("grounded=%v: %d calls, retried=%v, streamed %q", grounded, len(*requests), retried, streamed.String())
+		}
+	}
+}
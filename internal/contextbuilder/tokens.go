@@ -0,0 +1,60 @@
+package contextbuilder
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/errs"
+)
+
+// EstimateTokens approximates how many tokens text uses. Models tokenize
+// differently, so this is a deliberately slightly pessimistic rule of thumb
+// (about 3.5 characters per token for English) rather than an exact count.
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n*2 + 6) / 7
+}
+
+// messageOverhead approximates the template tokens around each chat message
+const messageOverhead = 4
+
+// historyMessageTokens is the size of a typical message of conversation
+const historyMessageTokens = 300
+
+// HistoryMessages returns how many earlier messages are sent with each
+// question: history_messages when set, or else as many typical messages as
+// fill a quarter of the prompt budget, between 4 and 40. The builder still
+// drops the oldest of them when they do not fit.
+func HistoryMessages(cfg *config.Config) int {
+	if cfg.HistoryMessages > 0 {
+		return cfg.HistoryMessages
+	}
+	budget := New(cfg).Budget
+	if budget <= 0 {
+		return 40
+	}
+	return min(max(budget/4/historyMessageTokens, 4), 40)
+}
+
+// EstimateMessages approximates the tokens a conversation uses, including
+// the template around each message
+func EstimateMessages(contents []string) int {
+	total := 0
+	for _, c := range contents {
+		total += EstimateTokens(c) + messageOverhead
+	}
+	return total
+}
+
+// CheckFits returns a ContextOverflow error when a prompt of tokens does not
+// fit budget; a budget of 0 means no limit
+func CheckFits(tokens, budget int) error {
+	if budget <= 0 || tokens <= budget {
+		return nil
+	}
+	return errs.New(errs.ContextOverflow, "", fmt.Errorf("about %d tokens, %d available", tokens, budget))
+}
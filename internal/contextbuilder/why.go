@@ -0,0 +1,116 @@
+package contextbuilder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Explanation is the part of one source that was in the prompt, split into
+// chunks, with the answer sentences each chunk appears to support
+type Explanation struct {
+	Source   Source
+	Passages []Passage
+}
+
+// Passage is an included chunk and the answer sentences it appears to support
+type Passage struct {
+	Chunk  Chunk
+	Claims []string
+}
+
+// Explain links each answer sentence to an included chunk of source n: the
+// passage it cites by ID ([n.k]), or else the one it shares the most
+// distinctive words with, if it cites [n] or the overlap is large. The word
+// matching is a heuristic, not verification.
+func Explain(report Report, n int, answer string) (Explanation, error) {
+	if n < 1 || n > len(report.Sources) {
+		return Explanation{}, fmt.Errorf("no source %d; the last answer used %d source(s)", n, len(report.Sources))
+	}
+	src := report.Sources[n-1]
+	ex := Explanation{Source: src}
+	for _, c := range src.Chunks {
+		ex.Passages = append(ex.Passages, Passage{Chunk: c})
+	}
+
+	citation := fmt.Sprintf("[%d]", n)
+	for _, sentence := range splitSentences(answer) {
+		if i := citedPassage(ex.Passages, n, sentence); i >= 0 {
+			ex.Passages[i].Claims = append(ex.Passages[i].Claims, sentence)
+			continue
+		}
+		best, bestScore := -1, 0.0
+		for i, p := range ex.Passages {
+			if score := overlap(sentence, p.Chunk.Text); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		cites := strings.Contains(sentence, citation)
+		if best >= 0 && (bestScore >= 0.5 || (cites && bestScore > 0)) {
+			ex.Passages[best].Claims = append(ex.Passages[best].Claims, sentence)
+		}
+	}
+	return ex, nil
+}
+
+// citedPassage returns the passage a sentence cites by ID, or -1
+func citedPassage(passages []Passage, n int, sentence string) int {
+	for i, p := range passages {
+		if strings.Contains(sentence, "["+ChunkID(n, p.Chunk)+"]") {
+			return i
+		}
+	}
+	return -1
+}
+
+var sentenceEnd = regexp.MustCompile(`([.!?])\s+`)
+
+// splitSentences breaks an answer into trimmed sentences
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, s := range strings.Split(sentenceEnd.ReplaceAllString(text, "$1\n"), "\n") {
+		s = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s), "-*#> "))
+		if len(strings.Fields(s)) >= 4 {
+			sentences = append(sentences, s)
+		}
+	}
+	return sentences
+}
+
+var stopWords = map[string]bool{
+	"that": true, "this": true, "with": true, "from": true, "have": true, "were": true,
+	"which": true, "their": true, "there": true, "about": true, "would": true, "these": true,
+	"been": true, "also": true, "into": true, "than": true, "they": true, "will": true,
+	"what": true, "when": true, "where": true, "more": true, "most": true, "such": true,
+}
+
+// contentWords returns the lower-cased distinctive words of text
+func contentWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		if len(w) > 3 || (len(w) > 1 && w[0] >= '0' && w[0] <= '9') {
+			if !stopWords[w] {
+				words[w] = true
+			}
+		}
+	}
+	return words
+}
+
+// overlap is the share of the sentence's distinctive words found in the passage
+func overlap(sentence, passage string) float64 {
+	sw := contentWords(sentence)
+	if len(sw) < 3 {
+		return 0
+	}
+	pw := contentWords(passage)
+	found := 0
+	for w := range sw {
+		if pw[w] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(sw))
+}
@@ -0,0 +1,73 @@
+package contextbuilder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/history"
+)
+
+var whyResults = []crawler.CrawlResult{
+	{URL: "https://down.example", Error: errors.New("timeout")},
+	{URL: "https://go.dev/blog", Title: "Go 1.22", Content: "Go 1.22 was released in February 2024. " +
+		"The release changes loop variable semantics so each iteration gets a fresh variable."},
+	{URL: "https://empty.example"},
+	{URL: "https://weather.example", Title: "Weather", Content: "Berlin will be sunny with highs of 21 degrees."},
+}
+
+func TestSourcesMatchPromptNumbering(t *testing.T) {
+	sources, _ := NumberSources(whyResults)
+	if len(sources) != 2 || sources[0].URL != "https://go.dev/blog" || sources[1].Number != 2 {
+		t.Fatalf("unexpected sources: %+v", sources)
+	}
+
+	prompt := SearchContext(whyResults)
+	if !strings.Contains(prompt, `<source id="2" url="https://weather.example"`) {
+		t.Errorf("prompt numbering differs from NumberSources:\n%s", prompt)
+	}
+}
+
+func TestExplainLinksClaims(t *testing.T) {
+	_, report := New(config.NewConfig()).Build(Turn{Query: "what changed in go 1.22?", Sources: whyResults})
+	answer := "Go 1.22 was released in February 2024 [1]. Loop variables are now fresh per iteration. " +
+		"Berlin will be sunny tomorrow."
+
+	ex, err := Explain(report, 1, answer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.Passages) != 1 {
+		t.Fatalf("got %d passages, want 1", len(ex.Passages))
+	}
+	claims := ex.Passages[0].Claims
+	if len(claims) != 2 || !strings.Contains(claims[0], "February 2024") || !strings.Contains(claims[1], "Loop variables") {
+		t.Errorf("unexpected claims: %q", claims)
+	}
+
+	if _, err := Explain(report, 3, answer); err == nil {
+		t.Error("expected an error for a source that was not in the prompt")
+	}
+}
+
+func TestRestoreFromSavedPages(t *testing.T) {
+	page := crawler.CrawlResult{URL: "https://a.example", Title: "A", Content: words("a", 2*chunkWords)}
+	refs := []history.PageRef{
+		{Number: 1, URL: page.URL, Chunks: []int{1}},
+		{Number: 2, URL: "https://gone.example", Chunks: []int{0}},
+		{Number: 3, URL: page.URL},
+	}
+	sources := Restore([]crawler.CrawlResult{page, {URL: "https://gone.example", Error: errors.New("missing")}, page}, refs)
+
+	if len(sources[0].Chunks) != 1 || sources[0].Chunks[0].Index != 1 || sources[0].Total != 2 {
+		t.Errorf("source 1: %+v", sources[0])
+	}
+	if len(sources[1].Chunks) != 0 || sources[1].Number != 2 {
+		t.Errorf("unreadable copy should keep its number without chunks: %+v", sources[1])
+	}
+	if len(sources[2].Chunks) != 2 {
+		t.Errorf("ref without chunk list should restore the whole page, got %d chunks", len(sources[2].Chunks))
+	}
+}
@@ -0,0 +1,166 @@
+// Package cost estimates what each answer costs: the energy a local model
+// uses, priced at the electricity rate, or the list price of a cloud
+// model's tokens. The figures are rough guides for comparing models, not
+// measurements.
+package cost
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/config"
+)
+
+// Energy per operation for the model-size estimate used when Ollama reports
+// no timings. Generating a token is bound by memory bandwidth and costs far
+// more per operation than reading the prompt in batches.
+const (
+	joulesPerGenerateFLOP = 5e-10
+	joulesPerPromptFLOP   = 5e-11
+)
+
+// Estimate is the cost of one answer
+type Estimate struct {
+	Model        string
+	PromptTokens int
+	AnswerTokens int
+	Cloud        bool    // priced per token rather than by energy
+	Priced       bool    // a price was known; a cloud model without one costs 0
+	WattHours    float64 // energy used locally; 0 for cloud models
+	Cost         float64 // in the currency of model_prices and electricity_price
+}
+
+// String renders e for the line after an answer, e.g.
+// "~0.42 Wh (≈$0.0001), 1830 → 412 tokens"
+func (e Estimate) String() string {
+	tokens := fmt.Sprintf("%d → %d tokens", e.PromptTokens, e.AnswerTokens)
+	switch {
+	case e.Cloud && !e.Priced:
+		return fmt.Sprintf("%s; no price set for %s in model_prices", tokens, e.Model)
+	case e.Cloud:
+		return fmt.Sprintf("≈%s, %s", FormatCost(e.Cost), tokens)
+	}
+	return fmt.Sprintf("~%.2f Wh (≈%s), %s", e.WattHours, FormatCost(e.Cost), tokens)
+}
+
+// FormatCost shows small amounts with enough digits to compare them
+func FormatCost(c float64) string {
+	switch {
+	case c == 0:
+		return "$0"
+	case c < 0.01:
+		return fmt.Sprintf("$%.4f", c)
+	}
+	return fmt.Sprintf("$%.2f", c)
+}
+
+// Estimator prices answers with the rates in the config
+type Estimator struct {
+	Prices           map[string]config.ModelPrice // per million tokens
+	Watts            float64                      // drawn while a local model runs
+	ElectricityPrice float64                      // per kWh
+}
+
+// New creates an estimator from the config
+func New(cfg *config.Config) *Estimator {
+	return &Estimator{Prices: cfg.ModelPrices, Watts: cfg.LocalWatts, ElectricityPrice: cfg.ElectricityPrice}
+}
+
+// Turn estimates one answer. Cloud models, those with a price or a "cloud"
+// tag, are priced per token. Local models are charged for the time Ollama
+// spent on the prompt and the answer at Watts; without timings, energy is
+// estimated from params, the model's parameter count.
+func (e *Estimator) Turn(model string, promptTokens, answerTokens int, busy time.Duration, params float64) Estimate {
+	est := Estimate{Model: model, PromptTokens: promptTokens, AnswerTokens: answerTokens}
+	if price, ok := e.price(model); ok || IsCloud(model) {
+		est.Cloud, est.Priced = true, ok
+		est.Cost = (float64(promptTokens)*price.Input + float64(answerTokens)*price.Output) / 1e6
+		return est
+	}
+
+	joules := busy.Seconds() * e.Watts
+	if busy <= 0 && params > 0 {
+		// About two operations per parameter for each token
+		joules = 2 * params * (float64(answerTokens)*joulesPerGenerateFLOP + float64(promptTokens)*joulesPerPromptFLOP)
+	}
+	est.WattHours = joules / 3600
+	est.Cost = est.WattHours / 1000 * e.ElectricityPrice
+	est.Priced = true
+	return est
+}
+
+// price finds model's price by its full name or its name without the tag
+func (e *Estimator) price(model string) (config.ModelPrice, bool) {
+	if p, ok := e.Prices[model]; ok {
+		return p, true
+	}
+	base, _, _ := strings.Cut(model, ":")
+	p, ok := e.Prices[base]
+	return p, ok
+}
+
+// IsCloud reports whether model runs on Ollama's cloud, which names such
+// models with a "cloud" tag, e.g. gpt-oss:120b-cloud
+func IsCloud(model string) bool {
+	_, tag, _ := strings.Cut(model, ":")
+	return tag == "cloud" || strings.HasSuffix(tag, "-cloud")
+}
+
+// ParseParams reads a parameter size as Ollama reports it, e.g. "8.0B" or
+// "350M", returning 0 when it cannot
+func ParseParams(size string) float64 {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0
+	}
+	scale := map[byte]float64{'K': 1e3, 'M': 1e6, 'B': 1e9, 'T': 1e12}[size[len(size)-1]]
+	if scale == 0 {
+		return 0
+	}
+	n, err := strconv.ParseFloat(size[:len(size)-1], 64)
+	if err != nil {
+		return 0
+	}
+	return n * scale
+}
+
+// Session adds up the estimates of a session's answers
+type Session struct {
+	mu     sync.Mutex
+	totals map[string]*Estimate // by model
+	order  []string
+}
+
+// Add records one answer
+func (s *Session) Add(e Estimate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.totals == nil {
+		s.totals = make(map[string]*Estimate)
+	}
+	t, ok := s.totals[e.Model]
+	if !ok {
+		t = &Estimate{Model: e.Model, Cloud: e.Cloud, Priced: true}
+		s.totals[e.Model] = t
+		s.order = append(s.order, e.Model)
+	}
+	t.PromptTokens += e.PromptTokens
+	t.AnswerTokens += e.AnswerTokens
+	t.WattHours += e.WattHours
+	t.Cost += e.Cost
+	t.Priced = t.Priced && e.Priced
+}
+
+// ByModel returns the totals for each model used, in order of first use
+func (s *Session) ByModel() []Estimate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Estimate, 0, len(s.order))
+	for _, m := range s.order {
+		out = append(out, *s.totals[m])
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package cost
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/config"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func newEstimator() *Estimator {
+	return &Estimator{
+		Prices:           map[string]config.ModelPrice{"gpt-oss:120b-cloud": {Input: 0.15, Output: 0.60}, "mistral-large": {Input: 2, Output: 6}},
+		Watts:            300,
+		ElectricityPrice: 0.20,
+	}
+}
+
+func TestTurnLocalFromTimings(t *testing.T) {
+	e := newEstimator().Turn("llama3.1:8b", 1000, 200, 12*time.Second, 0)
+	// 12 s at 300 W is 1 Wh, 0.001 kWh at 0.20
+	if e.Cloud || !near(e.WattHours, 1) || !near(e.Cost, 0.0002) {
+		t.Errorf("Turn = %+v, want 1 Wh costing 0.0002", e)
+	}
+}
+
+func TestTurnLocalFromModelSize(t *testing.T) {
+	e := newEstimator().Turn("llama3.1:8b", 1000, 100, 0, 8e9)
+	want := 2 * 8e9 * (100*joulesPerGenerateFLOP + 1000*joulesPerPromptFLOP) / 3600
+	if !near(e.WattHours, want) || e.WattHours == 0 {
+		t.Errorf("WattHours = %g, want %g", e.WattHours, want)
+	}
+}
+
+func TestTurnCloud(t *testing.T) {
+	est := newEstimator()
+	e := est.Turn("gpt-oss:120b-cloud", 2_000_000, 1_000_000, time.Minute, 0)
+	if !e.Cloud || !e.Priced || e.WattHours != 0 || !near(e.Cost, 0.9) {
+		t.Errorf("Turn = %+v, want 0.9 for the cloud model", e)
+	}
+	// Priced by the name without the tag
+	if e := est.Turn("mistral-large:latest", 1_000_000, 0, 0, 0); !e.Cloud || !near(e.Cost, 2) {
+		t.Errorf("Turn = %+v, want 2", e)
+	}
+	// Cloud tag without a price
+	e = est.Turn("deepseek-v3.1:671b-cloud", 100, 100, time.Second, 0)
+	if !e.Cloud || e.Priced || e.Cost != 0 || !strings.Contains(e.String(), "no price set") {
+		t.Errorf("Turn = %+v (%s), want an unpriced cloud model", e, e)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	cases := map[string]float64{"8.0B": 8e9, "350M": 350e6, " 70b ": 70e9, "1.2T": 1.2e12, "": 0, "B": 0, "big": 0}
+	for in, want := range cases {
+		if got := ParseParams(in); !near(got, want) {
+			t.Errorf("ParseParams(%q) = %g, want %g", in, got, want)
+		}
+	}
+}
+
+func TestSessionTotalsByModel(t *testing.T) {
+	est := newEstimator()
+	var s Session
+	s.Add(est.Turn("llama3.1:8b", 100, 10, 12*time.Second, 0))
+	s.Add(est.Turn("gpt-oss:120b-cloud", 1_000_000, 0, 0, 0))
+	s.Add(est.Turn("llama3.1:8b", 200, 20, 24*time.Second, 0))
+
+	models := s.ByModel()
+	if len(models) != 2 || models[0].Model != "llama3.1:8b" || models[1].Model != "gpt-oss:120b-cloud" {
+		t.Fatalf("ByModel = %+v, want llama then gpt-oss", models)
+	}
+	if m := models[0]; m.PromptTokens != 300 || m.AnswerTokens != 30 || !near(m.WattHours, 3) {
+		t.Errorf("llama totals = %+v, want 300 → 30 tokens and 3 Wh", m)
+	}
+	if m := models[1]; !m.Cloud || !near(m.Cost, 0.15) {
+		t.Errorf("gpt-oss totals = %+v, want 0.15", m)
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	cases := map[float64]string{0: "$0", 0.00012: "$0.0001", 1.5: "$1.50"}
+	for in, want := range cases {
+		if got := FormatCost(in); got != want {
+			t.Errorf("FormatCost(%g) = %q, want %q", in, got, want)
+		}
+	}
+}
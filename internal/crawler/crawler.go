@@ -3,19 +3,25 @@ package crawler
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
+
+	"web-ollama/internal/errs"
 )
 
 // CrawlResult represents the result of crawling a single URL
 type CrawlResult struct {
-	URL      string
-	Title    string
-	Content  string
-	Error    error
-	Duration time.Duration
+	URL        string
+	Title      string
+	Content    string
+	Error      error
+	Duration   time.Duration
+	Status     int       // HTTP status, 0 if there was no response
+	Saved      time.Time // when a reused saved copy was fetched; zero for a new crawl
+	Translated string    // language Content was machine-translated from; "" if it is the page's own text
 }
 
 // Crawler handles web page crawling
@@ -25,26 +31,78 @@ type Crawler struct {
 	maxSize        int64
 	userAgent      string
 	maxWorkers     int
+	tuner          *Tuner // sizes the worker pool when set
+	browser        *browserHeaders // browser-like headers in place of userAgent when set
+	extractors     *Registry // turns each response into text
+	policy         func(rawURL string) error // refuses URLs before they are fetched, redirects included
 }
 
 // NewCrawler creates a new crawler instance
 func NewCrawler(timeout time.Duration, maxWorkers int, maxSize int64, userAgent string) *Crawler {
-	return &Crawler{
+	c := &Crawler{
 		httpClient: &http.Client{
 			Timeout: timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				// Allow up to 10 redirects
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
 		},
 		timeout:    timeout,
 		maxSize:    maxSize,
 		userAgent:  userAgent,
 		maxWorkers: maxWorkers,
+		extractors: DefaultRegistry(),
+	}
+	c.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		// Allow up to 10 redirects
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		return c.allowed(req.URL.String())
+	}
+	return c
+}
+
+// SetTransport routes page fetches through rt
+func (c *Crawler) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// SetTimeout changes the per-page fetch timeout
+func (c *Crawler) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+	c.timeout = timeout
+}
+
+// SetExtractors replaces the registry that turns responses into text
+func (c *Crawler) SetExtractors(r *Registry) {
+	c.extractors = r
+}
+
+// Extractors returns the registry that turns responses into text, for
+// registering more handlers
+func (c *Crawler) Extractors() *Registry {
+	return c.extractors
+}
+
+// SetPolicy makes the crawler refuse every URL, including redirect targets,
+// for which policy returns an error
+func (c *Crawler) SetPolicy(policy func(rawURL string) error) {
+	c.policy = policy
+}
+
+// allowed applies the crawler's policy to rawURL
+func (c *Crawler) allowed(rawURL string) error {
+	if c.policy == nil {
+		return nil
 	}
+	return c.policy(rawURL)
+}
+
+// SetTuner lets t size the worker pool in place of the fixed worker count
+func (c *Crawler) SetTuner(t *Tuner) {
+	c.tuner = t
+}
+
+// Tuner returns the crawler's tuner, or nil if its worker count is fixed
+func (c *Crawler) Tuner() *Tuner {
+	return c.tuner
 }
 
 // CrawlURLs crawls multiple URLs in parallel and returns results
@@ -52,6 +110,9 @@ func (c *Crawler) CrawlURLs(ctx context.Context, urls []string) []CrawlResult {
 	if len(urls) == 0 {
 		return []CrawlResult{}
 	}
+	if c.tuner != nil {
+		return c.crawlTuned(ctx, urls)
+	}
 
 	// Create channels for job distribution and result collection
 	jobs := make(chan string, len(urls))
@@ -70,7 +131,9 @@ func (c *Crawler) CrawlURLs(ctx context.Context, urls []string) []CrawlResult {
 		go func() {
 			defer wg.Done()
 			for url := range jobs {
-				results <- c.crawlSingle(ctx, url)
+				result := c.crawlSingle(ctx, url)
+				logCrawl(result)
+				results <- result
 			}
 		}()
 	}
@@ -105,6 +168,11 @@ func (c *Crawler) crawlSingle(ctx context.Context, urlStr string) CrawlResult {
 		Duration: 0,
 	}
 
+	if err := c.allowed(urlStr); err != nil {
+		result.Error = err
+		return result
+	}
+
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
@@ -114,8 +182,7 @@ func (c *Crawler) crawlSingle(ctx context.Context, urlStr string) CrawlResult {
 	}
 
 	// Set headers
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	c.setHeaders(req)
 
 	// Execute request
 	resp, err := c.httpClient.Do(req)
@@ -125,32 +192,29 @@ func (c *Crawler) crawlSingle(ctx context.Context, urlStr string) CrawlResult {
 		return result
 	}
 	defer resp.Body.Close()
+	result.Status = resp.StatusCode
 
 	// Check status code
 	if resp.StatusCode != 200 {
 		result.Error = fmt.Errorf("HTTP %d", resp.StatusCode)
+		if blockedStatus(resp.StatusCode) {
+			result.Error = errs.New(errs.CrawlBlocked, urlStr, result.Error)
+		}
 		result.Duration = time.Since(start)
 		return result
 	}
 
-	// Check content type
+	// Pick the extractor for the page's domain or content type
 	contentType := resp.Header.Get("Content-Type")
-	if contentType != "" && !contains(contentType, "text/html") && !contains(contentType, "application/xhtml") {
-		result.Error = fmt.Errorf("non-HTML content type: %s", contentType)
-		result.Duration = time.Since(start)
-		return result
-	}
-
-	// Read body with size limit
-	body, err := ReadLimitedBody(resp.Body, c.maxSize)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to read body: %w", err)
+	extract, ok := c.extractors.Lookup(urlStr, contentType)
+	if !ok {
+		result.Error = fmt.Errorf("unsupported content type: %s", contentType)
 		result.Duration = time.Since(start)
 		return result
 	}
 
-	// Extract text from HTML
-	title, text, err := ExtractText(body, urlStr)
+	// Extract text as the body streams in, reading at most maxSize bytes
+	title, text, err := extract(io.LimitReader(resp.Body, c.maxSize), urlStr)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to extract text: %w", err)
 		result.Duration = time.Since(start)
@@ -164,10 +228,21 @@ func (c *Crawler) crawlSingle(ctx context.Context, urlStr string) CrawlResult {
 	return result
 }
 
-// contains checks if a string contains a substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr || len(s) > len(substr) &&
-		(s[:len(substr)] == substr ||
-		strings.Contains(strings.ToLower(s), strings.ToLower(substr))))
+// logCrawl records the outcome of a single crawl
+func logCrawl(result CrawlResult) {
+	if result.Error != nil {
+		slog.Warn("crawl failed", "url", result.URL, "duration", result.Duration, "error", result.Error)
+		return
+	}
+	slog.Info("crawl", "url", result.URL, "title", result.Title, "chars", len(result.Content), "duration", result.Duration)
+}
+
+// blockedStatus reports whether a status code means the site refused the
+// crawler rather than failed: auth walls, bot blocks and rate limits
+func blockedStatus(code int) bool {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusUnavailableForLegalReasons, 999:
+		return true
+	}
+	return false
 }
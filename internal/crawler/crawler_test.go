@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/errs"
+	"web-ollama/internal/testkit"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestCrawlFixturesMatchGolden(t *testing.T) {
+	site := testkit.NewSite(t)
+	c := NewCrawler(5*time.Second, 2, 1024*1024, "web-ollama-test")
+
+	for _, name := range testkit.FixtureNames() {
+		t.Run(name, func(t *testing.T) {
+			results := c.CrawlURLs(context.Background(), []string{site.URL + "/" + name})
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+			if r.Error != nil {
+				t.Fatalf("crawl failed: %v", r.Error)
+			}
+
+			got := "Title: " + r.Title + "\n\n" + r.Content + "\n"
+			golden := filepath.Join("testdata", strings.TrimSuffix(name, ".html")+".golden")
+			if *update {
+				if err := os.MkdirAll("testdata", 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("extracted text differs from %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestCrawlReportsHTTPErrors(t *testing.T) {
+	site := testkit.NewSite(t)
+	c := NewCrawler(5*time.Second, 2, 1024*1024, "web-ollama-test")
+
+	results := c.CrawlURLs(context.Background(), []string{
+		site.URL + "/status/404",
+		site.URL + "/status/500",
+		site.URL + "/status/403",
+	})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for _, r := range results {
+		if r.Error == nil {
+			t.Errorf("%s: expected an error", r.URL)
+		}
+		if blocked := strings.HasSuffix(r.URL, "/403"); errs.Is(r.Error, errs.CrawlBlocked) != blocked {
+			t.Errorf("%s: error %v, want CrawlBlocked only for 403", r.URL, r.Error)
+		}
+		if r.Content != "" {
+			t.Errorf("%s: expected no content, got %q", r.URL, r.Content)
+		}
+	}
+}
+
+func TestCrawlEmptyURLList(t *testing.T) {
+	c := NewCrawler(time.Second, 2, 1024, "web-ollama-test")
+	if results := c.CrawlURLs(context.Background(), nil); len(results) != 0 {
+		t.Errorf("got %d results for no URLs", len(results))
+	}
+}
+
+func TestCrawlPolicyRefusesURLsAndRedirects(t *testing.T) {
+	var hits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, r.URL.Path)
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/private/page", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<p>page</p>"))
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(5*time.Second, 1, 1024*1024, "web-ollama-test")
+	c.SetPolicy(func(rawURL string) error {
+		if strings.Contains(rawURL, "/private/") {
+			return fmt.Errorf("refused")
+		}
+		return nil
+	})
+	results := c.CrawlURLs(context.Background(), []string{srv.URL + "/private/a", srv.URL + "/moved", srv.URL + "/public"})
+	for _, r := range results {
+		if failed := r.Error != nil; failed == strings.HasSuffix(r.URL, "/public") {
+			t.Errorf("%s: error %v", r.URL, r.Error)
+		}
+	}
+	if strings.Join(hits, ",") != "/moved,/public" && strings.Join(hits, ",") != "/public,/moved" {
+		t.Errorf("server saw %v", hits)
+	}
+}
@@ -2,88 +2,92 @@ package crawler
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"strings"
 
 	"golang.org/x/net/html"
 )
 
-// ExtractText extracts clean text from HTML content
-func ExtractText(htmlContent []byte, sourceURL string) (title string, text string, err error) {
-	doc, err := html.Parse(bytes.NewReader(htmlContent))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
-	}
-
-	// Extract title
-	title = extractTitle(doc)
+// maxWords is how much of a page's text is kept
+const maxWords = 500
 
-	// Extract body text
-	text = extractBodyText(doc)
+// maxTokenBytes caps how much of a single token, such as an inline script,
+// the tokenizer buffers; a page with a larger one is cut off there
+const maxTokenBytes = 512 * 1024
 
-	// Clean up whitespace
-	text = cleanText(text)
-
-	// Truncate to reasonable size (approximately 500 words)
-	text = truncateWords(text, 500)
-
-	return title, text, nil
+// skippedTags hold navigation and code rather than page content
+var skippedTags = map[string]bool{
+	"script": true, "style": true, "nav": true, "footer": true, "header": true, "aside": true,
 }
 
-// extractTitle finds and returns the page title
-func extractTitle(n *html.Node) string {
-	if n.Type == html.ElementNode && n.Data == "title" {
-		return getNodeText(n)
-	}
-
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if title := extractTitle(c); title != "" {
-			return title
-		}
-	}
-
-	return ""
+// ExtractText extracts clean text from HTML content
+func ExtractText(htmlContent []byte, sourceURL string) (title string, text string, err error) {
+	return ExtractReader(bytes.NewReader(htmlContent), sourceURL)
 }
 
-// extractBodyText extracts text from the body, excluding unwanted elements
-func extractBodyText(n *html.Node) string {
-	// Skip unwanted elements
-	if n.Type == html.ElementNode {
-		tag := n.Data
-		if tag == "script" || tag == "style" || tag == "nav" || tag == "footer" || tag == "header" || tag == "aside" {
-			return ""
+// ExtractReader extracts the title and clean text from HTML as it streams
+// in, without holding the page in memory. It stops reading once it has the
+// title and more text than is kept.
+func ExtractReader(r io.Reader, sourceURL string) (title string, text string, err error) {
+	z := html.NewTokenizer(r)
+	z.SetMaxBuf(maxTokenBytes)
+
+	var body strings.Builder
+	words := 0
+	skip := 0           // depth inside skipped elements
+	inTitle := false    // the next text is the title
+	titleFound := false // stop looking for one
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				if !errors.Is(err, html.ErrBufferExceeded) {
+					return "", "", fmt.Errorf("failed to read HTML: %w", err)
+				}
+				slog.Debug("page cut off at an oversized token", "url", sourceURL, "limit", maxTokenBytes)
+			}
+			return title, truncateWords(cleanText(body.String()), maxWords), nil
+
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedTags[tag] {
+				skip++
+			}
+			if tag == "title" && !titleFound {
+				inTitle = true
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedTags[tag] && skip > 0 {
+				skip--
+			}
+			if tag == "title" {
+				inTitle = false
+			}
+
+		case html.TextToken:
+			chunk := string(z.Text())
+			if inTitle && chunk != "" {
+				title, titleFound, inTitle = chunk, true, false
+			}
+			if skip > 0 || words > maxWords {
+				break
+			}
+			body.WriteString(chunk)
+			body.WriteString(" ")
+			words += len(strings.Fields(chunk))
 		}
-	}
-
-	var text strings.Builder
-
-	// Add text from this node
-	if n.Type == html.TextNode {
-		text.WriteString(n.Data)
-		text.WriteString(" ")
-	}
-
-	// Recursively process children
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		text.WriteString(extractBodyText(c))
-	}
 
-	return text.String()
-}
-
-// getNodeText extracts all text from a node and its children
-func getNodeText(n *html.Node) string {
-	if n.Type == html.TextNode {
-		return n.Data
-	}
-
-	var text strings.Builder
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		text.WriteString(getNodeText(c))
+		if words > maxWords && titleFound {
+			return title, truncateWords(cleanText(body.String()), maxWords), nil
+		}
 	}
-
-	return text.String()
 }
 
 // cleanText removes excessive whitespace and normalizes text
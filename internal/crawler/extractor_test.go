@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// countingReader records how much of a page was read
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestExtractReaderSkipsBoilerplate(t *testing.T) {
+	page := `<html><head><title>A &amp; B</title><script>var x = "<p>no</p>";</script></head>
+<body><header><nav>Menu <b>Home</b></nav> Site</header>
+<p>First&nbsp;para<b>graph</b>.</p><!-- hidden --><aside>Ads</aside><p>Second</p>
+<footer>Legal</footer></body></html>`
+
+	title, text, err := ExtractReader(strings.NewReader(page), "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "A & B" {
+		t.Errorf("title = %q", title)
+	}
+	if want := "A & B First para graph . Second"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractReaderStopsAfterEnoughText(t *testing.T) {
+	page := "<title>Long</title><p>" + strings.Repeat("word ", 2000) + "</p>" + strings.Repeat("<p>tail</p>", 100000)
+	r := &countingReader{r: strings.NewReader(page)}
+
+	title, text, err := ExtractReader(r, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Long" || !strings.HasSuffix(text, "word...") || len(strings.Fields(text)) != maxWords {
+		t.Errorf("title = %q, text has %d words", title, len(strings.Fields(text)))
+	}
+	if r.n > len(page)/10 {
+		t.Errorf("read %d of %d bytes, want it to stop early", r.n, len(page))
+	}
+}
+
+func TestExtractReaderCutsOffOversizedTokens(t *testing.T) {
+	page := "<title>Big</title><p>Before</p><script>" + strings.Repeat("x", 2*maxTokenBytes) + "</script><p>After</p>"
+
+	title, text, err := ExtractReader(strings.NewReader(page), "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Big" || text != "Big Before" {
+		t.Errorf("title = %q, text = %q", title, text)
+	}
+}
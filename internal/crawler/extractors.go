@@ -0,0 +1,212 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExtractPlain reads plain text or Markdown. The title is the first
+// Markdown heading, or else the file name.
+func ExtractPlain(r io.Reader, sourceURL string) (title string, text string, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read text: %w", err)
+	}
+	title = FirstHeading(string(data))
+	if title == "" {
+		title = fileName(sourceURL)
+	}
+	return title, truncateWords(cleanText(string(data)), maxWords), nil
+}
+
+// ExtractJSON flattens a JSON document into "path: value" pairs, so API
+// responses read as text. A top-level title or name field is the title.
+func ExtractJSON(r io.Reader, sourceURL string) (title string, text string, err error) {
+	var doc interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return "", "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if obj, ok := doc.(map[string]interface{}); ok {
+		for _, key := range []string{"title", "name"} {
+			if s, ok := obj[key].(string); ok && s != "" {
+				title = s
+				break
+			}
+		}
+	}
+	if title == "" {
+		title = fileName(sourceURL)
+	}
+
+	var pairs []string
+	flattenJSON("", doc, &pairs)
+	return title, truncateWords(cleanText(strings.Join(pairs, "; ")), maxWords), nil
+}
+
+// flattenJSON appends "path: value" for each leaf of v
+func flattenJSON(prefix string, v interface{}, pairs *[]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flattenJSON(p, v[k], pairs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenJSON(fmt.Sprintf("%s[%d]", prefix, i), item, pairs)
+		}
+	case nil:
+	default:
+		*pairs = append(*pairs, fmt.Sprintf("%s: %v", prefix, v))
+	}
+}
+
+// pdfTimeout bounds one pdftotext run
+const pdfTimeout = time.Minute
+
+// ExtractPDF converts a PDF to text with pdftotext from poppler-utils. The
+// title is the document's first line.
+func ExtractPDF(r io.Reader, sourceURL string) (title string, text string, err error) {
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return "", "", fmt.Errorf("pdftotext not found; install poppler-utils to read PDFs")
+	}
+	tmp, err := os.CreateTemp("", "web-ollama-*.pdf")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pdfTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "pdftotext", "-enc", "UTF-8", tmp.Name(), "-").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("pdftotext failed: %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			title = line
+			break
+		}
+	}
+	if title == "" {
+		title = fileName(sourceURL)
+	}
+	return title, truncateWords(cleanText(string(out)), maxWords), nil
+}
+
+var (
+	ogTitle        = regexp.MustCompile(`<meta\s+(?:property|name)="og:title"\s+content="([^"]*)"`)
+	ogDescription  = regexp.MustCompile(`<meta\s+(?:property|name)="og:description"\s+content="([^"]*)"`)
+	youtubeDetails = regexp.MustCompile(`"shortDescription":("(?:[^"\\]|\\.)*")`)
+	youtubeChannel = regexp.MustCompile(`"ownerChannelName":("(?:[^"\\]|\\.)*")`)
+)
+
+// youtubeMaxPrefix is how much of a watch page is searched for the player
+// data, which sits near the start
+const youtubeMaxPrefix = 4 << 20
+
+// ExtractYouTube reads a video's title, channel and full description from
+// its watch page; the page's visible text is mostly navigation. Other
+// YouTube pages are read as HTML.
+func ExtractYouTube(r io.Reader, sourceURL string) (title string, text string, err error) {
+	data, err := io.ReadAll(io.LimitReader(r, youtubeMaxPrefix))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read page: %w", err)
+	}
+	if m := ogTitle.FindSubmatch(data); m != nil {
+		title = html.UnescapeString(string(m[1]))
+	}
+	description := jsonString(youtubeDetails.FindSubmatch(data))
+	if description == "" {
+		if m := ogDescription.FindSubmatch(data); m != nil {
+			description = html.UnescapeString(string(m[1]))
+		}
+	}
+	if title == "" || description == "" {
+		return ExtractReader(bytes.NewReader(data), sourceURL)
+	}
+
+	text = "Video: " + title + "."
+	if channel := jsonString(youtubeChannel.FindSubmatch(data)); channel != "" {
+		text += " Channel: " + channel + "."
+	}
+	text += " Description: " + description
+	return title, truncateWords(cleanText(text), maxWords), nil
+}
+
+// jsonString decodes the quoted JSON string captured by a submatch
+func jsonString(m [][]byte) string {
+	if m == nil {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(m[1], &s) != nil {
+		return ""
+	}
+	return s
+}
+
+// ExtractGitHubRaw reads a file from raw.githubusercontent.com as text,
+// whatever its extension, titled with its repository and path
+func ExtractGitHubRaw(r io.Reader, sourceURL string) (title string, text string, err error) {
+	_, text, err = ExtractPlain(r, sourceURL)
+	if err != nil {
+		return "", "", err
+	}
+	// Paths are /owner/repo/ref/file...
+	if u, err := url.Parse(sourceURL); err == nil {
+		if parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 4); len(parts) == 4 {
+			return parts[0] + "/" + parts[1] + ": " + parts[3], text, nil
+		}
+	}
+	return fileName(sourceURL), text, nil
+}
+
+// FirstHeading returns the text of the first Markdown "# " heading, or ""
+func FirstHeading(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:])
+		}
+	}
+	return ""
+}
+
+// fileName is the last element of a URL's path, or its host
+func fileName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if name := path.Base(u.Path); name != "/" && name != "." {
+		return name
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// DefaultBrowserAgents are current desktop browser user agents, rotated
+// when browser headers are on and none are configured
+var DefaultBrowserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+}
+
+// browserHeaders makes requests look like a browser's: a rotating user
+// agent, the Accept and Accept-Language a browser sends, and a Referer
+type browserHeaders struct {
+	agents         []string
+	acceptLanguage string
+	next           atomic.Uint32
+}
+
+// SetBrowserHeaders sends browser-like headers in place of the crawler's
+// own user agent, rotating through agents (DefaultBrowserAgents if empty).
+// Many sites refuse unknown user agents outright.
+func (c *Crawler) SetBrowserHeaders(agents []string, acceptLanguage string) {
+	if len(agents) == 0 {
+		agents = DefaultBrowserAgents
+	}
+	c.browser = &browserHeaders{agents: agents, acceptLanguage: acceptLanguage}
+}
+
+// setHeaders sets the request headers for a page fetch
+func (c *Crawler) setHeaders(req *http.Request) {
+	b := c.browser
+	if b == nil {
+		req.Header.Set("User-Agent", c.userAgent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		return
+	}
+
+	agent := b.agents[int(b.next.Add(1)-1)%len(b.agents)]
+	req.Header.Set("User-Agent", agent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if b.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", b.acceptLanguage)
+	}
+	// As if following a link from the site's front page
+	req.Header.Set("Referer", req.URL.Scheme+"://"+req.URL.Host+"/")
+	req.Header.Set("Upgrade-Insecure-Requests", "1")
+}
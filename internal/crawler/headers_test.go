@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// headerRecorder serves a small page and records each request's headers
+func headerRecorder(t *testing.T) (*httptest.Server, func() []http.Header) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>Page</title><p>Hello</p>"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+}
+
+func TestCrawlSendsOwnUserAgentByDefault(t *testing.T) {
+	srv, seen := headerRecorder(t)
+	c := NewCrawler(5*time.Second, 1, 1024*1024, "web-ollama-test")
+	c.CrawlURLs(context.Background(), []string{srv.URL + "/a"})
+
+	h := seen()[0]
+	if h.Get("User-Agent") != "web-ollama-test" || h.Get("Referer") != "" || h.Get("Accept-Language") != "" {
+		t.Errorf("headers = %v, want only the crawler's own user agent", h)
+	}
+}
+
+func TestCrawlRotatesBrowserHeaders(t *testing.T) {
+	srv, seen := headerRecorder(t)
+	c := NewCrawler(5*time.Second, 1, 1024*1024, "web-ollama-test")
+	c.SetBrowserHeaders([]string{"Agent A", "Agent B"}, "de-DE,de;q=0.9")
+	c.CrawlURLs(context.Background(), []string{srv.URL + "/1", srv.URL + "/2", srv.URL + "/3"})
+
+	agents := map[string]int{}
+	for _, h := range seen() {
+		agents[h.Get("User-Agent")]++
+		if h.Get("Accept-Language") != "de-DE,de;q=0.9" || h.Get("Referer") != srv.URL+"/" {
+			t.Errorf("headers = %v", h)
+		}
+	}
+	if agents["Agent A"] != 2 || agents["Agent B"] != 1 {
+		t.Errorf("user agents used = %v, want A twice and B once", agents)
+	}
+}
@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxLinks is how many links are kept per page
+const maxLinks = 500
+
+// ResolveURL resolves ref, an href or sitemap <loc> as written, against
+// base. Relative paths, "../" steps, query-only and protocol-relative
+// ("//host/path") references all become absolute. It reports false for
+// references that are not http(s) pages, such as mailto: or javascript:.
+func ResolveURL(base *url.URL, ref string) (string, bool) {
+	u := resolveURL(base, ref)
+	if u == nil {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func resolveURL(base *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	u := r
+	if base != nil {
+		u = base.ResolveReference(r)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+// ExtractLinks lists the distinct pages an HTML page links to, as absolute
+// URLs without fragments. Relative hrefs are resolved against the page's
+// <base href> when it has one, and otherwise against pageURL, which should
+// be the URL the page was finally served from.
+func ExtractLinks(r io.Reader, pageURL string) ([]string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL %q: %w", pageURL, err)
+	}
+	z := html.NewTokenizer(r)
+	z.SetMaxBuf(maxTokenBytes)
+
+	var links []string
+	seen := map[string]bool{}
+	baseSet := false // only the first <base href> counts
+	for len(links) < maxLinks {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF && !errors.Is(err, html.ErrBufferExceeded) {
+				return links, fmt.Errorf("failed to read HTML: %w", err)
+			}
+			return links, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			if !hasAttr || (tag != "a" && tag != "base") {
+				break
+			}
+			href := attr(z, "href")
+			if tag == "base" {
+				if !baseSet {
+					baseSet = true
+					if u := resolveURL(base, href); u != nil {
+						base = u
+					}
+				}
+				break
+			}
+			u := resolveURL(base, href)
+			if u == nil {
+				break
+			}
+			u.Fragment, u.RawFragment = "", ""
+			if link := u.String(); !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links, nil
+}
+
+// attr returns the value of the current tag's attribute key, or ""
+func attr(z *html.Tokenizer, key string) string {
+	for {
+		k, v, more := z.TagAttr()
+		if string(k) == key {
+			return string(v)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// SiteLinks lists the pages of site, a domain or URL, that its home page
+// links to. It stands in for Sitemap on sites without one, reaching one
+// level below the home page.
+func (c *Crawler) SiteLinks(ctx context.Context, site string) ([]SitemapURL, error) {
+	root, err := siteRoot(site)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.fetch(ctx, root.String()+"/")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	links, err := ExtractLinks(io.LimitReader(resp.Body, c.maxSize), resp.Request.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	var pages []SitemapURL
+	for _, link := range links {
+		if sameSite(link, root.Hostname()) {
+			pages = append(pages, SitemapURL{Loc: link})
+		}
+	}
+	return pages, nil
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractLinks(t *testing.T) {
+	page := `<html><head><title>Docs</title></head><body>
+<a href="install">Install</a>
+<a href="../blog/post?id=2#comments">Post</a>
+<a href="//cdn.example.org/file.pdf">PDF</a>
+<a href="https://go.dev/">Go</a>
+<a href="install#step-2">Again</a>
+<a href="mailto:someone@example.com">Mail</a>
+<a href="javascript:void(0)">Menu</a>
+<a name="top">No href</a>
+</body></html>`
+
+	links, err := ExtractLinks(strings.NewReader(page), "https://example.com/docs/guide/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://example.com/docs/guide/install",
+		"https://example.com/docs/blog/post?id=2",
+		"https://cdn.example.org/file.pdf",
+		"https://go.dev/",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links = %q, want %q", links, want)
+	}
+}
+
+func TestExtractLinksHonorsBaseHref(t *testing.T) {
+	page := `<head><base href="/v2/"><base href="/ignored/"></head>
+<body><a href="api">API</a><a href="/about">About</a></body>`
+
+	links, err := ExtractLinks(strings.NewReader(page), "https://example.com/v1/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/v2/api", "https://example.com/about"}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links = %q, want %q", links, want)
+	}
+}
+
+func TestSiteLinksWithoutSitemap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/home/", http.StatusFound)
+		case "/home/":
+			fmt.Fprint(w, `<a href="loops">Loops</a><a href="https://elsewhere.example/x">Out</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(5*time.Second, 2, 1<<20, "web-ollama-test")
+	pages, err := c.SiteLinks(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Loc != srv.URL+"/home/loops" {
+		t.Errorf("pages = %+v, want the link resolved against the redirected page", pages)
+	}
+}
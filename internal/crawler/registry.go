@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ExtractFunc reads a fetched body, at most the crawler's size limit, and
+// returns the page's title and text
+type ExtractFunc func(r io.Reader, sourceURL string) (title string, text string, err error)
+
+// Registry chooses how each fetched page is turned into text: by the page's
+// domain first, then by its MIME type. Adding a handler for new content
+// means registering an ExtractFunc, not changing the crawler.
+type Registry struct {
+	mu           sync.RWMutex
+	byDomain     map[string]ExtractFunc
+	htmlByDomain map[string]ExtractFunc
+	byType       map[string]ExtractFunc
+}
+
+// NewRegistry returns a registry without extractors
+func NewRegistry() *Registry {
+	return &Registry{
+		byDomain:     make(map[string]ExtractFunc),
+		htmlByDomain: make(map[string]ExtractFunc),
+		byType:       make(map[string]ExtractFunc),
+	}
+}
+
+// DefaultRegistry handles HTML, plain text, Markdown, JSON and PDF (through
+// pdftotext), plus YouTube videos and raw GitHub files
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("text/html", ExtractReader)
+	r.Register("application/xhtml+xml", ExtractReader)
+	r.Register("text/plain", ExtractPlain)
+	r.Register("text/markdown", ExtractPlain)
+	r.Register("application/json", ExtractJSON)
+	r.Register("application/pdf", ExtractPDF)
+	r.RegisterDomain("youtube.com", ExtractYouTube)
+	r.RegisterDomain("youtu.be", ExtractYouTube)
+	r.RegisterDomain("raw.githubusercontent.com", ExtractGitHubRaw)
+	return r
+}
+
+// Register handles pages of a MIME type, e.g. "application/json"
+func (r *Registry) Register(mimeType string, fn ExtractFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byType[strings.ToLower(mimeType)] = fn
+}
+
+// RegisterDomain handles every page on a domain and its subdomains,
+// whatever its MIME type
+func (r *Registry) RegisterDomain(domain string, fn ExtractFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byDomain[strings.ToLower(domain)] = fn
+}
+
+// RegisterDomainHTML handles the HTML pages of a domain and its
+// subdomains, ahead of any extractor registered with RegisterDomain. Its
+// other pages go by MIME type as usual.
+func (r *Registry) RegisterDomainHTML(domain string, fn ExtractFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.htmlByDomain[strings.ToLower(domain)] = fn
+}
+
+// Lookup returns the extractor for a page, or false if its content can't be
+// read. Pages without a Content-Type are treated as HTML.
+func (r *Registry) Lookup(rawURL, contentType string) (ExtractFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mediaType := "text/html"
+	if contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType) // "" if malformed
+	}
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+
+	if u, err := url.Parse(rawURL); err == nil {
+		for host := strings.ToLower(u.Hostname()); strings.Contains(host, "."); _, host, _ = strings.Cut(host, ".") {
+			if fn, ok := r.htmlByDomain[host]; ok && isHTML {
+				return fn, true
+			}
+			if fn, ok := r.byDomain[host]; ok {
+				return fn, true
+			}
+		}
+	}
+
+	if mediaType == "" {
+		return nil, false
+	}
+	fn, ok := r.byType[mediaType]
+	return fn, ok
+}
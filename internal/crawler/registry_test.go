@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	r := DefaultRegistry()
+	tests := []struct {
+		url, contentType string
+		want             ExtractFunc
+	}{
+		{"https://example.com/", "text/html; charset=utf-8", ExtractReader},
+		{"https://example.com/", "", ExtractReader},
+		{"https://example.com/a.txt", "TEXT/PLAIN", ExtractPlain},
+		{"https://api.example.com/v1", "application/json", ExtractJSON},
+		{"https://example.com/a.pdf", "application/pdf", ExtractPDF},
+		{"https://www.youtube.com/watch?v=x", "text/html", ExtractYouTube},
+		{"https://youtu.be/x", "", ExtractYouTube},
+		{"https://raw.githubusercontent.com/o/r/main/main.go", "text/plain", ExtractGitHubRaw},
+	}
+	for _, tt := range tests {
+		fn, ok := r.Lookup(tt.url, tt.contentType)
+		if !ok || reflect.ValueOf(fn).Pointer() != reflect.ValueOf(tt.want).Pointer() {
+			t.Errorf("Lookup(%q, %q) picked the wrong extractor", tt.url, tt.contentType)
+		}
+	}
+
+	for _, contentType := range []string{"image/png", "application/zip", ";;"} {
+		if _, ok := r.Lookup("https://example.com/x", contentType); ok {
+			t.Errorf("Lookup found an extractor for %q", contentType)
+		}
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	r.Register("text/csv", func(io.Reader, string) (string, string, error) { return "csv", "", nil })
+	fn, ok := r.Lookup("https://example.com/data.csv", "text/csv; header=present")
+	if !ok {
+		t.Fatal("registered type not found")
+	}
+	if title, _, _ := fn(strings.NewReader(""), ""); title != "csv" {
+		t.Errorf("title = %q", title)
+	}
+	if _, ok := r.Lookup("https://example.com/", ""); ok {
+		t.Error("empty registry handled HTML")
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	title, text, err := ExtractJSON(strings.NewReader(`{"name":"web-ollama","tags":["go","llm"],"meta":{"stars":12}}`), "https://api.example.com/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "web-ollama" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{"meta.stars: 12", "tags[0]: go", "tags[1]: llm"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q lacks %q", text, want)
+		}
+	}
+}
+
+func TestExtractPlainAndGitHubRaw(t *testing.T) {
+	title, _, err := ExtractPlain(strings.NewReader("intro\n# Install\nrun make"), "https://example.com/docs/README.md")
+	if err != nil || title != "Install" {
+		t.Errorf("ExtractPlain title = %q, %v", title, err)
+	}
+	title, _, _ = ExtractPlain(strings.NewReader("no heading"), "https://example.com/notes.txt")
+	if title != "notes.txt" {
+		t.Errorf("ExtractPlain fallback title = %q", title)
+	}
+
+	title, text, err := ExtractGitHubRaw(strings.NewReader("package main"), "https://raw.githubusercontent.com/owner/repo/main/cmd/main.go")
+	if err != nil || title != "owner/repo: cmd/main.go" || text != "package main" {
+		t.Errorf("ExtractGitHubRaw = %q, %q, %v", title, text, err)
+	}
+}
+
+func TestExtractYouTube(t *testing.T) {
+	page := `<html><head><meta property="og:title" content="Go &amp; You"></head><body>
+<script>var ytInitialPlayerResponse = {"videoDetails":{"shortDescription":"Line one\nLine \"two\"","ownerChannelName":"Gopher TV"}};</script>
+<nav>Home Subscriptions</nav></body></html>`
+	title, text, err := ExtractYouTube(strings.NewReader(page), "https://www.youtube.com/watch?v=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Go & You" {
+		t.Errorf("title = %q", title)
+	}
+	if !strings.Contains(text, "Channel: Gopher TV") || !strings.Contains(text, `Line "two"`) || strings.Contains(text, "Subscriptions") {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestRuleExtractor(t *testing.T) {
+	page := `<html><head><title>Thread</title></head><body>
+<div class="sidebar">Related threads</div>
+<div class="post"><p>First post text.</p><div class="signature">Sent from my phone</div></div>
+<div class="post"><p>Second post text.</p></div>
+</body></html>`
+
+	fn, err := RuleExtractor(Rule{Keep: []string{".post"}, Drop: []string{".signature"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, text, err := fn(strings.NewReader(page), "https://forum.example.com/t/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Thread" || text != "First post text. Second post text." {
+		t.Errorf("got %q / %q", title, text)
+	}
+
+	// A rule that matches nothing falls back to the whole page, after drops
+	fn, _ = RuleExtractor(Rule{Keep: []string{"article"}, Drop: []string{".sidebar"}})
+	if _, text, _ := fn(strings.NewReader(page), ""); strings.Contains(text, "Related") || !strings.Contains(text, "Second post") {
+		t.Errorf("fallback text = %q", text)
+	}
+
+	if _, err := RuleExtractor(Rule{Keep: []string{"p:first-child"}}); err == nil {
+		t.Error("unsupported selector accepted")
+	}
+}
+
+func TestRegistryHTMLRulesLeaveOtherTypes(t *testing.T) {
+	r := DefaultRegistry()
+	rule, _ := RuleExtractor(Rule{Keep: []string{"main"}})
+	r.RegisterDomainHTML("docs.example.com", rule)
+	r.RegisterDomainHTML("youtube.com", rule)
+
+	for _, tt := range []struct {
+		url, contentType string
+		want             ExtractFunc
+	}{
+		{"https://docs.example.com/guide", "text/html", rule},
+		{"https://v2.docs.example.com/guide", "", rule},
+		{"https://docs.example.com/guide.pdf", "application/pdf", ExtractPDF},
+		{"https://www.youtube.com/watch?v=x", "text/html", rule},
+		{"https://example.com/", "text/html", ExtractReader},
+	} {
+		fn, ok := r.Lookup(tt.url, tt.contentType)
+		if !ok || reflect.ValueOf(fn).Pointer() != reflect.ValueOf(tt.want).Pointer() {
+			t.Errorf("Lookup(%q, %q) picked the wrong extractor", tt.url, tt.contentType)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"web-ollama/internal/selector"
+)
+
+// Rule picks the content out of a site's pages by CSS selector, for sites
+// whose pages the default extraction reads badly
+type Rule struct {
+	Keep []string // elements holding the content; none keeps the whole page
+	Drop []string // elements removed first, such as sidebars or comment forms
+}
+
+// RuleExtractor returns an ExtractFunc that applies rule to HTML pages. A
+// page where Keep matches nothing is read whole, after the drops, so a
+// redesigned site degrades to the default extraction.
+func RuleExtractor(rule Rule) (ExtractFunc, error) {
+	keep, err := compileAll(rule.Keep)
+	if err != nil {
+		return nil, err
+	}
+	drop, err := compileAll(rule.Drop)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(r io.Reader, sourceURL string) (string, string, error) {
+		doc, err := html.Parse(r)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		title := pageTitle(doc)
+		for _, s := range drop {
+			for _, n := range s.MatchAll(doc) {
+				n.Parent.RemoveChild(n)
+			}
+		}
+
+		var parts []*html.Node
+		for _, s := range keep {
+			parts = append(parts, s.MatchAll(doc)...)
+		}
+		if len(parts) == 0 {
+			if len(keep) > 0 {
+				slog.Debug("extraction rule kept nothing; reading the whole page", "url", sourceURL)
+			}
+			parts = []*html.Node{doc}
+		}
+
+		var body strings.Builder
+		for _, n := range parts {
+			writeText(&body, n)
+		}
+		return title, truncateWords(cleanText(body.String()), maxWords), nil
+	}, nil
+}
+
+func compileAll(sources []string) ([]*selector.Selector, error) {
+	var compiled []*selector.Selector
+	for _, source := range sources {
+		s, err := selector.Compile(source)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, s)
+	}
+	return compiled, nil
+}
+
+// pageTitle returns the text of the document's first <title>
+func pageTitle(n *html.Node) string {
+	if n.Type == html.ElementNode && n.Data == "title" {
+		if n.FirstChild != nil {
+			return strings.TrimSpace(n.FirstChild.Data)
+		}
+		return ""
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if title := pageTitle(c); title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// writeText appends the text under n, leaving out the elements the default
+// extraction skips and the head
+func writeText(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		sb.WriteString(" ")
+		return
+	case html.ElementNode:
+		if skippedTags[n.Data] || n.Data == "head" {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeText(sb, c)
+	}
+}
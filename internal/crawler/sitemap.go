@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxSitemaps     = 20       // sitemap files read per site, indexes included
+	maxSitemapURLs  = 50000    // pages listed per site, the sitemap protocol's per-file cap
+	maxSitemapBytes = 20 << 20 // uncompressed size read per sitemap file
+)
+
+// SitemapURL is a page listed in a sitemap
+type SitemapURL struct {
+	Loc     string
+	LastMod time.Time // zero when the sitemap doesn't say
+}
+
+// sitemapDoc is a <urlset> or a <sitemapindex>
+type sitemapDoc struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// Sitemap lists the pages of site, a domain or URL, from the sitemaps its
+// robots.txt names or else /sitemap.xml. Sitemap indexes are followed, and
+// only pages on the site's host are kept.
+func (c *Crawler) Sitemap(ctx context.Context, site string) ([]SitemapURL, error) {
+	base, err := siteRoot(site)
+	if err != nil {
+		return nil, err
+	}
+	queue := c.robotsSitemaps(ctx, base)
+	if len(queue) == 0 {
+		queue = []string{base.String() + "/sitemap.xml"}
+	}
+
+	var pages []SitemapURL
+	seen := make(map[string]bool)
+	read := 0
+	var lastErr error
+	for len(queue) > 0 && read < maxSitemaps && len(pages) < maxSitemapURLs {
+		loc := queue[0]
+		queue = queue[1:]
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		read++
+
+		doc, err := c.fetchSitemap(ctx, loc)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// Locations are meant to be absolute, but some sites write them
+		// relative to the sitemap
+		from, _ := url.Parse(loc)
+		for _, s := range doc.Sitemaps {
+			if next, ok := ResolveURL(from, s.Loc); ok {
+				queue = append(queue, next)
+			}
+		}
+		for _, u := range doc.URLs {
+			loc, ok := ResolveURL(from, u.Loc)
+			if !ok || !sameSite(loc, base.Hostname()) || seen[loc] {
+				continue
+			}
+			seen[loc] = true
+			pages = append(pages, SitemapURL{Loc: loc, LastMod: parseLastMod(u.LastMod)})
+			if len(pages) == maxSitemapURLs {
+				break
+			}
+		}
+	}
+	if len(pages) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("failed to read the sitemap of %s: %w", base.Hostname(), lastErr)
+	}
+	return pages, nil
+}
+
+// siteRoot turns "example.com" or "https://example.com/docs" into
+// https://example.com
+func siteRoot(site string) (*url.URL, error) {
+	site = strings.TrimSpace(site)
+	if !strings.Contains(site, "://") {
+		site = "https://" + site
+	}
+	u, err := url.Parse(site)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%q is not a domain or http(s) URL", site)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// robotsSitemaps returns the Sitemap: lines of the site's robots.txt
+func (c *Crawler) robotsSitemaps(ctx context.Context, base *url.URL) []string {
+	body, err := c.fetchRaw(ctx, base.String()+"/robots.txt")
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	var sitemaps []string
+	scanner := bufio.NewScanner(io.LimitReader(body, 1<<20))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			if loc, ok := ResolveURL(base, value); ok {
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	}
+	return sitemaps
+}
+
+// fetchSitemap downloads and parses one sitemap file, gzipped or not
+func (c *Crawler) fetchSitemap(ctx context.Context, loc string) (*sitemapDoc, error) {
+	body, err := c.fetchRaw(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	r := bufio.NewReader(body)
+	var in io.Reader = r
+	if magic, _ := r.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", loc, err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(in, maxSitemapBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", loc, err)
+	}
+	return &doc, nil
+}
+
+// fetchRaw GETs rawURL and returns the body of a 200 response
+func (c *Crawler) fetchRaw(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	resp, err := c.fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// fetch GETs rawURL and returns a 200 response, whose Request holds the
+// URL it was served from after redirects
+func (c *Crawler) fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	if err := c.allowed(rawURL); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: HTTP %d", rawURL, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// sameSite reports whether rawURL is on host or its www. twin
+func sameSite(rawURL, host string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	trim := func(h string) string { return strings.TrimPrefix(strings.ToLower(h), "www.") }
+	return trim(u.Hostname()) == trim(host)
+}
+
+// parseLastMod reads a W3C datetime, e.g. "2024-03-01" or
+// "2024-03-01T10:00:00+00:00"
+func parseLastMod(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// RankSitemap picks the n pages whose URLs best match topic: pages whose
+// path and fragment contain more of the topic's words come first, newer
+// pages break ties. Pages matching no word are left out.
+func RankSitemap(pages []SitemapURL, topic string, n int) []SitemapURL {
+	terms := urlWords(topic)
+	type scored struct {
+		page  SitemapURL
+		score int
+	}
+	var matches []scored
+	for _, p := range pages {
+		u, err := url.Parse(p.Loc)
+		if err != nil {
+			continue
+		}
+		words := urlWords(u.Path + " " + u.Fragment)
+		score := 0
+		for _, t := range terms {
+			for _, w := range words {
+				if w == t || len(t) >= 4 && strings.HasPrefix(w, t) || len(w) >= 4 && strings.HasPrefix(t, w) {
+					score++
+					break
+				}
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{p, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].page.LastMod.After(matches[j].page.LastMod)
+	})
+
+	picked := make([]SitemapURL, 0, min(n, len(matches)))
+	for _, m := range matches[:min(n, len(matches))] {
+		picked = append(picked, m.page)
+	}
+	return picked
+}
+
+// urlWords splits text into distinct lowercase words of at least three
+// letters or digits, leaving out common words that say nothing about a page
+func urlWords(text string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 3 && !urlStopWords[w] && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+var urlStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "how": true, "what": true,
+	"about": true, "html": true, "htm": true, "php": true, "aspx": true, "www": true,
+}
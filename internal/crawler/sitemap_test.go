@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSitemapFollowsRobotsAndIndexes(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /admin\nSitemap: %s/index.xml\n", srv.URL)
+		case "/index.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>%[1]s/docs.xml.gz</loc></sitemap><sitemap><loc>%[1]s/index.xml</loc></sitemap></sitemapindex>`, srv.URL)
+		case "/docs.xml.gz":
+			gz := gzip.NewWriter(w)
+			fmt.Fprintf(gz, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>%[1]s/docs/install</loc><lastmod>2024-01-02</lastmod></url>
+<url><loc>%[1]s/blog/go-1.22-loop-variables</loc><lastmod>2024-02-06T10:00:00+00:00</lastmod></url>
+<url><loc>https://elsewhere.example/docs/install</loc></url>
+</urlset>`, srv.URL)
+			gz.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(5*time.Second, 2, 1<<20, "web-ollama-test")
+	pages, err := c.Sitemap(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("got %+v, want the two pages on the site", pages)
+	}
+	if !pages[1].LastMod.Equal(time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("lastmod = %v", pages[1].LastMod)
+	}
+
+	bare := httptest.NewServer(http.NotFoundHandler())
+	defer bare.Close()
+	if _, err := c.Sitemap(context.Background(), bare.URL+"/docs"); err == nil {
+		t.Error("a site without a sitemap should be reported")
+	}
+}
+
+func TestSitemapResolvesRelativeLocations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "Sitemap: /maps/index.xml\n")
+		case "/maps/index.xml":
+			fmt.Fprint(w, `<sitemapindex><sitemap><loc>docs.xml</loc></sitemap></sitemapindex>`)
+		case "/maps/docs.xml":
+			fmt.Fprint(w, `<urlset><url><loc>/docs/install</loc></url><url><loc> ../guide </loc></url></urlset>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(5*time.Second, 2, 1<<20, "web-ollama-test")
+	pages, err := c.Sitemap(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Loc != srv.URL+"/docs/install" || pages[1].Loc != srv.URL+"/guide" {
+		t.Errorf("pages = %+v", pages)
+	}
+}
+
+func TestRankSitemap(t *testing.T) {
+	old, recent := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pages := []SitemapURL{
+		{Loc: "https://go.dev/about"},
+		{Loc: "https://go.dev/blog/loopvar-preview", LastMod: old},
+		{Loc: "https://go.dev/blog/range-functions", LastMod: recent},
+		{Loc: "https://go.dev/doc/go1.22#loop-variables", LastMod: old},
+		{Loc: "https://go.dev/ref/spec#For_range", LastMod: recent},
+	}
+	got := RankSitemap(pages, "how do loop variables work in a range loop", 3)
+	want := []string{"https://go.dev/doc/go1.22#loop-variables", "https://go.dev/blog/range-functions", "https://go.dev/ref/spec#For_range"}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i].Loc != want[i] {
+			t.Errorf("rank %d = %s, want %s", i+1, got[i].Loc, want[i])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"web-ollama/internal/errs"
+)
+
+// Tuning constants for the AIMD worker limit
+const (
+	tunerWarmup     = 5               // successful fetches before latency is judged
+	tunerSlowFactor = 3               // a fetch this many times the usual latency counts as congestion
+	tunerCooldown   = 2 * time.Second // minimum gap between two decreases
+	tunerSmoothing  = 0.2             // weight of the newest fetch in the usual latency
+)
+
+// Tuner sizes the crawler's worker pool from how fetches go, the way TCP
+// sizes its window: while fetches are quick and succeed, the limit grows by
+// about one worker per round of fetches; a timeout, a throttling status or a
+// much slower fetch than usual halves it. The limit is shared by every crawl
+// using the tuner.
+type Tuner struct {
+	min, max int
+
+	mu       sync.Mutex
+	limit    float64
+	active   int
+	full     bool          // every worker was busy since the pool last drained
+	baseline time.Duration // smoothed latency of successful fetches
+	samples  int
+	lastCut  time.Time
+	wake     chan struct{} // closed when a worker is released
+	now      func() time.Time
+}
+
+// NewTuner creates a tuner that starts at start workers and stays between
+// min and max
+func NewTuner(start, min, max int) *Tuner {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if start < min {
+		start = min
+	}
+	if start > max {
+		start = max
+	}
+	return &Tuner{min: min, max: max, limit: float64(start), wake: make(chan struct{}), now: time.Now}
+}
+
+// Limit returns the current number of workers
+func (t *Tuner) Limit() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return int(t.limit)
+}
+
+// acquire waits until fewer than Limit fetches are running
+func (t *Tuner) acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if t.active < int(t.limit) {
+			t.active++
+			t.full = t.full || t.active >= int(t.limit)
+			t.mu.Unlock()
+			return nil
+		}
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release ends a fetch that took d and adjusts the limit. Fetches cut short
+// by their own context say nothing about the network and only free the slot.
+func (t *Tuner) release(d time.Duration, congested, cancelled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	saturated := t.full
+	t.active--
+	if t.active == 0 {
+		t.full = false
+	}
+	close(t.wake)
+	t.wake = make(chan struct{})
+	if cancelled {
+		return
+	}
+
+	slow := t.samples >= tunerWarmup && d > tunerSlowFactor*t.baseline
+	if !congested {
+		t.observe(d)
+	}
+
+	before := int(t.limit)
+	switch {
+	case congested || slow:
+		now := t.now()
+		if now.Sub(t.lastCut) < tunerCooldown {
+			return
+		}
+		t.lastCut = now
+		t.limit = max(float64(t.min), t.limit/2)
+	case saturated:
+		// Only grow while the limit is what holds fetches back
+		t.limit = min(float64(t.max), t.limit+1/t.limit)
+	}
+	if after := int(t.limit); after != before {
+		slog.Debug("crawl workers adjusted", "from", before, "to", after, "congested", congested, "slow", slow, "latency", d)
+	}
+}
+
+// observe folds a successful fetch's latency into the baseline
+func (t *Tuner) observe(d time.Duration) {
+	if t.samples == 0 {
+		t.baseline = d
+	} else {
+		t.baseline = time.Duration(tunerSmoothing*float64(d) + (1-tunerSmoothing)*float64(t.baseline))
+	}
+	t.samples++
+}
+
+// congested reports whether a fetch's outcome suggests too many fetches at
+// once: it timed out, the connection failed, or the server asked to slow down
+func congested(r CrawlResult) bool {
+	switch r.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	if r.Error == nil || r.Status != 0 {
+		return false
+	}
+	var netErr net.Error
+	return (errors.As(r.Error, &netErr) && netErr.Timeout()) || errs.Unreachable(r.Error)
+}
+
+// crawlTuned crawls urls with as many fetches at once as the tuner allows
+func (c *Crawler) crawlTuned(ctx context.Context, urls []string) []CrawlResult {
+	results := make([]CrawlResult, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			if err := c.tuner.acquire(ctx); err != nil {
+				results[i] = CrawlResult{URL: url, Error: fmt.Errorf("request failed: %w", err)}
+				logCrawl(results[i])
+				return
+			}
+			result := c.crawlSingle(ctx, url)
+			c.tuner.release(result.Duration, congested(result), ctx.Err() != nil)
+			logCrawl(result)
+			results[i] = result
+		}(i, url)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"web-ollama/internal/testkit"
+)
+
+// fakeClock is a settable time source for the tuner's cooldown
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+// round runs limit fetches at once, so the pool is saturated
+func round(t *Tuner, d time.Duration, congested bool) {
+	n := t.Limit()
+	for i := 0; i < n; i++ {
+		if err := t.acquire(context.Background()); err != nil {
+			panic(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		t.release(d, congested, false)
+	}
+}
+
+func TestTunerGrowsAdditivelyAndHalvesOnCongestion(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	tuner := NewTuner(4, 1, 10)
+	tuner.now = clock.now
+
+	round(tuner, 100*time.Millisecond, false)
+	round(tuner, 100*time.Millisecond, false)
+	if got := tuner.Limit(); got != 5 {
+		t.Fatalf("after two fast rounds limit = %d, want 5", got)
+	}
+	for i := 0; i < 20; i++ {
+		round(tuner, 100*time.Millisecond, false)
+	}
+	if got := tuner.Limit(); got != 10 {
+		t.Fatalf("limit = %d, want the ceiling of 10", got)
+	}
+
+	// One halving per cooldown, however many fetches fail at once
+	round(tuner, time.Second, true)
+	if got := tuner.Limit(); got != 5 {
+		t.Fatalf("after congestion limit = %d, want 5", got)
+	}
+	clock.t = clock.t.Add(tunerCooldown)
+	round(tuner, time.Second, true)
+	clock.t = clock.t.Add(tunerCooldown)
+	round(tuner, time.Second, true)
+	clock.t = clock.t.Add(tunerCooldown)
+	round(tuner, time.Second, true)
+	if got := tuner.Limit(); got != 1 {
+		t.Fatalf("limit = %d, want the floor of 1", got)
+	}
+}
+
+func TestTunerTreatsSlowFetchesAsCongestion(t *testing.T) {
+	tuner := NewTuner(6, 1, 10)
+	for i := 0; i < tunerWarmup; i++ {
+		tuner.observe(100 * time.Millisecond)
+	}
+
+	if err := tuner.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tuner.release(time.Second, false, false)
+	if got := tuner.Limit(); got != 3 {
+		t.Errorf("after a 10x slower fetch limit = %d, want 3", got)
+	}
+}
+
+func TestTunerIgnoresUnsaturatedAndCancelledFetches(t *testing.T) {
+	tuner := NewTuner(4, 1, 10)
+	for i := 0; i < 10; i++ {
+		tuner.acquire(context.Background())
+		tuner.release(10*time.Millisecond, false, false)
+	}
+	if got := tuner.Limit(); got != 4 {
+		t.Errorf("limit = %d, want 4: one fetch at a time never hits the limit", got)
+	}
+
+	tuner.acquire(context.Background())
+	tuner.release(time.Minute, true, true)
+	if got := tuner.Limit(); got != 4 {
+		t.Errorf("limit = %d, want 4: cancelled fetches say nothing", got)
+	}
+}
+
+func TestTunerAcquireWaitsForAFreeWorker(t *testing.T) {
+	tuner := NewTuner(1, 1, 1)
+	tuner.acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tuner.acquire(ctx); err == nil {
+		t.Fatal("second acquire should wait until cancelled")
+	}
+
+	done := make(chan error)
+	go func() { done <- tuner.acquire(context.Background()) }()
+	tuner.release(0, false, false)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrawlTunedKeepsOrderAndReadsThrottling(t *testing.T) {
+	site := testkit.NewSite(t)
+	c := NewCrawler(5*time.Second, 2, 1024*1024, "web-ollama-test")
+	c.SetTuner(NewTuner(2, 1, 4))
+
+	var urls []string
+	for _, code := range []int{429, 503} {
+		urls = append(urls, fmt.Sprintf("%s/status/%d", site.URL, code))
+	}
+	results := c.CrawlURLs(context.Background(), urls)
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+	for i, r := range results {
+		if r.URL != urls[i] {
+			t.Errorf("result %d is %s, want %s", i, r.URL, urls[i])
+		}
+	}
+	for _, r := range results {
+		if !congested(r) {
+			t.Errorf("%s not read as throttling", r.URL)
+		}
+	}
+	if got := c.Tuner().Limit(); got != 1 {
+		t.Errorf("limit = %d, want 1 after throttling", got)
+	}
+	if congested(CrawlResult{Status: 404, Error: fmt.Errorf("HTTP 404")}) {
+		t.Error("a 404 is not throttling")
+	}
+}
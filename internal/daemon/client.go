@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ErrNotRunning means nothing is listening on the daemon socket
+var ErrNotRunning = errors.New("daemon is not running")
+
+// Client talks to a daemon over its UNIX socket
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the daemon listening at socketPath
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "unix", socketPath)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+			}
+			return conn, nil
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport}}
+}
+
+// Ask sends a question and returns the finished answer. onEvent, if set,
+// receives queued and chunk events as they arrive.
+func (c *Client) Ask(ctx context.Context, question string, onEvent func(Event)) (*Result, error) {
+	return c.AskRequest(ctx, Request{Question: question}, onEvent)
+}
+
+// AskRequest is Ask with the request's other fields, such as its style
+func (c *Client) AskRequest(ctx context.Context, request Request, onEvent func(Event)) (*Result, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://daemon/ask", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotRunning) {
+			return nil, ErrNotRunning
+		}
+		return nil, fmt.Errorf("failed to reach daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("daemon returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to decode daemon event: %w", err)
+		}
+		switch e.Type {
+		case "answer":
+			return e.Answer, nil
+		case "error":
+			return nil, &RemoteError{Message: e.Error, Code: e.Code, Hint: e.Hint}
+		default:
+			if onEvent != nil {
+				onEvent(e)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read daemon response: %w", err)
+	}
+	return nil, errors.New("daemon closed the connection before answering")
+}
+
+// Status returns the daemon's status
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://daemon/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotRunning) {
+			return nil, ErrNotRunning
+		}
+		return nil, fmt.Errorf("failed to reach daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode daemon status: %w", err)
+	}
+	return &status, nil
+}
+
+// RemoteError is a failure reported by the daemon
+type RemoteError struct {
+	Message string
+	Code    string // errs kind name, empty if unknown
+	Hint    string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
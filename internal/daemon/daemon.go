@@ -0,0 +1,198 @@
+// Package daemon serves questions over a UNIX socket so one long-running
+// process can keep the model loaded and the caches and knowledge base open
+// while short-lived `web-ollama ask` commands come and go
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"web-ollama/internal/errs"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/scheduler"
+)
+
+// Request is the body of POST /ask
+type Request struct {
+	Question string `json:"question"`
+	Style    string `json:"style,omitempty"` // answer style; "" uses the daemon's
+}
+
+// Event is one line of the newline-delimited JSON stream answering a request
+type Event struct {
+	Type     string  `json:"type"`               // queued, chunk, answer or error
+	Position int     `json:"position,omitempty"` // queued: place in line, 1 is next
+	Text     string  `json:"text,omitempty"`     // chunk: answer tokens
+	Answer   *Result `json:"answer,omitempty"`   // answer: the finished answer
+	Error    string  `json:"error,omitempty"`    // error: what went wrong
+	Code     string  `json:"code,omitempty"`     // error: errs kind, if known
+	Hint     string  `json:"hint,omitempty"`     // error: how to fix it, if known
+}
+
+// Result is a finished answer
+type Result struct {
+	Answer          string   `json:"answer"`
+	Thinking        string   `json:"thinking,omitempty"`
+	Sources         []string `json:"sources,omitempty"`
+	SearchPerformed bool     `json:"search_performed"`
+	SearchQueries   []string `json:"search_queries,omitempty"`
+	Cached          bool     `json:"cached,omitempty"`
+	GroundMs        int64    `json:"ground_ms"`
+	GenerateMs      int64    `json:"generate_ms"`
+	TotalMs         int64    `json:"total_ms"`
+}
+
+// Status is the body of GET /status
+type Status struct {
+	Model    string `json:"model"`
+	UptimeMs int64  `json:"uptime_ms"`
+	Answered int64  `json:"answered"`
+	Waiting  int    `json:"waiting"`
+}
+
+// Server answers questions through a pipeline
+type Server struct {
+	pipeline *pipeline.Pipeline
+	queue    *scheduler.Queue // nil answers every request at once
+	started  time.Time
+	answered atomic.Int64
+	mux      *http.ServeMux
+}
+
+// NewServer creates a daemon server answering with p
+func NewServer(p *pipeline.Pipeline, queue *scheduler.Queue) *Server {
+	s := &Server{pipeline: p, queue: queue, started: time.Now(), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/ask", s.handleAsk)
+	s.mux.HandleFunc("/status", s.handleStatus)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on the UNIX socket at path until ctx is cancelled.
+// A socket left behind by a daemon that exited uncleanly is replaced; one
+// with a live daemon behind it is an error.
+func (s *Server) ListenAndServe(ctx context.Context, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	defer os.Remove(path)
+	// Anyone who can connect can run questions as this user
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	httpServer := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// handleAsk answers one question, streaming events as it goes
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Question == "" {
+		http.Error(w, "expected {\"question\": \"...\"}", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	send := func(e Event) {
+		encoder.Encode(e)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	release, err := s.queue.Enter(r.Context(), func(position int) {
+		send(Event{Type: "queued", Position: position})
+	})
+	if err != nil {
+		send(errorEvent(err))
+		return
+	}
+	defer release()
+
+	ctx := r.Context()
+	if req.Style != "" {
+		if !prompt.ValidStyle(req.Style) {
+			send(Event{Type: "error", Error: fmt.Sprintf("unknown answer style %q", req.Style)})
+			return
+		}
+		ctx = pipeline.WithStyle(ctx, req.Style)
+	}
+	answer, err := s.pipeline.Ask(ctx, req.Question, nil, func(chunk string) {
+		send(Event{Type: "chunk", Text: chunk})
+	})
+	if err != nil {
+		send(errorEvent(err))
+		return
+	}
+	s.answered.Add(1)
+	send(Event{Type: "answer", Answer: &Result{
+		Answer:          answer.Answer,
+		Thinking:        answer.Thinking,
+		Sources:         answer.Sources,
+		SearchPerformed: answer.SearchPerformed,
+		SearchQueries:   answer.SearchQueries,
+		Cached:          answer.Cached,
+		GroundMs:        answer.Timings.Ground.Milliseconds(),
+		GenerateMs:      answer.Timings.Generate.Milliseconds(),
+		TotalMs:         answer.Timings.Total.Milliseconds(),
+	}})
+}
+
+// handleStatus reports the model and how busy the daemon is
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Status{
+		Model:    s.pipeline.Config.ModelName,
+		UptimeMs: time.Since(s.started).Milliseconds(),
+		Answered: s.answered.Load(),
+		Waiting:  s.queue.Waiting(),
+	})
+}
+
+func errorEvent(err error) Event {
+	e := Event{Type: "error", Error: err.Error(), Hint: errs.Hint(err)}
+	if kind := errs.KindOf(err); kind != errs.Unknown {
+		e.Code = kind.String()
+	}
+	return e
+}
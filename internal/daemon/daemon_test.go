@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/testkit"
+)
+
+// socketPath returns a socket path short enough for the platform limit,
+// which t.TempDir paths can exceed
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "wo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+// startDaemon serves a pipeline backed by a fake Ollama on a fresh socket
+func startDaemon(t *testing.T) (string, *testkit.Ollama) {
+	t.Helper()
+	fakeOllama := testkit.NewOllama(t)
+	cfg := config.NewConfig()
+	cfg.ModelName = "test-model"
+	p := &pipeline.Pipeline{Config: cfg, Client: ollama.NewClient(fakeOllama.URL, 10*time.Second), Mode: "daemon"}
+
+	path := socketPath(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(p, nil).ListenAndServe(ctx, path) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("ListenAndServe: %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("socket was not removed on shutdown")
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); err == nil {
+			return path, fakeOllama
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("daemon did not start listening")
+	return "", nil
+}
+
+func TestAskStreamsAnswer(t *testing.T) {
+	path, fakeOllama := startDaemon(t)
+	fakeOllama.SetAnswer("Recursion is a function calling itself.")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	var streamed strings.Builder
+	result, err := NewClient(path).Ask(context.Background(), "Explain recursion", func(e Event) {
+		if e.Type == "chunk" {
+			streamed.WriteString(e.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if result.Answer != fakeOllama.Answer || streamed.String() != fakeOllama.Answer {
+		t.Errorf("answer = %q, streamed %q, want %q", result.Answer, streamed.String(), fakeOllama.Answer)
+	}
+
+	status, err := NewClient(path).Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Model != "test-model" || status.Answered != 1 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestAskWithoutDaemon(t *testing.T) {
+	_, err := NewClient(socketPath(t)).Ask(context.Background(), "hello", nil)
+	if !errors.Is(err, ErrNotRunning) {
+		t.Errorf("err = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := socketPath(t)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave the file behind as a crashed daemon would
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	srv := NewServer(&pipeline.Pipeline{Config: config.NewConfig()}, nil)
+	if err := srv.ListenAndServe(ctx, path); err != nil {
+		t.Fatalf("ListenAndServe: %v", err)
+	}
+}
+
+func TestListenRefusesRunningDaemon(t *testing.T) {
+	path, _ := startDaemon(t)
+	err := NewServer(&pipeline.Pipeline{Config: config.NewConfig()}, nil).ListenAndServe(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("err = %v, want already listening", err)
+	}
+}
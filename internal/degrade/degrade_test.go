@@ -0,0 +1,166 @@
+package degrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/searxng"
+)
+
+func TestTrackerStatus(t *testing.T) {
+	tr := NewTracker()
+	var changes []string
+	tr.OnChange = func(c string, s State) { changes = append(changes, c+"="+s.Mode.String()) }
+
+	if tr.Status() != "" {
+		t.Errorf("fresh tracker status = %q, want empty", tr.Status())
+	}
+	tr.Set(Search, Normal, "")
+	tr.Set(Search, Fallback, "cached results")
+	tr.Set(Search, Fallback, "cached results")
+	tr.Set(Ollama, Down, "retrying")
+
+	if got, want := tr.Status(), "ollama down (retrying) · search fallback (cached results)"; got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+	tr.Set(Search, Normal, "")
+	if got := strings.Join(changes, ","); got != "search=fallback,ollama=down,search=ok" {
+		t.Errorf("changes = %s", got)
+	}
+
+	var none *Tracker
+	none.Set(Search, Down, "")
+	if none.Status() != "" || none.Get(Search).Mode != Normal {
+		t.Error("nil tracker should report everything normal")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tr := NewTracker()
+	calls := 0
+	err := tr.Recover(context.Background(), Ollama, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || tr.Get(Ollama).Mode != Normal {
+		t.Errorf("err=%v calls=%d mode=%v", err, calls, tr.Get(Ollama).Mode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tr.Recover(ctx, Ollama, func() error { return errors.New("down") }); err == nil {
+		t.Error("expected an error once the context is cancelled")
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	opErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	if !IsUnreachable(fmt.Errorf("request failed: %w", opErr)) {
+		t.Error("dial errors should count as unreachable")
+	}
+	if IsUnreachable(errors.New("Ollama returned status 500")) {
+		t.Error("HTTP errors should not count as unreachable")
+	}
+}
+
+func TestSnippetsLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippets.json")
+	s := NewSnippets(path)
+	results := []searxng.SearchResult{{Title: "Go 1.22", URL: "https://go.dev/blog", Content: "Go 1.22 is out"}}
+	if err := s.Put("Go 1.22 release notes", results); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewSnippets(path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reloaded.Lookup("go 1.22 release notes?"); !ok || got[0].URL != "https://go.dev/blog" {
+		t.Errorf("exact lookup = %v, %v", got, ok)
+	}
+	if _, ok := reloaded.Lookup("go 1.22 notes"); !ok {
+		t.Error("expected a match for a query sharing most words")
+	}
+	if _, ok := reloaded.Lookup("berlin weather tomorrow"); ok {
+		t.Error("unrelated query should not match")
+	}
+}
+
+type fakeBackend struct {
+	err   error
+	calls int
+}
+
+func (f *fakeBackend) Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []searxng.SearchResult{{Title: query, URL: "https://example.com/" + query, Content: "snippet"}}, nil
+}
+
+func TestSearcherFallsBackToSnippets(t *testing.T) {
+	backend := &fakeBackend{}
+	tr := NewTracker()
+	s := &Searcher{
+		Primary:    backend,
+		Snippets:   NewSnippets(filepath.Join(t.TempDir(), "snippets.json")),
+		Tracker:    tr,
+		RetryAfter: time.Hour,
+	}
+
+	if _, err := s.Search(context.Background(), "weather", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.err = errors.New("connection refused")
+	results, err := s.Search(context.Background(), "weather", 5)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("fallback search = %v, %v", results, err)
+	}
+	if !s.Degraded() || tr.Get(Search).Mode != Fallback {
+		t.Error("search should be marked as degraded")
+	}
+
+	// Within RetryAfter the dead backend is not tried again
+	if _, err := s.Search(context.Background(), "news", 5); err == nil {
+		t.Error("expected an error for an uncached query")
+	}
+	if backend.calls != 2 {
+		t.Errorf("backend called %d times, want 2", backend.calls)
+	}
+
+	pages := SnippetPages(results)
+	if len(pages) != 1 || pages[0].Content != "snippet" {
+		t.Errorf("snippet pages = %+v", pages)
+	}
+}
+
+func TestSearcherOffline(t *testing.T) {
+	primary, local := &fakeBackend{}, &fakeBackend{}
+	snippets := NewSnippets(filepath.Join(t.TempDir(), "snippets.json"))
+	if err := snippets.Put("weather", []searxng.SearchResult{{URL: "https://example.com/weather", Content: "sunny"}}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Searcher{Primary: primary, Snippets: snippets, Local: local, Offline: true, Tracker: NewTracker()}
+
+	results, err := s.Search(context.Background(), "weather", 5)
+	if err != nil || len(results) != 1 || results[0].Cached.IsZero() {
+		t.Fatalf("offline search = %+v, %v", results, err)
+	}
+	if primary.calls != 0 || local.calls != 1 || !s.Degraded() {
+		t.Errorf("primary called %d times, local %d; degraded %v", primary.calls, local.calls, s.Degraded())
+	}
+	if results, err := s.Search(context.Background(), "news", 5); err != nil || len(results) != 1 {
+		t.Errorf("local results = %+v, %v", results, err)
+	}
+}
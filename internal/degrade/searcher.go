@@ -0,0 +1,136 @@
+package degrade
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/searxng"
+)
+
+// Backend is a search provider
+type Backend interface {
+	Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error)
+}
+
+// Searcher searches with Primary and, when it fails, answers from the
+// snippet cache instead. While in fallback it only retries Primary every
+// RetryAfter, so a dead backend does not cost a timeout per query. Offline,
+// Primary is never tried: results come from the snippet cache and Local.
+type Searcher struct {
+	Primary    Backend
+	Snippets   *Snippets // nil disables the fallback
+	Tracker    *Tracker
+	RetryAfter time.Duration
+	Offline    bool
+	Local      Backend // searched alongside the snippet cache offline, such as the page archive; may be nil
+
+	mu       sync.Mutex
+	failedAt time.Time
+	lastErr  error
+}
+
+// Search implements grounding.Searcher
+func (s *Searcher) Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error) {
+	if s.Offline {
+		return s.searchOffline(ctx, query, maxResults)
+	}
+	s.mu.Lock()
+	skip := !s.failedAt.IsZero() && time.Since(s.failedAt) < s.RetryAfter
+	s.mu.Unlock()
+
+	if !skip {
+		results, err := s.Primary.Search(ctx, query, maxResults)
+		if err == nil {
+			s.mu.Lock()
+			s.failedAt = time.Time{}
+			s.mu.Unlock()
+			s.Tracker.Set(Search, Normal, "")
+			if s.Snippets != nil {
+				if err := s.Snippets.Put(query, results); err != nil {
+					slog.Warn("failed to save search snippets", "error", err)
+				}
+			}
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		slog.Warn("search backend failed", "query", query, "error", err)
+		s.mu.Lock()
+		s.failedAt, s.lastErr = time.Now(), err
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	lastErr := s.lastErr
+	s.mu.Unlock()
+
+	if s.Snippets == nil {
+		s.Tracker.Set(Search, Down, "no fallback")
+		return nil, fmt.Errorf("search is unavailable: %w", lastErr)
+	}
+	s.Tracker.Set(Search, Fallback, "cached results")
+	results, ok := s.Snippets.Lookup(query)
+	if !ok {
+		return nil, fmt.Errorf("search is unavailable and no cached results match %q: %w", query, lastErr)
+	}
+	if maxResults > 0 && len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, nil
+}
+
+// searchOffline answers from the snippet cache and Local, the cached
+// results first, without duplicate URLs
+func (s *Searcher) searchOffline(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error) {
+	s.Tracker.Set(Search, Fallback, "cached results only (offline)")
+	var results []searxng.SearchResult
+	if s.Snippets != nil {
+		results, _ = s.Snippets.Lookup(query)
+	}
+	if s.Local != nil {
+		local, err := s.Local.Search(ctx, query, maxResults)
+		if err != nil {
+			slog.Warn("local search failed", "query", query, "error", err)
+		}
+		seen := make(map[string]bool, len(results))
+		for _, r := range results {
+			seen[r.URL] = true
+		}
+		for _, r := range local {
+			if !seen[r.URL] {
+				seen[r.URL] = true
+				results = append(results, r)
+			}
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("offline and no cached results match %q", query)
+	}
+	if maxResults > 0 && len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, nil
+}
+
+// Degraded reports whether results are currently coming from the cache
+func (s *Searcher) Degraded() bool {
+	return s.Tracker.Get(Search).Mode != Normal
+}
+
+// SnippetPages turns search results into pages whose content is the search
+// snippet, for when the pages themselves cannot be fetched
+func SnippetPages(results []searxng.SearchResult) []crawler.CrawlResult {
+	var pages []crawler.CrawlResult
+	for _, r := range results {
+		if r.Content == "" {
+			continue
+		}
+		pages = append(pages, crawler.CrawlResult{URL: r.URL, Title: r.Title, Content: r.Content, Saved: r.Cached})
+	}
+	return pages
+}
@@ -0,0 +1,169 @@
+package degrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/cache"
+	"web-ollama/internal/searxng"
+)
+
+// maxSnippetQueries bounds the snippet cache; the oldest queries are dropped first
+const maxSnippetQueries = 500
+
+// snippetEntry is the search results last seen for a query
+type snippetEntry struct {
+	Query   string                 `json:"query"`
+	Results []searxng.SearchResult `json:"results"`
+	SavedAt time.Time              `json:"saved_at"`
+}
+
+// Snippets is a persistent cache of search results, titles and snippets
+// included, used when the search backend is unavailable
+type Snippets struct {
+	filePath string
+	mu       sync.Mutex
+	entries  map[string]snippetEntry
+}
+
+// NewSnippets creates a snippet cache backed by a JSON file
+func NewSnippets(filePath string) *Snippets {
+	return &Snippets{filePath: filePath, entries: make(map[string]snippetEntry)}
+}
+
+// Load reads the cache from disk; a missing file yields an empty cache
+func (s *Snippets) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read snippet cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return fmt.Errorf("failed to parse snippet cache: %w", err)
+	}
+	return nil
+}
+
+// Put stores the results for query and saves the cache
+func (s *Snippets) Put(query string, results []searxng.SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := cache.Normalize(query)
+	s.entries[key] = snippetEntry{Query: query, Results: results, SavedAt: time.Now()}
+	s.pruneUnlocked()
+	return s.saveUnlocked()
+}
+
+// Lookup returns the results cached for query. Without an exact match it
+// falls back to the cached query sharing the most words, if at least half
+// of the query's words match.
+func (s *Snippets) Lookup(query string) ([]searxng.SearchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := cache.Normalize(query)
+	if e, ok := s.entries[key]; ok {
+		return e.results(), true
+	}
+
+	words := strings.Fields(key)
+	best, bestScore := "", 0.0
+	for k := range s.entries {
+		if score := shared(words, strings.Fields(k)); score > bestScore || (score == bestScore && k < best) {
+			best, bestScore = k, score
+		}
+	}
+	if bestScore < 0.5 {
+		return nil, false
+	}
+	return s.entries[best].results(), true
+}
+
+// results returns a copy of the entry's results marked with when they were
+// retrieved
+func (e snippetEntry) results() []searxng.SearchResult {
+	results := make([]searxng.SearchResult, len(e.Results))
+	for i, r := range e.Results {
+		r.Cached = e.SavedAt
+		results[i] = r
+	}
+	return results
+}
+
+// Len returns the number of cached queries
+func (s *Snippets) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// shared returns the fraction of words that also appear in other
+func shared(words, other []string) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(other))
+	for _, w := range other {
+		set[w] = true
+	}
+	n := 0
+	for _, w := range words {
+		if set[w] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(words))
+}
+
+// pruneUnlocked caps the size (must be called with lock held)
+func (s *Snippets) pruneUnlocked() {
+	if len(s.entries) <= maxSnippetQueries {
+		return
+	}
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return s.entries[keys[i]].SavedAt.Before(s.entries[keys[j]].SavedAt)
+	})
+	for _, key := range keys[:len(keys)-maxSnippetQueries] {
+		delete(s.entries, key)
+	}
+}
+
+// saveUnlocked writes the cache atomically (must be called with lock held)
+func (s *Snippets) saveUnlocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snippet cache: %w", err)
+	}
+
+	tempPath := s.filePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, s.filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
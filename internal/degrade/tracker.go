@@ -0,0 +1,147 @@
+// Package degrade tracks which dependencies are working and keeps the
+// assistant usable while one of them is not
+package degrade
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/errs"
+)
+
+// Capabilities that can degrade
+const (
+	Ollama = "ollama"
+	Search = "search"
+)
+
+// Mode is how well a capability is currently served
+type Mode int
+
+const (
+	Normal   Mode = iota
+	Fallback      // served by a substitute, e.g. cached search results
+	Down          // unavailable; requests wait or fail
+)
+
+// String returns the mode's name
+func (m Mode) String() string {
+	switch m {
+	case Fallback:
+		return "fallback"
+	case Down:
+		return "down"
+	}
+	return "ok"
+}
+
+// State is the current mode of one capability and why
+type State struct {
+	Mode   Mode
+	Detail string // what is serving it, or what went wrong
+	Since  time.Time
+}
+
+// Tracker records the state of each capability. A nil Tracker reports
+// everything as normal.
+type Tracker struct {
+	mu     sync.Mutex
+	states map[string]State
+
+	// OnChange, if set, is called after a capability changes mode
+	OnChange func(capability string, state State)
+}
+
+// NewTracker creates a tracker with every capability normal
+func NewTracker() *Tracker {
+	return &Tracker{states: make(map[string]State)}
+}
+
+// Set records the state of capability; repeated calls with the same mode
+// only update the detail
+func (t *Tracker) Set(capability string, mode Mode, detail string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	prev, ok := t.states[capability]
+	changed := (ok && prev.Mode != mode) || (!ok && mode != Normal)
+	state := State{Mode: mode, Detail: detail, Since: prev.Since}
+	if changed || !ok {
+		state.Since = time.Now()
+	}
+	t.states[capability] = state
+	onChange := t.OnChange
+	t.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(capability, state)
+	}
+}
+
+// Get returns the state of capability
+func (t *Tracker) Get(capability string) State {
+	if t == nil {
+		return State{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.states[capability]
+}
+
+// Status describes every degraded capability in one line, or returns ""
+// when all are normal
+func (t *Tracker) Status() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var parts []string
+	for name, s := range t.states {
+		if s.Mode == Normal {
+			continue
+		}
+		part := fmt.Sprintf("%s %s", name, s.Mode)
+		if s.Detail != "" {
+			part += " (" + s.Detail + ")"
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " · ")
+}
+
+// Recover marks capability down and polls check, backing off from one
+// second to thirty, until it succeeds or ctx is done
+func (t *Tracker) Recover(ctx context.Context, capability string, check func() error) error {
+	wait := time.Second
+	for {
+		err := check()
+		if err == nil {
+			t.Set(capability, Normal, "")
+			return nil
+		}
+		t.Set(capability, Down, "retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < 30*time.Second {
+			wait *= 2
+		}
+	}
+}
+
+// IsUnreachable reports whether err means the server could not be reached
+// or dropped the connection, as opposed to answering with an error
+func IsUnreachable(err error) bool {
+	return errs.Unreachable(err)
+}
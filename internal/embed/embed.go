@@ -0,0 +1,148 @@
+// Package embed turns text into vectors for the knowledge base through a
+// choice of backends: Ollama, a llama.cpp server or any OpenAI-compatible
+// embeddings API. The chat model can then run somewhere that has no
+// embedding model.
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Embedder turns texts into vectors, one per text, as ollama.Client does
+type Embedder interface {
+	Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// Providers that can be named in embed_provider
+const (
+	ProviderOllama   = "ollama"
+	ProviderLlamaCpp = "llamacpp"
+	ProviderOpenAI   = "openai"
+)
+
+// Default base URLs of the OpenAI-compatible providers
+var defaultURLs = map[string]string{
+	ProviderLlamaCpp: "http://localhost:8080/v1",
+	ProviderOpenAI:   "https://api.openai.com/v1",
+}
+
+// batchSize is the number of texts sent per request
+const batchSize = 32
+
+// New returns the embedder for provider. Ollama embeds through ollama,
+// the chat client; the others are reached at baseURL, or their usual
+// address when it is "".
+func New(provider, baseURL, apiKey string, ollama Embedder, timeout time.Duration) (Embedder, error) {
+	switch provider {
+	case "", ProviderOllama:
+		return ollama, nil
+	case ProviderLlamaCpp, ProviderOpenAI:
+		if baseURL == "" {
+			baseURL = defaultURLs[provider]
+		}
+		if provider == ProviderOpenAI && apiKey == "" && baseURL == defaultURLs[ProviderOpenAI] {
+			return nil, fmt.Errorf("the OpenAI embeddings API needs an API key; set OPENAI_API_KEY")
+		}
+		return NewOpenAI(baseURL, apiKey, timeout), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", provider)
+}
+
+// OpenAI embeds through an OpenAI-compatible /embeddings endpoint, as
+// served by OpenAI, llama.cpp's server (started with --embeddings), vLLM
+// and LM Studio
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAI creates a client for the embeddings API under baseURL, e.g.
+// "http://localhost:8080/v1". apiKey may be empty for local servers.
+func NewOpenAI(baseURL, apiKey string, timeout time.Duration) *OpenAI {
+	return &OpenAI{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Embeddings returns an embedding vector for each text, sending them in
+// batches
+func (o *OpenAI) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		batch, err := o.embed(ctx, model, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed texts %d-%d of %d: %w", start+1, end, len(texts), err)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedRequest is the body of a request to /embeddings
+type embedRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+// embedResponse is the part of the reply that is used
+type embedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embed sends one batch
+func (o *OpenAI) embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	start := time.Now()
+	jsonData, err := json.Marshal(embedRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/embeddings", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("embeddings API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Data) != len(inputs) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(result.Data), len(inputs))
+	}
+	sort.SliceStable(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	vectors := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		vectors[i] = d.Embedding
+	}
+	slog.Debug("embed", "url", o.baseURL, "model", model, "inputs", len(inputs), "duration", time.Since(start))
+	return vectors, nil
+}
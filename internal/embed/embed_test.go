@@ -0,0 +1,86 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenAIEmbeddings(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" || req.Model != "text-embedding-3-small" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		requests++
+		// Answer out of order to check vectors are matched by index
+		var resp embedResponse
+		resp.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}, len(req.Input))
+		for i := range req.Input {
+			j := len(req.Input) - 1 - i
+			resp.Data[i].Index = j
+			resp.Data[i].Embedding = []float32{float32(len(req.Input[j]))}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	texts := make([]string, batchSize+3)
+	for i := range texts {
+		texts[i] = string(make([]byte, i))
+	}
+	vectors, err := NewOpenAI(srv.URL+"/v1/", "sk-test", time.Second).Embeddings(context.Background(), "text-embedding-3-small", texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors in %d requests, want %d in 2", len(vectors), requests, len(texts))
+	}
+	for i, v := range vectors {
+		if v[0] != float32(i) {
+			t.Errorf("vector %d = %v, want [%d]", i, v, i)
+		}
+	}
+}
+
+func TestOpenAIReportsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := NewOpenAI(srv.URL, "", time.Second).Embeddings(context.Background(), "nope", []string{"hi"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+type fakeOllama struct{}
+
+func (fakeOllama) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	return nil, nil
+}
+
+func TestNew(t *testing.T) {
+	if e, err := New("ollama", "", "", fakeOllama{}, time.Second); err != nil || e != (fakeOllama{}) {
+		t.Errorf("New(ollama) = %v, %v, want the Ollama client", e, err)
+	}
+	if e, err := New("llamacpp", "", "", fakeOllama{}, time.Second); err != nil || e.(*OpenAI).baseURL != "http://localhost:8080/v1" {
+		t.Errorf("New(llamacpp) = %v, %v", e, err)
+	}
+	if _, err := New("openai", "", "", fakeOllama{}, time.Second); err == nil {
+		t.Error("New(openai) without an API key should fail")
+	}
+	if _, err := New("cohere", "", "", fakeOllama{}, time.Second); err == nil {
+		t.Error("New(cohere) should fail")
+	}
+}
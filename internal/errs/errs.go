@@ -0,0 +1,149 @@
+// Package errs classifies failures the user can fix, so each front end can
+// say how: the chat prints a hint, the server picks an HTTP status. Clients
+// wrap the underlying error in an *Error of the matching Kind.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Kind is a class of failure with a known remedy
+type Kind int
+
+const (
+	Unknown           Kind = iota
+	OllamaUnreachable      // nothing answers at ollama_url
+	ModelMissing           // the model has not been pulled
+	SearxForbidden         // SearXNG refuses JSON API requests
+	CrawlBlocked           // a site refused the crawler
+	ContextOverflow        // the prompt does not fit the model's context window
+	QueueFull              // too many questions are waiting to be answered
+)
+
+var kindNames = map[Kind]string{
+	Unknown:           "unknown",
+	OllamaUnreachable: "ollama_unreachable",
+	ModelMissing:      "model_missing",
+	SearxForbidden:    "searx_forbidden",
+	CrawlBlocked:      "crawl_blocked",
+	ContextOverflow:   "context_overflow",
+	QueueFull:         "queue_full",
+}
+
+// String returns the kind's snake_case name, also used as the server's
+// error code
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Error is a failure of a known kind
+type Error struct {
+	Kind    Kind
+	Subject string // what failed: a server URL, model name or page URL
+	Err     error  // the underlying error
+}
+
+// New wraps err as a failure of kind concerning subject
+func New(kind Kind, subject string, err error) *Error {
+	return &Error{Kind: kind, Subject: subject, Err: err}
+}
+
+func (e *Error) Error() string {
+	var msg string
+	switch e.Kind {
+	case OllamaUnreachable:
+		msg = fmt.Sprintf("Ollama is unreachable at %s", e.Subject)
+	case ModelMissing:
+		msg = fmt.Sprintf("model %q is not installed in Ollama", e.Subject)
+	case SearxForbidden:
+		msg = fmt.Sprintf("SearXNG at %s refused the JSON API (403 Forbidden)", e.Subject)
+	case CrawlBlocked:
+		msg = fmt.Sprintf("%s blocked the crawler", e.Subject)
+	case ContextOverflow:
+		msg = "the prompt does not fit the model's context window"
+	case QueueFull:
+		msg = "too many questions are queued"
+	default:
+		msg = "request failed"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of the first *Error in err's chain, or Unknown
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Unknown
+}
+
+// Is reports whether err is a failure of kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Hint tells the user how to fix err, or returns "" when there is no
+// specific advice
+func Hint(err error) string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return ""
+	}
+	switch e.Kind {
+	case OllamaUnreachable:
+		return fmt.Sprintf("Start Ollama with 'ollama serve', or point ollama_url (--ollama-url) at where it runs; it is set to %s", e.Subject)
+	case ModelMissing:
+		return fmt.Sprintf("Download it with 'ollama pull %s', or choose an installed model with --model ('ollama list' shows them)", e.Subject)
+	case SearxForbidden:
+		return "Enable the JSON API: add 'json' to search.formats in SearXNG's settings.yml and restart SearXNG"
+	case CrawlBlocked:
+		return "The site refuses automated access. Other results are still used; add the site to blocked_domains to stop trying it"
+	case ContextOverflow:
+		return "Raise context_tokens if the model supports a larger window, lower max_results, or turn on --summarize"
+	case QueueFull:
+		return "Try again once earlier questions are answered, or raise chat_queue_depth (and chat_concurrency if Ollama has the memory to run more at once)"
+	}
+	return ""
+}
+
+// HTTPStatus maps err to the status the server should answer with, or
+// fallback for errors of no known kind
+func HTTPStatus(err error, fallback int) int {
+	switch KindOf(err) {
+	case OllamaUnreachable:
+		return http.StatusServiceUnavailable
+	case ModelMissing:
+		return http.StatusNotFound
+	case SearxForbidden, CrawlBlocked:
+		return http.StatusBadGateway
+	case ContextOverflow:
+		return http.StatusRequestEntityTooLarge
+	case QueueFull:
+		return http.StatusTooManyRequests
+	}
+	return fallback
+}
+
+// Unreachable reports whether err means the server could not be reached
+// or dropped the connection, as opposed to answering with an error
+func Unreachable(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
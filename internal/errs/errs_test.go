@@ -0,0 +1,65 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestKindSurvivesWrapping(t *testing.T) {
+	err := fmt.Errorf("analysis failed: %w", New(ModelMissing, "qwen3:8b", errors.New("status 404")))
+
+	if KindOf(err) != ModelMissing || !Is(err, ModelMissing) || Is(err, CrawlBlocked) {
+		t.Errorf("KindOf = %v, want ModelMissing", KindOf(err))
+	}
+	if !strings.Contains(err.Error(), `model "qwen3:8b" is not installed in Ollama: status 404`) {
+		t.Errorf("message = %q", err)
+	}
+	if hint := Hint(err); !strings.Contains(hint, "ollama pull qwen3:8b") {
+		t.Errorf("hint = %q, want the pull command", hint)
+	}
+	if got := HTTPStatus(err, http.StatusBadGateway); got != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", got)
+	}
+}
+
+func TestUnknownErrors(t *testing.T) {
+	err := errors.New("boom")
+	if KindOf(err) != Unknown || Hint(err) != "" || HTTPStatus(err, http.StatusBadGateway) != http.StatusBadGateway {
+		t.Error("a plain error should have no kind, hint or special status")
+	}
+	if Is(nil, Unknown) {
+		t.Error("nil is not an error of any kind")
+	}
+}
+
+func TestEveryKindHasAHint(t *testing.T) {
+	for kind := OllamaUnreachable; kind <= QueueFull; kind++ {
+		err := New(kind, "subject", nil)
+		if Hint(err) == "" {
+			t.Errorf("%v has no hint", kind)
+		}
+		if HTTPStatus(err, 0) == 0 {
+			t.Errorf("%v has no HTTP status", kind)
+		}
+		if strings.HasPrefix(kind.String(), "kind(") {
+			t.Errorf("%v has no name", kind)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	_, err := net.Dial("tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Skip("something is listening on port 1")
+	}
+	if !Unreachable(fmt.Errorf("request failed: %w", err)) {
+		t.Errorf("Unreachable(%v) = false", err)
+	}
+	if Unreachable(errors.New("status 500")) {
+		t.Error("an error response is not unreachable")
+	}
+}
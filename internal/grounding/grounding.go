@@ -0,0 +1,389 @@
+package grounding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/history"
+	"web-ollama/internal/hostile"
+	"web-ollama/internal/metrics"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/tracing"
+	"web-ollama/internal/trust"
+)
+
+// Analyzer decides whether a query needs web search
+type Analyzer interface {
+	AnalyzeWithLLM(ctx context.Context, userQuery string) (analyzer.SearchDecision, error)
+}
+
+// StreamingAnalyzer is an Analyzer that reports search queries while it is
+// still deciding, so searching can start before the analysis finishes
+type StreamingAnalyzer interface {
+	AnalyzeStreaming(ctx context.Context, userQuery string, recent []analyzer.OllamaMessage, onQuery func(string)) (analyzer.SearchDecision, error)
+}
+
+// Searcher finds candidate pages for a query
+type Searcher interface {
+	Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error)
+}
+
+// Fetcher downloads and extracts pages
+type Fetcher interface {
+	CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult
+}
+
+// Grounder gathers web context for a query without any terminal output.
+// Callers that show progress, such as the REPL, set Hooks.
+type Grounder struct {
+	Analyzer       Analyzer
+	Searcher       Searcher
+	Fetcher        Fetcher
+	MaxResults     int
+	BlockedDomains []string
+	Trust          *trust.Weights   // ranks results by domain before crawling; nil keeps search order
+	Guard          *guard.Filter    // nil disables guardrails
+	Hostile        *hostile.Tracker // skips or demotes domains that keep refusing the crawler; nil disables it
+	Budget         time.Duration    // wall-clock limit on analysis, search and crawl; 0 means none
+	DownloadLimit  int64            // bytes crawled per query before further pages are skipped; 0 means none
+	Hooks          Hooks            // progress reports for callers that show them
+}
+
+// Hooks report grounding progress to callers that show it, such as the REPL
+// and the profiler. Any of them may be nil. The search and crawl hooks run on each query's
+// goroutine, so they must be safe to call concurrently.
+type Hooks struct {
+	OnAnalyzed func(decision analyzer.SearchDecision, err error)             // the analyzer decided, or failed
+	OnQuery    func(query string)                                            // a search starts
+	OnResults  func(query string, results []searxng.SearchResult, err error) // the results kept for crawling, or why the search failed
+	OnCrawled  func(query string, crawled []crawler.CrawlResult)             // the pages crawled for query
+
+	// Choose, if set, picks which results to crawl once every search is
+	// done; otherwise each query's results are crawled as soon as they
+	// arrive. Time spent choosing does not count against the Budget.
+	Choose func([]searxng.SearchResult) []searxng.SearchResult
+}
+
+// Result is the web context gathered for one query
+type Result struct {
+	Decision analyzer.SearchDecision
+	Context  string // formatted for the LLM, empty if nothing was found
+	URLs     []string
+	Scores   map[string]float64 // search engine score of each of URLs that had one
+	Crawled  []crawler.CrawlResult
+	Warnings []guard.Warning // sources that attempted prompt injection
+
+	Results     []searxng.SearchResult // the search results behind URLs, with their snippets
+	Outside     int                    // results the research sandbox kept from being crawled
+	BudgetSpent bool                   // the Budget ran out before grounding finished
+	CapReached  bool                   // pages were skipped at the DownloadLimit
+}
+
+// Ground analyzes the query and, if search is needed, searches and crawls.
+// With a StreamingAnalyzer each query is searched and crawled as soon as the
+// analyzer emits it, overlapping with the rest of the analysis. When the
+// Budget runs out, whatever pages have been crawled by then are used.
+// recent is the conversation before query, used to rewrite follow-ups into
+// standalone searches; only a StreamingAnalyzer makes use of it.
+func (g *Grounder) Ground(ctx context.Context, query string, recent []analyzer.OllamaMessage) (Result, error) {
+	ctx = bandwidth.WithTurn(ctx, g.DownloadLimit)
+	turnCtx, cancelTurn := WithBudget(ctx, g.Budget)
+	defer cancelTurn()
+	searchCtx, cancel := context.WithCancel(turnCtx)
+	defer cancel()
+	gather := g.newGather(ctx, searchCtx)
+
+	analyzeCtx, span := tracing.Start(turnCtx, "analyze")
+	start := time.Now()
+	var decision analyzer.SearchDecision
+	var err error
+	if streaming, ok := g.Analyzer.(StreamingAnalyzer); ok {
+		decision, err = streaming.AnalyzeStreaming(analyzeCtx, query, recent, gather.Start)
+	} else {
+		decision, err = g.Analyzer.AnalyzeWithLLM(analyzeCtx, query)
+	}
+	metrics.ObserveStage("analyze", time.Since(start))
+	span.SetAttr("needs_search", decision.NeedsSearch)
+	span.End(err)
+	if g.Hooks.OnAnalyzed != nil {
+		g.Hooks.OnAnalyzed(decision, err)
+	}
+	if err != nil && BudgetSpent(ctx, turnCtx) {
+		// Out of time mid-analysis: keep what the streamed queries found
+		result := gather.Wait()
+		result.Decision = analyzer.SearchDecision{NeedsSearch: len(result.URLs) > 0, Reason: "turn budget reached"}
+		result.BudgetSpent = true
+		result.CapReached = bandwidth.CapReached(ctx)
+		slog.Warn("turn budget reached during analysis", "query", query, "budget", g.Budget, "pages", len(result.Crawled))
+		return result, nil
+	}
+	if err != nil || !decision.NeedsSearch {
+		// Abandon anything started on a partial decision
+		cancel()
+		gather.Abandon()
+		if err != nil {
+			return Result{}, fmt.Errorf("analysis failed: %w", err)
+		}
+		return Result{Decision: decision}, nil
+	}
+
+	queries := decision.SearchQueries
+	if len(queries) == 0 {
+		queries = []string{decision.StandaloneQuery}
+	}
+	for _, q := range queries {
+		gather.Start(q)
+	}
+
+	result := gather.Wait()
+	result.Decision = decision
+	result.BudgetSpent = result.BudgetSpent || BudgetSpent(ctx, turnCtx)
+	result.CapReached = bandwidth.CapReached(ctx)
+	if result.BudgetSpent {
+		slog.Warn("turn budget reached", "query", query, "budget", g.Budget, "pages", len(result.Crawled))
+	}
+	return result, nil
+}
+
+// Conversation converts stored history for the analyzer
+func Conversation(msgs []history.Message) []analyzer.OllamaMessage {
+	out := make([]analyzer.OllamaMessage, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, analyzer.OllamaMessage{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+// WithBudget bounds a turn's analysis, search and crawl to budget; 0 means
+// no limit
+func WithBudget(ctx context.Context, budget time.Duration) (context.Context, context.CancelFunc) {
+	if budget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, budget)
+}
+
+// BudgetSpent reports whether turn ran out of budget while parent is still live
+func BudgetSpent(parent, turn context.Context) bool {
+	return parent.Err() == nil && errors.Is(turn.Err(), context.DeadlineExceeded)
+}
+
+// Search runs the queries concurrently, crawls the unique result URLs and
+// builds the context
+func (g *Grounder) Search(ctx context.Context, queries []string) Result {
+	gather := g.newGather(ctx, ctx)
+	for _, q := range queries {
+		gather.Start(q)
+	}
+	return gather.Wait()
+}
+
+// gather searches and crawls queries concurrently. Results keep the order
+// the queries were started in, and each URL is crawled only once.
+type gather struct {
+	g      *Grounder
+	parent context.Context // outside the turn budget, for crawling chosen results
+	ctx    context.Context
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	started map[string]bool
+	seen    map[string]bool
+	queries []string
+	parts   []Result
+}
+
+func (g *Grounder) newGather(parent, ctx context.Context) *gather {
+	return &gather{g: g, parent: parent, ctx: ctx, started: make(map[string]bool), seen: make(map[string]bool)}
+}
+
+// Start begins searching and crawling query unless it was already started
+func (a *gather) Start(query string) {
+	a.mu.Lock()
+	if a.started[query] {
+		a.mu.Unlock()
+		return
+	}
+	a.started[query] = true
+	slot := len(a.parts)
+	a.queries = append(a.queries, query)
+	a.parts = append(a.parts, Result{})
+	a.mu.Unlock()
+
+	if a.g.Hooks.OnQuery != nil {
+		a.g.Hooks.OnQuery(query)
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		part := a.run(query)
+		a.mu.Lock()
+		a.parts[slot] = part
+		a.mu.Unlock()
+	}()
+}
+
+// Abandon waits for queries started on a decision that turned out not to
+// need them; their context must already be cancelled
+func (a *gather) Abandon() {
+	a.wg.Wait()
+}
+
+// Wait blocks until every started query is done, crawls the chosen results
+// when the Grounder has a Choose hook, and merges the results
+func (a *gather) Wait() Result {
+	a.wg.Wait()
+
+	result := Result{Scores: make(map[string]float64)}
+	if a.g.Hooks.Choose != nil {
+		result.BudgetSpent = a.crawlChosen()
+	}
+	for _, part := range a.parts {
+		result.URLs = append(result.URLs, part.URLs...)
+		for url, score := range part.Scores {
+			result.Scores[url] = score
+		}
+		result.Crawled = append(result.Crawled, part.Crawled...)
+		result.Warnings = append(result.Warnings, part.Warnings...)
+		result.Results = append(result.Results, part.Results...)
+		result.Outside += part.Outside
+	}
+	result.Context = contextbuilder.SearchContext(result.Crawled)
+	return result
+}
+
+// crawlChosen lets the Choose hook pick among every query's results, then
+// crawls the picks under a fresh budget. It reports whether that ran out.
+func (a *gather) crawlChosen() bool {
+	g := a.g
+	var all []searxng.SearchResult
+	for _, part := range a.parts {
+		all = append(all, part.Results...)
+	}
+	if len(all) == 0 {
+		return false
+	}
+	picked := make(map[string]bool)
+	for _, r := range g.Hooks.Choose(all) {
+		picked[r.URL] = true
+	}
+
+	var urls []string
+	for i := range a.parts {
+		part := &a.parts[i]
+		kept := part.Results[:0]
+		part.URLs = nil
+		for _, r := range part.Results {
+			if picked[r.URL] {
+				kept = append(kept, r)
+				part.URLs = append(part.URLs, r.URL)
+			}
+		}
+		part.Results = kept
+		urls = append(urls, part.URLs...)
+	}
+	if len(urls) == 0 {
+		return false
+	}
+
+	ctx, cancel := WithBudget(a.parent, g.Budget)
+	defer cancel()
+	byURL := make(map[string]crawler.CrawlResult)
+	for _, c := range g.crawl(ctx, urls) {
+		byURL[c.URL] = c
+	}
+	for i := range a.parts {
+		part := &a.parts[i]
+		for _, url := range part.URLs {
+			if c, ok := byURL[url]; ok {
+				part.Crawled = append(part.Crawled, c)
+			}
+		}
+		part.Warnings = g.Guard.Clean(part.Crawled)
+		if g.Hooks.OnCrawled != nil && len(part.URLs) > 0 {
+			g.Hooks.OnCrawled(a.queries[i], part.Crawled)
+		}
+	}
+	return BudgetSpent(a.parent, ctx)
+}
+
+// run searches one query and crawls the URLs no other query has claimed
+func (a *gather) run(query string) Result {
+	g := a.g
+	searchCtx, span := tracing.Start(a.ctx, "search")
+	span.SetAttr("query", query)
+	start := time.Now()
+	results, err := g.Searcher.Search(searchCtx, query, g.Trust.Candidates(g.MaxResults))
+	metrics.ObserveStage("search", time.Since(start))
+	span.SetAttr("results", len(results))
+	span.End(err)
+	if err != nil {
+		if g.Hooks.OnResults != nil {
+			g.Hooks.OnResults(query, nil, err)
+		}
+		return Result{}
+	}
+
+	results = searxng.FilterBlocked(results, g.BlockedDomains)
+	part := Result{Scores: make(map[string]float64)}
+	if g.Guard != nil {
+		part.Outside = g.Guard.Outside(results)
+	}
+	a.mu.Lock()
+	for _, r := range g.Trust.Select(g.Hostile.FilterResults(g.Guard.FilterResults(results)), g.MaxResults) {
+		if !a.seen[r.URL] {
+			a.seen[r.URL] = true
+			part.URLs = append(part.URLs, r.URL)
+			part.Results = append(part.Results, r)
+			if r.Score != 0 {
+				part.Scores[r.URL] = r.Score
+			}
+		}
+	}
+	a.mu.Unlock()
+	if g.Hooks.OnResults != nil {
+		g.Hooks.OnResults(query, part.Results, nil)
+	}
+	if len(part.URLs) == 0 || g.Hooks.Choose != nil {
+		return part
+	}
+
+	part.Crawled = g.crawl(a.ctx, part.URLs)
+	part.Warnings = g.Guard.Clean(part.Crawled)
+	if g.Hooks.OnCrawled != nil {
+		g.Hooks.OnCrawled(query, part.Crawled)
+	}
+	return part
+}
+
+// crawl fetches urls, recording crawl metrics and a trace span
+func (g *Grounder) crawl(ctx context.Context, urls []string) []crawler.CrawlResult {
+	crawlCtx, span := tracing.Start(ctx, "crawl")
+	start := time.Now()
+	crawled := g.Fetcher.CrawlURLs(crawlCtx, urls)
+	metrics.ObserveStage("crawl", time.Since(start))
+
+	failed := 0
+	for _, c := range crawled {
+		if c.Error != nil {
+			failed++
+			metrics.Crawls.Inc("error")
+		} else {
+			metrics.Crawls.Inc("ok")
+		}
+	}
+	span.SetAttr("urls", len(urls))
+	span.SetAttr("failed", failed)
+	span.End(nil)
+	return crawled
+}
@@ -0,0 +1,133 @@
+package grounding
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/searxng"
+)
+
+type fakeAnalyzer struct{ decision analyzer.SearchDecision }
+
+func (a fakeAnalyzer) AnalyzeWithLLM(ctx context.Context, query string) (analyzer.SearchDecision, error) {
+	return a.decision, nil
+}
+
+type fakeSearcher map[string][]searxng.SearchResult
+
+func (s fakeSearcher) Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error) {
+	results, ok := s[query]
+	if !ok {
+		return nil, errors.New("search engine down")
+	}
+	return results, nil
+}
+
+type fakeFetcher struct {
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *fakeFetcher) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []crawler.CrawlResult
+	for _, u := range urls {
+		f.fetched = append(f.fetched, u)
+		out = append(out, crawler.CrawlResult{URL: u, Content: "page at " + u})
+	}
+	return out
+}
+
+func TestGroundReportsProgressThroughHooks(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	g := &Grounder{
+		Analyzer: fakeAnalyzer{analyzer.SearchDecision{NeedsSearch: true, SearchQueries: []string{"go", "rust", "zig"}}},
+		Searcher: fakeSearcher{
+			"go":   {{URL: "https://go.dev"}, {URL: "https://shared.example"}},
+			"rust": {{URL: "https://shared.example"}, {URL: "https://rust-lang.org"}},
+		},
+		Fetcher:    fetcher,
+		MaxResults: 5,
+	}
+
+	var mu sync.Mutex
+	var queries []string
+	results := make(map[string]int)
+	failed := make(map[string]error)
+	crawled := make(map[string]int)
+	var decided bool
+	g.Hooks = Hooks{
+		OnAnalyzed: func(decision analyzer.SearchDecision, err error) {
+			decided = decision.NeedsSearch && err == nil
+		},
+		OnQuery: func(query string) {
+			mu.Lock()
+			defer mu.Unlock()
+			queries = append(queries, query)
+		},
+		OnResults: func(query string, r []searxng.SearchResult, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			results[query], failed[query] = len(r), err
+		},
+		OnCrawled: func(query string, c []crawler.CrawlResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			crawled[query] = len(c)
+		},
+	}
+
+	result, err := g.Ground(context.Background(), "compare languages", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decided {
+		t.Error("OnAnalyzed should report the decision")
+	}
+	if len(queries) != 3 {
+		t.Errorf("OnQuery saw %v, want every search", queries)
+	}
+	if failed["zig"] == nil {
+		t.Error("OnResults should report the failed search")
+	}
+	if results["go"]+results["rust"] != 3 || crawled["go"]+crawled["rust"] != 3 {
+		t.Errorf("results %v, crawled %v; want each URL once", results, crawled)
+	}
+	if len(result.Results) != 3 || len(result.Crawled) != 3 {
+		t.Errorf("result has %d search results and %d pages, want 3 of each", len(result.Results), len(result.Crawled))
+	}
+}
+
+func TestChooseCrawlsOnlyPickedResults(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	var offered int
+	g := &Grounder{
+		Analyzer: fakeAnalyzer{analyzer.SearchDecision{NeedsSearch: true, SearchQueries: []string{"go", "rust"}}},
+		Searcher: fakeSearcher{
+			"go":   {{URL: "https://go.dev"}, {URL: "https://go.example"}},
+			"rust": {{URL: "https://rust-lang.org"}},
+		},
+		Fetcher:    fetcher,
+		MaxResults: 5,
+		Hooks: Hooks{Choose: func(results []searxng.SearchResult) []searxng.SearchResult {
+			offered = len(results)
+			return []searxng.SearchResult{{URL: "https://go.dev"}, {URL: "https://rust-lang.org"}}
+		}},
+	}
+
+	result, err := g.Ground(context.Background(), "compare languages", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered != 3 {
+		t.Errorf("Choose was offered %d results, want all 3", offered)
+	}
+	if len(fetcher.fetched) != 2 || len(result.Crawled) != 2 || len(result.URLs) != 2 {
+		t.Errorf("fetched %v, result URLs %v; want only the picks", fetcher.fetched, result.URLs)
+	}
+}
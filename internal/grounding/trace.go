@@ -0,0 +1,77 @@
+package grounding
+
+import (
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+)
+
+// Trace records how one answer was grounded, for evaluation tooling that
+// audits answers against their sources: what the analyzer decided, which
+// pages were quoted and how they ranked, which passages the model saw and
+// which it cited.
+type Trace struct {
+	Analyzer   analyzer.SearchDecision `json:"analyzer"`
+	Sources    []TraceSource           `json:"sources"`
+	Tokens     TraceTokens             `json:"tokens"`
+	Regrounded bool                    `json:"regrounded,omitempty"` // asked again after the answer ignored its sources
+	Cached     bool                    `json:"cached,omitempty"`     // answered from the answer cache, without generating
+}
+
+// TraceSource is one crawled page that made it into the prompt
+type TraceSource struct {
+	Number      int      `json:"number"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title,omitempty"`
+	SearchScore float64  `json:"search_score,omitempty"` // the search engine's score; 0 when it gave none
+	Trust       float64  `json:"trust"`                  // source_trust weight; 1 is neutral
+	Chunks      []string `json:"chunks"`                 // IDs of the passages in the prompt, e.g. "2.3"
+	TotalChunks int      `json:"total_chunks"`           // passages the page was split into
+	Cited       []string `json:"cited,omitempty"`        // IDs of the passages the answer cites
+}
+
+// TraceTokens counts the prompt and answer. Prompt and Answer are Ollama's
+// counts, 0 when it did not report them; Estimate is the prompt builder's.
+type TraceTokens struct {
+	Prompt   int `json:"prompt"`
+	Answer   int `json:"answer"`
+	Estimate int `json:"estimate,omitempty"`
+	Budget   int `json:"budget,omitempty"` // tokens the prompt builder could use; 0 means no limit
+}
+
+// Trace describes an answer grounded in result, given the sources as
+// numbered in the prompt (only those with passages count) and the final
+// answer. g may be nil when web search is disabled.
+func (g *Grounder) Trace(result Result, sources []contextbuilder.Source, answer string) *Trace {
+	trace := &Trace{Analyzer: result.Decision, Sources: []TraceSource{}}
+	cited := make(map[int][]string)
+	for _, s := range contextbuilder.Attribute(sources, answer).Cited {
+		cited[s.Number] = s.IDs()
+	}
+	for _, s := range sources {
+		if len(s.Chunks) == 0 {
+			continue
+		}
+		src := TraceSource{
+			Number:      s.Number,
+			URL:         s.URL,
+			Title:       s.Title,
+			SearchScore: result.Scores[s.URL],
+			Trust:       1,
+			Chunks:      s.IDs(),
+			TotalChunks: s.Total,
+			Cited:       cited[s.Number],
+		}
+		if g != nil {
+			src.Trust = g.Trust.Of(s.URL)
+		}
+		trace.Sources = append(trace.Sources, src)
+	}
+	return trace
+}
+
+// SetStats records Ollama's token counts for the answer
+func (t *Trace) SetStats(stats ollama.Stats) {
+	t.Tokens.Prompt = stats.PromptTokens
+	t.Tokens.Answer = stats.AnswerTokens
+}
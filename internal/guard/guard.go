@@ -0,0 +1,288 @@
+package guard
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/searxng"
+)
+
+// Categories are the built-in domain block lists. adult covers only the
+// best-known sites, and malware is a placeholder of test domains that shows
+// blocking works but protects against nothing; real coverage needs a feed
+// in block_lists.
+var Categories = map[string][]string{
+	"adult": {
+		"pornhub.com", "xvideos.com", "xnxx.com", "xhamster.com", "redtube.com",
+		"youporn.com", "spankbang.com", "chaturbate.com", "onlyfans.com", "brazzers.com",
+	},
+	"malware": {
+		"malware.wicar.org", "testsafebrowsing.appspot.com", "malware.testing.google.test",
+		"vx-underground.org",
+	},
+}
+
+// NotAllowed is the reason given for hosts outside the allow-list
+const NotAllowed = "not in allowed_domains"
+
+// Filter blocks unwanted domains and strips prompt injections from crawled pages
+type Filter struct {
+	domains         map[string]string // blocked domain -> reason
+	allowed         map[string]bool   // when set, the only domains that may be crawled
+	stripInjections bool
+}
+
+// Warning records instruction-like text removed from a source
+type Warning struct {
+	URL     string
+	Matches []string
+}
+
+// New builds a filter from category names and hosts-format block list files
+func New(categories, lists []string, stripInjections bool) (*Filter, error) {
+	f := &Filter{domains: make(map[string]string), stripInjections: stripInjections}
+
+	for _, category := range categories {
+		domains, ok := Categories[category]
+		if !ok {
+			return nil, fmt.Errorf("unknown block category %q", category)
+		}
+		for _, d := range domains {
+			f.domains[d] = category
+		}
+	}
+
+	for _, path := range lists {
+		if err := f.loadList(path); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// loadList reads a hosts file ("0.0.0.0 example.com") or a plain list of domains
+func (f *Filter) loadList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open block list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			f.add(fields[0], path)
+		default:
+			for _, host := range fields[1:] {
+				f.add(host, path)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read block list: %w", err)
+	}
+	return nil
+}
+
+func (f *Filter) add(host, reason string) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	switch host {
+	case "", "localhost", "localhost.localdomain", "local", "broadcasthost", "0.0.0.0":
+		return
+	}
+	f.domains[host] = reason
+}
+
+// SetAllowed restricts crawling to domains and their subdomains, the
+// research sandbox; an empty list lifts the restriction
+func (f *Filter) SetAllowed(domains []string) {
+	f.allowed = nil
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(d), "."))
+		if d == "" {
+			continue
+		}
+		if f.allowed == nil {
+			f.allowed = make(map[string]bool)
+		}
+		f.allowed[d] = true
+	}
+}
+
+// Sandboxed reports whether crawling is restricted to an allow-list
+func (f *Filter) Sandboxed() bool {
+	return f != nil && len(f.allowed) > 0
+}
+
+// Size returns the number of blocked domains
+func (f *Filter) Size() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.domains)
+}
+
+// Blocked reports whether a URL's host, or a parent domain, is blocked and
+// why. In the sandbox, hosts outside the allow-list are blocked too.
+func (f *Filter) Blocked(rawURL string) (string, bool) {
+	if f == nil || len(f.domains) == 0 && len(f.allowed) == 0 {
+		return "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return NotAllowed, f.Sandboxed()
+	}
+	allowed := len(f.allowed) == 0
+	for host := strings.ToLower(u.Hostname()); host != ""; {
+		if reason, ok := f.domains[host]; ok {
+			return reason, true
+		}
+		allowed = allowed || f.allowed[host]
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	if !allowed {
+		return NotAllowed, true
+	}
+	return "", false
+}
+
+// Check returns an error for URLs that must not be fetched, for
+// crawler.Crawler.SetPolicy
+func (f *Filter) Check(rawURL string) error {
+	if reason, blocked := f.Blocked(rawURL); blocked {
+		return fmt.Errorf("%s is blocked (%s)", Domain(rawURL), reason)
+	}
+	return nil
+}
+
+// Outside counts the results that the sandbox keeps from being crawled
+func (f *Filter) Outside(results []searxng.SearchResult) int {
+	n := 0
+	for _, r := range results {
+		if reason, blocked := f.Blocked(r.URL); blocked && reason == NotAllowed {
+			n++
+		}
+	}
+	return n
+}
+
+// FilterResults drops search results on blocked domains
+func (f *Filter) FilterResults(results []searxng.SearchResult) []searxng.SearchResult {
+	if f == nil || len(f.domains) == 0 && len(f.allowed) == 0 {
+		return results
+	}
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		if reason, blocked := f.Blocked(r.URL); blocked {
+			slog.Info("blocked search result", "url", r.URL, "reason", reason)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// injectionPatterns match text that tries to give the model instructions
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+|my\s+)?(previous|prior|above|earlier|preceding|system|original)\s+(instructions?|prompts?|directions?|rules|messages?|context)`),
+	regexp.MustCompile(`(?i)\bforget\s+(everything|all)\s+(you|above|before)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in|the)\b`),
+	regexp.MustCompile(`(?i)\b(new|updated|real)\s+(system\s+)?instructions\s*:`),
+	regexp.MustCompile(`(?i)\b(reveal|print|repeat|output)\s+(your|the)\s+(system\s+prompt|instructions)`),
+	regexp.MustCompile(`(?i)\bdo\s+not\s+(tell|inform|mention\s+(this\s+)?to)\s+the\s+user`),
+	regexp.MustCompile(`(?i)\b(AI|language model|assistant|LLM)s?\s+(reading|processing|summari[sz]ing)\s+this\b`),
+	regexp.MustCompile(`(?i)<\|?(im_start|im_end|system|endoftext)\|?>|\[/?INST\]|<</?SYS>>`),
+	regexp.MustCompile(`(?im)^\s*(system|assistant)\s*:`),
+}
+
+// Removed replaces instruction-like sentences in sanitized content
+const Removed = "[removed instruction-like text]"
+
+// Sanitize removes instruction-like sentences from text and returns what was removed
+func Sanitize(text string) (string, []string) {
+	type span struct{ start, end int }
+	var spans []span
+	for _, re := range injectionPatterns {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			start, end := sentenceBounds(text, m[0], m[1])
+			spans = append(spans, span{start, end})
+		}
+	}
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var sb strings.Builder
+	var matches []string
+	pos := 0
+	for _, s := range spans {
+		if s.end <= pos {
+			continue // overlaps a sentence already removed
+		}
+		if s.start < pos {
+			s.start = pos
+		}
+		sb.WriteString(text[pos:s.start])
+		sb.WriteString(Removed)
+		matches = append(matches, strings.TrimSpace(text[s.start:s.end]))
+		pos = s.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String(), matches
+}
+
+// sentenceBounds widens [start,end) to the enclosing sentence
+func sentenceBounds(text string, start, end int) (int, int) {
+	for start > 0 && !strings.ContainsRune(".!?\n", rune(text[start-1])) {
+		start--
+	}
+	for end < len(text) && !strings.ContainsRune(".!?\n", rune(text[end])) {
+		end++
+	}
+	if end < len(text) {
+		end++ // keep the terminator with the removed sentence
+	}
+	return start, end
+}
+
+// Clean strips prompt injections from crawled pages in place and returns a
+// warning for each source that attempted one
+func (f *Filter) Clean(results []crawler.CrawlResult) []Warning {
+	if f == nil || !f.stripInjections {
+		return nil
+	}
+	var warnings []Warning
+	for i := range results {
+		if results[i].Error != nil || results[i].Content == "" {
+			continue
+		}
+		clean, matches := Sanitize(results[i].Content)
+		if len(matches) == 0 {
+			continue
+		}
+		results[i].Content = clean
+		warnings = append(warnings, Warning{URL: results[i].URL, Matches: matches})
+		slog.Warn("removed prompt injection", "url", results[i].URL, "matches", len(matches))
+	}
+	return warnings
+}
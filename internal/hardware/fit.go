@@ -0,0 +1,61 @@
+package hardware
+
+import (
+	"sort"
+	"strings"
+
+	"web-ollama/internal/ollama"
+)
+
+// Bounds of the context cache per token. It grows with the model's layers
+// and attention heads, which the file size only hints at: an 8B model at
+// 4 bits takes about 128 KB per token, a 1.5B one about 28 KB.
+const (
+	minCachePerToken = 16 << 10
+	maxCachePerToken = 320 << 10
+)
+
+// Need estimates the memory a model of size bytes takes with a context
+// window of numCtx tokens: its weights, a tenth more for the runtime, and
+// the context cache. It is a rough guide, not what Ollama will allocate.
+func Need(size int64, numCtx int) int64 {
+	perToken := min(max(size/40000, minCachePerToken), maxCachePerToken)
+	return size + size/10 + perToken*int64(numCtx)
+}
+
+// Recommend picks up to n installed models that fit in vram in place of
+// current: models of the same family first, then the largest, which tend
+// to answer best. Embedding models are left out.
+func Recommend(models []ollama.ModelDetails, current ollama.ModelDetails, vram int64, numCtx, n int) []ollama.ModelDetails {
+	var fits []ollama.ModelDetails
+	for _, m := range models {
+		if m.Name == current.Name || m.Size == 0 || isEmbedding(m) || Need(m.Size, numCtx) > vram {
+			continue
+		}
+		fits = append(fits, m)
+	}
+	sort.SliceStable(fits, func(i, j int) bool {
+		if a, b := sameFamily(fits[i], current), sameFamily(fits[j], current); a != b {
+			return a
+		}
+		return fits[i].Size > fits[j].Size
+	})
+	return fits[:min(n, len(fits))]
+}
+
+// sameFamily reports whether m is a variant of current, by architecture or
+// by name before the tag
+func sameFamily(m, current ollama.ModelDetails) bool {
+	if m.Family != "" && m.Family == current.Family {
+		return true
+	}
+	base := func(name string) string {
+		name, _, _ = strings.Cut(name, ":")
+		return name
+	}
+	return base(m.Name) == base(current.Name)
+}
+
+func isEmbedding(m ollama.ModelDetails) bool {
+	return strings.Contains(m.Name, "embed") || strings.Contains(m.Family, "bert")
+}
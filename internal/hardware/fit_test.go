@@ -0,0 +1,55 @@
+package hardware
+
+import (
+	"testing"
+
+	"web-ollama/internal/ollama"
+)
+
+const gb = 1 << 30
+
+func TestNeed(t *testing.T) {
+	// An 8B model at 4 bits with a 32k context needs about 9 GB
+	if need := Need(47*gb/10, 32768); need < 8*gb || need > 10*gb {
+		t.Errorf("Need(4.7 GB, 32768) = %.1f GB", float64(need)/gb)
+	}
+	// A small model's cache stays small
+	if need := Need(1*gb, 8192); need > 2*gb {
+		t.Errorf("Need(1 GB, 8192) = %.1f GB", float64(need)/gb)
+	}
+	if Need(4*gb, 32768) <= Need(4*gb, 4096) {
+		t.Error("a longer context should need more memory")
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	current := ollama.ModelDetails{Name: "llama3.1:70b", Size: 40 * gb, Family: "llama"}
+	models := []ollama.ModelDetails{
+		current,
+		{Name: "qwen2.5:7b", Size: 44 * gb / 10, Family: "qwen2"},
+		{Name: "llama3.1:8b", Size: 47 * gb / 10, Family: "llama"},
+		{Name: "llama3.2:1b", Size: 13 * gb / 10, Family: "llama"},
+		{Name: "mistral-small:24b", Size: 14 * gb, Family: "llama"},
+		{Name: "nomic-embed-text:latest", Size: 3 * gb / 10, Family: "nomic-bert"},
+		{Name: "qwen2.5:1.5b", Size: 1 * gb, Family: "qwen2"},
+	}
+
+	picks := Recommend(models, current, 12*gb, 8192, 3)
+	var names []string
+	for _, m := range picks {
+		names = append(names, m.Name)
+	}
+	want := []string{"llama3.1:8b", "llama3.2:1b", "qwen2.5:7b"}
+	if len(names) != len(want) {
+		t.Fatalf("Recommend = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Recommend = %v, want %v", names, want)
+		}
+	}
+
+	if picks := Recommend(models, current, 512<<20, 8192, 3); len(picks) != 0 {
+		t.Errorf("Recommend with 512 MB = %v", picks)
+	}
+}
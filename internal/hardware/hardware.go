@@ -0,0 +1,106 @@
+// Package hardware finds how much memory this machine has for models and
+// estimates whether a model fits in it
+package hardware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Memory is what the machine offers for running models
+type Memory struct {
+	GPU     string // GPU name, "" when none was found
+	VRAM    int64  // bytes of GPU memory, summed over GPUs; 0 if unknown
+	Unified bool   // the GPU shares system memory, as on Apple silicon
+	RAM     int64  // bytes of system memory; 0 if unknown
+}
+
+// unifiedShare is the part of unified memory macOS lets the GPU use by default
+const unifiedShare = 0.7
+
+// Detect reads the machine's GPU and system memory from nvidia-smi, the
+// amdgpu driver or sysctl. Whatever cannot be found is left zero.
+func Detect(ctx context.Context) Memory {
+	var m Memory
+	switch runtime.GOOS {
+	case "darwin":
+		if out, err := exec.CommandContext(ctx, "sysctl", "-n", "hw.memsize").Output(); err == nil {
+			m.RAM, _ = strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		}
+		if runtime.GOARCH == "arm64" {
+			m.GPU, m.Unified = "Apple silicon", true
+			m.VRAM = int64(float64(m.RAM) * unifiedShare)
+		}
+		return m
+	case "linux":
+		m.RAM = linuxRAM()
+	}
+	if m.GPU, m.VRAM = nvidiaVRAM(ctx); m.VRAM == 0 && runtime.GOOS == "linux" {
+		m.GPU, m.VRAM = amdVRAM()
+	}
+	return m
+}
+
+// nvidiaVRAM sums the memory of NVIDIA GPUs
+func nvidiaVRAM(ctx context.Context) (string, int64) {
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return "", 0
+	}
+	var names []string
+	var total int64
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name, mib, ok := strings.Cut(scanner.Text(), ",")
+		n, err := strconv.ParseInt(strings.TrimSpace(mib), 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		names = append(names, strings.TrimSpace(name))
+		total += n << 20
+	}
+	return strings.Join(names, " + "), total
+}
+
+// amdVRAM sums the memory of GPUs run by the amdgpu driver
+func amdVRAM() (string, int64) {
+	paths, _ := filepath.Glob("/sys/class/drm/card*/device/mem_info_vram_total")
+	var total int64
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		total += n
+	}
+	if total == 0 {
+		return "", 0
+	}
+	return "AMD GPU", total
+}
+
+// linuxRAM reads MemTotal from /proc/meminfo
+func linuxRAM() int64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb << 10
+		}
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ErrInUse is returned by Load when another instance is using the history
+// file and sharing was not enabled with SetShared
+var ErrInUse = errors.New("history file is in use by another instance")
+
+// errLocked is returned by lockFile when the lock is held elsewhere
+var errLocked = errors.New("file is locked")
+
+// SetShared lets several instances use the history file at once. Each keeps
+// its own sessions, and every save merges them with what the others have
+// written. Without it, Load fails with ErrInUse while another instance has
+// the file. Call before Load.
+func (m *Manager) SetShared(shared bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shared = shared
+}
+
+// Close releases the history file for other instances
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lock == nil {
+		return nil
+	}
+	err := m.lock.Close()
+	m.lock = nil
+	return err
+}
+
+// acquireUnlocked takes the instance lock beside the history file, which is
+// exclusive unless the history is shared (must be called with lock held)
+func (m *Manager) acquireUnlocked() error {
+	if m.lock != nil {
+		return nil
+	}
+	f, err := os.OpenFile(m.filePath+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history lock: %w", err)
+	}
+	if err := lockFile(f, m.shared, false); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return fmt.Errorf("%w: %s", ErrInUse, m.filePath)
+		}
+		return fmt.Errorf("failed to lock history file: %w", err)
+	}
+	m.lock = f
+	return nil
+}
+
+// lockSaves serializes saves between instances sharing the history file
+func (m *Manager) lockSaves() (func(), error) {
+	f, err := os.OpenFile(m.filePath+".save.lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history lock: %w", err)
+	}
+	if err := lockFile(f, false, true); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock history file: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// mergeUnlocked folds the sessions other instances have saved into ours.
+// Sessions this instance changed since it last read or wrote the file are
+// kept as they are here; the others are taken from disk, including their
+// removal. Must be called with lock held.
+func (m *Manager) mergeUnlocked() error {
+	var disk History
+	data, err := os.ReadFile(m.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &disk); err != nil {
+			return fmt.Errorf("failed to parse history file: %w", err)
+		}
+	}
+
+	ours := make(map[string]Session, len(m.history.Sessions))
+	for _, s := range m.history.Sessions {
+		ours[s.ID] = s
+	}
+	changed := func(s Session) bool {
+		sum, ok := m.base[s.ID]
+		return !ok || sum != sessionHash(s)
+	}
+
+	isCurrent := func(id string) bool {
+		return m.current != nil && m.current.ID == id
+	}
+
+	var merged []Session
+	for _, theirs := range disk.Sessions {
+		s, ok := ours[theirs.ID]
+		delete(ours, theirs.ID)
+		if !ok {
+			if _, removed := m.base[theirs.ID]; !removed {
+				merged = append(merged, theirs)
+			}
+			continue
+		}
+		if changed(s) {
+			merged = append(merged, s)
+			continue
+		}
+		merged = append(merged, theirs)
+		if isCurrent(theirs.ID) {
+			current := theirs
+			m.current = &current
+		}
+	}
+	// Of the sessions missing from disk, keep the new ones and those changed
+	// here; the rest were removed by another instance. The current session
+	// is always kept.
+	for _, s := range m.history.Sessions {
+		if _, left := ours[s.ID]; left && (changed(s) || isCurrent(s.ID)) {
+			merged = append(merged, s)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].StartedAt.Before(merged[j].StartedAt) })
+	m.history.Sessions = merged
+	return nil
+}
+
+// remember records the sessions as last read or written, so the next merge
+// can tell which ones this instance changed
+func (m *Manager) remember(sessions []Session) {
+	m.base = make(map[string][sha256.Size]byte, len(sessions))
+	for _, s := range sessions {
+		m.base[s.ID] = sessionHash(s)
+	}
+}
+
+func sessionHash(s Session) [sha256.Size]byte {
+	data, _ := json.Marshal(s)
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package history
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile locks f, exclusively or shared with other shared holders. Unless
+// wait is set it fails with errLocked instead of waiting for a conflicting lock.
+func lockFile(f *os.File, shared, wait bool) error {
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	err := syscall.Flock(int(f.Fd()), how)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLocked
+	}
+	return err
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package history
+
+import "os"
+
+// lockFile is a no-op on Windows; instances are not kept apart there
+func lockFile(f *os.File, shared, wait bool) error {
+	return nil
+}
+
+// unlockFile is a no-op on Windows
+func unlockFile(f *os.File) error {
+	return nil
+}
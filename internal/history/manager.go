@@ -1,6 +1,7 @@
 package history
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -18,6 +19,10 @@ type Manager struct {
 	history    *History
 	current    *Session
 	maxSessions int
+	redact      func(string) string
+	shared      bool                         // other instances may use the file; see SetShared
+	lock        *os.File                     // instance lock, held until Close
+	base        map[string][sha256.Size]byte // sessions as last read or written, for merging
 }
 
 // NewManager creates a new history manager
@@ -29,6 +34,13 @@ func NewManager(filePath string, maxSessions int) *Manager {
 	}
 }
 
+// SetRedactor installs a filter applied to message content before it is stored
+func (m *Manager) SetRedactor(redact func(string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redact = redact
+}
+
 // Load loads history from disk
 func (m *Manager) Load() error {
 	m.mu.Lock()
@@ -39,6 +51,9 @@ func (m *Manager) Load() error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create history directory: %w", err)
 	}
+	if err := m.acquireUnlocked(); err != nil {
+		return err
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(m.filePath); os.IsNotExist(err) {
@@ -61,6 +76,7 @@ func (m *Manager) Load() error {
 		os.Rename(m.filePath, backupPath)
 		m.history = &History{Sessions: []Session{}}
 	}
+	m.remember(m.history.Sessions)
 
 	// Start a new session
 	m.startNewSession()
@@ -94,6 +110,11 @@ func (m *Manager) AddMessage(msg Message) error {
 		msg.Timestamp = time.Now()
 	}
 
+	// Keep secrets out of the history file
+	if m.redact != nil {
+		msg.Content = m.redact(msg.Content)
+	}
+
 	// Add to current session
 	m.current.Messages = append(m.current.Messages, msg)
 	m.current.UpdatedAt = time.Now()
@@ -136,6 +157,18 @@ func (m *Manager) Save() error {
 
 // saveUnlocked saves without acquiring the lock (must be called with lock held)
 func (m *Manager) saveUnlocked() error {
+	// Take in what other instances sharing the file have saved
+	if m.shared {
+		release, err := m.lockSaves()
+		if err != nil {
+			return err
+		}
+		defer release()
+		if err := m.mergeUnlocked(); err != nil {
+			return err
+		}
+	}
+
 	// Prune old sessions if needed
 	if len(m.history.Sessions) > m.maxSessions {
 		m.history.Sessions = m.history.Sessions[len(m.history.Sessions)-m.maxSessions:]
@@ -158,12 +191,219 @@ func (m *Manager) saveUnlocked() error {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
+	m.remember(m.history.Sessions)
 	return nil
 }
 
+// SessionForKey returns the ID of the session tied to an external
+// conversation key, creating one if needed. It does not change the current session.
+func (m *Manager) SessionForKey(key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.history.Sessions) - 1; i >= 0; i-- {
+		if m.history.Sessions[i].Key == key {
+			return m.history.Sessions[i].ID
+		}
+	}
+
+	now := time.Now()
+	session := Session{
+		ID:        uuid.New().String(),
+		Key:       key,
+		StartedAt: now,
+		UpdatedAt: now,
+		Messages:  []Message{},
+	}
+	m.history.Sessions = append(m.history.Sessions, session)
+	return session.ID
+}
+
+// ResetKey detaches an external conversation key from its session so the
+// next SessionForKey call starts fresh
+func (m *Manager) ResetKey(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].Key == key {
+			m.history.Sessions[i].Key = ""
+		}
+	}
+	return m.saveUnlocked()
+}
+
+// AddMessageToSession adds a message to a specific session
+func (m *Manager) AddMessageToSession(sessionID string, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if m.redact != nil {
+		msg.Content = m.redact(msg.Content)
+	}
+
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].ID == sessionID {
+			m.history.Sessions[i].Messages = append(m.history.Sessions[i].Messages, msg)
+			m.history.Sessions[i].UpdatedAt = time.Now()
+			if m.current != nil && m.current.ID == sessionID {
+				*m.current = m.history.Sessions[i]
+			}
+			return m.saveUnlocked()
+		}
+	}
+
+	return fmt.Errorf("session %s not found", sessionID)
+}
+
+// GetSessionMessages returns the last N messages of a specific session
+func (m *Manager) GetSessionMessages(sessionID string, limit int) []Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.history.Sessions {
+		if session.ID != sessionID {
+			continue
+		}
+		messages := session.Messages
+		if len(messages) > limit {
+			messages = messages[len(messages)-limit:]
+		}
+		return append([]Message(nil), messages...)
+	}
+	return []Message{}
+}
+
 // GetCurrentSession returns the current session
 func (m *Manager) GetCurrentSession() *Session {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.current
 }
+
+// SessionIDs returns the IDs of all stored sessions
+func (m *Manager) SessionIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.history.Sessions))
+	for _, s := range m.history.Sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// FindLast returns the newest message, across all sessions, for which match
+// returns true, along with the ID of its session
+func (m *Manager) FindLast(match func(Message) bool) (string, Message, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.history.Sessions) - 1; i >= 0; i-- {
+		s := m.history.Sessions[i]
+		for j := len(s.Messages) - 1; j >= 0; j-- {
+			if match(s.Messages[j]) {
+				return s.ID, s.Messages[j], true
+			}
+		}
+	}
+	return "", Message{}, false
+}
+
+// Sessions returns copies of the chat sessions that have messages, oldest
+// first. Sessions tied to an external conversation key are left out.
+func (m *Manager) Sessions() []Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var sessions []Session
+	for _, s := range m.history.Sessions {
+		if s.Key != "" || len(s.Messages) == 0 {
+			continue
+		}
+		s.Messages = append([]Message(nil), s.Messages...)
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// SetSummary stores the summary of a session
+func (m *Manager) SetSummary(sessionID, summary string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].ID == sessionID {
+			m.history.Sessions[i].Summary = summary
+			if m.current != nil && m.current.ID == sessionID {
+				m.current.Summary = summary
+			}
+			return m.saveUnlocked()
+		}
+	}
+	return fmt.Errorf("session %s not found", sessionID)
+}
+
+// SetWorkDir records the working directory of the current session
+func (m *Manager) SetWorkDir(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil {
+		m.startNewSession()
+	}
+	m.current.WorkDir = dir
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].ID == m.current.ID {
+			m.history.Sessions[i].WorkDir = dir
+			break
+		}
+	}
+	return m.saveUnlocked()
+}
+
+// StartSession ends the current session and starts a new one. It does
+// nothing if the current session has no messages yet.
+func (m *Manager) StartSession() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil && len(m.current.Messages) == 0 {
+		return nil
+	}
+	m.startNewSession()
+	return m.saveUnlocked()
+}
+
+// Resume makes a stored session current again, so new messages are added
+// to it. An empty current session is discarded.
+func (m *Manager) Resume(sessionID string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil && m.current.ID == sessionID {
+		return *m.current, nil
+	}
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].ID != sessionID {
+			continue
+		}
+		resumed := m.history.Sessions[i]
+		if m.current != nil && len(m.current.Messages) == 0 {
+			m.removeUnlocked(m.current.ID)
+		}
+		m.current = &resumed
+		return resumed, m.saveUnlocked()
+	}
+	return Session{}, fmt.Errorf("session %s not found", sessionID)
+}
+
+// removeUnlocked deletes a session (must be called with lock held)
+func (m *Manager) removeUnlocked(sessionID string) {
+	for i := range m.history.Sessions {
+		if m.history.Sessions[i].ID == sessionID {
+			m.history.Sessions = append(m.history.Sessions[:i], m.history.Sessions[i+1:]...)
+			return
+		}
+	}
+}
@@ -12,9 +12,12 @@ type History struct {
 // Session represents a single conversation session
 type Session struct {
 	ID        string    `json:"id"`
+	Key       string    `json:"key,omitempty"` // external conversation, e.g. "telegram:12345"
 	StartedAt time.Time `json:"started_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Messages  []Message `json:"messages"`
+	Summary   string    `json:"summary,omitempty"` // written when the session is closed
+	WorkDir   string    `json:"work_dir,omitempty"` // set with /cd; @file references resolve against it
 }
 
 // Message represents a single message in a conversation
@@ -22,6 +25,7 @@ type Message struct {
 	Role      string    `json:"role"`      // "user" or "assistant"
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Thinking  string    `json:"thinking,omitempty"` // the model's reasoning before the answer
 	Metadata  *Metadata `json:"metadata,omitempty"`
 }
 
@@ -29,4 +33,15 @@ type Message struct {
 type Metadata struct {
 	SearchPerformed bool     `json:"search_performed"`
 	SourceURLs      []string `json:"source_urls,omitempty"`
+	Pages           []PageRef `json:"pages,omitempty"` // saved copies of the pages the answer used
+	StandaloneQuery string   `json:"standalone_query,omitempty"` // follow-up question rewritten for search
+}
+
+// PageRef points to a saved copy of a crawled page
+type PageRef struct {
+	Number int    `json:"number"` // source number in the prompt
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	File   string `json:"file"`             // relative to the session's page directory
+	Chunks []int  `json:"chunks"`           // chunks that fit in the prompt; null means all
 }
@@ -0,0 +1,314 @@
+package hostile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/searxng"
+)
+
+// strikeMemory is how long a failure counts towards a domain's strikes
+const strikeMemory = 30 * 24 * time.Hour
+
+// interstitialChars is the length below which a page is checked for captcha
+// and paywall text; longer pages carry real content whatever else they say
+const interstitialChars = 2000
+
+// Reasons a fetch counts against its domain
+const (
+	Blocked = "blocked" // the site refused the crawler (401, 403, 429, 451)
+	Captcha = "captcha" // a bot check stood in for the page
+	Paywall = "paywall" // a subscription prompt stood in for the page
+)
+
+var captchaMarkers = []string{
+	"captcha", "verify you are human", "are you a robot", "not a robot",
+	"checking your browser", "unusual traffic", "enable javascript and cookies to continue",
+	"attention required", "just a moment...",
+}
+
+var paywallMarkers = []string{
+	"subscribe to continue reading", "subscribe to read", "subscribers only",
+	"for subscribers", "to continue reading", "already a subscriber",
+	"create a free account to continue", "you have reached your limit of free articles",
+}
+
+// domain is what is known about one site's recent failures
+type domain struct {
+	Strikes     int       `json:"strikes"` // failures in a row
+	Reason      string    `json:"reason"`  // of the last failure
+	LastFailure time.Time `json:"last_failure"`
+	SkipUntil   time.Time `json:"skip_until,omitempty"`
+}
+
+// Tracker remembers domains that keep refusing the crawler. After a number
+// of failures in a row a domain is skipped for a cooldown period; before
+// that its results are demoted behind other sites. A successful fetch clears
+// the domain's record. The tracker is persisted so every session benefits.
+type Tracker struct {
+	filePath string
+	strikes  int
+	cooldown time.Duration
+
+	mu      sync.Mutex
+	domains map[string]*domain
+	now     func() time.Time
+}
+
+// New creates a tracker backed by a JSON file that skips a domain for
+// cooldown once it has failed strikes times in a row
+func New(filePath string, strikes int, cooldown time.Duration) *Tracker {
+	return &Tracker{
+		filePath: filePath,
+		strikes:  max(strikes, 1),
+		cooldown: cooldown,
+		domains:  make(map[string]*domain),
+		now:      time.Now,
+	}
+}
+
+// Load reads the tracker from disk; a missing file yields an empty tracker
+func (t *Tracker) Load() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := os.ReadFile(t.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read hostile domains: %w", err)
+	}
+	if err := json.Unmarshal(data, &t.domains); err != nil {
+		return fmt.Errorf("failed to parse hostile domains: %w", err)
+	}
+	return nil
+}
+
+// Skipped reports whether rawURL's domain is in its cooldown, and why
+func (t *Tracker) Skipped(rawURL string) (reason string, until time.Time, ok bool) {
+	if t == nil {
+		return "", time.Time{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, found := t.domains[guard.Domain(rawURL)]
+	if !found || !t.now().Before(d.SkipUntil) {
+		return "", time.Time{}, false
+	}
+	return d.Reason, d.SkipUntil, true
+}
+
+// Skipping returns the domains in their cooldown, sorted
+func (t *Tracker) Skipping() []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	var names []string
+	for name, d := range t.domains {
+		if now.Before(d.SkipUntil) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FilterResults drops search results on skipped domains and moves results
+// on domains with strikes behind the others, lowering their score
+func (t *Tracker) FilterResults(results []searxng.SearchResult) []searxng.SearchResult {
+	if t == nil {
+		return results
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	kept := make([]searxng.SearchResult, 0, len(results))
+	var demoted []searxng.SearchResult
+	for _, r := range results {
+		d, ok := t.domains[guard.Domain(r.URL)]
+		switch {
+		case !ok:
+			kept = append(kept, r)
+		case now.Before(d.SkipUntil):
+			slog.Info("skipped hostile domain", "url", r.URL, "reason", d.Reason, "until", d.SkipUntil)
+		default:
+			r.Score /= float64(1 + d.Strikes)
+			demoted = append(demoted, r)
+		}
+	}
+	return append(kept, demoted...)
+}
+
+// Record counts the outcome of each fetch against its domain and saves the
+// tracker if anything changed. Failures that say nothing about the site's
+// attitude, such as timeouts or missing pages, are ignored.
+func (t *Tracker) Record(results []crawler.CrawlResult) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	changed := false
+	for _, r := range results {
+		name := guard.Domain(r.URL)
+		if name == "" || !r.Saved.IsZero() {
+			continue
+		}
+		reason := Classify(r)
+		d, ok := t.domains[name]
+		if reason == "" {
+			if ok && r.Error == nil {
+				delete(t.domains, name)
+				changed = true
+			}
+			continue
+		}
+		if !ok || now.Sub(d.LastFailure) > strikeMemory {
+			d = &domain{}
+			t.domains[name] = d
+		}
+		d.Strikes++
+		d.Reason = reason
+		d.LastFailure = now
+		if d.Strikes >= t.strikes {
+			d.SkipUntil = now.Add(t.cooldown)
+			slog.Info("skipping hostile domain", "domain", name, "reason", reason, "strikes", d.Strikes, "until", d.SkipUntil)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	t.pruneUnlocked(now)
+	return t.saveUnlocked()
+}
+
+// Classify returns why a fetch counts against its domain: Blocked, Captcha
+// or Paywall, or "" if it does not
+func Classify(r crawler.CrawlResult) string {
+	if errs.Is(r.Error, errs.CrawlBlocked) {
+		return Blocked
+	}
+	if r.Error != nil || len(r.Content) >= interstitialChars {
+		return ""
+	}
+	text := strings.ToLower(r.Title + "\n" + r.Content)
+	for _, m := range captchaMarkers {
+		if strings.Contains(text, m) {
+			return Captcha
+		}
+	}
+	for _, m := range paywallMarkers {
+		if strings.Contains(text, m) {
+			return Paywall
+		}
+	}
+	return ""
+}
+
+// pruneUnlocked forgets domains whose strikes are too old to count and
+// whose cooldown is over (must be called with lock held)
+func (t *Tracker) pruneUnlocked(now time.Time) {
+	for name, d := range t.domains {
+		if now.Sub(d.LastFailure) > strikeMemory && !now.Before(d.SkipUntil) {
+			delete(t.domains, name)
+		}
+	}
+}
+
+// saveUnlocked writes the tracker atomically (must be called with lock held)
+func (t *Tracker) saveUnlocked() error {
+	if err := os.MkdirAll(filepath.Dir(t.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create hostile domains directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(t.domains, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hostile domains: %w", err)
+	}
+
+	tempPath := t.filePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, t.filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// Backend crawls pages
+type Backend interface {
+	CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult
+}
+
+// Fetcher crawls with Next, skipping domains in their cooldown without a
+// request and recording how every fetch went. Captcha and paywall pages
+// are returned as errors so they are not quoted as sources.
+type Fetcher struct {
+	Tracker *Tracker
+	Next    Backend
+}
+
+// CrawlURLs implements grounding.Fetcher
+func (f *Fetcher) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	results := make([]crawler.CrawlResult, len(urls))
+	var fetch []string
+	var at []int
+	for i, url := range urls {
+		if reason, until, ok := f.Tracker.Skipped(url); ok {
+			err := fmt.Errorf("skipped after repeated failures (%s) until %s", reason, until.Format(time.RFC3339))
+			results[i] = crawler.CrawlResult{URL: url, Error: errs.New(errs.CrawlBlocked, url, err)}
+			continue
+		}
+		fetch = append(fetch, url)
+		at = append(at, i)
+	}
+	if len(fetch) == 0 {
+		return results
+	}
+
+	// The crawler returns results in completion order
+	byURL := make(map[string]crawler.CrawlResult, len(fetch))
+	for _, r := range f.Next.CrawlURLs(ctx, fetch) {
+		byURL[r.URL] = r
+	}
+	crawled := make([]crawler.CrawlResult, 0, len(fetch))
+	for j, url := range fetch {
+		r, ok := byURL[url]
+		if !ok {
+			continue
+		}
+		crawled = append(crawled, r)
+		if reason := Classify(r); r.Error == nil && reason != "" {
+			r.Error = errs.New(errs.CrawlBlocked, url, fmt.Errorf("%s page", reason))
+		}
+		results[at[j]] = r
+	}
+	if err := f.Tracker.Record(crawled); err != nil {
+		slog.Warn("failed to save hostile domains", "error", err)
+	}
+
+	kept := results[:0]
+	for _, r := range results {
+		if r.URL != "" {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,147 @@
+package hostile
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/searxng"
+)
+
+// fakeBackend answers every URL with a fixed result and counts requests
+type fakeBackend struct {
+	pages    map[string]crawler.CrawlResult
+	requests []string
+}
+
+func (b *fakeBackend) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	b.requests = append(b.requests, urls...)
+	results := make([]crawler.CrawlResult, 0, len(urls))
+	for i := len(urls) - 1; i >= 0; i-- { // out of order, like the crawler
+		r := b.pages[urls[i]]
+		r.URL = urls[i]
+		results = append(results, r)
+	}
+	return results
+}
+
+func forbidden(url string) crawler.CrawlResult {
+	return crawler.CrawlResult{URL: url, Status: 403, Error: errs.New(errs.CrawlBlocked, url, errors.New("HTTP 403"))}
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tr := New(filepath.Join(t.TempDir(), "hostile.json"), 2, time.Hour)
+	tr.now = func() time.Time { return now }
+	return tr, &now
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		r    crawler.CrawlResult
+		want string
+	}{
+		{"forbidden", forbidden("https://a.example"), Blocked},
+		{"timeout", crawler.CrawlResult{Error: errors.New("request failed: timeout")}, ""},
+		{"captcha", crawler.CrawlResult{Title: "Just a moment...", Content: "Checking your browser before accessing."}, Captcha},
+		{"paywall", crawler.CrawlResult{Title: "Rates rise", Content: "Subscribe to continue reading."}, Paywall},
+		{"article", crawler.CrawlResult{Title: "Rates rise", Content: "The central bank raised rates."}, ""},
+		{"long article mentioning captcha", crawler.CrawlResult{Content: "captcha " + strings.Repeat("text ", 500)}, ""},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.r); got != tt.want {
+			t.Errorf("%s: Classify = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStrikesThenCooldown(t *testing.T) {
+	tr, now := newTestTracker(t)
+	url := "https://www.news.example/story"
+
+	if err := tr.Record([]crawler.CrawlResult{forbidden(url)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := tr.Skipped(url); ok {
+		t.Fatal("skipped after a single failure")
+	}
+	results := tr.FilterResults([]searxng.SearchResult{{URL: url, Score: 1}, {URL: "https://other.example", Score: 0.5}})
+	if len(results) != 2 || results[0].URL != "https://other.example" || results[1].Score != 0.5 {
+		t.Fatalf("struck domain not demoted: %+v", results)
+	}
+
+	tr.Record([]crawler.CrawlResult{forbidden(url)})
+	reason, until, ok := tr.Skipped("https://news.example/other")
+	if !ok || reason != Blocked || !until.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Skipped = %q, %v, %v; want blocked until an hour from now", reason, until, ok)
+	}
+	if results := tr.FilterResults([]searxng.SearchResult{{URL: url}}); len(results) != 0 {
+		t.Fatalf("skipped domain kept in results: %+v", results)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, _, ok := tr.Skipped(url); ok {
+		t.Fatal("still skipped after the cooldown")
+	}
+	tr.Record([]crawler.CrawlResult{{URL: url, Content: "The story."}})
+	if results := tr.FilterResults([]searxng.SearchResult{{URL: url, Score: 1}}); results[0].Score != 1 {
+		t.Fatal("success did not clear the domain's strikes")
+	}
+}
+
+func TestOldStrikesAreForgotten(t *testing.T) {
+	tr, now := newTestTracker(t)
+	url := "https://a.example"
+	tr.Record([]crawler.CrawlResult{forbidden(url)})
+	*now = now.Add(strikeMemory + time.Hour)
+	tr.Record([]crawler.CrawlResult{forbidden(url)})
+	if _, _, ok := tr.Skipped(url); ok {
+		t.Fatal("failures a month apart counted as in a row")
+	}
+}
+
+func TestPersists(t *testing.T) {
+	tr, now := newTestTracker(t)
+	tr.Record([]crawler.CrawlResult{forbidden("https://a.example"), forbidden("https://a.example")})
+
+	loaded := New(tr.filePath, 2, time.Hour)
+	loaded.now = func() time.Time { return *now }
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Skipping(); len(got) != 1 || got[0] != "a.example" {
+		t.Fatalf("Skipping after Load = %v, want [a.example]", got)
+	}
+}
+
+func TestFetcherSkipsWithoutRequest(t *testing.T) {
+	tr, _ := newTestTracker(t)
+	backend := &fakeBackend{pages: map[string]crawler.CrawlResult{
+		"https://wall.example/a": {Title: "Subscribe", Content: "Already a subscriber? Log in."},
+		"https://ok.example/b":   {Title: "B", Content: "Real content."},
+	}}
+	f := &Fetcher{Tracker: tr, Next: backend}
+	urls := []string{"https://wall.example/a", "https://ok.example/b"}
+
+	for i := 0; i < 2; i++ {
+		results := f.CrawlURLs(context.Background(), urls)
+		if len(results) != 2 || results[0].URL != urls[0] || !errs.Is(results[0].Error, errs.CrawlBlocked) || results[1].Error != nil {
+			t.Fatalf("round %d: results = %+v", i, results)
+		}
+	}
+	backend.requests = nil
+	results := f.CrawlURLs(context.Background(), urls)
+	if len(backend.requests) != 1 || backend.requests[0] != urls[1] {
+		t.Fatalf("requested %v, want only the unskipped page", backend.requests)
+	}
+	if len(results) != 2 || !strings.Contains(results[0].Error.Error(), "paywall") {
+		t.Fatalf("skipped result = %+v", results[0])
+	}
+}
@@ -0,0 +1,120 @@
+package htmlexport
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/postprocess"
+)
+
+// Document is a session ready to be rendered
+type Document struct {
+	Title   string
+	Started time.Time
+	Model   string
+	Turns   []Turn
+
+	// Bibliography lists every source cited in the session
+	Bibliography []postprocess.Entry
+}
+
+// Turn is one question and its answer
+type Turn struct {
+	Question string
+	ReadAs   string // follow-up rewritten for search, if it was
+	Answer   string // Markdown
+	Thinking string
+	Sources  []contextbuilder.Source // saved passages, when copies were kept
+	URLs     []string                // source URLs, used when no passages were saved
+}
+
+// Write renders doc as a single HTML file with no external resources, so it
+// can be mailed or dropped into a shared folder and opened anywhere
+func Write(w io.Writer, doc Document) error {
+	if err := page.Execute(w, doc); err != nil {
+		return fmt.Errorf("failed to render HTML export: %w", err)
+	}
+	return nil
+}
+
+var page = template.Must(template.New("page").Funcs(template.FuncMap{
+	"markdown": renderMarkdown,
+	"anchor":   anchor,
+	"chunkID":  contextbuilder.ChunkID,
+	"title": func(s contextbuilder.Source) string {
+		if s.Title != "" {
+			return s.Title
+		}
+		return s.URL
+	},
+}).Parse(pageTemplate))
+
+// anchor is the element ID of a passage, the target of its citations
+func anchor(turn int, id string) string {
+	return fmt.Sprintf("t%d-%s", turn, id)
+}
+
+const pageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font: 16px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; background: #f6f8fa; margin: 0; }
+main { max-width: 52rem; margin: 0 auto; padding: 2rem 1rem 4rem; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 2rem; }
+header p { color: #59636e; margin-top: 0; }
+.turn { margin-bottom: 2.5rem; }
+.question { background: #0969da; color: #fff; border-radius: 12px 12px 2px 12px; padding: .75rem 1rem; margin-left: 15%; white-space: pre-wrap; }
+.read-as { color: #59636e; font-size: .875rem; text-align: right; margin: .25rem 0 0; }
+.answer { background: #fff; border: 1px solid #d0d7de; border-radius: 12px 12px 12px 2px; padding: .25rem 1rem; margin-top: 1rem; margin-right: 5%; }
+details { margin: .75rem 0; }
+summary { cursor: pointer; color: #59636e; font-size: .875rem; }
+.thinking { color: #59636e; font-style: italic; white-space: pre-wrap; border-left: 3px solid #d0d7de; padding-left: .75rem; }
+pre { background: #f6f8fa; border-radius: 6px; padding: .75rem; overflow-x: auto; }
+code { font: .875em ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+.cite { font-size: .8em; vertical-align: super; text-decoration: none; }
+.sources ol { padding-left: 1.25rem; }
+.sources blockquote { margin: .5rem 0; padding: .25rem .75rem; border-left: 3px solid #0969da; background: #f6f8fa; white-space: pre-wrap; font-size: .9rem; }
+.sources blockquote:target { background: #fff8c5; }
+.bibliography li { margin-bottom: .25rem; }
+.accessed { color: #59636e; font-size: .875rem; }
+.passage-id { color: #59636e; font-family: ui-monospace, monospace; font-size: .8rem; }
+</style>
+</head>
+<body>
+<main>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{.Started.Format "2 January 2006, 15:04"}}{{if .Model}} · {{.Model}}{{end}}</p>
+</header>
+{{range $i, $t := .Turns}}<section class="turn">
+<div class="question">{{$t.Question}}</div>
+{{if $t.ReadAs}}<p class="read-as">Read as: {{$t.ReadAs}}</p>
+{{end}}<div class="answer">
+{{if $t.Thinking}}<details><summary>Thinking</summary><div class="thinking">{{$t.Thinking}}</div></details>
+{{end}}{{markdown $i $t.Answer}}
+{{if $t.Sources}}<details class="sources" open><summary>Sources ({{len $t.Sources}})</summary><ol>
+{{range $t.Sources}}<li value="{{.Number}}"><a href="{{.URL}}">{{title .}}</a>
+{{$n := .Number}}{{range .Chunks}}<blockquote id="{{anchor $i (chunkID $n .)}}"><span class="passage-id">[{{chunkID $n .}}]</span> {{.Text}}</blockquote>
+{{end}}</li>
+{{end}}</ol></details>
+{{else if $t.URLs}}<details class="sources" open><summary>Sources ({{len $t.URLs}})</summary><ol>
+{{range $t.URLs}}<li><a href="{{.}}">{{.}}</a></li>
+{{end}}</ol></details>
+{{end}}</div>
+</section>
+{{end}}{{if .Bibliography}}<section class="bibliography">
+<h2>Bibliography</h2>
+<ol>
+{{range .Bibliography}}<li><a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a> <span class="accessed">first accessed {{.Accessed.Format "2 January 2006, 15:04"}}</span></li>
+{{end}}</ol>
+</section>
+{{end}}</main>
+</body>
+</html>
+`
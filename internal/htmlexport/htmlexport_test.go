@@ -0,0 +1,77 @@
+package htmlexport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/contextbuilder"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	answer := "## Price\n\nIt costs **$1,399** [1.2] per `go <run>`.\n\n- one\n- two\n\n1. first\n\n```\nif a < b {}\n```\n\nSee [the shop](https://frame.work/?a=1&b=2) or [bad](javascript:alert(1))."
+	got := string(renderMarkdown(0, answer))
+
+	for _, want := range []string{
+		"<h3>Price</h3>",
+		"<strong>$1,399</strong>",
+		`<a class="cite" href="#t0-1.2">[1.2]</a>`,
+		"<code>go &lt;run&gt;</code>",
+		"<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+		"<ol>\n<li>first</li>\n</ol>",
+		"<pre><code>if a &lt; b {}</code></pre>",
+		`<a href="https://frame.work/?a=1&amp;b=2">the shop</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `href="javascript`) {
+		t.Errorf("non-http link rendered:\n%s", got)
+	}
+}
+
+func TestWriteEscapesAndEmbedsSources(t *testing.T) {
+	doc := Document{
+		Title:   "web-ollama session",
+		Started: time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+		Model:   "qwen3",
+		Turns: []Turn{{
+			Question: "<script>alert(1)</script> how much?",
+			ReadAs:   "How much does the Framework Laptop 16 cost?",
+			Answer:   "About $1,399 [1.1].",
+			Thinking: "The user wants a price.",
+			Sources: []contextbuilder.Source{{
+				Number: 1, URL: "https://frame.work/laptop16", Title: "Framework",
+				Chunks: []contextbuilder.Chunk{{Index: 0, Text: "Starts at $1,399 <b>today</b>"}},
+			}},
+		}, {
+			Question: "Any alternatives?",
+			Answer:   "Several.",
+			URLs:     []string{"https://example.com/a"},
+		}},
+	}
+
+	var sb strings.Builder
+	if err := Write(&sb, doc); err != nil {
+		t.Fatal(err)
+	}
+	got := sb.String()
+
+	for _, want := range []string{
+		"&lt;script&gt;alert(1)&lt;/script&gt; how much?",
+		"Read as: How much does the Framework Laptop 16 cost?",
+		"<details><summary>Thinking</summary>",
+		`<blockquote id="t0-1.1">`,
+		"Starts at $1,399 &lt;b&gt;today&lt;/b&gt;",
+		`<a href="https://example.com/a">`,
+		"1 March 2025, 09:30 · qwen3",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") || strings.Contains(got, "<link") {
+		t.Error("export has script or external resources")
+	}
+}
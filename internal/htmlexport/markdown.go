@@ -0,0 +1,121 @@
+package htmlexport
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// The subset of Markdown that models write in answers: headings, fenced
+// code, bullet and numbered lists, paragraphs, and inline code, bold, links
+// and passage citations
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	numberedPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)]+)\)`)
+	citePattern     = regexp.MustCompile(`\[(\d+\.\d+)\]`)
+)
+
+// renderMarkdown converts an answer to HTML. Everything is escaped before
+// markup is added, and only http(s) links are kept. Passage citations link
+// to the quoted passage of the given turn.
+func renderMarkdown(turn int, text string) template.HTML {
+	var sb strings.Builder
+	var paragraph []string
+	list := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			fmt.Fprintf(&sb, "<p>%s</p>\n", inline(turn, strings.Join(paragraph, "\n")))
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			fmt.Fprintf(&sb, "</%s>\n", list)
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		flushParagraph()
+		if list != tag {
+			closeList()
+			fmt.Fprintf(&sb, "<%s>\n", tag)
+			list = tag
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			fmt.Fprintf(&sb, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case headingPattern.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			// The page title is the only h1
+			level := len(m[1]) + 1
+			if level > 6 {
+				level = 6
+			}
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", level, inline(turn, m[2]), level)
+		case bulletPattern.MatchString(line):
+			openList("ul")
+			fmt.Fprintf(&sb, "<li>%s</li>\n", inline(turn, bulletPattern.FindStringSubmatch(line)[1]))
+		case numberedPattern.MatchString(line):
+			openList("ol")
+			fmt.Fprintf(&sb, "<li>%s</li>\n", inline(turn, numberedPattern.FindStringSubmatch(line)[1]))
+		default:
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushParagraph()
+	closeList()
+	return template.HTML(sb.String())
+}
+
+// inline escapes a line of text and renders code spans, bold, links and
+// passage citations. Code spans are left as written.
+func inline(turn int, text string) string {
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			parts[i] = "<code>" + html.EscapeString(part) + "</code>"
+			continue
+		}
+		s := html.EscapeString(part)
+		s = boldPattern.ReplaceAllString(s, "<strong>$1</strong>")
+		s = linkPattern.ReplaceAllString(s, `<a href="$2">$1</a>`)
+		s = citePattern.ReplaceAllStringFunc(s, func(m string) string {
+			id := m[1 : len(m)-1]
+			return fmt.Sprintf(`<a class="cite" href="#%s">%s</a>`, anchor(turn, id), m)
+		})
+		if i%2 == 1 {
+			// Unmatched backtick
+			s = "`" + s
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,569 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entrySuffix marks cache files, so Clear and Stats leave anything else alone
+const entrySuffix = ".cache"
+
+// maxHeuristic caps the freshness guessed from Last-Modified
+const maxHeuristic = 24 * time.Hour
+
+// drainBytes is how much of an unread body Close reads to find its end, so
+// that responses decoded without reading the trailing newline are stored
+const drainBytes = 4096
+
+// storable lists the statuses cacheable without explicit freshness
+// (RFC 7231 section 6.1), less 206 since ranges are not supported
+var storable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// Cache is an on-disk HTTP cache that clients share through Transport. It
+// follows RFC 7234 for a private cache: responses are stored unless either
+// side says no-store, served while fresh by max-age, Expires or a heuristic,
+// and revalidated with ETag or Last-Modified once stale. The least recently
+// used responses are removed once the cache outgrows its size cap.
+type Cache struct {
+	dir       string
+	maxBytes  int64
+	heuristic time.Duration
+
+	mu   sync.Mutex
+	size int64 // bytes on disk; -1 until first counted
+	now  func() time.Time
+}
+
+// New creates a cache in dir holding at most maxBytes. Responses that give
+// neither an expiry nor Last-Modified stay fresh for heuristic; 0 stores them
+// only for revalidation.
+func New(dir string, maxBytes int64, heuristic time.Duration) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes, heuristic: heuristic, size: -1, now: time.Now}
+}
+
+// entry is a stored response
+type entry struct {
+	URL        string            `json:"url"`
+	Status     string            `json:"status"`
+	StatusCode int               `json:"status_code"`
+	Header     http.Header       `json:"header"`
+	Vary       map[string]string `json:"vary,omitempty"` // request headers named by Vary, as sent
+	Requested  time.Time         `json:"requested"`
+	Received   time.Time         `json:"received"`
+	Body       []byte            `json:"-"`
+}
+
+type revalidateKey struct{}
+
+// WithRevalidate makes requests under ctx check stored responses with the
+// server even while they are fresh
+func WithRevalidate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidateKey{}, true)
+}
+
+// Transport returns a RoundTripper that answers from the cache when it can
+// and stores what base returns. A nil cache returns base.
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if c == nil {
+		return base
+	}
+	return &transport{c: c, base: base}
+}
+
+type transport struct {
+	c    *Cache
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.c
+	if req.Method != http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && req.Method != http.MethodHead && resp.StatusCode < 400 {
+			c.remove(req.URL.String())
+		}
+		return resp, err
+	}
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	e := c.load(req)
+	if e != nil && !mustRevalidate(req, reqCC, e) && c.fresh(e) {
+		c.touch(req.URL.String())
+		return e.response(req, c.age(e), "HIT"), nil
+	}
+
+	outgoing := req
+	if e != nil {
+		outgoing = conditional(req, e)
+	}
+	requested := c.now()
+	resp, err := t.base.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	received := c.now()
+
+	if e != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		for key, values := range resp.Header {
+			switch key {
+			case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+				continue
+			}
+			e.Header[key] = values
+		}
+		e.Requested, e.Received = requested, received
+		c.store(e)
+		return e.response(req, c.age(e), "REVALIDATED"), nil
+	}
+
+	if !cacheable(resp) {
+		return resp, nil
+	}
+	saved := &entry{
+		URL:        req.URL.String(),
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Vary:       varyValues(req, resp.Header),
+		Requested:  requested,
+		Received:   received,
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, limit: c.maxBytes / 8, done: func(body []byte) {
+		saved.Body = body
+		c.store(saved)
+	}}
+	resp.Header.Set("X-Cache", "MISS")
+	return resp, nil
+}
+
+// mustRevalidate reports whether a stored response has to be checked with
+// the server before it is used
+func mustRevalidate(req *http.Request, reqCC cacheControl, e *entry) bool {
+	if reqCC.has("no-cache") || reqCC.value("max-age") == "0" || req.Header.Get("Pragma") == "no-cache" {
+		return true
+	}
+	if req.Context().Value(revalidateKey{}) != nil {
+		return true
+	}
+	return parseCacheControl(e.Header).has("no-cache")
+}
+
+// cacheable reports whether a response may be stored
+func cacheable(resp *http.Response) bool {
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	if storable[resp.StatusCode] {
+		return true
+	}
+	// Other statuses need explicit freshness
+	return cc.value("max-age") != "" || resp.Header.Get("Expires") != ""
+}
+
+// conditional copies req with the validators of a stored response
+func conditional(req *http.Request, e *entry) *http.Request {
+	etag, modified := e.Header.Get("ETag"), e.Header.Get("Last-Modified")
+	if etag == "" && modified == "" {
+		return req
+	}
+	out := req.Clone(req.Context())
+	if etag != "" {
+		out.Header.Set("If-None-Match", etag)
+	}
+	if modified != "" {
+		out.Header.Set("If-Modified-Since", modified)
+	}
+	return out
+}
+
+// lifetime is how long a response stays fresh after it was generated
+// (RFC 7234 section 4.2.1)
+func (c *Cache) lifetime(e *entry) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if v := cc.value("max-age"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	date := e.date()
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0 // an invalid Expires means already expired
+		}
+		return expires.Sub(date)
+	}
+	if !storable[e.StatusCode] {
+		return 0
+	}
+	if modified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil {
+		return min(date.Sub(modified)/10, maxHeuristic)
+	}
+	return c.heuristic
+}
+
+// age is how old a stored response is now (RFC 7234 section 4.2.3)
+func (c *Cache) age(e *entry) time.Duration {
+	apparent := max(e.Received.Sub(e.date()), 0)
+	var ageValue time.Duration
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	corrected := ageValue + e.Received.Sub(e.Requested)
+	return max(apparent, corrected) + c.now().Sub(e.Received)
+}
+
+// fresh reports whether a stored response can be used without the server
+func (c *Cache) fresh(e *entry) bool {
+	return c.lifetime(e) > c.age(e)
+}
+
+// date is when the server generated the response, or when it arrived
+func (e *entry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.Received
+}
+
+// response rebuilds an *http.Response for req from the stored entry
+func (e *entry) response(req *http.Request, age time.Duration, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	header.Set("X-Cache", status)
+	return &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// varyValues records the request headers a response varies on
+func varyValues(req *http.Request, header http.Header) map[string]string {
+	names := header.Values("Vary")
+	if len(names) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, list := range names {
+		for _, name := range strings.Split(list, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				values[name] = req.Header.Get(name)
+			}
+		}
+	}
+	return values
+}
+
+// matches reports whether req sends the headers a stored response varies on
+func (e *entry) matches(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheControl holds the directives of a Cache-Control header
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, line := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) value(name string) string {
+	return cc[name]
+}
+
+// recordingBody copies what is read so the response can be stored once the
+// body has been read to the end. Bodies over limit are not stored.
+type recordingBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	limit int64
+	eof   bool
+	over  bool
+	done  func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.over {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.over = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	if !b.eof && !b.over {
+		io.Copy(io.Discard, io.LimitReader(b, drainBytes+1))
+	}
+	err := b.ReadCloser.Close()
+	if b.eof && !b.over && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return err
+}
+
+// path is where the response for url is stored
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+entrySuffix)
+}
+
+// load reads the stored response for req, or returns nil
+func (c *Cache) load(req *http.Request) *entry {
+	data, err := os.ReadFile(c.path(req.URL.String()))
+	if err != nil {
+		return nil
+	}
+	meta, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(meta, &e); err != nil || e.URL != req.URL.String() || !e.matches(req) {
+		return nil
+	}
+	e.Body = body
+	return &e
+}
+
+// store writes a response to disk and evicts old ones if the cache is over
+// its size cap. Failures only cost a later cache miss, so they are logged.
+func (c *Cache) store(e *entry) {
+	if err := c.write(e); err != nil {
+		slog.Warn("failed to store HTTP response", "url", e.URL, "error", err)
+	}
+}
+
+func (c *Cache) write(e *entry) error {
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	data := append(append(meta, '\n'), e.Body...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.countUnlocked(); err != nil {
+		return err
+	}
+
+	path := c.path(e.URL)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	var old int64
+	if info, err := os.Stat(path); err == nil {
+		old = info.Size()
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	c.size += int64(len(data)) - old
+	if c.size > c.maxBytes {
+		return c.evictUnlocked()
+	}
+	return nil
+}
+
+// remove drops the stored response for url
+func (c *Cache) remove(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(url)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if os.Remove(path) == nil && c.size >= 0 {
+		c.size -= info.Size()
+	}
+}
+
+// touch marks the stored response for url as just used
+func (c *Cache) touch(url string) {
+	now := c.now()
+	os.Chtimes(c.path(url), now, now)
+}
+
+// stored is a cache file on disk
+type stored struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// list returns every cache file
+func (c *Cache) list() ([]stored, error) {
+	var files []stored
+	err := filepath.WalkDir(c.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == c.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, entrySuffix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed meanwhile
+		}
+		files = append(files, stored{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP cache: %w", err)
+	}
+	return files, nil
+}
+
+// countUnlocked totals the cache's size on first use (must be called with
+// lock held)
+func (c *Cache) countUnlocked() error {
+	if c.size >= 0 {
+		return nil
+	}
+	files, err := c.list()
+	if err != nil {
+		return err
+	}
+	c.size = 0
+	for _, f := range files {
+		c.size += f.size
+	}
+	return nil
+}
+
+// evictUnlocked removes the least recently used responses until the cache
+// is a tenth under its cap, so evictions come in batches (must be called
+// with lock held)
+func (c *Cache) evictUnlocked() error {
+	files, err := c.list()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	c.size = 0
+	for _, f := range files {
+		c.size += f.size
+	}
+	target := c.maxBytes - c.maxBytes/10
+	for _, f := range files {
+		if c.size <= target {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict HTTP response: %w", err)
+		}
+		c.size -= f.size
+	}
+	return nil
+}
+
+// Stats returns how many responses the cache holds and their size on disk
+func (c *Cache) Stats() (entries int, bytes int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := c.list()
+	if err != nil {
+		return 0, 0, err
+	}
+	c.size = 0
+	for _, f := range files {
+		c.size += f.size
+	}
+	return len(files), c.size, nil
+}
+
+// Clear removes every stored response
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := c.list()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear HTTP cache: %w", err)
+		}
+	}
+	c.size = 0
+	return nil
+}
+
+// MaxBytes returns the cache's size cap
+func (c *Cache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Dir returns the directory the cache is stored in
+func (c *Cache) Dir() string {
+	return c.dir
+}
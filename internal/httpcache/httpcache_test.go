@@ -0,0 +1,177 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// get fetches url through client and returns the body and X-Cache status
+func get(t *testing.T, client *http.Client, url string, header ...string) (string, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body), resp.Header.Get("X-Cache")
+}
+
+func newTestCache(t *testing.T, heuristic time.Duration) (*Cache, *http.Client, *time.Time) {
+	t.Helper()
+	now := time.Now()
+	c := New(t.TempDir(), 1<<20, heuristic)
+	c.now = func() time.Time { return now }
+	return c, &http.Client{Transport: c.Transport(nil)}, &now
+}
+
+func TestServesFreshResponses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "page")
+	}))
+	defer srv.Close()
+	_, client, now := newTestCache(t, 0)
+
+	if body, status := get(t, client, srv.URL); body != "page" || status != "MISS" {
+		t.Fatalf("first fetch = %q, %s", body, status)
+	}
+	if body, status := get(t, client, srv.URL); body != "page" || status != "HIT" || hits.Load() != 1 {
+		t.Fatalf("second fetch = %q, %s after %d requests", body, status, hits.Load())
+	}
+	if _, status := get(t, client, srv.URL, "Cache-Control", "no-cache"); status != "MISS" || hits.Load() != 2 {
+		t.Fatalf("no-cache request answered from the cache (%s)", status)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	if _, status := get(t, client, srv.URL); status != "MISS" || hits.Load() != 3 {
+		t.Fatalf("stale response served (%s)", status)
+	}
+}
+
+func TestRevalidatesWithETag(t *testing.T) {
+	var full atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		io.WriteString(w, "page")
+	}))
+	defer srv.Close()
+	_, client, _ := newTestCache(t, time.Hour)
+
+	get(t, client, srv.URL)
+	if body, status := get(t, client, srv.URL); body != "page" || status != "REVALIDATED" || full.Load() != 1 {
+		t.Fatalf("revalidated fetch = %q, %s after %d full responses", body, status, full.Load())
+	}
+}
+
+func TestNoStoreAndHeuristic(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		io.WriteString(w, "page")
+	}))
+	defer srv.Close()
+	_, client, now := newTestCache(t, 10*time.Minute)
+
+	get(t, client, srv.URL+"/private")
+	if _, status := get(t, client, srv.URL+"/private"); status == "HIT" {
+		t.Fatal("no-store response was cached")
+	}
+
+	get(t, client, srv.URL+"/plain")
+	if _, status := get(t, client, srv.URL+"/plain"); status != "HIT" {
+		t.Fatalf("response without freshness not kept for the heuristic (%s)", status)
+	}
+	*now = now.Add(11 * time.Minute)
+	if _, status := get(t, client, srv.URL+"/plain"); status != "MISS" {
+		t.Fatalf("heuristic freshness outlived (%s)", status)
+	}
+}
+
+func TestVary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, r.Header.Get("Accept-Language"))
+	}))
+	defer srv.Close()
+	_, client, _ := newTestCache(t, 0)
+
+	get(t, client, srv.URL, "Accept-Language", "en")
+	if body, status := get(t, client, srv.URL, "Accept-Language", "de"); body != "de" || status != "MISS" {
+		t.Fatalf("response for another language served: %q, %s", body, status)
+	}
+}
+
+func TestSizeCapAndClear(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}))
+	defer srv.Close()
+	c := New(t.TempDir(), 8000, 0)
+	client := &http.Client{Transport: c.Transport(nil)}
+
+	for i := 0; i < 20; i++ {
+		get(t, client, srv.URL+"/"+strings.Repeat("p", i+1))
+	}
+	entries, size, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size > 8000 || entries == 0 {
+		t.Fatalf("cache holds %d responses in %d bytes, cap 8000", entries, size)
+	}
+	if _, status := get(t, client, srv.URL+"/"+strings.Repeat("p", 20)); status != "HIT" {
+		t.Fatalf("newest response evicted (%s)", status)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _, _ := c.Stats(); entries != 0 {
+		t.Fatalf("%d responses left after Clear", entries)
+	}
+}
+
+func TestPartlyReadBodiesAreNotStored(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, strings.Repeat("x", 100000))
+	}))
+	defer srv.Close()
+	_, client, _ := newTestCache(t, 0)
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.CopyN(io.Discard, resp.Body, 10)
+	resp.Body.Close()
+	if _, status := get(t, client, srv.URL); status != "MISS" {
+		t.Fatalf("truncated body was stored (%s)", status)
+	}
+}
@@ -0,0 +1,130 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"web-ollama/internal/crawler"
+)
+
+// supportedExtensions lists the file types ExtractFile understands
+var supportedExtensions = map[string]bool{
+	".pdf":      true,
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+	".text":     true,
+	".rst":      true,
+}
+
+// IsSupported reports whether a file can be ingested
+func IsSupported(path string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ExpandPaths turns files and directories into a list of supported files.
+// Directories are walked recursively, skipping hidden entries.
+func ExpandPaths(paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			if !seen[path] {
+				files = append(files, path)
+				seen[path] = true
+			}
+			continue
+		}
+
+		err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil // Skip unreadable entries
+			}
+			if p != path && strings.HasPrefix(fi.Name(), ".") {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fi.IsDir() && IsSupported(p) && !seen[p] {
+				files = append(files, p)
+				seen[p] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// ExtractFile returns a title and the plain text of a PDF, Markdown or text file
+func ExtractFile(path string) (title string, text string, err error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	if ext == ".pdf" {
+		text, err = extractPDF(path)
+		return title, text, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", "", fmt.Errorf("%s does not look like a text file", path)
+	}
+	text = string(data)
+
+	if ext == ".md" || ext == ".markdown" {
+		text = stripFrontMatter(text)
+		if heading := crawler.FirstHeading(text); heading != "" {
+			title = heading
+		}
+	}
+
+	return title, text, nil
+}
+
+// extractPDF converts a PDF to text with pdftotext from poppler-utils
+func extractPDF(path string) (string, error) {
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return "", fmt.Errorf("pdftotext not found; install poppler-utils to ingest PDFs")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "pdftotext", "-layout", "-enc", "UTF-8", path, "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("pdftotext failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// stripFrontMatter removes a leading YAML front matter block
+func stripFrontMatter(text string) string {
+	if !strings.HasPrefix(text, "---\n") {
+		return text
+	}
+	if end := strings.Index(text[4:], "\n---"); end >= 0 {
+		return strings.TrimLeft(text[4+end+4:], "\n")
+	}
+	return text
+}
@@ -0,0 +1,97 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEmbedder embeds each text as its length
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i] = []float32{float32(len(t)), 1}
+	}
+	return vectors, nil
+}
+
+func lines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestStoreAppendsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knowledge.json")
+	ctx := context.Background()
+	s := NewStore(path, fakeEmbedder{}, "nomic")
+	s.Add(ctx, "https://a.example", "A", "first page")
+	s.Add(ctx, "https://b.example", "B", "second page")
+	s.Add(ctx, "https://a.example", "A", "first page, edited")
+
+	if got := lines(t, path); len(got) != 4 || !strings.Contains(got[0], `"embed_model":"nomic"`) {
+		t.Fatalf("file = %q, want a header and three records", got)
+	}
+
+	loaded := NewStore(path, fakeEmbedder{}, "nomic")
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if docs, chunks := loaded.Stats(); docs != 2 || chunks != 2 {
+		t.Errorf("Stats = %d documents, %d chunks; want 2, 2", docs, chunks)
+	}
+	if !loaded.HasContent("first page, edited") || loaded.HasContent("first page") {
+		t.Error("the re-added page should replace its earlier version")
+	}
+
+	if err := NewStore(path, fakeEmbedder{}, "other").Load(); err == nil {
+		t.Error("loading with another embedding model should fail")
+	}
+}
+
+func TestStoreBatchSavesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knowledge.json")
+	ctx := context.Background()
+	s := NewStore(path, fakeEmbedder{}, "nomic")
+	err := s.Batch(func() error {
+		s.Add(ctx, "a.txt", "a", "one")
+		s.Add(ctx, "b.txt", "b", "two")
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("the batch was saved before it ended")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(t, path); len(got) != 3 {
+		t.Errorf("file has %d lines, want a header and two records", len(got))
+	}
+}
+
+func TestStoreDropsTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knowledge.json")
+	s := NewStore(path, fakeEmbedder{}, "nomic")
+	if _, err := s.Add(context.Background(), "a.txt", "", "one"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A record cut short by a crash is dropped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"document":{"id":"2","source":"b.txt"`)
+	f.Close()
+	s = NewStore(path, fakeEmbedder{}, "nomic")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if docs, _ := s.Stats(); docs != 1 || !s.HasContent("one") {
+		t.Errorf("Stats = %d documents, want the one complete record", docs)
+	}
+}
@@ -0,0 +1,38 @@
+package knowledge
+
+import (
+	"time"
+)
+
+// Index is the knowledge base in memory. Its file starts with an Index,
+// which holds only EmbedModel there; the documents follow as records.
+type Index struct {
+	EmbedModel string     `json:"embed_model"`
+	Documents  []Document `json:"-"`
+	Chunks     []Chunk    `json:"-"`
+}
+
+// Document is a crawled page or ingested file that has been indexed
+type Document struct {
+	ID      string    `json:"id"`
+	Source  string    `json:"source"` // URL or file path
+	Title   string    `json:"title"`
+	Hash    string    `json:"hash"` // SHA-256 of the text
+	AddedAt time.Time `json:"added_at"`
+	Chunks  int       `json:"chunks"`
+}
+
+// Chunk is an embedded slice of a document's text
+type Chunk struct {
+	DocID  string    `json:"doc_id"`
+	Index  int       `json:"index"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// Result is a chunk returned by a similarity search
+type Result struct {
+	Chunk    Chunk
+	Document Document
+	Score    float64 // cosine similarity, higher is closer
+}
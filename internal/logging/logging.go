@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Setup installs the default slog logger. With level "off" (or empty) log
+// records are discarded; otherwise they are written as JSON lines to a daily
+// file in dir, moving to a new file when the date changes. String values and
+// errors pass through redact when it is non-nil. Returns the path of today's
+// log file, or "" when logging is off. On error records are discarded.
+func Setup(dir, level string, redact func(string) string) (string, error) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if level == "" || level == "off" {
+		return "", nil
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return "", fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	out := &dailyFile{dir: dir, now: time.Now}
+	if err := out.open(out.now()); err != nil {
+		return "", err
+	}
+
+	if redact == nil {
+		redact = func(s string) string { return s }
+	}
+	opts := &slog.HandlerOptions{
+		Level: lvl,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Value.Kind() {
+			case slog.KindString:
+				return slog.String(a.Key, redact(a.Value.String()))
+			case slog.KindDuration:
+				// "1.2s" reads better than nanoseconds
+				return slog.String(a.Key, a.Value.Duration().Round(time.Millisecond).String())
+			case slog.KindAny:
+				if err, ok := a.Value.Any().(error); ok {
+					return slog.String(a.Key, redact(err.Error()))
+				}
+			}
+			return a
+		},
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(out, opts)).With("pid", os.Getpid()))
+	return out.path, nil
+}
+
+// dailyFile appends to web-ollama-<date>.log in dir, switching files at the
+// first write after midnight so long-running processes roll over too
+type dailyFile struct {
+	dir string
+	now func() time.Time
+
+	mu   sync.Mutex
+	date string
+	path string
+	f    *os.File
+}
+
+func (d *dailyFile) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now := d.now(); now.Format("2006-01-02") != d.date {
+		if err := d.open(now); err != nil {
+			return 0, err
+		}
+	}
+	return d.f.Write(p)
+}
+
+// open switches to the file for now's date, closing the previous one
+func (d *dailyFile) open(now time.Time) error {
+	date := now.Format("2006-01-02")
+	path := filepath.Join(d.dir, fmt.Sprintf("web-ollama-%s.log", date))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	if d.f != nil {
+		d.f.Close()
+	}
+	d.f, d.date, d.path = f, date, path
+	return nil
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDailyFileRollsOverAtMidnight(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 23, 59, 0, 0, time.Local)
+	out := &dailyFile{dir: dir, now: func() time.Time { return now }}
+
+	if _, err := out.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := out.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]string{
+		"web-ollama-2026-03-01.log": "before\n",
+		"web-ollama-2026-03-02.log": "after\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", file, data, err, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistogramIsCumulative(t *testing.T) {
+	h := NewHistogram("test_latency_seconds", "Test latency", []float64{0.1, 1, 10}, "stage")
+	for _, v := range []float64{0.05, 0.5, 0.7, 5, 50} {
+		h.Observe(v, "search")
+	}
+	var buf bytes.Buffer
+	h.write(&buf)
+
+	want := `# HELP test_latency_seconds Test latency
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{stage="search",le="0.1"} 1
+test_latency_seconds_bucket{stage="search",le="1"} 3
+test_latency_seconds_bucket{stage="search",le="10"} 4
+test_latency_seconds_bucket{stage="search",le="+Inf"} 5
+test_latency_seconds_sum{stage="search"} 56.25
+test_latency_seconds_count{stage="search"} 5
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	c := NewCounter("test_requests_total", "Test requests", "path")
+	c.Inc("/a\"b\\c\nd/é")
+	c.Add(2, "/plain")
+	var buf bytes.Buffer
+	c.write(&buf)
+
+	for _, line := range []string{
+		`test_requests_total{path="/a\"b\\c\nd/é"} 1`,
+		`test_requests_total{path="/plain"} 2`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing %s in\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestHandlerServesRegisteredMetrics(t *testing.T) {
+	Crawls.Inc("ok")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE web_ollama_crawls_total counter\n") {
+		t.Errorf("crawl counter missing from\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,190 @@
+package notes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note is a question and answer saved to a notes vault
+type Note struct {
+	Question string
+	Answer   string
+	Sources  []string
+	Model    string
+	Tags     []string
+	Time     time.Time
+}
+
+// maxTitleLength keeps note file names manageable, in characters
+const maxTitleLength = 80
+
+// Save writes the note as Markdown into dir and returns its path. Notes that
+// share a source with earlier notes in dir link to them, so the connection
+// shows up in Obsidian's backlinks.
+func Save(dir string, note Note) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create notes directory: %w", err)
+	}
+
+	title := Title(note.Question)
+	path := uniquePath(dir, title)
+	title = strings.TrimSuffix(filepath.Base(path), ".md")
+
+	related, err := relatedNotes(dir, note.Sources)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, []byte(render(title, note, related)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write note: %w", err)
+	}
+	return path, nil
+}
+
+var unsafeTitle = regexp.MustCompile(`[\\/:*?"<>|#^\[\]\n\r\t]+`)
+
+// Title turns a question into a file-name-safe note title
+func Title(question string) string {
+	title := strings.Join(strings.Fields(unsafeTitle.ReplaceAllString(question, " ")), " ")
+	title = strings.Trim(title, ". ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = strings.TrimSpace(string(runes[:maxTitleLength]))
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+	return title
+}
+
+// uniquePath returns dir/title.md, adding a counter if the file exists
+func uniquePath(dir, title string) string {
+	path := filepath.Join(dir, title+".md")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d).md", title, i))
+	}
+}
+
+// render produces the note with YAML front matter
+func render(title string, note Note, related []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("---\n")
+	sb.WriteString(fmt.Sprintf("title: %s\n", yamlString(title)))
+	sb.WriteString(fmt.Sprintf("date: %s\n", note.Time.Format("2006-01-02T15:04:05")))
+	if note.Model != "" {
+		sb.WriteString(fmt.Sprintf("model: %s\n", yamlString(note.Model)))
+	}
+	sb.WriteString("tags:\n")
+	for _, tag := range note.Tags {
+		sb.WriteString(fmt.Sprintf("  - %s\n", yamlString(tag)))
+	}
+	if len(note.Sources) > 0 {
+		sb.WriteString("sources:\n")
+		for _, url := range note.Sources {
+			sb.WriteString(fmt.Sprintf("  - %s\n", yamlString(url)))
+		}
+	}
+	sb.WriteString("---\n\n")
+
+	sb.WriteString(fmt.Sprintf("> %s\n\n", strings.ReplaceAll(strings.TrimSpace(note.Question), "\n", "\n> ")))
+	sb.WriteString(strings.TrimSpace(note.Answer))
+	sb.WriteString("\n")
+
+	if len(note.Sources) > 0 {
+		sb.WriteString("\n## Sources\n\n")
+		for _, url := range note.Sources {
+			sb.WriteString(fmt.Sprintf("- <%s>\n", url))
+		}
+	}
+
+	if len(related) > 0 {
+		sb.WriteString("\n## Related\n\n")
+		for _, r := range related {
+			sb.WriteString(fmt.Sprintf("- [[%s]]\n", r))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\nSaved on [[%s]]\n", note.Time.Format("2006-01-02")))
+	return sb.String()
+}
+
+// relatedNotes lists titles of notes in dir whose front matter cites any of sources
+func relatedNotes(dir string, sources []string) ([]string, error) {
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		wanted[s] = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+
+	var related []string
+	for _, path := range paths {
+		for _, src := range frontMatterSources(path) {
+			if wanted[src] {
+				related = append(related, strings.TrimSuffix(filepath.Base(path), ".md"))
+				break
+			}
+		}
+	}
+	sort.Strings(related)
+	return related, nil
+}
+
+// frontMatterSources reads the sources list from a note's front matter
+func frontMatterSources(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var sources []string
+	scanner := bufio.NewScanner(f)
+	inFrontMatter, inSources := false, false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "---" {
+			if inFrontMatter {
+				break
+			}
+			inFrontMatter = true
+			continue
+		}
+		if !inFrontMatter {
+			break
+		}
+		if strings.HasPrefix(line, "sources:") {
+			inSources = true
+			continue
+		}
+		if inSources {
+			item, ok := strings.CutPrefix(line, "  - ")
+			if !ok {
+				inSources = false
+				continue
+			}
+			sources = append(sources, strings.Trim(item, `"`))
+		}
+	}
+	return sources
+}
+
+// yamlString quotes a scalar for YAML front matter
+func yamlString(s string) string {
+	return fmt.Sprintf("%q", s)
+}
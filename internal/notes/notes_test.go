@@ -0,0 +1,95 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveWritesFrontMatterAndBody(t *testing.T) {
+	dir := t.TempDir()
+	when := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	path, err := Save(dir, Note{
+		Question: "What's new in Go 1.22?",
+		Answer:   "Loop variables are per-iteration.",
+		Sources:  []string{"https://go.dev/blog/go1.22"},
+		Model:    "llama3",
+		Tags:     []string{"web-ollama", "go"},
+		Time:     when,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "What's new in Go 1.22.md" {
+		t.Errorf("file name = %q", filepath.Base(path))
+	}
+
+	data, _ := os.ReadFile(path)
+	note := string(data)
+	for _, want := range []string{
+		"---\ntitle: \"What's new in Go 1.22\"\n",
+		"date: 2026-03-14T09:30:00\n",
+		"tags:\n  - \"web-ollama\"\n  - \"go\"\n",
+		"sources:\n  - \"https://go.dev/blog/go1.22\"\n",
+		"Loop variables are per-iteration.",
+		"Saved on [[2026-03-14]]",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note is missing %q:\n%s", want, note)
+		}
+	}
+}
+
+func TestSaveLinksNotesSharingSources(t *testing.T) {
+	dir := t.TempDir()
+	shared := "https://go.dev/blog/go1.22"
+
+	if _, err := Save(dir, Note{Question: "Go loop variables", Sources: []string{shared}, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Save(dir, Note{Question: "Unrelated", Sources: []string{"https://example.com"}, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Save(dir, Note{Question: "Go 1.22 range over int", Sources: []string{shared}, Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "## Related\n\n- [[Go loop variables]]\n") {
+		t.Errorf("expected a link to the note sharing a source:\n%s", data)
+	}
+	if strings.Contains(string(data), "[[Unrelated]]") {
+		t.Error("should not link notes without shared sources")
+	}
+}
+
+func TestSaveAvoidsOverwriting(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := Save(dir, Note{Question: "Same question", Time: time.Now()})
+	second, err := Save(dir, Note{Question: "Same question", Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || filepath.Base(second) != "Same question (2).md" {
+		t.Errorf("second note saved to %q", second)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := map[string]string{
+		"What is a [[link]]?":       "What is a link",
+		"path/to:file":              "path to file",
+		"   ":                       "Untitled",
+		strings.Repeat("word ", 40): strings.TrimSpace(strings.Repeat("word ", 40)[:maxTitleLength]),
+		strings.Repeat("日本語", 40):   string([]rune(strings.Repeat("日本語", 40))[:maxTitleLength]),
+	}
+	for in, want := range tests {
+		if got := Title(in); got != want {
+			t.Errorf("Title(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ollama
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// headerTransport adds fixed headers to the requests it forwards, for Ollama
+// servers behind an authenticating proxy. Each server has its own headers,
+// so credentials for one are never sent to another.
+type headerTransport struct {
+	byHost map[string]http.Header // keyed by hostKey
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := t.byHost[hostKey(req.URL)]
+	if len(headers) == 0 {
+		return t.next.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	for name, values := range headers {
+		if r.Header.Get(name) == "" {
+			r.Header[name] = values
+		}
+	}
+	return t.next.RoundTrip(r)
+}
+
+// host returns the headers for the server at rawURL, creating them
+func (t *headerTransport) host(rawURL string) http.Header {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return make(http.Header) // dropped: SetHosts rejects the URL
+	}
+	key := hostKey(u)
+	if t.byHost[key] == nil {
+		t.byHost[key] = make(http.Header)
+	}
+	return t.byHost[key]
+}
+
+// hostKey identifies a server by scheme and host
+func hostKey(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// SetAuth sends credential as the Authorization header of every request to
+// the client's own server; see SetHostAuth
+func (c *Client) SetAuth(credential string) {
+	c.SetHostAuth(c.baseURL, credential)
+}
+
+// SetHostAuth sends credential as the Authorization header of requests to
+// the server at rawURL: "user:password" as basic auth, a full value such as
+// "Basic dXNlcjpwdw==" as it is, and anything else as a bearer token. ""
+// sends none.
+func (c *Client) SetHostAuth(rawURL, credential string) {
+	headers := c.auth.host(rawURL)
+	if credential == "" {
+		headers.Del("Authorization")
+		return
+	}
+	headers.Set("Authorization", authorization(credential))
+}
+
+// SetHeaders sends extra headers with every request to the client's own
+// server, such as CF-Access-Client-Id and CF-Access-Client-Secret for
+// Cloudflare Access
+func (c *Client) SetHeaders(headers map[string]string) {
+	c.SetHostHeaders(c.baseURL, headers)
+}
+
+// SetHostHeaders sends extra headers with every request to the server at
+// rawURL
+func (c *Client) SetHostHeaders(rawURL string, headers map[string]string) {
+	h := c.auth.host(rawURL)
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+}
+
+// stripUserinfo removes "user:password@" from rawURL, returning the URL
+// without it and the credential, or "" if it had none
+func stripUserinfo(rawURL string) (string, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL, ""
+	}
+	password, _ := u.User.Password()
+	credential := u.User.Username() + ":" + password
+	u.User = nil
+	return u.String(), credential
+}
+
+// authorization returns the Authorization header value for credential
+func authorization(credential string) string {
+	if strings.Contains(credential, " ") {
+		return credential
+	}
+	if strings.Contains(credential, ":") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credential))
+	}
+	return "Bearer " + credential
+}
@@ -0,0 +1,66 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAuthorization(t *testing.T) {
+	for credential, want := range map[string]string{
+		"sk-123":             "Bearer sk-123",
+		"alice:s3cret":       "Basic YWxpY2U6czNjcmV0",
+		"Basic YWxpY2U6czNj": "Basic YWxpY2U6czNj",
+	} {
+		if got := authorization(credential); got != want {
+			t.Errorf("authorization(%q) = %q, want %q", credential, got, want)
+		}
+	}
+}
+
+func TestHeadersReachOnlyTheirHost(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]http.Header{}
+	server := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen[name+r.URL.Path] = r.Header.Clone()
+			mu.Unlock()
+			w.Write([]byte(`{"message":{"content":"ok"},"done":true,"models":[]}`))
+		}))
+	}
+	primary, fallback := server("primary"), server("fallback")
+	defer primary.Close()
+	defer fallback.Close()
+
+	client := NewClient(primary.URL, 0)
+	client.SetAuth("sk-123")
+	client.SetHeaders(map[string]string{"CF-Access-Client-Id": "id"})
+	withUser := strings.Replace(fallback.URL, "http://", "http://alice:s3cret@", 1)
+	if err := client.SetHosts([]string{primary.URL, withUser}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Round robin: each endpoint is asked of both servers
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListModels(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat(context.Background(), ChatRequest{Model: "m"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{"/api/tags", "/api/chat"} {
+		if h := seen["primary"+path]; h.Get("Authorization") != "Bearer sk-123" || h.Get("CF-Access-Client-Id") != "id" {
+			t.Errorf("primary %s headers = %v", path, h)
+		}
+		if h := seen["fallback"+path]; h.Get("Authorization") != "Basic YWxpY2U6czNjcmV0" || h.Get("CF-Access-Client-Id") != "" {
+			t.Errorf("fallback %s headers = %v", path, h)
+		}
+	}
+}
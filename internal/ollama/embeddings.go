@@ -0,0 +1,125 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EmbedBatchSize is the number of texts Embeddings sends per request
+const EmbedBatchSize = 32
+
+// errNoEmbedEndpoint means the server predates /api/embed
+var errNoEmbedEndpoint = errors.New("no /api/embed endpoint")
+
+// Embeddings returns an embedding vector for each text, sending them in
+// batches of EmbedBatchSize so large documents don't produce one huge
+// request
+func (c *Client) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += EmbedBatchSize {
+		end := min(start+EmbedBatchSize, len(texts))
+		batch, err := c.Embed(ctx, model, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed texts %d-%d of %d: %w", start+1, end, len(texts), err)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// Embed returns an embedding vector for each input in one request to
+// /api/embed. Servers without it are asked through the older
+// /api/embeddings, one input at a time.
+func (c *Client) Embed(ctx context.Context, model string, inputs []string) (embeddings [][]float32, err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			slog.Warn("ollama embed failed", "model", model, "inputs", len(inputs), "duration", time.Since(start), "error", err)
+			return
+		}
+		slog.Debug("ollama embed", "model", model, "inputs", len(inputs), "duration", time.Since(start))
+	}()
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	if !c.legacyEmbed.Load() {
+		var resp EmbedResponse
+		err := c.postJSON(ctx, "/api/embed", model, EmbedRequest{Model: model, Input: inputs}, &resp)
+		if !errors.Is(err, errNoEmbedEndpoint) {
+			if err != nil {
+				return nil, err
+			}
+			if len(resp.Embeddings) != len(inputs) {
+				return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Embeddings))
+			}
+			return resp.Embeddings, checkVectors(resp.Embeddings)
+		}
+		slog.Info("ollama has no /api/embed, using /api/embeddings")
+		c.legacyEmbed.Store(true)
+	}
+
+	embeddings = make([][]float32, len(inputs))
+	for i, input := range inputs {
+		var resp LegacyEmbedResponse
+		if err := c.postJSON(ctx, "/api/embeddings", model, LegacyEmbedRequest{Model: model, Prompt: input}, &resp); err != nil {
+			return nil, err
+		}
+		embeddings[i] = resp.Embedding
+	}
+	return embeddings, checkVectors(embeddings)
+}
+
+// postJSON sends body to path and decodes the response into out
+func (c *Client) postJSON(ctx context.Context, path, model string, body, out interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return c.requestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// A missing route is plain text; a missing model is a JSON error
+		body, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(body), "page not found") {
+			return errNoEmbedEndpoint
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if resp.StatusCode != 200 {
+		return statusError(resp, model)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// checkVectors rejects empty embeddings, which models without embedding
+// support return
+func checkVectors(vectors [][]float32) error {
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return errors.New("the model returned an empty embedding; is it an embedding model?")
+		}
+	}
+	return nil
+}
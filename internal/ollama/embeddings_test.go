@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"web-ollama/internal/errs"
+)
+
+func TestEmbeddingsBatches(t *testing.T) {
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		batches = append(batches, len(req.Input))
+		resp := EmbedResponse{Model: req.Model}
+		for range req.Input {
+			resp.Embeddings = append(resp.Embeddings, []float32{1, 0})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	texts := make([]string, EmbedBatchSize+5)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	vectors, err := NewClient(srv.URL, 0).Embeddings(context.Background(), "nomic-embed-text", texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(texts) || len(batches) != 2 || batches[0] != EmbedBatchSize || batches[1] != 5 {
+		t.Errorf("got %d vectors in batches %v", len(vectors), batches)
+	}
+}
+
+func TestEmbeddingsFallsBackToLegacyEndpoint(t *testing.T) {
+	var legacy int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		var req LegacyEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}`))
+			return
+		}
+		legacy++
+		json.NewEncoder(w).Encode(LegacyEmbedResponse{Embedding: []float32{float32(len(req.Prompt)), 1}})
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+
+	vectors, err := client.Embeddings(context.Background(), "nomic-embed-text", []string{"a", "bcd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy != 2 || vectors[0][0] != 1 || vectors[1][0] != 3 {
+		t.Errorf("got %v from %d legacy requests", vectors, legacy)
+	}
+
+	_, err = client.Embeddings(context.Background(), "missing", []string{"a"})
+	if !errs.Is(err, errs.ModelMissing) {
+		t.Errorf("missing model: got %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package ollama
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,19 +8,28 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // StreamCallbacks defines callbacks for different parts of the response
 type StreamCallbacks struct {
-	OnThinking func(string) // Called for thinking tokens
-	OnAnswer   func(string) // Called for answer tokens
-	OnDone     func()       // Called when thinking transitions to answer
+	OnThinking func(string)   // Called for thinking tokens
+	OnAnswer   func(string)   // Called for answer tokens
+	OnDone     func()         // Called when thinking transitions to answer
+	OnToolCall func(ToolCall) // Called for each tool the model asks to run
+	OnStats    func(Stats)    // Called with the generation counts at the end
+	OnProgress func(Stats)    // Called as tokens arrive, with counts timed from their arrival
 }
 
 // ChatWithCallbacks sends a chat request with separate callbacks for thinking/answer
 func (c *Client) ChatWithCallbacks(ctx context.Context, req ChatRequest, callbacks StreamCallbacks) (thinking string, answer string, err error) {
+	defer logRequest("chat_callbacks", req.Model, time.Now(), &answer, &err)
+
 	// Force streaming
 	req.Stream = true
+	if req.KeepAlive == "" {
+		req.KeepAlive = c.keepAlive
+	}
 
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(req)
@@ -29,6 +37,42 @@ func (c *Client) ChatWithCallbacks(ctx context.Context, req ChatRequest, callbac
 		return "", "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	err = c.withRetry(ctx, "chat_callbacks", req.Model, func() (bool, error) {
+		watched, streamed := watchStream(callbacks)
+		var err error
+		thinking, answer, err = c.chatWithCallbacksOnce(ctx, jsonData, req.Model, watched)
+		return *streamed, err
+	})
+	return thinking, answer, err
+}
+
+// watchStream wraps callbacks so that the returned flag is set once any of
+// them has been called
+func watchStream(callbacks StreamCallbacks) (StreamCallbacks, *bool) {
+	streamed := new(bool)
+	text := func(f func(string)) func(string) {
+		if f == nil {
+			return nil
+		}
+		return func(s string) {
+			*streamed = true
+			f(s)
+		}
+	}
+	watched := callbacks
+	watched.OnThinking = text(callbacks.OnThinking)
+	watched.OnAnswer = text(callbacks.OnAnswer)
+	if callbacks.OnToolCall != nil {
+		watched.OnToolCall = func(call ToolCall) {
+			*streamed = true
+			callbacks.OnToolCall(call)
+		}
+	}
+	return watched, streamed
+}
+
+// chatWithCallbacksOnce posts a streaming chat request once
+func (c *Client) chatWithCallbacksOnce(ctx context.Context, jsonData []byte, model string, callbacks StreamCallbacks) (thinking string, answer string, err error) {
 	// Create HTTP request
 	url := fmt.Sprintf("%s/api/chat", c.baseURL)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
@@ -42,14 +86,13 @@ func (c *Client) ChatWithCallbacks(ctx context.Context, req ChatRequest, callbac
 	// Execute request with streaming client (no timeout)
 	resp, err := c.streamingClient.Do(httpReq)
 	if err != nil {
-		return "", "", fmt.Errorf("request failed: %w", err)
+		return "", "", c.requestError(err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("Ollama returned status %d: %s", resp.StatusCode, string(body))
+		return "", "", statusError(resp, model)
 	}
 
 	// Stream response with thinking detection
@@ -63,22 +106,24 @@ func (c *Client) ChatWithCallbacks(ctx context.Context, req ChatRequest, callbac
 
 // streamWithThinking reads response and separates thinking from answer
 func (c *Client) streamWithThinking(body io.Reader, callbacks StreamCallbacks) (thinking string, answer string, err error) {
-	scanner := bufio.NewScanner(body)
+	lines := newLineReader(body, c.maxLineBytes)
 	var thinkingBuf strings.Builder
 	var answerBuf strings.Builder
 	wasThinking := false
 	isFirstAnswer := true
+	var arrived arrivals
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+	for {
+		chunk, err := lines.chunk()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return thinkingBuf.String(), answerBuf.String(), err
 		}
 
-		// Parse JSON chunk
-		var chunk ChatResponse
-		if err := json.Unmarshal(line, &chunk); err != nil {
-			continue
+		if callbacks.OnProgress != nil && (chunk.Message.Thinking != "" || chunk.Message.Content != "") {
+			callbacks.OnProgress(arrived.add(time.Now()))
 		}
 
 		// Check for thinking field (deepseek-r1 style)
@@ -106,15 +151,27 @@ func (c *Client) streamWithThinking(body io.Reader, callbacks StreamCallbacks) (
 			}
 		}
 
+		if callbacks.OnToolCall != nil {
+			for _, call := range chunk.Message.ToolCalls {
+				callbacks.OnToolCall(call)
+			}
+		}
+
 		if chunk.Done {
+			if callbacks.OnStats != nil {
+				callbacks.OnStats(Stats{
+					PromptTokens:   chunk.PromptEvalCount,
+					AnswerTokens:   chunk.EvalCount,
+					Duration:       chunk.EvalDuration,
+					PromptDuration: chunk.PromptEvalDuration,
+					LoadDuration:   chunk.LoadDuration,
+					TotalDuration:  chunk.TotalDuration,
+				})
+			}
 			break
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return thinkingBuf.String(), answerBuf.String(), fmt.Errorf("scanner error: %w", err)
-	}
-
 	return thinkingBuf.String(), answerBuf.String(), nil
 }
 
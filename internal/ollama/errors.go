@@ -0,0 +1,44 @@
+package ollama
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"web-ollama/internal/errs"
+)
+
+// requestError classifies a request that got no response from Ollama
+func (c *Client) requestError(err error) error {
+	if errs.Unreachable(err) {
+		return errs.New(errs.OllamaUnreachable, c.baseURL, err)
+	}
+	return fmt.Errorf("request failed: %w", err)
+}
+
+// StatusError is an error response from Ollama
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Ollama returned status %d: %s", e.Code, e.Body)
+}
+
+// statusError classifies an error response from Ollama for model
+func statusError(resp *http.Response, model string) error {
+	body, _ := io.ReadAll(resp.Body)
+	err := &StatusError{Code: resp.StatusCode, Body: string(body)}
+
+	msg := strings.ToLower(string(body))
+	switch {
+	case resp.StatusCode == http.StatusNotFound && strings.Contains(msg, "not found"):
+		return errs.New(errs.ModelMissing, model, err)
+	case strings.Contains(msg, "context length") || strings.Contains(msg, "context window") ||
+		strings.Contains(msg, "exceeds the context"):
+		return errs.New(errs.ContextOverflow, model, err)
+	}
+	return err
+}
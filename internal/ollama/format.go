@@ -0,0 +1,55 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FormatJSON constrains a reply to any JSON value
+var FormatJSON = json.RawMessage(`"json"`)
+
+// jsonFormat is the format field for schema: the schema itself, or plain
+// JSON without one
+func jsonFormat(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return FormatJSON
+	}
+	return schema
+}
+
+// ParseFormat reads an answer format as given to --format: "" for free
+// text, "json" for any JSON, or "json-schema=<file>" for JSON matching the
+// schema in file. It returns the value for ChatRequest.Format.
+func ParseFormat(spec string) (json.RawMessage, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case spec == "json":
+		return FormatJSON, nil
+	case strings.HasPrefix(spec, "json-schema="):
+		path := strings.TrimPrefix(spec, "json-schema=")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON schema: %w", err)
+		}
+		return Schema(data)
+	}
+	return nil, fmt.Errorf("unknown format %q: use json or json-schema=<file>", spec)
+}
+
+// Schema checks that data is a JSON object, as Ollama requires of a schema,
+// and returns it compacted
+func Schema(data []byte) (json.RawMessage, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+package ollama
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "answer.json")
+	os.WriteFile(schema, []byte("{\n  \"type\": \"object\",\n  \"properties\": {\"summary\": {\"type\": \"string\"}}\n}\n"), 0644)
+	notObject := filepath.Join(dir, "list.json")
+	os.WriteFile(notObject, []byte(`["summary"]`), 0644)
+
+	cases := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"json", `"json"`, false},
+		{"json-schema=" + schema, `{"type":"object","properties":{"summary":{"type":"string"}}}`, false},
+		{"json-schema=" + notObject, "", true},
+		{"json-schema=" + filepath.Join(dir, "missing.json"), "", true},
+		{"yaml", "", true},
+	}
+	for _, c := range cases {
+		got, err := ParseFormat(c.spec)
+		if (err != nil) != c.wantErr || string(got) != c.want {
+			t.Errorf("ParseFormat(%q) = %s, %v; want %s, error %v", c.spec, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestChatRequestFormat(t *testing.T) {
+	for _, c := range []struct {
+		format json.RawMessage
+		want   string
+	}{
+		{nil, `{"model":"m","messages":null,"stream":false}`},
+		{jsonFormat(nil), `{"model":"m","messages":null,"stream":false,"format":"json"}`},
+		{jsonFormat(json.RawMessage(`{"type":"object"}`)), `{"model":"m","messages":null,"stream":false,"format":{"type":"object"}}`},
+	} {
+		data, err := json.Marshal(ChatRequest{Model: "m", Format: c.format})
+		if err != nil || string(data) != c.want {
+			t.Errorf("request = %s, %v; want %s", data, err, c.want)
+		}
+	}
+}
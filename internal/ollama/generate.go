@@ -0,0 +1,106 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GenerateRequest is a raw prompt completion request to /api/generate
+type GenerateRequest struct {
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	System    string                 `json:"system,omitempty"` // replaces the model's system prompt
+	Raw       bool                   `json:"raw,omitempty"`    // send the prompt without the model's template
+	Stream    bool                   `json:"stream"`
+	Format    string                 `json:"format,omitempty"` // "json" constrains output to JSON
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive string                 `json:"keep_alive,omitempty"` // as in ChatRequest
+}
+
+// GenerateResponse is one chunk of a completion, or all of it when not
+// streaming
+type GenerateResponse struct {
+	Model    string `json:"model"`
+	Response string `json:"response"`
+	Thinking string `json:"thinking,omitempty"`
+	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Generate completes a prompt through /api/generate, for models without a
+// chat template and one-off calls that need no conversation. With onChunk
+// set the completion is streamed to it; otherwise it arrives in one
+// response. Either way the full completion is returned.
+func (c *Client) Generate(ctx context.Context, req GenerateRequest, onChunk func(string)) (response string, err error) {
+	defer logRequest("generate", req.Model, time.Now(), &response, &err)
+
+	req.Stream = onChunk != nil
+	if req.KeepAlive == "" {
+		req.KeepAlive = c.keepAlive
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/generate", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := c.httpClient
+	if req.Stream {
+		client = c.streamingClient // context handles cancellation
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", c.requestError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", statusError(resp, req.Model)
+	}
+
+	if !req.Stream {
+		var result GenerateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+		if result.Error != "" {
+			return "", fmt.Errorf("ollama failed: %s", result.Error)
+		}
+		return result.Response, nil
+	}
+
+	lines := newLineReader(resp.Body, c.maxLineBytes)
+	var full strings.Builder
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			return full.String(), nil
+		}
+		if err != nil {
+			return full.String(), fmt.Errorf("failed to stream response: %w", err)
+		}
+		var chunk GenerateResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return full.String(), fmt.Errorf("malformed stream line %d: %w", lines.n, err)
+		}
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("ollama failed mid-stream: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			onChunk(chunk.Response)
+		}
+		if chunk.Done {
+			return full.String(), nil
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/api/generate" || req.Prompt != "The capital of France is" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		enc := json.NewEncoder(w)
+		if !req.Stream {
+			enc.Encode(GenerateResponse{Model: req.Model, Response: " Paris.", Done: true})
+			return
+		}
+		enc.Encode(GenerateResponse{Model: req.Model, Response: " Par"})
+		enc.Encode(GenerateResponse{Model: req.Model, Response: "is."})
+		enc.Encode(GenerateResponse{Model: req.Model, Done: true})
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+	req := GenerateRequest{Model: "llama3", Prompt: "The capital of France is", Raw: true}
+
+	got, err := client.Generate(context.Background(), req, nil)
+	if err != nil || got != " Paris." {
+		t.Fatalf("Generate = %q, %v", got, err)
+	}
+
+	var chunks []string
+	got, err = client.Generate(context.Background(), req, func(s string) { chunks = append(chunks, s) })
+	if err != nil || got != " Paris." || strings.Join(chunks, "|") != " Par|is." {
+		t.Fatalf("streamed Generate = %q in %q, %v", got, chunks, err)
+	}
+}
+
+func TestGenerateReportsMidStreamErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"Par"}` + "\n" + `{"error":"out of memory"}` + "\n"))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(srv.URL, 0).Generate(context.Background(), GenerateRequest{Model: "llama3", Prompt: "x"}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "out of memory") || got != "Par" {
+		t.Errorf("Generate = %q, %v", got, err)
+	}
+}
+
+func TestKeepAliveDefaultsToClientSetting(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KeepAlive *string `json:"keep_alive"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.KeepAlive == nil {
+			got = append(got, "unset")
+		} else {
+			got = append(got, *req.KeepAlive)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": "ok"}, "response": "ok", "done": true})
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+	ctx := context.Background()
+
+	client.ChatWithCallbacks(ctx, ChatRequest{Model: "llama3"}, StreamCallbacks{})
+	client.SetKeepAlive("30m")
+	client.ChatWithCallbacks(ctx, ChatRequest{Model: "llama3"}, StreamCallbacks{})
+	client.ChatWithCallbacks(ctx, ChatRequest{Model: "llama3", KeepAlive: "0"}, StreamCallbacks{})
+	client.Generate(ctx, GenerateRequest{Model: "llama3", Prompt: "hi"}, nil)
+
+	if strings.Join(got, ",") != "unset,30m,0,30m" {
+		t.Errorf("keep_alive sent = %v", got)
+	}
+}
+
+func TestWarmupSendsAnEmptyGenerate(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := req["prompt"]; r.URL.Path != "/api/generate" || ok || req["model"] != "llama3" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		got = append(got, fmt.Sprint(req["keep_alive"]))
+		w.Write([]byte(`{"model":"llama3","response":"","done":true}`))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+
+	if err := client.Warmup(context.Background(), "llama3"); err != nil {
+		t.Fatal(err)
+	}
+	client.SetKeepAlive("30m")
+	if err := client.Warmup(context.Background(), "llama3"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "<nil>,30m" {
+		t.Errorf("keep_alive sent = %v", got)
+	}
+}
+
+func TestModelsAndRunning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3.1:8b","size":4920753328,"details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_K_M"}}]}`))
+		case "/api/ps":
+			w.Write([]byte(`{"models":[{"name":"llama3.1:8b","size":6654289920,"size_vram":3327144960,"expires_at":"2024-06-04T14:38:31.83753-07:00"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+
+	models, err := client.Models(context.Background())
+	if err != nil || len(models) != 1 {
+		t.Fatalf("Models = %v, %v", models, err)
+	}
+	if m := models[0]; m.Name != "llama3.1:8b" || m.Size != 4920753328 || m.Family != "llama" || m.QuantizationLevel != "Q4_K_M" {
+		t.Errorf("model = %+v", m)
+	}
+
+	running, err := client.Running(context.Background())
+	if err != nil || len(running) != 1 || running[0].SizeVRAM != running[0].Size/2 || running[0].ExpiresAt.IsZero() {
+		t.Errorf("Running = %+v, %v", running, err)
+	}
+}
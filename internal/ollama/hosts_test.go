@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// namedServer answers every chat request with its name
+func namedServer(t *testing.T, name string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"` + name + `"},"done":true,"models":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// deadURL returns the address of a server that has stopped
+func deadURL() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestHostsFailOver(t *testing.T) {
+	backup := namedServer(t, "backup")
+	client := NewClient(deadURL(), 0)
+	if err := client.SetHosts([]string{client.baseURL, backup.URL}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := client.ChatSync(context.Background(), "llama3", []Message{{Role: "user", Content: "hi"}})
+		if err != nil || got != "backup" {
+			t.Fatalf("ChatSync = %q, %v, want the backup's answer", got, err)
+		}
+	}
+	if order := client.hosts.order(); order[0] != 1 {
+		t.Errorf("order = %v, want the dead host last", order)
+	}
+
+	statuses := client.CheckHosts(context.Background())
+	if len(statuses) != 2 || statuses[0].Err == nil || statuses[1].Err != nil {
+		t.Errorf("CheckHosts = %+v, want the first down and the second up", statuses)
+	}
+}
+
+func TestHostsRoundRobin(t *testing.T) {
+	a, b := namedServer(t, "a"), namedServer(t, "b")
+	client := NewClient(a.URL, 0)
+	client.SetHosts([]string{a.URL, b.URL}, true)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		answer, err := client.ChatSync(context.Background(), "llama3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, answer)
+	}
+	if got[0] == got[1] || got[0] != got[2] || got[1] != got[3] {
+		t.Errorf("answers = %v, want the hosts in turn", got)
+	}
+}
+
+func TestHostsAllDown(t *testing.T) {
+	client := NewClient(deadURL(), 0)
+	client.SetHosts([]string{client.baseURL, deadURL()}, false)
+	if err := client.HealthCheck(); err == nil {
+		t.Error("HealthCheck succeeded with every host down")
+	}
+}
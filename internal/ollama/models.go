@@ -0,0 +1,79 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ModelDetails describes an installed model, as listed by /api/tags
+type ModelDetails struct {
+	Name              string `json:"name"`
+	Size              int64  `json:"size"` // bytes on disk, roughly the memory its weights take
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`     // e.g. "8.0B"
+	QuantizationLevel string `json:"quantization_level"` // e.g. "Q4_K_M"
+}
+
+// RunningModel is a model loaded in memory, as listed by /api/ps
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`      // bytes in memory, context included
+	SizeVRAM  int64     `json:"size_vram"` // the part of Size on the GPU
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Models lists the installed models with their size and quantization
+func (c *Client) Models(ctx context.Context) ([]ModelDetails, error) {
+	var result struct {
+		Models []struct {
+			Name    string       `json:"name"`
+			Size    int64        `json:"size"`
+			Details ModelDetails `json:"details"`
+		} `json:"models"`
+	}
+	if err := c.getJSON(ctx, "/api/tags", &result); err != nil {
+		return nil, err
+	}
+	models := make([]ModelDetails, len(result.Models))
+	for i, m := range result.Models {
+		models[i] = m.Details
+		models[i].Name = m.Name
+		models[i].Size = m.Size
+	}
+	return models, nil
+}
+
+// Running lists the models Ollama has loaded and how much of each sits in
+// GPU memory
+func (c *Client) Running(ctx context.Context) ([]RunningModel, error) {
+	var result struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := c.getJSON(ctx, "/api/ps", &result); err != nil {
+		return nil, err
+	}
+	return result.Models, nil
+}
+
+// getJSON GETs path and decodes the response into out
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return c.requestError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Ollama returned status %d for %s", resp.StatusCode, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// PullProgress is one update of a model download. Total and Completed are
+// set while a layer is downloading, and Digest names the layer.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PullModel downloads a model through /api/pull, passing each progress
+// update to onProgress, which may be nil. It returns once the model is
+// ready or the download fails; cancelling ctx stops it.
+func (c *Client) PullModel(ctx context.Context, model string, onProgress func(PullProgress)) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			slog.Warn("ollama pull failed", "model", model, "duration", time.Since(start), "error", err)
+			return
+		}
+		slog.Info("ollama pull", "model", model, "duration", time.Since(start))
+	}()
+
+	jsonData, err := json.Marshal(map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/pull", bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Downloads take minutes, so use the client without a timeout
+	resp, err := c.streamingClient.Do(httpReq)
+	if err != nil {
+		return c.requestError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return statusError(resp, model)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress PullProgress
+		if err := decoder.Decode(&progress); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("pull of %s ended before it finished", model)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", model, progress.Error)
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+		if progress.Status == "success" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPullModelReportsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "broken" {
+			w.Write([]byte(`{"status":"pulling manifest"}` + "\n" + `{"error":"pull model manifest: file does not exist"}` + "\n"))
+			return
+		}
+		w.Write([]byte(strings.Join([]string{
+			`{"status":"pulling manifest"}`,
+			`{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":1000,"completed":400}`,
+			`{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":1000,"completed":1000}`,
+			`{"status":"verifying sha256 digest"}`,
+			`{"status":"success"}`,
+		}, "\n") + "\n"))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+
+	var updates []PullProgress
+	if err := client.PullModel(context.Background(), "qwen2.5", func(p PullProgress) { updates = append(updates, p) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 5 || updates[1].Completed != 400 || updates[1].Total != 1000 {
+		t.Errorf("updates = %+v", updates)
+	}
+
+	err := client.PullModel(context.Background(), "broken", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("broken pull: got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"time"
+
+	"web-ollama/internal/errs"
+)
+
+// maxRetryDelay caps the wait between attempts
+const maxRetryDelay = 30 * time.Second
+
+// SetRetry makes chat requests try up to attempts times when Ollama fails
+// transiently, waiting about delay before the second attempt and twice as
+// long before each one after. attempts <= 1 disables retries.
+func (c *Client) SetRetry(attempts int, delay time.Duration) {
+	c.retryAttempts = attempts
+	c.retryDelay = delay
+}
+
+// withRetry runs attempt until it succeeds, fails for good or has been
+// tried retryAttempts times. attempt reports whether it had already passed
+// output on, after which trying again would repeat it.
+func (c *Client) withRetry(ctx context.Context, op, model string, attempt func() (streamed bool, err error)) error {
+	for n := 1; ; n++ {
+		streamed, err := attempt()
+		if err == nil || streamed || n >= c.retryAttempts || !Transient(err) || ctx.Err() != nil {
+			return err
+		}
+		delay := backoff(c.retryDelay, n)
+		slog.Warn("ollama request failed, retrying", "op", op, "model", model, "attempt", n, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the wait after failed attempt n: delay doubled for each
+// earlier failure, capped, with up to half of it replaced by jitter so
+// that clients retrying together spread out
+func backoff(delay time.Duration, n int) time.Duration {
+	d := delay << (n - 1)
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Transient reports whether err is a failure that may pass on its own: a
+// refused or reset connection, a timeout, a connection cut mid-response or
+// a 5xx status other than a context overflow
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errs.Is(err, errs.ContextOverflow) || errs.Is(err, errs.ModelMissing) {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code >= 500
+	}
+	var netErr net.Error
+	return errs.Unreachable(err) || (errors.As(err, &netErr) && netErr.Timeout()) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF)
+}
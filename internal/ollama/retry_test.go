@@ -0,0 +1,115 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-ollama/internal/errs"
+)
+
+// flakyServer fails the first failures chat requests with status, then
+// answers "ok"
+func flakyServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			http.Error(w, body, status)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestChatRetriesServerErrors(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, "busy")
+	client := NewClient(srv.URL, 0)
+	client.SetRetry(3, time.Millisecond)
+
+	got, err := client.ChatSync(context.Background(), "llama3", []Message{{Role: "user", Content: "hi"}})
+	if err != nil || got != "ok" || calls.Load() != 3 {
+		t.Errorf("ChatSync = %q, %v after %d calls, want ok after 3", got, err, calls.Load())
+	}
+
+	calls.Store(0)
+	var chunks []string
+	_, _, err = client.ChatWithCallbacks(context.Background(), ChatRequest{Model: "llama3"}, StreamCallbacks{OnAnswer: func(s string) { chunks = append(chunks, s) }})
+	if err != nil || len(chunks) != 1 || chunks[0] != "ok" {
+		t.Errorf("ChatWithCallbacks = %v, %v after %d calls", chunks, err, calls.Load())
+	}
+}
+
+func TestChatGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := flakyServer(t, 5, http.StatusInternalServerError, "crashed")
+	client := NewClient(srv.URL, 0)
+	client.SetRetry(2, time.Millisecond)
+
+	if _, err := client.Chat(context.Background(), ChatRequest{Model: "llama3"}, nil); err == nil || calls.Load() != 2 {
+		t.Errorf("Chat = %v after %d calls, want an error after 2", err, calls.Load())
+	}
+}
+
+func TestChatDoesNotRetryPermanentErrors(t *testing.T) {
+	for _, c := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusBadRequest, "invalid request"},
+		{http.StatusInternalServerError, "input length exceeds the context length"},
+		{http.StatusNotFound, `model "nope" not found`},
+	} {
+		srv, calls := flakyServer(t, 5, c.status, c.body)
+		client := NewClient(srv.URL, 0)
+		client.SetRetry(3, time.Millisecond)
+		if _, err := client.ChatSync(context.Background(), "nope", nil); err == nil || calls.Load() != 1 {
+			t.Errorf("%d %s: ChatSync = %v after %d calls, want one call", c.status, c.body, err, calls.Load())
+		}
+	}
+}
+
+func TestChatDoesNotRetryAfterStreaming(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: "assistant", Content: "Par"}})
+		w.Write([]byte("{not json\n"))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, 0)
+	client.SetRetry(3, time.Millisecond)
+
+	var got string
+	_, err := client.Chat(context.Background(), ChatRequest{Model: "llama3"}, func(s string) { got += s })
+	if err == nil || got != "Par" || calls.Load() != 1 {
+		t.Errorf("Chat streamed %q, %v after %d calls, want one call", got, err, calls.Load())
+	}
+}
+
+func TestTransient(t *testing.T) {
+	if !Transient(&StatusError{Code: 502}) || Transient(&StatusError{Code: 400}) {
+		t.Error("only 5xx statuses are transient")
+	}
+	if Transient(errs.New(errs.ContextOverflow, "m", &StatusError{Code: 500})) {
+		t.Error("a context overflow is not transient")
+	}
+	if Transient(context.Canceled) {
+		t.Error("a cancelled request is not transient")
+	}
+}
+
+func TestBackoffDoublesWithJitter(t *testing.T) {
+	for n, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if d := backoff(time.Second, n+1); d < want/2 || d > want {
+			t.Errorf("backoff(1s, %d) = %s, want between %s and %s", n+1, d, want/2, want)
+		}
+	}
+	if d := backoff(time.Second, 20); d > maxRetryDelay {
+		t.Errorf("backoff(1s, 20) = %s, above the cap", d)
+	}
+}
@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ModelInfo is what Ollama's /api/show says about an installed model
+type ModelInfo struct {
+	ContextLength     int               // context window the model was trained with; 0 if unknown
+	Family            string            // e.g. "llama"
+	ParameterSize     string            // e.g. "8.0B"
+	QuantizationLevel string            // e.g. "Q4_K_M"
+	Parameters        map[string]string // Modelfile defaults such as num_ctx and temperature
+	Capabilities      []string          // e.g. "completion", "tools", "vision"; nil before Ollama 0.6.4
+}
+
+// ShowModel returns a model's metadata from /api/show
+func (c *Client) ShowModel(ctx context.Context, modelName string) (*ModelInfo, error) {
+	result, err := c.show(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+	info := &ModelInfo{
+		Family:            result.Details.Family,
+		ParameterSize:     result.Details.ParameterSize,
+		QuantizationLevel: result.Details.QuantizationLevel,
+		Parameters:        parseParameters(result.Parameters),
+		Capabilities:      result.Capabilities,
+	}
+	// The key is prefixed with the architecture, e.g. "llama.context_length"
+	for key, value := range result.ModelInfo {
+		if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			info.ContextLength = int(n)
+		}
+	}
+	return info, nil
+}
+
+// ContextLength returns the context window model was trained with, as
+// reported by Ollama's /api/show, or 0 when the model does not say. Ollama
+// silently drops the oldest part of a prompt longer than this.
+func (c *Client) ContextLength(ctx context.Context, modelName string) (int, error) {
+	info, err := c.ShowModel(ctx, modelName)
+	if err != nil {
+		return 0, err
+	}
+	return info.ContextLength, nil
+}
+
+// Capabilities lists what a model supports, such as "completion", "tools"
+// and "vision". Ollama versions before 0.6.4 don't say, and nil is returned.
+func (c *Client) Capabilities(ctx context.Context, modelName string) ([]string, error) {
+	info, err := c.ShowModel(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+	return info.Capabilities, nil
+}
+
+// showResponse is the part of /api/show's response that is used
+type showResponse struct {
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+	Parameters   string                 `json:"parameters"`
+	Details      struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// parseParameters reads the Modelfile parameters, one "name value" per
+// line. Repeated names such as stop keep their last value.
+func parseParameters(text string) map[string]string {
+	params := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			params[name] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return params
+}
+
+// show fetches a model's details from /api/show
+func (c *Client) show(ctx context.Context, modelName string) (*showResponse, error) {
+	jsonData, err := json.Marshal(map[string]string{"model": modelName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/show", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, c.requestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, statusError(resp, modelName)
+	}
+
+	var result showResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,59 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextLength(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"model_info":{"general.architecture":"qwen2","qwen2.block_count":28,"qwen2.context_length":32768}}`))
+	}))
+	defer srv.Close()
+
+	n, err := NewClient(srv.URL, 0).ContextLength(context.Background(), "qwen2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 32768 {
+		t.Errorf("ContextLength = %d, want 32768", n)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model_info":{},"capabilities":["completion","vision"]}`))
+	}))
+	defer srv.Close()
+
+	caps, err := NewClient(srv.URL, 0).Capabilities(context.Background(), "llava")
+	if err != nil || len(caps) != 2 || caps[1] != "vision" {
+		t.Errorf("Capabilities = %v, %v", caps, err)
+	}
+}
+
+func TestShowModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"parameters":"num_ctx                        8192\nstop                           \"<|eot_id|>\"\ntemperature                    0.6",` +
+			`"details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_K_M"},` +
+			`"model_info":{"llama.context_length":131072},"capabilities":["completion","tools"]}`))
+	}))
+	defer srv.Close()
+
+	info, err := NewClient(srv.URL, 0).ShowModel(context.Background(), "llama3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContextLength != 131072 || info.Family != "llama" || info.ParameterSize != "8.0B" || info.QuantizationLevel != "Q4_K_M" {
+		t.Errorf("ShowModel = %+v", info)
+	}
+	if info.Parameters["num_ctx"] != "8192" || info.Parameters["stop"] != "<|eot_id|>" || info.Parameters["temperature"] != "0.6" {
+		t.Errorf("Parameters = %v", info.Parameters)
+	}
+}
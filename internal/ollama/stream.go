@@ -0,0 +1,113 @@
+package ollama
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultMaxLineBytes is the longest stream line accepted by default. Lines
+// are single JSON chunks, normally a token or two, but a tool call or a
+// final chunk with a large payload can run far past bufio.Scanner's 64KB.
+const DefaultMaxLineBytes = 8 << 20
+
+// ErrLineTooLong means a stream line was longer than the client accepts
+var ErrLineTooLong = errors.New("stream line exceeds the maximum length")
+
+// lineReader splits a newline-delimited JSON stream into lines of any
+// length up to max, reusing its buffer between lines
+type lineReader struct {
+	r    *bufio.Reader
+	max  int
+	line []byte
+	n    int // lines read so far, for error messages
+}
+
+func newLineReader(body io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineBytes
+	}
+	return &lineReader{r: bufio.NewReaderSize(body, 64*1024), max: max}
+}
+
+// next returns the next non-empty line without its line ending, or io.EOF
+// once the stream ends. The line is only valid until the following call.
+func (l *lineReader) next() ([]byte, error) {
+	for {
+		l.line = l.line[:0]
+		for {
+			fragment, err := l.r.ReadSlice('\n')
+			if len(l.line)+len(fragment) > l.max+2 { // allow for \r\n
+				l.n++
+				return nil, fmt.Errorf("line %d: %w (%d bytes)", l.n, ErrLineTooLong, l.max)
+			}
+			l.line = append(l.line, fragment...)
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read stream: %w", err)
+			}
+			if len(l.line) == 0 && err == io.EOF {
+				return nil, io.EOF
+			}
+			break
+		}
+
+		l.n++
+		line := trimLineEnding(l.line)
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// chunk decodes the next stream line, returning io.EOF at the end and an
+// error for lines that are not a chat chunk or that report a failure
+func (l *lineReader) chunk() (ChatResponse, error) {
+	var chunk ChatResponse
+	line, err := l.next()
+	if err != nil {
+		return chunk, err
+	}
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return chunk, fmt.Errorf("malformed stream line %d: %w", l.n, err)
+	}
+	if chunk.Error != "" {
+		return chunk, fmt.Errorf("ollama failed mid-stream: %s", chunk.Error)
+	}
+	return chunk, nil
+}
+
+// arrivals times a stream's tokens as they arrive, one per chunk, for a
+// running speed before Ollama reports its own counts at the end
+type arrivals struct {
+	first, last time.Time
+	tokens      int
+}
+
+// add counts a token arriving at now and returns the counts so far. The
+// speed is measured between arrivals, so it is 0 until the second token.
+func (a *arrivals) add(now time.Time) Stats {
+	if a.tokens == 0 {
+		a.first = now
+	}
+	a.last = now
+	a.tokens++
+	stats := Stats{AnswerTokens: a.tokens}
+	if elapsed := a.last.Sub(a.first); elapsed > 0 {
+		// Scaled so TokensPerSecond counts the intervals, not the tokens
+		stats.Duration = elapsed * time.Duration(a.tokens) / time.Duration(a.tokens-1)
+	}
+	return stats
+}
+
+func trimLineEnding(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
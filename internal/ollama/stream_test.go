@@ -0,0 +1,120 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamHandlesLinesBeyondScannerBuffer(t *testing.T) {
+	big := strings.Repeat("a", 200*1024)
+	body := `{"message":{"content":"` + big + `"}}` + "\r\n\n" +
+		`{"message":{"thinking":"hm","content":"!"},"done":true}`
+
+	c := NewClient("http://unused", 0)
+	var chunks []string
+	thinking, answer, err := c.streamWithThinking(strings.NewReader(body), StreamCallbacks{
+		OnAnswer: func(s string) { chunks = append(chunks, s) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != big+"!" || thinking != "hm" || len(chunks) != 2 {
+		t.Errorf("answer of %d bytes, thinking %q, %d chunks", len(answer), thinking, len(chunks))
+	}
+}
+
+func TestStreamSurfacesFramingErrors(t *testing.T) {
+	c := NewClient("http://unused", 0)
+	c.SetMaxLineBytes(64 * 1024)
+
+	for name, tc := range map[string]struct {
+		body    string
+		partial string
+		want    string
+	}{
+		"too long": {
+			body:    `{"message":{"content":"ok "}}` + "\n" + `{"message":{"content":"` + strings.Repeat("a", 100*1024) + `"}}` + "\n",
+			partial: "ok ",
+			want:    "line 2",
+		},
+		"malformed": {
+			body:    `{"message":{"content":"ok "}}` + "\n" + `{"message":` + "\n",
+			partial: "ok ",
+			want:    "malformed stream line 2",
+		},
+		"error chunk": {
+			body: `{"error":"model runner crashed"}` + "\n",
+			want: "model runner crashed",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.streamResponse(strings.NewReader(tc.body), nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want one mentioning %q", err, tc.want)
+			}
+			if got != tc.partial {
+				t.Errorf("partial answer = %q, want %q", got, tc.partial)
+			}
+			if name == "too long" && !errors.Is(err, ErrLineTooLong) {
+				t.Errorf("err = %v, want ErrLineTooLong", err)
+			}
+		})
+	}
+}
+
+func TestStreamReportsGenerationStats(t *testing.T) {
+	body := `{"message":{"content":"hi"}}` + "\n" +
+		`{"message":{"content":""},"done":true,"total_duration":3500000000,"load_duration":1000000000,` +
+		`"prompt_eval_count":120,"prompt_eval_duration":500000000,"eval_count":40,"eval_duration":2000000000}` + "\n"
+
+	var stats Stats
+	c := NewClient("http://unused", 0)
+	if _, _, err := c.streamWithThinking(strings.NewReader(body), StreamCallbacks{
+		OnStats: func(s Stats) { stats = s },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if stats.PromptTokens != 120 || stats.AnswerTokens != 40 || stats.TokensPerSecond() != 20 {
+		t.Errorf("stats = %+v (%.1f tok/s)", stats, stats.TokensPerSecond())
+	}
+	if stats.PromptDuration != 500*time.Millisecond || stats.LoadDuration != time.Second || stats.TotalDuration != 3500*time.Millisecond {
+		t.Errorf("durations = %+v", stats)
+	}
+}
+
+func TestStreamReportsProgressPerToken(t *testing.T) {
+	body := `{"message":{"thinking":"hmm"}}` + "\n" +
+		`{"message":{"content":"hi"}}` + "\n" +
+		`{"message":{"content":" there"}}` + "\n" +
+		`{"message":{"content":""},"done":true,"eval_count":3}` + "\n"
+
+	var counts []int
+	c := NewClient("http://unused", 0)
+	if _, _, err := c.streamWithThinking(strings.NewReader(body), StreamCallbacks{
+		OnProgress: func(s Stats) { counts = append(counts, s.AnswerTokens) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(counts) != "[1 2 3]" {
+		t.Errorf("progress counts = %v, want one per token chunk", counts)
+	}
+}
+
+func TestArrivalsRateBetweenTokens(t *testing.T) {
+	var a arrivals
+	start := time.Now()
+	if s := a.add(start); s.TokensPerSecond() != 0 {
+		t.Errorf("one token: %.1f tok/s, want 0", s.TokensPerSecond())
+	}
+	var s Stats
+	for i := 1; i <= 10; i++ {
+		s = a.add(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+	if s.AnswerTokens != 11 || math.Abs(s.TokensPerSecond()-10) > 0.001 {
+		t.Errorf("11 tokens 100ms apart: %d tokens, %.3f tok/s, want 10 tok/s", s.AnswerTokens, s.TokensPerSecond())
+	}
+}
@@ -0,0 +1,39 @@
+package ollama
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// dialTimeout bounds connecting to Ollama, separately from how long it may
+// take to answer
+const dialTimeout = 10 * time.Second
+
+// newTransport returns the keep-alive transport shared by the streaming and
+// non-streaming clients. Ollama only sends response headers once the model
+// is loaded, so headerTimeout must cover a cold load; 0 means no limit.
+// Streams have no overall deadline and end with their context.
+func newTransport(headerTimeout time.Duration) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          32,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: headerTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Transport returns the connection pool the client's requests go through,
+// which also adds the SetAuth and SetHeaders headers, for wrapping in
+// another RoundTripper before passing it to SetTransport
+func (c *Client) Transport() http.RoundTripper {
+	return c.auth
+}
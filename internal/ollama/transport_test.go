@@ -0,0 +1,75 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamOutlastsRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, word := range []string{"slow ", "but ", "complete"} {
+			fmt.Fprintf(w, `{"message":{"content":%q}}`+"\n", word)
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+		}
+		fmt.Fprintln(w, `{"done":true}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, 200*time.Millisecond)
+	got, err := c.Chat(context.Background(), ChatRequest{Model: "m"}, nil)
+	if err != nil || got != "slow but complete" {
+		t.Fatalf("Chat = %q, %v", got, err)
+	}
+}
+
+func TestHeaderTimeoutEndsSilentRequests(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := NewClient(srv.URL, 100*time.Millisecond)
+	start := time.Now()
+	if _, err := c.Chat(context.Background(), ChatRequest{Model: "m"}, nil); err == nil {
+		t.Fatal("want an error when Ollama never answers")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("gave up after %v", d)
+	}
+}
+
+func TestClientsShareConnections(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"content":"ok"},"done":true}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c := NewClient(srv.URL, time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, err := c.ChatSync(context.Background(), "m", []Message{{Role: "user", Content: "hi"}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Chat(context.Background(), ChatRequest{Model: "m"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("opened %d connections for six sequential requests, want 1", n)
+	}
+}
@@ -1,18 +1,56 @@
 package ollama
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ChatRequest represents a chat request to Ollama
 type ChatRequest struct {
 	Model    string                 `json:"model"`
 	Messages []Message              `json:"messages"`
 	Stream   bool                   `json:"stream"`
 	Options  map[string]interface{} `json:"options,omitempty"`
+	Tools    []Tool                 `json:"tools,omitempty"`
+	Format   json.RawMessage        `json:"format,omitempty"` // FormatJSON or a JSON schema constrains output to JSON
+	// KeepAlive is how long the model stays loaded afterwards, e.g. "30m";
+	// "0" unloads it at once and a negative duration keeps it loaded.
+	// Empty uses the client's default.
+	KeepAlive string `json:"keep_alive,omitempty"`
 }
 
 // Message represents a chat message
 type Message struct {
-	Role     string `json:"role"`    // "user", "assistant", or "system"
-	Content  string `json:"content"`
-	Thinking string `json:"thinking"` // For reasoning models like deepseek-r1
+	Role      string     `json:"role"` // "user", "assistant", or "system"
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking"`         // For reasoning models like deepseek-r1
+	Images    []string   `json:"images,omitempty"` // base64-encoded images, for vision models such as llava
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"` // set on "tool" role results
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is a tool's name, purpose and JSON Schema parameters
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall is a model's request to run a tool
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the called tool's name and arguments
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 // ChatResponse represents a streaming response chunk from Ollama
@@ -21,4 +59,55 @@ type ChatResponse struct {
 	CreatedAt string  `json:"created_at"`
 	Message   Message `json:"message"`
 	Done      bool    `json:"done"`
+	Error     string  `json:"error,omitempty"` // set instead of a message when generation fails mid-stream
+
+	// Set on the final chunk only
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`       // whole request, in nanoseconds
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`        // time spent loading the model, in nanoseconds
+	PromptEvalCount    int           `json:"prompt_eval_count,omitempty"`    // prompt tokens evaluated
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"` // time spent reading the prompt, in nanoseconds
+	EvalCount          int           `json:"eval_count,omitempty"`           // tokens generated
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`        // time spent generating, in nanoseconds
+}
+
+// Stats are Ollama's counts for one generation
+type Stats struct {
+	PromptTokens   int
+	AnswerTokens   int // thinking and answer tokens generated
+	Duration       time.Duration
+	PromptDuration time.Duration // time spent reading the prompt
+	LoadDuration   time.Duration // time spent loading the model, 0 when it was already loaded
+	TotalDuration  time.Duration // the whole request as Ollama timed it
+}
+
+// TokensPerSecond is the generation speed, or 0 if it is unknown
+func (s Stats) TokensPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.AnswerTokens) / s.Duration.Seconds()
+}
+
+// EmbedRequest represents a request to the embeddings endpoint
+type EmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbedResponse holds one embedding vector per input
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// LegacyEmbedRequest is a request to /api/embeddings, which servers older
+// than /api/embed offer
+type LegacyEmbedRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+// LegacyEmbedResponse holds the vector of a LegacyEmbedRequest
+type LegacyEmbedResponse struct {
+	Embedding []float32 `json:"embedding"`
 }
@@ -0,0 +1,307 @@
+// Package pages keeps compressed copies of crawled pages with the history
+// session that used them, so answers can be traced back to the exact text
+// they were based on
+package pages
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/history"
+)
+
+// indexFile maps URLs to their latest saved copy within a session
+const indexFile = "index.json"
+
+// Page is a saved copy of a crawled page
+type Page struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Result converts the page back into a crawl result
+func (p Page) Result() crawler.CrawlResult {
+	return crawler.CrawlResult{URL: p.URL, Title: p.Title, Content: p.Content, Saved: p.FetchedAt}
+}
+
+// Store saves pages under dir, one directory per session
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a page store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save stores the successfully crawled pages of results for session and
+// returns references numbered as the sources appear in the prompt. Pages
+// with identical content are stored once; crawling one again renews its age.
+func (s *Store) Save(sessionID string, results []crawler.CrawlResult) ([]history.PageRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, sessionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create page directory: %w", err)
+	}
+	index, err := s.readIndexUnlocked(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []history.PageRef
+	for _, r := range results {
+		if r.Error != nil || r.Content == "" {
+			continue
+		}
+		file := fileName(r.URL, r.Content)
+		if _, err := os.Stat(filepath.Join(dir, file)); os.IsNotExist(err) || r.Saved.IsZero() {
+			page := Page{URL: r.URL, Title: r.Title, Content: r.Content, FetchedAt: r.Saved}
+			if page.FetchedAt.IsZero() {
+				page.FetchedAt = time.Now()
+			}
+			if err := writePage(filepath.Join(dir, file), page); err != nil {
+				return nil, err
+			}
+		}
+		index[r.URL] = file
+		refs = append(refs, history.PageRef{Number: len(refs) + 1, URL: r.URL, Title: r.Title, File: file})
+	}
+
+	if err := s.writeIndexUnlocked(sessionID, index); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// Load reads the saved copy ref points to
+func (s *Store) Load(sessionID string, ref history.PageRef) (Page, error) {
+	return readPage(filepath.Join(s.dir, sessionID, filepath.Base(ref.File)))
+}
+
+// LoadAll reads the saved copies of refs in order, as crawl results. Pages
+// that cannot be read come back with an error, so source numbering is kept.
+func (s *Store) LoadAll(sessionID string, refs []history.PageRef) []crawler.CrawlResult {
+	results := make([]crawler.CrawlResult, len(refs))
+	for i, ref := range refs {
+		page, err := s.Load(sessionID, ref)
+		if err != nil {
+			results[i] = crawler.CrawlResult{URL: ref.URL, Title: ref.Title, Error: err}
+			continue
+		}
+		results[i] = page.Result()
+	}
+	return results
+}
+
+// Lookup returns the latest copy of url saved in session
+func (s *Store) Lookup(sessionID, url string) (Page, bool) {
+	s.mu.Lock()
+	index, err := s.readIndexUnlocked(sessionID)
+	s.mu.Unlock()
+	if err != nil || index[url] == "" {
+		return Page{}, false
+	}
+	page, err := readPage(filepath.Join(s.dir, sessionID, index[url]))
+	return page, err == nil
+}
+
+// Prune removes the pages of sessions not listed in keep
+func (s *Store) Prune(keep []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read page directory: %w", err)
+	}
+	live := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		live[id] = true
+	}
+	for _, e := range entries {
+		if e.IsDir() && !live[e.Name()] {
+			if err := os.RemoveAll(filepath.Join(s.dir, e.Name())); err != nil {
+				return fmt.Errorf("failed to remove pages of session %s: %w", e.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// fileName identifies a page by URL and content
+func fileName(url, content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", url, content)
+	return hex.EncodeToString(h.Sum(nil))[:16] + ".json.gz"
+}
+
+// writePage stores page gzip-compressed, atomically
+func writePage(path string, page Page) error {
+	tempPath := path + ".tmp"
+	f, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(page); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode page: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to compress page: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// readPage loads a page written by writePage
+func readPage(path string) (Page, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to open saved page: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to decompress saved page: %w", err)
+	}
+	defer zr.Close()
+
+	var page Page
+	if err := json.NewDecoder(zr).Decode(&page); err != nil {
+		return Page{}, fmt.Errorf("failed to parse saved page: %w", err)
+	}
+	return page, nil
+}
+
+// readIndexUnlocked loads a session's URL index (must be called with lock held)
+func (s *Store) readIndexUnlocked(sessionID string) (map[string]string, error) {
+	index := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(s.dir, sessionID, indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read page index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse page index: %w", err)
+	}
+	return index, nil
+}
+
+// writeIndexUnlocked saves a session's URL index atomically (must be called with lock held)
+func (s *Store) writeIndexUnlocked(sessionID string, index map[string]string) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page index: %w", err)
+	}
+	path := filepath.Join(s.dir, sessionID, indexFile)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// Backend fetches pages that have no saved copy
+type Backend interface {
+	CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult
+}
+
+// Fetcher serves pages already saved in the current session from the store
+// and crawls the rest, so re-asking reuses the exact sources
+type Fetcher struct {
+	Store   *Store
+	Session func() string // current session ID; "" disables reuse
+	Next    Backend
+	MaxAge  time.Duration // older saved copies are fetched again; 0 means no limit
+}
+
+type freshKey struct{}
+
+// WithFresh marks ctx so that a Fetcher crawls every page again instead of
+// reusing saved copies, for questions about what is happening now
+func WithFresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// fresh reports whether ctx was marked by WithFresh
+func fresh(ctx context.Context) bool {
+	v, _ := ctx.Value(freshKey{}).(bool)
+	return v
+}
+
+// CrawlURLs implements grounding.Fetcher
+func (f *Fetcher) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	session := f.Session()
+	if fresh(ctx) {
+		session = ""
+	}
+	var results []crawler.CrawlResult
+	var missing []string
+	for _, url := range urls {
+		if session != "" {
+			if page, ok := f.Store.Lookup(session, url); ok && f.current(page) {
+				results = append(results, page.Result())
+				continue
+			}
+		}
+		missing = append(missing, url)
+	}
+
+	if len(missing) > 0 {
+		results = append(results, f.Next.CrawlURLs(ctx, missing)...)
+	}
+	return results
+}
+
+// current reports whether a saved page is recent enough to reuse
+func (f *Fetcher) current(page Page) bool {
+	return f.MaxAge <= 0 || time.Since(page.FetchedAt) <= f.MaxAge
+}
+
+// Unique drops pages whose content repeats an earlier page's, such as one
+// article found under two URLs, so the same text is not quoted twice
+func Unique(results []crawler.CrawlResult) []crawler.CrawlResult {
+	seen := make(map[[sha256.Size]byte]bool, len(results))
+	var unique []crawler.CrawlResult
+	for _, r := range results {
+		if r.Error == nil && r.Content != "" {
+			sum := sha256.Sum256([]byte(r.Content))
+			if seen[sum] {
+				continue
+			}
+			seen[sum] = true
+		}
+		unique = append(unique, r)
+	}
+	return unique
+}
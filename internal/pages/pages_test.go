@@ -0,0 +1,149 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-ollama/internal/crawler"
+)
+
+var crawled = []crawler.CrawlResult{
+	{URL: "https://down.example", Error: errors.New("timeout")},
+	{URL: "https://a.example", Title: "A", Content: "page a"},
+	{URL: "https://b.example", Title: "B", Content: "page b"},
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	store := NewStore(t.TempDir())
+	refs, err := store.Save("s1", crawled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs[0].Number != 1 || refs[1].URL != "https://b.example" {
+		t.Fatalf("unexpected refs: %+v", refs)
+	}
+
+	page, err := store.Load("s1", refs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Content != "page b" || page.Title != "B" || page.FetchedAt.IsZero() {
+		t.Errorf("unexpected page: %+v", page)
+	}
+
+	// Saving the same content again reuses the file
+	again, err := store.Save("s1", crawled[1:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].File != refs[0].File {
+		t.Errorf("identical page saved twice: %s and %s", refs[0].File, again[0].File)
+	}
+
+	results := store.LoadAll("s1", append(refs, refs[0]))
+	if len(results) != 3 || results[0].Content != "page a" {
+		t.Errorf("LoadAll = %+v", results)
+	}
+}
+
+func TestLookupAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	if _, err := store.Save("old", crawled); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save("current", crawled[1:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if page, ok := store.Lookup("current", "https://a.example"); !ok || page.Content != "page a" {
+		t.Errorf("Lookup = %+v, %v", page, ok)
+	}
+	if _, ok := store.Lookup("current", "https://b.example"); ok {
+		t.Error("page saved in another session should not be found")
+	}
+
+	if err := store.Prune([]string{"current"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Error("pages of a pruned session were kept")
+	}
+}
+
+type fakeCrawler struct{ fetched []string }
+
+func (f *fakeCrawler) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	var results []crawler.CrawlResult
+	for _, u := range urls {
+		f.fetched = append(f.fetched, u)
+		results = append(results, crawler.CrawlResult{URL: u, Content: "fresh"})
+	}
+	return results
+}
+
+func TestFetcherReusesSavedPages(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := store.Save("s1", crawled); err != nil {
+		t.Fatal(err)
+	}
+	next := &fakeCrawler{}
+	f := &Fetcher{Store: store, Session: func() string { return "s1" }, Next: next}
+
+	results := f.CrawlURLs(context.Background(), []string{"https://a.example", "https://c.example"})
+	if len(next.fetched) != 1 || next.fetched[0] != "https://c.example" {
+		t.Errorf("fetched %v, want only the unsaved page", next.fetched)
+	}
+	if len(results) != 2 || results[0].Content != "page a" || results[1].Content != "fresh" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestFetcherRefetchesForFreshQuestionsAndOldCopies(t *testing.T) {
+	store := NewStore(t.TempDir())
+	old := crawler.CrawlResult{URL: "https://a.example", Content: "page a", Saved: time.Now().Add(-2 * time.Hour)}
+	if _, err := store.Save("s1", []crawler.CrawlResult{old}); err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{"https://a.example"}
+
+	next := &fakeCrawler{}
+	f := &Fetcher{Store: store, Session: func() string { return "s1" }, Next: next}
+	f.CrawlURLs(WithFresh(context.Background()), urls)
+	if len(next.fetched) != 1 {
+		t.Errorf("time-sensitive question reused a saved page")
+	}
+
+	next.fetched = nil
+	f.MaxAge = time.Hour
+	results := f.CrawlURLs(context.Background(), urls)
+	if len(next.fetched) != 1 || results[0].Content != "fresh" {
+		t.Errorf("saved page older than MaxAge was reused: %+v", results)
+	}
+
+	// Crawling the same content again renews the saved copy
+	if _, err := store.Save("s1", []crawler.CrawlResult{{URL: "https://a.example", Content: "page a"}}); err != nil {
+		t.Fatal(err)
+	}
+	next.fetched = nil
+	f.CrawlURLs(context.Background(), urls)
+	if len(next.fetched) != 0 {
+		t.Errorf("renewed page was fetched again")
+	}
+}
+
+func TestUnique(t *testing.T) {
+	results := Unique([]crawler.CrawlResult{
+		{URL: "https://a.example/post", Content: "same text"},
+		{URL: "https://down.example", Error: errors.New("timeout")},
+		{URL: "https://a.example/post?utm_source=x", Content: "same text"},
+		{URL: "https://b.example", Content: "other text"},
+	})
+	if len(results) != 3 || results[2].URL != "https://b.example" {
+		t.Errorf("Unique = %+v", results)
+	}
+}
@@ -0,0 +1,209 @@
+// Package perspectives groups the sources of a contested question by the
+// view they take, so the answer can set out each view with its sources
+// instead of blending them into one voice. Pages are grouped by the
+// similarity of their embeddings: pages arguing the same side tend to use
+// the same framing and vocabulary.
+package perspectives
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/embed"
+)
+
+// DefaultSimilarity is how alike two groups of pages must be, as the cosine
+// similarity of their embeddings, to be merged into one perspective
+const DefaultSimilarity = 0.8
+
+// embedWords is how much of each page is embedded: its opening states the
+// page's position, the rest mostly supports it
+const embedWords = 250
+
+// contested matches questions that ask for a judgment people disagree on
+var contested = regexp.MustCompile(`(?i)\b(vs\.?|versus|should (i|we|you|they|the|it|a|an|people|governments?)|` +
+	`(is|are) (.{1,60} )?(safe|ethical|moral|fair|healthy|harmful|dangerous|overrated|a scam|worth it|good or bad|better|worse)|` +
+	`pros and cons|debate[sd]?|controvers(y|ial)|arguments? (for|against)|for and against|` +
+	`opinions? on|views? on|critics|criticism|disagree|side with)\b`)
+
+// Contested reports whether a question invites differing perspectives,
+// such as "is nuclear power safe?" or "React vs Vue"
+func Contested(query string) bool {
+	return contested.MatchString(query)
+}
+
+// Grouper embeds the sources of contested questions and groups them
+type Grouper struct {
+	Embedder   embed.Embedder
+	Model      string
+	Similarity float64 // merge threshold; DefaultSimilarity when 0
+}
+
+// New creates a grouper from the config, or returns nil when perspectives
+// are off
+func New(embedder embed.Embedder, cfg *config.Config) *Grouper {
+	if !cfg.Perspectives || embedder == nil {
+		return nil
+	}
+	return &Grouper{Embedder: embedder, Model: cfg.EmbedModel}
+}
+
+// Group returns the URLs of the usable pages grouped by perspective, or nil
+// when the question is not contested, the pages do not split into distinct
+// groups, or they could not be embedded. A nil Grouper never groups.
+func (g *Grouper) Group(ctx context.Context, query string, pages []crawler.CrawlResult) [][]string {
+	if g == nil || !Contested(query) {
+		return nil
+	}
+	var urls, texts []string
+	for _, p := range pages {
+		if p.Error != nil || p.Content == "" {
+			continue
+		}
+		urls = append(urls, p.URL)
+		texts = append(texts, opening(p))
+	}
+	if len(urls) < 3 {
+		return nil
+	}
+
+	vectors, err := g.Embedder.Embeddings(ctx, g.Model, texts)
+	if err != nil || len(vectors) != len(texts) {
+		slog.Debug("could not embed sources for perspectives", "model", g.Model, "error", err)
+		return nil
+	}
+	similarity := g.Similarity
+	if similarity == 0 {
+		similarity = DefaultSimilarity
+	}
+	clusters := Cluster(vectors, similarity)
+
+	// One group means the sources agree; a group per page means the
+	// embeddings found no structure worth pointing out
+	if len(clusters) < 2 || len(clusters) > max(2, len(urls)/2) {
+		return nil
+	}
+	groups := make([][]string, len(clusters))
+	for i, members := range clusters {
+		for _, m := range members {
+			groups[i] = append(groups[i], urls[m])
+		}
+	}
+	return groups
+}
+
+// opening is the text of a page that is embedded: its title and start
+func opening(p crawler.CrawlResult) string {
+	words := strings.Fields(p.Content)
+	if len(words) > embedWords {
+		words = words[:embedWords]
+	}
+	return p.Title + "\n" + strings.Join(words, " ")
+}
+
+// Cluster groups vectors by average-linkage agglomerative clustering,
+// merging the two most similar groups while their mean cosine similarity is
+// at least threshold. Groups are returned in order of their first member,
+// with members in order.
+func Cluster(vectors [][]float32, threshold float64) [][]int {
+	n := len(vectors)
+	sim := make([][]float64, n)
+	for i := range sim {
+		sim[i] = make([]float64, n)
+		for j := range sim[i] {
+			sim[i][j] = cosine(vectors[i], vectors[j])
+		}
+	}
+
+	groups := make([][]int, n)
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+	for len(groups) > 1 {
+		bestA, bestB, best := -1, -1, math.Inf(-1)
+		for a := range groups {
+			for b := a + 1; b < len(groups); b++ {
+				if s := linkage(sim, groups[a], groups[b]); s > best {
+					bestA, bestB, best = a, b, s
+				}
+			}
+		}
+		if best < threshold {
+			break
+		}
+		groups[bestA] = mergeSorted(groups[bestA], groups[bestB])
+		groups = append(groups[:bestB], groups[bestB+1:]...)
+	}
+	return groups
+}
+
+// linkage is the mean similarity between the members of two groups
+func linkage(sim [][]float64, a, b []int) float64 {
+	total := 0.0
+	for _, i := range a {
+		for _, j := range b {
+			total += sim[i][j]
+		}
+	}
+	return total / float64(len(a)*len(b))
+}
+
+func mergeSorted(a, b []int) []int {
+	out := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			out, a = append(out, a[0]), a[1:]
+		} else {
+			out, b = append(out, b[0]), b[1:]
+		}
+	}
+	return append(append(out, a...), b...)
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Instruction tells the model to present each group of sources as its own
+// perspective. groups hold source numbers as the prompt numbers them; it
+// returns "" unless at least two groups are left.
+func Instruction(groups [][]int) string {
+	var lines []string
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		refs := make([]string, len(g))
+		for i, n := range g {
+			refs[i] = fmt.Sprint(n)
+		}
+		lines = append(lines, fmt.Sprintf("- Perspective %c: sources %s", 'A'+len(lines), strings.Join(refs, ", ")))
+	}
+	if len(lines) < 2 {
+		return ""
+	}
+	return "## Perspectives\n\n" +
+		"This question is contested, and the sources fall into groups that may take different positions:\n" +
+		strings.Join(lines, "\n") + "\n\n" +
+		"Do not blend them into one voice. Set out each perspective in turn, attributing it to its sources " +
+		"(\"According to sources 1 and 3, ...\", citing their passages as usual), then say where they agree and where they disagree. " +
+		"If the groups turn out to agree, say so instead of inventing a disagreement."
+}
@@ -0,0 +1,99 @@
+package perspectives
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/crawler"
+)
+
+func TestContested(t *testing.T) {
+	for query, want := range map[string]bool{
+		"is nuclear power safe?":            true,
+		"React vs Vue for a small team":     true,
+		"should I learn Rust or Go first":   true,
+		"pros and cons of remote work":      true,
+		"is the Apple Vision Pro worth it":  true,
+		"arguments against a four-day week": true,
+		"what time is it in Tokyo":          false,
+		"how do I reverse a slice in Go":    false,
+		"go 1.22 release notes":             false,
+		"who won the 2022 world cup":        false,
+	} {
+		if got := Contested(query); got != want {
+			t.Errorf("Contested(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestClusterGroupsSimilarVectors(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0.1, 0},
+		{0, 1, 0.1},
+		{0.9, 0.2, 0},
+		{0.1, 0.9, 0},
+		{0, 0, 1},
+	}
+	got := fmt.Sprint(Cluster(vectors, 0.9))
+	if got != "[[0 2] [1 3] [4]]" {
+		t.Errorf("clusters = %s", got)
+	}
+	if got := Cluster(vectors, -1); len(got) != 1 || len(got[0]) != 5 {
+		t.Errorf("a threshold of -1 should merge everything, got %v", got)
+	}
+}
+
+// fakeEmbedder embeds a page as its side: "pro" or "con" in its first words
+type fakeEmbedder struct {
+	calls int
+}
+
+func (f *fakeEmbedder) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	f.calls++
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		if strings.Contains(t, "pro") {
+			vectors[i] = []float32{1, 0}
+		} else {
+			vectors[i] = []float32{0, 1}
+		}
+	}
+	return vectors, nil
+}
+
+func TestGroupSplitsSourcesBySide(t *testing.T) {
+	pages := []crawler.CrawlResult{
+		{URL: "https://a.example", Content: "pro nuclear"},
+		{URL: "https://b.example", Content: "con nuclear"},
+		{URL: "https://broken.example", Error: fmt.Errorf("timeout")},
+		{URL: "https://c.example", Content: "pro again"},
+		{URL: "https://d.example", Content: "con again"},
+	}
+	embedder := &fakeEmbedder{}
+	g := &Grouper{Embedder: embedder}
+
+	groups := g.Group(context.Background(), "is nuclear power safe?", pages)
+	if fmt.Sprint(groups) != "[[https://a.example https://c.example] [https://b.example https://d.example]]" {
+		t.Errorf("groups = %v", groups)
+	}
+
+	if g.Group(context.Background(), "how does a reactor work", pages) != nil || embedder.calls != 1 {
+		t.Error("an uncontested question should not be grouped or embedded")
+	}
+	var none *Grouper
+	if none.Group(context.Background(), "is nuclear power safe?", pages) != nil {
+		t.Error("nil Grouper grouped sources")
+	}
+}
+
+func TestInstruction(t *testing.T) {
+	text := Instruction([][]int{{1, 3}, {2}, nil})
+	if !strings.Contains(text, "Perspective A: sources 1, 3") || !strings.Contains(text, "Perspective B: sources 2") {
+		t.Errorf("instruction = %q", text)
+	}
+	if Instruction([][]int{{1, 2}, nil}) != "" {
+		t.Error("one group left should give no instruction")
+	}
+}
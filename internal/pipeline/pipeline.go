@@ -0,0 +1,272 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/cache"
+	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/history"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/perspectives"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/sinks"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/tools"
+)
+
+// Pipeline runs the full ask flow (analyze, search, crawl, generate)
+// without terminal output, for batch and other headless modes
+type Pipeline struct {
+	Config       *config.Config
+	Client       *ollama.Client
+	Grounder     *grounding.Grounder    // nil disables web search
+	Cache        *cache.Answers         // nil disables answer caching
+	Sinks        *sinks.Dispatcher      // receives every answer; may be nil
+	Tools        *tools.Loop            // lets the model run code; nil disables it
+	Post         *postprocess.Processor // cleans up answers; nil leaves them as generated
+	Summary      *summarize.Summarizer  // condenses large crawls; nil quotes pages as crawled
+	Perspectives *perspectives.Grouper  // groups the sources of contested questions by view; nil blends them
+	Knowledge    *knowledge.Store       // searched for excerpts to add; nil disables it
+	Sources      []string               // what answers come from besides the web, such as a document; part of the cache key
+	Mode         string                 // reported to sinks, e.g. "batch"
+}
+
+// Timings records how long each stage of a question took
+type Timings struct {
+	Ground   time.Duration // analysis, search and crawl
+	Generate time.Duration
+	Total    time.Duration
+}
+
+// Answer is the outcome of one question
+type Answer struct {
+	Question        string
+	Answer          string
+	Thinking        string
+	Sources         []string // cited sources, or every source in the prompt if none are cited
+	SearchPerformed bool
+	SearchQueries   []string
+	StandaloneQuery string // the question rewritten without reference to history, if it changed
+	Cached          bool   // answered from the answer cache
+	Timings         Timings
+	Trace           *grounding.Trace // how the answer was grounded, for auditing
+}
+
+type styleKey struct{}
+
+// WithStyle answers questions asked under ctx in an answer style other than
+// the configured one
+func WithStyle(ctx context.Context, style string) context.Context {
+	return context.WithValue(ctx, styleKey{}, style)
+}
+
+// config returns the configuration for a question asked under ctx
+func (p *Pipeline) config(ctx context.Context) *config.Config {
+	style, _ := ctx.Value(styleKey{}).(string)
+	if style == "" || style == p.Config.AnswerStyle {
+		return p.Config
+	}
+	cfg := *p.Config
+	cfg.AnswerStyle = style
+	return &cfg
+}
+
+// Ask answers a question with optional conversation history. onChunk, if set,
+// receives answer tokens as they stream. Questions without history are
+// answered from the cache when possible; prefix one with /nocache to skip it.
+func (p *Pipeline) Ask(ctx context.Context, question string, recent []history.Message, onChunk func(string)) (*Answer, error) {
+	start := time.Now()
+	cfg := p.config(ctx)
+	question, noCache := cache.StripNoCache(question)
+	answer := &Answer{Question: question}
+
+	// Follow-up questions depend on the conversation, so only cache fresh
+	// ones. The lookup comes before grounding so a hit costs no searching;
+	// /nocache skips the lookup but still refreshes the entry
+	cacheKey := ""
+	if p.Cache != nil && len(recent) == 0 {
+		mode := ""
+		if cfg.AnswerStyle != prompt.StyleDefault {
+			mode += "style=" + cfg.AnswerStyle // answers in another style differ
+		}
+		if cfg.CitationStyle != postprocess.CiteInline {
+			mode += " cite=" + cfg.CitationStyle
+		}
+		cacheKey = cache.Key(question, cfg.ModelName, mode, p.Sources)
+		if entry, ok := p.Cache.Get(cacheKey); ok && !noCache {
+			answer.Answer = entry.Answer
+			answer.Sources = entry.Sources
+			answer.SearchPerformed = len(entry.Sources) > 0
+			answer.Cached = true
+			answer.Trace = p.Grounder.Trace(grounding.Result{}, nil, "")
+			answer.Trace.Cached = true
+			if onChunk != nil {
+				onChunk(entry.Answer)
+			}
+			answer.Timings.Total = time.Since(start)
+			slog.Info("answered from cache", "question", question, "age", time.Since(entry.CreatedAt))
+			p.publish(answer)
+			return answer, nil
+		}
+	}
+
+	var crawled []crawler.CrawlResult
+	var result grounding.Result
+	if p.Grounder != nil {
+		var err error
+		result, err = p.Grounder.Ground(ctx, question, grounding.Conversation(recent))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			// Answer without web context, as the REPL does; the trace
+			// records why
+			slog.Warn("grounding failed; answering without web context", "question", question, "error", err)
+			result = grounding.Result{Decision: analyzer.SearchDecision{StandaloneQuery: question, Reason: err.Error()}}
+		}
+		if result.Decision.StandaloneQuery != question {
+			answer.StandaloneQuery = result.Decision.StandaloneQuery
+		}
+		crawled = result.Crawled
+		answer.SearchPerformed = result.Decision.NeedsSearch
+		answer.SearchQueries = result.Decision.SearchQueries
+		for _, c := range result.Crawled {
+			if c.Error == nil && c.Content != "" {
+				answer.Sources = append(answer.Sources, c.URL)
+			}
+		}
+	}
+	answer.Timings.Ground = time.Since(start)
+
+	if p.Summary.Needed(crawled) {
+		crawled = p.Summary.Summarize(ctx, question, crawled)
+	}
+
+	var knowledgeContext string
+	if p.Knowledge != nil {
+		knowledgeContext = p.knowledgeContext(ctx, question)
+	}
+
+	messages, report := contextbuilder.New(cfg).Build(contextbuilder.Turn{
+		Query:            question,
+		KnowledgeContext: knowledgeContext,
+		Sources:          crawled,
+		History:          recent,
+		Perspectives:     p.Perspectives.Group(ctx, question, crawled),
+	})
+	slog.Debug("built prompt", "summary", report.Summary(), "dropped", len(report.Dropped()))
+	if err := report.Overflow(); err != nil {
+		return nil, err
+	}
+
+	genStart := time.Now()
+	var chat contextbuilder.Chat = p.Client.ChatWithCallbacks
+	if p.Tools != nil {
+		chat = p.Tools.Chat
+	}
+	options := cfg.ModelOptions()
+	prompt.ApplyStyle(options, cfg.AnswerStyle, cfg.AnswerTokens)
+	format, err := ollama.ParseFormat(cfg.AnswerFormat)
+	if err != nil {
+		return nil, err
+	}
+	// JSON answers are returned as the model wrote them: they carry no
+	// citations to check or restyle
+	structured := len(format) > 0
+	var stats ollama.Stats
+	thinking, text, regrounded, err := contextbuilder.ChatGrounded(ctx, chat, ollama.ChatRequest{
+		Model:    cfg.ModelName,
+		Messages: messages,
+		Options:  options,
+		Format:   format,
+	}, ollama.StreamCallbacks{
+		OnAnswer: onChunk,
+		OnStats:  func(s ollama.Stats) { stats = s },
+	}, report.Grounded() && !structured)
+	if err != nil {
+		return nil, fmt.Errorf("generation failed: %w", err)
+	}
+
+	if !structured {
+		var changed []string
+		text, changed = p.Post.Process(text)
+		if len(changed) > 0 {
+			slog.Debug("post-processed answer", "steps", changed)
+		}
+
+		// Keep only citations of passages that were in the prompt, and
+		// report the sources the answer cites
+		attribution := contextbuilder.Attribute(report.Sources, text)
+		if len(attribution.Invalid) > 0 {
+			slog.Warn("removed citations of unknown passages", "question", question, "citations", attribution.Invalid)
+			text = attribution.Answer
+		}
+		if len(report.Sources) > 0 {
+			answer.Sources = nil
+			for _, s := range attribution.Footer() {
+				answer.Sources = append(answer.Sources, s.URL)
+			}
+		}
+	}
+
+	// Trace the citations as passage IDs, before they are restyled
+	answer.Trace = p.Grounder.Trace(result, report.Sources, text)
+	answer.Trace.SetStats(stats)
+	answer.Trace.Regrounded = regrounded
+	answer.Trace.Tokens.Estimate, answer.Trace.Tokens.Budget = report.Used, report.Budget
+
+	if !structured {
+		text = postprocess.Citations{Style: cfg.CitationStyle, Sources: postprocess.References(report.Sources), Accessed: time.Now()}.Apply(text)
+	}
+	answer.Answer = text
+	answer.Thinking = thinking
+	answer.Timings.Generate = time.Since(genStart)
+	answer.Timings.Total = time.Since(start)
+
+	if cacheKey != "" {
+		if err := p.Cache.Put(cacheKey, cache.Entry{Query: question, Model: cfg.ModelName, Answer: text, Sources: answer.Sources}); err != nil {
+			slog.Warn("failed to cache answer", "error", err)
+		}
+	}
+
+	slog.Info("answered", "question", question, "sources", len(answer.Sources),
+		"ground", answer.Timings.Ground, "generate", answer.Timings.Generate, "total", answer.Timings.Total)
+	p.publish(answer)
+	return answer, nil
+}
+
+// knowledgeContext returns the knowledge base excerpts relevant to question
+func (p *Pipeline) knowledgeContext(ctx context.Context, question string) string {
+	results, err := p.Knowledge.Search(ctx, question, p.Config.KnowledgeTopK)
+	if err != nil {
+		slog.Warn("knowledge base lookup failed", "error", err)
+		return ""
+	}
+	relevant := results[:0]
+	for _, r := range results {
+		if r.Score >= p.Config.KnowledgeMinScore {
+			relevant = append(relevant, r)
+		}
+	}
+	return knowledge.BuildContext(relevant)
+}
+
+// publish hands a finished answer to the configured sinks
+func (p *Pipeline) publish(answer *Answer) {
+	p.Sinks.Publish(sinks.Record{
+		Mode:     p.Mode,
+		Model:    p.Config.ModelName,
+		Question: answer.Question,
+		Answer:   answer.Answer,
+		Sources:  answer.Sources,
+	})
+}
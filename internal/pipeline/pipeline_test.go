@@ -0,0 +1,343 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/cache"
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/history"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/testkit"
+)
+
+// newTestPipeline wires a pipeline to fake Ollama and SearXNG servers
+func newTestPipeline(t *testing.T) (*Pipeline, *testkit.Ollama, *testkit.SearXNG) {
+	t.Helper()
+	fakeOllama := testkit.NewOllama(t)
+	fakeSearch := testkit.NewSearXNG(t)
+
+	cfg := config.NewConfig()
+	cfg.ModelName = "test-model"
+	cfg.OllamaURL = fakeOllama.URL
+	cfg.SearXNGURL = fakeSearch.URL
+
+	client := ollama.NewClient(cfg.OllamaURL, 10*time.Second)
+	grounder := &grounding.Grounder{
+		Analyzer:   analyzer.NewLLMAnalyzer(client, cfg.ModelName),
+		Searcher:   searxng.NewClient(cfg.SearXNGURL, 5*time.Second),
+		Fetcher:    crawler.NewCrawler(5*time.Second, 2, 1024*1024, "web-ollama-test"),
+		MaxResults: cfg.MaxResults,
+	}
+	return &Pipeline{Config: cfg, Client: client, Grounder: grounder}, fakeOllama, fakeSearch
+}
+
+func TestAskWithWebSearch(t *testing.T) {
+	p, fakeOllama, fakeSearch := newTestPipeline(t)
+	site := testkit.NewSite(t)
+
+	fakeOllama.SetSearch("berlin weather")
+	fakeOllama.SetAnswer("It is 14°C and cloudy in Berlin.")
+	fakeSearch.SetResults("berlin weather",
+		searxng.SearchResult{Title: "Berlin Weather", URL: site.URL + "/weather.html", Score: 2},
+		searxng.SearchResult{Title: "Broken", URL: site.URL + "/status/500", Score: 1},
+	)
+
+	var streamed strings.Builder
+	answer, err := p.Ask(context.Background(), "What's the weather in Berlin?", nil, func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+
+	if answer.Answer != fakeOllama.Answer {
+		t.Errorf("answer = %q, want %q", answer.Answer, fakeOllama.Answer)
+	}
+	if streamed.String() != answer.Answer {
+		t.Errorf("streamed %q, want the full answer", streamed.String())
+	}
+	if !answer.SearchPerformed {
+		t.Error("expected a search to be performed")
+	}
+	if got := fakeSearch.Queries(); len(got) != 1 || got[0] != "berlin weather" {
+		t.Errorf("searched %v, want [berlin weather]", got)
+	}
+	if len(answer.Sources) != 1 || answer.Sources[0] != site.URL+"/weather.html" {
+		t.Errorf("sources = %v, want only the page that crawled successfully", answer.Sources)
+	}
+	if tr := answer.Trace; tr == nil || !tr.Analyzer.NeedsSearch || len(tr.Sources) != 1 ||
+		tr.Sources[0].SearchScore != 2 || len(tr.Sources[0].Chunks) == 0 || tr.Tokens.Estimate == 0 {
+		t.Errorf("trace = %+v, want the decision and the quoted page with its score", tr)
+	}
+
+	req, ok := fakeOllama.LastStreamed()
+	if !ok {
+		t.Fatal("no generation request reached Ollama")
+	}
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		prompt.WriteString(m.Content)
+	}
+	if !strings.Contains(prompt.String(), "Currently 14°C and cloudy") {
+		t.Error("crawled page content was not passed to the model")
+	}
+}
+
+func TestAskWithoutSearch(t *testing.T) {
+	p, fakeOllama, fakeSearch := newTestPipeline(t)
+
+	answer, err := p.Ask(context.Background(), "Explain recursion", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+
+	if answer.SearchPerformed || len(answer.Sources) != 0 {
+		t.Errorf("expected no search, got performed=%v sources=%v", answer.SearchPerformed, answer.Sources)
+	}
+	if len(fakeSearch.Queries()) != 0 {
+		t.Errorf("unexpected searches: %v", fakeSearch.Queries())
+	}
+	if answer.Answer != fakeOllama.Answer {
+		t.Errorf("answer = %q, want %q", answer.Answer, fakeOllama.Answer)
+	}
+}
+
+func TestAskPostProcessesAnswer(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	p.Grounder = nil
+	post, err := postprocess.New(p.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Post = post
+	fakeOllama.SetAnswer("<think>draft</think>Recursion calls itself[^1].")
+
+	answer, err := p.Ask(context.Background(), "Explain recursion", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if answer.Answer != "Recursion calls itself[1]." {
+		t.Errorf("answer = %q, want the cleaned-up text", answer.Answer)
+	}
+}
+
+func TestAskInStyle(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	p.Grounder = nil
+
+	ctx := WithStyle(context.Background(), prompt.StyleConcise)
+	if _, err := p.Ask(ctx, "Explain recursion", nil, nil); err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	req, ok := fakeOllama.LastStreamed()
+	if !ok {
+		t.Fatal("no generation request reached Ollama")
+	}
+	if got, want := req.Options["num_predict"], float64(p.Config.AnswerTokens/4); got != want {
+		t.Errorf("num_predict = %v, want %v", got, want)
+	}
+	if !strings.Contains(req.Messages[0].Content, prompt.StyleInstruction(prompt.StyleConcise)) {
+		t.Errorf("system prompt lacks the style instruction: %q", req.Messages[0].Content)
+	}
+	if p.Config.AnswerStyle != prompt.StyleDefault {
+		t.Errorf("WithStyle changed the shared config to %q", p.Config.AnswerStyle)
+	}
+}
+
+func TestAskIncludesRecentHistory(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	p.Grounder = nil
+
+	recent := []history.Message{
+		{Role: "user", Content: "My name is Ada."},
+		{Role: "assistant", Content: "Nice to meet you, Ada."},
+	}
+	if _, err := p.Ask(context.Background(), "What is my name?", recent, nil); err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+
+	req, ok := fakeOllama.LastStreamed()
+	if !ok {
+		t.Fatal("no generation request reached Ollama")
+	}
+	if len(req.Messages) < 4 {
+		t.Fatalf("got %d messages, want system + 2 history + question", len(req.Messages))
+	}
+	if req.Messages[0].Role != "system" {
+		t.Errorf("first message role = %q, want system", req.Messages[0].Role)
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "user" || last.Content != "What is my name?" {
+		t.Errorf("last message = %+v, want the question", last)
+	}
+	if len(fakeOllama.Requests()) != 1 {
+		t.Errorf("got %d Ollama requests, want 1 with search disabled", len(fakeOllama.Requests()))
+	}
+}
+
+func TestAskRewritesFollowUpForSearch(t *testing.T) {
+	p, fakeOllama, fakeSearch := newTestPipeline(t)
+	site := testkit.NewSite(t)
+
+	fakeOllama.Decision = `{"standalone_query": "How much does the Framework Laptop 16 cost?", "needs_search": true, "search_queries": ["Framework Laptop 16 price"], "reason": "prices"}`
+	fakeSearch.SetResults("Framework Laptop 16 price",
+		searxng.SearchResult{Title: "Framework Laptop 16", URL: site.URL + "/weather.html", Score: 1},
+	)
+
+	recent := []history.Message{
+		{Role: "user", Content: "What is the Framework Laptop 16?"},
+		{Role: "assistant", Content: "A modular 16-inch laptop."},
+	}
+	answer, err := p.Ask(context.Background(), "how much does it cost?", recent, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if answer.StandaloneQuery != "How much does the Framework Laptop 16 cost?" {
+		t.Errorf("StandaloneQuery = %q", answer.StandaloneQuery)
+	}
+	if len(answer.Sources) != 1 {
+		t.Errorf("sources = %v, want the page found for the rewritten query", answer.Sources)
+	}
+	analysis := fakeOllama.Requests()[0].Messages[0].Content
+	if !strings.Contains(analysis, "User: What is the Framework Laptop 16?") {
+		t.Errorf("analyzer prompt lacks the conversation:\n%s", analysis)
+	}
+}
+
+func TestAskAnswersWhenTurnBudgetRunsOut(t *testing.T) {
+	p, fakeOllama, fakeSearch := newTestPipeline(t)
+	p.Grounder.Budget = 300 * time.Millisecond
+	site := testkit.NewSite(t)
+
+	fakeOllama.SetSearch("berlin weather")
+	fakeSearch.SetResults("berlin weather",
+		searxng.SearchResult{Title: "Berlin Weather", URL: site.URL + "/weather.html", Score: 2},
+		searxng.SearchResult{Title: "Stuck", URL: site.URL + "/slow/forecast", Score: 1},
+	)
+
+	start := time.Now()
+	answer, err := p.Ask(context.Background(), "What's the weather in Berlin?", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Ask took %v, want the slow page cut off by the budget", elapsed)
+	}
+	if len(answer.Sources) != 1 || answer.Sources[0] != site.URL+"/weather.html" {
+		t.Errorf("sources = %v, want the page that was ready in time", answer.Sources)
+	}
+}
+
+func TestAskReportsGenerationFailure(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	fakeOllama.Close()
+
+	if _, err := p.Ask(context.Background(), "anything", nil, nil); err == nil {
+		t.Fatal("expected an error when Ollama is down")
+	}
+}
+
+// failingAnalyzer stands in for an analyzer model that cannot be reached
+type failingAnalyzer struct{}
+
+func (failingAnalyzer) AnalyzeWithLLM(context.Context, string) (analyzer.SearchDecision, error) {
+	return analyzer.SearchDecision{}, errors.New("analyzer model not found")
+}
+
+func TestAskAnswersWhenAnalysisFails(t *testing.T) {
+	p, fakeOllama, fakeSearch := newTestPipeline(t)
+	p.Grounder.Analyzer = failingAnalyzer{}
+
+	answer, err := p.Ask(context.Background(), "Explain recursion", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if answer.Answer != fakeOllama.Answer || answer.SearchPerformed || len(fakeSearch.Queries()) != 0 {
+		t.Errorf("answer = %+v, want an answer without web context", answer)
+	}
+	if !strings.Contains(answer.Trace.Analyzer.Reason, "analyzer model not found") {
+		t.Errorf("trace reason = %q, want the analysis error", answer.Trace.Analyzer.Reason)
+	}
+}
+
+func TestAskUsesAnswerCache(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	p.Cache = cache.NewAnswers(filepath.Join(t.TempDir(), "answers.json"), time.Hour)
+
+	first, err := p.Ask(context.Background(), "Explain recursion", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("first answer should not come from the cache")
+	}
+
+	fakeOllama.SetAnswer("A different answer.")
+	second, err := p.Ask(context.Background(), "explain   recursion?", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if !second.Cached || second.Answer != first.Answer {
+		t.Errorf("repeated question: cached=%v answer=%q, want the cached %q", second.Cached, second.Answer, first.Answer)
+	}
+
+	third, err := p.Ask(context.Background(), "/nocache Explain recursion", nil, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if third.Cached || third.Answer != "A different answer." {
+		t.Errorf("/nocache: cached=%v answer=%q, want a fresh answer", third.Cached, third.Answer)
+	}
+	if third.Question != "Explain recursion" {
+		t.Errorf("question = %q, want /nocache stripped", third.Question)
+	}
+	if again, _ := p.Ask(context.Background(), "Explain recursion", nil, nil); !again.Cached || again.Answer != third.Answer {
+		t.Errorf("after /nocache: answer=%q, want the refreshed %q from the cache", again.Answer, third.Answer)
+	}
+
+	recent := []history.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+	followUp, err := p.Ask(context.Background(), "Explain recursion", recent, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if followUp.Cached {
+		t.Error("questions with conversation history should not use the cache")
+	}
+}
+
+func TestAskAddsKnowledgeExcerpts(t *testing.T) {
+	p, fakeOllama, _ := newTestPipeline(t)
+	p.Grounder = nil
+	p.Config.KnowledgeMinScore = 0.5
+	fakeOllama.SetAnswer("The launch code is in the runbook.")
+
+	kb := knowledge.NewStore(filepath.Join(t.TempDir(), "kb.json"), p.Client, "test-embed")
+	if _, err := kb.Add(context.Background(), "runbook.md", "Runbook", "the launch code lives in the ops runbook"); err != nil {
+		t.Fatal(err)
+	}
+	p.Knowledge = kb
+
+	if _, err := p.Ask(context.Background(), "where is the launch code", nil, nil); err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	req, _ := fakeOllama.LastStreamed()
+	var found bool
+	for _, m := range req.Messages {
+		found = found || strings.Contains(m.Content, "ops runbook")
+	}
+	if !found {
+		t.Error("knowledge base excerpt missing from the prompt")
+	}
+}
@@ -0,0 +1,121 @@
+package postprocess
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cited returns the sources an answer cites with [n] or [n.k], in source
+// order. Citations inside code are ignored.
+func Cited(answer string, sources []Reference) []Reference {
+	byNumber := make(map[int]Reference, len(sources))
+	for _, s := range sources {
+		byNumber[s.Number] = s
+	}
+	cited := make(map[int]bool)
+	code := codeSpan.FindAllStringIndex(answer, -1)
+	for _, m := range citeRun.FindAllStringIndex(answer, -1) {
+		bracket := m[0] + strings.IndexByte(answer[m[0]:m[1]], '[')
+		if bracket > 0 && isWordByte(answer[bracket-1]) || inSpans(code, bracket) {
+			continue
+		}
+		for _, n := range citedNumbers(answer[m[0]:m[1]], byNumber) {
+			cited[n] = true
+		}
+	}
+	var refs []Reference
+	for _, s := range sources {
+		if cited[s.Number] {
+			refs = append(refs, s)
+		}
+	}
+	return refs
+}
+
+// Entry is one source in a bibliography
+type Entry struct {
+	URL      string
+	Title    string
+	Accessed time.Time // when an answer citing it first fetched it
+	Answers  int       // how many answers cite it
+}
+
+// Bibliography collects the sources cited across a conversation, listing
+// each page once however many answers cite it or however its URL was written
+type Bibliography struct {
+	entries []Entry
+	byKey   map[string]int
+}
+
+// Add records the sources one answer cites, fetched at accessed
+func (b *Bibliography) Add(refs []Reference, accessed time.Time) {
+	if b.byKey == nil {
+		b.byKey = make(map[string]int)
+	}
+	for _, r := range refs {
+		key := bibliographyKey(r.URL)
+		i, ok := b.byKey[key]
+		if !ok {
+			b.byKey[key] = len(b.entries)
+			b.entries = append(b.entries, Entry{URL: r.URL, Title: r.Title, Accessed: accessed, Answers: 1})
+			continue
+		}
+		e := &b.entries[i]
+		e.Answers++
+		if accessed.Before(e.Accessed) {
+			e.Accessed = accessed
+		}
+		if e.Title == "" {
+			e.Title = r.Title
+		}
+	}
+}
+
+// Entries returns the sources in the order they were first cited
+func (b *Bibliography) Entries() []Entry {
+	return append([]Entry(nil), b.entries...)
+}
+
+// Markdown renders the bibliography as a reference list: APA entries in
+// site order for the apa style, otherwise a numbered list in order of
+// first citation. It returns "" when nothing was cited.
+func (b *Bibliography) Markdown(style string) string {
+	if len(b.entries) == 0 {
+		return ""
+	}
+	entries := b.Entries()
+	lines := make([]string, len(entries))
+	if style == CiteAPA {
+		sort.SliceStable(entries, func(i, j int) bool { return siteName(entries[i].URL) < siteName(entries[j].URL) })
+		for i, e := range entries {
+			lines[i] = fmt.Sprintf("%s. (n.d.). *%s*. Retrieved %s, from %s",
+				siteName(e.URL), referenceTitle(Reference{URL: e.URL, Title: e.Title}), e.Accessed.Format("January 2, 2006"), e.URL)
+		}
+		return strings.Join(lines, "\n\n") + "\n"
+	}
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("%d. [%s](%s), first accessed %s", i+1,
+			referenceTitle(Reference{URL: e.URL, Title: e.Title}), e.URL, e.Accessed.Format("2 January 2006 15:04"))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// bibliographyKey identifies a page whatever the case of its host, a
+// leading www., a trailing slash or a fragment
+func bibliographyKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.Fragment = ""
+	return u.String()
+}
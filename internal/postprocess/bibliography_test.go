@@ -0,0 +1,54 @@
+package postprocess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCited(t *testing.T) {
+	got := Cited("Go 1.22 shipped [3][1.2]. Use `arr[2]`, not x[2]. See [9].", citeSources)
+	if len(got) != 2 || got[0].Number != 1 || got[1].Number != 3 {
+		t.Errorf("Cited = %+v, want sources 1 and 3", got)
+	}
+}
+
+func TestBibliographyDeduplicates(t *testing.T) {
+	first := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	later := first.Add(time.Hour)
+
+	var b Bibliography
+	b.Add([]Reference{{URL: "https://www.example.com/loops/", Title: "Loop variables"}, {URL: "https://go.dev/blog/go1.22"}}, later)
+	b.Add([]Reference{{URL: "http://example.com/loops#top"}, {URL: "https://go.dev/blog/go1.22", Title: "Go 1.22 is released!"}}, first)
+
+	entries := b.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries = %+v, want 2", entries)
+	}
+	loops := entries[0]
+	if loops.URL != "https://www.example.com/loops/" || loops.Answers != 2 || !loops.Accessed.Equal(first) {
+		t.Errorf("loops = %+v, want the first URL, 2 answers, accessed at %v", loops, first)
+	}
+	if entries[1].Title != "Go 1.22 is released!" {
+		t.Errorf("title = %q, want the title filled in by the later answer", entries[1].Title)
+	}
+}
+
+func TestBibliographyMarkdown(t *testing.T) {
+	accessed := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	var b Bibliography
+	if b.Markdown(CiteInline) != "" {
+		t.Error("empty bibliography rendered")
+	}
+	b.Add([]Reference{citeSources[1], citeSources[0]}, accessed)
+
+	want := "1. [Loop variables](https://www.example.com/loops), first accessed 16 October 2026 09:30\n" +
+		"2. [Go 1.22 is released!](https://go.dev/blog/go1.22), first accessed 16 October 2026 09:30\n"
+	if got := b.Markdown(CiteInline); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	want = "example.com. (n.d.). *Loop variables*. Retrieved October 16, 2026, from https://www.example.com/loops\n\n" +
+		"go.dev. (n.d.). *Go 1.22 is released!*. Retrieved October 16, 2026, from https://go.dev/blog/go1.22\n"
+	if got := b.Markdown(CiteAPA); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package postprocess
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ollama/internal/contextbuilder"
+)
+
+// Citation styles, chosen with citation_style
+const (
+	CiteInline   = "inline"   // [1] and [1.2], with the source list under the answer
+	CiteFootnote = "footnote" // Markdown footnotes [^1] defined after the answer
+	CiteAPA      = "apa"      // (site, n.d.) in the text and a reference list with access dates
+)
+
+// Reference is a numbered source an answer may cite
+type Reference struct {
+	Number int
+	URL    string
+	Title  string
+}
+
+// Citations rewrites the [n] and [n.k] citations of an answer in a style
+type Citations struct {
+	Style    string
+	Sources  []Reference
+	Accessed time.Time // when the sources were fetched, for the APA reference list
+	Label    string    // prefixes footnote labels, keeping them unique when several answers share a document
+}
+
+var (
+	citeRun  = regexp.MustCompile(`(?:[ \t]*\[\d+(?:\.\d+)?\])+`)
+	citeOne  = regexp.MustCompile(`\[(\d+)(?:\.\d+)?\]`)
+	codeSpan = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+)
+
+// Apply returns answer with its citations in c.Style, followed by the
+// footnotes or reference list for the sources it cites. Answers citing no
+// known source, and the inline style, are returned unchanged.
+func (c Citations) Apply(answer string) string {
+	if c.Style != CiteFootnote && c.Style != CiteAPA {
+		return answer
+	}
+	byNumber := make(map[int]Reference, len(c.Sources))
+	for _, s := range c.Sources {
+		byNumber[s.Number] = s
+	}
+	authors := c.apaAuthors()
+
+	cited := make(map[int]bool)
+	code := codeSpan.FindAllStringIndex(answer, -1)
+	var out strings.Builder
+	last := 0
+	for _, m := range citeRun.FindAllStringIndex(answer, -1) {
+		bracket := m[0] + strings.IndexByte(answer[m[0]:m[1]], '[')
+		if bracket > 0 && isWordByte(answer[bracket-1]) || inSpans(code, bracket) {
+			continue
+		}
+		numbers := citedNumbers(answer[m[0]:m[1]], byNumber)
+		if len(numbers) == 0 {
+			continue
+		}
+		out.WriteString(answer[last:m[0]])
+		last = m[1]
+		for _, n := range numbers {
+			cited[n] = true
+		}
+		if c.Style == CiteFootnote {
+			for _, n := range numbers {
+				fmt.Fprintf(&out, "[^%s%d]", c.Label, n)
+			}
+			continue
+		}
+		parts := make([]string, len(numbers))
+		for i, n := range numbers {
+			parts[i] = authors[n].site + ", " + authors[n].date
+		}
+		out.WriteString(" (" + strings.Join(parts, "; ") + ")")
+	}
+	if len(cited) == 0 {
+		return answer
+	}
+	out.WriteString(answer[last:])
+
+	var refs []Reference
+	for _, s := range c.Sources {
+		if cited[s.Number] {
+			refs = append(refs, s)
+		}
+	}
+	text := strings.TrimRight(out.String(), "\n") + "\n\n"
+	if c.Style == CiteFootnote {
+		lines := make([]string, len(refs))
+		for i, r := range refs {
+			lines[i] = fmt.Sprintf("[^%s%d]: [%s](%s)", c.Label, r.Number, referenceTitle(r), r.URL)
+		}
+		return text + strings.Join(lines, "\n")
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool { return authors[refs[i].Number].site < authors[refs[j].Number].site })
+	entries := make([]string, len(refs))
+	for i, r := range refs {
+		a := authors[r.Number]
+		entries[i] = fmt.Sprintf("%s. (%s). *%s*. Retrieved %s, from %s",
+			a.site, a.date, referenceTitle(r), c.Accessed.Format("January 2, 2006"), r.URL)
+	}
+	return text + "**References**\n\n" + strings.Join(entries, "\n\n")
+}
+
+// citedNumbers returns the known sources a run of adjacent citations names,
+// in order and without repeats
+func citedNumbers(run string, byNumber map[int]Reference) []int {
+	var numbers []int
+	for _, m := range citeOne.FindAllStringSubmatch(run, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if _, ok := byNumber[n]; !ok || containsInt(numbers, n) {
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// apaAuthor is how a source is credited in the APA style
+type apaAuthor struct {
+	site string // the stand-in for an author
+	date string // "n.d.", since pages rarely carry a reliable date
+}
+
+// apaAuthors credits each source to its site. Sources sharing a site get
+// n.d.-a, n.d.-b, ... in source order, as APA does for one author's
+// undated works.
+func (c Citations) apaAuthors() map[int]apaAuthor {
+	authors := make(map[int]apaAuthor, len(c.Sources))
+	bySite := make(map[string][]int)
+	for _, s := range c.Sources {
+		site := siteName(s.URL)
+		authors[s.Number] = apaAuthor{site: site, date: "n.d."}
+		bySite[site] = append(bySite[site], s.Number)
+	}
+	for site, numbers := range bySite {
+		if len(numbers) < 2 {
+			continue
+		}
+		for i, n := range numbers {
+			authors[n] = apaAuthor{site: site, date: fmt.Sprintf("n.d.-%c", 'a'+i%26)}
+		}
+	}
+	return authors
+}
+
+// siteName is the host of rawURL without a leading www.
+func siteName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func referenceTitle(r Reference) string {
+	if title := strings.Join(strings.Fields(r.Title), " "); title != "" {
+		return title
+	}
+	return r.URL
+}
+
+// References lists the numbered sources of a prompt for citation
+func References(sources []contextbuilder.Source) []Reference {
+	refs := make([]Reference, len(sources))
+	for i, s := range sources {
+		refs[i] = Reference{Number: s.Number, URL: s.URL, Title: s.Title}
+	}
+	return refs
+}
+
+func inSpans(spans [][]int, i int) bool {
+	for _, s := range spans {
+		if i >= s[0] && i < s[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
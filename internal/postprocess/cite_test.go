@@ -0,0 +1,47 @@
+package postprocess
+
+import (
+	"testing"
+	"time"
+)
+
+var citeSources = []Reference{
+	{Number: 1, URL: "https://go.dev/blog/go1.22", Title: "Go 1.22 is released!"},
+	{Number: 2, URL: "https://www.example.com/loops", Title: "Loop variables"},
+	{Number: 3, URL: "https://go.dev/doc/go1.22", Title: "Go 1.22 Release Notes"},
+}
+
+func TestFootnoteCitations(t *testing.T) {
+	c := Citations{Style: CiteFootnote, Sources: citeSources, Label: "4-"}
+	in := "Go 1.22 shipped in February [1.2][1.3], with per-iteration loops [2]. Use `arr[1]`, not arr[2]."
+	want := "Go 1.22 shipped in February[^4-1], with per-iteration loops[^4-2]. Use `arr[1]`, not arr[2].\n\n" +
+		"[^4-1]: [Go 1.22 is released!](https://go.dev/blog/go1.22)\n" +
+		"[^4-2]: [Loop variables](https://www.example.com/loops)"
+	if got := c.Apply(in); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestAPACitations(t *testing.T) {
+	c := Citations{Style: CiteAPA, Sources: citeSources, Accessed: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	in := "Go 1.22 shipped in February [1][3], with per-iteration loops [2]."
+	want := "Go 1.22 shipped in February (go.dev, n.d.-a; go.dev, n.d.-b), with per-iteration loops (example.com, n.d.).\n\n" +
+		"**References**\n\n" +
+		"example.com. (n.d.). *Loop variables*. Retrieved October 16, 2026, from https://www.example.com/loops\n\n" +
+		"go.dev. (n.d.-a). *Go 1.22 is released!*. Retrieved October 16, 2026, from https://go.dev/blog/go1.22\n\n" +
+		"go.dev. (n.d.-b). *Go 1.22 Release Notes*. Retrieved October 16, 2026, from https://go.dev/doc/go1.22"
+	if got := c.Apply(in); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestInlineAndUncitedAnswersAreUnchanged(t *testing.T) {
+	in := "Go 1.22 shipped in February [1]."
+	if got := (Citations{Style: CiteInline, Sources: citeSources}).Apply(in); got != in {
+		t.Errorf("inline style changed the answer: %q", got)
+	}
+	uncited := "Go 1.22 shipped in February [7]."
+	if got := (Citations{Style: CiteAPA, Sources: citeSources}).Apply(uncited); got != uncited {
+		t.Errorf("answer citing no known source changed: %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+// Package postprocess cleans up completed answers before they are shown and
+// stored
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"web-ollama/internal/config"
+)
+
+// TruncatedNotice is appended to answers cut by the length limit
+const TruncatedNotice = "\n\n[answer truncated]"
+
+// Step is one named transformation of an answer
+type Step struct {
+	Name  string
+	Apply func(answer string) string
+}
+
+// Processor runs its steps in order; a nil Processor leaves answers alone
+type Processor struct {
+	steps []Step
+}
+
+// New builds the processor described by cfg: think tags are stripped first,
+// then rewrites, citation normalization and the TL;DR clean-up run, and the
+// length limit is applied last
+func New(cfg *config.Config) (*Processor, error) {
+	p := &Processor{}
+	if cfg.StripThinkTags {
+		p.Add(Step{Name: "strip-think", Apply: StripThinkTags})
+	}
+	for i, rw := range cfg.AnswerRewrites {
+		step, err := Rewrite(rw.Pattern, rw.Replace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile answer rewrite %d: %w", i, err)
+		}
+		p.Add(step)
+	}
+	if cfg.NormalizeCitations {
+		p.Add(Step{Name: "citations", Apply: NormalizeCitations})
+	}
+	if cfg.TLDR {
+		p.Add(Step{Name: "tldr", Apply: TidyTLDR})
+	}
+	if cfg.MaxAnswerChars > 0 {
+		p.Add(Truncate(cfg.MaxAnswerChars))
+	}
+	return p, nil
+}
+
+// Add appends a step
+func (p *Processor) Add(step Step) {
+	p.steps = append(p.steps, step)
+}
+
+// Process runs every step over answer and returns the result along with the
+// names of the steps that changed it
+func (p *Processor) Process(answer string) (string, []string) {
+	if p == nil {
+		return answer, nil
+	}
+	var changed []string
+	for _, step := range p.steps {
+		if out := step.Apply(answer); out != answer {
+			answer = out
+			changed = append(changed, step.Name)
+		}
+	}
+	return answer, changed
+}
+
+var (
+	thinkBlock = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	thinkTag   = regexp.MustCompile(`</?think>`)
+)
+
+// StripThinkTags removes reasoning that leaked into the answer: complete
+// <think> blocks, everything before a closing tag whose opening tag was
+// consumed by the chat template, and any stray tags
+func StripThinkTags(answer string) string {
+	if !strings.Contains(answer, "think>") {
+		return answer
+	}
+	out := thinkBlock.ReplaceAllString(answer, "")
+	if i := strings.LastIndex(out, "</think>"); i >= 0 {
+		out = out[i+len("</think>"):]
+	}
+	return strings.TrimSpace(thinkTag.ReplaceAllString(out, ""))
+}
+
+var (
+	footnoteCite = regexp.MustCompile(`\[\^(\d+(?:\.\d+)?)\]`)
+	bracketCite  = regexp.MustCompile(`【(\d+(?:\.\d+)?)(?:†[^】]*)?】`)
+	wordCite     = regexp.MustCompile(`\[(?i:source|ref|citation)\s*#?(\d+(?:\.\d+)?)\]`)
+	listCite     = regexp.MustCompile(`\[(\d+(?:\.\d+)?(?:\s*[,;]\s*\d+(?:\.\d+)?)+)\]`)
+	listSep      = regexp.MustCompile(`\s*[,;]\s*`)
+)
+
+// NormalizeCitations rewrites the citation styles models drift into
+// ([^1], 【1†source】, [Source 1], [1, 2.3]) as the [1][2.3] form the prompt
+// asks for
+func NormalizeCitations(answer string) string {
+	if !strings.ContainsAny(answer, "[【") {
+		return answer
+	}
+	out := footnoteCite.ReplaceAllString(answer, "[$1]")
+	out = bracketCite.ReplaceAllString(out, "[$1]")
+	out = wordCite.ReplaceAllString(out, "[$1]")
+	return listCite.ReplaceAllStringFunc(out, func(m string) string {
+		nums := listSep.Split(strings.Trim(m, "[]"), -1)
+		return "[" + strings.Join(nums, "][") + "]"
+	})
+}
+
+// Truncate returns a step that cuts answers longer than max characters,
+// preferring the end of a paragraph or sentence in the last half. A TL;DR
+// at the end is not counted and is kept after the cut.
+func Truncate(max int) Step {
+	return Step{Name: "max-length", Apply: func(answer string) string {
+		body, tldr := SplitTLDR(answer)
+		runes := []rune(body)
+		if len(runes) <= max {
+			return answer
+		}
+		cut := string(runes[:max])
+		if i := strings.LastIndex(cut, "\n\n"); i >= len(cut)/2 {
+			cut = cut[:i]
+		} else if i := lastSentenceEnd(cut); i >= len(cut)/2 {
+			cut = cut[:i+1]
+		}
+		return WithTLDR(strings.TrimSpace(cut)+TruncatedNotice, tldr)
+	}}
+}
+
+// lastSentenceEnd returns the index of the last ., ! or ? followed by a space
+func lastSentenceEnd(s string) int {
+	for i := len(s) - 2; i >= 0; i-- {
+		if strings.IndexByte(".!?", s[i]) >= 0 && (s[i+1] == ' ' || s[i+1] == '\n') {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rewrite returns a step replacing every match of pattern with replace
+func Rewrite(pattern, replace string) (Step, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{Name: "rewrite " + pattern, Apply: func(answer string) string {
+		return re.ReplaceAllString(answer, replace)
+	}}, nil
+}
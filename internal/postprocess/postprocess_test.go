@@ -0,0 +1,80 @@
+package postprocess
+
+import (
+	"strings"
+	"testing"
+
+	"web-ollama/internal/config"
+)
+
+func TestStripThinkTags(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"<think>hmm</think>\n\nParis.", "Paris."},
+		{"the user wants a city</think>Paris.", "Paris."},
+		{"Paris.<think>", "Paris."},
+		{"Paris.", "Paris."},
+	}
+	for _, tt := range tests {
+		if got := StripThinkTags(tt.in); got != tt.want {
+			t.Errorf("StripThinkTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCitations(t *testing.T) {
+	in := "Go 1.22 shipped[^1] in February【2†source】, per [Source 3] and [1, 4; 5]. Arrays[0] stay."
+	want := "Go 1.22 shipped[1] in February[2], per [3] and [1][4][5]. Arrays[0] stay."
+	if got := NormalizeCitations(in); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	in = "Loop variables are per-iteration[^2.1], see [1.3, 2.2] and 【1.1†source】."
+	want = "Loop variables are per-iteration[2.1], see [1.3][2.2] and [1.1]."
+	if got := NormalizeCitations(in); got != want {
+		t.Errorf("passage IDs: got  %q\nwant %q", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	step := Truncate(40)
+	if got := step.Apply("short"); got != "short" {
+		t.Errorf("short answer changed: %q", got)
+	}
+
+	got := step.Apply("First sentence is here. Second sentence runs past the limit.")
+	if got != "First sentence is here."+TruncatedNotice {
+		t.Errorf("got %q", got)
+	}
+
+	got = step.Apply(strings.Repeat("é", 50))
+	if !strings.HasPrefix(got, strings.Repeat("é", 40)) || !strings.HasSuffix(got, TruncatedNotice) {
+		t.Errorf("multi-byte cut went wrong: %q", got)
+	}
+}
+
+func TestProcessorFromConfig(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AnswerRewrites = []config.AnswerRewrite{{Pattern: `(?i)as an ai language model, `, Replace: ""}}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, changed := p.Process("<think>x</think>As an AI language model, I think so [^1].")
+	if out != "I think so [1]." {
+		t.Errorf("got %q", out)
+	}
+	if len(changed) != 3 {
+		t.Errorf("changed steps = %v, want 3", changed)
+	}
+
+	var none *Processor
+	if out, _ := none.Process("as is"); out != "as is" {
+		t.Error("nil processor should not change answers")
+	}
+
+	cfg.AnswerRewrites = []config.AnswerRewrite{{Pattern: "("}}
+	if _, err := New(cfg); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,51 @@
+package postprocess
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// tldrHeading matches the line a TL;DR starts on, however the model
+	// dressed it up: "TL;DR:", "**TL;DR:**", "## TL;DR", "TLDR -"
+	tldrHeading = regexp.MustCompile(`(?im)^[ \t>]*(?:#{1,6}[ \t]*)?(?:\*\*|__)?TL;?DR\b(?:\*\*|__)?[ \t]*[:\-–—]?[ \t]*(?:\*\*|__)?[ \t]*`)
+	tldrMarkup  = regexp.MustCompile("[*]+|`+|__")
+)
+
+// SplitTLDR separates the TL;DR the prompt asks for after long answers from
+// the answer. It returns the answer without it and the summary as plain
+// sentences, without citations or markdown, for reading aloud. An answer
+// without a TL;DR, or with one inside code, is returned unchanged with "".
+func SplitTLDR(answer string) (string, string) {
+	matches := tldrHeading.FindAllStringIndex(answer, -1)
+	if len(matches) == 0 {
+		return answer, ""
+	}
+	m := matches[len(matches)-1]
+	if inSpans(codeSpan.FindAllStringIndex(answer, -1), m[0]) {
+		return answer, ""
+	}
+	summary := citeRun.ReplaceAllString(answer[m[1]:], "")
+	summary = strings.Join(strings.Fields(tldrMarkup.ReplaceAllString(summary, "")), " ")
+	if summary == "" {
+		return answer, ""
+	}
+	body := strings.TrimSpace(answer[:m[0]])
+	body = strings.TrimSpace(strings.TrimSuffix(body, "---"))
+	return body, summary
+}
+
+// TidyTLDR rewrites the TL;DR at the end of an answer as a plain
+// "TL;DR: ..." paragraph, without citations or markdown
+func TidyTLDR(answer string) string {
+	return WithTLDR(SplitTLDR(answer))
+}
+
+// WithTLDR puts a TL;DR taken out by SplitTLDR back at the end of answer,
+// for storing the answer whole
+func WithTLDR(answer, summary string) string {
+	if summary == "" {
+		return answer
+	}
+	return answer + "\n\nTL;DR: " + summary
+}
@@ -0,0 +1,59 @@
+package postprocess
+
+import (
+	"strings"
+	"testing"
+
+	"web-ollama/internal/config"
+)
+
+func TestSplitTLDR(t *testing.T) {
+	tests := []struct{ in, body, tldr string }{
+		{
+			"Go 1.22 changed loop variables[1].\n\nTL;DR: Loop variables are now per-iteration [1]. Old code keeps working.",
+			"Go 1.22 changed loop variables[1].",
+			"Loop variables are now per-iteration. Old code keeps working.",
+		},
+		{
+			"Long answer.\n\n---\n\n**TL;DR:** Use `go vet` to\nfind **loop** bugs[2.1].",
+			"Long answer.",
+			"Use go vet to find loop bugs.",
+		},
+		{"Long answer.\n\n## TLDR\nShort summary.", "Long answer.", "Short summary."},
+		{"Short answer.", "Short answer.", ""},
+		{"TLDRs are everywhere.", "TLDRs are everywhere.", ""},
+		{"Example:\n```\nTL;DR: not a summary\n```", "Example:\n```\nTL;DR: not a summary\n```", ""},
+		{"Answer.\n\nTL;DR:", "Answer.\n\nTL;DR:", ""},
+	}
+	for _, tt := range tests {
+		body, tldr := SplitTLDR(tt.in)
+		if body != tt.body || tldr != tt.tldr {
+			t.Errorf("SplitTLDR(%q) = %q, %q; want %q, %q", tt.in, body, tldr, tt.body, tt.tldr)
+		}
+	}
+}
+
+func TestWithTLDR(t *testing.T) {
+	if got := WithTLDR("Answer.", "Summary."); got != "Answer.\n\nTL;DR: Summary." {
+		t.Errorf("WithTLDR = %q", got)
+	}
+	if got := WithTLDR("Answer.", ""); got != "Answer." {
+		t.Errorf("WithTLDR without summary = %q", got)
+	}
+}
+
+func TestTLDRSurvivesTruncation(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.TLDR = true
+	cfg.MaxAnswerChars = 40
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Repeat("Long details here. ", 10) + "\n\n**TL;DR:** It is long [1]."
+	got, changed := p.Process(in)
+	body, tldr := SplitTLDR(got)
+	if tldr != "It is long." || !strings.HasSuffix(body, TruncatedNotice) || len(changed) != 2 {
+		t.Errorf("Process = %q (changed %v)", got, changed)
+	}
+}
@@ -0,0 +1,58 @@
+package prompt
+
+// Answer styles, chosen with --style or /style
+const (
+	StyleDefault  = "default"
+	StyleConcise  = "concise"
+	StyleDetailed = "detailed"
+	StyleBullet   = "bullet"
+)
+
+// Styles lists the answer styles in the order they are offered
+var Styles = []string{StyleDefault, StyleConcise, StyleDetailed, StyleBullet}
+
+// style is how an answer style changes a chat request
+type style struct {
+	instruction string // appended to the system prompt
+	share       int    // num_predict is answer_tokens/share; 0 leaves the model's default
+}
+
+var styles = map[string]style{
+	StyleDefault: {},
+	StyleConcise: {
+		instruction: "Answer concisely: give the direct answer in one to three sentences, without preamble or recap.",
+		share:       4,
+	},
+	StyleDetailed: {
+		instruction: "Answer in depth: explain the background, cover the relevant details and caveats, and organize longer answers with headings.",
+		share:       1,
+	},
+	StyleBullet: {
+		instruction: "Answer as a list of short bullet points, one fact per bullet, without an introduction or conclusion.",
+		share:       2,
+	},
+}
+
+// TLDRInstruction is appended to the system prompt of chat turns with tldr
+// on. The summary is tidied by the post-processor and shown apart from the
+// answer.
+const TLDRInstruction = `If your answer is longer than a short paragraph, end it with a line starting "TL;DR:" followed by a summary in two or three plain sentences that read well aloud: no citations, lists, markdown, URLs or code.`
+
+// ValidStyle reports whether name is an answer style
+func ValidStyle(name string) bool {
+	_, ok := styles[name]
+	return ok
+}
+
+// StyleInstruction returns what an answer style adds to the system prompt
+func StyleInstruction(name string) string {
+	return styles[name].instruction
+}
+
+// ApplyStyle sets num_predict in options for an answer style. The limit is
+// a share of answerTokens, generous enough for models that think first.
+func ApplyStyle(options map[string]interface{}, name string, answerTokens int) {
+	if s := styles[name]; s.share > 0 && answerTokens > 0 {
+		options["num_predict"] = answerTokens / s.share
+	}
+}
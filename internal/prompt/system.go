@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SystemVars holds the variables available to the system prompt template
+type SystemVars struct {
+	Date                string   // Current date, e.g. "2006-01-02"
+	Time                string   // Current local time, e.g. "15:04"
+	Model               string   // Name of the model answering
+	MemoryFacts         []string // Facts about the user from the config file
+	SearchEnabled       bool     // Whether automatic web search is turned on
+	HasSearchContext    bool     // Whether web results are attached to this turn
+	HasFileContext      bool     // Whether @file contents are attached to this turn
+	HasKnowledgeContext bool     // Whether knowledge base excerpts are attached to this turn
+}
+
+// NewSystemVars fills in the date and time fields from now
+func NewSystemVars(now time.Time, model string) SystemVars {
+	return SystemVars{
+		Date:  now.Format("2006-01-02"),
+		Time:  now.Format("15:04"),
+		Model: model,
+	}
+}
+
+// RenderSystem executes a system prompt template with the given variables
+func RenderSystem(tmpl string, vars SystemVars) (string, error) {
+	t, err := template.New("system").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid system prompt template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("failed to render system prompt: %w", err)
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,174 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"web-ollama/internal/ui"
+)
+
+// version of the recording file format
+const version = 1
+
+// Header is the first line of a recording file
+type Header struct {
+	Version int       `json:"version"`
+	Model   string    `json:"model"`
+	Started time.Time `json:"started"`
+}
+
+// Entry is a display event and when it happened, relative to the start
+type Entry struct {
+	Offset time.Duration `json:"t"`
+	ui.Event
+}
+
+// Recorder writes display events to a JSON Lines file as they happen
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	enc     *json.Encoder
+	path    string
+	start   time.Time
+	entries int
+	redact  func(string) string
+}
+
+// Start creates the recording file and writes its header. redact, if set,
+// is applied to all recorded text.
+func Start(path, model string, redact func(string) string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	r := &Recorder{file: file, enc: json.NewEncoder(file), path: path, start: time.Now(), redact: redact}
+	if err := r.enc.Encode(Header{Version: version, Model: model, Started: r.start}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write recording: %w", err)
+	}
+	return r, nil
+}
+
+// DefaultPath returns a timestamped file name in dir
+func DefaultPath(dir string, now time.Time) string {
+	return filepath.Join(dir, now.Format("2006-01-02-150405")+".jsonl")
+}
+
+// Record appends an event; write errors are ignored so recording never
+// interrupts the session
+func (r *Recorder) Record(e ui.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	if r.redact != nil {
+		e.Text = r.redact(e.Text)
+	}
+	if r.enc.Encode(Entry{Offset: time.Since(r.start), Event: e}) == nil {
+		r.entries++
+	}
+}
+
+// Path returns the recording file path
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Entries returns the number of events recorded so far
+func (r *Recorder) Entries() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries
+}
+
+// Close finishes the recording
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close recording: %w", err)
+	}
+	return nil
+}
+
+// Load reads a recording file
+func Load(path string) (Header, []Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var header Header
+	if !scanner.Scan() {
+		return Header{}, nil, fmt.Errorf("recording %s is empty", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == 0 {
+		return Header{}, nil, fmt.Errorf("%s is not a web-ollama recording", path)
+	}
+	if header.Version > version {
+		return Header{}, nil, fmt.Errorf("recording format v%d is newer than this build supports", header.Version)
+	}
+
+	var entries []Entry
+	for line := 2; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return Header{}, nil, fmt.Errorf("failed to parse recording line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return Header{}, nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return header, entries, nil
+}
+
+// Player re-renders recorded events
+type Player struct {
+	Display *ui.EnhancedDisplay
+	Speed   float64       // 1 replays in real time, 2 twice as fast; 0 or less is instant
+	MaxGap  time.Duration // longest pause between events; 0 means no cap
+}
+
+// Play shows entries with their original pacing until done or ctx is cancelled
+func (p *Player) Play(ctx context.Context, entries []Entry) error {
+	var last time.Duration
+	for _, e := range entries {
+		if p.Speed > 0 {
+			wait := time.Duration(float64(e.Offset-last) / p.Speed)
+			if p.MaxGap > 0 && wait > p.MaxGap {
+				wait = p.MaxGap
+			}
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		last = e.Offset
+		p.Display.Replay(e.Event)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package recording
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/ui"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "session.jsonl")
+	redact := func(s string) string { return strings.ReplaceAll(s, "sk-secret", "[REDACTED]") }
+
+	r, err := Start(path, "test-model", redact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asked := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	r.Record(ui.Event{Kind: "user", Text: "my key is sk-secret", Time: asked})
+	r.Record(ui.Event{Kind: "answer", Text: "Hello"})
+	r.Record(ui.Event{Kind: "end", Sources: []string{"https://a.example"}, Duration: 2 * time.Second})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r.Record(ui.Event{Kind: "info", Text: "after close"}) // ignored
+
+	header, entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Model != "test-model" || header.Version != version {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if len(entries) != 3 || r.Entries() != 3 {
+		t.Fatalf("got %d entries (recorder says %d), want 3", len(entries), r.Entries())
+	}
+	if entries[0].Text != "my key is [REDACTED]" || !entries[0].Time.Equal(asked) {
+		t.Errorf("unexpected user entry: %+v", entries[0])
+	}
+	if end := entries[2]; end.Duration != 2*time.Second || len(end.Sources) != 1 {
+		t.Errorf("unexpected end entry: %+v", end)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Offset < entries[i-1].Offset {
+			t.Errorf("offsets not increasing: %v then %v", entries[i-1].Offset, entries[i].Offset)
+		}
+	}
+}
+
+func TestLoadRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.jsonl")
+	if err := os.WriteFile(path, []byte(`{"question":"hi"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Load(path); err == nil {
+		t.Error("expected an error for a file that is not a recording")
+	}
+}
+
+func TestPlayCapsPauses(t *testing.T) {
+	entries := []Entry{
+		{Offset: 0, Event: ui.Event{Kind: "info", Text: "first"}},
+		{Offset: time.Hour, Event: ui.Event{Kind: "info", Text: "an hour later"}},
+	}
+	p := &Player{Display: ui.NewEnhancedDisplay(false), Speed: 1, MaxGap: 10 * time.Millisecond}
+
+	start := time.Now()
+	if err := p.Play(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("replay took %v despite MaxGap", elapsed)
+	}
+}
+
+func TestPlayStopsOnCancel(t *testing.T) {
+	entries := []Entry{{Offset: time.Hour, Event: ui.Event{Kind: "info", Text: "never shown"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := &Player{Display: ui.NewEnhancedDisplay(false), Speed: 1}
+	if err := p.Play(ctx, entries); err == nil {
+		t.Error("expected Play to stop when the context is cancelled")
+	}
+}
@@ -0,0 +1,364 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/trust"
+)
+
+// ChatClient is the subset of the Ollama client used for research
+type ChatClient interface {
+	ChatSync(ctx context.Context, model string, messages interface{}) (string, error)
+	Chat(ctx context.Context, req ollama.ChatRequest, onChunk func(string)) (string, error)
+}
+
+// Searcher finds candidate pages for a query
+type Searcher interface {
+	Search(ctx context.Context, query string, maxResults int) ([]searxng.SearchResult, error)
+}
+
+// Fetcher downloads and extracts pages
+type Fetcher interface {
+	CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult
+}
+
+// Subtopic is one section of the research plan
+type Subtopic struct {
+	Title   string   `json:"title"`
+	Queries []string `json:"queries"`
+}
+
+// Plan is the model's breakdown of a research topic
+type Plan struct {
+	Subtopics []Subtopic `json:"subtopics"`
+}
+
+// Source is a crawled page numbered for citation
+type Source struct {
+	Number   int
+	URL      string
+	Title    string
+	Content  string
+	Subtopic int // index into Plan.Subtopics
+}
+
+// Report is the outcome of a research run
+type Report struct {
+	Topic   string
+	Plan    Plan
+	Sources []Source
+	Body    string // Markdown including the references section
+}
+
+// Researcher runs plan → gather → write research sessions
+type Researcher struct {
+	Client         ChatClient
+	Searcher       Searcher
+	Fetcher        Fetcher
+	Model          string
+	Options        map[string]interface{}
+	MaxResults     int            // search results crawled per query
+	Rounds         int            // search rounds per subtopic
+	MaxSubtopics   int            // cap on planned subtopics
+	BlockedDomains []string       // never crawl these hosts
+	Trust          *trust.Weights // ranks results by domain; nil keeps search order
+	Guard          *guard.Filter  // blocks unwanted domains and prompt injections; may be nil
+
+	// Progress receives short status lines; OnChunk receives report tokens
+	Progress func(string)
+	OnChunk  func(string)
+}
+
+// maxSourceWords limits how much of each page goes into the writing context
+const maxSourceWords = 300
+
+// Run researches a topic and returns the finished report
+func (r *Researcher) Run(ctx context.Context, topic string) (*Report, error) {
+	r.progress("Planning research")
+	plan, err := r.plan(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	for i, st := range plan.Subtopics {
+		r.progress(fmt.Sprintf("Subtopic %d: %s", i+1, st.Title))
+	}
+
+	report := &Report{Topic: topic, Plan: plan}
+	seen := make(map[string]bool)
+
+	for i, st := range plan.Subtopics {
+		queries := st.Queries
+		for round := 1; round <= r.Rounds && len(queries) > 0; round++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			r.progress(fmt.Sprintf("[%s] round %d: %s", st.Title, round, strings.Join(queries, " | ")))
+			added := r.gather(ctx, queries, i, seen, report)
+
+			if round == r.Rounds {
+				break
+			}
+			queries = r.followUp(ctx, topic, st, added)
+		}
+	}
+
+	if len(report.Sources) == 0 {
+		return nil, fmt.Errorf("no sources could be gathered for %q", topic)
+	}
+
+	r.progress(fmt.Sprintf("Writing report from %d sources", len(report.Sources)))
+	body, err := r.write(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Body = body + "\n\n" + references(report.Sources)
+
+	return report, nil
+}
+
+// plan asks the model to split the topic into subtopics with search queries
+func (r *Researcher) plan(ctx context.Context, topic string) (Plan, error) {
+	prompt := fmt.Sprintf(`You are planning a research report on: "%s"
+
+Break the topic into %d or fewer subtopics that together cover it thoroughly.
+For each subtopic give 1-3 concise web search queries (2-6 words each).
+
+Respond ONLY with JSON in this exact format:
+{"subtopics": [{"title": "subtopic title", "queries": ["query 1", "query 2"]}]}`, topic, r.MaxSubtopics)
+
+	response, err := r.Client.ChatSync(ctx, r.Model, []ollama.Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return Plan{}, fmt.Errorf("planning failed: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal([]byte(extractJSON(response)), &plan); err != nil {
+		return Plan{}, fmt.Errorf("failed to parse research plan: %w\nResponse: %s", err, response)
+	}
+
+	// Drop empty subtopics and enforce the cap
+	subtopics := plan.Subtopics[:0]
+	for _, st := range plan.Subtopics {
+		if strings.TrimSpace(st.Title) != "" && len(st.Queries) > 0 {
+			subtopics = append(subtopics, st)
+		}
+	}
+	if len(subtopics) > r.MaxSubtopics {
+		subtopics = subtopics[:r.MaxSubtopics]
+	}
+	if len(subtopics) == 0 {
+		subtopics = []Subtopic{{Title: topic, Queries: []string{topic}}}
+	}
+	plan.Subtopics = subtopics
+
+	return plan, nil
+}
+
+// gather searches and crawls the queries, appending new sources to the report
+func (r *Researcher) gather(ctx context.Context, queries []string, subtopic int, seen map[string]bool, report *Report) []Source {
+	var urls []string
+	for _, q := range queries {
+		results, err := r.Searcher.Search(ctx, q, r.Trust.Candidates(r.MaxResults))
+		if err != nil {
+			r.progress(fmt.Sprintf("Search failed for %q: %v", q, err))
+			continue
+		}
+		for _, res := range r.Trust.Select(r.Guard.FilterResults(searxng.FilterBlocked(results, r.BlockedDomains)), r.MaxResults) {
+			if !seen[res.URL] {
+				seen[res.URL] = true
+				urls = append(urls, res.URL)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	crawled := r.Fetcher.CrawlURLs(ctx, urls)
+	for _, w := range r.Guard.Clean(crawled) {
+		r.progress(fmt.Sprintf("Removed instruction-like text from %s", w.URL))
+	}
+
+	var added []Source
+	for _, cr := range crawled {
+		if cr.Error != nil || cr.Content == "" {
+			continue
+		}
+		src := Source{
+			Number:   len(report.Sources) + 1,
+			URL:      cr.URL,
+			Title:    cr.Title,
+			Content:  truncateWords(cr.Content, maxSourceWords),
+			Subtopic: subtopic,
+		}
+		report.Sources = append(report.Sources, src)
+		added = append(added, src)
+	}
+
+	r.progress(fmt.Sprintf("Gathered %d new source(s)", len(added)))
+	return added
+}
+
+// followUp asks the model which gaps remain after a round of searching
+func (r *Researcher) followUp(ctx context.Context, topic string, st Subtopic, found []Source) []string {
+	var titles strings.Builder
+	for _, src := range found {
+		titles.WriteString(fmt.Sprintf("- %s\n", src.Title))
+	}
+
+	prompt := fmt.Sprintf(`Research topic: "%s"
+Subtopic: "%s"
+Queries already run: %s
+Pages found so far:
+%s
+Suggest up to 2 new web search queries that would fill the most important gaps.
+Respond ONLY with JSON: {"queries": ["query 1"]}. Use an empty list if coverage is sufficient.`,
+		topic, st.Title, strings.Join(st.Queries, ", "), titles.String())
+
+	response, err := r.Client.ChatSync(ctx, r.Model, []ollama.Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return nil
+	}
+
+	var result struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(response)), &result); err != nil {
+		return nil
+	}
+	if len(result.Queries) > 2 {
+		result.Queries = result.Queries[:2]
+	}
+	return result.Queries
+}
+
+// write streams the long-form report from the structured context
+func (r *Researcher) write(ctx context.Context, report *Report) (string, error) {
+	system := `You are a meticulous research analyst. Write a long-form report in Markdown.
+Rules:
+- Start with a "# " title, then an "## Executive Summary".
+- Use one "## " section per subtopic, in the order given, followed by "## Conclusion".
+- Support every factual claim with citations like [1] or [2][5] that refer to the numbered sources.
+- Only use information from the sources; say so when the sources are silent or disagree.
+- Do not write a references list; it will be appended automatically.
+- Sources are numbered by the id of their <source> block.
+
+` + guard.UntrustedNotice
+
+	req := ollama.ChatRequest{
+		Model: r.Model,
+		Messages: []ollama.Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: buildContext(report)},
+		},
+		Options: r.Options,
+	}
+
+	body, err := r.Client.Chat(ctx, req, r.OnChunk)
+	if err != nil {
+		return "", fmt.Errorf("report generation failed: %w", err)
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// buildContext lays out sources grouped by subtopic
+func buildContext(report *Report) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Research topic: %s\n\n", report.Topic))
+
+	for i, st := range report.Plan.Subtopics {
+		sb.WriteString(fmt.Sprintf("## Subtopic %d: %s\n\n", i+1, st.Title))
+		count := 0
+		for _, src := range report.Sources {
+			if src.Subtopic != i {
+				continue
+			}
+			sb.WriteString(guard.Quote(src.Number, src.URL, src.Title, src.Content))
+			count++
+		}
+		if count == 0 {
+			sb.WriteString("(no sources found)\n\n")
+		}
+	}
+
+	sb.WriteString("Write the report now.")
+	return sb.String()
+}
+
+// references renders the numbered source list
+func references(sources []Source) string {
+	var sb strings.Builder
+	sb.WriteString("## References\n\n")
+	for _, src := range sources {
+		title := src.Title
+		if title == "" {
+			title = src.URL
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%s](%s)\n", src.Number, title, src.URL))
+	}
+	return sb.String()
+}
+
+// Save writes the report to dir as a Markdown file and returns its path
+func (rep *Report) Save(dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create research directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.md", now.Format("2006-01-02-1504"), slugify(rep.Topic))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(rep.Body+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+func (r *Researcher) progress(msg string) {
+	if r.Progress != nil {
+		r.Progress(msg)
+	}
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a topic into a short file-name-safe string
+func slugify(s string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == "" {
+		slug = "research"
+	}
+	return slug
+}
+
+// extractJSON returns the outermost {...} in a model response
+func extractJSON(response string) string {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return response
+	}
+	return response[start : end+1]
+}
+
+// truncateWords truncates text to approximately n words
+func truncateWords(text string, n int) string {
+	words := strings.Fields(text)
+	if len(words) <= n {
+		return text
+	}
+	return strings.Join(words[:n], " ") + "..."
+}
@@ -0,0 +1,137 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// isolateScript runs in new namespaces as the mapped root user. It makes
+// every mount read-only, then mounts each "rw:" directory writable, covers
+// each "dir:" directory with an empty one and each "file:" with /dev/null,
+// and runs the command after "--" from the working directory's new mount.
+const isolateScript = `set -e
+while read -r _ _ _ _ mnt _; do
+	mount -o remount,bind,ro "$(printf '%b' "$mnt")"
+done < /proc/self/mountinfo
+while [ "$1" != -- ]; do
+	path=${1#*:}
+	case $1 in
+	rw:*) mount --bind "$path" "$path" && mount -o remount,bind,rw "$path" ;;
+	dir:*) mount -t tmpfs -o ro,mode=0755 tmpfs "$path" ;;
+	file:*) mount --bind /dev/null "$path" ;;
+	esac
+	shift
+done
+shift
+cd "$PWD"
+exec "$@"
+`
+
+// isolate wraps command to run without network access, in its own PID
+// namespace, with every file read-only except under the writable
+// directories, and with the home directory hidden apart from the writable
+// and readable directories in it
+func isolate(writable, readable []string, command []string) []string {
+	args := []string{"unshare", "--map-root-user", "--net", "--mount", "--pid", "--ipc", "--uts",
+		"--fork", "--kill-child", "--mount-proc", "sh", "-c", isolateScript, "sh"}
+	for _, dir := range writable {
+		args = append(args, "rw:"+dir)
+	}
+	if home, _ := os.UserHomeDir(); home != "" && home != string(filepath.Separator) {
+		args = append(args, hiddenPaths(home, append(append([]string(nil), writable...), readable...))...)
+	}
+	args = append(args, "--")
+	return append(args, command...)
+}
+
+// hiddenPaths lists what to cover in home so that only the keep
+// directories, and those leading to them, stay visible
+func hiddenPaths(home string, keep []string) []string {
+	var hide []string
+	var walk func(path string, isDir bool)
+	walk = func(path string, isDir bool) {
+		switch {
+		case !isDir:
+			hide = append(hide, "file:"+path)
+		case within(path, keep):
+		case !leadsTo(path, keep):
+			hide = append(hide, "dir:"+path)
+		default:
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				hide = append(hide, "dir:"+path)
+				return
+			}
+			for _, e := range entries {
+				// A symbolic link shows only what its target shows
+				if e.Type()&os.ModeSymlink == 0 {
+					walk(filepath.Join(path, e.Name()), e.IsDir())
+				}
+			}
+		}
+	}
+	walk(home, true)
+	return hide
+}
+
+// within reports whether path is one of dirs or inside one
+func within(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsTo reports whether one of dirs is inside path
+func leadsTo(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIsolation reports why snippets cannot be isolated on this machine
+func checkIsolation() error {
+	dir, err := os.MkdirTemp("", "web-ollama-sandbox-check-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	args := isolate([]string{dir}, nil, []string{"true"})
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot isolate code snippets; code execution needs unprivileged user namespaces and unshare from util-linux: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// checkPrivate reports why dir is not a directory that only the current
+// user can read and write
+func checkPrivate(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("permissions are %v, want drwx------", perm)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("owned by another user")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package sandbox
+
+import "errors"
+
+// isolate is only implemented on Linux; Check keeps Run from getting here
+func isolate(writable, readable []string, command []string) []string {
+	return command
+}
+
+// checkIsolation reports that snippets cannot be isolated on this platform
+func checkIsolation() error {
+	return errors.New("code execution is only available on Linux, where snippets run in their own namespaces")
+}
+
+// checkPrivate is only needed where snippets can run
+func checkPrivate(dir string) error {
+	return nil
+}
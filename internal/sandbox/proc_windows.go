@@ -0,0 +1,13 @@
+//go:build windows
+
+package sandbox
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command; children are not tracked on Windows
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
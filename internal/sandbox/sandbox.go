@@ -0,0 +1,252 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Languages the sandbox can run
+var Languages = []string{"python", "go"}
+
+// Result is the outcome of running a snippet
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+}
+
+// Sandbox runs short code snippets with a time limit, capped output, a
+// throwaway working directory and a scrubbed environment. Snippets run in
+// their own Linux namespaces: without network access, with every file
+// read-only except the working directory and with the user's home directory
+// hidden. Where that isolation is unavailable nothing is run.
+type Sandbox struct {
+	Timeout   time.Duration
+	MaxOutput int // bytes kept from each of stdout and stderr
+
+	checkOnce sync.Once
+	checkErr  error
+
+	mu         sync.Mutex
+	toolchains map[string]toolchain
+}
+
+// toolchain is an interpreter or compiler resolved outside the sandbox
+type toolchain struct {
+	path  string   // absolute path of the executable
+	roots []string // directories it needs to read
+}
+
+// New creates a sandbox with the given per-run time limit
+func New(timeout time.Duration) *Sandbox {
+	return &Sandbox{Timeout: timeout, MaxOutput: 8 * 1024}
+}
+
+// Check reports why snippets cannot be isolated on this machine, in which
+// case Run refuses to run them
+func (s *Sandbox) Check() error {
+	s.checkOnce.Do(func() {
+		s.checkErr = checkIsolation()
+	})
+	return s.checkErr
+}
+
+// Run executes code in the given language
+func (s *Sandbox) Run(ctx context.Context, language, code string) (Result, error) {
+	if err := s.Check(); err != nil {
+		return Result{}, err
+	}
+	tc, err := s.toolchain(language)
+	if err != nil {
+		return Result{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "web-ollama-sandbox-*")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	start := time.Now()
+
+	var command []string
+	switch language {
+	case "python":
+		path := filepath.Join(dir, "main.py")
+		if err := os.WriteFile(path, []byte(code), 0600); err != nil {
+			return Result{}, fmt.Errorf("failed to write snippet: %w", err)
+		}
+		command = []string{tc.path, "-I", path}
+	case "go":
+		path := filepath.Join(dir, "main.go")
+		if err := os.WriteFile(path, []byte(code), 0600); err != nil {
+			return Result{}, fmt.Errorf("failed to write snippet: %w", err)
+		}
+		// Standard library only. The build cache is kept between runs so
+		// they stay fast, and only the compiler writes to it: the snippet
+		// itself runs afterwards without it
+		cache, err := goCache()
+		if err != nil {
+			return Result{}, err
+		}
+		bin := filepath.Join(dir, "main")
+		buildEnv := append(env, "GO111MODULE=off", "GOPROXY=off", "CGO_ENABLED=0", "GOCACHE="+cache)
+		build, err := s.exec(ctx, dir, buildEnv, isolate([]string{dir, cache}, tc.roots, []string{tc.path, "build", "-o", bin, path}))
+		if err != nil || build.ExitCode != 0 || build.TimedOut {
+			build.Duration = time.Since(start)
+			return build, err
+		}
+		command = []string{bin}
+	}
+
+	result, err := s.exec(ctx, dir, env, isolate([]string{dir}, tc.roots, command))
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// goCache returns the build cache for Go snippets, in the user's cache
+// directory. Its compiled packages are linked into every snippet, so it must
+// be the user's own and closed to everyone else.
+func goCache() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no cache directory for the Go build cache: %w", err)
+	}
+	dir := filepath.Join(base, "web-ollama", "sandbox-gocache")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create the Go build cache: %w", err)
+	}
+	if err := checkPrivate(dir); err != nil {
+		return "", fmt.Errorf("not using the Go build cache %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// exec runs one isolated command from dir until it exits or ctx ends
+func (s *Sandbox) exec(ctx context.Context, dir string, env, args []string) (Result, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	setProcessGroup(cmd)
+
+	stdout := &limitedBuffer{max: s.MaxOutput}
+	stderr := &limitedBuffer{max: s.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	// Kill the whole process group so child processes can't outlive the timeout
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	result := Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		return result, fmt.Errorf("failed to run snippet: %w", err)
+	}
+	return result, nil
+}
+
+// toolchain finds the executable for language and the directories it reads,
+// which stay visible when they are in the hidden home directory
+func (s *Sandbox) toolchain(language string) (toolchain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tc, ok := s.toolchains[language]; ok {
+		return tc, nil
+	}
+
+	var tc toolchain
+	switch language {
+	case "python":
+		// Resolve version-manager shims to the real interpreter
+		out, err := exec.Command("python3", "-I", "-c", "import sys; print(sys.executable); print(sys.base_prefix); print(sys.prefix)").Output()
+		lines := strings.Fields(string(out))
+		if err != nil || len(lines) != 3 {
+			return toolchain{}, fmt.Errorf("python3 is not available: %v", err)
+		}
+		tc = toolchain{path: lines[0], roots: lines[1:]}
+	case "go":
+		out, err := exec.Command("go", "env", "GOROOT").Output()
+		root := strings.TrimSpace(string(out))
+		if err != nil || root == "" {
+			return toolchain{}, fmt.Errorf("go is not available: %v", err)
+		}
+		tc = toolchain{path: filepath.Join(root, "bin", "go"), roots: []string{root}}
+	default:
+		return toolchain{}, fmt.Errorf("unsupported language %q (supported: python, go)", language)
+	}
+
+	if s.toolchains == nil {
+		s.toolchains = make(map[string]toolchain)
+	}
+	s.toolchains[language] = tc
+	return tc, nil
+}
+
+// limitedBuffer keeps the first max bytes written and notes truncation
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n[output truncated]"
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,145 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+}
+
+func requireIsolation(t *testing.T, sb *Sandbox) {
+	t.Helper()
+	if err := sb.Check(); err != nil {
+		t.Skip(err)
+	}
+}
+
+func TestRunPython(t *testing.T) {
+	requirePython(t)
+	sb := New(10 * time.Second)
+	requireIsolation(t, sb)
+
+	result, err := sb.Run(context.Background(), "python", "print(6 * 7)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(result.Stdout) != "42" || result.ExitCode != 0 {
+		t.Errorf("got stdout %q exit %d, want 42 exit 0", result.Stdout, result.ExitCode)
+	}
+}
+
+func TestRunReportsFailure(t *testing.T) {
+	requirePython(t)
+	sb := New(10 * time.Second)
+	requireIsolation(t, sb)
+
+	result, err := sb.Run(context.Background(), "python", "raise SystemExit('boom')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "boom") {
+		t.Errorf("got exit %d stderr %q, want failure mentioning boom", result.ExitCode, result.Stderr)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	requirePython(t)
+	sb := New(time.Second)
+	requireIsolation(t, sb)
+
+	start := time.Now()
+	result, err := sb.Run(context.Background(), "python", "import time\ntime.sleep(30)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut {
+		t.Error("expected the run to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("timeout took %v to take effect", elapsed)
+	}
+}
+
+func TestRunTruncatesOutput(t *testing.T) {
+	requirePython(t)
+	sb := New(10 * time.Second)
+	requireIsolation(t, sb)
+	sb.MaxOutput = 100
+
+	result, err := sb.Run(context.Background(), "python", "print('x' * 10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Stdout) > 200 || !strings.Contains(result.Stdout, "[output truncated]") {
+		t.Errorf("output not truncated: %d bytes", len(result.Stdout))
+	}
+}
+
+func TestRunIsolatesFiles(t *testing.T) {
+	requirePython(t)
+	sb := New(10 * time.Second)
+	requireIsolation(t, sb)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "secret"), []byte("hunter2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+
+	code := `import os
+try:
+    print(open(os.path.join(%q, "secret")).read())
+except OSError:
+    print("hidden")
+try:
+    open(os.path.join(%q, "written"), "w").write("x")
+except OSError:
+    print("read-only")
+open("scratch", "w").write("ok")
+`
+	result, err := sb.Run(context.Background(), "python", fmt.Sprintf(code, home, outside))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(result.Stdout); strings.Join(got, " ") != "hidden read-only" || result.ExitCode != 0 {
+		t.Errorf("got stdout %q stderr %q, want the home directory hidden, the rest read-only and the working directory writable", result.Stdout, result.Stderr)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "written")); err == nil {
+		t.Error("the snippet wrote outside its working directory")
+	}
+}
+
+func TestRunUnsupportedLanguage(t *testing.T) {
+	if _, err := New(time.Second).Run(context.Background(), "ruby", "puts 1"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
+
+func TestGoCacheMustBePrivate(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", base)
+	t.Setenv("HOME", base)
+
+	dir, err := goCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goCache(); err == nil {
+		t.Error("a build cache others can write to should be refused")
+	}
+}
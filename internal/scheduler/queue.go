@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"web-ollama/internal/errs"
+)
+
+// Queue admits a limited number of questions at a time and holds the rest
+// in arrival order. The scheduler's Ollama limit spreads individual requests
+// across questions; on a single GPU that makes every answer slow, so server
+// and bot modes answer whole questions one at a time instead.
+type Queue struct {
+	limit int // questions answered at once; 0 means unlimited
+	depth int // questions that may wait; 0 means unlimited
+
+	mu      sync.Mutex
+	active  int
+	waiting []*ticket
+}
+
+// ticket is one waiting question
+type ticket struct {
+	admitted bool
+	moved    chan struct{} // signalled when the ticket is admitted or moves up
+}
+
+// NewQueue creates a queue answering limit questions at once with up to
+// depth more waiting
+func NewQueue(limit, depth int) *Queue {
+	return &Queue{limit: limit, depth: depth}
+}
+
+// Enter waits for the question's turn. onPosition, if set, is called with
+// the question's place in line (1 is next) when it has to wait and whenever
+// it moves up. The returned function must be called when the question is
+// answered. A full queue returns a QueueFull error without waiting.
+func (q *Queue) Enter(ctx context.Context, onPosition func(int)) (func(), error) {
+	if q == nil || q.limit <= 0 {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	if q.active < q.limit && len(q.waiting) == 0 {
+		q.active++
+		q.mu.Unlock()
+		return q.releaser(), nil
+	}
+	if q.depth > 0 && len(q.waiting) >= q.depth {
+		q.mu.Unlock()
+		return nil, errs.New(errs.QueueFull, "", fmt.Errorf("%d questions already waiting", q.depth))
+	}
+	t := &ticket{moved: make(chan struct{}, 1)}
+	q.waiting = append(q.waiting, t)
+	q.mu.Unlock()
+
+	last := 0
+	for {
+		q.mu.Lock()
+		if t.admitted {
+			q.mu.Unlock()
+			return q.releaser(), nil
+		}
+		pos := q.position(t)
+		q.mu.Unlock()
+
+		if pos != last && onPosition != nil {
+			onPosition(pos)
+		}
+		last = pos
+
+		select {
+		case <-t.moved:
+		case <-ctx.Done():
+			q.mu.Lock()
+			if t.admitted {
+				q.active--
+			} else {
+				q.remove(t)
+			}
+			q.admit()
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Waiting returns how many questions are queued
+func (q *Queue) Waiting() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// releaser returns the function ending an admitted question
+func (q *Queue) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			q.active--
+			q.admit()
+			q.mu.Unlock()
+		})
+	}
+}
+
+// admit lets waiting questions in while there is room and tells the rest
+// they moved (must be called with lock held)
+func (q *Queue) admit() {
+	for q.active < q.limit && len(q.waiting) > 0 {
+		t := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		t.admitted = true
+		q.active++
+		notify(t)
+	}
+	for _, t := range q.waiting {
+		notify(t)
+	}
+}
+
+// position returns t's 1-based place in line (must be called with lock held)
+func (q *Queue) position(t *ticket) int {
+	for i, w := range q.waiting {
+		if w == t {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// remove drops t from the line (must be called with lock held)
+func (q *Queue) remove(t *ticket) {
+	for i, w := range q.waiting {
+		if w == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func notify(t *ticket) {
+	select {
+	case t.moved <- struct{}{}:
+	default:
+	}
+}
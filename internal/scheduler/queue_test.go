@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web-ollama/internal/errs"
+)
+
+func TestQueueAdmitsInOrderAndReportsPositions(t *testing.T) {
+	q := NewQueue(1, 0)
+	first, err := q.Enter(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		positions chan int // closed once admitted
+		admitted  chan func()
+	}
+	var entries []*entry
+	for i := 0; i < 3; i++ {
+		e := &entry{positions: make(chan int, 10), admitted: make(chan func(), 1)}
+		entries = append(entries, e)
+		go func() {
+			release, err := q.Enter(context.Background(), func(pos int) { e.positions <- pos })
+			if err != nil {
+				t.Error(err)
+			}
+			close(e.positions)
+			e.admitted <- release
+		}()
+		// Wait until it is in line, so the order is known
+		for q.Waiting() < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if got := q.Waiting(); got != 3 {
+		t.Fatalf("waiting = %d, want 3", got)
+	}
+
+	release := first
+	for i, e := range entries {
+		select {
+		case <-e.admitted:
+			t.Fatalf("question %d admitted before its turn", i)
+		default:
+		}
+		release()
+		select {
+		case release = <-e.admitted:
+		case <-time.After(time.Second):
+			t.Fatalf("question %d was not admitted", i)
+		}
+	}
+	release()
+
+	var got []int
+	for pos := range entries[2].positions {
+		got = append(got, pos)
+	}
+	// Moves that happen while it is not looking are reported together
+	if len(got) == 0 || got[0] != 3 {
+		t.Fatalf("third question's positions = %v, want 3 first", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] >= got[i-1] {
+			t.Errorf("third question's positions = %v, want them to count down", got)
+		}
+	}
+}
+
+func TestQueueRefusesWhenFull(t *testing.T) {
+	q := NewQueue(1, 1)
+	release, _ := q.Enter(context.Background(), nil)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error)
+	go func() {
+		_, err := q.Enter(ctx, nil)
+		waiting <- err
+	}()
+	for q.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := q.Enter(context.Background(), nil); !errs.Is(err, errs.QueueFull) {
+		t.Errorf("err = %v, want QueueFull", err)
+	}
+
+	// A cancelled question leaves the line
+	cancel()
+	if err := <-waiting; err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if got := q.Waiting(); got != 0 {
+		t.Errorf("waiting = %d, want 0", got)
+	}
+}
+
+func TestQueueUnlimited(t *testing.T) {
+	q := NewQueue(0, 0)
+	for i := 0; i < 5; i++ {
+		if _, err := q.Enter(context.Background(), func(int) { t.Error("an unlimited queue never waits") }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var nilQueue *Queue
+	if _, err := nilQueue.Enter(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
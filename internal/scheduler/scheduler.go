@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/metrics"
+)
+
+// Endpoints the scheduler knows about
+const (
+	Ollama = "ollama"
+	Search = "search"
+	Crawl  = "crawl"
+)
+
+// Limits caps concurrent requests per endpoint. Zero means unlimited.
+type Limits struct {
+	Ollama       int           // concurrent requests per Ollama server, including open streams
+	Search       int           // concurrent SearXNG requests
+	Crawl        int           // concurrent page fetches across all sites
+	CrawlPerHost int           // concurrent page fetches per site
+	HostDelay    time.Duration // minimum gap between fetches from the same site
+}
+
+// Scheduler queues outgoing requests so that every mode shares one set of
+// limits: a single-GPU Ollama box sees a bounded number of generations and
+// no site gets hammered by the crawler
+type Scheduler struct {
+	limits Limits
+
+	mu    sync.Mutex
+	pools map[string]*pool
+}
+
+// pool is a counting semaphore with an optional gap between acquisitions
+type pool struct {
+	slots chan struct{} // nil when unlimited
+	delay time.Duration
+	users int // requests waiting for or holding a slot; guarded by Scheduler.mu
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+}
+
+// New creates a scheduler with the given limits
+func New(limits Limits) *Scheduler {
+	return &Scheduler{limits: limits, pools: make(map[string]*pool)}
+}
+
+// pool returns the pool for key, creating it on first use, and counts the
+// caller as a user until it calls leave. Creating a pool drops the ones no
+// request uses whose delay has passed, so the pools of every site crawled
+// during a long-running serve do not pile up.
+func (s *Scheduler) pool(key string, limit int, delay time.Duration) *pool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pools[key]
+	if !ok {
+		now := time.Now()
+		for k, idle := range s.pools {
+			if idle.users == 0 && !idle.waiting(now) {
+				delete(s.pools, k)
+			}
+		}
+		p = &pool{delay: delay}
+		if limit > 0 {
+			p.slots = make(chan struct{}, limit)
+		}
+		s.pools[key] = p
+	}
+	p.users++
+	return p
+}
+
+// leave stops counting a caller of pool as one of p's users
+func (s *Scheduler) leave(p *pool) {
+	s.mu.Lock()
+	p.users--
+	s.mu.Unlock()
+}
+
+// waiting reports whether the next request must still wait for the delay
+func (p *pool) waiting(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.next.After(now)
+}
+
+// acquire waits for a slot and any required delay
+func (p *pool) acquire(ctx context.Context) error {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if p.delay > 0 {
+		p.mu.Lock()
+		now := time.Now()
+		start := p.next
+		if start.Before(now) {
+			start = now
+		}
+		p.next = start.Add(p.delay)
+		p.mu.Unlock()
+
+		if wait := time.Until(start); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				p.release()
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+func (p *pool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// Acquire waits for a slot on endpoint (and, for crawls, on host). Ollama
+// requests are capped per server, named by host, so that every server added
+// adds capacity. The returned function must be called to give the slot back.
+func (s *Scheduler) Acquire(ctx context.Context, endpoint, host string) (func(), error) {
+	start := time.Now()
+	var pools []*pool
+
+	switch endpoint {
+	case Ollama:
+		pools = append(pools, s.pool(Ollama+":"+strings.ToLower(host), s.limits.Ollama, 0))
+	case Search:
+		pools = append(pools, s.pool(Search, s.limits.Search, 0))
+	case Crawl:
+		pools = append(pools, s.pool(Crawl, s.limits.Crawl, 0))
+		pools = append(pools, s.pool("host:"+strings.ToLower(host), s.limits.CrawlPerHost, s.limits.HostDelay))
+	}
+
+	for i, p := range pools {
+		if err := p.acquire(ctx); err != nil {
+			for _, held := range pools[:i] {
+				held.release()
+			}
+			for _, p := range pools {
+				s.leave(p)
+			}
+			return nil, err
+		}
+	}
+
+	wait := time.Since(start)
+	metrics.QueueWait.Observe(wait.Seconds(), endpoint)
+	if wait > time.Second {
+		slog.Debug("request queued", "endpoint", endpoint, "host", host, "wait", wait)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for _, p := range pools {
+				p.release()
+				s.leave(p)
+			}
+		})
+	}, nil
+}
+
+// Transport returns a RoundTripper that schedules every request on endpoint.
+// Slots are held until the response body is closed, so open streams count.
+func (s *Scheduler) Transport(endpoint string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{s: s, endpoint: endpoint, base: base}
+}
+
+type transport struct {
+	s        *Scheduler
+	endpoint string
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Ollama servers are told apart by port too, as several may share a machine
+	host := req.URL.Hostname()
+	if t.endpoint == Ollama {
+		host = req.URL.Host
+	}
+	release, err := t.s.Acquire(req.Context(), t.endpoint, host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody gives the slot back when the body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
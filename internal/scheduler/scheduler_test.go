@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcquireCapsConcurrency(t *testing.T) {
+	s := New(Limits{Ollama: 2})
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), Ollama, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak != 2 {
+		t.Errorf("peak concurrency %d, want 2", peak)
+	}
+}
+
+func TestAcquireHonoursContext(t *testing.T) {
+	s := New(Limits{Search: 1})
+	release, err := s.Acquire(context.Background(), Search, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, Search, ""); err == nil {
+		t.Error("expected the queued request to give up when its context ends")
+	}
+}
+
+func TestHostDelaySpacesFetches(t *testing.T) {
+	s := New(Limits{HostDelay: 30 * time.Millisecond})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := s.Acquire(context.Background(), Crawl, "Example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("three fetches took %v, want at least 60ms", elapsed)
+	}
+
+	// Other hosts are not slowed down
+	start = time.Now()
+	release, _ := s.Acquire(context.Background(), Crawl, "other.example")
+	release()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("unrelated host waited %v", elapsed)
+	}
+}
+
+func TestTransportHoldsSlotUntilBodyClosed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	s := New(Limits{Ollama: 1})
+	client := &http.Client{Transport: s.Transport(Ollama, nil)}
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, Ollama, host); err == nil {
+		t.Fatal("slot was released before the body was closed")
+	}
+
+	resp.Body.Close()
+	release, err := s.Acquire(context.Background(), Ollama, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestIdleHostPoolsAreDropped(t *testing.T) {
+	s := New(Limits{CrawlPerHost: 1})
+	for i := 0; i < 100; i++ {
+		release, err := s.Acquire(context.Background(), Crawl, fmt.Sprintf("site%d.example", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	held, _ := s.Acquire(context.Background(), Crawl, "busy.example")
+	defer held()
+	last, _ := s.Acquire(context.Background(), Crawl, "last.example")
+	defer last()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pools["host:busy.example"]; !ok || len(s.pools) > 4 {
+		t.Errorf("%d pools kept, want the crawl pool, the busy host and the last ones", len(s.pools))
+	}
+}
+
+func TestOllamaLimitIsPerServer(t *testing.T) {
+	s := New(Limits{Ollama: 1})
+	first, err := s.Acquire(context.Background(), Ollama, "gpu1:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	second, err := s.Acquire(ctx, Ollama, "gpu2:11434")
+	if err != nil {
+		t.Fatal("a second server should have its own slots")
+	}
+	second()
+	if _, err := s.Acquire(ctx, Ollama, "GPU1:11434"); err == nil {
+		t.Error("the first server's slot should still be taken")
+	}
+}
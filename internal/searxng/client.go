@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
+
+	"web-ollama/internal/errs"
 )
 
 // Client handles communication with SearXNG
@@ -29,8 +33,28 @@ func NewClient(baseURL string, timeout time.Duration) *Client {
 	}
 }
 
+// SetTransport routes search requests through rt
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// SetTimeout changes the timeout for search requests
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+	c.timeout = timeout
+}
+
 // Search performs a web search and returns the top N results
-func (c *Client) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+func (c *Client) Search(ctx context.Context, query string, maxResults int) (results []SearchResult, err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			slog.Warn("search failed", "query", query, "duration", time.Since(start), "error", err)
+			return
+		}
+		slog.Info("search", "query", query, "results", len(results), "duration", time.Since(start))
+	}()
+
 	// Build URL with query parameters
 	searchURL := fmt.Sprintf("%s/search", c.baseURL)
 	params := url.Values{}
@@ -58,7 +82,7 @@ func (c *Client) Search(ctx context.Context, query string, maxResults int) ([]Se
 
 	// Check status code
 	if resp.StatusCode == 403 {
-		return nil, fmt.Errorf("SearXNG returned 403 Forbidden. JSON API may not be enabled. Check settings.yml for 'formats: [html, json]'")
+		return nil, errs.New(errs.SearxForbidden, c.baseURL, nil)
 	}
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
@@ -96,6 +120,7 @@ func (c *Client) HealthCheck() error {
 	}
 
 	req.Header.Set("User-Agent", "web-ollama/1.0")
+	req.Header.Set("Cache-Control", "no-cache") // ask SearXNG itself, not a cached answer
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -104,7 +129,7 @@ func (c *Client) HealthCheck() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 403 {
-		return fmt.Errorf("SearXNG API access forbidden. Check settings.yml to enable JSON format")
+		return errs.New(errs.SearxForbidden, c.baseURL, nil)
 	}
 
 	if resp.StatusCode >= 500 {
@@ -113,3 +138,33 @@ func (c *Client) HealthCheck() error {
 
 	return nil
 }
+
+// FilterBlocked drops results whose host is a blocked domain or one of its subdomains
+func FilterBlocked(results []SearchResult, blocked []string) []SearchResult {
+	if len(blocked) == 0 {
+		return results
+	}
+
+	filtered := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		u, err := url.Parse(result.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+
+		isBlocked := false
+		for _, domain := range blocked {
+			domain = strings.ToLower(strings.TrimSpace(domain))
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				isBlocked = true
+				break
+			}
+		}
+		if !isBlocked {
+			filtered = append(filtered, result)
+		}
+	}
+
+	return filtered
+}
@@ -1,5 +1,7 @@
 package searxng
 
+import "time"
+
 // SearchResponse represents the JSON response from SearXNG
 type SearchResponse struct {
 	Query           string         `json:"query"`
@@ -9,9 +11,10 @@ type SearchResponse struct {
 
 // SearchResult represents a single search result
 type SearchResult struct {
-	Title   string  `json:"title"`
-	URL     string  `json:"url"`
-	Content string  `json:"content"` // Snippet
-	Engine  string  `json:"engine"`
-	Score   float64 `json:"score"`
+	Title   string    `json:"title"`
+	URL     string    `json:"url"`
+	Content string    `json:"content"` // Snippet
+	Engine  string    `json:"engine"`
+	Score   float64   `json:"score"`
+	Cached  time.Time `json:"-"` // when a result answered from a local cache was retrieved; zero for a live search
 }
@@ -0,0 +1,155 @@
+package secrets
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ollama/internal/config"
+)
+
+// Source describes where a secret was found
+type Source string
+
+const (
+	SourceNone     Source = ""
+	SourceEnv      Source = "env"
+	SourceKeychain Source = "keychain"
+)
+
+// keychainService is the service name secrets are stored under in the OS keychain
+const keychainService = "web-ollama"
+
+// defaultEnv maps known providers to the environment variable checked when the
+// config file does not name one
+var defaultEnv = map[string]string{
+	"brave":    "BRAVE_API_KEY",
+	"tavily":   "TAVILY_API_KEY",
+	"github":   "GITHUB_TOKEN",
+	"ollama":   "OLLAMA_API_KEY",
+	"openai":   "OPENAI_API_KEY",
+	"telegram": "TELEGRAM_BOT_TOKEN",
+}
+
+// Status reports whether a provider has a credential, without revealing it
+type Status struct {
+	Provider string
+	Source   Source
+	Location string // env var or keychain account consulted
+	Masked   string // e.g. "sk-…9f2a"
+}
+
+// Store holds resolved provider credentials in memory only
+type Store struct {
+	values   map[string]string
+	statuses map[string]Status
+}
+
+// Load resolves credentials for every known provider plus any listed in refs.
+// Environment variables win over the keychain.
+func Load(refs map[string]config.SecretRef) *Store {
+	s := &Store{
+		values:   make(map[string]string),
+		statuses: make(map[string]Status),
+	}
+
+	providers := make(map[string]config.SecretRef)
+	for name := range defaultEnv {
+		providers[name] = config.SecretRef{}
+	}
+	for name, ref := range refs {
+		providers[strings.ToLower(name)] = ref
+	}
+
+	for name, ref := range providers {
+		env := ref.Env
+		if env == "" {
+			env = defaultEnv[name]
+		}
+		account := ref.Keychain
+		if account == "" {
+			account = name
+		}
+
+		status := Status{Provider: name}
+		if value := strings.TrimSpace(config.GetEnv(env)); value != "" {
+			s.values[name] = value
+			status.Source, status.Location = SourceEnv, env
+		} else if value := keychainLookup(account); value != "" {
+			s.values[name] = value
+			status.Source, status.Location = SourceKeychain, keychainService+"/"+account
+		} else {
+			status.Location = env
+		}
+		status.Masked = mask(s.values[name])
+		s.statuses[name] = status
+	}
+
+	return s
+}
+
+// Get returns the credential for a provider
+func (s *Store) Get(provider string) (string, bool) {
+	v, ok := s.values[strings.ToLower(provider)]
+	return v, ok
+}
+
+// Statuses lists every provider sorted by name
+func (s *Store) Statuses() []Status {
+	list := make([]Status, 0, len(s.statuses))
+	for _, st := range s.statuses {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Provider < list[j].Provider })
+	return list
+}
+
+// Redact replaces any known credential appearing in text with a placeholder.
+// Use it on anything written to disk or printed in logs.
+func (s *Store) Redact(text string) string {
+	if s == nil {
+		return text
+	}
+	for name, value := range s.values {
+		if len(value) >= 4 {
+			text = strings.ReplaceAll(text, value, "[REDACTED:"+name+"]")
+		}
+	}
+	return text
+}
+
+// mask shows only enough of a secret to tell keys apart
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return strings.Repeat("•", len(value))
+	}
+	return value[:3] + "…" + value[len(value)-4:]
+}
+
+// keychainLookup reads a secret from the OS keychain, returning "" if unavailable
+func keychainLookup(account string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", keychainService, "account", account)
+	default:
+		return ""
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,264 @@
+// Package selector matches HTML elements with a subset of CSS selectors:
+// type, class, ID and attribute selectors, joined by descendant (space) and
+// child (>) combinators, in comma-separated groups. That covers what is
+// needed to pick out the content of a page without a full CSS engine.
+package selector
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled selector
+type Selector struct {
+	source string
+	groups [][]step
+}
+
+// step is one compound selector of a group, e.g. "div.post"
+type step struct {
+	tag     string // "" or "*" matches any element
+	id      string
+	classes []string
+	attrs   []attr
+	child   bool // must be a child, not just a descendant, of the previous step's element
+}
+
+type attr struct {
+	name, value string
+	hasValue    bool
+}
+
+// Compile parses a selector such as "article .content, #main > p"
+func Compile(source string) (*Selector, error) {
+	s := &Selector{source: source}
+	for _, group := range strings.Split(source, ",") {
+		steps, err := parseGroup(strings.TrimSpace(group))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", source, err)
+		}
+		s.groups = append(s.groups, steps)
+	}
+	return s, nil
+}
+
+// String returns the selector as written
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match reports whether n is an element the selector matches
+func (s *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, steps := range s.groups {
+		if matchFrom(n, steps, len(steps)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAll returns the elements under root that the selector matches, in
+// document order, leaving out those inside another match
+func (s *Selector) MatchAll(root *html.Node) []*html.Node {
+	var found []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if s.Match(n) {
+			found = append(found, n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// matchFrom reports whether n matches steps[i], with its ancestors matching
+// the steps before it
+func matchFrom(n *html.Node, steps []step, i int) bool {
+	if !steps[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if matchFrom(p, steps, i-1) {
+			return true
+		}
+		if steps[i].child {
+			return false
+		}
+	}
+	return false
+}
+
+func (st step) match(n *html.Node) bool {
+	if st.tag != "" && st.tag != "*" && st.tag != n.Data {
+		return false
+	}
+	if st.id != "" && attrValue(n, "id") != st.id {
+		return false
+	}
+	if len(st.classes) > 0 {
+		have := strings.Fields(attrValue(n, "class"))
+		for _, want := range st.classes {
+			if !contains(have, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range st.attrs {
+		value, ok := lookupAttr(n, a.name)
+		if !ok || (a.hasValue && value != a.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseGroup parses one comma-separated group into its steps
+func parseGroup(group string) ([]step, error) {
+	if group == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	var steps []step
+	child := false
+	for i := 0; i < len(group); {
+		switch c := group[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '>':
+			if len(steps) == 0 || child {
+				return nil, fmt.Errorf("misplaced '>' at offset %d", i)
+			}
+			child = true
+			i++
+		default:
+			st, n, err := parseCompound(group[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at offset %d", err, i+n)
+			}
+			st.child = child
+			steps = append(steps, st)
+			child = false
+			i += n
+		}
+	}
+	if child {
+		return nil, fmt.Errorf("'>' without a selector after it")
+	}
+	return steps, nil
+}
+
+// parseCompound parses a compound selector at the start of s, returning it
+// and how many bytes it took
+func parseCompound(s string) (step, int, error) {
+	var st step
+	i := 0
+	if s[0] == '*' {
+		st.tag, i = "*", 1
+	} else if name := ident(s); name != "" {
+		st.tag, i = strings.ToLower(name), len(name)
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '.', '#':
+			name := ident(s[i+1:])
+			if name == "" {
+				return st, i, fmt.Errorf("expected a name after %q", s[i])
+			}
+			if s[i] == '.' {
+				st.classes = append(st.classes, name)
+			} else {
+				st.id = name
+			}
+			i += 1 + len(name)
+		case '[':
+			a, n, err := parseAttr(s[i:])
+			if err != nil {
+				return st, i, err
+			}
+			st.attrs = append(st.attrs, a)
+			i += n
+		case ' ', '\t', '\n', '>':
+			return st, i, nil
+		default:
+			return st, i, fmt.Errorf("unsupported %q", s[i])
+		}
+	}
+	if i == 0 {
+		return st, 0, fmt.Errorf("expected a selector")
+	}
+	return st, i, nil
+}
+
+// parseAttr parses [name] or [name=value], with the value optionally quoted
+func parseAttr(s string) (attr, int, error) {
+	var a attr
+	i := 1
+	a.name = strings.ToLower(ident(s[i:]))
+	if a.name == "" {
+		return a, 0, fmt.Errorf("expected an attribute name")
+	}
+	i += len(a.name)
+	if i < len(s) && s[i] == '=' {
+		i++
+		a.hasValue = true
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return a, 0, fmt.Errorf("unterminated attribute value")
+			}
+			a.value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			a.value = ident(s[i:])
+			i += len(a.value)
+		}
+	}
+	if i >= len(s) || s[i] != ']' {
+		return a, 0, fmt.Errorf("expected ']'")
+	}
+	return a, i + 1, nil
+}
+
+// ident returns the name at the start of s: letters, digits, '-' and '_'
+func ident(s string) string {
+	for i, r := range s {
+		if !(r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func lookupAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(n *html.Node, name string) string {
+	v, _ := lookupAttr(n, name)
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package selector
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const page = `<html><body>
+<div id="main" class="page wide">
+  <article class="post"><p class="lead">Lead</p><div><p>Nested</p></div></article>
+  <aside data-role="sidebar"><p>Side</p></aside>
+</div>
+<p>Outside</p>
+</body></html>`
+
+func texts(t *testing.T, sel string) string {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Compile(sel)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", sel, err)
+	}
+	var out []string
+	for _, n := range s.MatchAll(doc) {
+		var sb strings.Builder
+		var walk func(*html.Node)
+		walk = func(n *html.Node) {
+			if n.Type == html.TextNode {
+				sb.WriteString(strings.TrimSpace(n.Data))
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(n)
+		out = append(out, sb.String())
+	}
+	return strings.Join(out, "|")
+}
+
+func TestMatchAll(t *testing.T) {
+	cases := map[string]string{
+		"p":                          "Lead|Nested|Side|Outside",
+		"article p":                  "Lead|Nested",
+		"article > p":                "Lead",
+		"#main>article>p":            "Lead",
+		"p.lead":                     "Lead",
+		".page.wide > aside":         "Side",
+		"[data-role=sidebar]":        "Side",
+		`aside[data-role="sidebar"]`: "Side",
+		"[data-role=nav]":            "",
+		"article, aside":             "LeadNested|Side",
+		"div":                        "LeadNestedSide", // nested matches are inside the first
+		"body > p":                   "Outside",
+		"* > .lead":                  "Lead",
+	}
+	for sel, want := range cases {
+		if got := texts(t, sel); got != want {
+			t.Errorf("%q matched %q, want %q", sel, got, want)
+		}
+	}
+}
+
+func TestCompileRejectsUnsupportedSyntax(t *testing.T) {
+	for _, sel := range []string{"", "p:first-child", "a + b", "div >", "> p", "[href", "p,", ".", "a ~ b"} {
+		if _, err := Compile(sel); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", sel)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"web-ollama/internal/grounding"
+)
+
+// OpenAI-compatible wire types for /v1/chat/completions
+
+// ChatCompletionRequest is the subset of the OpenAI request we understand
+type ChatCompletionRequest struct {
+	Model       string          `json:"model"`
+	Messages    []OpenAIMessage `json:"messages"`
+	Stream      bool            `json:"stream"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
+	MaxTokens   *int            `json:"max_tokens,omitempty"`
+	Seed        *int            `json:"seed,omitempty"`
+	Stop        json.RawMessage `json:"stop,omitempty"`
+}
+
+// OpenAIMessage is a chat message whose content may be a string or a list of parts
+type OpenAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// Text returns the message content, joining text parts when content is an array
+func (m OpenAIMessage) Text() string {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return ""
+	}
+
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ChatCompletion is a non-streaming response
+type ChatCompletion struct {
+	ID      string           `json:"id"`
+	Object  string           `json:"object"`
+	Created int64            `json:"created"`
+	Model   string           `json:"model"`
+	Choices []Choice         `json:"choices"`
+	Usage   Usage            `json:"usage"`
+	Sources []string         `json:"x_sources,omitempty"` // web pages used for grounding
+	Trace   *grounding.Trace `json:"x_trace,omitempty"`   // how the answer was grounded, for auditing
+}
+
+// Choice is one completion alternative
+type Choice struct {
+	Index        int           `json:"index"`
+	Message      *ReplyMessage `json:"message,omitempty"`
+	Delta        *ReplyMessage `json:"delta,omitempty"`
+	FinishReason *string       `json:"finish_reason"`
+}
+
+// ReplyMessage is an assistant message or stream delta
+type ReplyMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Usage reports token counts
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatCompletionChunk is one server-sent event in a streaming response
+type ChatCompletionChunk struct {
+	ID      string           `json:"id"`
+	Object  string           `json:"object"`
+	Created int64            `json:"created"`
+	Model   string           `json:"model"`
+	Choices []Choice         `json:"choices"`
+	Sources []string         `json:"x_sources,omitempty"`
+	Trace   *grounding.Trace `json:"x_trace,omitempty"` // sent with the final chunk
+}
+
+// ModelList is the response for /v1/models
+type ModelList struct {
+	Object string        `json:"object"`
+	Data   []ModelObject `json:"data"`
+}
+
+// ModelObject describes one model
+type ModelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// ErrorResponse is the OpenAI error envelope
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an API error
+type ErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"` // the errs.Kind, for failures of a known kind
+}
@@ -0,0 +1,686 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/cache"
+	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/metrics"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/sinks"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/tracing"
+)
+
+// Server exposes web-grounded chat over HTTP
+type Server struct {
+	cfg      *config.Config
+	ollama   *ollama.Client
+	grounder *grounding.Grounder
+	cache    *cache.Answers
+	sinks    *sinks.Dispatcher
+	post     *postprocess.Processor
+	summary  *summarize.Summarizer
+	queue    *scheduler.Queue
+	mux      *http.ServeMux
+}
+
+// New creates a server; grounder may be nil to disable web search
+func New(cfg *config.Config, ollamaClient *ollama.Client, grounder *grounding.Grounder) *Server {
+	s := &Server{
+		cfg:      cfg,
+		ollama:   ollamaClient,
+		grounder: grounder,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("/v1/models", s.handleModels)
+	s.mux.HandleFunc("/health", s.handleHealth)
+	s.mux.Handle("/metrics", metrics.Handler())
+	s.mux.Handle("/", uiHandler())
+
+	return s
+}
+
+// SetCache enables answer caching for single-turn requests
+func (s *Server) SetCache(c *cache.Answers) {
+	s.cache = c
+}
+
+// SetPostprocessor cleans up answers before they are cached and, for
+// non-streaming requests, returned
+func (s *Server) SetPostprocessor(p *postprocess.Processor) {
+	s.post = p
+}
+
+// SetSummarizer condenses large crawls before they are put into the prompt
+func (s *Server) SetSummarizer(sum *summarize.Summarizer) {
+	s.summary = sum
+}
+
+// SetSinks forwards every completed answer to d
+func (s *Server) SetSinks(d *sinks.Dispatcher) {
+	s.sinks = d
+}
+
+// SetQueue makes chat requests wait their turn in q
+func (s *Server) SetQueue(q *scheduler.Queue) {
+	s.queue = q
+}
+
+// Handle registers an additional handler on the server's mux
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler, counting requests by route and status
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, pattern := s.mux.Handler(r)
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.mux.ServeHTTP(rec, r)
+	metrics.Requests.Inc(pattern, strconv.Itoa(rec.status))
+}
+
+// statusRecorder captures the response status while still supporting streaming
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ListenAndServe serves until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// handleHealth reports whether Ollama is reachable
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if err := s.ollama.HealthCheck(); err != nil {
+		writeFailure(w, err, http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleModels lists the local Ollama models
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	models, err := s.ollama.ListModels()
+	if err != nil {
+		writeFailure(w, err, http.StatusBadGateway)
+		return
+	}
+
+	list := ModelList{Object: "list"}
+	for _, m := range models {
+		list.Data = append(list.Data, ModelObject{ID: m, Object: "model", OwnedBy: "ollama"})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleChatCompletions grounds the last user message with web results and
+// forwards the conversation to Ollama
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var req ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "messages cannot be empty")
+		return
+	}
+
+	model := req.Model
+	if model == "" || model == "web-ollama" {
+		model = s.cfg.ModelName
+	}
+
+	// Ollama would silently cut the start of a conversation that is too long
+	var contents []string
+	for _, m := range req.Messages {
+		contents = append(contents, m.Text())
+	}
+	if err := contextbuilder.CheckFits(contextbuilder.EstimateMessages(contents), contextbuilder.New(s.cfg).Budget); err != nil {
+		writeFailure(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Only fresh single-question conversations can be answered from the
+	// cache. The lookup comes before queueing and grounding, so a hit costs
+	// no searching; /nocache skips it but still refreshes the entry
+	question, noCache := stripNoCache(req.Messages)
+	cacheKey := ""
+	if s.cache != nil && question != "" {
+		cacheKey = cache.Key(question, model, "serve", cacheSources(req.Messages))
+		if entry, ok := s.cache.Get(cacheKey); ok && !noCache {
+			s.replyCached(w, req.Stream, model, question, entry)
+			return
+		}
+	}
+
+	// Wait for earlier questions; streaming clients are told their place
+	var onPosition func(int)
+	if req.Stream {
+		onPosition = func(pos int) { writeQueuePosition(w, pos) }
+	}
+	release, err := s.queue.Enter(r.Context(), onPosition)
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeFailure(w, err, http.StatusServiceUnavailable)
+		}
+		return
+	}
+	defer release()
+
+	ctx, span := tracing.Start(r.Context(), "chat.completions")
+	span.SetAttr("model", model)
+	span.SetAttr("stream", req.Stream)
+	defer span.End(nil)
+
+	messages, shown, grounded := s.prepareMessages(ctx, model, req.Messages)
+	sources := sourceURLs(shown)
+	span.SetAttr("sources", len(sources))
+
+	chatReq := ollama.ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Options:  requestOptions(req, s.cfg.ModelOptions()),
+	}
+
+	id := "chatcmpl-" + uuid.New().String()
+	created := time.Now().Unix()
+
+	if req.Stream {
+		s.streamCompletion(w, ctx, chatReq, cacheKey, id, created, shown, grounded)
+		return
+	}
+
+	answer, gen, err := s.generate(ctx, chatReq, cacheKey, shown, ollama.StreamCallbacks{})
+	if err != nil {
+		writeFailure(w, err, http.StatusBadGateway)
+		return
+	}
+
+	stop := "stop"
+	writeJSON(w, http.StatusOK, ChatCompletion{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      &ReplyMessage{Role: "assistant", Content: attributed(shown, answer)},
+			FinishReason: &stop,
+		}},
+		Usage: Usage{
+			PromptTokens:     gen.stats.PromptTokens,
+			CompletionTokens: gen.stats.AnswerTokens,
+			TotalTokens:      gen.stats.PromptTokens + gen.stats.AnswerTokens,
+		},
+		Sources: sources,
+		Trace:   s.trace(grounded, shown, chatReq.Messages, answer, gen),
+	})
+}
+
+// streamCompletion relays Ollama tokens as OpenAI server-sent events
+func (s *Server) streamCompletion(w http.ResponseWriter, ctx context.Context, chatReq ollama.ChatRequest, cacheKey string, id string, created int64, shown []contextbuilder.Source, grounded grounding.Result) {
+	sources := sourceURLs(shown)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	setEventStream(w)
+
+	var trace *grounding.Trace
+	send := func(delta *ReplyMessage, finish *string, withSources bool) {
+		chunk := ChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   chatReq.Model,
+			Choices: []Choice{{Index: 0, Delta: delta, FinishReason: finish}},
+		}
+		if withSources {
+			chunk.Sources = sources
+			chunk.Trace = trace
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	send(&ReplyMessage{Role: "assistant"}, nil, false)
+
+	answer, gen, err := s.generate(ctx, chatReq, cacheKey, shown, ollama.StreamCallbacks{
+		OnAnswer: func(chunk string) {
+			send(&ReplyMessage{Content: chunk}, nil, false)
+		},
+	})
+	if err != nil {
+		// Headers are already sent; report the failure in-band
+		send(&ReplyMessage{Content: errorPrefix + failureMessage(err) + "]"}, nil, false)
+	} else {
+		trace = s.trace(grounded, shown, chatReq.Messages, answer, gen)
+	}
+
+	// The answer has already been sent, so only the footer reflects the
+	// citation check
+	if footer := sourcesFooter(contextbuilder.Attribute(shown, answer)); footer != "" {
+		send(&ReplyMessage{Content: footer}, nil, false)
+	}
+
+	stop := "stop"
+	send(&ReplyMessage{}, &stop, true)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// setEventStream sets the headers of a server-sent event stream
+func setEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+// writeQueuePosition tells a streaming client where its request waits, as
+// an SSE comment that OpenAI clients skip and the web UI shows
+func writeQueuePosition(w http.ResponseWriter, pos int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return
+	}
+	setEventStream(w)
+	fmt.Fprintf(w, ": queue position %d\n\n", pos)
+	flusher.Flush()
+}
+
+// replyCached answers from a cache entry without grounding. The entry holds
+// the answer as it was shown, sources footer included.
+func (s *Server) replyCached(w http.ResponseWriter, stream bool, model, question string, entry cache.Entry) {
+	s.sinks.Publish(sinks.Record{Mode: "serve", Model: model, Question: question, Answer: entry.Answer, Sources: entry.Sources})
+	trace := s.grounder.Trace(grounding.Result{}, nil, "")
+	trace.Cached = true
+	id := "chatcmpl-" + uuid.New().String()
+	created := time.Now().Unix()
+	stop := "stop"
+
+	if !stream {
+		writeJSON(w, http.StatusOK, ChatCompletion{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   model,
+			Choices: []Choice{{
+				Index:        0,
+				Message:      &ReplyMessage{Role: "assistant", Content: entry.Answer},
+				FinishReason: &stop,
+			}},
+			Sources: entry.Sources,
+			Trace:   trace,
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	setEventStream(w)
+	for _, chunk := range []ChatCompletionChunk{
+		{Choices: []Choice{{Delta: &ReplyMessage{Role: "assistant"}}}},
+		{Choices: []Choice{{Delta: &ReplyMessage{Content: entry.Answer}}}},
+		{Choices: []Choice{{Delta: &ReplyMessage{}, FinishReason: &stop}}, Sources: entry.Sources, Trace: trace},
+	} {
+		chunk.ID, chunk.Object, chunk.Created, chunk.Model = id, "chat.completion.chunk", created, model
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// generation is what generate learned about an answer besides its text
+type generation struct {
+	stats      ollama.Stats
+	regrounded bool // asked again after ignoring its sources
+}
+
+// generate runs the Ollama request, recording the generate stage. With a
+// cache key the answer, as shown with its sources footer, is saved to the
+// answer cache.
+func (s *Server) generate(ctx context.Context, chatReq ollama.ChatRequest, cacheKey string, shown []contextbuilder.Source, callbacks ollama.StreamCallbacks) (string, generation, error) {
+	question := chatReq.Messages[len(chatReq.Messages)-1].Content
+	sources := sourceURLs(shown)
+
+	var gen generation
+	onStats := callbacks.OnStats
+	callbacks.OnStats = func(stats ollama.Stats) {
+		gen.stats = stats
+		if onStats != nil {
+			onStats(stats)
+		}
+	}
+	genCtx, span := tracing.Start(ctx, "generate")
+	start := time.Now()
+	_, answer, regrounded, err := contextbuilder.ChatGrounded(genCtx, s.ollama.ChatWithCallbacks, chatReq, callbacks, len(sources) > 0)
+	gen.regrounded = regrounded
+	metrics.ObserveStage("generate", time.Since(start))
+	span.SetAttr("answer_chars", len(answer))
+	span.End(err)
+
+	if err != nil {
+		return answer, gen, err
+	}
+	answer, _ = s.post.Process(answer)
+
+	if cacheKey != "" {
+		a := contextbuilder.Attribute(shown, answer)
+		entry := cache.Entry{Query: question, Model: chatReq.Model, Answer: a.Answer + sourcesFooter(a), Sources: sources}
+		if err := s.cache.Put(cacheKey, entry); err != nil {
+			slog.Warn("failed to cache answer", "error", err)
+		}
+	}
+	s.sinks.Publish(sinks.Record{Mode: "serve", Model: chatReq.Model, Question: question, Answer: answer, Sources: sources})
+	return answer, gen, nil
+}
+
+// trace describes how an answer was grounded, for the x_trace field
+func (s *Server) trace(grounded grounding.Result, shown []contextbuilder.Source, messages []ollama.Message, answer string, gen generation) *grounding.Trace {
+	trace := s.grounder.Trace(grounded, shown, answer)
+	trace.SetStats(gen.stats)
+	trace.Regrounded = gen.regrounded
+	contents := make([]string, len(messages))
+	for i, m := range messages {
+		contents[i] = m.Content
+	}
+	trace.Tokens.Estimate = contextbuilder.EstimateMessages(contents)
+	trace.Tokens.Budget = contextbuilder.New(s.cfg).Budget
+	return trace
+}
+
+// stripNoCache removes a /nocache prefix from the last user message. It
+// returns that message's text when it is the only turn in the conversation
+// (system messages aside), or "" when the request cannot be cached.
+func stripNoCache(messages []OpenAIMessage) (string, bool) {
+	lastUser, turns := -1, 0
+	for i, m := range messages {
+		if m.Role == "system" {
+			continue
+		}
+		turns++
+		if m.Role == "user" {
+			lastUser = i
+		}
+	}
+	if lastUser < 0 {
+		return "", false
+	}
+
+	text, noCache := cache.StripNoCache(messages[lastUser].Text())
+	if noCache {
+		messages[lastUser].Content, _ = json.Marshal(text)
+	}
+	if turns != 1 {
+		return "", noCache
+	}
+	return text, noCache
+}
+
+// cacheSources lists what a question was asked about besides the web: the
+// client's system messages, which often carry documents, and any images or
+// files attached to the question
+func cacheSources(messages []OpenAIMessage) []string {
+	var sources []string
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			sources = append(sources, "system:"+m.Text())
+		case "user":
+			var parts []struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(m.Content, &parts) != nil {
+				continue // plain text, which the question already covers
+			}
+			for _, part := range parts {
+				if part.Type != "text" {
+					sources = append(sources, "user:"+string(m.Content))
+					break
+				}
+			}
+		}
+	}
+	return sources
+}
+
+// prepareMessages converts the client's messages, adds the configured system
+// prompt when the client sent none, and injects web context before the last
+// user message. Returns the messages, the sources used and what grounding
+// found.
+func (s *Server) prepareMessages(ctx context.Context, model string, in []OpenAIMessage) ([]ollama.Message, []contextbuilder.Source, grounding.Result) {
+	lastUser := -1
+	hasSystem := false
+	for i, m := range in {
+		if m.Role == "user" {
+			lastUser = i
+		}
+		if m.Role == "system" {
+			hasSystem = true
+		}
+	}
+
+	var result grounding.Result
+	if s.grounder != nil && lastUser >= 0 {
+		var recent []analyzer.OllamaMessage
+		for _, m := range in[:lastUser] {
+			recent = append(recent, analyzer.OllamaMessage{Role: m.Role, Content: messageText(m)})
+		}
+		grounded, err := s.grounder.Ground(ctx, in[lastUser].Text(), recent)
+		if err != nil {
+			slog.Warn("grounding failed", "error", err)
+		} else {
+			result = grounded
+		}
+	}
+	if s.summary.Needed(result.Crawled) {
+		result.Crawled = s.summary.Summarize(ctx, in[lastUser].Text(), result.Crawled)
+		result.Context = contextbuilder.SearchContext(result.Crawled)
+	}
+
+	var messages []ollama.Message
+	if !hasSystem {
+		vars := prompt.NewSystemVars(time.Now(), model)
+		vars.MemoryFacts = s.cfg.MemoryFacts
+		vars.SearchEnabled = s.grounder != nil
+		vars.HasSearchContext = result.Context != ""
+		if system, err := prompt.RenderSystem(s.cfg.SystemPrompt, vars); err == nil {
+			messages = append(messages, ollama.Message{Role: "system", Content: system})
+		}
+	}
+
+	for i, m := range in {
+		if i == lastUser && result.Context != "" {
+			messages = append(messages, ollama.Message{
+				Role:    "system",
+				Content: result.Context + "\n" + guard.UntrustedNotice + "\nUse these web results to answer the next message and cite the passage IDs you rely on.",
+			})
+		}
+		messages = append(messages, ollama.Message{Role: m.Role, Content: messageText(m)})
+	}
+
+	return messages, contextbuilder.AllSources(result.Crawled), result
+}
+
+// messageText returns the text of a message as the model should see it.
+// Clients send earlier answers back as they were streamed, so the sources
+// footer and any in-band error the server appended are removed from them.
+func messageText(m OpenAIMessage) string {
+	text := m.Text()
+	if m.Role != "assistant" {
+		return text
+	}
+	if i := strings.LastIndex(text, sourcesHeading); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.LastIndex(text, errorPrefix); i >= 0 && strings.HasSuffix(text, "]") {
+		text = text[:i]
+	}
+	return text
+}
+
+// sourceURLs lists the URLs of the sources in the prompt
+func sourceURLs(sources []contextbuilder.Source) []string {
+	var urls []string
+	for _, s := range sources {
+		urls = append(urls, s.URL)
+	}
+	return urls
+}
+
+// requestOptions maps OpenAI sampling parameters to Ollama options,
+// overriding the configured defaults
+func requestOptions(req ChatCompletionRequest, options map[string]interface{}) map[string]interface{} {
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.MaxTokens != nil {
+		options["num_predict"] = *req.MaxTokens
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if len(req.Stop) > 0 {
+		var stops []string
+		var single string
+		if err := json.Unmarshal(req.Stop, &stops); err == nil {
+			options["stop"] = stops
+		} else if err := json.Unmarshal(req.Stop, &single); err == nil {
+			options["stop"] = []string{single}
+		}
+	}
+	return options
+}
+
+// attributed removes citations of passages that were not in the prompt and
+// appends the sources footer
+func attributed(shown []contextbuilder.Source, answer string) string {
+	a := contextbuilder.Attribute(shown, answer)
+	if len(a.Invalid) > 0 {
+		slog.Warn("removed citations of unknown passages", "citations", a.Invalid)
+	}
+	return a.Answer + sourcesFooter(a)
+}
+
+// sourcesHeading and errorPrefix start what the server appends to an
+// answer, so that messageText can take it off again
+const (
+	sourcesHeading = "\n\n**Sources:**\n"
+	errorPrefix    = "\n\n[error: "
+)
+
+// sourcesFooter renders the sources an answer cites, or every grounding
+// source if it cites none, as a Markdown list
+func sourcesFooter(a contextbuilder.Attribution) string {
+	sources := a.Footer()
+	if len(sources) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sourcesHeading)
+	for _, s := range sources {
+		sb.WriteString(fmt.Sprintf("- [%d] %s\n", s.Number, s.URL))
+	}
+	return sb.String()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Message: message, Type: "server_error"}})
+}
+
+// writeFailure reports err with the status its kind calls for, or fallback,
+// and a hint on how to fix it
+func writeFailure(w http.ResponseWriter, err error, fallback int) {
+	body := ErrorBody{Message: failureMessage(err), Type: "server_error"}
+	switch kind := errs.KindOf(err); kind {
+	case errs.Unknown:
+	case errs.ModelMissing, errs.ContextOverflow:
+		body.Type = "invalid_request_error"
+		body.Code = kind.String()
+	case errs.QueueFull:
+		body.Type = "rate_limit_error"
+		body.Code = kind.String()
+	default:
+		body.Code = kind.String()
+	}
+	writeJSON(w, errs.HTTPStatus(err, fallback), ErrorResponse{Error: body})
+}
+
+// failureMessage is err's text followed by its hint, if it has one
+func failureMessage(err error) string {
+	if hint := errs.Hint(err); hint != "" {
+		return fmt.Sprintf("%v. %s", err, hint)
+	}
+	return err.Error()
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/cache"
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/testkit"
+)
+
+// newTestServer returns an httptest server for Server backed by fakes that
+// always search and find the article fixture
+func newTestServer(t *testing.T) (*httptest.Server, *testkit.Ollama, string) {
+	t.Helper()
+	fakeOllama := testkit.NewOllama(t)
+	fakeOllama.SetSearch("go release")
+	fakeOllama.SetAnswer("Go 1.22 changed loop variables.")
+
+	site := testkit.NewSite(t)
+	source := site.URL + "/article.html"
+	fakeSearch := testkit.NewSearXNG(t)
+	fakeSearch.SetResults("", searxng.SearchResult{Title: "Go 1.22", URL: source})
+
+	cfg := config.NewConfig()
+	cfg.ModelName = "test-model"
+	client := ollama.NewClient(fakeOllama.URL, 10*time.Second)
+	grounder := &grounding.Grounder{
+		Analyzer:   analyzer.NewLLMAnalyzer(client, cfg.ModelName),
+		Searcher:   searxng.NewClient(fakeSearch.URL, 5*time.Second),
+		Fetcher:    crawler.NewCrawler(5*time.Second, 2, 1024*1024, "web-ollama-test"),
+		MaxResults: 5,
+	}
+
+	srv := httptest.NewServer(New(cfg, client, grounder))
+	t.Cleanup(srv.Close)
+	return srv, fakeOllama, source
+}
+
+func TestChatCompletion(t *testing.T) {
+	srv, fakeOllama, source := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model": "web-ollama", "messages": [{"role": "user", "content": "What is new in Go?"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var completion ChatCompletion
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion.Model != "test-model" {
+		t.Errorf("model = %q, want the configured model", completion.Model)
+	}
+	if len(completion.Choices) != 1 || completion.Choices[0].Message == nil {
+		t.Fatalf("unexpected choices: %+v", completion.Choices)
+	}
+	content := completion.Choices[0].Message.Content
+	if !strings.HasPrefix(content, fakeOllama.Answer) || !strings.Contains(content, source) {
+		t.Errorf("content = %q, want the answer followed by the source", content)
+	}
+	if len(completion.Sources) != 1 || completion.Sources[0] != source {
+		t.Errorf("x_sources = %v, want [%s]", completion.Sources, source)
+	}
+
+	trace := completion.Trace
+	if trace == nil {
+		t.Fatal("no x_trace")
+	}
+	if !trace.Analyzer.NeedsSearch || strings.Join(trace.Analyzer.SearchQueries, ",") != "go release" {
+		t.Errorf("trace analyzer = %+v, want the search decision", trace.Analyzer)
+	}
+	if len(trace.Sources) != 1 || trace.Sources[0].URL != source || trace.Sources[0].Trust != 1 ||
+		len(trace.Sources[0].Chunks) == 0 || trace.Sources[0].Chunks[0] != "1.1" {
+		t.Errorf("trace sources = %+v", trace.Sources)
+	}
+	if trace.Tokens.Estimate == 0 {
+		t.Errorf("trace tokens = %+v, want the prompt estimate", trace.Tokens)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	srv, fakeOllama, source := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"stream": true, "messages": [{"role": "user", "content": "What is new in Go?"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	var content strings.Builder
+	var sources []string
+	var trace *grounding.Trace
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("bad chunk %q: %v", data, err)
+		}
+		if d := chunk.Choices[0].Delta; d != nil {
+			content.WriteString(d.Content)
+		}
+		if len(chunk.Sources) > 0 {
+			sources = chunk.Sources
+			trace = chunk.Trace
+		}
+	}
+
+	if !done {
+		t.Error("stream did not end with [DONE]")
+	}
+	if !strings.HasPrefix(content.String(), fakeOllama.Answer) {
+		t.Errorf("streamed content = %q", content.String())
+	}
+	if len(sources) != 1 || sources[0] != source {
+		t.Errorf("sources = %v, want [%s]", sources, source)
+	}
+	if trace == nil || len(trace.Sources) != 1 || trace.Sources[0].URL != source {
+		t.Errorf("final chunk trace = %+v, want the grounding source", trace)
+	}
+}
+
+func TestChatCompletionRejectsBadRequests(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid json", http.MethodPost, "{", http.StatusBadRequest},
+		{"no messages", http.MethodPost, `{"messages": []}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+"/v1/chat/completions", strings.NewReader(tt.body))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatCompletionMapsKnownFailures(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"model not pulled", `{"model": "missing:7b", "messages": [{"role": "user", "content": "hi"}]}`, http.StatusNotFound, "model_missing"},
+		{"conversation too long", `{"messages": [{"role": "user", "content": "` + strings.Repeat("word ", 40000) + `"}]}`, http.StatusRequestEntityTooLarge, "context_overflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body ErrorResponse
+			json.NewDecoder(resp.Body).Decode(&body)
+			if resp.StatusCode != tt.status || body.Error.Code != tt.code {
+				t.Errorf("got %d %q, want %d %q", resp.StatusCode, body.Error.Code, tt.status, tt.code)
+			}
+			if body.Error.Type != "invalid_request_error" {
+				t.Errorf("type = %q, want invalid_request_error", body.Error.Type)
+			}
+		})
+	}
+}
+
+func TestChatCompletionQueues(t *testing.T) {
+	fakeOllama := testkit.NewOllama(t)
+	fakeOllama.SetAnswer("Queued answer.")
+	cfg := config.NewConfig()
+	cfg.ModelName = "test-model"
+	s := New(cfg, ollama.NewClient(fakeOllama.URL, 10*time.Second), nil)
+	queue := scheduler.NewQueue(1, 1)
+	s.SetQueue(queue)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	// Another question holds the only slot
+	release, err := queue.Enter(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"stream": true, "messages": [{"role": "user", "content": "hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": queue position 1\n" {
+		t.Fatalf("first line = %q, want the queue position", line)
+	}
+
+	// The queue is full now
+	full, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"messages": [{"role": "user", "content": "hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body ErrorResponse
+	json.NewDecoder(full.Body).Decode(&body)
+	full.Body.Close()
+	if full.StatusCode != http.StatusTooManyRequests || body.Error.Code != "queue_full" {
+		t.Errorf("got %d %q, want 429 queue_full", full.StatusCode, body.Error.Code)
+	}
+
+	release()
+	rest, _ := io.ReadAll(reader)
+	if !strings.Contains(string(rest), `"finish_reason":"stop"`) || !strings.HasSuffix(string(rest), "data: [DONE]\n\n") {
+		t.Errorf("stream after the wait = %q", rest)
+	}
+}
+
+func TestModelsAndHealth(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list ModelList
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.Data) != 1 || list.Data[0].ID != "test-model" {
+		t.Errorf("models = %+v", list.Data)
+	}
+
+	resp, err = http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("health status = %d", resp.StatusCode)
+	}
+}
+
+func TestChatCompletionCache(t *testing.T) {
+	srv, fakeOllama, _ := newTestServer(t)
+	srv.Config.Handler.(*Server).SetCache(cache.NewAnswers(filepath.Join(t.TempDir(), "answers.json"), time.Hour))
+
+	ask := func(body string) string {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var completion ChatCompletion
+		if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+			t.Fatal(err)
+		}
+		return completion.Choices[0].Message.Content
+	}
+	generations := func() int {
+		n := 0
+		for _, req := range fakeOllama.Requests() {
+			if testkit.IsGeneration(req) {
+				n++
+			}
+		}
+		return n
+	}
+
+	single := `{"messages": [{"role": "user", "content": "What is new in Go?"}]}`
+	first := ask(single)
+	requests := len(fakeOllama.Requests())
+	fakeOllama.SetAnswer("Something else entirely.")
+	if second := ask(single); second != first || len(fakeOllama.Requests()) != requests {
+		t.Errorf("repeat: got %q after %d Ollama requests, want the cached answer without analysis or generation", second, len(fakeOllama.Requests())-requests)
+	}
+
+	fresh := ask(`{"messages": [{"role": "user", "content": "/nocache What is new in Go?"}]}`)
+	if !strings.HasPrefix(fresh, "Something else") {
+		t.Errorf("/nocache: got %q, want a fresh answer", fresh)
+	}
+	if again := ask(single); again != fresh {
+		t.Errorf("after /nocache: got %q, want the refreshed %q", again, fresh)
+	}
+
+	// Clients put documents in a system message; each is its own context
+	report := `{"messages": [{"role": "system", "content": "Document: report.pdf"}, {"role": "user", "content": "What is new in Go?"}]}`
+	notes := `{"messages": [{"role": "system", "content": "Document: notes.md"}, {"role": "user", "content": "What is new in Go?"}]}`
+	before := generations()
+	ask(report)
+	ask(notes)
+	ask(report)
+	if generations() != before+2 {
+		t.Errorf("documents: %d generations, want one for each document and none for the repeat", generations()-before)
+	}
+
+	multiTurn := `{"messages": [{"role": "user", "content": "hi"}, {"role": "assistant", "content": "hello"}, {"role": "user", "content": "What is new in Go?"}]}`
+	before = generations()
+	ask(multiTurn)
+	ask(multiTurn)
+	if generations() != before+2 {
+		t.Error("multi-turn conversations should not be cached")
+	}
+}
+
+func TestEarlierAnswersLoseTheirFooter(t *testing.T) {
+	srv, fakeOllama, _ := newTestServer(t)
+
+	earlier := "Go 1.22 changed loop variables [1.1]." +
+		"\n\n[error: the model stopped]" +
+		"\n\n**Sources:**\n- [1] https://go.dev/blog\n"
+	content, _ := json.Marshal(earlier)
+	body, _ := json.Marshal(ChatCompletionRequest{Messages: []OpenAIMessage{
+		{Role: "user", Content: json.RawMessage(`"What is new in Go?"`)},
+		{Role: "assistant", Content: content},
+		{Role: "user", Content: json.RawMessage(`"And in 1.23?"`)},
+	}})
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	req, ok := fakeOllama.LastStreamed()
+	if !ok {
+		t.Fatal("no generation request")
+	}
+	for _, m := range req.Messages {
+		if m.Role == "assistant" && m.Content != "Go 1.22 changed loop variables [1.1]." {
+			t.Errorf("earlier answer sent as %q, want only its text", m.Content)
+		}
+	}
+}
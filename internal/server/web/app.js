@@ -0,0 +1,209 @@
+// web-ollama browser UI: chats are kept in localStorage and sent to the
+// server's OpenAI-compatible endpoint, which adds web search results.
+(function () {
+  "use strict";
+
+  const storageKey = "web-ollama-sessions";
+  const $ = (id) => document.getElementById(id);
+
+  let sessions = load();
+  let current = sessions[0] || newSession();
+  let controller = null;
+
+  function load() {
+    try {
+      return JSON.parse(localStorage.getItem(storageKey)) || [];
+    } catch (e) {
+      return [];
+    }
+  }
+
+  function save() {
+    localStorage.setItem(storageKey, JSON.stringify(sessions));
+  }
+
+  function newSession() {
+    const session = { id: Date.now().toString(36), title: "New chat", messages: [] };
+    sessions.unshift(session);
+    save();
+    return session;
+  }
+
+  function renderSessions() {
+    const list = $("session-list");
+    list.innerHTML = "";
+    for (const session of sessions) {
+      const li = document.createElement("li");
+      li.textContent = session.title;
+      li.className = session === current ? "active" : "";
+      li.onclick = () => {
+        current = session;
+        render();
+      };
+      list.appendChild(li);
+    }
+  }
+
+  function renderMessages() {
+    const box = $("messages");
+    box.innerHTML = "";
+    current.messages.forEach((msg, i) => box.appendChild(messageElement(msg, i)));
+    box.scrollTop = box.scrollHeight;
+    const lastAnswer = current.messages.filter((m) => m.role === "assistant").pop();
+    renderSources(lastAnswer ? lastAnswer.sources : []);
+  }
+
+  function messageElement(msg, index) {
+    const div = document.createElement("div");
+    div.className = "message " + msg.role;
+    div.dataset.index = index;
+    const role = document.createElement("div");
+    role.className = "role";
+    role.textContent = msg.role === "user" ? "You" : "Assistant";
+    const body = document.createElement("div");
+    body.className = "body";
+    body.textContent = withNote(msg);
+    div.append(role, body);
+    if (msg.role === "assistant") {
+      div.onclick = () => {
+        document.querySelectorAll(".message.selected").forEach((el) => el.classList.remove("selected"));
+        div.classList.add("selected");
+        renderSources(msg.sources);
+      };
+    }
+    return div;
+  }
+
+  function renderSources(sources) {
+    const list = $("source-list");
+    list.innerHTML = "";
+    for (const url of sources || []) {
+      const li = document.createElement("li");
+      const a = document.createElement("a");
+      a.href = url;
+      a.target = "_blank";
+      a.rel = "noopener";
+      a.textContent = url;
+      li.appendChild(a);
+      list.appendChild(li);
+    }
+    $("no-sources").hidden = (sources || []).length > 0;
+  }
+
+  function render() {
+    renderSessions();
+    renderMessages();
+  }
+
+  async function send(text) {
+    const session = current;
+    session.messages.push({ role: "user", content: text });
+    if (session.title === "New chat") {
+      session.title = text.slice(0, 40);
+    }
+    const answer = { role: "assistant", content: "", sources: [] };
+    session.messages.push(answer);
+    render();
+
+    const bodyEl = $("messages").lastChild.querySelector(".body");
+    controller = new AbortController();
+    setBusy(true);
+
+    try {
+      const resp = await fetch("/v1/chat/completions", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          model: "web-ollama",
+          stream: true,
+          messages: session.messages
+            .slice(0, -1)
+            .filter((m) => m.content)
+            .map((m) => ({ role: m.role, content: m.content })),
+        }),
+        signal: controller.signal,
+      });
+      if (!resp.ok) {
+        const err = await resp.json().catch(() => ({}));
+        throw new Error((err.error && err.error.message) || resp.statusText);
+      }
+
+      const reader = resp.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = "";
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+        const events = buffer.split("\n\n");
+        buffer = events.pop();
+        for (const event of events) {
+          if (event.startsWith(":")) {
+            const queued = event.match(/queue position (\d+)/);
+            if (queued && !answer.content) {
+              bodyEl.textContent = "Waiting for earlier questions (position " + queued[1] + ")...";
+            }
+            continue;
+          }
+          const data = event.replace(/^data: /, "");
+          if (data === "[DONE]") continue;
+          const chunk = JSON.parse(data);
+          const delta = chunk.choices[0].delta || {};
+          if (delta.content) {
+            answer.content += delta.content;
+            bodyEl.textContent = answer.content;
+            $("messages").scrollTop = $("messages").scrollHeight;
+          }
+          if (chunk.x_sources) {
+            answer.sources = chunk.x_sources;
+            renderSources(answer.sources);
+          }
+        }
+      }
+    } catch (e) {
+      // Kept apart from the content so it is not sent back to the model
+      answer.note = e.name === "AbortError" ? "[stopped]" : "[error: " + e.message + "]";
+      bodyEl.textContent = withNote(answer);
+    } finally {
+      controller = null;
+      setBusy(false);
+      save();
+      renderSessions();
+    }
+  }
+
+  function withNote(msg) {
+    return msg.note ? msg.content + (msg.content ? "\n\n" : "") + msg.note : msg.content;
+  }
+
+  function setBusy(busy) {
+    $("send").hidden = busy;
+    $("stop").hidden = !busy;
+    $("input").disabled = busy;
+  }
+
+  $("composer").onsubmit = (e) => {
+    e.preventDefault();
+    const text = $("input").value.trim();
+    if (!text || controller) return;
+    $("input").value = "";
+    send(text);
+  };
+
+  $("input").onkeydown = (e) => {
+    if (e.key === "Enter" && !e.shiftKey) {
+      e.preventDefault();
+      $("composer").requestSubmit();
+    }
+  };
+
+  $("stop").onclick = () => controller && controller.abort();
+
+  $("new-session").onclick = () => {
+    current = newSession();
+    render();
+    $("input").focus();
+  };
+
+  render();
+})();
@@ -0,0 +1,201 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/notify"
+)
+
+// Record is one completed question and answer
+type Record struct {
+	Time     time.Time `json:"time"`
+	Mode     string    `json:"mode"` // chat, batch, bot or serve
+	Model    string    `json:"model"`
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+	Sources  []string  `json:"sources"`
+}
+
+// Sink receives completed answers
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, rec Record) error
+}
+
+// New creates a sink from its configuration
+func New(c config.SinkConfig) (Sink, error) {
+	switch c.Type {
+	case "file":
+		format := c.Format
+		if format == "" {
+			format = "jsonl"
+			if ext := strings.ToLower(filepath.Ext(c.Path)); ext == ".md" || ext == ".markdown" {
+				format = "markdown"
+			}
+		}
+		return &fileSink{path: c.Path, format: format}, nil
+	case "webhook":
+		return &webhookSink{url: c.URL}, nil
+	case "command":
+		return &commandSink{command: c.Command}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", c.Type)
+	}
+}
+
+// Dispatcher delivers records to every sink in the background
+type Dispatcher struct {
+	sinks []Sink
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher builds the configured sinks
+func NewDispatcher(configs []config.SinkConfig) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for _, c := range configs {
+		sink, err := New(c)
+		if err != nil {
+			return nil, err
+		}
+		d.sinks = append(d.sinks, sink)
+	}
+	return d, nil
+}
+
+// Publish sends rec to all sinks without blocking the caller. Failures are
+// logged rather than returned so a broken sink never interrupts answering.
+func (d *Dispatcher) Publish(rec Record) {
+	if d == nil {
+		return
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if rec.Sources == nil {
+		rec.Sources = []string{}
+	}
+
+	for _, sink := range d.sinks {
+		d.wg.Add(1)
+		go func(sink Sink) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := sink.Send(ctx, rec); err != nil {
+				slog.Warn("sink failed", "sink", sink.Name(), "error", err)
+			}
+		}(sink)
+	}
+}
+
+// Close waits for pending deliveries
+func (d *Dispatcher) Close() {
+	if d != nil {
+		d.wg.Wait()
+	}
+}
+
+// fileSink appends records to a JSON Lines or Markdown file
+type fileSink struct {
+	path   string
+	format string
+	mu     sync.Mutex
+}
+
+func (s *fileSink) Name() string { return "file:" + s.path }
+
+func (s *fileSink) Send(ctx context.Context, rec Record) error {
+	var entry []byte
+	if s.format == "markdown" {
+		entry = []byte(formatMarkdown(rec))
+	} else {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to marshal record: %w", err)
+		}
+		entry = append(data, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sink directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open sink file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(entry); err != nil {
+		return fmt.Errorf("failed to write sink file: %w", err)
+	}
+	return nil
+}
+
+// formatMarkdown renders a record as a Markdown note section
+func formatMarkdown(rec Record) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## %s\n\n", rec.Question))
+	sb.WriteString(fmt.Sprintf("*%s · %s*\n\n", rec.Time.Format("2006-01-02 15:04"), rec.Model))
+	sb.WriteString(strings.TrimSpace(rec.Answer))
+	sb.WriteString("\n\n")
+	if len(rec.Sources) > 0 {
+		sb.WriteString("Sources:\n")
+		for _, url := range rec.Sources {
+			sb.WriteString(fmt.Sprintf("- %s\n", url))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// webhookSink POSTs each record as JSON
+type webhookSink struct {
+	url string
+}
+
+func (s *webhookSink) Name() string { return "webhook:" + s.url }
+
+func (s *webhookSink) Send(ctx context.Context, rec Record) error {
+	return notify.Webhook(ctx, s.url, rec)
+}
+
+// commandSink runs a shell command with the record as JSON on stdin
+type commandSink struct {
+	command string
+}
+
+func (s *commandSink) Name() string { return "command:" + s.command }
+
+func (s *commandSink) Send(ctx context.Context, rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"WEB_OLLAMA_QUESTION="+rec.Question,
+		"WEB_OLLAMA_MODEL="+rec.Model,
+		"WEB_OLLAMA_MODE="+rec.Mode,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package sinks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/config"
+)
+
+var testRecord = Record{
+	Mode:     "batch",
+	Model:    "test-model",
+	Question: "What is new in Go?",
+	Answer:   "Go 1.22 changed loop variables.",
+	Sources:  []string{"https://go.dev/blog/go1.22"},
+}
+
+func TestDispatcherDeliversToAllSinks(t *testing.T) {
+	dir := t.TempDir()
+
+	var received Record
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer hook.Close()
+
+	d, err := NewDispatcher([]config.SinkConfig{
+		{Type: "file", Path: filepath.Join(dir, "answers.jsonl")},
+		{Type: "file", Path: filepath.Join(dir, "notes.md")},
+		{Type: "webhook", URL: hook.URL},
+		{Type: "command", Command: "cat > " + filepath.Join(dir, "stdin.json")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Publish(testRecord)
+	d.Publish(testRecord)
+	d.Close()
+
+	jsonl, _ := os.ReadFile(filepath.Join(dir, "answers.jsonl"))
+	lines := strings.Split(strings.TrimSpace(string(jsonl)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("jsonl has %d lines, want 2", len(lines))
+	}
+	var rec Record
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil || rec.Question != testRecord.Question {
+		t.Errorf("jsonl record = %+v, %v", rec, err)
+	}
+
+	notes, _ := os.ReadFile(filepath.Join(dir, "notes.md"))
+	if !strings.Contains(string(notes), "## What is new in Go?") || !strings.Contains(string(notes), "- https://go.dev/blog/go1.22") {
+		t.Errorf("markdown sink wrote:\n%s", notes)
+	}
+
+	if received.Answer != testRecord.Answer {
+		t.Errorf("webhook received %+v", received)
+	}
+
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	if err := json.Unmarshal(stdin, &rec); err != nil || rec.Model != "test-model" {
+		t.Errorf("command stdin = %q", stdin)
+	}
+}
+
+func TestNewRejectsUnknownType(t *testing.T) {
+	if _, err := New(config.SinkConfig{Type: "carrier-pigeon"}); err == nil {
+		t.Error("expected an error for an unknown sink type")
+	}
+}
+
+func TestNilDispatcherIsNoop(t *testing.T) {
+	var d *Dispatcher
+	d.Publish(testRecord)
+	d.Close()
+}
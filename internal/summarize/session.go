@@ -0,0 +1,74 @@
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/history"
+	"web-ollama/internal/postprocess"
+)
+
+// Limits on how much of a session goes into its summary prompt; the newest
+// messages are kept
+const (
+	maxMessageChars    = 1500
+	maxTranscriptChars = 24000
+)
+
+// Session writes a one-paragraph summary of a conversation, stored on the
+// session when it is closed and used to prime the model when it is resumed
+func Session(ctx context.Context, client Client, model string, messages []history.Message) (string, error) {
+	transcript := transcript(messages)
+	if transcript == "" {
+		return "", fmt.Errorf("failed to summarize session: no messages")
+	}
+
+	prompt := fmt.Sprintf(`Summarize the conversation below in one paragraph of at most 120 words.
+
+Say what the user wanted to find out, the main facts and conclusions reached (with figures, names and dates), and any question left open. Write it so the assistant could pick the conversation back up from the summary alone. Reply with the paragraph only.
+
+%s`, transcript)
+
+	response, err := client.ChatSync(ctx, model, []message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize session: %w", err)
+	}
+	summary := strings.Join(strings.Fields(postprocess.StripThinkTags(response)), " ")
+	if summary == "" {
+		return "", fmt.Errorf("failed to summarize session: empty response")
+	}
+	return summary, nil
+}
+
+// transcript renders messages as "User:"/"Assistant:" lines, truncating long
+// messages and dropping the oldest ones beyond the limit
+func transcript(messages []history.Message) string {
+	var lines []string
+	total := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		content := strings.TrimSpace(m.Content)
+		if content == "" || (m.Role != "user" && m.Role != "assistant") {
+			continue
+		}
+		if r := []rune(content); len(r) > maxMessageChars {
+			content = string(r[:maxMessageChars]) + "..."
+		}
+		role := "User"
+		if m.Role == "assistant" {
+			role = "Assistant"
+		}
+		line := role + ": " + content
+		if total+len(line) > maxTranscriptChars && len(lines) > 0 {
+			break
+		}
+		total += len(line)
+		lines = append(lines, line)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n\n")
+}
@@ -0,0 +1,63 @@
+package summarize
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/history"
+)
+
+// replyClient answers every call with reply and keeps the last prompt
+type replyClient struct {
+	reply  string
+	prompt string
+}
+
+func (c *replyClient) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	c.prompt = messages.([]message)[0].Content
+	return c.reply, nil
+}
+
+func TestSessionSummary(t *testing.T) {
+	client := &replyClient{reply: "<think>ok</think>\nThe user compared\n laptops."}
+	messages := []history.Message{
+		{Role: "user", Content: "Framework 16 or XPS 16?"},
+		{Role: "assistant", Content: "The Framework is repairable."},
+		{Role: "system", Content: "ignored"},
+	}
+
+	summary, err := Session(context.Background(), client, "m", messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "The user compared laptops." {
+		t.Errorf("summary = %q, want one clean paragraph", summary)
+	}
+	if !strings.Contains(client.prompt, "User: Framework 16 or XPS 16?\n\nAssistant: The Framework is repairable.") {
+		t.Errorf("prompt lacks the transcript:\n%s", client.prompt)
+	}
+	if strings.Contains(client.prompt, "ignored") {
+		t.Error("system messages should not be summarized")
+	}
+
+	if _, err := Session(context.Background(), client, "m", nil); err == nil {
+		t.Error("want an error for an empty session")
+	}
+}
+
+func TestTranscriptKeepsNewestMessages(t *testing.T) {
+	var messages []history.Message
+	for i := 0; i < 40; i++ {
+		messages = append(messages, history.Message{Role: "user", Content: strings.Repeat("x", 2000)})
+	}
+	messages = append(messages, history.Message{Role: "assistant", Content: "latest"})
+
+	got := transcript(messages)
+	if len(got) > maxTranscriptChars+100 {
+		t.Errorf("transcript is %d chars, want about %d", len(got), maxTranscriptChars)
+	}
+	if !strings.HasSuffix(got, "Assistant: latest") {
+		t.Error("newest message missing")
+	}
+}
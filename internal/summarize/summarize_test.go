@@ -0,0 +1,101 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+)
+
+// fakeClient summarizes a page as "summary of <url>", failing for URLs
+// containing "fail"
+type fakeClient struct {
+	mu     sync.Mutex
+	models []string
+}
+
+func (f *fakeClient) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	f.mu.Lock()
+	f.models = append(f.models, model)
+	f.mu.Unlock()
+
+	prompt := messages.([]message)[0].Content
+	url := strings.TrimPrefix(strings.Split(prompt[strings.Index(prompt, "URL: "):], "\n")[0], "URL: ")
+	if strings.Contains(url, "fail") {
+		return "", errors.New("model unavailable")
+	}
+	return "<think>skim it</think>summary of " + url, nil
+}
+
+func page(url string, words int) crawler.CrawlResult {
+	return crawler.CrawlResult{URL: url, Title: url, Content: strings.Repeat("word ", words)}
+}
+
+func TestSummarizeCondensesLongPages(t *testing.T) {
+	client := &fakeClient{}
+	s := &Summarizer{Client: client, Model: "fast", Words: 50}
+	in := []crawler.CrawlResult{
+		page("https://a.example", 500),
+		page("https://short.example", 20),
+		page("https://fail.example", 500),
+		{URL: "https://broken.example", Error: errors.New("timeout")},
+	}
+
+	out := s.Summarize(context.Background(), "q", in)
+
+	if out[0].Content != "summary of https://a.example" {
+		t.Errorf("long page = %q, want its summary without think tags", out[0].Content)
+	}
+	if out[1].Content != in[1].Content {
+		t.Error("a page shorter than a summary should be kept as is")
+	}
+	if out[2].Content != in[2].Content {
+		t.Error("a page whose summary failed should keep its text")
+	}
+	if in[0].Content == out[0].Content {
+		t.Error("the input slice should not be modified")
+	}
+	if len(client.models) != 2 || client.models[0] != "fast" {
+		t.Errorf("calls = %v, want two with the summary model", client.models)
+	}
+}
+
+func TestNeeded(t *testing.T) {
+	s := &Summarizer{Words: 50, Budget: 2000}
+	if s.Needed([]crawler.CrawlResult{page("https://a.example", 100)}) {
+		t.Error("a small crawl should be quoted directly")
+	}
+	var large []crawler.CrawlResult
+	for i := 0; i < 15; i++ {
+		large = append(large, page("https://a.example", 300))
+	}
+	if !s.Needed(large) {
+		t.Error("15 long pages should not fit half of a 2000 token budget")
+	}
+
+	var off *Summarizer
+	if off.Needed(large) {
+		t.Error("a nil summarizer should never summarize")
+	}
+}
+
+func TestNewUsesMainModelByDefault(t *testing.T) {
+	cfg := config.NewConfig()
+	if New(&fakeClient{}, cfg) != nil {
+		t.Error("summarization should be off by default")
+	}
+
+	cfg.SummarizeSources = true
+	s := New(&fakeClient{}, cfg)
+	if s.Model != cfg.ModelName || s.Words != cfg.SummaryWords {
+		t.Errorf("got model %q and %d words, want the config's", s.Model, s.Words)
+	}
+	cfg.SummaryModel = "qwen2.5:0.5b"
+	if s := New(&fakeClient{}, cfg); s.Model != "qwen2.5:0.5b" {
+		t.Errorf("model = %q, want summary_model", s.Model)
+	}
+}
@@ -117,7 +117,8 @@ func ShowFileSuggestions(workingDir string, query string) {
 }
 
 // ListenForESC listens for ESC key press in a goroutine and sends signal when pressed
-// Returns a channel that will receive true when ESC is pressed
+// Returns a channel that will receive true when ESC or Ctrl+C is pressed. In
+// raw mode Ctrl+C arrives as a byte rather than an interrupt signal.
 func ListenForESC() chan bool {
 	escChan := make(chan bool, 1)
 
@@ -139,8 +140,8 @@ func ListenForESC() chan bool {
 				break
 			}
 
-			// ESC key is byte 27
-			if buf[0] == 27 {
+			// ESC key is byte 27, Ctrl+C byte 3
+			if buf[0] == 27 || buf[0] == 3 {
 				escChan <- true
 				break
 			}
@@ -0,0 +1,193 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"web-ollama/internal/ollama"
+)
+
+// Ollama is a fake Ollama server. Non-streaming and JSON-format chat requests
+// (used by the search analyzer) are answered with Decision; other streaming
+// requests with Answer. Streamed replies are split into a few chunks.
+// Requests for a model not in Models fail as they do in Ollama.
+type Ollama struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	Models   []string
+	Decision string // raw analyzer response, usually JSON
+	Answer   string
+	Thinking string
+	requests []ollama.ChatRequest
+}
+
+// NewOllama starts a fake Ollama that never asks for a web search
+func NewOllama(t testing.TB) *Ollama {
+	t.Helper()
+	o := &Ollama{
+		Models:   []string{"test-model"},
+		Decision: `{"needs_search": false, "search_queries": [], "reason": "general knowledge"}`,
+		Answer:   "This is a test answer.",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", o.handleTags)
+	mux.HandleFunc("/api/chat", o.handleChat)
+	mux.HandleFunc("/api/embed", o.handleEmbed)
+	mux.HandleFunc("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"done": true})
+	})
+
+	o.Server = httptest.NewServer(mux)
+	t.Cleanup(o.Close)
+	return o
+}
+
+// SetSearch makes the analyzer ask for a web search with the given queries
+func (o *Ollama) SetSearch(queries ...string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"needs_search":   true,
+		"search_queries": queries,
+		"reason":         "current information",
+	})
+	o.mu.Lock()
+	o.Decision = string(data)
+	o.mu.Unlock()
+}
+
+// SetAnswer changes the answer streamed for generation requests
+func (o *Ollama) SetAnswer(answer string) {
+	o.mu.Lock()
+	o.Answer = answer
+	o.mu.Unlock()
+}
+
+// Requests returns the chat requests received so far
+func (o *Ollama) Requests() []ollama.ChatRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]ollama.ChatRequest(nil), o.requests...)
+}
+
+// LastStreamed returns the most recent streaming generation request
+func (o *Ollama) LastStreamed() (ollama.ChatRequest, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.requests) - 1; i >= 0; i-- {
+		if IsGeneration(o.requests[i]) {
+			return o.requests[i], true
+		}
+	}
+	return ollama.ChatRequest{}, false
+}
+
+// IsGeneration reports whether req asks for an answer rather than an analysis
+func IsGeneration(req ollama.ChatRequest) bool {
+	return req.Stream && len(req.Format) == 0
+}
+
+func (o *Ollama) handleTags(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var models []map[string]string
+	for _, m := range o.Models {
+		models = append(models, map[string]string{"name": m})
+	}
+	writeJSON(w, map[string]interface{}{"models": models})
+}
+
+func (o *Ollama) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req ollama.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	o.requests = append(o.requests, req)
+	decision, answer, thinking := o.Decision, o.Answer, o.Thinking
+	installed := false
+	for _, m := range o.Models {
+		installed = installed || m == req.Model
+	}
+	o.mu.Unlock()
+
+	if !installed {
+		// What Ollama answers for a model that has not been pulled
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("model '%s' not found, try pulling it first", req.Model)})
+		return
+	}
+
+	if !req.Stream {
+		writeJSON(w, ollama.ChatResponse{
+			Model:   req.Model,
+			Message: ollama.Message{Role: "assistant", Content: decision},
+			Done:    true,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	if len(req.Format) > 0 {
+		answer, thinking = decision, ""
+	}
+	if thinking != "" {
+		enc.Encode(ollama.ChatResponse{Model: req.Model, Message: ollama.Message{Role: "assistant", Thinking: thinking}})
+	}
+	for _, chunk := range splitChunks(answer, 3) {
+		enc.Encode(ollama.ChatResponse{Model: req.Model, Message: ollama.Message{Role: "assistant", Content: chunk}})
+	}
+	enc.Encode(ollama.ChatResponse{Model: req.Model, Done: true})
+}
+
+// handleEmbed returns a small deterministic vector per input based on its words
+func (o *Ollama) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req ollama.EmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ollama.EmbedResponse{Model: req.Model}
+	for _, input := range req.Input {
+		vec := make([]float32, 8)
+		for _, word := range strings.Fields(strings.ToLower(input)) {
+			var h uint32
+			for _, c := range word {
+				h = h*31 + uint32(c)
+			}
+			vec[h%8]++
+		}
+		resp.Embeddings = append(resp.Embeddings, vec)
+	}
+	writeJSON(w, resp)
+}
+
+// splitChunks splits s into at most n pieces of roughly equal size
+func splitChunks(s string, n int) []string {
+	size := (len(s) + n - 1) / n
+	if size == 0 {
+		return nil
+	}
+	var chunks []string
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
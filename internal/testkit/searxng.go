@@ -0,0 +1,66 @@
+package testkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"web-ollama/internal/searxng"
+)
+
+// SearXNG is a fake SearXNG server returning canned results per query
+type SearXNG struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	results map[string][]searxng.SearchResult // by query; "" is the fallback
+	queries []string
+}
+
+// NewSearXNG starts a fake SearXNG with no results
+func NewSearXNG(t testing.TB) *SearXNG {
+	t.Helper()
+	s := &SearXNG{results: make(map[string][]searxng.SearchResult)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handleSearch))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetResults sets the results for a query; use "" for any other query
+func (s *SearXNG) SetResults(query string, results ...searxng.SearchResult) {
+	s.mu.Lock()
+	s.results[query] = results
+	s.mu.Unlock()
+}
+
+// Queries returns the queries searched so far, excluding health checks
+func (s *SearXNG) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *SearXNG) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/search" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("format") != "json" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	s.mu.Lock()
+	results, ok := s.results[query]
+	if !ok {
+		results = s.results[""]
+	}
+	if query != "test" {
+		s.queries = append(s.queries, query)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, searxng.SearchResponse{Query: query, NumberOfResults: len(results), Results: results})
+}
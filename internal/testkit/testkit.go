@@ -0,0 +1,67 @@
+// Package testkit provides fake Ollama and SearXNG servers and HTML page
+// fixtures for tests that exercise the full ask pipeline without network access.
+package testkit
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+//go:embed fixtures/*.html
+var fixtures embed.FS
+
+// Fixture returns the contents of a page fixture, e.g. "article.html"
+func Fixture(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := fixtures.ReadFile(path.Join("fixtures", name))
+	if err != nil {
+		t.Fatalf("missing fixture %s: %v", name, err)
+	}
+	return data
+}
+
+// FixtureNames lists the available page fixtures
+func FixtureNames() []string {
+	entries, _ := fixtures.ReadDir("fixtures")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// NewSite serves the page fixtures over HTTP, e.g. <URL>/article.html.
+// Paths under /status/<code> return that status code; paths under /slow/
+// never answer, holding the request until the client gives up.
+func NewSite(t testing.TB) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status/", func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/status/"))
+		if err != nil || code < 400 || code > 599 {
+			code = http.StatusInternalServerError
+		}
+		http.Error(w, http.StatusText(code), code)
+	})
+	mux.HandleFunc("/slow/", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		data, err := fixtures.ReadFile(path.Join("fixtures", path.Base(r.URL.Path)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
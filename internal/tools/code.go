@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/sandbox"
+)
+
+// CodeToolName is the name the model uses to run code
+const CodeToolName = "run_code"
+
+// defaultMaxRounds bounds how many times the model may run code per answer
+const defaultMaxRounds = 4
+
+// CodeTool describes the code-execution tool to the model
+func CodeTool() ollama.Tool {
+	return ollama.Tool{
+		Type: "function",
+		Function: ollama.ToolFunction{
+			Name: CodeToolName,
+			Description: "Run a short Python or Go program and get its output. Use it for arithmetic, " +
+				"statistics, unit conversions or processing numbers found in the sources instead of " +
+				"calculating in your head. Print every result you need. There is no network access and " +
+				"only the working directory can be written.",
+			Parameters: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"language", "code"},
+				"properties": map[string]interface{}{
+					"language": map[string]interface{}{
+						"type": "string",
+						"enum": sandbox.Languages,
+					},
+					"code": map[string]interface{}{
+						"type":        "string",
+						"description": "Complete program source. Go code must be package main with a main function.",
+					},
+				},
+			},
+		},
+	}
+}
+
+// Chatter streams a chat response
+type Chatter interface {
+	ChatWithCallbacks(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error)
+}
+
+// Loop answers a chat request while letting the model call tools: run code
+// in a sandbox, and any others in Tools
+type Loop struct {
+	Client    Chatter
+	Sandbox   *sandbox.Sandbox // offers run_code when set
+	Tools     []Tool
+	MaxRounds int // tool rounds before the model must answer; 0 uses the default
+
+	// OnRun, if set, is called after each snippet runs
+	OnRun func(language, code string, result sandbox.Result, err error)
+	// OnCall, if set, is called before each call of one of Tools
+	OnCall func(call ollama.ToolCall)
+}
+
+// Chat works like ollama.Client.ChatWithCallbacks, running the tools the
+// model calls and feeding their output back until the model answers
+func (l *Loop) Chat(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error) {
+	maxRounds := l.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxRounds
+	}
+
+	messages := append([]ollama.Message(nil), req.Messages...)
+	req.Tools = append([]ollama.Tool(nil), req.Tools...)
+	if l.Sandbox != nil {
+		req.Tools = append(req.Tools, CodeTool())
+	}
+	for _, tool := range l.Tools {
+		req.Tools = append(req.Tools, tool.Spec)
+	}
+
+	var thinking, answer []string
+	for round := 0; ; round++ {
+		if round == maxRounds {
+			req.Tools = nil // no more tool calls, answer with what you have
+		}
+		req.Messages = messages
+
+		var calls []ollama.ToolCall
+		cb := callbacks
+		cb.OnToolCall = func(call ollama.ToolCall) {
+			calls = append(calls, call)
+		}
+
+		t, a, err := l.Client.ChatWithCallbacks(ctx, req, cb)
+		if t != "" {
+			thinking = append(thinking, t)
+		}
+		if a != "" {
+			answer = append(answer, a)
+		}
+		if err != nil || len(calls) == 0 {
+			return strings.Join(thinking, "\n\n"), strings.Join(answer, "\n\n"), err
+		}
+
+		messages = append(messages, ollama.Message{Role: "assistant", Content: a, ToolCalls: calls})
+		for _, call := range calls {
+			messages = append(messages, ollama.Message{
+				Role:     "tool",
+				ToolName: call.Function.Name,
+				Content:  l.execute(ctx, call),
+			})
+		}
+	}
+}
+
+// execute runs one tool call and formats the outcome for the model
+func (l *Loop) execute(ctx context.Context, call ollama.ToolCall) string {
+	for _, tool := range l.Tools {
+		if tool.Spec.Function.Name == call.Function.Name {
+			if l.OnCall != nil {
+				l.OnCall(call)
+			}
+			return tool.Run(ctx, call.Function.Arguments)
+		}
+	}
+	if call.Function.Name != CodeToolName || l.Sandbox == nil {
+		return fmt.Sprintf("error: unknown tool %q", call.Function.Name)
+	}
+
+	language, _ := call.Function.Arguments["language"].(string)
+	code, _ := call.Function.Arguments["code"].(string)
+	if strings.TrimSpace(code) == "" {
+		return "error: no code given"
+	}
+
+	result, err := l.Sandbox.Run(ctx, strings.ToLower(language), code)
+	slog.Info("ran code", "language", language, "exit", result.ExitCode, "timed_out", result.TimedOut,
+		"duration", result.Duration, "error", err)
+	if l.OnRun != nil {
+		l.OnRun(language, code, result, err)
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return FormatResult(result)
+}
+
+// FormatResult renders a run's output for the model
+func FormatResult(r sandbox.Result) string {
+	var sb strings.Builder
+	if r.TimedOut {
+		sb.WriteString("The program was stopped because it ran too long.\n")
+	}
+	sb.WriteString(fmt.Sprintf("exit code: %d\n", r.ExitCode))
+	if r.Stdout != "" {
+		sb.WriteString("stdout:\n" + r.Stdout + "\n")
+	}
+	if r.Stderr != "" {
+		sb.WriteString("stderr:\n" + r.Stderr + "\n")
+	}
+	if r.Stdout == "" && r.Stderr == "" {
+		sb.WriteString("(no output; remember to print results)\n")
+	}
+	return sb.String()
+}
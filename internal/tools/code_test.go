@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/sandbox"
+)
+
+// scriptedChat replays tool calls, then answers with the last tool output
+type scriptedChat struct {
+	calls    []ollama.ToolCall
+	requests []ollama.ChatRequest
+}
+
+func (c *scriptedChat) ChatWithCallbacks(ctx context.Context, req ollama.ChatRequest, callbacks ollama.StreamCallbacks) (string, string, error) {
+	c.requests = append(c.requests, req)
+	if len(c.calls) > 0 && len(req.Tools) > 0 {
+		call := c.calls[0]
+		c.calls = c.calls[1:]
+		callbacks.OnToolCall(call)
+		return "", "", nil
+	}
+	last := req.Messages[len(req.Messages)-1]
+	return "", "The answer is " + strings.TrimSpace(last.Content), nil
+}
+
+func runCode(language, code string) ollama.ToolCall {
+	return ollama.ToolCall{Function: ollama.ToolCallFunction{
+		Name:      CodeToolName,
+		Arguments: map[string]interface{}{"language": language, "code": code},
+	}}
+}
+
+func TestLoopFeedsToolOutputBack(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	sb := sandbox.New(10 * time.Second)
+	if err := sb.Check(); err != nil {
+		t.Skip(err)
+	}
+	chat := &scriptedChat{calls: []ollama.ToolCall{runCode("python", "print(2 ** 10)")}}
+	loop := &Loop{Client: chat, Sandbox: sb}
+
+	_, answer, err := loop.Chat(context.Background(), ollama.ChatRequest{
+		Messages: []ollama.Message{{Role: "user", Content: "What is 2^10?"}},
+	}, ollama.StreamCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(answer, "stdout:\n1024") {
+		t.Errorf("answer %q does not include the tool output", answer)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("got %d model requests, want 2", len(chat.requests))
+	}
+	msgs := chat.requests[1].Messages
+	if len(msgs) != 3 || msgs[1].Role != "assistant" || len(msgs[1].ToolCalls) != 1 ||
+		msgs[2].Role != "tool" || msgs[2].ToolName != CodeToolName {
+		t.Errorf("unexpected follow-up messages: %+v", msgs)
+	}
+}
+
+func TestLoopStopsOfferingToolsAfterMaxRounds(t *testing.T) {
+	calls := make([]ollama.ToolCall, 5)
+	for i := range calls {
+		calls[i] = runCode("ruby", "puts 1")
+	}
+	chat := &scriptedChat{calls: calls}
+	loop := &Loop{Client: chat, Sandbox: sandbox.New(time.Second), MaxRounds: 2}
+
+	_, answer, err := loop.Chat(context.Background(), ollama.ChatRequest{
+		Messages: []ollama.Message{{Role: "user", Content: "hi"}},
+	}, ollama.StreamCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chat.requests) != 3 {
+		t.Errorf("got %d model requests, want 3", len(chat.requests))
+	}
+	if !strings.Contains(answer, "unsupported language") {
+		t.Errorf("answer %q should carry the sandbox error", answer)
+	}
+}
@@ -0,0 +1,268 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is one timed operation in a trace. A nil *Span is valid and does
+// nothing, so callers need not check whether tracing is enabled.
+type Span struct {
+	exporter *Exporter
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs map[string]interface{}
+}
+
+type spanKey struct{}
+
+var (
+	exporterMu sync.RWMutex
+	exporter   *Exporter
+)
+
+// Start begins a span, parented to the span in ctx if any. Returns ctx
+// carrying the new span. Without an installed exporter it returns a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	exporterMu.RLock()
+	exp := exporter
+	exporterMu.RUnlock()
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		exporter: exp,
+		spanID:   randomHex(8),
+		name:     name,
+		start:    time.Now(),
+		attrs:    make(map[string]interface{}),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttr records a string, bool, int or float attribute on the span
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// End finishes the span, marking it failed when err is non-nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	attrs := make([]otlpAttr, 0, len(s.attrs))
+	for k, v := range s.attrs {
+		attrs = append(attrs, newAttr(k, v))
+	}
+	s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              1, // internal
+		StartTimeUnixNano: fmt.Sprint(s.start.UnixNano()),
+		EndTimeUnixNano:   fmt.Sprint(time.Now().UnixNano()),
+		Attributes:        attrs,
+		Status:            otlpStatus{Code: 1},
+	}
+	if err != nil {
+		span.Status = otlpStatus{Code: 2, Message: err.Error()}
+	}
+	s.exporter.enqueue(span)
+}
+
+// Exporter batches finished spans and sends them to an OTLP/HTTP collector
+type Exporter struct {
+	endpoint   string
+	service    string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	pending []otlpSpan
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Install starts exporting spans to an OTLP/HTTP endpoint such as
+// http://localhost:4318 and makes Start record spans. Call Shutdown to flush.
+func Install(endpoint, service string) *Exporter {
+	exp := &Exporter{
+		endpoint:   strings.TrimRight(endpoint, "/") + "/v1/traces",
+		service:    service,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go exp.loop()
+
+	exporterMu.Lock()
+	exporter = exp
+	exporterMu.Unlock()
+	return exp
+}
+
+// Shutdown stops recording spans and flushes any that are pending
+func (e *Exporter) Shutdown() {
+	exporterMu.Lock()
+	if exporter == e {
+		exporter = nil
+	}
+	exporterMu.Unlock()
+
+	close(e.stop)
+	<-e.done
+}
+
+func (e *Exporter) enqueue(span otlpSpan) {
+	e.mu.Lock()
+	e.pending = append(e.pending, span)
+	e.mu.Unlock()
+}
+
+// loop flushes pending spans every few seconds
+func (e *Exporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.flush()
+		case <-e.stop:
+			e.flush()
+			return
+		}
+	}
+}
+
+func (e *Exporter) flush() {
+	e.mu.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	payload := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttr{newAttr("service.name", e.service)}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "web-ollama"},
+			Spans: spans,
+		}},
+	}}}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Warn("failed to marshal spans", "error", err)
+		return
+	}
+
+	resp, err := e.httpClient.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("failed to export spans", "endpoint", e.endpoint, "spans", len(spans), "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("span export rejected", "endpoint", e.endpoint, "status", resp.StatusCode)
+	}
+}
+
+// OTLP/HTTP JSON encoding, see opentelemetry-proto's trace.proto
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 = ok, 2 = error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func newAttr(key string, value interface{}) otlpAttr {
+	switch v := value.(type) {
+	case bool:
+		return otlpAttr{Key: key, Value: map[string]interface{}{"boolValue": v}}
+	case int:
+		return otlpAttr{Key: key, Value: map[string]interface{}{"intValue": fmt.Sprint(v)}}
+	case int64:
+		return otlpAttr{Key: key, Value: map[string]interface{}{"intValue": fmt.Sprint(v)}}
+	case float64:
+		return otlpAttr{Key: key, Value: map[string]interface{}{"doubleValue": v}}
+	default:
+		return otlpAttr{Key: key, Value: map[string]interface{}{"stringValue": fmt.Sprint(v)}}
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
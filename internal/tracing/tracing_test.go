@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpansAreExportedAsOTLP(t *testing.T) {
+	var got otlpRequest
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	exp := Install(srv.URL+"/", "web-ollama-test")
+	ctx, parent := Start(context.Background(), "answer")
+	_, child := Start(ctx, "search")
+	child.SetAttr("queries", 2)
+	child.SetAttr("offline", false)
+	child.SetAttr("engine", "searxng")
+	child.End(errors.New("timeout"))
+	parent.End(nil)
+	exp.Shutdown()
+
+	if path != "/v1/traces" {
+		t.Errorf("exported to %s, want /v1/traces", path)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("payload = %+v", got)
+	}
+	res := got.ResourceSpans[0]
+	if a := res.Resource.Attributes; len(a) != 1 || a[0].Key != "service.name" || a[0].Value["stringValue"] != "web-ollama-test" {
+		t.Errorf("resource attributes = %+v", a)
+	}
+	spans := res.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	search, answer := spans[0], spans[1]
+	if len(answer.TraceID) != 32 || len(answer.SpanID) != 16 || answer.ParentSpanID != "" || answer.Status.Code != 1 {
+		t.Errorf("parent span = %+v", answer)
+	}
+	if search.TraceID != answer.TraceID || search.ParentSpanID != answer.SpanID {
+		t.Errorf("child span is not in its parent's trace: %+v", search)
+	}
+	if search.Status.Code != 2 || search.Status.Message != "timeout" {
+		t.Errorf("failed span status = %+v", search.Status)
+	}
+	attrs := map[string]map[string]interface{}{}
+	for _, a := range search.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if attrs["queries"]["intValue"] != "2" || attrs["offline"]["boolValue"] != false || attrs["engine"]["stringValue"] != "searxng" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if search.StartTimeUnixNano == "" || search.EndTimeUnixNano < search.StartTimeUnixNano {
+		t.Errorf("times = %s..%s", search.StartTimeUnixNano, search.EndTimeUnixNano)
+	}
+}
+
+func TestStartWithoutExporterIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "answer")
+	if span != nil || ctx.Value(spanKey{}) != nil {
+		t.Error("Start recorded a span with no exporter installed")
+	}
+	span.SetAttr("k", "v")
+	span.End(nil)
+}
@@ -0,0 +1,102 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"web-ollama/internal/crawler"
+)
+
+const (
+	english = "The committee published its report on Tuesday. It found that the new rules were " +
+		"working and that costs for households have fallen by a fifth since the start of the year. "
+	german = "Der Ausschuss hat seinen Bericht am Dienstag veröffentlicht. Er stellte fest, dass die neuen " +
+		"Regeln wirken und die Kosten für Haushalte seit Jahresbeginn um ein Fünftel gesunken sind. "
+	japanese = "委員会は火曜日に報告書を公表した。新しい規則は機能しており、年初から家計の負担は五分の一減ったと結論づけた。"
+)
+
+func TestForeign(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"english", strings.Repeat(english, 10), false},
+		{"german", strings.Repeat(german, 10), true},
+		{"japanese", strings.Repeat(japanese, 10), true},
+		{"short", german, false},
+		{"numbers", strings.Repeat("1,024 2,048 4,096 ", 100), false},
+	}
+	for _, c := range cases {
+		if got := Foreign(c.text); got != c.want {
+			t.Errorf("Foreign(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	language, text := parseResponse("Language: **German**\n\nThe committee published its report.")
+	if language != "German" || text != "The committee published its report." {
+		t.Errorf("parseResponse = %q, %q", language, text)
+	}
+	language, text = parseResponse("The committee published its report.")
+	if language != "another language" || text != "The committee published its report." {
+		t.Errorf("parseResponse without a language line = %q, %q", language, text)
+	}
+}
+
+// fakeClient translates every page to "translated", recording the model
+type fakeClient struct {
+	mu     sync.Mutex
+	models []string
+}
+
+func (f *fakeClient) ChatSync(ctx context.Context, model string, messages interface{}) (string, error) {
+	f.mu.Lock()
+	f.models = append(f.models, model)
+	f.mu.Unlock()
+	return "<think>easy</think>Language: German\ntranslated", nil
+}
+
+// backend returns the pages in reverse order, as a crawler finishing the
+// last URL first would
+type backend map[string]string
+
+func (b backend) CrawlURLs(ctx context.Context, urls []string) []crawler.CrawlResult {
+	var results []crawler.CrawlResult
+	for i := len(urls) - 1; i >= 0; i-- {
+		results = append(results, crawler.CrawlResult{URL: urls[i], Content: b[urls[i]]})
+	}
+	return results
+}
+
+func TestFetcherTranslatesMostRelevantForeignPages(t *testing.T) {
+	client := &fakeClient{}
+	f := &Fetcher{Client: client, Model: "aya", Max: 2, Next: backend{
+		"https://a.example": strings.Repeat(german, 10),
+		"https://b.example": strings.Repeat(english, 10),
+		"https://c.example": strings.Repeat(german, 10),
+		"https://d.example": strings.Repeat(german, 10),
+	}}
+
+	results := f.CrawlURLs(context.Background(), []string{"https://a.example", "https://b.example", "https://c.example", "https://d.example"})
+	got := map[string]crawler.CrawlResult{}
+	for _, r := range results {
+		got[r.URL] = r
+	}
+	for _, url := range []string{"https://a.example", "https://c.example"} {
+		if r := got[url]; r.Translated != "German" || r.Content != "translated" {
+			t.Errorf("%s = %q from %q, want translated from German", url, r.Content, r.Translated)
+		}
+	}
+	for _, url := range []string{"https://b.example", "https://d.example"} {
+		if r := got[url]; r.Translated != "" {
+			t.Errorf("%s was translated", url)
+		}
+	}
+	if len(client.models) != 2 || client.models[0] != "aya" {
+		t.Errorf("models = %v, want two calls to aya", client.models)
+	}
+}
@@ -0,0 +1,107 @@
+// Package trust weights sources by domain, so that official documentation
+// and government sites are preferred and content farms demoted both when
+// choosing which search results to crawl and when sharing the prompt
+// budget between the pages crawled
+package trust
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"web-ollama/internal/searxng"
+)
+
+// candidateFactor is how many more results than needed are requested when
+// weights are set, so that demoted results can be replaced
+const candidateFactor = 2
+
+// Weights maps domains to trust weights. 1 is neutral, above 1 preferred,
+// below 1 demoted and 0 never crawled. A domain covers its subdomains and
+// may be a bare suffix such as "gov" or "edu". A nil *Weights is neutral.
+type Weights struct {
+	domains map[string]float64
+}
+
+// New creates weights from a domain → weight map; an empty map returns nil
+func New(domains map[string]float64) *Weights {
+	if len(domains) == 0 {
+		return nil
+	}
+	w := &Weights{domains: make(map[string]float64, len(domains))}
+	for domain, weight := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		domain = strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+		if domain != "" {
+			w.domains[domain] = weight
+		}
+	}
+	return w
+}
+
+// Of returns the weight of rawURL's host. The most specific matching domain
+// wins, so "blog.example.com" can differ from "example.com".
+func (w *Weights) Of(rawURL string) float64 {
+	if w == nil {
+		return 1
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 1
+	}
+	host := strings.ToLower(u.Hostname())
+	for {
+		if weight, ok := w.domains[host]; ok {
+			return weight
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			return 1
+		}
+		host = host[dot+1:]
+	}
+}
+
+// Candidates returns how many search results to request to end up with n
+func (w *Weights) Candidates(n int) int {
+	if w == nil {
+		return n
+	}
+	return n * candidateFactor
+}
+
+// Select orders results by search rank scaled by trust, drops those with a
+// weight of 0 and keeps the best n. Nil weights return results unchanged.
+func (w *Weights) Select(results []searxng.SearchResult, n int) []searxng.SearchResult {
+	if w == nil {
+		return results
+	}
+
+	type ranked struct {
+		result searxng.SearchResult
+		score  float64
+	}
+	var kept []ranked
+	for i, r := range results {
+		weight := w.Of(r.URL)
+		if weight <= 0 {
+			continue
+		}
+		// Results arrive best first; not every engine reports a score
+		base := r.Score
+		if base <= 0 {
+			base = 1 / float64(i+1)
+		}
+		kept = append(kept, ranked{r, base * weight})
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].score > kept[j].score })
+
+	if len(kept) > n {
+		kept = kept[:n]
+	}
+	selected := make([]searxng.SearchResult, len(kept))
+	for i, k := range kept {
+		selected[i] = k.result
+	}
+	return selected
+}
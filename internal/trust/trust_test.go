@@ -0,0 +1,53 @@
+package trust
+
+import (
+	"testing"
+
+	"web-ollama/internal/searxng"
+)
+
+func TestWeightOfMostSpecificDomain(t *testing.T) {
+	w := New(map[string]float64{"gov": 2, "example.com": 0.5, "docs.example.com": 3, "*.farm.net": 0})
+
+	for url, want := range map[string]float64{
+		"https://www.irs.gov/forms":       2,
+		"https://example.com/a":           0.5,
+		"https://blog.example.com/a":      0.5,
+		"https://docs.example.com/a":      3,
+		"https://a.b.farm.net/":           0,
+		"https://notexample.com/":         1,
+		"https://go.dev/doc/":             1,
+		"https://docs.EXAMPLE.com:8443/x": 3,
+	} {
+		if got := w.Of(url); got != want {
+			t.Errorf("Of(%s) = %v, want %v", url, got, want)
+		}
+	}
+	var none *Weights
+	if none.Of("https://example.com") != 1 || New(nil) != nil {
+		t.Error("nil weights should be neutral")
+	}
+}
+
+func TestSelectRanksByTrust(t *testing.T) {
+	w := New(map[string]float64{"docs.example": 3, "farm.example": 0.2, "spam.example": 0})
+	results := []searxng.SearchResult{
+		{URL: "https://farm.example/1"},
+		{URL: "https://spam.example/2"},
+		{URL: "https://blog.example/3"},
+		{URL: "https://docs.example/4"},
+	}
+
+	got := w.Select(results, 2)
+	if len(got) != 2 || got[0].URL != "https://docs.example/4" || got[1].URL != "https://blog.example/3" {
+		t.Errorf("Select = %v, want docs then blog", got)
+	}
+	if w.Candidates(5) != 10 {
+		t.Errorf("Candidates(5) = %d", w.Candidates(5))
+	}
+
+	var none *Weights
+	if got := none.Select(results, 2); len(got) != 4 || none.Candidates(5) != 5 {
+		t.Error("nil weights should leave results alone")
+	}
+}
@@ -2,12 +2,16 @@ package ui
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/glamour"
+	"web-ollama/internal/errs"
 	"web-ollama/internal/history"
 )
 
@@ -19,9 +23,20 @@ type EnhancedDisplay struct {
 	showThinking   bool
 	thinkingBuffer strings.Builder
 	responseBuffer strings.Builder
+	tldr           string // summary shown above the sources
 	startTime      time.Time
-	tokenCount     int
+	tokenCount     int     // words streamed, until the model reports its count
+	realTokens     bool    // tokenCount is the model's own count
+	tokensPerSec   float64 // generation speed reported by the model
+	liveTokens     int     // tokens streamed so far, timed as they arrived
+	liveRate       float64 // their speed, shown after the streamed text
+	liveShown      bool    // the speed is drawn after the cursor
+	column         int     // characters streamed since the last newline
+	live           bool    // stdout is a terminal that can redraw the speed
 	renderer       *glamour.TermRenderer
+	recorder       Recorder
+	spinStop       chan struct{} // closed to stop the spinner; nil when none runs
+	spinDone       chan struct{} // closed once the spinner has cleared its line
 }
 
 // NewEnhancedDisplay creates a new enhanced display
@@ -40,6 +55,23 @@ func NewEnhancedDisplay(showThinking bool) *EnhancedDisplay {
 		historyWidth: width / 3, // Left 1/3 for history
 		showThinking: showThinking,
 		renderer:     renderer,
+		live:         isTerminal(os.Stdout),
+	}
+}
+
+// SetTheme switches the markdown style (auto, dark, light, notty)
+func (d *EnhancedDisplay) SetTheme(theme string) {
+	style := glamour.WithAutoStyle()
+	if theme != "" && theme != "auto" {
+		style = glamour.WithStandardStyle(theme)
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		style,
+		glamour.WithWordWrap(d.width-10),
+	)
+	if err == nil {
+		d.renderer = renderer
 	}
 }
 
@@ -72,7 +104,7 @@ func (d *EnhancedDisplay) PrintWelcome(modelName string) {
 	fmt.Printf("%s%s║                                                          ║%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("%s%s╚══════════════════════════════════════════════════════════╝%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("\n%s%sModel:%s %s\n", colorBold, colorGray, colorReset, modelName)
-	fmt.Printf("%sCommands:%s /exit | /clear | /history | /files (list files for @reference)\n", colorGray, colorReset)
+	fmt.Printf("%sCommands:%s /exit | /clear | /history | /sessions | /resume <n> | /close | /files (list files for @reference) | /cd <path> | /image <path> | /archive search <terms> | /keys | /stats | /unload | /cache [clear] | /ingest <path> | /crawl-site <domain> <topic> | /research <topic> | /why [n] | /consensus <question> | /save-note [#tags] | /export [file|html] | /bibliography | /record | /select [on|off] | /style [concise|detailed|bullet|default] | /preview <question>\n", colorGray, colorReset)
 	fmt.Printf("%sFile Reference:%s Use @filename in queries (e.g., \"What does @main.go do?\")\n", colorGray, colorReset)
 	fmt.Println()
 }
@@ -94,8 +126,15 @@ func (d *EnhancedDisplay) PrintPrompt() {
 	fmt.Printf("\n%s%s❯%s ", colorBold, colorGreen, colorReset)
 }
 
+// PrintStatus shows a status line above the prompt, e.g. which
+// dependencies are degraded
+func (d *EnhancedDisplay) PrintStatus(status string) {
+	fmt.Printf("\n%s%s◆ %s%s", colorDim, colorYellow, status, colorReset)
+}
+
 // PrintUserMessage displays a user message with timestamp
 func (d *EnhancedDisplay) PrintUserMessage(content string, timestamp time.Time) {
+	d.record(Event{Kind: "user", Text: content, Time: timestamp})
 	fmt.Printf("\n%s┌─ You · %s%s\n", colorGray, timestamp.Format("15:04:05"), colorReset)
 	fmt.Printf("%s│%s %s\n", colorGray, colorReset, content)
 	fmt.Printf("%s└%s\n", colorGray, colorReset)
@@ -105,40 +144,131 @@ func (d *EnhancedDisplay) PrintUserMessage(content string, timestamp time.Time)
 func (d *EnhancedDisplay) StartAssistantResponse() {
 	d.startTime = time.Now()
 	d.tokenCount = 0
+	d.realTokens = false
+	d.tokensPerSec = 0
+	d.liveTokens, d.liveRate = 0, 0
 	d.thinkingBuffer.Reset()
 	d.responseBuffer.Reset()
+	d.tldr = ""
+	d.record(Event{Kind: "start"})
 
 	fmt.Printf("\n%s┌─ Assistant · %s%s\n", colorGray, time.Now().Format("15:04:05"), colorReset)
 }
 
 // WriteThinking writes thinking tokens (dimmed)
 func (d *EnhancedDisplay) WriteThinking(text string) {
+	d.record(Event{Kind: "thinking", Text: text})
 	if d.showThinking {
 		d.thinkingBuffer.WriteString(text)
-		fmt.Printf("%s%s%s", colorDim, text, colorReset)
+		d.streamText(colorDim+text+colorReset, text)
 	}
 }
 
 // StartAnswer prints thinking section separator
 func (d *EnhancedDisplay) StartAnswer() {
+	d.record(Event{Kind: "answer_start"})
+	d.clearLiveRate()
 	if d.showThinking && d.thinkingBuffer.Len() > 0 {
 		fmt.Printf("\n%s│%s\n%s│ ─── Answer ───%s\n%s│%s\n", colorGray, colorReset, colorGray, colorReset, colorGray, colorReset)
 	}
 	fmt.Printf("%s│%s ", colorGray, colorReset)
+	d.column = 2
 }
 
 // WriteAnswer writes answer tokens (streams live, renders markdown at end)
 func (d *EnhancedDisplay) WriteAnswer(text string) {
+	d.record(Event{Kind: "answer", Text: text})
 	d.responseBuffer.WriteString(text)
-	d.tokenCount += len(strings.Fields(text))
+	if !d.realTokens {
+		d.tokenCount += len(strings.Fields(text))
+	}
 	// Stream raw text in real-time for better UX
+	d.streamText(text, text)
+}
+
+// streamText prints streamed text, keeping the live speed after it. plain
+// is the text without colors, for tracking the column.
+func (d *EnhancedDisplay) streamText(text, plain string) {
+	d.clearLiveRate()
 	fmt.Print(text)
+	if i := strings.LastIndex(plain, "\n"); i >= 0 {
+		d.column = utf8.RuneCountInString(plain[i+1:])
+	} else {
+		d.column += utf8.RuneCountInString(plain)
+	}
+	d.drawLiveRate()
+}
+
+// drawLiveRate shows the live speed after the cursor, leaving the cursor
+// where the next token goes. It is skipped when it would wrap the line.
+func (d *EnhancedDisplay) drawLiveRate() {
+	if !d.live || d.liveRate <= 0 {
+		return
+	}
+	label := fmt.Sprintf("  %.1f tok/s", d.liveRate)
+	if d.width <= 0 || d.column%d.width+len(label) >= d.width {
+		return
+	}
+	fmt.Printf("\0337%s%s%s\0338", colorDim, label, colorReset)
+	d.liveShown = true
+}
+
+// clearLiveRate erases the live speed before more is printed
+func (d *EnhancedDisplay) clearLiveRate() {
+	if d.liveShown {
+		fmt.Print("\033[K")
+		d.liveShown = false
+	}
+}
+
+// SetAnswer replaces the streamed answer with its cleaned-up version, which
+// is what the final rendering shows
+func (d *EnhancedDisplay) SetAnswer(text string) {
+	d.record(Event{Kind: "answer_final", Text: text})
+	d.responseBuffer.Reset()
+	d.responseBuffer.WriteString(text)
+	if !d.realTokens {
+		d.tokenCount = len(strings.Fields(text))
+	}
+}
+
+// SetTLDR sets the answer's TL;DR, shown in the footer above the sources
+func (d *EnhancedDisplay) SetTLDR(text string) {
+	d.record(Event{Kind: "tldr", Text: text})
+	d.tldr = text
+}
+
+// SetLiveRate records how many tokens have been streamed and their speed,
+// timed as they arrived, shown while the answer streams and in the footer
+// when the model does not report its own counts
+func (d *EnhancedDisplay) SetLiveRate(tokens int, tokensPerSecond float64) {
+	d.liveTokens, d.liveRate = tokens, tokensPerSecond
+}
+
+// SetTokenStats records the model's own count of the tokens it generated
+// and its speed, shown instead of the streamed word count
+func (d *EnhancedDisplay) SetTokenStats(tokens int, tokensPerSecond float64) {
+	if tokens <= 0 {
+		return
+	}
+	d.tokenCount = tokens
+	d.realTokens = true
+	d.tokensPerSec = tokensPerSecond
 }
 
 // EndAssistantResponse finishes response and shows metadata
 func (d *EnhancedDisplay) EndAssistantResponse(sourceURLs []string) {
-	duration := time.Since(d.startTime)
+	d.endAssistantResponse(sourceURLs, time.Since(d.startTime))
+}
+
+func (d *EnhancedDisplay) endAssistantResponse(sourceURLs []string, duration time.Duration) {
+	end := Event{Kind: "end", Sources: sourceURLs, Duration: duration}
+	if d.realTokens {
+		end.Tokens, end.Rate = d.tokenCount, d.tokensPerSec
+	}
+	d.record(end)
 
+	d.clearLiveRate()
 	fmt.Println()
 	fmt.Println()
 
@@ -156,6 +286,14 @@ func (d *EnhancedDisplay) EndAssistantResponse(sourceURLs []string) {
 
 	fmt.Println()
 
+	// The TL;DR, worded to be read aloud, comes before the sources
+	if d.tldr != "" {
+		fmt.Printf("%s│ 🔊 TL;DR:%s\n", colorGray, colorReset)
+		for _, line := range wrapWords(d.tldr, max(20, d.width-8)) {
+			fmt.Printf("%s│%s    %s\n", colorGray, colorReset, line)
+		}
+	}
+
 	// Show sources if available
 	if len(sourceURLs) > 0 {
 		fmt.Printf("%s│%s\n", colorGray, colorReset)
@@ -168,10 +306,23 @@ func (d *EnhancedDisplay) EndAssistantResponse(sourceURLs []string) {
 
 	// Show metadata
 	fmt.Printf("%s│%s\n", colorGray, colorReset)
-	fmt.Printf("%s│ ⏱️  %s · 📝 ~%d words%s\n",
+	count := fmt.Sprintf("~%d words", d.tokenCount)
+	if !d.realTokens && d.liveTokens > 0 {
+		count = fmt.Sprintf("%d tokens", d.liveTokens)
+		if d.liveRate > 0 {
+			count += fmt.Sprintf(" · %.1f tok/s", d.liveRate)
+		}
+	}
+	if d.realTokens {
+		count = fmt.Sprintf("%d tokens", d.tokenCount)
+		if d.tokensPerSec > 0 {
+			count += fmt.Sprintf(" · %.1f tok/s", d.tokensPerSec)
+		}
+	}
+	fmt.Printf("%s│ ⏱️  %s · 📝 %s%s\n",
 		colorGray,
 		formatDuration(duration),
-		d.tokenCount,
+		count,
 		colorReset)
 
 	fmt.Printf("%s└%s\n", colorGray, colorReset)
@@ -179,26 +330,105 @@ func (d *EnhancedDisplay) EndAssistantResponse(sourceURLs []string) {
 
 // PrintSearchActivity shows search progress
 func (d *EnhancedDisplay) PrintSearchActivity(message string) {
+	d.record(Event{Kind: "activity", Text: message})
 	fmt.Printf("%s%s🔍 %s...%s\n", colorDim, colorCyan, message, colorReset)
 }
 
+// PrintProgress redraws a progress bar in place, e.g. for a model download.
+// fraction is between 0 and 1; detail follows the bar. EndProgress ends the
+// line.
+func (d *EnhancedDisplay) PrintProgress(label string, fraction float64, detail string) {
+	fraction = math.Max(0, math.Min(1, fraction))
+	width := min(40, max(10, d.width-len(label)-len(detail)-16))
+	filled := int(fraction * float64(width))
+	fmt.Printf("\r%s  %s [%s%s] %3.0f%% %s%s\033[K", colorCyan, label,
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled), fraction*100, detail, colorReset)
+}
+
+// EndProgress finishes the line of a progress bar
+func (d *EnhancedDisplay) EndProgress() {
+	fmt.Println()
+}
+
+// ShowSpinner animates a spinner before msg until StopSpinner, e.g. while a
+// model loads. When stdout is not a terminal it prints msg once instead.
+func (d *EnhancedDisplay) ShowSpinner(msg string) {
+	d.StopSpinner()
+	d.record(Event{Kind: "activity", Text: msg})
+	if !d.live {
+		fmt.Printf("%s%s...%s\n", colorCyan, msg, colorReset)
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	d.spinStop, d.spinDone = stop, done
+	go func() {
+		defer close(done)
+		frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+		ticker := time.NewTicker(80 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Printf("\r%s%s %s...%s\033[K", colorCyan, frames[i%len(frames)], msg, colorReset)
+			select {
+			case <-stop:
+				fmt.Print("\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// StopSpinner stops the spinner, if any, and clears its line
+func (d *EnhancedDisplay) StopSpinner() {
+	if d.spinStop == nil {
+		return
+	}
+	close(d.spinStop)
+	<-d.spinDone
+	d.spinStop, d.spinDone = nil, nil
+}
+
 // PrintInfo displays info message
 func (d *EnhancedDisplay) PrintInfo(msg string) {
+	d.record(Event{Kind: "info", Text: msg})
 	fmt.Printf("%sℹ %s%s\n", colorCyan, msg, colorReset)
 }
 
-// PrintWarning displays warning message
+// PrintWarning displays warning message and records it in the debug log
 func (d *EnhancedDisplay) PrintWarning(msg string) {
+	d.record(Event{Kind: "warning", Text: msg})
+	slog.Warn(msg)
 	fmt.Printf("%s⚠ %s%s\n", colorYellow, msg, colorReset)
 }
 
-// PrintError displays error message
+// PrintError displays error message, followed by how to fix it when the
+// error is of a known kind, and records it in the debug log
 func (d *EnhancedDisplay) PrintError(err error) {
-	fmt.Printf("%s✗ Error: %v%s\n", colorRed, err, colorReset)
+	d.printError(err.Error())
+	d.PrintHint(err)
+}
+
+// PrintHint shows how to fix err, if it is of a known kind
+func (d *EnhancedDisplay) PrintHint(err error) {
+	if hint := errs.Hint(err); hint != "" {
+		d.printHint(hint)
+	}
+}
+
+func (d *EnhancedDisplay) printHint(hint string) {
+	d.record(Event{Kind: "hint", Text: hint})
+	fmt.Printf("%s  → %s%s\n", colorYellow, hint, colorReset)
+}
+
+func (d *EnhancedDisplay) printError(msg string) {
+	d.record(Event{Kind: "error", Text: msg})
+	slog.Error(msg)
+	fmt.Printf("%s✗ Error: %s%s\n", colorRed, msg, colorReset)
 }
 
 // PrintSuccess displays success message
 func (d *EnhancedDisplay) PrintSuccess(msg string) {
+	d.record(Event{Kind: "success", Text: msg})
 	fmt.Printf("%s✓ %s%s\n", colorGreen, msg, colorReset)
 }
 
@@ -216,6 +446,27 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
+// wrapWords breaks text into lines of at most width characters, breaking
+// only between words
+func wrapWords(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	for _, word := range strings.Fields(text) {
+		if line.Len() > 0 && utf8.RuneCountInString(line.String())+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -230,6 +481,12 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fs", d.Seconds())
 }
 
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func getTerminalSize() (width, height int) {
 	cmd := exec.Command("stty", "size")
 	cmd.Stdin = os.Stdin
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// colorHighlight marks query terms in source text
+const colorHighlight = "\033[1;33m"
+
+// highlightSkip are words too common to be worth marking
+var highlightSkip = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true, "with": true,
+	"what": true, "which": true, "who": true, "whom": true, "why": true, "how": true, "when": true,
+	"where": true, "does": true, "did": true, "can": true, "could": true, "should": true, "would": true,
+	"will": true, "this": true, "that": true, "these": true, "those": true, "from": true, "about": true,
+	"into": true, "than": true, "then": true, "there": true, "their": true, "they": true, "you": true,
+	"your": true, "have": true, "has": true, "had": true, "not": true, "any": true, "all": true,
+	"tell": true, "explain": true, "please": true, "between": true, "vs": true, "versus": true,
+}
+
+// stemSuffixes are cut from query words so other forms of them match too:
+// "crawlers" becomes "crawl", which marks "crawling" as well
+var stemSuffixes = []string{"ing", "ers", "er", "es", "ed", "s"}
+
+// QueryTerms picks the words of a question worth highlighting in sources:
+// those of three or more letters that are not common function words
+func QueryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, f := range strings.Fields(strings.ToLower(query)) {
+		w := strings.TrimFunc(f, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if utf8.RuneCountInString(w) < 3 || highlightSkip[w] {
+			continue
+		}
+		w = stem(w)
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// stem cuts a common English ending off a word of letters, leaving at least
+// four of them
+func stem(w string) string {
+	if strings.IndexFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return w
+	}
+	for _, suffix := range stemSuffixes {
+		if base := strings.TrimSuffix(w, suffix); base != w && utf8.RuneCountInString(base) >= 4 {
+			return base
+		}
+	}
+	return w
+}
+
+// Highlight marks the words of text that start with one of terms, ignoring
+// case, so "crawl" also marks "crawler" and "Crawling"
+func Highlight(text string, terms []string) string {
+	if len(terms) == 0 {
+		return text
+	}
+	// Longest first, so the alternation prefers the fuller match
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	quoted := make([]string, len(sorted))
+	for i, t := range sorted {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	re, err := regexp.Compile(`(?i)(^|[^\pL\pN])((?:` + strings.Join(quoted, "|") + `)[\pL\pN]*)`)
+	if err != nil {
+		return text
+	}
+	return re.ReplaceAllString(text, "${1}"+colorHighlight+"${2}"+colorReset)
+}
@@ -0,0 +1,67 @@
+package ui
+
+import "time"
+
+// Event is one thing the display showed. Kind is one of user, start,
+// thinking, answer_start, answer, answer_final, tldr, end, activity, info, warning, error or
+// success.
+type Event struct {
+	Kind     string        `json:"kind"`
+	Text     string        `json:"text,omitempty"`
+	Time     time.Time     `json:"time,omitempty"`     // user messages
+	Sources  []string      `json:"sources,omitempty"`  // end
+	Duration time.Duration `json:"duration,omitempty"` // end
+	Tokens   int           `json:"tokens,omitempty"`   // end: generated tokens, as the model counted them
+	Rate     float64       `json:"rate,omitempty"`     // end: tokens per second
+}
+
+// Recorder receives every event the display shows
+type Recorder interface {
+	Record(Event)
+}
+
+// SetRecorder starts sending display events to r; nil stops recording
+func (d *EnhancedDisplay) SetRecorder(r Recorder) {
+	d.recorder = r
+}
+
+func (d *EnhancedDisplay) record(e Event) {
+	if d.recorder != nil {
+		d.recorder.Record(e)
+	}
+}
+
+// Replay shows a recorded event
+func (d *EnhancedDisplay) Replay(e Event) {
+	switch e.Kind {
+	case "user":
+		d.PrintUserMessage(e.Text, e.Time)
+	case "start":
+		d.StartAssistantResponse()
+	case "thinking":
+		d.WriteThinking(e.Text)
+	case "answer_start":
+		d.StartAnswer()
+	case "answer":
+		d.WriteAnswer(e.Text)
+	case "answer_final":
+		d.SetAnswer(e.Text)
+	case "tldr":
+		d.SetTLDR(e.Text)
+	case "end":
+		d.SetTokenStats(e.Tokens, e.Rate)
+		d.endAssistantResponse(e.Sources, e.Duration)
+	case "activity":
+		d.PrintSearchActivity(e.Text)
+	case "info":
+		d.PrintInfo(e.Text)
+	case "warning":
+		d.PrintWarning(e.Text)
+	case "error":
+		d.printError(e.Text)
+	case "hint":
+		d.printHint(e.Text)
+	case "success":
+		d.PrintSuccess(e.Text)
+	}
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Trace is a tree of what happened during one turn, shown in verbose mode.
+// A nil *Trace ignores everything added to it, so callers need not check
+// whether verbose mode is on.
+type Trace struct {
+	label string
+
+	mu       sync.Mutex
+	children []*Trace
+}
+
+// NewTrace starts a trace with label at its root
+func NewTrace(label string) *Trace {
+	return &Trace{label: label}
+}
+
+// Add appends a child node and returns it
+func (t *Trace) Add(format string, args ...interface{}) *Trace {
+	if t == nil {
+		return nil
+	}
+	child := &Trace{label: fmt.Sprintf(format, args...)}
+	t.mu.Lock()
+	t.children = append(t.children, child)
+	t.mu.Unlock()
+	return child
+}
+
+// Lines renders the tree with box-drawing branches, one node per line
+func (t *Trace) Lines() []string {
+	if t == nil {
+		return nil
+	}
+	lines := []string{t.label}
+	t.walk("", &lines)
+	return lines
+}
+
+func (t *Trace) walk(indent string, lines *[]string) {
+	t.mu.Lock()
+	children := append([]*Trace(nil), t.children...)
+	t.mu.Unlock()
+
+	for i, child := range children {
+		branch, next := "├─ ", "│  "
+		if i == len(children)-1 {
+			branch, next = "└─ ", "   "
+		}
+		*lines = append(*lines, indent+branch+child.label)
+		child.walk(indent+next, lines)
+	}
+}
+
+// PrintTrace shows a turn's trace; a nil trace prints nothing
+func (d *EnhancedDisplay) PrintTrace(t *Trace) {
+	lines := t.Lines()
+	if len(lines) == 0 {
+		return
+	}
+	d.record(Event{Kind: "info", Text: strings.Join(lines, "\n")})
+	fmt.Printf("%s%s%s\n", colorGray, strings.Join(lines, "\n"), colorReset)
+}
@@ -0,0 +1,77 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/trust"
+)
+
+// fakeClient replies with a fixed verdict and records the prompt
+type fakeClient struct {
+	reply  string
+	prompt string
+}
+
+func (f *fakeClient) ChatSyncJSON(ctx context.Context, model string, messages interface{}, schema json.RawMessage) (string, error) {
+	f.prompt = messages.([]ollama.Message)[0].Content
+	return f.reply, nil
+}
+
+var sources = []contextbuilder.Source{
+	{Number: 1, URL: "https://blog.example.com/go", Chunks: []contextbuilder.Chunk{{Index: 0, Text: "Go 1.22 came out in 2023."}}},
+	{Number: 2, URL: "https://go.dev/doc/go1.22", Chunks: []contextbuilder.Chunk{{Index: 0, Text: "Go 1.22 was released in February 2024."}}},
+	{Number: 3, URL: "https://go.dev/blog/unused"},
+}
+
+func newChecker(reply string) (*Checker, *fakeClient) {
+	client := &fakeClient{reply: reply}
+	return &Checker{Client: client, Model: "m", Trust: trust.New(map[string]float64{"go.dev": 2}), MinTrust: 1.5}, client
+}
+
+func TestCheckReportsContradictedTrustedSource(t *testing.T) {
+	c, client := newChecker(`{"contradicts": true, "source": 2, "claim": "Go 1.22 came out in 2023.", "source_says": "February 2024."}`)
+
+	conflict, err := c.Check(context.Background(), "When did Go 1.22 ship?", "Go 1.22 came out in 2023 [1.1].", sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflict == nil || conflict.Source.Number != 2 || conflict.SourceSays != "February 2024." {
+		t.Fatalf("conflict = %+v, want one with source 2", conflict)
+	}
+	if strings.Contains(client.prompt, "blog.example.com") || !strings.Contains(client.prompt, "[2.1] Go 1.22 was released") {
+		t.Errorf("prompt should quote only the trusted passages:\n%s", client.prompt)
+	}
+
+	again := Insist([]ollama.Message{{Role: "user", Content: "When did Go 1.22 ship?"}}, *conflict)
+	if !strings.Contains(again[0].Content, "source [2] (https://go.dev/doc/go1.22)") {
+		t.Errorf("insisted question = %q", again[0].Content)
+	}
+}
+
+func TestCheckIgnoresUncheckedSources(t *testing.T) {
+	for name, reply := range map[string]string{
+		"consistent":    `{"contradicts": false, "source": 0, "claim": "", "source_says": ""}`,
+		"untrusted one": `{"contradicts": true, "source": 1, "claim": "x", "source_says": "y"}`,
+	} {
+		c, _ := newChecker(reply)
+		conflict, err := c.Check(context.Background(), "q", "an answer", sources)
+		if err != nil || conflict != nil {
+			t.Errorf("%s: conflict = %+v, %v, want none", name, conflict, err)
+		}
+	}
+
+	c, client := newChecker(`{"contradicts": true, "source": 1}`)
+	c.MinTrust = 5
+	if conflict, _ := c.Check(context.Background(), "q", "an answer", sources); conflict != nil || client.prompt != "" {
+		t.Error("no source is trusted enough, so the model should not be asked")
+	}
+	var none *Checker
+	if conflict, err := none.Check(context.Background(), "q", "an answer", sources); conflict != nil || err != nil {
+		t.Error("a nil checker should find nothing")
+	}
+}
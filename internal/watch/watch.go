@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/ollama"
+)
+
+// State is the last recorded result of a watched query
+type State struct {
+	Query     string    `json:"query"`
+	Answer    string    `json:"answer"`
+	Sources   []string  `json:"sources"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Change describes how a new answer differs from the previous one
+type Change struct {
+	Changed    bool
+	Summary    string
+	NewSources []string
+}
+
+// ChatClient is used to judge whether two answers differ materially
+type ChatClient interface {
+	ChatSync(ctx context.Context, model string, messages interface{}) (string, error)
+}
+
+// StatePath returns the state file for a query inside dir
+func StatePath(dir, query string) string {
+	hash := knowledge.ContentHash(strings.ToLower(strings.TrimSpace(query)))
+	return filepath.Join(dir, hash[:16]+".json")
+}
+
+// LoadState reads a previous state; it returns nil if there is none
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state: %w", err)
+	}
+	return &state, nil
+}
+
+// Save writes the state atomically
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create watch directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return os.Rename(tempPath, path)
+}
+
+// Compare decides whether next reports something new compared to prev.
+// Answers are reworded on every run, so the model judges whether the facts
+// changed; if it cannot be asked, new sources count as a change.
+func Compare(ctx context.Context, client ChatClient, model string, prev, next *State) Change {
+	change := Change{NewSources: newSources(prev.Sources, next.Sources)}
+
+	if strings.TrimSpace(prev.Answer) == strings.TrimSpace(next.Answer) {
+		return change
+	}
+
+	prompt := fmt.Sprintf(`You monitor a recurring question for new developments.
+
+Question: %s
+
+PREVIOUS ANSWER (%s):
+%s
+
+NEW ANSWER (%s):
+%s
+
+Does the new answer contain materially new or different facts (not just rewording)?
+Respond ONLY with JSON: {"changed": true/false, "summary": "one or two sentences describing what is new"}`,
+		next.Query, prev.CheckedAt.Format("2006-01-02 15:04"), prev.Answer, next.CheckedAt.Format("2006-01-02 15:04"), next.Answer)
+
+	response, err := client.ChatSync(ctx, model, []ollama.Message{{Role: "user", Content: prompt}})
+	if err == nil {
+		var verdict struct {
+			Changed bool   `json:"changed"`
+			Summary string `json:"summary"`
+		}
+		start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+		if start >= 0 && end > start && json.Unmarshal([]byte(response[start:end+1]), &verdict) == nil {
+			change.Changed = verdict.Changed
+			change.Summary = verdict.Summary
+			return change
+		}
+	}
+
+	if len(change.NewSources) > 0 {
+		change.Changed = true
+		change.Summary = fmt.Sprintf("%d new source(s) found", len(change.NewSources))
+	}
+	return change
+}
+
+// newSources lists URLs in next that were not in prev
+func newSources(prev, next []string) []string {
+	seen := make(map[string]bool, len(prev))
+	for _, u := range prev {
+		seen[u] = true
+	}
+
+	var added []string
+	for _, u := range next {
+		if !seen[u] {
+			added = append(added, u)
+		}
+	}
+	sort.Strings(added)
+	return added
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/degrade"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/ui"
+)
+
+// liveSearch shows the REPL's web searches as the grounder runs them. Each
+// query starts as soon as the analyzer emits it; how they went is reported
+// together once grounding is done.
+type liveSearch struct {
+	display *ui.EnhancedDisplay
+
+	mu      sync.Mutex
+	parts   []*searchPart // in the order the queries started
+	byQuery map[string]*searchPart
+}
+
+// searchPart is the outcome of one query, as the grounder's hooks report it
+type searchPart struct {
+	query   string
+	err     error
+	results []searxng.SearchResult // not already claimed by another query
+	crawled []crawler.CrawlResult
+}
+
+func newLiveSearch(display *ui.EnhancedDisplay) *liveSearch {
+	return &liveSearch{display: display, byQuery: make(map[string]*searchPart)}
+}
+
+// Hooks records each search for Report
+func (s *liveSearch) Hooks() grounding.Hooks {
+	return grounding.Hooks{
+		OnQuery: func(query string) {
+			s.mu.Lock()
+			part := &searchPart{query: query}
+			s.parts = append(s.parts, part)
+			s.byQuery[query] = part
+			first := len(s.parts) == 1
+			s.mu.Unlock()
+			if first {
+				s.display.PrintSearchActivity("Searching the web")
+			}
+		},
+		OnResults: func(query string, results []searxng.SearchResult, err error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.byQuery[query].results, s.byQuery[query].err = results, err
+		},
+		OnCrawled: func(query string, crawled []crawler.CrawlResult) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.byQuery[query].crawled = crawled
+		},
+	}
+}
+
+// Report shows how the searches went and returns the pages to answer from:
+// those crawled, or cached snippets when search is degraded and no page
+// could be fetched. trace, in verbose mode, gets a node for each search.
+func (s *liveSearch) Report(result grounding.Result, trace *ui.Trace, cfg *config.Config, searcher *degrade.Searcher, contentGuard *guard.Filter) []crawler.CrawlResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.parts) == 0 {
+		return nil
+	}
+
+	hinted := false
+	for i, part := range s.parts {
+		traceSearch(trace, i+1, part)
+		if part.err != nil {
+			s.display.PrintWarning(fmt.Sprintf("Search %d failed: %v", i+1, part.err))
+			if !hinted {
+				// Every search fails the same way; one hint is enough
+				s.display.PrintHint(part.err)
+				hinted = true
+			}
+		}
+	}
+	warnInjections(s.display, result.Warnings)
+
+	crawled := result.Crawled
+	successCount := 0
+	var blocked []error
+	for _, c := range crawled {
+		if c.Error == nil {
+			successCount++
+		} else if errs.Is(c.Error, errs.CrawlBlocked) {
+			blocked = append(blocked, c.Error)
+		}
+	}
+	if len(blocked) > 0 && cfg.Verbose {
+		trace.Add("%d of %d sites refused the crawler", len(blocked), len(crawled))
+		s.display.PrintHint(blocked[0])
+	}
+
+	if result.Outside > 0 {
+		s.display.PrintInfo(fmt.Sprintf("Research sandbox: skipped %d result(s) outside allowed_domains", result.Outside))
+	}
+
+	switch {
+	case successCount > 0 && len(s.parts) == 1:
+		s.display.PrintSuccess(fmt.Sprintf("Gathered information from %d sources", successCount))
+	case successCount > 0:
+		s.display.PrintSuccess(fmt.Sprintf("Gathered information from %d sources across %d searches", successCount, len(s.parts)))
+	case searcher.Degraded() && len(result.Results) > 0:
+		crawled = snippetFallback(s.display, contentGuard, result.Results)
+	case result.Outside > 0 && len(result.Results) == 0:
+		s.display.PrintWarning("No search results on the allowed domains; add domains to allowed_domains or rephrase the question")
+	default:
+		s.display.PrintWarning("No information gathered from searches")
+	}
+	return crawled
+}
+
+// traceSearch adds one query's results and crawls to trace
+func traceSearch(trace *ui.Trace, n int, part *searchPart) {
+	if part.err != nil {
+		trace.Add("Search %d: %q failed: %v", n, part.query, part.err)
+		return
+	}
+	node := trace.Add("Search %d: %q → %d new result(s)", n, part.query, len(part.results))
+	for _, c := range part.crawled {
+		if c.Error != nil {
+			node.Add("✗ %s: %v", c.URL, c.Error)
+			continue
+		}
+		node.Add("✓ %s (%d words, %v)", c.URL, len(strings.Fields(c.Content)), c.Duration.Round(time.Millisecond))
+	}
+}
@@ -2,23 +2,48 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"web-ollama/internal/analyzer"
+	"web-ollama/internal/bandwidth"
 	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
 	"web-ollama/internal/crawler"
+	"web-ollama/internal/degrade"
+	"web-ollama/internal/errs"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/guard"
 	"web-ollama/internal/history"
+	"web-ollama/internal/hostile"
+	"web-ollama/internal/httpcache"
+	"web-ollama/internal/knowledge"
+	"web-ollama/internal/logging"
+	"web-ollama/internal/notes"
 	"web-ollama/internal/ollama"
+	"web-ollama/internal/pages"
+	"web-ollama/internal/postprocess"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/recording"
+	"web-ollama/internal/sandbox"
+	"web-ollama/internal/scheduler"
 	"web-ollama/internal/searxng"
+	"web-ollama/internal/secrets"
+	"web-ollama/internal/sinks"
+	"web-ollama/internal/summarize"
 	"web-ollama/internal/terminal"
+	"web-ollama/internal/tools"
 	"web-ollama/internal/ui"
 )
 
@@ -26,8 +51,35 @@ func main() {
 	// Set the GetEnv function for config
 	config.GetEnv = os.Getenv
 
+	// Dispatch subcommands before regular flag parsing
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			os.Exit(runCheck(args[1:]))
+		case "ingest":
+			os.Exit(runIngest(args[1:]))
+		case "serve":
+			os.Exit(runServe(args[1:]))
+		case "batch":
+			os.Exit(runBatch(args[1:]))
+		case "watch":
+			os.Exit(runWatch(args[1:]))
+		case "profile":
+			os.Exit(runProfile(args[1:]))
+		case "bot":
+			os.Exit(runBot(args[1:]))
+		case "replay":
+			os.Exit(runReplay(args[1:]))
+		case "daemon":
+			os.Exit(runDaemon(args[1:]))
+		case "ask":
+			os.Exit(runAsk(args[1:]))
+		}
+	}
+
 	// Parse command-line flags
-	cfg, showThinking := parseFlags()
+	cfg, showThinking := parseFlags(args)
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -35,50 +87,195 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Make sure the system prompt template renders before starting
+	if _, err := prompt.RenderSystem(cfg.SystemPrompt, prompt.NewSystemVars(time.Now(), cfg.ModelName)); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize enhanced display
 	display := ui.NewEnhancedDisplay(showThinking)
+	display.SetTheme(cfg.Theme)
+	tee, err := openTee(cfg.TeePath, display)
+	if err != nil {
+		display.PrintError(err)
+		os.Exit(1)
+	}
+	defer tee.Close()
 
 	// Initialize components
 	historyMgr := history.NewManager(cfg.HistoryPath, cfg.MaxHistorySize)
+	secretStore := loadSecrets(cfg)
+	historyMgr.SetRedactor(secretStore.Redact)
 	searxngClient := searxng.NewClient(cfg.SearXNGURL, cfg.SearchTimeout)
-	webCrawler := crawler.NewCrawler(cfg.CrawlTimeout, cfg.MaxCrawlers, cfg.MaxContentSize, cfg.UserAgent)
-	ollamaClient := ollama.NewClient(cfg.OllamaURL, cfg.OllamaTimeout)
-
-	// LLM-based query analyzer (uses same model)
-	llmAnalyzer := analyzer.NewLLMAnalyzer(ollamaClient, cfg.ModelName)
+	webCrawler := newCrawler(cfg)
+	ollamaClient := newOllamaClient(cfg)
+
+	// Share request limits between the clients
+	sched := newScheduler(cfg)
+	ollamaClient.SetTransport(sched.Transport(scheduler.Ollama, ollamaClient.Transport()))
+	httpCache := newHTTPCache(cfg)
+	searxngClient.SetTransport(httpCache.Transport(sched.Transport(scheduler.Search, nil)))
+	meter := bandwidth.NewMeter()
+	webCrawler.SetTransport(httpCache.Transport(meter.Transport(sched.Transport(scheduler.Crawl, nil))))
+	searxngCheck := searxngClient
+	if cfg.Offline {
+		goOffline(searxngClient, webCrawler)
+		searxngCheck = nil
+	}
+	costs := newTurnCosts(cfg, ollamaClient)
 
-	// Health checks
-	if err := ollamaClient.HealthCheck(); err != nil {
+	// Health checks, model listing and history loading, all at once
+	historyMgr.SetShared(cfg.SharedHistory)
+	ready := checkStartup(cfg.StartupBudget, ollamaClient, searxngCheck, historyMgr)
+	printReadiness(ready, display)
+	if err := historyLoaded(ready.history.err, display); err != nil {
+		os.Exit(1)
+	}
+	defer historyMgr.Close()
+	if err := ready.ollama.err; err != nil {
 		display.PrintError(err)
-		display.PrintInfo("Make sure Ollama is running: ollama serve")
 		os.Exit(1)
 	}
+	if len(cfg.OllamaFallbackURLs) > 0 {
+		checkOllamaHosts(ollamaClient, display)
+	}
 
-	// Check if model exists
-	if err := checkModel(ollamaClient, cfg.ModelName, display); err != nil {
-		os.Exit(1)
+	// Check if model exists, offering to download it if not
+	if err := findModel(ready.available, ready.models.err, cfg.ModelName, display); err != nil {
+		if !errs.Is(err, errs.ModelMissing) || !offerPull(ollamaClient, cfg.ModelName, display) {
+			os.Exit(1)
+		}
+	}
+	checkContextWindow(ollamaClient, cfg, display.PrintWarning)
+
+	// LLM-based query analyzer, on analyzer_model when it is pulled
+	llmAnalyzer := newAnalyzer(cfg, ollamaClient, ready.available, display.PrintWarning)
+	// Load the model ahead of the first question, unless it won't fit and
+	// loading it would hold up the prompt for minutes
+	if fits := checkVRAM(ollamaClient, cfg, display); cfg.Warmup && fits {
+		warmUp(ollamaClient, cfg.ModelName, cfg.OllamaTimeout, display)
+	} else if cfg.Warmup {
+		display.PrintInfo(fmt.Sprintf("Not loading %s ahead of time; the first question loads it", cfg.ModelName))
 	}
 
-	// SearXNG health check (non-fatal)
-	if err := searxngClient.HealthCheck(); err != nil {
+	// Track degraded dependencies and fall back to cached search results
+	tracker := degrade.NewTracker()
+	tracker.OnChange = func(capability string, state degrade.State) {
+		switch state.Mode {
+		case degrade.Normal:
+			display.PrintSuccess(fmt.Sprintf("%s is working again", capability))
+		case degrade.Fallback:
+			display.PrintWarning(fmt.Sprintf("%s is degraded, using %s", capability, state.Detail))
+		case degrade.Down:
+			display.PrintWarning(fmt.Sprintf("%s is down", capability))
+		}
+	}
+	searcher := fallbackSearcher(cfg, searxngClient, tracker, display)
+
+	// SearXNG is optional
+	if cfg.Offline {
+		display.PrintInfo("Offline: answering from cached results, archived and saved pages and the knowledge base")
+	} else if err := ready.searxng.err; err != nil {
 		display.PrintWarning(fmt.Sprintf("SearXNG check failed: %v", err))
+		display.PrintHint(err)
 		display.PrintInfo("Web search will be disabled. Start SearXNG or use --no-search flag.")
 		cfg.AutoSearch = false
 	}
 
-	// Load conversation history
-	if err := historyMgr.Load(); err != nil {
-		display.PrintWarning(fmt.Sprintf("Failed to load history: %v", err))
+	// Open the knowledge base (non-fatal)
+	var kb *knowledge.Store
+	if cfg.KnowledgeBase {
+		var err error
+		kb, err = newKnowledgeStore(cfg, ollamaClient, cfg.KnowledgePath)
+		if err == nil {
+			err = kb.Load()
+		}
+		if err != nil {
+			display.PrintWarning(fmt.Sprintf("Knowledge base disabled: %v", err))
+			kb = nil
+		}
+	}
+
+	// Guardrails for search results and crawled pages
+	contentGuard := openGuard(cfg, display)
+	webCrawler.SetPolicy(contentGuard.Check)
+	if contentGuard.Sandboxed() {
+		display.PrintInfo(fmt.Sprintf("Research sandbox: only crawling %s", strings.Join(cfg.AllowedDomains, ", ")))
+	}
+
+	// Domains that keep refusing the crawler are demoted, then skipped
+	hostileDomains := openHostile(cfg, display)
+	crawlFetcher := hostileFetcher(hostileDomains, webCrawler)
+
+	// Answer clean-up before display and history
+	post := openPostprocessor(cfg, display)
+	summarizer := summarize.New(ollamaClient, cfg)
+	grouper := newPerspectives(cfg, ollamaClient)
+	conflicts := newConflictChecker(cfg, ollamaClient)
+
+	// Forward answers to the configured sinks
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+
+	// Keep copies of crawled pages with the session, and reuse them when a
+	// question is asked again
+	var pageStore *pages.Store
+	fetcher := archiveFetcher(cfg, crawlFetcher, display)
+	if cfg.PagesDir != "" {
+		pageStore = pages.NewStore(cfg.PagesDir)
+		if err := pageStore.Prune(historyMgr.SessionIDs()); err != nil {
+			slog.Warn("failed to prune saved pages", "dir", cfg.PagesDir, "error", err)
+		}
+		maxAge := cfg.PageReuseAge
+		if cfg.Offline {
+			maxAge = 0 // an old copy beats none
+		}
+		fetcher = &pages.Fetcher{Store: pageStore, Session: currentSessionID(historyMgr), Next: fetcher, MaxAge: maxAge}
+	}
+	fetcher = translateFetcher(cfg, ollamaClient, fetcher)
+
+	// Let the model run code, search the web and read pages when enabled
+	chat := ollamaClient.ChatWithCallbacks
+	var toolSearcher tools.Searcher
+	if cfg.AutoSearch {
+		toolSearcher = searcher
+	}
+	if loop := chatTools(cfg, ollamaClient, toolSearcher, fetcher, contentGuard, display); loop != nil {
+		loop.OnRun = func(language, code string, result sandbox.Result, err error) {
+			if err != nil {
+				display.PrintWarning(fmt.Sprintf("\n[code] %s snippet failed: %v", language, err))
+				return
+			}
+			display.PrintSearchActivity(fmt.Sprintf("\n[code] ran %s snippet (%d lines, exit %d, %s)",
+				language, strings.Count(strings.TrimSpace(code), "\n")+1, result.ExitCode, result.Duration.Round(time.Millisecond)))
+		}
+		loop.OnCall = func(call ollama.ToolCall) {
+			switch call.Function.Name {
+			case tools.WebSearchToolName:
+				display.PrintSearchActivity(fmt.Sprintf("\n[web] searching for %v", call.Function.Arguments["query"]))
+			case tools.FetchURLToolName:
+				display.PrintSearchActivity(fmt.Sprintf("\n[web] reading %v", call.Function.Arguments["url"]))
+			}
+		}
+		chat = loop.Chat
 	}
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Ctrl+C stops the answer being written, if any; otherwise it quits
+	var generating turnStop
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-sigChan
+		for sig := range sigChan {
+			if sig == os.Interrupt && generating.stop() {
+				continue
+			}
+			break
+		}
 		display.PrintInfo("\nShutting down gracefully...")
 		display.PrintInfo("Stopping model to free up RAM...")
 		if err := ollamaClient.StopModel(cfg.ModelName); err != nil {
@@ -90,8 +287,34 @@ func main() {
 		os.Exit(0)
 	}()
 
+	// Pick up safe config file edits without a restart
+	watcher := config.NewWatcher(cfg.ConfigPath, 2*time.Second)
+	watcher.Start()
+	defer watcher.Stop()
+
 	// Print welcome message
 	display.PrintWelcome(cfg.ModelName)
+	if cfg.DryRun {
+		display.PrintInfo("Dry run: each prompt is shown instead of sent to the model")
+	}
+
+	// Active /record session, if any
+	var recorder *recording.Recorder
+
+	// Sources and answer of the last turn, for /why
+	var lastReport contextbuilder.Report
+	var lastAnswer string
+	var lastQuery string // the question, and its rewrite for search, for highlighting
+
+	// Summary of the session picked with /resume, given to the model as a primer
+	var primer string
+
+	// Archived pages picked with /archive use for the next question
+	var archived archiveBrowser
+	var attached imageQueue
+
+	// Files quoted in recent turns, for follow-ups that refer back to them
+	var carried carriedFiles
 
 	// Main conversation loop
 	for {
@@ -100,12 +323,17 @@ func main() {
 		display.DrawHistoryPanel(recentMessages)
 
 		// Get user input
+		if status := tracker.Status(); status != "" {
+			display.PrintStatus(status)
+		}
 		display.PrintPrompt()
 		query, err := terminal.ReadUserInput()
 		if err != nil {
 			break
 		}
 
+		applyConfigReloads(watcher, cfg, display, ollamaClient, searxngClient, webCrawler)
+
 		// Handle commands
 		if query == "/exit" || query == "/quit" || query == "exit" || query == "quit" {
 			break
@@ -119,12 +347,136 @@ func main() {
 			displayFullHistory(historyMgr, display)
 			continue
 		}
-		if query == "/files" {
-			workingDir, _ := os.Getwd()
-			if workingDir == "" {
-				workingDir = "."
+		if query == "/sessions" {
+			listSessions(historyMgr, display)
+			continue
+		}
+		if query == "/close" {
+			closeSession(ctx, cfg, ollamaClient, historyMgr, display)
+			primer = ""
+			carried.forget()
+			continue
+		}
+		if query == "/resume" || strings.HasPrefix(query, "/resume ") {
+			if summary, ok := resumeSession(ctx, strings.TrimPrefix(query, "/resume"), cfg, ollamaClient, historyMgr, display); ok {
+				primer = summary
+				carried.forget()
+			}
+			continue
+		}
+		if query == "/unload" {
+			if err := ollamaClient.StopModel(cfg.ModelName); err != nil {
+				display.PrintWarning(fmt.Sprintf("Failed to unload %s: %v", cfg.ModelName, err))
+			} else {
+				display.PrintSuccess(fmt.Sprintf("Unloaded %s; the next question loads it again", cfg.ModelName))
+			}
+			continue
+		}
+		if query == "/stats" {
+			displayStats(meter, webCrawler.Tuner(), hostileDomains, costs, display)
+			continue
+		}
+		if query == "/cache" || strings.HasPrefix(query, "/cache ") {
+			handleCache(strings.TrimPrefix(query, "/cache"), httpCache, display)
+			continue
+		}
+		if query == "/keys" {
+			displayKeys(secretStore, display)
+			continue
+		}
+		if query == "/ingest" || strings.HasPrefix(query, "/ingest ") {
+			paths := strings.Fields(strings.TrimPrefix(query, "/ingest"))
+			if len(paths) == 0 {
+				display.PrintInfo("Usage: /ingest <file or directory>...")
+				continue
+			}
+			if kb = enableKnowledge(kb, cfg, ollamaClient, display); kb == nil {
+				continue
+			}
+			ingestPaths(ctx, kb, paths, display)
+			continue
+		}
+		if query == "/crawl-site" || strings.HasPrefix(query, "/crawl-site ") {
+			kb = crawlSite(ctx, strings.TrimPrefix(query, "/crawl-site"), cfg, kb, ollamaClient, webCrawler, archiveFetcher(cfg, crawlFetcher, display), contentGuard, display)
+			continue
+		}
+		if query == "/research" || strings.HasPrefix(query, "/research ") {
+			topic := strings.TrimSpace(strings.TrimPrefix(query, "/research"))
+			if topic == "" {
+				display.PrintInfo("Usage: /research <topic>")
+				continue
+			}
+			runResearch(ctx, topic, cfg, display, tee, ollamaClient, searcher, archiveFetcher(cfg, crawlFetcher, display), contentGuard)
+			continue
+		}
+		consensusTurn := false
+		if query == "/consensus" || strings.HasPrefix(query, "/consensus ") {
+			query = strings.TrimSpace(strings.TrimPrefix(query, "/consensus"))
+			if query == "" {
+				display.PrintInfo("Usage: /consensus <question>")
+				continue
+			}
+			if len(cfg.ConsensusModels) < 2 {
+				display.PrintWarning("Set consensus_models in the config file to at least two models")
+				continue
+			}
+			consensusTurn = true
+		}
+		previewTurn := cfg.DryRun
+		if query == "/preview" || strings.HasPrefix(query, "/preview ") {
+			query = strings.TrimSpace(strings.TrimPrefix(query, "/preview"))
+			if query == "" {
+				display.PrintInfo("Usage: /preview <question>")
+				continue
+			}
+			previewTurn = true
+		}
+		if query == "/style" || strings.HasPrefix(query, "/style ") {
+			changeStyle(strings.TrimPrefix(query, "/style"), cfg, display)
+			continue
+		}
+		if query == "/select" || strings.HasPrefix(query, "/select ") {
+			toggleSelect(strings.TrimPrefix(query, "/select"), cfg, display)
+			continue
+		}
+		if query == "/why" || strings.HasPrefix(query, "/why ") {
+			report, answer, asked := lastReport, lastAnswer, lastQuery
+			if len(report.Sources) == 0 {
+				report, answer, asked = savedReport(historyMgr, pageStore)
 			}
-			displayAvailableFiles(workingDir, display)
+			showWhy(strings.Fields(strings.TrimPrefix(query, "/why")), report, answer, asked, display)
+			continue
+		}
+		if query == "/record" || strings.HasPrefix(query, "/record ") {
+			recorder = handleRecord(recorder, strings.Fields(strings.TrimPrefix(query, "/record")), cfg, display, secretStore.Redact)
+			continue
+		}
+		if query == "/save-note" || strings.HasPrefix(query, "/save-note ") {
+			saveLastNote(historyMgr, cfg, strings.Fields(strings.TrimPrefix(query, "/save-note")), display)
+			continue
+		}
+		if query == "/export" || strings.HasPrefix(query, "/export ") {
+			exportSession(strings.Fields(strings.TrimPrefix(query, "/export")), cfg, historyMgr, pageStore, display)
+			continue
+		}
+		if query == "/bibliography" {
+			showBibliography(historyMgr, display)
+			continue
+		}
+		if query == "/files" {
+			displayAvailableFiles(sessionWorkDir(historyMgr), display)
+			continue
+		}
+		if query == "/archive" || strings.HasPrefix(query, "/archive ") {
+			archived.handle(strings.TrimPrefix(query, "/archive"), cfg, display)
+			continue
+		}
+		if query == "/image" || strings.HasPrefix(query, "/image ") {
+			attached.handle(strings.TrimPrefix(query, "/image"), sessionWorkDir(historyMgr), display)
+			continue
+		}
+		if query == "/cd" || strings.HasPrefix(query, "/cd ") {
+			changeDir(strings.TrimPrefix(query, "/cd"), historyMgr, display)
 			continue
 		}
 
@@ -137,6 +489,12 @@ func main() {
 		now := time.Now()
 		display.PrintUserMessage(query, now)
 
+		// In verbose mode, collect what happens this turn for one trace
+		var trace *ui.Trace
+		if cfg.Verbose {
+			trace = ui.NewTrace("Turn")
+		}
+
 		// DON'T save user message yet - wait until after LLM response
 		// to avoid duplicate query in context
 
@@ -146,12 +504,7 @@ func main() {
 		var fileContext string
 
 		if len(fileRefs) > 0 {
-			workingDir, err := os.Getwd()
-			if err != nil {
-				display.PrintWarning(fmt.Sprintf("Failed to get working directory: %v", err))
-				workingDir = "."
-			}
-
+			workingDir := sessionWorkDir(historyMgr)
 			fileReferences = readFileReferences(fileRefs, workingDir)
 			fileContext = buildFileContext(fileReferences)
 
@@ -165,9 +518,7 @@ func main() {
 
 			if successCount > 0 {
 				display.PrintSuccess(fmt.Sprintf("Loaded %d file(s): %s", successCount, strings.Join(fileRefs, ", ")))
-				if cfg.Verbose {
-					display.PrintInfo(fmt.Sprintf("File context size: %d characters", len(fileContext)))
-				}
+				trace.Add("Files: %d loaded, %d characters", successCount, len(fileContext))
 			}
 
 			// Display errors for failed file loads with suggestions
@@ -187,11 +538,29 @@ func main() {
 					}
 				}
 			}
+		} else if cfg.CarryFiles {
+			if paths := carried.referredTo(query); len(paths) > 0 {
+				fileReferences = carryFiles(ctx, cfg, ollamaClient, paths, query, sessionWorkDir(historyMgr), display)
+				fileContext = buildFileContext(fileReferences)
+				trace.Add("Files: %d carried over from earlier turns, %d characters", len(fileReferences), len(fileContext))
+			}
+		}
+		carried.record(fileReferences)
+		gitContext := sessionGitContext(ctx, historyMgr)
+		if gitContext != "" {
+			trace.Add("Git: context for %s, %d characters", sessionWorkDir(historyMgr), len(gitContext))
 		}
 
 		// Analyze query for search trigger using LLM
-		var searchContext string
 		var sourceURLs []string
+		var crawlResults []crawler.CrawlResult
+		var standaloneQuery string // set when a follow-up was rewritten for search
+
+		// Look up the knowledge base while the web search runs
+		var knowledgeLookup func(*ui.EnhancedDisplay, *ui.Trace) string
+		if kb != nil {
+			knowledgeLookup = startKnowledgeLookup(ctx, kb, query, cfg)
+		}
 
 		if cfg.AutoSearch {
 			// Strip file references from query before search analysis
@@ -201,36 +570,135 @@ func main() {
 				queryForAnalysis = strings.ReplaceAll(queryForAnalysis, "@"+ref, ref)
 			}
 
+			// Saved copies of pages may be out of date for questions about now
+			searchCtx := ctx
+			if analyzer.TimeSensitive(queryForAnalysis) && !cfg.Offline {
+				if pageStore != nil {
+					searchCtx = pages.WithFresh(searchCtx)
+					trace.Add("Pages: time-sensitive question, not reusing saved copies")
+				}
+				if httpCache != nil {
+					searchCtx = httpcache.WithRevalidate(searchCtx)
+				}
+			}
+
+			// Searches start as soon as the analyzer names them
 			display.PrintInfo("Analyzing query...")
-			decision, err := llmAnalyzer.AnalyzeWithLLM(ctx, queryForAnalysis)
+			live := newLiveSearch(display)
+			grounder := newGrounder(cfg, llmAnalyzer, searcher, fetcher, contentGuard, hostileDomains)
+			grounder.Hooks = live.Hooks()
+			if cfg.SelectSources {
+				grounder.Hooks.Choose = func(results []searxng.SearchResult) []searxng.SearchResult {
+					return chooseSources(display, results, queryForAnalysis)
+				}
+			}
+			recent := grounding.Conversation(historyMgr.GetRecentMessages(contextbuilder.HistoryMessages(cfg)))
+			result, err := grounder.Ground(searchCtx, queryForAnalysis, recent)
 			if err != nil {
-				display.PrintWarning(fmt.Sprintf("Analysis failed: %v", err))
+				display.PrintWarning(fmt.Sprintf("Analysis failed: %v", errors.Unwrap(err)))
+				display.PrintHint(err)
 			} else {
-				if decision.NeedsSearch {
-					// Use the LLM's optimized search queries
-					searchQueries := decision.SearchQueries
-					if len(searchQueries) == 0 {
-						searchQueries = []string{query} // Fallback to original
-					}
-					if cfg.Verbose {
-						if len(searchQueries) == 1 {
-							display.PrintInfo(fmt.Sprintf("Search query: \"%s\" (Reason: %s)", searchQueries[0], decision.Reason))
-						} else {
-							display.PrintInfo(fmt.Sprintf("Search queries: %v (Reason: %s)", searchQueries, decision.Reason))
-						}
-					}
-					searchContext, sourceURLs = performMultiSearch(ctx, display, searxngClient, webCrawler, searchQueries, cfg)
-				} else if cfg.Verbose {
-					display.PrintInfo(fmt.Sprintf("No search needed: %s", decision.Reason))
+				decision := result.Decision
+				if decision.StandaloneQuery != "" && decision.StandaloneQuery != queryForAnalysis {
+					standaloneQuery = decision.StandaloneQuery
+					display.PrintInfo(fmt.Sprintf("Follow-up read as: \"%s\"", standaloneQuery))
+				}
+				if decision.NeedsSearch || result.BudgetSpent {
+					node := trace.Add("Decision: search (%s)", decision.Reason)
+					sourceURLs = result.URLs
+					crawlResults = live.Report(result, node, cfg, searcher, contentGuard)
+				} else {
+					trace.Add("Decision: no search (%s)", decision.Reason)
 				}
 			}
+			if result.BudgetSpent {
+				display.PrintWarning(fmt.Sprintf("Turn budget of %v reached; answering with the sources ready so far", cfg.TurnBudget))
+			}
+			if result.CapReached {
+				display.PrintWarning(fmt.Sprintf("Download limit of %s reached; pages not yet fetched were skipped", bandwidth.FormatBytes(cfg.TurnDownloadLimit)))
+			}
+		}
+
+		// Archived pages picked for this question come first, without fetching
+		if picked := archived.Take(); len(picked) > 0 {
+			node := trace.Add("Archive: %d page(s)", len(picked))
+			var urls []string
+			for _, page := range picked {
+				node.Add("%s (archived %s)", page.URL, page.Saved.Format("2006-01-02"))
+				urls = append(urls, page.URL)
+			}
+			sourceURLs = append(urls, sourceURLs...)
+			crawlResults = append(picked, crawlResults...)
+		}
+
+		// Retrieve related excerpts from the knowledge base
+		var knowledgeContext string
+		if knowledgeLookup != nil {
+			knowledgeContext = knowledgeLookup(display, trace)
+		}
+
+		// The same article is often found under several URLs
+		crawlResults = pages.Unique(crawlResults)
+
+		// Condense large crawls so more sources fit; the originals are
+		// still indexed into the knowledge base below
+		promptSources := crawlResults
+		if summarizer.Needed(crawlResults) {
+			display.PrintInfo(fmt.Sprintf("Summarizing %d sources with %s...", len(crawlResults), summarizer.Model))
+			promptSources = summarizer.Summarize(ctx, query, crawlResults)
+		}
+
+		// Contested questions get their sources' differing views set out
+		readAs := query
+		if standaloneQuery != "" {
+			readAs = standaloneQuery
+		}
+		groups := grouper.Group(ctx, readAs, promptSources)
+		if len(groups) > 0 {
+			trace.Add("Perspectives: sources fall into %d groups", len(groups))
 		}
 
 		// Build messages with context
-		if cfg.Verbose && fileContext != "" {
-			display.PrintInfo(fmt.Sprintf("Sending %d chars of file context to LLM", len(fileContext)))
+		turn := contextbuilder.Turn{
+			Query:            query,
+			FileContext:      fileContext,
+			GitContext:       gitContext,
+			KnowledgeContext: knowledgeContext,
+			Sources:          promptSources,
+			History:          historyMgr.GetRecentMessages(contextbuilder.HistoryMessages(cfg)),
+			Primer:           primer,
+			Perspectives:     groups,
+			TLDR:             cfg.TLDR,
+		}
+		messages, report := contextbuilder.New(cfg).Build(turn)
+		if report.Trimmed() && !previewTurn && report.Overflow() == nil {
+			var ok bool
+			turn, messages, report, ok = fitTurn(ctx, cfg, ollamaClient, turn, messages, report, trace, display)
+			if !ok {
+				display.PrintTrace(trace)
+				continue
+			}
+			promptSources = turn.Sources
 		}
-		messages := buildMessages(historyMgr, query, searchContext, fileContext)
+		traceContext(trace, report)
+		if previewTurn {
+			display.PrintTrace(trace)
+			showPreview(messages, report, display)
+			continue
+		}
+		if err := report.Overflow(); err != nil {
+			display.PrintTrace(trace)
+			display.PrintError(err)
+			continue
+		}
+		// JSON answers are shown as the model wrote them: they carry no
+		// citations to check or restyle
+		format, err := ollama.ParseFormat(cfg.AnswerFormat)
+		if err != nil {
+			display.PrintError(err)
+			continue
+		}
+		structured := len(format) > 0
 
 		// Start assistant response
 		display.StartAssistantResponse()
@@ -242,81 +710,215 @@ func main() {
 		escChan := terminal.ListenForESC()
 
 		// Watch for ESC key press
+		generating.start(streamCancel)
 		go func() {
-			<-escChan
-			display.PrintWarning("\n\n[Response stopped by user - press ESC]")
-			streamCancel()
+			if <-escChan {
+				generating.stop()
+			}
 		}()
 
 		// Stream response from Ollama with thinking support
-		thinking, answer, err := ollamaClient.ChatWithCallbacks(streamCtx, ollama.ChatRequest{
-			Model:    cfg.ModelName,
-			Messages: messages,
-			Options: map[string]interface{}{
-				"num_ctx": 32768, // Set context window to 32K tokens (enough for file references)
-			},
-		}, ollama.StreamCallbacks{
+		options := cfg.ModelOptions()
+		prompt.ApplyStyle(options, cfg.AnswerStyle, cfg.AnswerTokens)
+		var generation ollama.Stats
+		callbacks := ollama.StreamCallbacks{
 			OnThinking: func(chunk string) {
 				display.WriteThinking(chunk)
 			},
 			OnAnswer: func(chunk string) {
 				display.WriteAnswer(chunk)
+				tee.Write(chunk)
 			},
 			OnDone: func() {
 				display.StartAnswer()
 			},
-		})
+			OnStats: func(stats ollama.Stats) {
+				generation = stats
+				display.SetTokenStats(stats.AnswerTokens, stats.TokensPerSecond())
+			},
+			OnProgress: func(stats ollama.Stats) {
+				display.SetLiveRate(stats.AnswerTokens, stats.TokensPerSecond())
+			},
+		}
+
+		// Images go with the question, for vision models
+		images := attached.Take()
+		for _, ref := range fileReferences {
+			if ref.Image != "" {
+				images = append(images, ref.Image)
+			}
+		}
+		if len(images) > 0 {
+			messages[len(messages)-1].Images = images
+			warnIfBlind(ollamaClient, cfg.ModelName, display)
+		}
+
+		var thinking, answer string
+		tee.Start(query)
+		for {
+			if consensusTurn {
+				thinking, answer, err = askConsensus(streamCtx, cfg, display, ollamaClient, messages, options, callbacks)
+			} else {
+				var retried bool
+				thinking, answer, retried, err = contextbuilder.ChatGrounded(streamCtx, chat, ollama.ChatRequest{
+					Model:    cfg.ModelName,
+					Messages: messages,
+					Options:  options,
+					Format:   format,
+				}, callbacks, report.Grounded() && !structured)
+				if retried {
+					trace.Add("Retried: the first answer ignored the sources")
+				}
+			}
+
+			// Hold the question while Ollama is unreachable, unless part of
+			// the answer has already been shown
+			if err == nil || answer != "" || !degrade.IsUnreachable(err) || streamCtx.Err() != nil {
+				break
+			}
+			display.PrintWarning("Ollama is unreachable; your question is queued and will be sent when it is back (ESC or Ctrl+C to give up)")
+			if tracker.Recover(streamCtx, degrade.Ollama, ollamaClient.HealthCheck) != nil {
+				break
+			}
+		}
 
 		// Clean up the stream context
+		stopped := streamCtx.Err() == context.Canceled && ctx.Err() == nil
+		generating.done()
+		tee.End(err != nil)
 		streamCancel()
 
 		if err != nil {
-			// Check if error was due to user cancellation
-			if streamCtx.Err() == context.Canceled {
+			// Keep what was written before the user stopped the answer
+			if stopped && answer != "" {
+				display.PrintWarning("[Response stopped by user - keeping the partial answer]")
+				trace.Add("Stopped by the user after %d characters", len(answer))
+				answer = strings.TrimRight(answer, " \n") + truncatedMarker
+				display.SetAnswer(answer)
+			} else if stopped {
 				display.PrintInfo("Response stopped. You can ask a new question.")
 				continue
+			} else {
+				display.PrintTrace(trace)
+				display.PrintError(err)
+				continue
 			}
-			display.PrintError(err)
-			continue
 		}
 
-		// End response with metadata
-		display.EndAssistantResponse(sourceURLs)
+		// Ask before keeping an answer that contradicts a trusted source
+		if conflicts != nil && !stopped && !structured && !consensusTurn {
+			thinking, answer = resolveConflict(ctx, conflicts, chat, ollama.ChatRequest{
+				Model:    cfg.ModelName,
+				Messages: messages,
+				Options:  options,
+			}, callbacks, &generating, tee, query, thinking, answer, report.Sources, trace, display)
+		}
 
-		// Only save to history if we got a complete response
-		// (not cancelled by user)
-		if streamCtx.Err() != context.Canceled {
-			// NOW save both user and assistant messages to history
-			userMsg := history.Message{
-				Role:      "user",
-				Content:   query,
-				Timestamp: now,
-			}
-			historyMgr.AddMessage(userMsg)
+		// Clean up the answer before it is rendered and saved
+		if cleaned, changed := post.Process(answer); len(changed) > 0 && !structured {
+			answer = cleaned
+			display.SetAnswer(answer)
+			trace.Add("Post-processed: %s", strings.Join(changed, ", "))
+		}
 
-			assistantMsg := history.Message{
-				Role:      "assistant",
-				Content:   answer,
-				Timestamp: time.Now(),
+		// Show the TL;DR above the sources rather than at the end of the
+		// answer; history keeps it with the answer
+		var tldr string
+		if cfg.TLDR && !structured {
+			if body, summary := postprocess.SplitTLDR(answer); summary != "" {
+				answer, tldr = body, summary
+				display.SetAnswer(answer)
+				display.SetTLDR(tldr)
 			}
+		}
 
-			if len(sourceURLs) > 0 {
-				assistantMsg.Metadata = &history.Metadata{
-					SearchPerformed: true,
-					SourceURLs:      sourceURLs,
-				}
+		// Drop citations of passages the model never saw, and list only
+		// the sources the answer actually cites
+		attribution := contextbuilder.Attribute(report.Sources, answer)
+		if structured {
+			attribution.Invalid = nil
+		}
+		if len(attribution.Invalid) > 0 {
+			answer = attribution.Answer
+			display.SetAnswer(answer)
+		}
+
+		// Show citations in the configured style; history keeps the [n] form
+		var retrieved map[string]time.Time
+		if cfg.Offline {
+			retrieved = retrievedDates(crawlResults, report.Sources)
+		}
+		footer := footerLines(attribution.Footer(), retrieved)
+		cited := postprocess.Citations{Style: cfg.CitationStyle, Sources: postprocess.References(report.Sources), Accessed: now}
+		if styled := cited.Apply(answer); styled != answer && !structured {
+			display.SetAnswer(styled)
+			footer = nil // the styled answer carries its own references
+		}
+
+		// End response with metadata
+		display.EndAssistantResponse(footer)
+		if notice := cachedNotice(retrieved); notice != "" {
+			display.PrintWarning(notice)
+		}
+		if len(attribution.Invalid) > 0 {
+			display.PrintWarning(fmt.Sprintf("Removed citations of passages that were not in the prompt: %s", strings.Join(attribution.Invalid, " ")))
+		}
+		traceGeneration(trace, generation)
+		if generation.AnswerTokens > 0 {
+			estimate := costs.add(cfg.ModelName, generation)
+			trace.Add("Estimated cost: %s", estimate)
+			if cfg.ShowCost {
+				display.PrintInfo(fmt.Sprintf("Estimated cost: %s", estimate))
 			}
+		}
+		display.PrintTrace(trace)
+		lastReport, lastAnswer, lastQuery = report, answer, query+" "+standaloneQuery
+
+		// Save both messages to history, including an answer the user
+		// stopped, which ends with truncatedMarker
+		userMsg := history.Message{
+			Role:      "user",
+			Content:   query,
+			Timestamp: now,
+		}
+		historyMgr.AddMessage(userMsg)
+
+		assistantMsg := history.Message{
+			Role:      "assistant",
+			Content:   postprocess.WithTLDR(answer, tldr),
+			Timestamp: time.Now(),
+			Thinking:  thinking,
+		}
 
-			// Store thinking separately if available (for future reference)
-			if thinking != "" && cfg.Verbose {
-				// Could save thinking to a separate field in future
-				_ = thinking
+		if len(sourceURLs) > 0 || standaloneQuery != "" {
+			assistantMsg.Metadata = &history.Metadata{
+				SearchPerformed: len(sourceURLs) > 0,
+				SourceURLs:      sourceURLs,
+				StandaloneQuery: standaloneQuery,
 			}
+			assistantMsg.Metadata.Pages = savePages(pageStore, historyMgr, promptSources, report, display)
+		}
+
+		historyMgr.AddMessage(assistantMsg)
+		if !stopped {
+			answerSinks.Publish(sinks.Record{
+				Mode:     "chat",
+				Model:    cfg.ModelName,
+				Question: query,
+				Answer:   postprocess.WithTLDR(answer, tldr),
+				Sources:  sourceURLs,
+			})
+		}
 
-			historyMgr.AddMessage(assistantMsg)
+		// Keep what was crawled for future questions
+		if kb != nil && len(crawlResults) > 0 {
+			indexCrawlResults(ctx, kb, crawlResults, cfg, display)
 		}
 	}
 
+	stopRecording(recorder, display)
+	summarizeSession(ctx, cfg, ollamaClient, historyMgr, display)
+
 	// Stop the model before exiting
 	display.PrintInfo("Stopping model to free up RAM...")
 	if err := ollamaClient.StopModel(cfg.ModelName); err != nil {
@@ -328,26 +930,106 @@ func main() {
 }
 
 // parseFlags parses command-line flags with thinking option
-func parseFlags() (*config.Config, bool) {
+func parseFlags(args []string) (*config.Config, bool) {
 	cfg := config.NewConfig()
 
+	// Load the config file first so that flags override it
+	configPath := configPathFromArgs(args)
+	if err := cfg.LoadFile(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	flag.String("config", configPath, "Path to JSON config file")
+
 	flag.StringVar(&cfg.ModelName, "model", cfg.ModelName, "Ollama model name")
+	flag.StringVar(&cfg.AnalyzerModel, "analyzer-model", cfg.AnalyzerModel, "Small model for search decisions (e.g. qwen2.5:0.5b); empty uses --model")
 	flag.StringVar(&cfg.OllamaURL, "ollama-url", cfg.OllamaURL, "Ollama API URL")
+	flag.Func("fallback-ollama", "Comma-separated Ollama URLs to fail over to when --ollama-url is down", func(s string) error {
+		cfg.OllamaFallbackURLs = nil
+		for _, u := range strings.Split(s, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				cfg.OllamaFallbackURLs = append(cfg.OllamaFallbackURLs, u)
+			}
+		}
+		return nil
+	})
+	flag.StringVar(&cfg.OllamaAuth, "ollama-auth", cfg.OllamaAuth, "Authorization for Ollama behind a proxy: a bearer token or user:password (default: $OLLAMA_API_KEY)")
 	flag.StringVar(&cfg.SearXNGURL, "searxng-url", cfg.SearXNGURL, "SearXNG instance URL")
 	flag.BoolVar(&cfg.AutoSearch, "auto-search", cfg.AutoSearch, "Enable automatic web search")
+	flag.BoolVar(&cfg.Offline, "offline", cfg.Offline, "Answer from cached search results, archived and saved pages and the knowledge base, without the network")
 	flag.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "Enable verbose logging")
+	flag.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Search and crawl, then show the assembled prompt instead of asking the model")
+	flag.StringVar(&cfg.TeePath, "tee", cfg.TeePath, "Append each answer to this Markdown file as it is generated")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Write a debug log to log_dir: off, debug, info, warn or error")
 	flag.IntVar(&cfg.MaxResults, "max-results", cfg.MaxResults, "Maximum search results to crawl")
+	flag.DurationVar(&cfg.TurnBudget, "turn-budget", cfg.TurnBudget, "Limit analysis, search and crawl per question (e.g. 20s); 0 means no limit")
+	flag.Int64Var(&cfg.TurnDownloadLimit, "download-limit", cfg.TurnDownloadLimit, "Stop crawling new pages once a question has downloaded this many bytes; 0 means no limit")
+	flag.BoolVar(&cfg.BrowserHeaders, "browser-headers", cfg.BrowserHeaders, "Crawl with rotating browser user agents, Accept-Language and Referer")
+	flag.Func("allow-domains", "Research sandbox: crawl only these comma-separated domains and their subdomains", func(v string) error {
+		cfg.AllowedDomains = nil
+		for _, d := range strings.Split(v, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				cfg.AllowedDomains = append(cfg.AllowedDomains, d)
+			}
+		}
+		return nil
+	})
+	flag.StringVar(&cfg.ServeAddr, "addr", cfg.ServeAddr, "Listen address for the serve subcommand")
+	flag.StringVar(&cfg.DaemonSocket, "socket", cfg.DaemonSocket, "UNIX socket for the daemon and ask subcommands")
+	flag.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint, "Send serve traces to this OTLP/HTTP collector")
+	flag.BoolVar(&cfg.CodeExecution, "code-exec", cfg.CodeExecution, "Let the model run short Python/Go snippets in a sandbox")
+	flag.BoolVar(&cfg.WebTools, "web-tools", cfg.WebTools, "Let the model search the web and read pages while it answers")
+	flag.StringVar(&cfg.AnswerStyle, "style", cfg.AnswerStyle, "Answer style: default, concise, detailed or bullet")
+	flag.BoolVar(&cfg.TLDR, "tldr", cfg.TLDR, "End long chat answers with a 2-3 sentence TL;DR, shown above the sources")
+	flag.IntVar(&cfg.ContextTokens, "num-ctx", cfg.ContextTokens, "Model context window in tokens")
+	flag.Func("temperature", "Sampling temperature, 0 for the likeliest answer (default: the model's)", func(v string) error {
+		t, err := strconv.ParseFloat(v, 64)
+		cfg.Temperature = &t
+		return err
+	})
+	flag.Func("top-p", "Sample from the likeliest tokens covering this share, 0 to 1 (default: the model's)", func(v string) error {
+		p, err := strconv.ParseFloat(v, 64)
+		cfg.TopP = &p
+		return err
+	})
+	flag.Func("seed", "Sampling seed, for reproducible answers", func(v string) error {
+		n, err := strconv.Atoi(v)
+		cfg.Seed = &n
+		return err
+	})
+	flag.StringVar(&cfg.AnswerFormat, "answer-format", cfg.AnswerFormat, "Have the model answer in JSON: json, or json-schema=<file> to match a JSON schema")
+	flag.StringVar(&cfg.CitationStyle, "cite", cfg.CitationStyle, "Citation style: inline, footnote or apa")
+	flag.BoolVar(&cfg.SelectSources, "select-sources", cfg.SelectSources, "Pick which search results to crawl before answering")
+	flag.BoolVar(&cfg.SummarizeSources, "summarize", cfg.SummarizeSources, "Condense each source with summary_model when the crawl is too large to quote")
+	flag.BoolVar(&cfg.TranslateSources, "translate", cfg.TranslateSources, "Translate relevant sources in other languages into English with translate_model")
+	flag.BoolVar(&cfg.CheckConflicts, "check-conflicts", cfg.CheckConflicts, "Check answers against trusted sources and ask before keeping one that contradicts them")
+	flag.BoolVar(&cfg.ShowCost, "cost", cfg.ShowCost, "Show the estimated energy or price of each answer")
+	flag.BoolVar(&cfg.Archive, "archive", cfg.Archive, "Snapshot every crawled page into archive_dir")
+	flag.BoolVar(&cfg.KnowledgeBase, "knowledge", cfg.KnowledgeBase, "Index crawled pages and answer from the local knowledge base")
 
 	// Timeout flag (in seconds)
-	timeoutSeconds := flag.Int("timeout", 600, "Ollama request timeout in seconds (default: 600)")
+	flag.StringVar(&cfg.KeepAlive, "keep-alive", cfg.KeepAlive, "How long the model stays loaded between turns (e.g. 30m); 0 unloads it after each answer, -1m keeps it loaded")
+	timeoutSeconds := flag.Int("timeout", int(cfg.OllamaTimeout.Seconds()), "Ollama request timeout in seconds")
 
 	// Model selection flags
 	useCloud := flag.Bool("cloud", false, "Use gpt-oss:120b-cloud model")
 	showThinking := flag.Bool("show-thinking", true, "Show model thinking process (default: true)")
 	hideThinking := flag.Bool("hide-thinking", false, "Hide model thinking process")
 	noSearch := flag.Bool("no-search", false, "Disable automatic web search")
+	noWarmup := flag.Bool("no-warmup", false, "Don't load the model at startup; the first question loads it instead")
 
-	flag.Parse()
+	// Allow flags after positional arguments (e.g. `batch questions.txt --out x`)
+	var positional []string
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.CommandLine.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(append([]string{"--"}, positional...))
 
 	// Apply cloud model if requested
 	if *useCloud {
@@ -360,6 +1042,19 @@ func parseFlags() (*config.Config, bool) {
 	if *noSearch {
 		cfg.AutoSearch = false
 	}
+	if *noWarmup {
+		cfg.Warmup = false
+	}
+	cfg.Normalize()
+
+	// Keep credentials out of the debug log
+	var redact func(string) string
+	if cfg.LogLevel != "off" {
+		redact = loadSecrets(cfg).Redact
+	}
+	if _, err := logging.Setup(cfg.LogDir, cfg.LogLevel, redact); err != nil {
+		fmt.Fprintf(os.Stderr, "Logging disabled: %v\n", err)
+	}
 
 	// Hide thinking takes precedence if specified
 	if *hideThinking {
@@ -369,9 +1064,67 @@ func parseFlags() (*config.Config, bool) {
 	return cfg, *showThinking
 }
 
+// loadSecrets resolves the credentials named in cfg once per process and
+// shares them: keychain lookups are slow, and the log's redaction, the REPL
+// and the clients all need them
+func loadSecrets(cfg *config.Config) *secrets.Store {
+	secretsOnce.Do(func() { loadedSecrets = secrets.Load(cfg.Secrets) })
+	return loadedSecrets
+}
+
+var (
+	secretsOnce   sync.Once
+	loadedSecrets *secrets.Store
+)
+
+// configPathFromArgs finds the --config flag value ahead of full flag parsing
+func configPathFromArgs(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return config.DefaultConfigPath()
+}
+
+// applyConfigReloads applies any pending config file changes to the running session
+func applyConfigReloads(watcher *config.Watcher, cfg *config.Config, display *ui.EnhancedDisplay, ollamaClient *ollama.Client, searxngClient *searxng.Client, webCrawler *crawler.Crawler) {
+	for {
+		select {
+		case err := <-watcher.Errors():
+			display.PrintWarning(fmt.Sprintf("Config reload failed: %v", err))
+		case reload := <-watcher.Reloads():
+			changes := cfg.ApplyReload(reload)
+			if len(changes) == 0 {
+				continue
+			}
+			ollamaClient.SetTimeout(cfg.OllamaTimeout)
+			searxngClient.SetTimeout(cfg.SearchTimeout)
+			webCrawler.SetTimeout(cfg.CrawlTimeout)
+			display.SetTheme(cfg.Theme)
+			display.PrintInfo(fmt.Sprintf("Config reloaded: %s", strings.Join(changes, ", ")))
+		default:
+			return
+		}
+	}
+}
+
 // checkModel verifies that the specified model exists
 func checkModel(client *ollama.Client, modelName string, display *ui.EnhancedDisplay) error {
 	models, err := client.ListModels()
+	return findModel(models, err, modelName, display)
+}
+
+// findModel reports whether modelName is among the listed models, showing
+// the ones available when it is not
+func findModel(models []string, err error, modelName string, display *ui.EnhancedDisplay) error {
 	if err != nil {
 		display.PrintError(fmt.Errorf("failed to list models: %w", err))
 		return err
@@ -383,204 +1136,113 @@ func checkModel(client *ollama.Client, modelName string, display *ui.EnhancedDis
 		}
 	}
 
-	display.PrintError(fmt.Errorf("model '%s' not found", modelName))
+	err = errs.New(errs.ModelMissing, modelName, nil)
+	display.PrintError(err)
 	display.PrintInfo("Available models:")
 	for _, m := range models {
 		fmt.Printf("  - %s\n", m)
 	}
-	display.PrintInfo(fmt.Sprintf("Pull the model with: ollama pull %s", modelName))
-
-	return fmt.Errorf("model not found")
+	return err
 }
 
-// performSearch executes web search with enhanced display
-func performSearch(ctx context.Context, display *ui.EnhancedDisplay, searxngClient *searxng.Client, webCrawler *crawler.Crawler, query string, cfg *config.Config) (string, []string) {
-	display.PrintSearchActivity("Searching the web")
-
-	results, err := searxngClient.Search(ctx, query, cfg.MaxResults)
-	if err != nil {
-		display.PrintWarning(fmt.Sprintf("Search failed: %v", err))
-		return "", nil
+// snippetFallback stands in for pages that could not be fetched while search
+// is degraded, using the snippets stored with the cached results
+func snippetFallback(display *ui.EnhancedDisplay, contentGuard *guard.Filter, results []searxng.SearchResult) []crawler.CrawlResult {
+	pages := degrade.SnippetPages(results)
+	warnInjections(display, contentGuard.Clean(pages))
+	if len(pages) > 0 {
+		display.PrintInfo(fmt.Sprintf("Pages unreachable; using cached snippets from %d results", len(pages)))
 	}
+	return pages
+}
 
-	if len(results) == 0 {
-		display.PrintInfo("No search results found")
-		return "", nil
-	}
-
-	urls := make([]string, len(results))
-	for i, result := range results {
-		urls[i] = result.URL
-	}
-
-	display.PrintSearchActivity(fmt.Sprintf("Crawling %d URLs", len(urls)))
-
-	crawlResults := webCrawler.CrawlURLs(ctx, urls)
-
-	successCount := 0
-	for _, result := range crawlResults {
-		if result.Error == nil {
-			successCount++
+// footerLines labels each source listed under an answer with its citation
+// number and, for those in retrieved, the date of the copy used
+func footerLines(sources []contextbuilder.Source, retrieved map[string]time.Time) []string {
+	lines := make([]string, len(sources))
+	for i, s := range sources {
+		lines[i] = fmt.Sprintf("[%d] %s", s.Number, s.URL)
+		if date, ok := retrieved[s.URL]; ok {
+			lines[i] += fmt.Sprintf(" (retrieved %s)", date.Format("2006-01-02"))
 		}
 	}
-
-	if successCount > 0 {
-		display.PrintSuccess(fmt.Sprintf("Gathered information from %d sources", successCount))
-	}
-
-	searchContext := buildSearchContext(crawlResults)
-	return searchContext, urls
+	return lines
 }
 
-// performMultiSearch executes multiple web searches and aggregates results
-func performMultiSearch(ctx context.Context, display *ui.EnhancedDisplay, searxngClient *searxng.Client, webCrawler *crawler.Crawler, queries []string, cfg *config.Config) (string, []string) {
-	if len(queries) == 1 {
-		return performSearch(ctx, display, searxngClient, webCrawler, queries[0], cfg)
-	}
-
-	display.PrintSearchActivity(fmt.Sprintf("Performing %d web searches", len(queries)))
-
-	allCrawlResults := []crawler.CrawlResult{}
-	allURLs := []string{}
-	seenURLs := make(map[string]bool)
-
-	// Perform each search
-	for i, query := range queries {
-		if cfg.Verbose {
-			display.PrintSearchActivity(fmt.Sprintf("Search %d/%d: \"%s\"", i+1, len(queries), query))
-		}
-
-		results, err := searxngClient.Search(ctx, query, cfg.MaxResults)
-		if err != nil {
-			display.PrintWarning(fmt.Sprintf("Search %d failed: %v", i+1, err))
-			continue
-		}
-
-		if len(results) == 0 {
-			if cfg.Verbose {
-				display.PrintInfo(fmt.Sprintf("Search %d: No results found", i+1))
-			}
-			continue
-		}
-
-		// Collect unique URLs
-		urls := []string{}
-		for _, result := range results {
-			if !seenURLs[result.URL] {
-				urls = append(urls, result.URL)
-				seenURLs[result.URL] = true
-				allURLs = append(allURLs, result.URL)
-			}
-		}
-
-		if len(urls) > 0 {
-			// Crawl URLs for this search
-			crawlResults := webCrawler.CrawlURLs(ctx, urls)
-			allCrawlResults = append(allCrawlResults, crawlResults...)
-		}
+// warnInjections tells the user which sources tried to instruct the model
+func warnInjections(display *ui.EnhancedDisplay, warnings []guard.Warning) {
+	for _, w := range warnings {
+		display.PrintWarning(fmt.Sprintf("Removed instruction-like text from %s (%d passage(s))", w.URL, len(w.Matches)))
 	}
+}
 
-	// Count successful crawls
-	successCount := 0
-	for _, result := range allCrawlResults {
-		if result.Error == nil {
-			successCount++
-		}
+// startKnowledgeLookup searches the knowledge base in the background. The
+// returned function waits for it and formats the relevant excerpts.
+func startKnowledgeLookup(ctx context.Context, kb *knowledge.Store, query string, cfg *config.Config) func(display *ui.EnhancedDisplay, trace *ui.Trace) string {
+	type lookup struct {
+		results []knowledge.Result
+		err     error
 	}
+	done := make(chan lookup, 1)
+	go func() {
+		results, err := kb.Search(ctx, query, cfg.KnowledgeTopK)
+		done <- lookup{results, err}
+	}()
 
-	if successCount > 0 {
-		display.PrintSuccess(fmt.Sprintf("Gathered information from %d sources across %d searches", successCount, len(queries)))
-	} else {
-		display.PrintWarning("No information gathered from searches")
+	return func(display *ui.EnhancedDisplay, trace *ui.Trace) string {
+		l := <-done
+		return relevantKnowledge(l.results, l.err, cfg, display, trace)
 	}
-
-	searchContext := buildSearchContext(allCrawlResults)
-	return searchContext, allURLs
 }
 
-// buildSearchContext formats crawled content for LLM
-func buildSearchContext(results []crawler.CrawlResult) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Web Search Results\n\n")
-	sb.WriteString("The following information was retrieved from the web:\n\n")
-
-	sourceNum := 1
-	for _, result := range results {
-		if result.Error != nil {
-			continue // Skip failed crawls
-		}
+// relevantKnowledge formats knowledge base results above the minimum score
+func relevantKnowledge(results []knowledge.Result, err error, cfg *config.Config, display *ui.EnhancedDisplay, trace *ui.Trace) string {
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Knowledge base lookup failed: %v", err))
+		return ""
+	}
 
-		if result.Content == "" {
-			continue // Skip empty content
+	relevant := results[:0]
+	for _, r := range results {
+		if r.Score >= cfg.KnowledgeMinScore {
+			relevant = append(relevant, r)
 		}
-
-		sb.WriteString(fmt.Sprintf("## Source %d: %s\n", sourceNum, result.Title))
-		sb.WriteString(fmt.Sprintf("URL: %s\n\n", result.URL))
-		sb.WriteString(result.Content)
-		sb.WriteString("\n\n---\n\n")
-
-		sourceNum++
 	}
 
-	return sb.String()
+	trace.Add("Knowledge base: %d of %d excerpt(s) above %.2f", len(relevant), len(results), cfg.KnowledgeMinScore)
+	return knowledge.BuildContext(relevant)
 }
 
-// buildMessages constructs the message array for Ollama
-func buildMessages(historyMgr *history.Manager, currentQuery string, searchContext string, fileContext string) []ollama.Message {
-	messages := []ollama.Message{}
-
-	// Add system message
-	systemPrompt := "You are a helpful AI assistant."
-	if searchContext != "" {
-		systemPrompt += " You have access to current web information to answer questions accurately. Cite sources when referencing specific information."
-	}
-	if fileContext != "" {
-		systemPrompt += " The user has provided file contents that you MUST read and analyze carefully. Base your answer on the ACTUAL contents of the files provided, not on assumptions or general knowledge."
-	}
-
-	messages = append(messages, ollama.Message{
-		Role:    "system",
-		Content: systemPrompt,
+// indexCrawlResults adds successfully crawled pages to the knowledge base
+// and returns how many were added
+func indexCrawlResults(ctx context.Context, kb *knowledge.Store, results []crawler.CrawlResult, cfg *config.Config, display *ui.EnhancedDisplay) int {
+	indexed := 0
+	var failed []error
+	err := kb.Batch(func() error {
+		for _, result := range results {
+			if result.Error != nil || result.Content == "" {
+				continue
+			}
+			if _, err := kb.Add(ctx, result.URL, result.Title, result.Content); err != nil {
+				slog.Warn("failed to index page", "url", result.URL, "error", err)
+				failed = append(failed, err)
+				continue
+			}
+			indexed++
+		}
+		return nil
 	})
-
-	// File context will be prepended to the current query instead of being a separate message
-
-	// Add search context if available
-	if searchContext != "" {
-		messages = append(messages, ollama.Message{
-			Role:    "user",
-			Content: searchContext,
-		})
-		messages = append(messages, ollama.Message{
-			Role:    "assistant",
-			Content: "I've reviewed the web search results and I'm ready to answer your question based on this information.",
-		})
+	if len(failed) > 0 {
+		display.PrintWarning(fmt.Sprintf("Failed to index %d page(s) into the knowledge base: %v", len(failed), failed[0]))
 	}
-
-	// Add recent conversation history (last 10 messages, excluding current)
-	recentMessages := historyMgr.GetRecentMessages(10)
-	for _, msg := range recentMessages {
-		messages = append(messages, ollama.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to save the knowledge base: %v", err))
 	}
 
-	// Add current query (with file context prepended if available)
-	finalQuery := currentQuery
-	if fileContext != "" {
-		// Prepend file contents directly to the query for better context
-		finalQuery = fileContext + "\n\n" + currentQuery
-		// Debug output would go here but we can't print from this function
+	if indexed > 0 && cfg.Verbose {
+		display.PrintInfo(fmt.Sprintf("Indexed %d page(s) into the knowledge base", indexed))
 	}
-
-	messages = append(messages, ollama.Message{
-		Role:    "user",
-		Content: finalQuery,
-	})
-
-	return messages
+	return indexed
 }
 
 // displayFullHistory shows all conversation history
@@ -613,6 +1275,103 @@ func displayFullHistory(historyMgr *history.Manager, display *ui.EnhancedDisplay
 	display.PrintSeparator()
 }
 
+// saveLastNote writes the most recent question and answer to the notes vault.
+// Extra arguments are added as tags, with or without a leading #.
+func saveLastNote(historyMgr *history.Manager, cfg *config.Config, args []string, display *ui.EnhancedDisplay) {
+	messages := historyMgr.GetRecentMessages(10)
+
+	var question, answer *history.Message
+	for i := len(messages) - 1; i > 0; i-- {
+		if messages[i].Role == "assistant" && messages[i-1].Role == "user" {
+			answer, question = &messages[i], &messages[i-1]
+			break
+		}
+	}
+	if answer == nil {
+		display.PrintInfo("Nothing to save yet. Ask a question first.")
+		return
+	}
+
+	tags := append([]string(nil), cfg.NotesTags...)
+	for _, arg := range args {
+		if tag := strings.TrimPrefix(arg, "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	note := notes.Note{
+		Question: question.Content,
+		Answer:   answer.Content,
+		Model:    cfg.ModelName,
+		Tags:     tags,
+		Time:     answer.Timestamp,
+	}
+	if answer.Metadata != nil {
+		note.Sources = answer.Metadata.SourceURLs
+	}
+
+	path, err := notes.Save(cfg.NotesDir, note)
+	if err != nil {
+		display.PrintError(err)
+		return
+	}
+	display.PrintSuccess(fmt.Sprintf("Saved note to %s", path))
+}
+
+// displayKeys shows which providers have credentials without revealing them
+func displayKeys(store *secrets.Store, display *ui.EnhancedDisplay) {
+	display.PrintSeparator()
+	fmt.Println("Provider Credentials")
+	display.PrintSeparator()
+
+	for _, st := range store.Statuses() {
+		if st.Source == secrets.SourceNone {
+			fmt.Printf("  %-10s not configured (set %s)\n", st.Provider, st.Location)
+			continue
+		}
+		fmt.Printf("  %-10s %s  from %s %s\n", st.Provider, st.Masked, st.Source, st.Location)
+	}
+
+	display.PrintSeparator()
+}
+
+// statsTopSites is how many sites /stats lists
+const statsTopSites = 10
+
+// displayStats shows what the crawler has downloaded this session, in total
+// and from the busiest sites, the auto-tuned worker count if any, and the
+// estimated cost of the session's answers
+func displayStats(meter *bandwidth.Meter, tuner *crawler.Tuner, hostileDomains *hostile.Tracker, costs *turnCosts, display *ui.EnhancedDisplay) {
+	totals := meter.Totals()
+	display.PrintSeparator()
+	fmt.Println("Session Statistics")
+	display.PrintSeparator()
+
+	if totals.Requests == 0 {
+		fmt.Printf("\n  Nothing crawled yet this session\n")
+		costs.printTotals()
+		display.PrintSeparator()
+		return
+	}
+	fmt.Printf("\n  %s downloaded in %d requests from %d sites\n\n", bandwidth.FormatBytes(totals.Bytes), totals.Requests, len(totals.Sites))
+	for i, site := range totals.Sites {
+		if i == statsTopSites {
+			fmt.Printf("  ... and %d more sites\n", len(totals.Sites)-statsTopSites)
+			break
+		}
+		fmt.Printf("  %-32s %10s  %d requests\n", site.Host, bandwidth.FormatBytes(site.Bytes), site.Requests)
+	}
+	if tuner != nil {
+		fmt.Printf("\n  Crawl workers: %d (auto-tuned)\n", tuner.Limit())
+	}
+	if skipping := hostileDomains.Skipping(); len(skipping) > 0 {
+		fmt.Printf("\n  Skipping after repeated refusals: %s\n", strings.Join(skipping, ", "))
+	}
+	costs.printTotals()
+
+	display.PrintSeparator()
+}
+
 // displayAvailableFiles shows all files that can be referenced with @
 func displayAvailableFiles(workingDir string, display *ui.EnhancedDisplay) {
 	display.PrintSeparator()
@@ -639,6 +1398,8 @@ func displayAvailableFiles(workingDir string, display *ui.EnhancedDisplay) {
 type FileReference struct {
 	Path    string
 	Content string
+	Image   string // base64 contents of an image file, sent to the model as an image
+	Summary bool   // Content is a summary of the file, carried over from an earlier turn
 	Error   error
 }
 
@@ -684,6 +1445,13 @@ func readFileReferences(files []string, workingDir string) []FileReference {
 			fullPath = filepath.Join(workingDir, file)
 		}
 
+		// Images are attached to the question rather than quoted
+		if isImageFile(file) {
+			ref.Image, ref.Error = loadImage(fullPath)
+			references = append(references, ref)
+			continue
+		}
+
 		// Read file content
 		content, err := os.ReadFile(fullPath)
 		if err != nil {
@@ -700,7 +1468,13 @@ func readFileReferences(files []string, workingDir string) []FileReference {
 
 // buildFileContext formats file references for LLM
 func buildFileContext(references []FileReference) string {
-	if len(references) == 0 {
+	text := 0
+	for _, ref := range references {
+		if ref.Image == "" {
+			text++
+		}
+	}
+	if text == 0 {
 		return ""
 	}
 
@@ -708,12 +1482,19 @@ func buildFileContext(references []FileReference) string {
 	sb.WriteString("=== FILE CONTENTS FOR ANALYSIS ===\n\n")
 
 	for _, ref := range references {
+		if ref.Image != "" {
+			continue
+		}
 		if ref.Error != nil {
 			sb.WriteString(fmt.Sprintf("File: %s - Error: %v\n\n", ref.Path, ref.Error))
 			continue
 		}
 
-		sb.WriteString(fmt.Sprintf("File path: %s\n", ref.Path))
+		if ref.Summary {
+			sb.WriteString(fmt.Sprintf("File path: %s (summary of the file, which is too long to quote)\n", ref.Path))
+		} else {
+			sb.WriteString(fmt.Sprintf("File path: %s\n", ref.Path))
+		}
 		sb.WriteString("--- BEGIN FILE CONTENTS ---\n")
 		sb.WriteString(ref.Content)
 		sb.WriteString("\n--- END FILE CONTENTS ---\n\n")
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/searxng"
+)
+
+// offlineTransport refuses every request, so that in offline mode nothing
+// reaches the network even by a path that skips the local copies
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("offline mode: not fetching %s", req.URL.Redacted())
+}
+
+// goOffline keeps the SearXNG client and the crawler off the network
+func goOffline(searxngClient *searxng.Client, webCrawler *crawler.Crawler) {
+	searxngClient.SetTransport(offlineTransport{})
+	webCrawler.SetTransport(offlineTransport{})
+}
+
+// retrievedDates maps the URL of each source in the prompt that came from a
+// local copy to when that copy was retrieved
+func retrievedDates(pages []crawler.CrawlResult, sources []contextbuilder.Source) map[string]time.Time {
+	used := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		used[s.URL] = true
+	}
+	dates := make(map[string]time.Time)
+	for _, p := range pages {
+		if used[p.URL] && !p.Saved.IsZero() {
+			dates[p.URL] = p.Saved
+		}
+	}
+	return dates
+}
+
+// cachedNotice says that an answer was built from cached data and how old
+// that data is, or returns "" when no source came from a cache
+func cachedNotice(dates map[string]time.Time) string {
+	if len(dates) == 0 {
+		return ""
+	}
+	days := make([]string, 0, len(dates))
+	for _, d := range dates {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	sort.Strings(days)
+	if days[0] == days[len(days)-1] {
+		return fmt.Sprintf("Offline: this answer is based on cached data retrieved %s", days[0])
+	}
+	return fmt.Sprintf("Offline: this answer is based on cached data retrieved between %s and %s", days[0], days[len(days)-1])
+}
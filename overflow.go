@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/terminal"
+	"web-ollama/internal/ui"
+)
+
+// checkContextWindow reads the model's metadata and lowers context_tokens to
+// the window the model was trained with when it is set larger. Ollama cuts
+// the start off prompts that exceed the model's window without saying so,
+// which would lose the system prompt and sources rather than the oldest
+// history. The prompt budget, and with it the history window and the room
+// for search excerpts, follows context_tokens. warn reports the change.
+func checkContextWindow(client *ollama.Client, cfg *config.Config, warn func(string)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := client.ShowModel(ctx, cfg.ModelName)
+	if err != nil {
+		slog.Debug("could not read the model's metadata", "model", cfg.ModelName, "error", err)
+		return
+	}
+	slog.Debug("model metadata", "model", cfg.ModelName, "family", info.Family, "parameters", info.ParameterSize,
+		"quantization", info.QuantizationLevel, "context_length", info.ContextLength)
+	window := info.ContextLength
+	if window == 0 || window >= cfg.ContextTokens {
+		return
+	}
+
+	warn(fmt.Sprintf("%s has a %d-token context window but context_tokens is %d; using %d so long prompts are trimmed here instead of cut off by Ollama",
+		cfg.ModelName, window, cfg.ContextTokens, window))
+	cfg.ContextTokens = window
+	if cfg.AnswerTokens >= window/2 {
+		cfg.AnswerTokens = window / 8
+	}
+}
+
+// fitTurn handles a turn whose context does not fit the prompt budget, as
+// overflow_action says: keep the builder's trimming of the oldest history,
+// fit the conversation before the sources, or summarize the sources and
+// build again. "ask" lets the user choose when there are sources to give
+// up; otherwise the history is trimmed with a warning. It returns the turn
+// as built and false if the user cancelled it.
+func fitTurn(ctx context.Context, cfg *config.Config, client summarize.Client, turn contextbuilder.Turn, messages []ollama.Message, report contextbuilder.Report, trace *ui.Trace, display *ui.EnhancedDisplay) (contextbuilder.Turn, []ollama.Message, contextbuilder.Report, bool) {
+	display.PrintWarning(fmt.Sprintf("This turn needs ~%d tokens but only %d fit the model's context; left out: %s",
+		report.Wanted(), report.Budget, droppedSummary(report)))
+
+	action := cfg.OverflowAction
+	if len(turn.Sources) == 0 {
+		action = "trim"
+	}
+	if action == "ask" {
+		action = askOverflowAction(display)
+	}
+	trace.Add("Overflow: %s (~%d tokens wanted, %d budget)", action, report.Wanted(), report.Budget)
+
+	switch action {
+	case "cancel":
+		return turn, nil, report, false
+	case "drop_sources":
+		turn.KeepHistory = true
+	case "summarize":
+		display.PrintInfo(fmt.Sprintf("Summarizing %d sources...", len(turn.Sources)))
+		turn.Sources = summarize.OnDemand(client, cfg).Summarize(ctx, turn.Query, turn.Sources)
+	default:
+		return turn, messages, report, true
+	}
+
+	messages, report = contextbuilder.New(cfg).Build(turn)
+	if report.Trimmed() {
+		display.PrintInfo("Still left out: " + droppedSummary(report))
+	}
+	return turn, messages, report, true
+}
+
+// askOverflowAction asks what to give up to fit the context
+func askOverflowAction(display *ui.EnhancedDisplay) string {
+	for {
+		display.PrintInfo("Enter: trim the oldest history and extra source passages | d: drop sources to keep the conversation | s: summarize the sources | c: cancel")
+		display.PrintPrompt()
+		input, err := terminal.ReadUserInput()
+		if err != nil {
+			return "trim"
+		}
+		switch strings.ToLower(input) {
+		case "", "t", "trim":
+			return "trim"
+		case "d", "drop":
+			return "drop_sources"
+		case "s", "summarize":
+			return "summarize"
+		case "c", "cancel":
+			return "cancel"
+		}
+		display.PrintWarning(fmt.Sprintf("Unknown choice %q", input))
+	}
+}
+
+// droppedSummary counts what a report left out, e.g. "3 history messages,
+// 12 source passages"
+func droppedSummary(report contextbuilder.Report) string {
+	nouns := map[string]string{
+		"history":   "history messages",
+		"source":    "source passages",
+		"knowledge": "knowledge blocks",
+		"memory":    "memory blocks",
+		"primer":    "session summaries",
+	}
+	counts := map[string]int{}
+	for _, item := range report.Dropped() {
+		counts[item.Kind]++
+	}
+	var parts []string
+	for _, kind := range []string{"history", "source", "knowledge", "memory", "primer"} {
+		if counts[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[kind], nouns[kind]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
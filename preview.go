@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// previewChars is how much of each message /preview and --dry-run show
+const previewChars = 600
+
+// showPreview prints the prompt a turn would send, for /preview and
+// --dry-run, in place of the model's answer
+func showPreview(messages []ollama.Message, report contextbuilder.Report, display *ui.EnhancedDisplay) {
+	display.PrintSeparator()
+	fmt.Println("Prompt Preview (not sent)")
+	display.PrintSeparator()
+	fmt.Println()
+	fmt.Println(contextbuilder.Preview(messages, previewChars))
+	fmt.Println()
+
+	display.PrintInfo(report.Summary())
+	for _, item := range report.Dropped() {
+		display.PrintInfo(fmt.Sprintf("  dropped %s (~%d tokens)", item.Label, item.Tokens))
+	}
+	if err := report.Overflow(); err != nil {
+		display.PrintError(err)
+	}
+	display.PrintSeparator()
+}
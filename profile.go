@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"web-ollama/internal/analyzer"
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/crawler"
+	"web-ollama/internal/grounding"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/ui"
+)
+
+// profileStep is one timed line of the profile report
+type profileStep struct {
+	name     string
+	duration time.Duration
+	detail   string
+	indent   bool // sub-step of the previous stage
+}
+
+// runProfile implements `web-ollama profile "query"`: answer one query and
+// print how long each stage took. Returns the process exit code.
+func runProfile(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	query := strings.TrimSpace(strings.Join(flag.Args(), " "))
+	if query == "" {
+		display.PrintError(fmt.Errorf("usage: web-ollama profile [flags] \"query\""))
+		return 2
+	}
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	display.PrintInfo(fmt.Sprintf("Profiling %q with %s", query, cfg.ModelName))
+	start := time.Now()
+	var steps []profileStep
+
+	var crawled []crawler.CrawlResult
+	if grounder != nil {
+		var groundSteps []profileStep
+		crawled, groundSteps, err = profileGrounding(ctx, grounder, query)
+		steps = append(steps, groundSteps...)
+		if err != nil {
+			printProfile(steps, time.Since(start))
+			display.PrintError(err)
+			return 1
+		}
+	} else {
+		steps = append(steps, profileStep{name: "analysis", detail: "skipped, web search disabled"})
+	}
+
+	// Prompt build
+	buildStart := time.Now()
+	messages, report := contextbuilder.New(cfg).Build(contextbuilder.Turn{Query: query, Sources: crawled})
+	steps = append(steps, profileStep{
+		name:     "prompt build",
+		duration: time.Since(buildStart),
+		detail:   fmt.Sprintf("%d messages, %s", len(messages), report.Summary()),
+	})
+
+	// Generation, split at the first token the model emits
+	var firstToken time.Time
+	markFirst := func(string) {
+		if firstToken.IsZero() {
+			firstToken = time.Now()
+		}
+	}
+	var stats ollama.Stats
+	genStart := time.Now()
+	thinking, answer, err := ollamaClient.ChatWithCallbacks(ctx, ollama.ChatRequest{
+		Model:    cfg.ModelName,
+		Messages: messages,
+		Options:  cfg.ModelOptions(),
+	}, ollama.StreamCallbacks{OnThinking: markFirst, OnAnswer: markFirst, OnStats: func(s ollama.Stats) { stats = s }})
+	genEnd := time.Now()
+	if err != nil {
+		printProfile(steps, time.Since(start))
+		display.PrintError(fmt.Errorf("generation failed: %w", err))
+		return 1
+	}
+
+	if firstToken.IsZero() {
+		firstToken = genEnd
+	}
+	firstDetail := "includes model load and prompt evaluation"
+	genDetail := fmt.Sprintf("%d answer chars, %d thinking chars", len(answer), len(thinking))
+	if stats.AnswerTokens > 0 {
+		// Ollama's own timings split the wait before the first token
+		firstDetail = fmt.Sprintf("model load %v, %d prompt tokens read in %v",
+			stats.LoadDuration.Round(time.Millisecond), stats.PromptTokens, stats.PromptDuration.Round(time.Millisecond))
+		genDetail = fmt.Sprintf("%d tokens at %.1f tok/s, %s", stats.AnswerTokens, stats.TokensPerSecond(), genDetail)
+	}
+	steps = append(steps,
+		profileStep{name: "time to first token", duration: firstToken.Sub(genStart), detail: firstDetail},
+		profileStep{name: "generation", duration: genEnd.Sub(firstToken), detail: genDetail},
+	)
+
+	printProfile(steps, time.Since(start))
+	return 0
+}
+
+// profileGrounding grounds query as every other mode does, timing each stage
+// through the Grounder's hooks. Searches the analyzer names while it is still
+// deciding overlap with the analysis.
+func profileGrounding(ctx context.Context, g *grounding.Grounder, query string) ([]crawler.CrawlResult, []profileStep, error) {
+	var mu sync.Mutex
+	start := time.Now()
+	analysis := profileStep{name: "analysis"}
+	var queries []string
+	started := make(map[string]time.Time)
+	searched := make(map[string]time.Time)
+	steps := make(map[string][]profileStep)
+
+	g.Hooks.OnAnalyzed = func(decision analyzer.SearchDecision, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		analysis.duration = time.Since(start)
+		analysis.detail = fmt.Sprintf("needs_search=%v", decision.NeedsSearch)
+		if err != nil {
+			analysis.detail = "failed"
+		}
+	}
+	g.Hooks.OnQuery = func(q string) {
+		mu.Lock()
+		defer mu.Unlock()
+		queries = append(queries, q)
+		started[q] = time.Now()
+	}
+	g.Hooks.OnResults = func(q string, results []searxng.SearchResult, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		searched[q] = time.Now()
+		search := profileStep{name: fmt.Sprintf("search %q", q), duration: searched[q].Sub(started[q])}
+		if err != nil {
+			search.detail = fmt.Sprintf("failed: %v", err)
+		} else {
+			search.detail = fmt.Sprintf("%d new results", len(results))
+		}
+		steps[q] = append(steps[q], search)
+	}
+	g.Hooks.OnCrawled = func(q string, crawled []crawler.CrawlResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps[q] = append(steps[q], profileStep{
+			name:     "crawl",
+			duration: time.Since(searched[q]),
+			detail:   fmt.Sprintf("%d pages in parallel", len(crawled)),
+		})
+		for _, c := range crawled {
+			detail := fmt.Sprintf("%.1f KB", float64(len(c.Content))/1024)
+			if c.Error != nil {
+				detail = fmt.Sprintf("failed: %v", c.Error)
+			}
+			steps[q] = append(steps[q], profileStep{name: shortURL(c.URL), duration: c.Duration, detail: detail, indent: true})
+		}
+	}
+
+	result, err := g.Ground(ctx, query, nil)
+	mu.Lock()
+	defer mu.Unlock()
+	all := []profileStep{analysis}
+	for _, q := range queries {
+		all = append(all, steps[q]...)
+	}
+	if err != nil {
+		return nil, all, err
+	}
+	return result.Crawled, all, nil
+}
+
+// printProfile writes the timing table to stdout
+func printProfile(steps []profileStep, total time.Duration) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range steps {
+		name := s.name
+		if s.indent {
+			name = "  " + name
+		}
+		fmt.Fprintf(w, "%s\t%s\t  %s\t\n", name, formatStepDuration(s.duration), s.detail)
+	}
+	fmt.Fprintf(w, "total\t%s\t\t\n", formatStepDuration(total))
+	w.Flush()
+}
+
+// formatStepDuration rounds a duration for display
+func formatStepDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(10 * time.Millisecond).String()
+	}
+}
+
+// shortURL trims the scheme and long paths so URLs fit in the table
+func shortURL(u string) string {
+	u = strings.TrimPrefix(strings.TrimPrefix(u, "https://"), "http://")
+	if r := []rune(u); len(r) > 60 {
+		u = string(r[:57]) + "..."
+	}
+	return u
+}
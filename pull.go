@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/terminal"
+	"web-ollama/internal/ui"
+)
+
+// offerPull asks whether to download a missing model and pulls it with a
+// progress bar. It reports whether the model is now available; without a
+// terminal to ask on, it is not.
+func offerPull(client *ollama.Client, model string, display *ui.EnhancedDisplay) bool {
+	if !terminal.IsTerminal() {
+		return false
+	}
+	display.PrintInfo(fmt.Sprintf("Download %s now? [Y/n]", model))
+	display.PrintPrompt()
+	input, err := terminal.ReadUserInput()
+	if err != nil || (input != "" && !strings.HasPrefix(strings.ToLower(input), "y")) {
+		return false
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := pullModel(ctx, client, model, display); err != nil {
+		display.PrintError(err)
+		return false
+	}
+	display.PrintSuccess(fmt.Sprintf("Downloaded %s", model))
+	return true
+}
+
+// pullModel downloads model, drawing a progress bar for each layer and
+// printing the other steps as they happen. Ctrl+C stops the download.
+func pullModel(ctx context.Context, client *ollama.Client, model string, display *ui.EnhancedDisplay) error {
+	status, bar := "", false
+	err := client.PullModel(ctx, model, func(p ollama.PullProgress) {
+		if p.Total > 0 {
+			if p.Status != status && bar {
+				display.EndProgress()
+			}
+			status, bar = p.Status, true
+			display.PrintProgress(p.Status, float64(p.Completed)/float64(p.Total),
+				fmt.Sprintf("%s / %s", bandwidth.FormatBytes(p.Completed), bandwidth.FormatBytes(p.Total)))
+			return
+		}
+		if p.Status == status {
+			return
+		}
+		if bar {
+			display.EndProgress()
+			bar = false
+		}
+		status = p.Status
+		if p.Status != "success" {
+			display.PrintInfo(p.Status)
+		}
+	})
+	if bar {
+		display.EndProgress()
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/recording"
+	"web-ollama/internal/ui"
+)
+
+// handleRecord implements /record [file] and /record stop. It returns the
+// active recorder, or nil when not recording.
+func handleRecord(recorder *recording.Recorder, args []string, cfg *config.Config, display *ui.EnhancedDisplay, redact func(string) string) *recording.Recorder {
+	if len(args) == 1 && args[0] == "stop" {
+		if recorder == nil {
+			display.PrintInfo("Not recording")
+			return nil
+		}
+		stopRecording(recorder, display)
+		return nil
+	}
+
+	if recorder != nil {
+		display.PrintInfo(fmt.Sprintf("Already recording to %s (%d events); /record stop to finish", recorder.Path(), recorder.Entries()))
+		return recorder
+	}
+
+	path := recording.DefaultPath(cfg.RecordingsDir, time.Now())
+	if len(args) > 0 {
+		path = args[0]
+	}
+	recorder, err := recording.Start(path, cfg.ModelName, redact)
+	if err != nil {
+		display.PrintError(err)
+		return nil
+	}
+	display.SetRecorder(recorder)
+	display.PrintSuccess(fmt.Sprintf("Recording to %s; /record stop to finish", path))
+	return recorder
+}
+
+// stopRecording detaches and closes the recorder
+func stopRecording(recorder *recording.Recorder, display *ui.EnhancedDisplay) {
+	if recorder == nil {
+		return
+	}
+	display.SetRecorder(nil)
+	if err := recorder.Close(); err != nil {
+		display.PrintError(err)
+		return
+	}
+	display.PrintSuccess(fmt.Sprintf("Saved recording (%d events) to %s", recorder.Entries(), recorder.Path()))
+	display.PrintInfo(fmt.Sprintf("Play it back with: web-ollama replay %s", recorder.Path()))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"web-ollama/internal/recording"
+	"web-ollama/internal/ui"
+)
+
+// runReplay implements `web-ollama replay file`: re-render a session
+// captured with /record. Returns the process exit code.
+func runReplay(args []string) int {
+	speed := flag.Float64("speed", 1, "Playback speed multiplier")
+	instant := flag.Bool("instant", false, "Print the whole recording without pauses")
+	maxGap := flag.Duration("max-gap", 3*time.Second, "Longest pause between events (0 for none)")
+	cfg, showThinking := parseFlags(args)
+	display := ui.NewEnhancedDisplay(showThinking)
+	display.SetTheme(cfg.Theme)
+
+	if flag.NArg() != 1 {
+		display.PrintError(fmt.Errorf("usage: web-ollama replay [--speed 2] [--instant] [--max-gap 3s] file.jsonl"))
+		return 2
+	}
+
+	header, entries, err := recording.Load(flag.Arg(0))
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	display.PrintWelcome(header.Model)
+	display.PrintInfo(fmt.Sprintf("Replaying session recorded %s (%d events)", header.Started.Format("2006-01-02 15:04"), len(entries)))
+
+	player := &recording.Player{Display: display, Speed: *speed, MaxGap: *maxGap}
+	if *instant {
+		player.Speed = 0
+	}
+	if err := player.Play(ctx, entries); err != nil {
+		fmt.Println()
+		display.PrintInfo("Replay stopped")
+		return 130
+	}
+	return 0
+}
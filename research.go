@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/research"
+	"web-ollama/internal/trust"
+	"web-ollama/internal/ui"
+)
+
+// runResearch handles /research <topic>: plan, gather over several rounds,
+// then stream a cited report and save it as Markdown
+func runResearch(ctx context.Context, topic string, cfg *config.Config, display *ui.EnhancedDisplay, tee *answerTee, ollamaClient *ollama.Client, searcher research.Searcher, fetcher research.Fetcher, contentGuard *guard.Filter) {
+	if !cfg.AutoSearch {
+		display.PrintWarning("Research needs web search; SearXNG is unavailable or search is disabled")
+		return
+	}
+
+	started := false
+	researcher := &research.Researcher{
+		Client:         ollamaClient,
+		Searcher:       searcher,
+		Fetcher:        fetcher,
+		Model:          cfg.ModelName,
+		Options:        cfg.ModelOptions(),
+		MaxResults:     cfg.MaxResults,
+		Rounds:         cfg.ResearchRounds,
+		MaxSubtopics:   cfg.ResearchMaxSubtopics,
+		BlockedDomains: cfg.BlockedDomains,
+		Trust:          trust.New(cfg.SourceTrust),
+		Guard:          contentGuard,
+		Progress: func(msg string) {
+			display.PrintSearchActivity(msg)
+		},
+		OnChunk: func(chunk string) {
+			if !started {
+				display.StartAssistantResponse()
+				display.StartAnswer()
+				tee.Start(topic)
+				started = true
+			}
+			display.WriteAnswer(chunk)
+			tee.Write(chunk)
+		},
+	}
+
+	report, err := researcher.Run(ctx, topic)
+	if started {
+		tee.End(err != nil)
+	}
+	if err != nil {
+		display.PrintError(err)
+		return
+	}
+
+	urls := make([]string, len(report.Sources))
+	for i, src := range report.Sources {
+		urls[i] = src.URL
+	}
+	display.EndAssistantResponse(urls)
+
+	path, err := report.Save(cfg.ResearchDir, time.Now())
+	if err != nil {
+		display.PrintError(err)
+		return
+	}
+	display.PrintSuccess(fmt.Sprintf("Report saved to %s", path))
+}
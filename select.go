@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/guard"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/terminal"
+	"web-ollama/internal/ui"
+)
+
+// chooseSources lists search results, with the words of query marked in
+// their snippets, and asks which of them to crawl
+func chooseSources(display *ui.EnhancedDisplay, results []searxng.SearchResult, query string) []searxng.SearchResult {
+	terms := ui.QueryTerms(query)
+	display.PrintInfo(fmt.Sprintf("Found %d results:", len(results)))
+	for i, r := range results {
+		display.PrintInfo(fmt.Sprintf("%2d. %s (%s)", i+1, r.Title, guard.Domain(r.URL)))
+		if snippet := strings.Join(strings.Fields(r.Content), " "); snippet != "" {
+			display.PrintInfo("    " + ui.Highlight(truncateSnippet(snippet), terms))
+		}
+	}
+
+	for {
+		display.PrintInfo("Crawl which? e.g. 1,3,5 or 2-4; Enter or 'all' for all, 'none' to skip")
+		display.PrintPrompt()
+		input, err := terminal.ReadUserInput()
+		if err != nil {
+			return results
+		}
+		picks, err := parseSelection(input, len(results))
+		if err != nil {
+			display.PrintWarning(err.Error())
+			continue
+		}
+		chosen := make([]searxng.SearchResult, len(picks))
+		for i, n := range picks {
+			chosen[i] = results[n-1]
+		}
+		return chosen
+	}
+}
+
+// truncateSnippet shortens a search snippet to one line of output
+func truncateSnippet(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 100 {
+		return s
+	}
+	return string(runes[:97]) + "..."
+}
+
+// parseSelection turns "1,3,5", "2-4", "all", "none" or "" (all) into
+// sorted result numbers between 1 and n
+func parseSelection(input string, n int) ([]int, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "", "all", "a":
+		input = fmt.Sprintf("1-%d", n)
+	case "none", "0":
+		return nil, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi := field, field
+		if i := strings.IndexByte(field, '-'); i > 0 {
+			lo, hi = field[:i], field[i+1:]
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("%q is not a result number or range between 1 and %d", field, n)
+		}
+		for k := from; k <= to; k++ {
+			seen[k] = true
+		}
+	}
+
+	picks := make([]int, 0, len(seen))
+	for k := range seen {
+		picks = append(picks, k)
+	}
+	sort.Ints(picks)
+	return picks, nil
+}
+
+// toggleSelect handles /select [on|off]
+func toggleSelect(args string, cfg *config.Config, display *ui.EnhancedDisplay) {
+	switch strings.TrimSpace(args) {
+	case "on":
+		cfg.SelectSources = true
+	case "off":
+		cfg.SelectSources = false
+	case "":
+		cfg.SelectSources = !cfg.SelectSources
+	default:
+		display.PrintInfo("Usage: /select [on|off]")
+		return
+	}
+	if cfg.SelectSources {
+		display.PrintSuccess("You will pick which search results to crawl")
+	} else {
+		display.PrintSuccess("Search results will be crawled automatically")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"web-ollama/internal/scheduler"
+	"web-ollama/internal/server"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/tracing"
+	"web-ollama/internal/ui"
+)
+
+// runServe implements `web-ollama serve`: an HTTP server with an
+// OpenAI-compatible chat endpoint that adds web search results before
+// forwarding to Ollama. Returns the process exit code.
+func runServe(args []string) int {
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if cfg.OTLPEndpoint != "" {
+		exporter := tracing.Install(cfg.OTLPEndpoint, "web-ollama")
+		defer exporter.Shutdown()
+		display.PrintInfo(fmt.Sprintf("Exporting traces to %s", cfg.OTLPEndpoint))
+	}
+
+	srv := server.New(cfg, ollamaClient, grounder)
+	srv.SetCache(openAnswerCache(cfg, display))
+	srv.SetPostprocessor(openPostprocessor(cfg, display))
+	srv.SetSummarizer(summarize.New(ollamaClient, cfg))
+	srv.SetQueue(scheduler.NewQueue(cfg.ChatConcurrency, cfg.ChatQueueDepth))
+	answerSinks := openSinks(cfg, display)
+	defer answerSinks.Close()
+	srv.SetSinks(answerSinks)
+	display.PrintSuccess(fmt.Sprintf("Serving OpenAI-compatible API at http://%s/v1 (model: %s)", cfg.ServeAddr, cfg.ModelName))
+	display.PrintInfo(fmt.Sprintf("Open http://%s/ in your browser for the web UI", cfg.ServeAddr))
+	display.PrintInfo(fmt.Sprintf("Prometheus metrics at http://%s/metrics", cfg.ServeAddr))
+
+	if err := srv.ListenAndServe(ctx, cfg.ServeAddr); err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	display.PrintInfo("Server stopped")
+	return 0
+}
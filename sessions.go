@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/history"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+)
+
+// sessionSummaryTimeout bounds the summary written when a session closes,
+// so a slow model cannot hold up exiting
+const sessionSummaryTimeout = 45 * time.Second
+
+// summarizeSession stores a summary of the current session, if it has an
+// answer and session summaries are enabled
+func summarizeSession(ctx context.Context, cfg *config.Config, client summarize.Client, historyMgr *history.Manager, display *ui.EnhancedDisplay) {
+	session := historyMgr.GetCurrentSession()
+	if !cfg.SessionSummaries || session == nil || !hasAnswer(session.Messages) {
+		return
+	}
+	model := cfg.SummaryModel
+	if model == "" {
+		model = cfg.ModelName
+	}
+
+	display.PrintInfo("Summarizing this session...")
+	ctx, cancel := context.WithTimeout(ctx, sessionSummaryTimeout)
+	defer cancel()
+	summary, err := summarize.Session(ctx, client, model, session.Messages)
+	if err != nil {
+		display.PrintWarning(err.Error())
+		return
+	}
+	if err := historyMgr.SetSummary(session.ID, summary); err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to save session summary: %v", err))
+	}
+}
+
+// hasAnswer reports whether the assistant replied at least once
+func hasAnswer(messages []history.Message) bool {
+	for _, m := range messages {
+		if m.Role == "assistant" {
+			return true
+		}
+	}
+	return false
+}
+
+// listSessions implements /sessions: past chat sessions, newest first, with
+// their summaries or, for sessions without one, their first question
+func listSessions(historyMgr *history.Manager, display *ui.EnhancedDisplay) {
+	sessions := historyMgr.Sessions()
+	if len(sessions) == 0 {
+		display.PrintInfo("No saved sessions yet")
+		return
+	}
+	current := ""
+	if s := historyMgr.GetCurrentSession(); s != nil {
+		current = s.ID
+	}
+
+	display.PrintSeparator()
+	for i := len(sessions) - 1; i >= 0; i-- {
+		s := sessions[i]
+		marker := ""
+		if s.ID == current {
+			marker = " (current)"
+		}
+		fmt.Printf("\n%d. %s, %d messages%s\n", len(sessions)-i, s.StartedAt.Format("2006-01-02 15:04"), len(s.Messages), marker)
+		if s.Summary != "" {
+			fmt.Printf("   %s\n", s.Summary)
+		} else {
+			fmt.Printf("   %s\n", truncateSnippet(strings.Join(strings.Fields(s.Messages[0].Content), " ")))
+		}
+	}
+	display.PrintSeparator()
+	display.PrintInfo("Continue one with /resume <n>")
+}
+
+// loadHistory loads the history file. Another instance holding it is an
+// error, reported with how to share it; other failures are warnings and
+// start an empty history.
+func loadHistory(historyMgr *history.Manager, display *ui.EnhancedDisplay) error {
+	return historyLoaded(historyMgr.Load(), display)
+}
+
+// historyLoaded reports the outcome of loading the history file as
+// loadHistory does
+func historyLoaded(err error, display *ui.EnhancedDisplay) error {
+	if errors.Is(err, history.ErrInUse) {
+		display.PrintError(err)
+		display.PrintInfo(`Close the other instance, or set "shared_history": true to let instances share the history file`)
+		return err
+	}
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to load history: %v", err))
+	}
+	return nil
+}
+
+// resumeSession implements /resume <n>, where n numbers sessions as
+// /sessions lists them. The current session is summarized first. It returns
+// the resumed session's summary, to prime the model with, and whether a
+// session was resumed.
+func resumeSession(ctx context.Context, args string, cfg *config.Config, client summarize.Client, historyMgr *history.Manager, display *ui.EnhancedDisplay) (string, bool) {
+	sessions := historyMgr.Sessions()
+	n, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || n < 1 || n > len(sessions) {
+		display.PrintInfo("Usage: /resume <n>, with n from /sessions")
+		return "", false
+	}
+	target := sessions[len(sessions)-n]
+	if current := historyMgr.GetCurrentSession(); current != nil && current.ID == target.ID {
+		display.PrintInfo("That is the current session")
+		return "", false
+	}
+
+	summarizeSession(ctx, cfg, client, historyMgr, display)
+	resumed, err := historyMgr.Resume(target.ID)
+	if err != nil {
+		display.PrintError(err)
+		return "", false
+	}
+	display.PrintSuccess(fmt.Sprintf("Resumed the session from %s (%d messages)", resumed.StartedAt.Format("2006-01-02 15:04"), len(resumed.Messages)))
+	if resumed.Summary != "" {
+		display.PrintInfo(resumed.Summary)
+	}
+	if resumed.WorkDir != "" {
+		display.PrintInfo("Working directory: " + describeDir(resumed.WorkDir))
+	}
+	return resumed.Summary, true
+}
+
+// closeSession implements /close: it summarizes the current session and
+// starts a new one
+func closeSession(ctx context.Context, cfg *config.Config, client summarize.Client, historyMgr *history.Manager, display *ui.EnhancedDisplay) {
+	summarizeSession(ctx, cfg, client, historyMgr, display)
+	if err := historyMgr.StartSession(); err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to save history: %v", err))
+	}
+	display.PrintSuccess("Session closed; starting a new one")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"web-ollama/internal/history"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/searxng"
+	"web-ollama/internal/ui"
+)
+
+// startupCheck is one of the checks run concurrently at startup
+type startupCheck struct {
+	name    string
+	run     func() error
+	local   bool // waited for past the budget, e.g. loading files others then read
+	skipped bool // not run, e.g. SearXNG in offline mode
+
+	err      error
+	took     time.Duration
+	finished bool
+}
+
+// readiness is what the startup checks found
+type readiness struct {
+	ollama, models, searxng, history *startupCheck
+	available                        []string // models Ollama has pulled
+	elapsed                          time.Duration
+}
+
+// checkStartup runs the Ollama and SearXNG health checks, the model listing
+// and history loading at the same time, giving up on the network checks
+// still running when budget is spent. A check given up on fails with a
+// budget error; its request ends on its own timeout in the background. A nil
+// searxngClient skips the SearXNG check.
+func checkStartup(budget time.Duration, ollamaClient *ollama.Client, searxngClient *searxng.Client, historyMgr *history.Manager) readiness {
+	var r readiness
+	var available []string
+	r.ollama = &startupCheck{name: "Ollama", run: ollamaClient.HealthCheck}
+	r.models = &startupCheck{name: "models", run: func() error {
+		var err error
+		available, err = ollamaClient.ListModels()
+		return err
+	}}
+	r.history = &startupCheck{name: "history", run: historyMgr.Load, local: true}
+	checks := []*startupCheck{r.ollama, r.models, r.history}
+	if searxngClient != nil {
+		r.searxng = &startupCheck{name: "SearXNG", run: searxngClient.HealthCheck}
+		checks = append(checks, r.searxng)
+	} else {
+		r.searxng = &startupCheck{name: "SearXNG", skipped: true, finished: true}
+	}
+	r.elapsed = runStartupChecks(budget, checks...)
+	if r.models.finished {
+		r.available = available
+	}
+	return r
+}
+
+// runStartupChecks runs checks concurrently and returns how long they took
+// together
+func runStartupChecks(budget time.Duration, checks ...*startupCheck) time.Duration {
+	start := time.Now()
+	type result struct {
+		i    int
+		err  error
+		took time.Duration
+	}
+	results := make(chan result, len(checks)) // abandoned checks must not block
+	for i, c := range checks {
+		go func(i int, run func() error) {
+			began := time.Now()
+			err := run()
+			results <- result{i, err, time.Since(began)}
+		}(i, c.run)
+	}
+
+	deadline := time.NewTimer(budget)
+	defer deadline.Stop()
+	pending := len(checks)
+	expired := false
+	for pending > 0 {
+		select {
+		case res := <-results:
+			c := checks[res.i]
+			c.err, c.took, c.finished = res.err, res.took, true
+			pending--
+		case <-deadline.C:
+			expired = true
+		}
+		if expired && !waitingOnLocal(checks) {
+			break
+		}
+	}
+
+	for _, c := range checks {
+		if !c.finished {
+			c.err = fmt.Errorf("%s did not answer within the %v startup budget", c.name, budget)
+			c.took = time.Since(start)
+		}
+	}
+	return time.Since(start)
+}
+
+// waitingOnLocal reports whether a local check is still running
+func waitingOnLocal(checks []*startupCheck) bool {
+	for _, c := range checks {
+		if c.local && !c.finished {
+			return true
+		}
+	}
+	return false
+}
+
+// printReadiness shows every startup check on one line, with how long each
+// took. The failures are reported in detail by the callers.
+func printReadiness(r readiness, display *ui.EnhancedDisplay) {
+	var parts []string
+	failed := false
+	for _, c := range []*startupCheck{r.ollama, r.models, r.searxng, r.history} {
+		if c.skipped {
+			parts = append(parts, c.name+" skipped")
+			continue
+		}
+		mark := "✓"
+		if c.err != nil {
+			mark, failed = "✗", true
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %v", c.name, mark, c.took.Round(time.Millisecond)))
+	}
+	line := fmt.Sprintf("Started in %v: %s", r.elapsed.Round(time.Millisecond), strings.Join(parts, ", "))
+	if failed {
+		display.PrintWarning(line)
+		return
+	}
+	display.PrintSuccess(line)
+}
+
+// warmUp loads the model before the first question, so that question does
+// not wait for it to load. Failing to is not fatal; the first question then
+// loads it.
+func warmUp(client *ollama.Client, model string, timeout time.Duration, display *ui.EnhancedDisplay) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	display.ShowSpinner(fmt.Sprintf("Loading %s", model))
+	err := client.Warmup(ctx, model)
+	display.StopSpinner()
+	if err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to load %s ahead of the first question: %v", model, err))
+		return
+	}
+	display.PrintSuccess(fmt.Sprintf("%s loaded in %v", model, time.Since(start).Round(100*time.Millisecond)))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"web-ollama/internal/config"
+	"web-ollama/internal/prompt"
+	"web-ollama/internal/ui"
+)
+
+// changeStyle handles /style [default|concise|detailed|bullet]
+func changeStyle(args string, cfg *config.Config, display *ui.EnhancedDisplay) {
+	name := strings.ToLower(strings.TrimSpace(args))
+	if name == "" {
+		display.PrintInfo(fmt.Sprintf("Answer style: %s (choose from %s)", cfg.AnswerStyle, strings.Join(prompt.Styles, ", ")))
+		return
+	}
+	if !prompt.ValidStyle(name) {
+		display.PrintInfo("Usage: /style [" + strings.Join(prompt.Styles, "|") + "]")
+		return
+	}
+	cfg.AnswerStyle = name
+	display.PrintSuccess(fmt.Sprintf("Answers will use the %s style", name))
+}
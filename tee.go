@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"web-ollama/internal/ui"
+)
+
+// answerTee copies answers to a Markdown file token by token as they are
+// generated (--tee), so a long answer survives the terminal dying midway.
+// A nil answerTee does nothing.
+type answerTee struct {
+	file    *os.File
+	display *ui.EnhancedDisplay // warned once if writing fails; may be nil
+	failed  bool
+}
+
+// openTee opens path for appending, or returns nil when path is empty
+func openTee(path string, display *ui.EnhancedDisplay) (*answerTee, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tee file: %w", err)
+	}
+	return &answerTee{file: f, display: display}, nil
+}
+
+// Start heads the next answer with its question
+func (t *answerTee) Start(question string) {
+	t.write(fmt.Sprintf("\n## %s\n\n_%s_\n\n", strings.Join(strings.Fields(question), " "), time.Now().Format("2006-01-02 15:04")))
+}
+
+// Write appends a chunk of the answer
+func (t *answerTee) Write(chunk string) {
+	t.write(chunk)
+}
+
+// End finishes an answer, noting when it was cut short, and flushes it to
+// disk
+func (t *answerTee) End(stopped bool) {
+	if stopped {
+		t.write("\n\n[stopped]")
+	}
+	t.write("\n")
+	if t != nil && !t.failed {
+		t.file.Sync()
+	}
+}
+
+// Close closes the file
+func (t *answerTee) Close() {
+	if t != nil {
+		t.file.Close()
+	}
+}
+
+func (t *answerTee) write(s string) {
+	if t == nil || t.failed {
+		return
+	}
+	if _, err := t.file.WriteString(s); err != nil {
+		t.failed = true
+		if t.display != nil {
+			t.display.PrintWarning(fmt.Sprintf("Stopped writing to %s: %v", t.file.Name(), err))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"time"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// traceContext adds the chunks selected for the prompt and what was dropped
+func traceContext(trace *ui.Trace, report contextbuilder.Report) {
+	node := trace.Add("%s", report.Summary())
+	for _, src := range report.Sources {
+		label := src.Title
+		if label == "" {
+			label = src.URL
+		}
+		node.Add("[%d] %s: %d/%d chunks", src.Number, label, len(src.Chunks), src.Total)
+	}
+	for _, item := range report.Dropped() {
+		node.Add("dropped %s (~%d tokens)", item.Label, item.Tokens)
+	}
+}
+
+// traceGeneration adds Ollama's counts for the answer, when it reported them
+func traceGeneration(trace *ui.Trace, stats ollama.Stats) {
+	if stats.AnswerTokens == 0 {
+		trace.Add("Generation: no statistics reported")
+		return
+	}
+	trace.Add("Generation: %d prompt tokens, %d generated in %v (%.1f tok/s)",
+		stats.PromptTokens, stats.AnswerTokens, stats.Duration.Round(time.Millisecond), stats.TokensPerSecond())
+}
@@ -0,0 +1,40 @@
+package main
+
+import "sync"
+
+// truncatedMarker ends an answer the user stopped, in the display and in
+// history, so neither the user nor the model takes it for a complete one
+const truncatedMarker = "\n\n_[truncated by user]_"
+
+// turnStop cancels the answer being generated, from the ESC listener or the
+// interrupt handler, whichever comes first
+type turnStop struct {
+	mu     sync.Mutex
+	cancel func()
+}
+
+// start records how to cancel the answer now being generated
+func (t *turnStop) start(cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancel = cancel
+}
+
+// done forgets the answer once it is finished
+func (t *turnStop) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancel = nil
+}
+
+// stop cancels the answer being generated and reports whether there was one
+func (t *turnStop) stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel == nil {
+		return false
+	}
+	t.cancel()
+	t.cancel = nil
+	return true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"web-ollama/internal/bandwidth"
+	"web-ollama/internal/config"
+	"web-ollama/internal/hardware"
+	"web-ollama/internal/ollama"
+	"web-ollama/internal/ui"
+)
+
+// checkVRAM warns when the model will not fit in GPU memory, before the
+// first question waits minutes for it to load half on the CPU, and suggests
+// installed models that would fit. A model Ollama already has loaded is
+// judged by how much of it sits on the GPU; otherwise, when Ollama runs on
+// this machine, its size is compared with the GPU's memory. It reports
+// whether the model fits, or could not be judged.
+func checkVRAM(client *ollama.Client, cfg *config.Config, display *ui.EnhancedDisplay) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	models, err := client.Models(ctx)
+	if err != nil {
+		slog.Debug("could not list models", "error", err)
+		return true
+	}
+	var current ollama.ModelDetails
+	for _, m := range models {
+		if m.Name == cfg.ModelName {
+			current = m
+		}
+	}
+	if current.Size == 0 {
+		return true
+	}
+
+	running, err := client.Running(ctx)
+	if err != nil {
+		slog.Debug("could not list running models", "error", err)
+	}
+	for _, r := range running {
+		if r.Name != cfg.ModelName || r.Size == 0 {
+			continue
+		}
+		if r.SizeVRAM < r.Size*9/10 {
+			display.PrintWarning(fmt.Sprintf("%s is loaded with %d%% of its %s in GPU memory; the rest runs on the CPU, so answers will be slow",
+				cfg.ModelName, r.SizeVRAM*100/r.Size, bandwidth.FormatBytes(r.Size)))
+			suggestModels(models, current, r.SizeVRAM, cfg, display)
+			return false
+		}
+		return true
+	}
+
+	if !localOllama(cfg.OllamaURL) {
+		return true
+	}
+	mem := hardware.Detect(ctx)
+	need := hardware.Need(current.Size, cfg.ContextTokens)
+	switch {
+	case mem.VRAM > 0 && need > mem.VRAM:
+		display.PrintWarning(fmt.Sprintf("%s needs about %s with a %d-token context, but %s has %s; it will load partly on the CPU, so the first answer may take minutes",
+			cfg.ModelName, bandwidth.FormatBytes(need), cfg.ContextTokens, mem.GPU, bandwidth.FormatBytes(mem.VRAM)))
+		suggestModels(models, current, mem.VRAM, cfg, display)
+		return false
+	case mem.VRAM == 0 && mem.RAM > 0 && need > mem.RAM:
+		display.PrintWarning(fmt.Sprintf("%s needs about %s but this machine has %s of memory; it may not load at all",
+			cfg.ModelName, bandwidth.FormatBytes(need), bandwidth.FormatBytes(mem.RAM)))
+		suggestModels(models, current, mem.RAM, cfg, display)
+		return false
+	}
+	return true
+}
+
+// suggestModels lists installed models that fit in memory, or else
+// suggests a smaller download
+func suggestModels(models []ollama.ModelDetails, current ollama.ModelDetails, memory int64, cfg *config.Config, display *ui.EnhancedDisplay) {
+	picks := hardware.Recommend(models, current, memory, cfg.ContextTokens, 3)
+	if len(picks) == 0 {
+		hint := "a model with fewer parameters"
+		if q := strings.ToUpper(current.QuantizationLevel); q != "" && !strings.HasPrefix(q, "Q4") && !strings.HasPrefix(q, "Q3") && !strings.HasPrefix(q, "Q2") {
+			hint = fmt.Sprintf("a 4-bit (q4_K_M) tag of %s, or %s", strings.Split(current.Name, ":")[0], hint)
+		}
+		display.PrintInfo(fmt.Sprintf("No installed model fits; try pulling %s, or lower context_tokens", hint))
+		return
+	}
+	display.PrintInfo("Installed models that fit (use --model):")
+	for _, m := range picks {
+		fmt.Printf("  - %s (%s, %s %s)\n", m.Name, bandwidth.FormatBytes(m.Size), m.ParameterSize, m.QuantizationLevel)
+	}
+}
+
+// localOllama reports whether Ollama runs on this machine, so that its
+// memory is what the model loads into
+func localOllama(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
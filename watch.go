@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"web-ollama/internal/notify"
+	"web-ollama/internal/pipeline"
+	"web-ollama/internal/summarize"
+	"web-ollama/internal/ui"
+	"web-ollama/internal/watch"
+)
+
+// runWatch implements `web-ollama watch --every 6h --query "..." --notify`:
+// re-run a query periodically and notify only when the answer changes.
+// Returns the process exit code.
+func runWatch(args []string) int {
+	every := flag.Duration("every", 6*time.Hour, "How often to re-run the query")
+	query := flag.String("query", "", "Question to watch")
+	desktop := flag.Bool("notify", false, "Show a desktop notification when the answer changes")
+	webhook := flag.String("webhook", "", "POST a JSON payload to this URL when the answer changes")
+	once := flag.Bool("once", false, "Check once and exit (for cron)")
+	cfg, _ := parseFlags(args)
+	display := ui.NewEnhancedDisplay(false)
+
+	if *query == "" {
+		display.PrintError(fmt.Errorf("usage: web-ollama watch --query \"question\" [--every 6h] [--notify] [--webhook URL] [--once]"))
+		return 2
+	}
+	if *every < time.Minute {
+		display.PrintError(fmt.Errorf("--every must be at least 1m"))
+		return 2
+	}
+
+	ollamaClient, grounder, err := setupHeadless(cfg, display)
+	if err != nil {
+		display.PrintError(err)
+		return 1
+	}
+	p := &pipeline.Pipeline{
+		Config:       cfg,
+		Client:       ollamaClient,
+		Grounder:     grounder,
+		Post:         openPostprocessor(cfg, display),
+		Summary:      summarize.New(ollamaClient, cfg),
+		Perspectives: newPerspectives(cfg, ollamaClient),
+	}
+	statePath := watch.StatePath(cfg.WatchDir, *query)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	for {
+		if err := checkWatch(ctx, p, *query, statePath, *desktop, *webhook, display); err != nil {
+			display.PrintError(err)
+			if *once {
+				return 1
+			}
+		}
+		if *once {
+			return 0
+		}
+
+		display.PrintInfo(fmt.Sprintf("Next check at %s", time.Now().Add(*every).Format("2006-01-02 15:04")))
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(*every):
+		}
+	}
+}
+
+// checkWatch runs the query once, compares it with the last run and notifies
+// on change. The new answer is saved only once every notification went out,
+// so a change that could not be delivered is reported again next time.
+func checkWatch(ctx context.Context, p *pipeline.Pipeline, query, statePath string, desktop bool, webhook string, display *ui.EnhancedDisplay) error {
+	display.PrintSearchActivity(fmt.Sprintf("Checking %q", query))
+
+	answer, err := p.Ask(ctx, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	next := &watch.State{Query: query, Answer: answer.Answer, Sources: answer.Sources, CheckedAt: time.Now()}
+
+	prev, err := watch.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	if prev == nil {
+		if err := next.Save(statePath); err != nil {
+			return err
+		}
+		display.PrintSuccess("Recorded first answer; future runs will report changes")
+		return nil
+	}
+
+	change := watch.Compare(ctx, p.Client, p.Config.ModelName, prev, next)
+	if !change.Changed {
+		display.PrintInfo("No changes since " + prev.CheckedAt.Format("2006-01-02 15:04"))
+		return next.Save(statePath)
+	}
+
+	display.PrintSuccess(fmt.Sprintf("Changed: %s", change.Summary))
+	fmt.Println(next.Answer)
+
+	if desktop {
+		if err := notify.Desktop("web-ollama: "+query, change.Summary); err != nil {
+			return fmt.Errorf("change not recorded, it will be reported again: %w", err)
+		}
+	}
+	if webhook != "" {
+		payload := map[string]interface{}{
+			"query":       query,
+			"summary":     change.Summary,
+			"answer":      next.Answer,
+			"sources":     next.Sources,
+			"new_sources": change.NewSources,
+			"checked_at":  next.CheckedAt,
+			"previous_at": prev.CheckedAt,
+		}
+		if err := notify.Webhook(ctx, webhook, payload); err != nil {
+			return fmt.Errorf("change not recorded, it will be reported again: %w", err)
+		}
+	}
+	return next.Save(statePath)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"web-ollama/internal/contextbuilder"
+	"web-ollama/internal/history"
+	"web-ollama/internal/pages"
+	"web-ollama/internal/ui"
+)
+
+// showWhy implements /why [n]: without an argument it lists the numbered
+// sources of the last answer; with one it shows the chunks of source n
+// that were in the prompt, with the words of query marked, and the answer
+// sentences they appear to support
+func showWhy(args []string, report contextbuilder.Report, answer, query string, display *ui.EnhancedDisplay) {
+	sources := report.Sources
+	if len(sources) == 0 {
+		display.PrintInfo("The last answer did not use any web sources")
+		return
+	}
+
+	if len(args) == 0 {
+		display.PrintSeparator()
+		fmt.Println("Sources in the last prompt (use /why <n> for details)")
+		display.PrintSeparator()
+		for _, s := range sources {
+			fmt.Printf("[%d] %s\n    %s\n", s.Number, s.Title, s.URL)
+			if len(s.Chunks) < s.Total {
+				fmt.Printf("    %d of %d chunk(s) fit in the prompt\n", len(s.Chunks), s.Total)
+			}
+		}
+		return
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		display.PrintInfo("Usage: /why [source number]")
+		return
+	}
+	ex, err := contextbuilder.Explain(report, n, answer)
+	if err != nil {
+		display.PrintWarning(err.Error())
+		return
+	}
+
+	src := ex.Source
+	display.PrintSeparator()
+	fmt.Printf("[%d] %s\n%s\n", src.Number, src.Title, src.URL)
+	display.PrintSeparator()
+
+	if len(ex.Passages) == 0 {
+		display.PrintInfo("None of this source fit in the prompt")
+		return
+	}
+
+	terms := ui.QueryTerms(query)
+	supported := 0
+	for _, p := range ex.Passages {
+		fmt.Printf("\n[%s] Chunk %d/%d:\n%s\n", contextbuilder.ChunkID(src.Number, p.Chunk), p.Chunk.Index+1, src.Total, ui.Highlight(p.Chunk.Text, terms))
+		for _, claim := range p.Claims {
+			fmt.Printf("  ↳ supports: %s\n", claim)
+			supported++
+		}
+	}
+
+	fmt.Println()
+	if supported == 0 {
+		display.PrintInfo("No answer sentence closely matches this source")
+	} else {
+		display.PrintInfo(fmt.Sprintf("%d answer sentence(s) matched by shared wording; this is a hint, not a fact check", supported))
+	}
+}
+
+// savedReport rebuilds the sources of the newest answer in history that has
+// saved page copies, so /why works after a restart and shows the text the
+// model saw even if the pages have changed since. It also returns the
+// answer and the question it answered.
+func savedReport(historyMgr *history.Manager, store *pages.Store) (contextbuilder.Report, string, string) {
+	if store == nil {
+		return contextbuilder.Report{}, "", ""
+	}
+	sessionID, msg, ok := historyMgr.FindLast(func(m history.Message) bool {
+		return m.Role == "assistant" && m.Metadata != nil && len(m.Metadata.Pages) > 0
+	})
+	if !ok {
+		return contextbuilder.Report{}, "", ""
+	}
+	refs := msg.Metadata.Pages
+	report := contextbuilder.Report{Sources: contextbuilder.Restore(store.LoadAll(sessionID, refs), refs)}
+	return report, msg.Content, questionFor(historyMgr.GetSessionMessages(sessionID, math.MaxInt), msg) + " " + msg.Metadata.StandaloneQuery
+}
+
+// questionFor finds the user message answer replied to
+func questionFor(messages []history.Message, answer history.Message) string {
+	for i := len(messages) - 1; i > 0; i-- {
+		if messages[i].Timestamp.Equal(answer.Timestamp) && messages[i].Role == answer.Role && messages[i-1].Role == "user" {
+			return messages[i-1].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-ollama/internal/history"
+	"web-ollama/internal/ui"
+)
+
+// sessionWorkDir returns the directory @file references resolve against:
+// the one set with /cd for the current session, or the process's own
+func sessionWorkDir(historyMgr *history.Manager) string {
+	if s := historyMgr.GetCurrentSession(); s != nil && s.WorkDir != "" {
+		return s.WorkDir
+	}
+	if dir, err := os.Getwd(); err == nil {
+		return dir
+	}
+	return "."
+}
+
+// changeDir implements /cd <path>, which sets the current session's working
+// directory. Relative paths are taken from the session's current one, and
+// /cd on its own shows it.
+func changeDir(args string, historyMgr *history.Manager, display *ui.EnhancedDisplay) {
+	current := sessionWorkDir(historyMgr)
+	path := strings.TrimSpace(args)
+	if path == "" {
+		display.PrintInfo("Working directory: " + describeDir(current))
+		return
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			display.PrintError(fmt.Errorf("failed to find home directory: %w", err))
+			return
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(current, path)
+	}
+	path = filepath.Clean(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		display.PrintError(fmt.Errorf("failed to change directory: %w", err))
+		return
+	}
+	if !info.IsDir() {
+		display.PrintError(fmt.Errorf("failed to change directory: %s is not a directory", path))
+		return
+	}
+	if err := historyMgr.SetWorkDir(path); err != nil {
+		display.PrintWarning(fmt.Sprintf("Failed to save history: %v", err))
+	}
+	display.PrintSuccess("Working directory: " + describeDir(path))
+}
+
+// sessionGitContext summarizes the git repository of the directory set with
+// /cd for the model: the branch, the latest commits and the uncommitted
+// changes. It returns "" when no directory was set or it is not in a
+// repository, and only the branch when git cannot be run.
+func sessionGitContext(ctx context.Context, historyMgr *history.Manager) string {
+	s := historyMgr.GetCurrentSession()
+	if s == nil || s.WorkDir == "" {
+		return ""
+	}
+	branch := gitBranch(s.WorkDir)
+	if branch == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("=== GIT REPOSITORY OF THE WORKING DIRECTORY ===\n\n")
+	sb.WriteString(fmt.Sprintf("Directory: %s\nBranch: %s\n", s.WorkDir, branch))
+	if commits := runGit(ctx, s.WorkDir, "log", "-5", "--oneline", "--no-decorate"); commits != "" {
+		sb.WriteString("\nRecent commits:\n" + commits + "\n")
+	}
+	if status := runGit(ctx, s.WorkDir, "status", "--short"); status != "" {
+		lines := strings.Split(status, "\n")
+		if len(lines) > gitStatusLines {
+			lines = append(lines[:gitStatusLines], fmt.Sprintf("... and %d more", len(lines)-gitStatusLines))
+		}
+		sb.WriteString("\nUncommitted changes:\n" + strings.Join(lines, "\n") + "\n")
+	}
+	return sb.String()
+}
+
+// gitStatusLines caps the changed files listed in the git context
+const gitStatusLines = 20
+
+// runGit runs a read-only git command in dir and returns its output,
+// or "" when git is missing, fails or takes too long
+func runGit(ctx context.Context, dir string, args ...string) string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+	// Keep the leading spaces git status uses for unstaged changes
+	return strings.TrimRight(string(out), "\n")
+}
+
+// describeDir names dir along with the git branch checked out there, if any
+func describeDir(dir string) string {
+	if branch := gitBranch(dir); branch != "" {
+		return fmt.Sprintf("%s (git: %s)", dir, branch)
+	}
+	return dir
+}
+
+// gitBranch returns the branch checked out in the repository containing
+// dir, a short commit hash when HEAD is detached, or "" outside a
+// repository. It reads .git/HEAD rather than running git.
+func gitBranch(dir string) string {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitDir); err == nil {
+			if !info.IsDir() {
+				// A worktree or submodule: .git names the real directory
+				data, err := os.ReadFile(gitDir)
+				if err != nil {
+					return ""
+				}
+				gitDir = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+				if !filepath.IsAbs(gitDir) {
+					gitDir = filepath.Join(dir, gitDir)
+				}
+			}
+			head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+			if err != nil {
+				return ""
+			}
+			ref := strings.TrimSpace(string(head))
+			if branch, ok := strings.CutPrefix(ref, "ref: refs/heads/"); ok {
+				return branch
+			}
+			if len(ref) > 7 {
+				ref = ref[:7]
+			}
+			return ref
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}